@@ -10,10 +10,10 @@ import (
 	"reflect"
 	"time"
 
+	"github.com/ness-network/ness/src/daemon"
+	"github.com/ness-network/ness/src/daemon/gnet"
+	"github.com/ness-network/ness/src/util/iputil"
 	"github.com/skycoin/skycoin/src/cipher/encoder"
-	"github.com/skycoin/skycoin/src/daemon"
-	"github.com/skycoin/skycoin/src/daemon/gnet"
-	"github.com/skycoin/skycoin/src/util/iputil"
 )
 
 const (
@@ -55,8 +55,7 @@ func NewConnection(addr string, connectTimeout, readTimeout time.Duration) (*Con
 
 // Connect tries to connect to the node
 func (c *Connection) Connect() error {
-	conn, err := net.DialTimeout("tcp",
-		fmt.Sprintf("%s:%v", c.IP, c.Port), c.ConnectTimeout)
+	conn, err := net.DialTimeout("tcp", iputil.JoinAddr(c.IP, c.Port), c.ConnectTimeout)
 	if err != nil {
 		return err
 	}
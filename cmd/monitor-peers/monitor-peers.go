@@ -21,10 +21,11 @@ import (
 
 	"github.com/sirupsen/logrus"
 
-	"github.com/skycoin/skycoin/cmd/monitor-peers/connection"
+	"github.com/ness-network/ness/cmd/monitor-peers/connection"
+	"github.com/ness-network/ness/src/daemon"
+	"github.com/ness-network/ness/src/daemon/pex"
+	"github.com/ness-network/ness/src/util/iputil"
 	"github.com/skycoin/skycoin/src/cipher"
-	"github.com/skycoin/skycoin/src/daemon"
-	"github.com/skycoin/skycoin/src/daemon/pex"
 	"github.com/skycoin/skycoin/src/util/logging"
 )
 
@@ -244,7 +245,7 @@ func getPeersReport(peers []string, connectTimeout, readTimeout time.Duration) R
 			continue
 		}
 
-		if err := introduction.Verify(dc, logrus.Fields{
+		if err := introduction.Verify(dc, nil, logrus.Fields{
 			"addr": addr,
 		}); err != nil {
 			report = report.Append(addr, StateSentIntroduction, introduction, err)
@@ -277,12 +278,12 @@ func buildReport(report Report) string {
 // validateAddress returns a sanitized address if valid, otherwise an error
 func validateAddress(ipPort string, allowLocalhost bool) (string, error) {
 	ipPort = whitespaceFilter.ReplaceAllString(ipPort, "")
-	pts := strings.Split(ipPort, ":")
-	if len(pts) != 2 {
+	host, port, err := iputil.SplitAddr(ipPort)
+	if err != nil {
 		return "", pex.ErrInvalidAddress
 	}
 
-	ip := net.ParseIP(pts[0])
+	ip := net.ParseIP(host)
 	if ip == nil {
 		return "", pex.ErrInvalidAddress
 	} else if ip.IsLoopback() {
@@ -293,11 +294,6 @@ func validateAddress(ipPort string, allowLocalhost bool) (string, error) {
 		return "", pex.ErrNotExternalIP
 	}
 
-	port, err := strconv.ParseUint(pts[1], 10, 16)
-	if err != nil {
-		return "", pex.ErrInvalidAddress
-	}
-
 	if port < 1024 {
 		return "", pex.ErrPortTooLow
 	}
@@ -9,10 +9,11 @@ import (
 
 	"github.com/sirupsen/logrus"
 
-	"github.com/skycoin/skycoin/src/cli"
+	"github.com/ness-network/ness/src/cli"
 	"github.com/skycoin/skycoin/src/util/logging"
 
 	// register the supported wallets
+	_ "github.com/ness-network/ness/src/wallet/watch"
 	_ "github.com/skycoin/skycoin/src/wallet/bip44wallet"
 	_ "github.com/skycoin/skycoin/src/wallet/collection"
 	_ "github.com/skycoin/skycoin/src/wallet/deterministic"
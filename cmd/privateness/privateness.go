@@ -13,12 +13,13 @@ import (
 	_ "net/http/pprof"
 	"os"
 
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/skycoin"
 	"github.com/skycoin/skycoin/src/fiber"
-	"github.com/skycoin/skycoin/src/readable"
-	"github.com/skycoin/skycoin/src/skycoin"
 	"github.com/skycoin/skycoin/src/util/logging"
 
 	// register the supported wallets
+	_ "github.com/ness-network/ness/src/wallet/watch"
 	_ "github.com/skycoin/skycoin/src/wallet/bip44wallet"
 	_ "github.com/skycoin/skycoin/src/wallet/collection"
 	_ "github.com/skycoin/skycoin/src/wallet/deterministic"
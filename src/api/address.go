@@ -2,8 +2,12 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"strconv"
 
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/visor"
 	"github.com/skycoin/skycoin/src/cipher"
 )
 
@@ -54,3 +58,111 @@ func addressVerifyHandler(w http.ResponseWriter, r *http.Request) {
 		},
 	})
 }
+
+// AddressHistoryResponse is returned by GET /api/v2/address/history
+type AddressHistoryResponse struct {
+	Address     string                            `json:"address"`
+	Granularity string                            `json:"granularity"`
+	History     []readable.AddressBalanceSnapshot `json:"history"`
+}
+
+// addressHistoryHandler returns the confirmed balance history of an address
+// Method: GET
+// URI: /api/v2/address/history
+// Args:
+//	address: address [required]
+//	granularity: "block" for a snapshot after each block that changes the balance,
+//	    "day" for a snapshot at the last block of each UTC day [optional, default "block"]
+//	from_seq, to_seq: inclusive range of block seqs [optional]
+//	from_time, to_time: inclusive range of block times [optional]
+//	after: only return snapshots of blocks after this block seq, to fetch the next page [optional]
+//	limit: maximum number of snapshots [optional, default 1000, maximum 10000]
+func addressHistoryHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError405Response(w)
+			return
+		}
+
+		addrStr := r.FormValue("address")
+		if addrStr == "" {
+			writeError400Response(w, "address is required")
+			return
+		}
+
+		addr, err := cipher.DecodeBase58Address(addrStr)
+		if err != nil {
+			writeError400Response(w, fmt.Sprintf("invalid address: %v", err))
+			return
+		}
+
+		granularity := visor.AddressHistoryBlock
+		switch g := visor.AddressHistoryGranularity(r.FormValue("granularity")); g {
+		case "":
+		case visor.AddressHistoryBlock, visor.AddressHistoryDay:
+			granularity = g
+		default:
+			writeError400Response(w, visor.ErrInvalidAddressHistoryGranularity.Error())
+			return
+		}
+
+		br, err := parseBlockRange(r)
+		if err != nil {
+			writeError400Response(w, err.Error())
+			return
+		}
+
+		var after *uint64
+		if afterStr := r.FormValue("after"); afterStr != "" {
+			v, err := strconv.ParseUint(afterStr, 10, 64)
+			if err != nil {
+				writeError400Response(w, fmt.Sprintf("invalid 'after' value: %v", err))
+				return
+			}
+			after = &v
+		}
+
+		limit := uint64(visor.DefaultAddressHistoryLimit)
+		if limitStr := r.FormValue("limit"); limitStr != "" {
+			limit, err = strconv.ParseUint(limitStr, 10, 64)
+			if err != nil {
+				writeError400Response(w, fmt.Sprintf("invalid 'limit' value: %v", err))
+				return
+			}
+
+			if limit == 0 || limit > visor.MaxAddressHistoryLimit {
+				writeError400Response(w, fmt.Sprintf("limit must be between 1 and %d", visor.MaxAddressHistoryLimit))
+				return
+			}
+		}
+
+		snapshots, err := gateway.GetAddressBalanceHistory(visor.AddressHistoryQuery{
+			Address:     addr,
+			Granularity: granularity,
+			FromSeq:     br.fromSeq,
+			ToSeq:       br.toSeq,
+			FromTime:    br.fromTime,
+			ToTime:      br.toTime,
+			After:       after,
+			Limit:       limit,
+		})
+		if err != nil {
+			writeError500Response(w, err.Error())
+			return
+		}
+
+		history, err := readable.NewAddressBalanceSnapshots(snapshots)
+		if err != nil {
+			writeError500Response(w, err.Error())
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: AddressHistoryResponse{
+				Address:     addr.String(),
+				Granularity: string(granularity),
+				History:     history,
+			},
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/visor"
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+const (
+	// ContentTypeNDJSON is the content type of newline delimited JSON streams
+	ContentTypeNDJSON = "application/x-ndjson"
+
+	// MaxAddressBatchAddresses is the maximum number of addresses of a request to the batch address endpoints
+	MaxAddressBatchAddresses = 10000
+	// maxAddressBatchBodySize is the maximum size in bytes of the request body of the batch address endpoints
+	maxAddressBatchBodySize = 1024 * 1024
+)
+
+// AddressBatchRequest is the request body of the batch address endpoints
+type AddressBatchRequest struct {
+	Addresses []string `json:"addrs"`
+}
+
+// AddressBalanceResponse is a line of the /api/v2/balance stream
+type AddressBalanceResponse struct {
+	Address string `json:"address"`
+	// HeadSeq is the seq of the head block that the balance was computed at
+	HeadSeq uint64 `json:"head_seq"`
+	readable.BalancePair
+}
+
+// AddressOutputsResponse is a line of the /api/v2/outputs stream
+type AddressOutputsResponse struct {
+	Address string `json:"address"`
+	// HeadSeq is the seq of the head block that the outputs were read at
+	HeadSeq         uint64                  `json:"head_seq"`
+	HeadOutputs     readable.UnspentOutputs `json:"head_outputs"`
+	OutgoingOutputs readable.UnspentOutputs `json:"outgoing_outputs"`
+	IncomingOutputs readable.UnspentOutputs `json:"incoming_outputs"`
+}
+
+// ndjsonStream writes values as newline delimited JSON.
+// The response header is written with the first value, so that errors
+// before any value is written are returned as a regular error response.
+type ndjsonStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	started bool
+}
+
+func (s *ndjsonStream) write(v interface{}) error {
+	if !s.started {
+		s.w.Header().Set("Content-Type", ContentTypeNDJSON)
+		s.w.WriteHeader(http.StatusOK)
+		s.started = true
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.w.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+
+	return nil
+}
+
+// fail reports an error. Once the stream has started, the error is written as the last line
+// of the stream, in the format of an error response.
+func (s *ndjsonStream) fail(err error) {
+	if !s.started {
+		writeError500Response(s.w, err.Error())
+		return
+	}
+
+	if err := s.write(NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())); err != nil {
+		logger.WithError(err).Error("ndjsonStream.fail: write failed")
+	}
+}
+
+func newNDJSONStream(w http.ResponseWriter) *ndjsonStream {
+	flusher, _ := w.(http.Flusher)
+	return &ndjsonStream{
+		w:       w,
+		flusher: flusher,
+	}
+}
+
+// parseAddressBatchRequest decodes and validates the request body of the batch address endpoints
+func parseAddressBatchRequest(w http.ResponseWriter, r *http.Request) ([]cipher.Address, error) {
+	var req AddressBatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxAddressBatchBodySize)).Decode(&req); err != nil {
+		return nil, err
+	}
+
+	if len(req.Addresses) == 0 {
+		return nil, fmt.Errorf("addrs is required")
+	}
+
+	if len(req.Addresses) > MaxAddressBatchAddresses {
+		return nil, fmt.Errorf("addrs must not contain more than %d addresses", MaxAddressBatchAddresses)
+	}
+
+	addrs := make([]cipher.Address, len(req.Addresses))
+	for i, s := range req.Addresses {
+		a, err := cipher.DecodeBase58Address(s)
+		if err != nil {
+			return nil, fmt.Errorf("address %q is invalid: %v", s, err)
+		}
+
+		addrs[i] = a
+	}
+
+	return addrs, nil
+}
+
+// balanceHandlerV2 streams the confirmed and predicted balance of each address of a set,
+// as newline delimited JSON. All balances are computed at the same head block.
+// URI: /api/v2/balance
+// Method: POST
+// Content-Type: application/json
+// Body: {"addrs": ["<address>", ...]}
+func balanceHandlerV2(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError405Response(w)
+			return
+		}
+
+		addrs, err := parseAddressBatchRequest(w, r)
+		if err != nil {
+			writeError400Response(w, err.Error())
+			return
+		}
+
+		s := newNDJSONStream(w)
+		if err := gateway.WalkBalancesOfAddresses(addrs, func(headSeq uint64, b visor.AddressBalancePair) error {
+			return s.write(AddressBalanceResponse{
+				Address:     b.Address.String(),
+				HeadSeq:     headSeq,
+				BalancePair: readable.NewBalancePair(b.BalancePair),
+			})
+		}); err != nil {
+			s.fail(err)
+		}
+	}
+}
+
+// outputsHandlerV2 streams the unspent outputs of each address of a set,
+// as newline delimited JSON. All outputs are read at the same head block.
+// URI: /api/v2/outputs
+// Method: POST
+// Content-Type: application/json
+// Body: {"addrs": ["<address>", ...]}
+func outputsHandlerV2(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError405Response(w)
+			return
+		}
+
+		addrs, err := parseAddressBatchRequest(w, r)
+		if err != nil {
+			writeError400Response(w, err.Error())
+			return
+		}
+
+		s := newNDJSONStream(w)
+		if err := gateway.WalkUnspentOutputsOfAddresses(addrs, func(headSeq uint64, o visor.AddressUnspentOutputs) error {
+			resp := AddressOutputsResponse{
+				Address: o.Address.String(),
+				HeadSeq: headSeq,
+			}
+
+			var err error
+			resp.HeadOutputs, err = readable.NewUnspentOutputs(o.Confirmed)
+			if err != nil {
+				return err
+			}
+
+			resp.OutgoingOutputs, err = readable.NewUnspentOutputs(o.Outgoing)
+			if err != nil {
+				return err
+			}
+
+			resp.IncomingOutputs, err = readable.NewUnspentOutputs(o.Incoming)
+			if err != nil {
+				return err
+			}
+
+			return s.write(resp)
+		}); err != nil {
+			s.fail(err)
+		}
+	}
+}
@@ -0,0 +1,312 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/visor"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// ndjsonLines encodes values as newline delimited JSON
+func ndjsonLines(t *testing.T, values ...interface{}) string {
+	var lines []string
+	for _, v := range values {
+		b, err := json.Marshal(v)
+		require.NoError(t, err)
+		lines = append(lines, string(b)+"\n")
+	}
+	return strings.Join(lines, "")
+}
+
+func TestBalanceHandlerV2(t *testing.T) {
+	addrA := testutil.MakeAddress()
+	addrB := testutil.MakeAddress()
+
+	balances := []visor.AddressBalancePair{
+		{
+			Address: addrA,
+			BalancePair: wallet.BalancePair{
+				Confirmed: wallet.Balance{Coins: 2e6, Hours: 10},
+				Predicted: wallet.Balance{Coins: 1e6, Hours: 4},
+			},
+		},
+		{
+			Address: addrB,
+		},
+	}
+
+	cases := []struct {
+		name        string
+		method      string
+		body        string
+		status      int
+		err         string
+		addrs       []cipher.Address
+		walkErr     error
+		walkErrAt   int
+		contentType string
+		expect      string
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:   "400 - invalid body",
+			method: http.MethodPost,
+			body:   "{",
+			status: http.StatusBadRequest,
+			err:    "unexpected EOF",
+		},
+		{
+			name:   "400 - addrs missing",
+			method: http.MethodPost,
+			body:   `{"addrs":[]}`,
+			status: http.StatusBadRequest,
+			err:    "addrs is required",
+		},
+		{
+			name:   "400 - invalid address",
+			method: http.MethodPost,
+			body:   `{"addrs":["badaddr"]}`,
+			status: http.StatusBadRequest,
+			err:    `address "badaddr" is invalid: Invalid address length`,
+		},
+		{
+			name:   "400 - too many addresses",
+			method: http.MethodPost,
+			body:   `{"addrs":[` + strings.Repeat(`"`+addrA.String()+`",`, MaxAddressBatchAddresses) + `"` + addrA.String() + `"]}`,
+			status: http.StatusBadRequest,
+			err:    "addrs must not contain more than 10000 addresses",
+		},
+		{
+			name:   "400 - body too large",
+			method: http.MethodPost,
+			body:   `{"addrs":["` + strings.Repeat("a", maxAddressBatchBodySize) + `"]}`,
+			status: http.StatusBadRequest,
+			err:    "http: request body too large",
+		},
+		{
+			name:      "500 - walk error",
+			method:    http.MethodPost,
+			body:      `{"addrs":["` + addrA.String() + `","` + addrB.String() + `"]}`,
+			status:    http.StatusInternalServerError,
+			err:       "walk failed",
+			addrs:     []cipher.Address{addrA, addrB},
+			walkErr:   errors.New("walk failed"),
+			walkErrAt: 0,
+		},
+		{
+			name:        "200 - walk error after the stream started",
+			method:      http.MethodPost,
+			body:        `{"addrs":["` + addrA.String() + `","` + addrB.String() + `"]}`,
+			status:      http.StatusOK,
+			addrs:       []cipher.Address{addrA, addrB},
+			walkErr:     errors.New("walk failed"),
+			walkErrAt:   1,
+			contentType: ContentTypeNDJSON,
+			expect: ndjsonLines(t,
+				AddressBalanceResponse{
+					Address: addrA.String(),
+					HeadSeq: 20,
+					BalancePair: readable.BalancePair{
+						Confirmed: readable.Balance{Coins: 2e6, Hours: 10},
+						Predicted: readable.Balance{Coins: 1e6, Hours: 4},
+					},
+				},
+				NewHTTPErrorResponse(http.StatusInternalServerError, "walk failed"),
+			),
+		},
+		{
+			name:        "200",
+			method:      http.MethodPost,
+			body:        `{"addrs":["` + addrA.String() + `","` + addrB.String() + `"]}`,
+			status:      http.StatusOK,
+			addrs:       []cipher.Address{addrA, addrB},
+			contentType: ContentTypeNDJSON,
+			expect: ndjsonLines(t,
+				AddressBalanceResponse{
+					Address: addrA.String(),
+					HeadSeq: 20,
+					BalancePair: readable.BalancePair{
+						Confirmed: readable.Balance{Coins: 2e6, Hours: 10},
+						Predicted: readable.Balance{Coins: 1e6, Hours: 4},
+					},
+				},
+				AddressBalanceResponse{
+					Address: addrB.String(),
+					HeadSeq: 20,
+				},
+			),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("WalkBalancesOfAddresses", tc.addrs, mock.Anything).Return(func(addrs []cipher.Address, f func(uint64, visor.AddressBalancePair) error) error {
+				for i, b := range balances {
+					if tc.walkErr != nil && i == tc.walkErrAt {
+						return tc.walkErr
+					}
+
+					if err := f(20, b); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+
+			req, err := http.NewRequest(tc.method, "/api/v2/balance", bytes.NewBufferString(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", ContentTypeJSON)
+
+			setCSRFParameters(t, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			if tc.err != "" {
+				var rsp ReceivedHTTPResponse
+				err = json.Unmarshal(rr.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Equal(t, tc.contentType, rr.Header().Get("Content-Type"))
+			require.Equal(t, tc.expect, rr.Body.String())
+		})
+	}
+}
+
+func TestOutputsHandlerV2(t *testing.T) {
+	addr := testutil.MakeAddress()
+
+	ux := coin.UxOut{
+		Head: coin.UxHead{
+			Time:  1000,
+			BkSeq: 5,
+		},
+		Body: coin.UxBody{
+			SrcTransaction: testutil.RandSHA256(t),
+			Address:        addr,
+			Coins:          2e6,
+			Hours:          10,
+		},
+	}
+
+	out, err := visor.NewUnspentOutput(ux, 1000)
+	require.NoError(t, err)
+
+	readableOuts, err := readable.NewUnspentOutputs([]visor.UnspentOutput{out})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name    string
+		method  string
+		body    string
+		status  int
+		err     string
+		addrs   []cipher.Address
+		walkErr error
+		expect  string
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:   "400 - addrs missing",
+			method: http.MethodPost,
+			body:   `{}`,
+			status: http.StatusBadRequest,
+			err:    "addrs is required",
+		},
+		{
+			name:    "500 - walk error",
+			method:  http.MethodPost,
+			body:    `{"addrs":["` + addr.String() + `"]}`,
+			status:  http.StatusInternalServerError,
+			err:     "walk failed",
+			addrs:   []cipher.Address{addr},
+			walkErr: errors.New("walk failed"),
+		},
+		{
+			name:   "200",
+			method: http.MethodPost,
+			body:   `{"addrs":["` + addr.String() + `"]}`,
+			status: http.StatusOK,
+			addrs:  []cipher.Address{addr},
+			expect: ndjsonLines(t, AddressOutputsResponse{
+				Address:         addr.String(),
+				HeadSeq:         20,
+				HeadOutputs:     readableOuts,
+				OutgoingOutputs: readable.UnspentOutputs{},
+				IncomingOutputs: readableOuts,
+			}),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("WalkUnspentOutputsOfAddresses", tc.addrs, mock.Anything).Return(func(addrs []cipher.Address, f func(uint64, visor.AddressUnspentOutputs) error) error {
+				if tc.walkErr != nil {
+					return tc.walkErr
+				}
+
+				return f(20, visor.AddressUnspentOutputs{
+					Address:   addr,
+					Confirmed: []visor.UnspentOutput{out},
+					Incoming:  []visor.UnspentOutput{out},
+				})
+			})
+
+			req, err := http.NewRequest(tc.method, "/api/v2/outputs", bytes.NewBufferString(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", ContentTypeJSON)
+
+			setCSRFParameters(t, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code, "got `%v` want `%v`", rr.Code, tc.status)
+
+			if tc.err != "" {
+				var rsp ReceivedHTTPResponse
+				err = json.Unmarshal(rr.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Equal(t, ContentTypeNDJSON, rr.Header().Get("Content-Type"))
+			require.Equal(t, tc.expect, rr.Body.String())
+		})
+	}
+}
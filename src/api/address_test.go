@@ -2,12 +2,18 @@ package api
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/visor"
+	"github.com/skycoin/skycoin/src/testutil"
 )
 
 func toJSON(t *testing.T, r interface{}) string {
@@ -146,3 +152,280 @@ func TestVerifyAddress(t *testing.T) {
 		})
 	}
 }
+
+func TestAddressHistory(t *testing.T) {
+	addr := testutil.MakeAddress()
+
+	u64 := func(v uint64) *uint64 {
+		return &v
+	}
+
+	snapshots := []visor.AddressBalanceSnapshot{
+		{
+			BlockSeq: 10,
+			Time:     1000,
+			Coins:    2e6,
+			Hours:    5,
+			Received: 2e6,
+		},
+		{
+			BlockSeq: 12,
+			Time:     1020,
+			Coins:    1500000,
+			Hours:    9,
+			Received: 1e6,
+			Sent:     1500000,
+		},
+	}
+
+	cases := []struct {
+		name         string
+		method       string
+		status       int
+		query        url.Values
+		q            visor.AddressHistoryQuery
+		gatewayErr   error
+		snapshots    []visor.AddressBalanceSnapshot
+		httpResponse HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodPost,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:         "400 - missing address",
+			method:       http.MethodGet,
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "address is required"),
+		},
+		{
+			name:   "400 - invalid address",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			query: url.Values{
+				"address": []string{"foo"},
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "invalid address: Invalid address length"),
+		},
+		{
+			name:   "400 - invalid granularity",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			query: url.Values{
+				"address":     []string{addr.String()},
+				"granularity": []string{"week"},
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "invalid address history granularity, must be 'block' or 'day'"),
+		},
+		{
+			name:   "400 - invalid from_seq",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			query: url.Values{
+				"address":  []string{addr.String()},
+				"from_seq": []string{"-1"},
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, `invalid 'from_seq' value: strconv.ParseUint: parsing "-1": invalid syntax`),
+		},
+		{
+			name:   "400 - invalid after",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			query: url.Values{
+				"address": []string{addr.String()},
+				"after":   []string{"x"},
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, `invalid 'after' value: strconv.ParseUint: parsing "x": invalid syntax`),
+		},
+		{
+			name:   "400 - invalid limit",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			query: url.Values{
+				"address": []string{addr.String()},
+				"limit":   []string{"-1"},
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, `invalid 'limit' value: strconv.ParseUint: parsing "-1": invalid syntax`),
+		},
+		{
+			name:   "400 - zero limit",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			query: url.Values{
+				"address": []string{addr.String()},
+				"limit":   []string{"0"},
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "limit must be between 1 and 10000"),
+		},
+		{
+			name:   "400 - limit too large",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			query: url.Values{
+				"address": []string{addr.String()},
+				"limit":   []string{"10001"},
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "limit must be between 1 and 10000"),
+		},
+		{
+			name:   "500 - gateway error",
+			method: http.MethodGet,
+			status: http.StatusInternalServerError,
+			query: url.Values{
+				"address": []string{addr.String()},
+			},
+			q: visor.AddressHistoryQuery{
+				Address:     addr,
+				Granularity: visor.AddressHistoryBlock,
+				Limit:       visor.DefaultAddressHistoryLimit,
+			},
+			gatewayErr:   errors.New("GetAddressBalanceHistory failed"),
+			httpResponse: NewHTTPErrorResponse(http.StatusInternalServerError, "GetAddressBalanceHistory failed"),
+		},
+		{
+			name:   "200 - no history",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			query: url.Values{
+				"address": []string{addr.String()},
+			},
+			q: visor.AddressHistoryQuery{
+				Address:     addr,
+				Granularity: visor.AddressHistoryBlock,
+				Limit:       visor.DefaultAddressHistoryLimit,
+			},
+			httpResponse: HTTPResponse{
+				Data: AddressHistoryResponse{
+					Address:     addr.String(),
+					Granularity: "block",
+					History:     []readable.AddressBalanceSnapshot{},
+				},
+			},
+		},
+		{
+			name:   "200 - day with range",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			query: url.Values{
+				"address":     []string{addr.String()},
+				"granularity": []string{"day"},
+				"from_seq":    []string{"10"},
+				"to_seq":      []string{"20"},
+				"from_time":   []string{"900"},
+				"to_time":     []string{"2000"},
+			},
+			q: visor.AddressHistoryQuery{
+				Address:     addr,
+				Granularity: visor.AddressHistoryDay,
+				FromSeq:     u64(10),
+				ToSeq:       u64(20),
+				FromTime:    u64(900),
+				ToTime:      u64(2000),
+				Limit:       visor.DefaultAddressHistoryLimit,
+			},
+			snapshots: snapshots,
+			httpResponse: HTTPResponse{
+				Data: AddressHistoryResponse{
+					Address:     addr.String(),
+					Granularity: "day",
+					History: []readable.AddressBalanceSnapshot{
+						{
+							BlockSeq: 10,
+							Time:     1000,
+							Coins:    "2.000000",
+							Hours:    5,
+							Received: "2.000000",
+							Sent:     "0.000000",
+						},
+						{
+							BlockSeq: 12,
+							Time:     1020,
+							Coins:    "1.500000",
+							Hours:    9,
+							Received: "1.000000",
+							Sent:     "1.500000",
+						},
+					},
+				},
+			},
+		},
+		{
+			name:   "200 - page",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			query: url.Values{
+				"address": []string{addr.String()},
+				"after":   []string{"10"},
+				"limit":   []string{"1"},
+			},
+			q: visor.AddressHistoryQuery{
+				Address:     addr,
+				Granularity: visor.AddressHistoryBlock,
+				After:       u64(10),
+				Limit:       1,
+			},
+			snapshots: snapshots[1:],
+			httpResponse: HTTPResponse{
+				Data: AddressHistoryResponse{
+					Address:     addr.String(),
+					Granularity: "block",
+					History: []readable.AddressBalanceSnapshot{
+						{
+							BlockSeq: 12,
+							Time:     1020,
+							Coins:    "1.500000",
+							Hours:    9,
+							Received: "1.000000",
+							Sent:     "1.500000",
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetAddressBalanceHistory", tc.q).Return(tc.snapshots, tc.gatewayErr)
+
+			endpoint := "/api/v2/address/history"
+			if len(tc.query) > 0 {
+				endpoint += "?" + tc.query.Encode()
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", ContentTypeJSON)
+
+			setCSRFParameters(t, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.Unmarshal(rr.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data == nil {
+				require.Nil(t, tc.httpResponse.Data)
+			} else {
+				require.NotNil(t, tc.httpResponse.Data)
+
+				var historyRsp AddressHistoryResponse
+				err := json.Unmarshal(rsp.Data, &historyRsp)
+				require.NoError(t, err)
+
+				require.Equal(t, tc.httpResponse.Data.(AddressHistoryResponse), historyRsp)
+			}
+		})
+	}
+}
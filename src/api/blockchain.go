@@ -3,17 +3,18 @@ package api
 // APIs for blockchain related information
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
 	"strings"
 
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/visor"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
-	"github.com/skycoin/skycoin/src/readable"
 	wh "github.com/skycoin/skycoin/src/util/http"
-	"github.com/skycoin/skycoin/src/visor"
 )
 
 // blockchainMetadataHandler returns the blockchain metadata
@@ -399,3 +400,95 @@ func lastBlocksHandler(gateway Gatewayer) http.HandlerFunc {
 		wh.SendJSONOr500(logger, w, rb)
 	}
 }
+
+// blockTemplateHandler returns the transactions that the block publisher would put into the next block,
+// ordered by the fee they burn per byte
+// Method: GET
+// URI: /api/v2/block/template
+func blockTemplateHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError405Response(w)
+			return
+		}
+
+		bt, err := gateway.GetBlockTemplate()
+		if err != nil {
+			writeError500Response(w, err.Error())
+			return
+		}
+
+		rbt, err := readable.NewBlockTemplate(bt)
+		if err != nil {
+			writeError500Response(w, err.Error())
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: rbt,
+		})
+	}
+}
+
+// KeyCheckpointRequest is sent to POST /api/v2/blockchain/key_checkpoint
+type KeyCheckpointRequest struct {
+	Seq    uint64 `json:"seq"`
+	Pubkey string `json:"pubkey"`
+}
+
+// keyCheckpointHandler creates a key checkpoint that hands the block signing authority to a new
+// blockchain pubkey from a block seq onward, and broadcasts it to the network
+// Method: POST
+// URI: /api/v2/blockchain/key_checkpoint
+// Args:
+//	seq: seq of the first block signed by the new pubkey [required]
+//	pubkey: the new blockchain pubkey [required]
+func keyCheckpointHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			writeError405Response(w)
+			return
+		}
+
+		var req KeyCheckpointRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError400Response(w, err.Error())
+			return
+		}
+
+		if req.Seq == 0 {
+			writeError400Response(w, "seq is required")
+			return
+		}
+
+		if req.Pubkey == "" {
+			writeError400Response(w, "pubkey is required")
+			return
+		}
+
+		pubkey, err := cipher.PubKeyFromHex(req.Pubkey)
+		if err != nil {
+			writeError400Response(w, fmt.Sprintf("Invalid pubkey: %v", err))
+			return
+		}
+
+		kc, err := gateway.CreateBroadcastKeyCheckpoint(req.Seq, pubkey)
+		if err != nil {
+			var resp HTTPResponse
+			switch err {
+			case visor.ErrKeyCheckpointNotPublisher:
+				resp = NewHTTPErrorResponse(http.StatusForbidden, err.Error())
+			case visor.ErrKeyCheckpointSeq, visor.ErrKeyCheckpointSigner:
+				resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			default:
+				resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			}
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: readable.NewKeyCheckpoint(*kc),
+		})
+	}
+}
@@ -15,13 +15,15 @@ import (
 	"github.com/stretchr/testify/require"
 
 	"errors"
+	"fmt"
 
+	"github.com/ness-network/ness/src/daemon"
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/visor"
+	"github.com/ness-network/ness/src/visor/blockdb"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
-	"github.com/skycoin/skycoin/src/daemon"
-	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/testutil"
-	"github.com/skycoin/skycoin/src/visor"
 )
 
 func TestGetBlockchainMetadata(t *testing.T) {
@@ -69,8 +71,9 @@ func TestGetBlockchainMetadata(t *testing.T) {
 					BodyHash:     "0000000000000000000000000000000000000000000000000000000000000000",
 					UxHash:       "0000000000000000000000000000000000000000000000000000000000000000",
 				},
-				Unspents:    12,
-				Unconfirmed: 13,
+				Unspents:       12,
+				Unconfirmed:    13,
+				KeyCheckpoints: []readable.KeyCheckpoint{},
 			},
 		},
 	}
@@ -162,8 +165,11 @@ func TestGetBlockchainProgress(t *testing.T) {
 						Height:  102,
 					},
 				},
-				Current: 99,
-				Highest: 102,
+				Current:          99,
+				Highest:          102,
+				Headers:          101,
+				BlocksInFlight:   1,
+				BlocksDownloaded: 2,
 			},
 			result: readable.BlockchainProgress{
 				Peers: []readable.PeerBlockchainHeight{
@@ -176,8 +182,11 @@ func TestGetBlockchainProgress(t *testing.T) {
 						Height:  102,
 					},
 				},
-				Current: 99,
-				Highest: 102,
+				Current:          99,
+				Highest:          102,
+				Headers:          101,
+				BlocksInFlight:   1,
+				BlocksDownloaded: 2,
 			},
 		},
 	}
@@ -1221,3 +1230,235 @@ func TestGetLastBlocks(t *testing.T) {
 		})
 	}
 }
+
+func TestGetBlockTemplate(t *testing.T) {
+	txn := coin.Transaction{
+		Length:    100,
+		InnerHash: testutil.RandSHA256(t),
+		In:        []cipher.SHA256{testutil.RandSHA256(t)},
+	}
+
+	bt := &visor.BlockTemplate{
+		Head: coin.BlockHeader{
+			BkSeq: 10,
+		},
+		Transactions: []visor.BlockTemplateTransaction{
+			{
+				Transaction: txn,
+				Fee:         20,
+				Size:        100,
+				PackageFee:  50,
+				PackageSize: 200,
+			},
+		},
+		Fee:  20,
+		Size: 100,
+	}
+
+	rbt, err := readable.NewBlockTemplate(bt)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name                   string
+		method                 string
+		status                 int
+		getBlockTemplateResult *visor.BlockTemplate
+		getBlockTemplateErr    error
+		httpResponse           HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodPost,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:                "500 - GetBlockTemplate error",
+			method:              http.MethodGet,
+			status:              http.StatusInternalServerError,
+			getBlockTemplateErr: errors.New("GetBlockTemplate error"),
+			httpResponse:        NewHTTPErrorResponse(http.StatusInternalServerError, "GetBlockTemplate error"),
+		},
+		{
+			name:                   "200",
+			method:                 http.MethodGet,
+			status:                 http.StatusOK,
+			getBlockTemplateResult: bt,
+			httpResponse: HTTPResponse{
+				Data: *rbt,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetBlockTemplate").Return(tc.getBlockTemplateResult, tc.getBlockTemplateErr)
+
+			endpoint := "/api/v2/block/template"
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", ContentTypeJSON)
+
+			setCSRFParameters(t, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.Unmarshal(rr.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data == nil {
+				require.Nil(t, tc.httpResponse.Data)
+			} else {
+				require.NotNil(t, tc.httpResponse.Data)
+
+				var msg readable.BlockTemplate
+				err := json.Unmarshal(rsp.Data, &msg)
+				require.NoError(t, err)
+
+				require.Equal(t, tc.httpResponse.Data.(readable.BlockTemplate), msg)
+			}
+		})
+	}
+}
+
+func TestKeyCheckpoint(t *testing.T) {
+	pubkey, seckey := cipher.GenerateKeyPair()
+	kc := blockdb.NewKeyCheckpoint(100, pubkey, seckey)
+
+	cases := []struct {
+		name                   string
+		method                 string
+		status                 int
+		body                   string
+		seq                    uint64
+		pubkey                 cipher.PubKey
+		createKeyCheckpoint    *blockdb.KeyCheckpoint
+		createKeyCheckpointErr error
+		httpResponse           HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodGet,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:         "400 - invalid json",
+			method:       http.MethodPost,
+			status:       http.StatusBadRequest,
+			body:         `{"seq":`,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "unexpected EOF"),
+		},
+		{
+			name:         "400 - missing seq",
+			method:       http.MethodPost,
+			status:       http.StatusBadRequest,
+			body:         fmt.Sprintf(`{"pubkey":"%s"}`, pubkey.Hex()),
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "seq is required"),
+		},
+		{
+			name:         "400 - missing pubkey",
+			method:       http.MethodPost,
+			status:       http.StatusBadRequest,
+			body:         `{"seq":100}`,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "pubkey is required"),
+		},
+		{
+			name:         "400 - invalid pubkey",
+			method:       http.MethodPost,
+			status:       http.StatusBadRequest,
+			body:         `{"seq":100,"pubkey":"abc"}`,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "Invalid pubkey: Invalid public key"),
+		},
+		{
+			name:                   "400 - invalid seq",
+			method:                 http.MethodPost,
+			status:                 http.StatusBadRequest,
+			body:                   fmt.Sprintf(`{"seq":100,"pubkey":"%s"}`, pubkey.Hex()),
+			seq:                    100,
+			pubkey:                 pubkey,
+			createKeyCheckpointErr: visor.ErrKeyCheckpointSeq,
+			httpResponse:           NewHTTPErrorResponse(http.StatusBadRequest, visor.ErrKeyCheckpointSeq.Error()),
+		},
+		{
+			name:                   "403 - not a block publisher",
+			method:                 http.MethodPost,
+			status:                 http.StatusForbidden,
+			body:                   fmt.Sprintf(`{"seq":100,"pubkey":"%s"}`, pubkey.Hex()),
+			seq:                    100,
+			pubkey:                 pubkey,
+			createKeyCheckpointErr: visor.ErrKeyCheckpointNotPublisher,
+			httpResponse:           NewHTTPErrorResponse(http.StatusForbidden, visor.ErrKeyCheckpointNotPublisher.Error()),
+		},
+		{
+			name:                   "500 - CreateBroadcastKeyCheckpoint error",
+			method:                 http.MethodPost,
+			status:                 http.StatusInternalServerError,
+			body:                   fmt.Sprintf(`{"seq":100,"pubkey":"%s"}`, pubkey.Hex()),
+			seq:                    100,
+			pubkey:                 pubkey,
+			createKeyCheckpointErr: errors.New("CreateBroadcastKeyCheckpoint error"),
+			httpResponse:           NewHTTPErrorResponse(http.StatusInternalServerError, "CreateBroadcastKeyCheckpoint error"),
+		},
+		{
+			name:                "200",
+			method:              http.MethodPost,
+			status:              http.StatusOK,
+			body:                fmt.Sprintf(`{"seq":100,"pubkey":"%s"}`, pubkey.Hex()),
+			seq:                 100,
+			pubkey:              pubkey,
+			createKeyCheckpoint: &kc,
+			httpResponse: HTTPResponse{
+				Data: readable.NewKeyCheckpoint(kc),
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("CreateBroadcastKeyCheckpoint", tc.seq, tc.pubkey).Return(tc.createKeyCheckpoint, tc.createKeyCheckpointErr)
+
+			endpoint := "/api/v2/blockchain/key_checkpoint"
+			req, err := http.NewRequest(tc.method, endpoint, strings.NewReader(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", ContentTypeJSON)
+
+			setCSRFParameters(t, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.Unmarshal(rr.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data == nil {
+				require.Nil(t, tc.httpResponse.Data)
+			} else {
+				require.NotNil(t, tc.httpResponse.Data)
+
+				var msg readable.KeyCheckpoint
+				err := json.Unmarshal(rsp.Data, &msg)
+				require.NoError(t, err)
+
+				require.Equal(t, tc.httpResponse.Data.(readable.KeyCheckpoint), msg)
+			}
+		})
+	}
+}
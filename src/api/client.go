@@ -13,11 +13,11 @@ import (
 	"strings"
 	"time"
 
+	"github.com/ness-network/ness/src/daemon"
+	"github.com/ness-network/ness/src/readable"
 	"github.com/skycoin/skycoin/src/cipher/bip44"
 	"github.com/skycoin/skycoin/src/coin"
-	"github.com/skycoin/skycoin/src/daemon"
 	"github.com/skycoin/skycoin/src/kvstorage"
-	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/wallet"
 )
 
@@ -318,6 +318,83 @@ func (c *Client) requestV2(method, endpoint string, body io.Reader, respObj inte
 	return true, rspErr
 }
 
+// PostJSONStream makes a POST request to an endpoint with body of json data,
+// and calls f with each line of a newline delimited JSON response.
+// An error line written by the server after the stream has started is returned as a ClientError.
+func (c *Client) PostJSONStream(endpoint string, reqObj interface{}, f func(json.RawMessage) error) error {
+	csrf, err := c.CSRF()
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(reqObj)
+	if err != nil {
+		return err
+	}
+
+	endpoint = strings.TrimLeft(endpoint, "/")
+	endpoint = c.Addr + endpoint
+
+	req, err := http.NewRequest(http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	c.applyAuth(req)
+
+	if csrf != "" {
+		req.Header.Set(CSRFHeaderName, csrf)
+	}
+
+	req.Header.Set("Content-Type", ContentTypeJSON)
+	req.Header.Set("Accept", ContentTypeNDJSON)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+
+		var wrapObj ReceivedHTTPResponse
+		if err := json.Unmarshal(respBody, &wrapObj); err != nil || wrapObj.Error == nil {
+			return NewClientError(resp.Status, resp.StatusCode, string(respBody))
+		}
+
+		return NewClientError(resp.Status, resp.StatusCode, wrapObj.Error.Message)
+	}
+
+	decoder := json.NewDecoder(resp.Body)
+	for {
+		var line json.RawMessage
+		if err := decoder.Decode(&line); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		var wrapObj ReceivedHTTPResponse
+		if err := json.Unmarshal(line, &wrapObj); err != nil {
+			return err
+		}
+
+		if wrapObj.Error != nil {
+			return NewClientError(http.StatusText(wrapObj.Error.Code), wrapObj.Error.Code, wrapObj.Error.Message)
+		}
+
+		if err := f(line); err != nil {
+			return err
+		}
+	}
+}
+
 // CSRF returns a CSRF token. If CSRF is disabled on the node, returns an empty string and nil error.
 func (c *Client) CSRF() (string, error) {
 	resp, err := c.get("/api/v1/csrf")
@@ -576,6 +653,16 @@ func (c *Client) BlockchainProgress() (*readable.BlockchainProgress, error) {
 	return &b, nil
 }
 
+// CreateKeyCheckpoint makes a request to POST /api/v2/blockchain/key_checkpoint
+func (c *Client) CreateKeyCheckpoint(req KeyCheckpointRequest) (*readable.KeyCheckpoint, error) {
+	var kc readable.KeyCheckpoint
+	ok, err := c.PostJSONV2("/api/v2/blockchain/key_checkpoint", req, &kc)
+	if ok {
+		return &kc, err
+	}
+	return nil, err
+}
+
 // Balance makes a request to POST /api/v1/balance?addrs=xxx
 func (c *Client) Balance(addrs []string) (*BalanceResponse, error) {
 	v := url.Values{}
@@ -589,6 +676,36 @@ func (c *Client) Balance(addrs []string) (*BalanceResponse, error) {
 	return &b, nil
 }
 
+// BalanceV2 makes a request to POST /api/v2/balance and calls f with the balance of each address
+func (c *Client) BalanceV2(addrs []string, f func(AddressBalanceResponse) error) error {
+	req := AddressBatchRequest{
+		Addresses: addrs,
+	}
+
+	return c.PostJSONStream("/api/v2/balance", req, func(line json.RawMessage) error {
+		var b AddressBalanceResponse
+		if err := json.Unmarshal(line, &b); err != nil {
+			return err
+		}
+		return f(b)
+	})
+}
+
+// OutputsV2 makes a request to POST /api/v2/outputs and calls f with the unspent outputs of each address
+func (c *Client) OutputsV2(addrs []string, f func(AddressOutputsResponse) error) error {
+	req := AddressBatchRequest{
+		Addresses: addrs,
+	}
+
+	return c.PostJSONStream("/api/v2/outputs", req, func(line json.RawMessage) error {
+		var o AddressOutputsResponse
+		if err := json.Unmarshal(line, &o); err != nil {
+			return err
+		}
+		return f(o)
+	})
+}
+
 // UxOut makes a request to GET /api/v1/uxout?uxid=xxx
 func (c *Client) UxOut(uxID string) (*readable.SpentOutput, error) {
 	v := url.Values{}
@@ -651,6 +768,7 @@ type CreateWalletOptions struct {
 	Encrypt               bool
 	Bip44Coin             *bip44.CoinType
 	CollectionPrivateKeys string
+	Addresses             string
 }
 
 // CreateWallet makes a request to POST /api/v1/wallet/create and creates a wallet.
@@ -686,6 +804,10 @@ func (c *Client) CreateWallet(o CreateWalletOptions) (*WalletResponse, error) {
 		v.Add("private-keys", o.CollectionPrivateKeys)
 	}
 
+	if o.Addresses != "" {
+		v.Add("addresses", o.Addresses)
+	}
+
 	var w WalletResponse
 	if err := c.PostForm("/api/v1/wallet/create", strings.NewReader(v.Encode()), &w); err != nil {
 		return nil, err
@@ -751,6 +873,19 @@ func (c *Client) NewWalletAddress(id string, password string, options ...wallet.
 		v.Add("private-keys", strings.Join(keys, ","))
 	}
 
+	var bip44Opts wallet.Bip44EntriesOptions
+	for _, f := range options {
+		f(&bip44Opts)
+	}
+
+	if bip44Opts.Account > 0 {
+		v.Add("account", fmt.Sprint(bip44Opts.Account))
+	}
+
+	if bip44Opts.ChainMode == wallet.ChangeChain {
+		v.Add("change", "true")
+	}
+
 	var obj struct {
 		Addresses []string `json:"addresses"`
 	}
@@ -800,6 +935,7 @@ type CreateTransactionRequest struct {
 	To                []Receiver     `json:"to"`
 	UxOuts            []string       `json:"unspents,omitempty"`
 	Addresses         []string       `json:"addresses,omitempty"`
+	SpendStrategy     string         `json:"spend_strategy,omitempty"`
 }
 
 // HoursSelection defines options for hours distribution
@@ -818,9 +954,10 @@ type Receiver struct {
 
 // WalletCreateTransactionRequest is sent to /api/v1/wallet/transaction
 type WalletCreateTransactionRequest struct {
-	Unsigned bool   `json:"unsigned"`
-	WalletID string `json:"wallet_id"`
-	Password string `json:"password"`
+	Unsigned bool    `json:"unsigned"`
+	WalletID string  `json:"wallet_id"`
+	Password string  `json:"password"`
+	Account  *uint32 `json:"account,omitempty"`
 	CreateTransactionRequest
 }
 
@@ -846,6 +983,28 @@ func (c *Client) WalletSignTransaction(req WalletSignTransactionRequest) (*Creat
 	return nil, err
 }
 
+// WalletBumpFee makes a request to POST /api/v2/wallet/transaction/bump_fee
+func (c *Client) WalletBumpFee(req WalletBumpFeeRequest) (*CreateTransactionResponse, error) {
+	var r CreateTransactionResponse
+	endpoint := "/api/v2/wallet/transaction/bump_fee"
+	ok, err := c.PostJSONV2(endpoint, req, &r)
+	if ok {
+		return &r, err
+	}
+	return nil, err
+}
+
+// WalletSignPacket makes a request to POST /api/v2/wallet/psbt/sign
+func (c *Client) WalletSignPacket(req WalletSignPacketRequest) (*WalletSignPacketResponse, error) {
+	var r WalletSignPacketResponse
+	endpoint := "/api/v2/wallet/psbt/sign"
+	ok, err := c.PostJSONV2(endpoint, req, &r)
+	if ok {
+		return &r, err
+	}
+	return nil, err
+}
+
 // CreateTransaction makes a request to POST /api/v2/transaction
 func (c *Client) CreateTransaction(req CreateTransactionRequest) (*CreateTransactionResponse, error) {
 	var r CreateTransactionResponse
@@ -1267,6 +1426,54 @@ func (c *Client) VerifyAddress(addr string) (*VerifyAddressResponse, error) {
 	return nil, err
 }
 
+// AddressHistoryParams are arguments to the /api/v2/address/history endpoint
+type AddressHistoryParams struct {
+	Address     string
+	Granularity string
+	FromSeq     *uint64
+	ToSeq       *uint64
+	FromTime    *uint64
+	ToTime      *uint64
+	After       *uint64
+	Limit       uint64
+}
+
+// AddressHistory makes a request to GET /api/v2/address/history
+func (c *Client) AddressHistory(params AddressHistoryParams) (*AddressHistoryResponse, error) {
+	v := url.Values{}
+	v.Add("address", params.Address)
+	if params.Granularity != "" {
+		v.Add("granularity", params.Granularity)
+	}
+
+	for _, p := range []struct {
+		name  string
+		value *uint64
+	}{
+		{"from_seq", params.FromSeq},
+		{"to_seq", params.ToSeq},
+		{"from_time", params.FromTime},
+		{"to_time", params.ToTime},
+		{"after", params.After},
+	} {
+		if p.value != nil {
+			v.Add(p.name, fmt.Sprint(*p.value))
+		}
+	}
+
+	if params.Limit != 0 {
+		v.Add("limit", fmt.Sprint(params.Limit))
+	}
+
+	var rsp AddressHistoryResponse
+	ok, err := c.GetV2("/api/v2/address/history?"+v.Encode(), &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
 // RichlistParams are arguments to the /richlist endpoint
 type RichlistParams struct {
 	N                   int
@@ -1359,6 +1566,78 @@ func (c *Client) RecoverWallet(req WalletRecoverRequest) (*WalletResponse, error
 	return nil, err
 }
 
+// WalletAccounts makes a request to GET /api/v2/wallet/accounts
+func (c *Client) WalletAccounts(id string) (*WalletAccountsResponse, error) {
+	v := url.Values{}
+	v.Add("id", id)
+
+	var rsp WalletAccountsResponse
+	ok, err := c.GetV2("/api/v2/wallet/accounts?"+v.Encode(), &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// CreateWalletAccount makes a request to POST /api/v2/wallet/account/create
+func (c *Client) CreateWalletAccount(req WalletAccountCreateRequest) (*WalletAccountCreateResponse, error) {
+	var rsp WalletAccountCreateResponse
+	ok, err := c.PostJSONV2("/api/v2/wallet/account/create", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// WalletOutputs makes a request to GET /api/v2/wallet/outputs
+func (c *Client) WalletOutputs(id string) (*WalletOutputsResponse, error) {
+	v := url.Values{}
+	v.Add("id", id)
+
+	var rsp WalletOutputsResponse
+	ok, err := c.GetV2("/api/v2/wallet/outputs?"+v.Encode(), &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// FreezeWalletOutputs makes a request to POST /api/v2/wallet/outputs/freeze
+func (c *Client) FreezeWalletOutputs(req WalletOutputsFreezeRequest) (*WalletOutputsResponse, error) {
+	var rsp WalletOutputsResponse
+	ok, err := c.PostJSONV2("/api/v2/wallet/outputs/freeze", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// UnfreezeWalletOutputs makes a request to POST /api/v2/wallet/outputs/unfreeze
+func (c *Client) UnfreezeWalletOutputs(req WalletOutputsFreezeRequest) (*WalletOutputsResponse, error) {
+	var rsp WalletOutputsResponse
+	ok, err := c.PostJSONV2("/api/v2/wallet/outputs/unfreeze", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
+// SetWalletOutputLabel makes a request to POST /api/v2/wallet/outputs/label
+func (c *Client) SetWalletOutputLabel(req WalletOutputLabelRequest) (*WalletOutputsResponse, error) {
+	var rsp WalletOutputsResponse
+	ok, err := c.PostJSONV2("/api/v2/wallet/outputs/label", req, &rsp)
+	if ok {
+		return &rsp, err
+	}
+
+	return nil, err
+}
+
 // Disconnect disconnect a connections by ID
 func (c *Client) Disconnect(id uint64) error {
 	v := url.Values{}
@@ -1368,6 +1647,43 @@ func (c *Client) Disconnect(id uint64) error {
 	return c.PostForm("/api/v1/network/connection/disconnect", strings.NewReader(v.Encode()), &obj)
 }
 
+// NetworkBans makes a request to GET /api/v1/network/bans
+func (c *Client) NetworkBans() (*Bans, error) {
+	var b Bans
+	if err := c.Get("/api/v1/network/bans", &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// BanPeer makes a request to POST /api/v1/network/bans/add.
+// If duration is 0, the node's ban duration is used
+func (c *Client) BanPeer(addr string, duration time.Duration, reason string) (*readable.Ban, error) {
+	v := url.Values{}
+	v.Add("addr", addr)
+	if duration != 0 {
+		v.Add("duration", duration.String())
+	}
+	if reason != "" {
+		v.Add("reason", reason)
+	}
+
+	var b readable.Ban
+	if err := c.PostForm("/api/v1/network/bans/add", strings.NewReader(v.Encode()), &b); err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// UnbanPeer makes a request to POST /api/v1/network/bans/remove
+func (c *Client) UnbanPeer(addr string) error {
+	v := url.Values{}
+	v.Add("addr", addr)
+
+	var obj struct{}
+	return c.PostForm("/api/v1/network/bans/remove", strings.NewReader(v.Encode()), &obj)
+}
+
 // GetAllStorageValues makes a GET request to /api/v2/data to get all the values from the storage of
 // `storageType` type
 func (c *Client) GetAllStorageValues(storageType kvstorage.Type) (map[string]string, error) {
@@ -1418,16 +1734,20 @@ type RequestArg struct {
 	Value string
 }
 
-// TransactionsWithStatusV2 represents transactions result with page info
+// TransactionsWithStatusV2 represents transactions result with page info.
+// CursorInfo is set instead of PageInfo when the transactions are paged through with cursors.
 type TransactionsWithStatusV2 struct {
-	PageInfo readable.PageInfo                `json:"page_info"`
-	Txns     []readable.TransactionWithStatus `json:"txns"`
+	PageInfo   readable.PageInfo                `json:"page_info"`
+	CursorInfo *readable.CursorPageInfo         `json:"cursor_info,omitempty"`
+	Txns       []readable.TransactionWithStatus `json:"txns"`
 }
 
-// TransactionsWithStatusVerboseV2 represents verbose transactions result with page info
+// TransactionsWithStatusVerboseV2 represents verbose transactions result with page info.
+// CursorInfo is set instead of PageInfo when the transactions are paged through with cursors.
 type TransactionsWithStatusVerboseV2 struct {
-	PageInfo readable.PageInfo                       `json:"page_info"`
-	Txns     []readable.TransactionWithStatusVerbose `json:"txns"`
+	PageInfo   readable.PageInfo                       `json:"page_info"`
+	CursorInfo *readable.CursorPageInfo                `json:"cursor_info,omitempty"`
+	Txns       []readable.TransactionWithStatusVerbose `json:"txns"`
 }
 
 // TransactionsV2 make a GET request to /api/v2/transaction to get transactions with no verbose.
@@ -0,0 +1,429 @@
+package api
+
+// APIs for streaming blockchain events
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/visor"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/util/timeutil"
+)
+
+const (
+	// EventAddressTxn is the event type of a transaction that touches one of the subscribed addresses,
+	// sent when the transaction enters the unconfirmed pool and when it is executed in a block
+	EventAddressTxn = "address_txn"
+
+	// eventsKeepAliveInterval is how often a comment is sent to keep an idle event stream open
+	eventsKeepAliveInterval = 15 * time.Second
+	// eventsReplayPageSize is the number of blocks read at a time when replaying blocks
+	eventsReplayPageSize = 100
+)
+
+// UnconfirmedTxnEvent is the data of the unconfirmed_txn_added and unconfirmed_txn_removed events
+type UnconfirmedTxnEvent struct {
+	readable.UnconfirmedTransactionVerbose
+	// Reason is why the transaction left the unconfirmed pool, "confirmed", "invalid", "evicted" or "replaced"
+	Reason string `json:"reason,omitempty"`
+}
+
+// AddressTxnEvent is the data of the address_txn event
+type AddressTxnEvent struct {
+	// Addresses are the subscribed addresses that the transaction touches
+	Addresses   []string                    `json:"addresses"`
+	Transaction readable.TransactionVerbose `json:"transaction"`
+}
+
+// eventStream writes events to a client as Server-Sent Events
+type eventStream struct {
+	w       io.Writer
+	flusher http.Flusher
+	types   map[string]struct{}
+	addrs   map[cipher.Address]struct{}
+	// lastSeq is the seq of the last block that was sent, blocks up to it are not sent again
+	lastSeq    uint64
+	hasLastSeq bool
+}
+
+type streamEvent struct {
+	typ  string
+	data interface{}
+}
+
+func (s *eventStream) wants(typ string) bool {
+	_, ok := s.types[typ]
+	return ok
+}
+
+// matchAddresses returns the subscribed addresses that a transaction touches
+func (s *eventStream) matchAddresses(txn coin.Transaction, inputs []visor.TransactionInput) []string {
+	if len(s.addrs) == 0 || !s.wants(EventAddressTxn) {
+		return nil
+	}
+
+	var addrs []string
+	for _, a := range visor.TransactionAddresses(txn, inputs) {
+		if _, ok := s.addrs[a]; ok {
+			addrs = append(addrs, a.String())
+		}
+	}
+
+	return addrs
+}
+
+// write writes events and flushes them. The last event is given the id, if not empty
+func (s *eventStream) write(id string, events ...streamEvent) error {
+	for i, e := range events {
+		data, err := json.Marshal(e.data)
+		if err != nil {
+			return err
+		}
+
+		if id != "" && i == len(events)-1 {
+			if _, err := fmt.Fprintf(s.w, "id: %s\n", id); err != nil {
+				return err
+			}
+		}
+
+		if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", e.typ, data); err != nil {
+			return err
+		}
+	}
+
+	s.flusher.Flush()
+	return nil
+}
+
+// keepAlive writes a comment, which clients ignore
+func (s *eventStream) keepAlive() error {
+	if _, err := io.WriteString(s.w, ":\n\n"); err != nil {
+		return err
+	}
+
+	s.flusher.Flush()
+	return nil
+}
+
+// sendBlock sends the address_txn events of a block, followed by the block event.
+// The last of them has the block seq as its id, so that a client that reconnects resumes after the block.
+func (s *eventStream) sendBlock(b coin.SignedBlock, inputs [][]visor.TransactionInput, headSeq uint64) error {
+	if s.hasLastSeq && b.Seq() <= s.lastSeq {
+		return nil
+	}
+
+	var events []streamEvent
+
+	for i, txn := range b.Body.Transactions {
+		addrs := s.matchAddresses(txn, inputs[i])
+		if len(addrs) == 0 {
+			continue
+		}
+
+		rTxn, err := readable.NewTransactionVerbose(visor.Transaction{
+			Transaction: txn,
+			Status:      visor.NewConfirmedTransactionStatus(headSeq-b.Seq()+1, b.Seq()),
+			Time:        b.Time(),
+		}, inputs[i])
+		if err != nil {
+			return err
+		}
+
+		events = append(events, streamEvent{
+			typ: EventAddressTxn,
+			data: AddressTxnEvent{
+				Addresses:   addrs,
+				Transaction: rTxn,
+			},
+		})
+	}
+
+	if s.wants(string(visor.EventBlock)) {
+		rb, err := readable.NewBlockVerbose(b.Block, inputs)
+		if err != nil {
+			return err
+		}
+
+		events = append(events, streamEvent{
+			typ:  string(visor.EventBlock),
+			data: rb,
+		})
+	}
+
+	s.lastSeq = b.Seq()
+	s.hasLastSeq = true
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	return s.write(strconv.FormatUint(b.Seq(), 10), events...)
+}
+
+// sendBlockDisconnected sends the event of a block rolled back by a reorg.
+// The blocks of the branch that replace it have the same seqs, so the blocks after its parent are sent again
+// and the event has the seq of its parent as its id.
+func (s *eventStream) sendBlockDisconnected(b coin.SignedBlock, inputs [][]visor.TransactionInput) error {
+	if s.hasLastSeq && b.Seq() <= s.lastSeq {
+		s.lastSeq = b.Seq() - 1
+	}
+
+	if !s.wants(string(visor.EventBlockDisconnected)) {
+		return nil
+	}
+
+	rb, err := readable.NewBlockVerbose(b.Block, inputs)
+	if err != nil {
+		return err
+	}
+
+	return s.write(strconv.FormatUint(b.Seq()-1, 10), streamEvent{
+		typ:  string(visor.EventBlockDisconnected),
+		data: rb,
+	})
+}
+
+// sendUnconfirmedTxn sends the events of a transaction entering or leaving the unconfirmed pool
+func (s *eventStream) sendUnconfirmedTxn(e visor.Event) error {
+	var events []streamEvent
+
+	if s.wants(string(e.Type)) {
+		rTxn, err := readable.NewUnconfirmedTransactionVerbose(e.Transaction, e.TransactionInputs)
+		if err != nil {
+			return err
+		}
+
+		events = append(events, streamEvent{
+			typ: string(e.Type),
+			data: UnconfirmedTxnEvent{
+				UnconfirmedTransactionVerbose: *rTxn,
+				Reason:                        e.Reason,
+			},
+		})
+	}
+
+	// Confirmed transactions are sent with their block
+	if e.Type == visor.EventUnconfirmedTxnAdded {
+		if addrs := s.matchAddresses(e.Transaction.Transaction, e.TransactionInputs); len(addrs) != 0 {
+			rTxn, err := readable.NewTransactionVerbose(visor.Transaction{
+				Transaction: e.Transaction.Transaction,
+				Status:      visor.NewUnconfirmedTransactionStatus(),
+				Time:        uint64(timeutil.NanoToTime(e.Transaction.Received).Unix()),
+			}, e.TransactionInputs)
+			if err != nil {
+				return err
+			}
+
+			events = append(events, streamEvent{
+				typ: EventAddressTxn,
+				data: AddressTxnEvent{
+					Addresses:   addrs,
+					Transaction: rTxn,
+				},
+			})
+		}
+	}
+
+	if len(events) == 0 {
+		return nil
+	}
+
+	return s.write("", events...)
+}
+
+// send sends the events of a visor.Event
+func (s *eventStream) send(e visor.Event) error {
+	switch e.Type {
+	case visor.EventBlock:
+		return s.sendBlock(*e.Block, e.BlockInputs, e.Block.Seq())
+	case visor.EventBlockDisconnected:
+		return s.sendBlockDisconnected(*e.Block, e.BlockInputs)
+	case visor.EventUnconfirmedTxnAdded, visor.EventUnconfirmedTxnRemoved:
+		return s.sendUnconfirmedTxn(e)
+	default:
+		return nil
+	}
+}
+
+// replay sends the blocks after seq up to the head block
+func (s *eventStream) replay(gateway Gatewayer, seq uint64) error {
+	headSeq, ok, err := gateway.HeadBkSeq()
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return nil
+	}
+
+	for start := seq + 1; start <= headSeq; start += eventsReplayPageSize {
+		end := start + eventsReplayPageSize - 1
+		if end > headSeq {
+			end = headSeq
+		}
+
+		blocks, inputs, err := gateway.GetBlocksInRangeVerbose(start, end)
+		if err != nil {
+			return err
+		}
+
+		for i, b := range blocks {
+			if err := s.sendBlock(b, inputs[i], headSeq); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// eventsHandler streams new blocks, blocks rolled back by a reorg, transactions entering or leaving
+// the unconfirmed pool and transactions that touch given addresses as Server-Sent Events
+// Method: GET
+// URI: /api/v2/events
+// Args:
+//	types: comma separated event types [optional, defaults to all types]
+//	addrs: comma separated addresses to send address_txn events for [optional]
+//	since_seq: replay the blocks after this seq before streaming [optional]
+// The Last-Event-ID header, sent by clients that reconnect, takes precedence over since_seq.
+// maxDuration closes the stream before the server write timeout, clients are expected to reconnect.
+func eventsHandler(gateway Gatewayer, maxDuration time.Duration) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writeError405Response(w)
+			return
+		}
+
+		addrs, err := parseAddressesFromStr(r.FormValue("addrs"))
+		if err != nil {
+			writeError400Response(w, fmt.Sprintf("parse parameter: 'addrs' failed: %v", err))
+			return
+		}
+
+		s := &eventStream{
+			w:     w,
+			types: make(map[string]struct{}),
+			addrs: make(map[cipher.Address]struct{}, len(addrs)),
+		}
+
+		for _, a := range addrs {
+			s.addrs[a] = struct{}{}
+		}
+
+		types := splitCommaString(r.FormValue("types"))
+		if len(types) == 0 {
+			types = []string{
+				string(visor.EventBlock),
+				string(visor.EventBlockDisconnected),
+				string(visor.EventUnconfirmedTxnAdded),
+				string(visor.EventUnconfirmedTxnRemoved),
+			}
+			if len(addrs) != 0 {
+				types = append(types, EventAddressTxn)
+			}
+		}
+
+		for _, typ := range types {
+			switch typ {
+			case string(visor.EventBlock),
+				string(visor.EventBlockDisconnected),
+				string(visor.EventUnconfirmedTxnAdded),
+				string(visor.EventUnconfirmedTxnRemoved),
+				EventAddressTxn:
+				s.types[typ] = struct{}{}
+			default:
+				writeError400Response(w, fmt.Sprintf("Invalid event type %q", typ))
+				return
+			}
+		}
+
+		if s.wants(EventAddressTxn) && len(addrs) == 0 {
+			writeError400Response(w, "addrs is required for address_txn events")
+			return
+		}
+
+		sinceStr := strings.TrimSpace(r.Header.Get("Last-Event-ID"))
+		if sinceStr == "" {
+			sinceStr = r.FormValue("since_seq")
+		}
+
+		var since uint64
+		replay := sinceStr != ""
+		if replay {
+			since, err = strconv.ParseUint(sinceStr, 10, 64)
+			if err != nil {
+				writeError400Response(w, "Invalid since_seq or Last-Event-ID")
+				return
+			}
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeError500Response(w, "Streaming is not supported")
+			return
+		}
+		s.flusher = flusher
+
+		// Subscribe before replaying, blocks executed meanwhile are skipped by seq
+		sub := gateway.SubscribeEvents()
+		defer sub.Unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		logger.WithField("addr", r.RemoteAddr).Info("Event stream opened")
+		defer logger.WithField("addr", r.RemoteAddr).Info("Event stream closed")
+
+		if replay {
+			if err := s.replay(gateway, since); err != nil {
+				logger.WithError(err).Error("Event stream replay failed")
+				return
+			}
+		}
+
+		keepAlive := time.NewTicker(eventsKeepAliveInterval)
+		defer keepAlive.Stop()
+
+		var done <-chan time.Time
+		if maxDuration > 0 {
+			timer := time.NewTimer(maxDuration)
+			defer timer.Stop()
+			done = timer.C
+		}
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-done:
+				return
+			case <-keepAlive.C:
+				if err := s.keepAlive(); err != nil {
+					return
+				}
+			case e, ok := <-sub.Events:
+				if !ok {
+					// The subscriber fell behind and was dropped, the client reconnects and replays the missed blocks
+					logger.WithField("addr", r.RemoteAddr).Warning("Event stream dropped")
+					return
+				}
+
+				if err := s.send(e); err != nil {
+					logger.WithError(err).Error("Event stream send failed")
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,433 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/visor"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// parseSSE parses a Server-Sent Events stream, skipping comments
+func parseSSE(t *testing.T, body string) []sseEvent {
+	var events []sseEvent
+	var e sseEvent
+
+	scanner := bufio.NewScanner(strings.NewReader(body))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if e.event != "" {
+				events = append(events, e)
+			}
+			e = sseEvent{}
+		case strings.HasPrefix(line, ":"):
+		case strings.HasPrefix(line, "id: "):
+			e.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			e.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			e.data = strings.TrimPrefix(line, "data: ")
+		default:
+			t.Fatalf("unexpected event stream line %q", line)
+		}
+	}
+	require.NoError(t, scanner.Err())
+
+	return events
+}
+
+func makeEventsTestBlock(seq uint64, txns ...coin.Transaction) coin.SignedBlock {
+	return coin.SignedBlock{
+		Block: coin.Block{
+			Head: coin.BlockHeader{
+				BkSeq: seq,
+				Time:  1000 + seq*10,
+			},
+			Body: coin.BlockBody{
+				Transactions: txns,
+			},
+		},
+	}
+}
+
+func TestEventsHandler(t *testing.T) {
+	fromAddr := testutil.MakeAddress()
+	toAddr := testutil.MakeAddress()
+	otherAddr := testutil.MakeAddress()
+
+	ux := coin.UxOut{
+		Body: coin.UxBody{
+			SrcTransaction: testutil.RandSHA256(t),
+			Address:        fromAddr,
+			Coins:          2e6,
+			Hours:          10,
+		},
+	}
+
+	txn := coin.Transaction{
+		In: []cipher.SHA256{ux.Hash()},
+		Out: []coin.TransactionOutput{
+			{
+				Address: toAddr,
+				Coins:   2e6,
+				Hours:   5,
+			},
+		},
+	}
+	inputs := []visor.TransactionInput{
+		{
+			UxOut:           ux,
+			CalculatedHours: 10,
+		},
+	}
+
+	unconfirmed := &visor.UnconfirmedTransaction{
+		Transaction: txn,
+		Received:    time.Now().UnixNano(),
+		IsValid:     1,
+	}
+
+	b2 := makeEventsTestBlock(2)
+	b3 := makeEventsTestBlock(3, txn)
+	b4 := makeEventsTestBlock(4)
+	b3b := makeEventsTestBlock(3)
+	b3b.Head.Time++
+	b4b := makeEventsTestBlock(4)
+	b4b.Head.Time++
+
+	blockEvent := func(b coin.SignedBlock, inputs [][]visor.TransactionInput) visor.Event {
+		return visor.Event{
+			Type:        visor.EventBlock,
+			Block:       &b,
+			BlockInputs: inputs,
+		}
+	}
+
+	liveEvents := []visor.Event{
+		{
+			Type:              visor.EventUnconfirmedTxnAdded,
+			Transaction:       unconfirmed,
+			TransactionInputs: inputs,
+		},
+		blockEvent(b3, [][]visor.TransactionInput{inputs}),
+		{
+			Type:              visor.EventUnconfirmedTxnRemoved,
+			Transaction:       unconfirmed,
+			TransactionInputs: inputs,
+			Reason:            visor.RemovedTxnConfirmed,
+		},
+		blockEvent(b4, [][]visor.TransactionInput{}),
+	}
+
+	// Blocks 3 and 4 are replaced by a reorg
+	reorgEvents := append(liveEvents[:len(liveEvents):len(liveEvents)],
+		visor.Event{
+			Type:        visor.EventBlockDisconnected,
+			Block:       &b4,
+			BlockInputs: [][]visor.TransactionInput{},
+		},
+		visor.Event{
+			Type:        visor.EventBlockDisconnected,
+			Block:       &b3,
+			BlockInputs: [][]visor.TransactionInput{inputs},
+		},
+		blockEvent(b3b, [][]visor.TransactionInput{}),
+		blockEvent(b4b, [][]visor.TransactionInput{}),
+	)
+
+	type blocksInRange struct {
+		start  uint64
+		end    uint64
+		blocks []coin.SignedBlock
+		inputs [][][]visor.TransactionInput
+	}
+
+	cases := []struct {
+		name          string
+		method        string
+		status        int
+		query         url.Values
+		lastEventID   string
+		err           string
+		events        []visor.Event
+		headBkSeq     uint64
+		blocksInRange *blocksInRange
+		expect        []sseEvent
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "Method Not Allowed",
+		},
+		{
+			name:   "400 - invalid addrs",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			query: url.Values{
+				"addrs": []string{"foo"},
+			},
+			err: `parse parameter: 'addrs' failed: address "foo" is invalid: Invalid address length`,
+		},
+		{
+			name:   "400 - invalid type",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			query: url.Values{
+				"types": []string{"block,foo"},
+			},
+			err: `Invalid event type "foo"`,
+		},
+		{
+			name:   "400 - address_txn without addrs",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			query: url.Values{
+				"types": []string{EventAddressTxn},
+			},
+			err: "addrs is required for address_txn events",
+		},
+		{
+			name:   "400 - invalid since_seq",
+			method: http.MethodGet,
+			status: http.StatusBadRequest,
+			query: url.Values{
+				"since_seq": []string{"-1"},
+			},
+			err: "Invalid since_seq or Last-Event-ID",
+		},
+		{
+			name:   "200 - all events",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			query: url.Values{
+				"addrs": []string{otherAddr.String() + "," + toAddr.String()},
+			},
+			events: liveEvents,
+			expect: []sseEvent{
+				{event: string(visor.EventUnconfirmedTxnAdded)},
+				{event: EventAddressTxn},
+				{event: EventAddressTxn},
+				{event: string(visor.EventBlock), id: "3"},
+				{event: string(visor.EventUnconfirmedTxnRemoved)},
+				{event: string(visor.EventBlock), id: "4"},
+			},
+		},
+		{
+			name:   "200 - blocks only",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			query: url.Values{
+				"types": []string{string(visor.EventBlock)},
+			},
+			events: liveEvents,
+			expect: []sseEvent{
+				{event: string(visor.EventBlock), id: "3"},
+				{event: string(visor.EventBlock), id: "4"},
+			},
+		},
+		{
+			name:   "200 - reorg",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			query: url.Values{
+				"types": []string{string(visor.EventBlock) + "," + string(visor.EventBlockDisconnected)},
+			},
+			events: reorgEvents,
+			expect: []sseEvent{
+				{event: string(visor.EventBlock), id: "3"},
+				{event: string(visor.EventBlock), id: "4"},
+				{event: string(visor.EventBlockDisconnected), id: "3"},
+				{event: string(visor.EventBlockDisconnected), id: "2"},
+				{event: string(visor.EventBlock), id: "3"},
+				{event: string(visor.EventBlock), id: "4"},
+			},
+		},
+		{
+			name:   "200 - reorg, blocks only",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			query: url.Values{
+				"types": []string{string(visor.EventBlock)},
+			},
+			events: reorgEvents,
+			expect: []sseEvent{
+				{event: string(visor.EventBlock), id: "3"},
+				{event: string(visor.EventBlock), id: "4"},
+				{event: string(visor.EventBlock), id: "3"},
+				{event: string(visor.EventBlock), id: "4"},
+			},
+		},
+		{
+			name:   "200 - address_txn only, address not touched",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			query: url.Values{
+				"addrs": []string{otherAddr.String()},
+				"types": []string{EventAddressTxn},
+			},
+			events: liveEvents,
+		},
+		{
+			name:   "200 - replay since_seq",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			query: url.Values{
+				"since_seq": []string{"1"},
+				"types":     []string{string(visor.EventBlock)},
+			},
+			events:    liveEvents,
+			headBkSeq: 3,
+			blocksInRange: &blocksInRange{
+				start:  2,
+				end:    3,
+				blocks: []coin.SignedBlock{b2, b3},
+				inputs: [][][]visor.TransactionInput{{}, {inputs}},
+			},
+			expect: []sseEvent{
+				{event: string(visor.EventBlock), id: "2"},
+				{event: string(visor.EventBlock), id: "3"},
+				{event: string(visor.EventBlock), id: "4"},
+			},
+		},
+		{
+			name:   "200 - Last-Event-ID overrides since_seq",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			query: url.Values{
+				"since_seq": []string{"0"},
+				"types":     []string{string(visor.EventBlock)},
+			},
+			lastEventID: "2",
+			events:      liveEvents,
+			headBkSeq:   3,
+			blocksInRange: &blocksInRange{
+				start:  3,
+				end:    3,
+				blocks: []coin.SignedBlock{b3},
+				inputs: [][][]visor.TransactionInput{{inputs}},
+			},
+			expect: []sseEvent{
+				{event: string(visor.EventBlock), id: "3"},
+				{event: string(visor.EventBlock), id: "4"},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+
+			// The stream ends once all events are read, like a subscriber that is dropped
+			ch := make(chan visor.Event, len(tc.events))
+			for _, e := range tc.events {
+				ch <- e
+			}
+			close(ch)
+			gateway.On("SubscribeEvents").Return(&visor.EventSubscription{Events: ch})
+
+			if tc.blocksInRange != nil {
+				gateway.On("HeadBkSeq").Return(tc.headBkSeq, true, nil)
+				gateway.On("GetBlocksInRangeVerbose", tc.blocksInRange.start, tc.blocksInRange.end).Return(tc.blocksInRange.blocks, tc.blocksInRange.inputs, nil)
+			}
+
+			endpoint := "/api/v2/events"
+			if len(tc.query) > 0 {
+				endpoint += "?" + tc.query.Encode()
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", ContentTypeJSON)
+			if tc.lastEventID != "" {
+				req.Header.Set("Last-Event-ID", tc.lastEventID)
+			}
+
+			setCSRFParameters(t, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				var rsp ReceivedHTTPResponse
+				err = json.Unmarshal(rr.Body.Bytes(), &rsp)
+				require.NoError(t, err)
+				require.NotNil(t, rsp.Error)
+				require.Equal(t, tc.err, rsp.Error.Message)
+				return
+			}
+
+			require.Equal(t, "text/event-stream", rr.Header().Get("Content-Type"))
+
+			events := parseSSE(t, rr.Body.String())
+			require.Len(t, events, len(tc.expect))
+			for i, e := range events {
+				require.Equal(t, tc.expect[i].event, e.event)
+				require.Equal(t, tc.expect[i].id, e.id)
+
+				switch e.event {
+				case string(visor.EventBlock):
+					var b readable.BlockVerbose
+					require.NoError(t, json.Unmarshal([]byte(e.data), &b))
+					require.Equal(t, e.id, strconv.FormatUint(b.Head.BkSeq, 10))
+				case string(visor.EventBlockDisconnected):
+					var b readable.BlockVerbose
+					require.NoError(t, json.Unmarshal([]byte(e.data), &b))
+					require.Equal(t, e.id, strconv.FormatUint(b.Head.BkSeq-1, 10))
+				case string(visor.EventUnconfirmedTxnAdded):
+					var u UnconfirmedTxnEvent
+					require.NoError(t, json.Unmarshal([]byte(e.data), &u))
+					require.Equal(t, txn.Hash().Hex(), u.Transaction.Hash)
+					require.Empty(t, u.Reason)
+				case string(visor.EventUnconfirmedTxnRemoved):
+					var u UnconfirmedTxnEvent
+					require.NoError(t, json.Unmarshal([]byte(e.data), &u))
+					require.Equal(t, txn.Hash().Hex(), u.Transaction.Hash)
+					require.Equal(t, visor.RemovedTxnConfirmed, u.Reason)
+				case EventAddressTxn:
+					var a AddressTxnEvent
+					require.NoError(t, json.Unmarshal([]byte(e.data), &a))
+					require.Equal(t, []string{toAddr.String()}, a.Addresses)
+					require.Equal(t, txn.Hash().Hex(), a.Transaction.Hash)
+				}
+			}
+
+			// The first address_txn event is sent when the transaction is unconfirmed,
+			// the second when it is executed in block 3
+			if len(events) > 2 && events[1].event == EventAddressTxn {
+				var a AddressTxnEvent
+				require.NoError(t, json.Unmarshal([]byte(events[1].data), &a))
+				require.True(t, a.Transaction.Status.Unconfirmed)
+
+				require.NoError(t, json.Unmarshal([]byte(events[2].data), &a))
+				require.True(t, a.Transaction.Status.Confirmed)
+				require.Equal(t, uint64(3), a.Transaction.Status.BlockSeq)
+				require.Equal(t, uint64(1), a.Transaction.Status.Height)
+			}
+		})
+	}
+}
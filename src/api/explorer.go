@@ -5,8 +5,8 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/ness-network/ness/src/readable"
 	"github.com/skycoin/skycoin/src/cipher"
-	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/util/droplet"
 	wh "github.com/skycoin/skycoin/src/util/http"
 	"github.com/skycoin/skycoin/src/util/mathutil"
@@ -199,7 +199,7 @@ func richlistHandler(gateway Gatewayer) http.HandlerFunc {
 			}
 		}
 
-		richlist, err := gateway.GetRichlist(includeDistribution)
+		richlist, err := gateway.GetRichlist(includeDistribution, topn)
 		if err != nil {
 			wh.Error500(w, err.Error())
 			return
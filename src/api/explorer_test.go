@@ -13,13 +13,13 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/visor"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/params"
-	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/testutil"
 	"github.com/skycoin/skycoin/src/util/droplet"
-	"github.com/skycoin/skycoin/src/visor"
 )
 
 func makeSuccessCoinSupplyResult(t *testing.T, allUnspents readable.UnspentOutputsSummary) *CoinSupply {
@@ -255,6 +255,7 @@ func TestGetRichlist(t *testing.T) {
 		err                      string
 		httpParams               *httpParams
 		includeDistribution      bool
+		topn                     int
 		gatewayGetRichlistResult visor.Richlist
 		gatewayGetRichlistErr    error
 		result                   Richlist
@@ -294,6 +295,7 @@ func TestGetRichlist(t *testing.T) {
 				topn:                "1",
 				includeDistribution: "false",
 			},
+			topn:                  1,
 			gatewayGetRichlistErr: errors.New("gatewayGetRichlistErr"),
 		},
 		{
@@ -304,6 +306,7 @@ func TestGetRichlist(t *testing.T) {
 				topn:                "3",
 				includeDistribution: "false",
 			},
+			topn: 3,
 			gatewayGetRichlistResult: visor.Richlist{
 				{
 					Address: cipher.MustDecodeBase58Address("2fGC7kwAM9yZyEF1QqBqp8uo9RUsF6ENGJF"),
@@ -422,7 +425,7 @@ func TestGetRichlist(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			endpoint := "/api/v1/richlist"
 			gateway := &MockGatewayer{}
-			gateway.On("GetRichlist", tc.includeDistribution).Return(tc.gatewayGetRichlistResult, tc.gatewayGetRichlistErr)
+			gateway.On("GetRichlist", tc.includeDistribution, tc.topn).Return(tc.gatewayGetRichlistResult, tc.gatewayGetRichlistErr)
 
 			v := url.Values{}
 			if tc.httpParams != nil {
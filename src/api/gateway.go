@@ -3,13 +3,16 @@ package api
 import (
 	"time"
 
+	"github.com/ness-network/ness/src/daemon"
+	"github.com/ness-network/ness/src/daemon/pex"
+	"github.com/ness-network/ness/src/psbt"
+	"github.com/ness-network/ness/src/visor"
+	"github.com/ness-network/ness/src/visor/blockdb"
+	"github.com/ness-network/ness/src/visor/historydb"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
-	"github.com/skycoin/skycoin/src/daemon"
 	"github.com/skycoin/skycoin/src/kvstorage"
 	"github.com/skycoin/skycoin/src/transaction"
-	"github.com/skycoin/skycoin/src/visor"
-	"github.com/skycoin/skycoin/src/visor/historydb"
 	"github.com/skycoin/skycoin/src/wallet"
 )
 
@@ -53,6 +56,10 @@ type Daemoner interface {
 	GetBlockchainProgress(headSeq uint64) *daemon.BlockchainProgress
 	InjectBroadcastTransaction(txn coin.Transaction) error
 	InjectTransaction(txn coin.Transaction) error
+	CreateBroadcastKeyCheckpoint(seq uint64, pubkey cipher.PubKey) (*blockdb.KeyCheckpoint, error)
+	GetBans() []pex.Ban
+	BanPeer(addr string, duration time.Duration, reason string) (pex.Ban, error)
+	UnbanPeer(addr string) error
 }
 
 // Visorer interface for visor.Visor methods used by the API
@@ -74,17 +81,24 @@ type Visorer interface {
 	GetLastBlocksVerbose(num uint64) ([]coin.SignedBlock, [][][]visor.TransactionInput, error)
 	GetUnspentOutputsSummary(filters []visor.OutputsFilter) (*visor.UnspentOutputsSummary, error)
 	GetBalanceOfAddresses(addrs []cipher.Address) ([]wallet.BalancePair, error)
+	WalkBalancesOfAddresses(addrs []cipher.Address, f func(headSeq uint64, b visor.AddressBalancePair) error) error
+	WalkUnspentOutputsOfAddresses(addrs []cipher.Address, f func(headSeq uint64, o visor.AddressUnspentOutputs) error) error
+	GetAddressBalanceHistory(q visor.AddressHistoryQuery) ([]visor.AddressBalanceSnapshot, error)
 	VerifyTxnVerbose(txn *coin.Transaction, signed transaction.TxnSignedFlag) ([]visor.TransactionInput, bool, error)
 	AddressCount() (uint64, error)
 	GetUxOutByID(id cipher.SHA256) (*historydb.UxOut, uint64, error)
 	GetSpentOutputsForAddresses(addr []cipher.Address) ([][]historydb.UxOut, uint64, error)
-	GetRichlist(includeDistribution bool) (visor.Richlist, error)
+	GetRichlist(includeDistribution bool, n int) (visor.Richlist, error)
 	GetAllUnconfirmedTransactions() ([]visor.UnconfirmedTransaction, error)
+	GetUnconfirmedMinFeePerKB() (uint64, error)
+	GetBlockTemplate() (*visor.BlockTemplate, error)
 	GetAllUnconfirmedTransactionsVerbose() ([]visor.UnconfirmedTransaction, [][]visor.TransactionInput, error)
 	GetTransaction(txid cipher.SHA256) (*visor.Transaction, error)
 	GetTransactionWithInputs(txid cipher.SHA256) (*visor.Transaction, []visor.TransactionInput, error)
 	GetTransactions(flts []visor.TxFilter, order visor.SortOrder, page *visor.PageIndex) ([]visor.Transaction, uint64, error)
 	GetTransactionsWithInputs(flts []visor.TxFilter, order visor.SortOrder, page *visor.PageIndex) ([]visor.Transaction, [][]visor.TransactionInput, uint64, error)
+	GetTransactionsByCursor(q visor.TransactionsCursorQuery) ([]visor.Transaction, *historydb.TxnCursor, error)
+	GetTransactionsByCursorWithInputs(q visor.TransactionsCursorQuery) ([]visor.Transaction, [][]visor.TransactionInput, *historydb.TxnCursor, error)
 	GetTransactionsNum() (uint64, error)
 	GetWalletUnconfirmedTransactions(wltID string) ([]visor.UnconfirmedTransaction, error)
 	GetWalletUnconfirmedTransactionsVerbose(wltID string) ([]visor.UnconfirmedTransaction, [][]visor.TransactionInput, error)
@@ -93,8 +107,17 @@ type Visorer interface {
 	WalletCreateTransaction(wltID string, p transaction.Params, wp visor.CreateTransactionParams) (*coin.Transaction, []visor.TransactionInput, error)
 	WalletCreateTransactionSigned(wltID string, password []byte, p transaction.Params, wp visor.CreateTransactionParams) (*coin.Transaction, []visor.TransactionInput, error)
 	WalletSignTransaction(wltID string, password []byte, txn *coin.Transaction, signIndexes []int) (*coin.Transaction, []visor.TransactionInput, error)
+	WalletBumpFee(wltID string, password []byte, txid cipher.SHA256, newFee uint64) (*coin.Transaction, []visor.TransactionInput, error)
+	WalletSignPacket(wltID string, password []byte, p *psbt.Packet) (*psbt.Packet, error)
+	GetWalletAccounts(wltID string) ([]visor.WalletAccount, error)
+	NewWalletAccount(wltID string, password []byte, name string) (uint32, error)
+	DiscoverWalletAddresses(wltID string, password []byte, gapLimit uint64) ([]cipher.Address, error)
+	GetWalletOutputs(wltID string) ([]visor.WalletOutput, error)
+	FreezeWalletOutputs(wltID string, hashes []cipher.SHA256, frozen bool) ([]visor.WalletOutput, error)
+	SetWalletOutputLabel(wltID string, hash cipher.SHA256, label string) (*visor.WalletOutput, error)
 	ScanWalletAddresses(wltID string, password []byte, num uint64) ([]cipher.Address, error)
 	TransactionsFinder() wallet.TransactionsFinder
+	SubscribeEvents() *visor.EventSubscription
 }
 
 // Walleter interface for wallet.Service methods used by the API
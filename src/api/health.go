@@ -5,9 +5,9 @@ import (
 	"net/http"
 	"time"
 
-	"github.com/skycoin/skycoin/src/daemon"
+	"github.com/ness-network/ness/src/daemon"
+	"github.com/ness-network/ness/src/readable"
 	"github.com/skycoin/skycoin/src/params"
-	"github.com/skycoin/skycoin/src/readable"
 	wh "github.com/skycoin/skycoin/src/util/http"
 )
 
@@ -19,24 +19,25 @@ type BlockchainMetadata struct {
 
 // HealthResponse is returned by the /health endpoint
 type HealthResponse struct {
-	BlockchainMetadata   BlockchainMetadata   `json:"blockchain"`
-	Version              readable.BuildInfo   `json:"version"`
-	CoinName             string               `json:"coin"`
-	DaemonUserAgent      string               `json:"user_agent"`
-	OpenConnections      int                  `json:"open_connections"`
-	OutgoingConnections  int                  `json:"outgoing_connections"`
-	IncomingConnections  int                  `json:"incoming_connections"`
-	Uptime               wh.Duration          `json:"uptime"`
-	CSRFEnabled          bool                 `json:"csrf_enabled"`
-	HeaderCheckEnabled   bool                 `json:"header_check_enabled"`
-	CSPEnabled           bool                 `json:"csp_enabled"`
-	WalletAPIEnabled     bool                 `json:"wallet_api_enabled"`
-	GUIEnabled           bool                 `json:"gui_enabled"`
-	BlockPublisher       bool                 `json:"block_publisher"`
-	UserVerifyTxn        readable.VerifyTxn   `json:"user_verify_transaction"`
-	UnconfirmedVerifyTxn readable.VerifyTxn   `json:"unconfirmed_verify_transaction"`
-	StartedAt            int64                `json:"started_at"`
-	Fiber                readable.FiberConfig `json:"fiber"`
+	BlockchainMetadata     BlockchainMetadata   `json:"blockchain"`
+	Version                readable.BuildInfo   `json:"version"`
+	CoinName               string               `json:"coin"`
+	DaemonUserAgent        string               `json:"user_agent"`
+	OpenConnections        int                  `json:"open_connections"`
+	OutgoingConnections    int                  `json:"outgoing_connections"`
+	IncomingConnections    int                  `json:"incoming_connections"`
+	Uptime                 wh.Duration          `json:"uptime"`
+	CSRFEnabled            bool                 `json:"csrf_enabled"`
+	HeaderCheckEnabled     bool                 `json:"header_check_enabled"`
+	CSPEnabled             bool                 `json:"csp_enabled"`
+	WalletAPIEnabled       bool                 `json:"wallet_api_enabled"`
+	GUIEnabled             bool                 `json:"gui_enabled"`
+	BlockPublisher         bool                 `json:"block_publisher"`
+	UserVerifyTxn          readable.VerifyTxn   `json:"user_verify_transaction"`
+	UnconfirmedVerifyTxn   readable.VerifyTxn   `json:"unconfirmed_verify_transaction"`
+	UnconfirmedMinFeePerKB uint64               `json:"unconfirmed_min_fee_per_kb"`
+	StartedAt              int64                `json:"started_at"`
+	Fiber                  readable.FiberConfig `json:"fiber"`
 }
 
 func getHealthData(c muxConfig, gateway Gatewayer) (*HealthResponse, error) {
@@ -62,6 +63,11 @@ func getHealthData(c muxConfig, gateway Gatewayer) (*HealthResponse, error) {
 		}
 	}
 
+	minFee, err := gateway.GetUnconfirmedMinFeePerKB()
+	if err != nil {
+		return nil, fmt.Errorf("gateway.GetUnconfirmedMinFeePerKB failed: %v", err)
+	}
+
 	elapsedBlockTime := time.Now().UTC().Unix() - int64(metadata.HeadBlock.Head.Time)
 	timeSinceLastBlock := time.Second * time.Duration(elapsedBlockTime)
 
@@ -77,23 +83,24 @@ func getHealthData(c muxConfig, gateway Gatewayer) (*HealthResponse, error) {
 			BlockchainMetadata: readable.NewBlockchainMetadata(*metadata),
 			TimeSinceLastBlock: wh.FromDuration(timeSinceLastBlock),
 		},
-		Version:              c.health.BuildInfo,
-		CoinName:             c.health.Fiber.Name,
-		Fiber:                c.health.Fiber,
-		DaemonUserAgent:      userAgent,
-		OpenConnections:      len(conns),
-		OutgoingConnections:  outgoingConns,
-		IncomingConnections:  incomingConns,
-		CSRFEnabled:          !c.disableCSRF,
-		HeaderCheckEnabled:   !c.disableHeaderCheck,
-		CSPEnabled:           !c.disableCSP,
-		GUIEnabled:           c.enableGUI,
-		BlockPublisher:       c.health.BlockPublisher,
-		WalletAPIEnabled:     walletAPIEnabled,
-		UserVerifyTxn:        readable.NewVerifyTxn(params.UserVerifyTxn),
-		UnconfirmedVerifyTxn: readable.NewVerifyTxn(gateway.DaemonConfig().UnconfirmedVerifyTxn),
-		Uptime:               wh.FromDuration(time.Since(gateway.StartedAt())),
-		StartedAt:            gateway.StartedAt().Unix(),
+		Version:                c.health.BuildInfo,
+		CoinName:               c.health.Fiber.Name,
+		Fiber:                  c.health.Fiber,
+		DaemonUserAgent:        userAgent,
+		OpenConnections:        len(conns),
+		OutgoingConnections:    outgoingConns,
+		IncomingConnections:    incomingConns,
+		CSRFEnabled:            !c.disableCSRF,
+		HeaderCheckEnabled:     !c.disableHeaderCheck,
+		CSPEnabled:             !c.disableCSP,
+		GUIEnabled:             c.enableGUI,
+		BlockPublisher:         c.health.BlockPublisher,
+		WalletAPIEnabled:       walletAPIEnabled,
+		UserVerifyTxn:          readable.NewVerifyTxn(params.UserVerifyTxn),
+		UnconfirmedVerifyTxn:   readable.NewVerifyTxn(gateway.DaemonConfig().UnconfirmedVerifyTxn),
+		UnconfirmedMinFeePerKB: minFee,
+		Uptime:                 wh.FromDuration(time.Since(gateway.StartedAt())),
+		StartedAt:              gateway.StartedAt().Unix(),
 	}, nil
 }
 
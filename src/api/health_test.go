@@ -13,13 +13,13 @@ import (
 	"encoding/json"
 	"net/http/httptest"
 
+	"github.com/ness-network/ness/src/daemon"
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/visor"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
-	"github.com/skycoin/skycoin/src/daemon"
 	"github.com/skycoin/skycoin/src/params"
-	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/util/useragent"
-	"github.com/skycoin/skycoin/src/visor"
 )
 
 func TestHealthHandler(t *testing.T) {
@@ -30,6 +30,7 @@ func TestHealthHandler(t *testing.T) {
 		err                      string
 		getBlockchainMetadataErr error
 		getConnectionsErr        error
+		getMinFeeErr             error
 		cfg                      muxConfig
 		walletAPIEnabled         bool
 	}{
@@ -59,6 +60,15 @@ func TestHealthHandler(t *testing.T) {
 			cfg:               defaultMuxConfig(),
 		},
 
+		{
+			name:         "gateway.GetUnconfirmedMinFeePerKB error",
+			method:       http.MethodGet,
+			code:         http.StatusInternalServerError,
+			err:          "500 Internal Server Error - gateway.GetUnconfirmedMinFeePerKB failed: GetUnconfirmedMinFeePerKB failed",
+			getMinFeeErr: errors.New("GetUnconfirmedMinFeePerKB failed"),
+			cfg:          defaultMuxConfig(),
+		},
+
 		{
 			name:             "valid response",
 			method:           http.MethodGet,
@@ -182,6 +192,13 @@ func TestHealthHandler(t *testing.T) {
 				gateway.On("GetConnections", mock.Anything).Return(conns, nil)
 			}
 
+			minFee := uint64(1234)
+			if tc.getMinFeeErr != nil {
+				gateway.On("GetUnconfirmedMinFeePerKB").Return(uint64(0), tc.getMinFeeErr)
+			} else {
+				gateway.On("GetUnconfirmedMinFeePerKB").Return(minFee, nil)
+			}
+
 			startedAt := time.Now().Add(time.Second * -4)
 
 			gateway.On("StartedAt").Return(startedAt)
@@ -253,6 +270,7 @@ func TestHealthHandler(t *testing.T) {
 			require.Equal(t, dc.UnconfirmedVerifyTxn.BurnFactor, r.UnconfirmedVerifyTxn.BurnFactor)
 			require.Equal(t, dc.UnconfirmedVerifyTxn.MaxTransactionSize, r.UnconfirmedVerifyTxn.MaxTransactionSize)
 			require.Equal(t, dc.UnconfirmedVerifyTxn.MaxDropletPrecision, r.UnconfirmedVerifyTxn.MaxDropletPrecision)
+			require.Equal(t, minFee, r.UnconfirmedMinFeePerKB)
 			require.True(t, time.Now().Unix() > r.StartedAt)
 
 		})
@@ -12,6 +12,7 @@ import (
 	"net"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 	"unicode"
@@ -19,13 +20,13 @@ import (
 	"github.com/rs/cors"
 	"github.com/skycoin/skycoin/src/util/gziphandler"
 
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/visor"
 	"github.com/skycoin/skycoin/src/cipher"
-	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/util/file"
 	wh "github.com/skycoin/skycoin/src/util/http"
 	"github.com/skycoin/skycoin/src/util/logging"
 	"github.com/skycoin/skycoin/src/util/useragent"
-	"github.com/skycoin/skycoin/src/visor"
 )
 
 var (
@@ -58,6 +59,8 @@ const (
 	EndpointsNetCtrl = "NET_CTRL"
 	// EndpointsStorage endpoints implement interface for key-value storage for arbitrary data
 	EndpointsStorage = "STORAGE"
+	// EndpointsPublisher endpoints for block publisher operators
+	EndpointsPublisher = "PUBLISHER"
 )
 
 // Server exposes an HTTP API
@@ -104,6 +107,7 @@ type muxConfig struct {
 	username           string
 	password           string
 	health             HealthConfig
+	writeTimeout       time.Duration
 }
 
 // HTTPResponse represents the http response struct
@@ -210,6 +214,7 @@ func create(host string, c Config, gateway Gatewayer) (*Server, error) {
 		hostWhitelist:      c.HostWhitelist,
 		username:           c.Username,
 		password:           c.Password,
+		writeTimeout:       c.WriteTimeout,
 	}
 
 	srvMux := newServerMux(mc, gateway)
@@ -394,8 +399,13 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 		})
 	}
 
-	webHandlerWithOptionals := func(apiVersion, endpoint string, handlerFunc http.Handler, checkCSRF, checkHeaders bool) {
-		handler := wh.ElapsedHandler(logger, handlerFunc)
+	// stream handlers are not wrapped by the elapsed time logger and the gzip handler,
+	// whose response writers can't flush the response while it is written
+	webHandlerWithOptionals := func(apiVersion, endpoint string, handlerFunc http.Handler, checkCSRF, checkHeaders, stream bool) {
+		handler := handlerFunc
+		if !stream {
+			handler = wh.ElapsedHandler(logger, handler)
+		}
 
 		handler = corsHandler.Handler(handler)
 
@@ -412,7 +422,9 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 		}
 
 		handler = basicAuth(apiVersion, c.username, c.password, "skycoin daemon", handler)
-		handler = gziphandler.New(handler)
+		if !stream {
+			handler = gziphandler.New(handler)
+		}
 		mux.Handle(endpoint, handler)
 	}
 
@@ -423,7 +435,7 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 			handler = forMethodAPISets(apiVersion, handler, methodAPISets)
 		}
 
-		webHandlerWithOptionals(apiVersion, endpoint, handler, true, !c.disableHeaderCheck)
+		webHandlerWithOptionals(apiVersion, endpoint, handler, true, !c.disableHeaderCheck, false)
 	}
 
 	webHandlerV1 := func(endpoint string, handler http.Handler, methodAPISets map[string][]string) {
@@ -463,7 +475,7 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 
 	// get the current CSRF token
 	csrfHandlerV1 := func(endpoint string, handler http.Handler) {
-		webHandlerWithOptionals(apiVersion1, "/api/v1"+endpoint, handler, false, !c.disableHeaderCheck)
+		webHandlerWithOptionals(apiVersion1, "/api/v1"+endpoint, handler, false, !c.disableHeaderCheck, false)
 	}
 	csrfHandlerV1("/csrf", getCSRFToken(c.disableCSRF)) // csrf is always available, regardless of the API set
 
@@ -498,6 +510,30 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 	webHandlerV2("/wallet/transaction/sign", walletSignTransactionHandler(gateway), map[string][]string{
 		http.MethodPost: {EndpointsWallet},
 	})
+	webHandlerV2("/wallet/transaction/bump_fee", walletBumpFeeHandler(gateway), map[string][]string{
+		http.MethodPost: {EndpointsWallet},
+	})
+	webHandlerV2("/wallet/psbt/sign", walletSignPacketHandler(gateway), map[string][]string{
+		http.MethodPost: {EndpointsWallet},
+	})
+	webHandlerV2("/wallet/accounts", walletAccountsHandler(gateway), map[string][]string{
+		http.MethodGet: {EndpointsWallet},
+	})
+	webHandlerV2("/wallet/account/create", walletAccountCreateHandler(gateway), map[string][]string{
+		http.MethodPost: {EndpointsWallet},
+	})
+	webHandlerV2("/wallet/outputs", walletOutputsHandler(gateway), map[string][]string{
+		http.MethodGet: {EndpointsWallet},
+	})
+	webHandlerV2("/wallet/outputs/freeze", walletOutputsFreezeHandler(gateway, true), map[string][]string{
+		http.MethodPost: {EndpointsWallet},
+	})
+	webHandlerV2("/wallet/outputs/unfreeze", walletOutputsFreezeHandler(gateway, false), map[string][]string{
+		http.MethodPost: {EndpointsWallet},
+	})
+	webHandlerV2("/wallet/outputs/label", walletOutputLabelHandler(gateway), map[string][]string{
+		http.MethodPost: {EndpointsWallet},
+	})
 	webHandlerV1("/wallet/transactions", walletTransactionsHandler(gateway), map[string][]string{
 		http.MethodGet: {EndpointsWallet},
 	})
@@ -551,6 +587,14 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 		http.MethodGet: {EndpointsRead},
 	})
 
+	// Block publisher admin endpoints
+	webHandlerV2("/block/template", blockTemplateHandler(gateway), map[string][]string{
+		http.MethodGet: {EndpointsPublisher},
+	})
+	webHandlerV2("/blockchain/key_checkpoint", keyCheckpointHandler(gateway), map[string][]string{
+		http.MethodPost: {EndpointsPublisher},
+	})
+
 	// Network stats endpoints
 	webHandlerV1("/network/connection", connectionHandler(gateway), map[string][]string{
 		http.MethodGet: {EndpointsRead, EndpointsStatus},
@@ -567,11 +611,20 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 	webHandlerV1("/network/connections/exchange", exchgConnectionsHandler(gateway), map[string][]string{
 		http.MethodGet: {EndpointsRead, EndpointsStatus},
 	})
+	webHandlerV1("/network/bans", bansHandler(gateway), map[string][]string{
+		http.MethodGet: {EndpointsRead, EndpointsStatus},
+	})
 
 	// Network admin endpoints
 	webHandlerV1("/network/connection/disconnect", disconnectHandler(gateway), map[string][]string{
 		http.MethodPost: {EndpointsNetCtrl},
 	})
+	webHandlerV1("/network/bans/add", banHandler(gateway), map[string][]string{
+		http.MethodPost: {EndpointsNetCtrl},
+	})
+	webHandlerV1("/network/bans/remove", unbanHandler(gateway), map[string][]string{
+		http.MethodPost: {EndpointsNetCtrl},
+	})
 
 	// Transaction related endpoints
 	webHandlerV1("/pendingTxs", pendingTxnsHandler(gateway), map[string][]string{
@@ -598,6 +651,12 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 	webHandlerV2("/transactions", transactionsHandlerV2(gateway), map[string][]string{
 		http.MethodGet: {EndpointsRead},
 	})
+
+	// Event stream endpoint, closed before the server write timeout
+	eventsMaxDuration := c.writeTimeout - c.writeTimeout/10
+	webHandlerWithOptionals(apiVersion2, "/api/v2/events", forMethodAPISets(apiVersion2, eventsHandler(gateway, eventsMaxDuration), map[string][]string{
+		http.MethodGet: {EndpointsRead},
+	}), true, !c.disableHeaderCheck, true)
 	webHandlerV1("/injectTransaction", injectTransactionHandler(gateway), map[string][]string{
 		http.MethodPost: {EndpointsTransaction, EndpointsWallet},
 	})
@@ -617,6 +676,12 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 		http.MethodGet:  {EndpointsRead},
 		http.MethodPost: {EndpointsRead},
 	})
+	webHandlerV2("/balance", balanceHandlerV2(gateway), map[string][]string{
+		http.MethodPost: {EndpointsRead},
+	})
+	webHandlerV2("/outputs", outputsHandlerV2(gateway), map[string][]string{
+		http.MethodPost: {EndpointsRead},
+	})
 	webHandlerV1("/uxout", uxOutHandler(gateway), map[string][]string{
 		http.MethodGet: {EndpointsRead},
 	})
@@ -628,6 +693,9 @@ func newServerMux(c muxConfig, gateway Gatewayer) *http.ServeMux {
 	webHandlerV2("/address/verify", http.HandlerFunc(addressVerifyHandler), map[string][]string{
 		http.MethodPost: {EndpointsRead},
 	})
+	webHandlerV2("/address/history", addressHistoryHandler(gateway), map[string][]string{
+		http.MethodGet: {EndpointsRead},
+	})
 
 	// Explorer endpoints
 	webHandlerV1("/coinSupply", coinSupplyHandler(gateway), map[string][]string{
@@ -741,3 +809,38 @@ func parseHashesFromStr(s string) ([]cipher.SHA256, error) {
 
 	return hashes, nil
 }
+
+// blockRange is the optional range of block seqs and block times of a request
+type blockRange struct {
+	fromSeq  *uint64
+	toSeq    *uint64
+	fromTime *uint64
+	toTime   *uint64
+}
+
+// parseBlockRange parses the optional from_seq, to_seq, from_time and to_time parameters
+func parseBlockRange(r *http.Request) (blockRange, error) {
+	var br blockRange
+	for _, p := range []struct {
+		name  string
+		value **uint64
+	}{
+		{"from_seq", &br.fromSeq},
+		{"to_seq", &br.toSeq},
+		{"from_time", &br.fromTime},
+		{"to_time", &br.toTime},
+	} {
+		str := r.FormValue(p.name)
+		if str == "" {
+			continue
+		}
+
+		v, err := strconv.ParseUint(str, 10, 64)
+		if err != nil {
+			return blockRange{}, fmt.Errorf("invalid '%s' value: %v", p.name, err)
+		}
+		*p.value = &v
+	}
+
+	return br, nil
+}
@@ -22,6 +22,7 @@ var allAPISetsEnabled = map[string]struct{}{
 	EndpointsInsecureWalletSeed: struct{}{},
 	EndpointsNetCtrl:            struct{}{},
 	EndpointsStorage:            struct{}{},
+	EndpointsPublisher:          struct{}{},
 }
 
 func defaultMuxConfig() muxConfig {
@@ -91,6 +92,15 @@ var endpointsMethods = map[string][]string{
 	"/api/v1/network/connection/disconnect": []string{
 		http.MethodPost,
 	},
+	"/api/v1/network/bans": []string{
+		http.MethodGet,
+	},
+	"/api/v1/network/bans/add": []string{
+		http.MethodPost,
+	},
+	"/api/v1/network/bans/remove": []string{
+		http.MethodPost,
+	},
 	"/api/v1/outputs": []string{
 		http.MethodGet,
 		http.MethodPost,
@@ -160,6 +170,15 @@ var endpointsMethods = map[string][]string{
 	"/api/v2/address/verify": []string{
 		http.MethodPost,
 	},
+	"/api/v2/address/history": []string{
+		http.MethodGet,
+	},
+	"/api/v2/balance": []string{
+		http.MethodPost,
+	},
+	"/api/v2/outputs": []string{
+		http.MethodPost,
+	},
 	"/api/v2/wallet/recover": []string{
 		http.MethodPost,
 	},
@@ -169,9 +188,36 @@ var endpointsMethods = map[string][]string{
 	"/api/v2/wallet/transaction/sign": []string{
 		http.MethodPost,
 	},
+	"/api/v2/wallet/transaction/bump_fee": []string{
+		http.MethodPost,
+	},
+	"/api/v2/wallet/psbt/sign": []string{
+		http.MethodPost,
+	},
+	"/api/v2/wallet/accounts": []string{
+		http.MethodGet,
+	},
+	"/api/v2/wallet/account/create": []string{
+		http.MethodPost,
+	},
+	"/api/v2/wallet/outputs": []string{
+		http.MethodGet,
+	},
+	"/api/v2/wallet/outputs/freeze": []string{
+		http.MethodPost,
+	},
+	"/api/v2/wallet/outputs/unfreeze": []string{
+		http.MethodPost,
+	},
+	"/api/v2/wallet/outputs/label": []string{
+		http.MethodPost,
+	},
 	"/api/v2/transaction": []string{
 		http.MethodPost,
 	},
+	"/api/v2/block/template": []string{
+		http.MethodGet,
+	},
 
 	"/api/v2/data": []string{
 		http.MethodGet,
@@ -7,9 +7,9 @@ import (
 	"net/url"
 	"strings"
 
+	"github.com/ness-network/ness/src/util/iputil"
 	"github.com/skycoin/skycoin/src/cipher"
 	wh "github.com/skycoin/skycoin/src/util/http"
-	"github.com/skycoin/skycoin/src/util/iputil"
 )
 
 // ContentSecurityPolicy represents the value of content-security-policy
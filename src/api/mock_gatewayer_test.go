@@ -6,19 +6,25 @@ import (
 	cipher "github.com/skycoin/skycoin/src/cipher"
 	coin "github.com/skycoin/skycoin/src/coin"
 
-	daemon "github.com/skycoin/skycoin/src/daemon"
+	daemon "github.com/ness-network/ness/src/daemon"
 
-	historydb "github.com/skycoin/skycoin/src/visor/historydb"
+	blockdb "github.com/ness-network/ness/src/visor/blockdb"
+
+	historydb "github.com/ness-network/ness/src/visor/historydb"
 
 	kvstorage "github.com/skycoin/skycoin/src/kvstorage"
 
 	mock "github.com/stretchr/testify/mock"
 
+	pex "github.com/ness-network/ness/src/daemon/pex"
+
+	psbt "github.com/ness-network/ness/src/psbt"
+
 	time "time"
 
 	transaction "github.com/skycoin/skycoin/src/transaction"
 
-	visor "github.com/skycoin/skycoin/src/visor"
+	visor "github.com/ness-network/ness/src/visor"
 
 	wallet "github.com/skycoin/skycoin/src/wallet"
 )
@@ -63,6 +69,50 @@ func (_m *MockGatewayer) AddressCount() (uint64, error) {
 	return r0, r1
 }
 
+// BanPeer provides a mock function with given fields: addr, duration, reason
+func (_m *MockGatewayer) BanPeer(addr string, duration time.Duration, reason string) (pex.Ban, error) {
+	ret := _m.Called(addr, duration, reason)
+
+	var r0 pex.Ban
+	if rf, ok := ret.Get(0).(func(string, time.Duration, string) pex.Ban); ok {
+		r0 = rf(addr, duration, reason)
+	} else {
+		r0 = ret.Get(0).(pex.Ban)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, time.Duration, string) error); ok {
+		r1 = rf(addr, duration, reason)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CreateBroadcastKeyCheckpoint provides a mock function with given fields: seq, pubkey
+func (_m *MockGatewayer) CreateBroadcastKeyCheckpoint(seq uint64, pubkey cipher.PubKey) (*blockdb.KeyCheckpoint, error) {
+	ret := _m.Called(seq, pubkey)
+
+	var r0 *blockdb.KeyCheckpoint
+	if rf, ok := ret.Get(0).(func(uint64, cipher.PubKey) *blockdb.KeyCheckpoint); ok {
+		r0 = rf(seq, pubkey)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*blockdb.KeyCheckpoint)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64, cipher.PubKey) error); ok {
+		r1 = rf(seq, pubkey)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // CreateTransaction provides a mock function with given fields: p, wp
 func (_m *MockGatewayer) CreateTransaction(p transaction.Params, wp visor.CreateTransactionParams) (*coin.Transaction, []visor.TransactionInput, error) {
 	ret := _m.Called(p, wp)
@@ -169,6 +219,29 @@ func (_m *MockGatewayer) DisconnectByGnetID(gnetID uint64) error {
 	return r0
 }
 
+// DiscoverWalletAddresses provides a mock function with given fields: wltID, password, gapLimit
+func (_m *MockGatewayer) DiscoverWalletAddresses(wltID string, password []byte, gapLimit uint64) ([]cipher.Address, error) {
+	ret := _m.Called(wltID, password, gapLimit)
+
+	var r0 []cipher.Address
+	if rf, ok := ret.Get(0).(func(string, []byte, uint64) []cipher.Address); ok {
+		r0 = rf(wltID, password, gapLimit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]cipher.Address)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, []byte, uint64) error); ok {
+		r1 = rf(wltID, password, gapLimit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // EncryptWallet provides a mock function with given fields: wltID, password
 func (_m *MockGatewayer) EncryptWallet(wltID string, password []byte) (wallet.Wallet, error) {
 	ret := _m.Called(wltID, password)
@@ -192,6 +265,52 @@ func (_m *MockGatewayer) EncryptWallet(wltID string, password []byte) (wallet.Wa
 	return r0, r1
 }
 
+// FreezeWalletOutputs provides a mock function with given fields: wltID, hashes, frozen
+func (_m *MockGatewayer) FreezeWalletOutputs(wltID string, hashes []cipher.SHA256, frozen bool) ([]visor.WalletOutput, error) {
+	ret := _m.Called(wltID, hashes, frozen)
+
+	var r0 []visor.WalletOutput
+	if rf, ok := ret.Get(0).(func(string, []cipher.SHA256, bool) []visor.WalletOutput); ok {
+		r0 = rf(wltID, hashes, frozen)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]visor.WalletOutput)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, []cipher.SHA256, bool) error); ok {
+		r1 = rf(wltID, hashes, frozen)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAddressBalanceHistory provides a mock function with given fields: q
+func (_m *MockGatewayer) GetAddressBalanceHistory(q visor.AddressHistoryQuery) ([]visor.AddressBalanceSnapshot, error) {
+	ret := _m.Called(q)
+
+	var r0 []visor.AddressBalanceSnapshot
+	if rf, ok := ret.Get(0).(func(visor.AddressHistoryQuery) []visor.AddressBalanceSnapshot); ok {
+		r0 = rf(q)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]visor.AddressBalanceSnapshot)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(visor.AddressHistoryQuery) error); ok {
+		r1 = rf(q)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetAllStorageValues provides a mock function with given fields: storageType
 func (_m *MockGatewayer) GetAllStorageValues(storageType kvstorage.Type) (map[string]string, error) {
 	ret := _m.Called(storageType)
@@ -293,6 +412,45 @@ func (_m *MockGatewayer) GetBalanceOfAddresses(addrs []cipher.Address) ([]wallet
 	return r0, r1
 }
 
+// GetBans provides a mock function with given fields:
+func (_m *MockGatewayer) GetBans() []pex.Ban {
+	ret := _m.Called()
+
+	var r0 []pex.Ban
+	if rf, ok := ret.Get(0).(func() []pex.Ban); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]pex.Ban)
+		}
+	}
+
+	return r0
+}
+
+// GetBlockTemplate provides a mock function with given fields:
+func (_m *MockGatewayer) GetBlockTemplate() (*visor.BlockTemplate, error) {
+	ret := _m.Called()
+
+	var r0 *visor.BlockTemplate
+	if rf, ok := ret.Get(0).(func() *visor.BlockTemplate); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*visor.BlockTemplate)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetBlockchainMetadata provides a mock function with given fields:
 func (_m *MockGatewayer) GetBlockchainMetadata() (*visor.BlockchainMetadata, error) {
 	ret := _m.Called()
@@ -575,13 +733,13 @@ func (_m *MockGatewayer) GetLastBlocksVerbose(num uint64) ([]coin.SignedBlock, [
 	return r0, r1, r2
 }
 
-// GetRichlist provides a mock function with given fields: includeDistribution
-func (_m *MockGatewayer) GetRichlist(includeDistribution bool) (visor.Richlist, error) {
-	ret := _m.Called(includeDistribution)
+// GetRichlist provides a mock function with given fields: includeDistribution, n
+func (_m *MockGatewayer) GetRichlist(includeDistribution bool, n int) (visor.Richlist, error) {
+	ret := _m.Called(includeDistribution, n)
 
 	var r0 visor.Richlist
-	if rf, ok := ret.Get(0).(func(bool) visor.Richlist); ok {
-		r0 = rf(includeDistribution)
+	if rf, ok := ret.Get(0).(func(bool, int) visor.Richlist); ok {
+		r0 = rf(includeDistribution, n)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).(visor.Richlist)
@@ -589,8 +747,8 @@ func (_m *MockGatewayer) GetRichlist(includeDistribution bool) (visor.Richlist,
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(bool) error); ok {
-		r1 = rf(includeDistribution)
+	if rf, ok := ret.Get(1).(func(bool, int) error); ok {
+		r1 = rf(includeDistribution, n)
 	} else {
 		r1 = ret.Error(1)
 	}
@@ -844,6 +1002,79 @@ func (_m *MockGatewayer) GetTransactions(flts []visor.TxFilter, order visor.Sort
 	return r0, r1, r2
 }
 
+// GetTransactionsByCursor provides a mock function with given fields: q
+func (_m *MockGatewayer) GetTransactionsByCursor(q visor.TransactionsCursorQuery) ([]visor.Transaction, *historydb.TxnCursor, error) {
+	ret := _m.Called(q)
+
+	var r0 []visor.Transaction
+	if rf, ok := ret.Get(0).(func(visor.TransactionsCursorQuery) []visor.Transaction); ok {
+		r0 = rf(q)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]visor.Transaction)
+		}
+	}
+
+	var r1 *historydb.TxnCursor
+	if rf, ok := ret.Get(1).(func(visor.TransactionsCursorQuery) *historydb.TxnCursor); ok {
+		r1 = rf(q)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(*historydb.TxnCursor)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(visor.TransactionsCursorQuery) error); ok {
+		r2 = rf(q)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
+// GetTransactionsByCursorWithInputs provides a mock function with given fields: q
+func (_m *MockGatewayer) GetTransactionsByCursorWithInputs(q visor.TransactionsCursorQuery) ([]visor.Transaction, [][]visor.TransactionInput, *historydb.TxnCursor, error) {
+	ret := _m.Called(q)
+
+	var r0 []visor.Transaction
+	if rf, ok := ret.Get(0).(func(visor.TransactionsCursorQuery) []visor.Transaction); ok {
+		r0 = rf(q)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]visor.Transaction)
+		}
+	}
+
+	var r1 [][]visor.TransactionInput
+	if rf, ok := ret.Get(1).(func(visor.TransactionsCursorQuery) [][]visor.TransactionInput); ok {
+		r1 = rf(q)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([][]visor.TransactionInput)
+		}
+	}
+
+	var r2 *historydb.TxnCursor
+	if rf, ok := ret.Get(2).(func(visor.TransactionsCursorQuery) *historydb.TxnCursor); ok {
+		r2 = rf(q)
+	} else {
+		if ret.Get(2) != nil {
+			r2 = ret.Get(2).(*historydb.TxnCursor)
+		}
+	}
+
+	var r3 error
+	if rf, ok := ret.Get(3).(func(visor.TransactionsCursorQuery) error); ok {
+		r3 = rf(q)
+	} else {
+		r3 = ret.Error(3)
+	}
+
+	return r0, r1, r2, r3
+}
+
 // GetTransactionsNum provides a mock function with given fields:
 func (_m *MockGatewayer) GetTransactionsNum() (uint64, error) {
 	ret := _m.Called()
@@ -920,6 +1151,27 @@ func (_m *MockGatewayer) GetTrustConnections() []string {
 	return r0
 }
 
+// GetUnconfirmedMinFeePerKB provides a mock function with given fields:
+func (_m *MockGatewayer) GetUnconfirmedMinFeePerKB() (uint64, error) {
+	ret := _m.Called()
+
+	var r0 uint64
+	if rf, ok := ret.Get(0).(func() uint64); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(uint64)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetUnspentOutputsSummary provides a mock function with given fields: filters
 func (_m *MockGatewayer) GetUnspentOutputsSummary(filters []visor.OutputsFilter) (*visor.UnspentOutputsSummary, error) {
 	ret := _m.Called(filters)
@@ -996,6 +1248,29 @@ func (_m *MockGatewayer) GetWallet(wltID string) (wallet.Wallet, error) {
 	return r0, r1
 }
 
+// GetWalletAccounts provides a mock function with given fields: wltID
+func (_m *MockGatewayer) GetWalletAccounts(wltID string) ([]visor.WalletAccount, error) {
+	ret := _m.Called(wltID)
+
+	var r0 []visor.WalletAccount
+	if rf, ok := ret.Get(0).(func(string) []visor.WalletAccount); ok {
+		r0 = rf(wltID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]visor.WalletAccount)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(wltID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetWalletBalance provides a mock function with given fields: wltID
 func (_m *MockGatewayer) GetWalletBalance(wltID string) (wallet.BalancePair, wallet.AddressBalances, error) {
 	ret := _m.Called(wltID)
@@ -1026,6 +1301,29 @@ func (_m *MockGatewayer) GetWalletBalance(wltID string) (wallet.BalancePair, wal
 	return r0, r1, r2
 }
 
+// GetWalletOutputs provides a mock function with given fields: wltID
+func (_m *MockGatewayer) GetWalletOutputs(wltID string) ([]visor.WalletOutput, error) {
+	ret := _m.Called(wltID)
+
+	var r0 []visor.WalletOutput
+	if rf, ok := ret.Get(0).(func(string) []visor.WalletOutput); ok {
+		r0 = rf(wltID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]visor.WalletOutput)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(wltID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetWalletSeed provides a mock function with given fields: wltID, password
 func (_m *MockGatewayer) GetWalletSeed(wltID string, password []byte) (string, string, error) {
 	ret := _m.Called(wltID, password)
@@ -1218,6 +1516,27 @@ func (_m *MockGatewayer) NewAddresses(wltID string, password []byte, options ...
 	return r0, r1
 }
 
+// NewWalletAccount provides a mock function with given fields: wltID, password, name
+func (_m *MockGatewayer) NewWalletAccount(wltID string, password []byte, name string) (uint32, error) {
+	ret := _m.Called(wltID, password, name)
+
+	var r0 uint32
+	if rf, ok := ret.Get(0).(func(string, []byte, string) uint32); ok {
+		r0 = rf(wltID, password, name)
+	} else {
+		r0 = ret.Get(0).(uint32)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, []byte, string) error); ok {
+		r1 = rf(wltID, password, name)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RecoverWallet provides a mock function with given fields: wltID, seed, seedPassphrase, password
 func (_m *MockGatewayer) RecoverWallet(wltID string, seed string, seedPassphrase string, password []byte) (wallet.Wallet, error) {
 	ret := _m.Called(wltID, seed, seedPassphrase, password)
@@ -1324,6 +1643,29 @@ func (_m *MockGatewayer) ScanWalletAddresses(wltID string, password []byte, num
 	return r0, r1
 }
 
+// SetWalletOutputLabel provides a mock function with given fields: wltID, hash, label
+func (_m *MockGatewayer) SetWalletOutputLabel(wltID string, hash cipher.SHA256, label string) (*visor.WalletOutput, error) {
+	ret := _m.Called(wltID, hash, label)
+
+	var r0 *visor.WalletOutput
+	if rf, ok := ret.Get(0).(func(string, cipher.SHA256, string) *visor.WalletOutput); ok {
+		r0 = rf(wltID, hash, label)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*visor.WalletOutput)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, cipher.SHA256, string) error); ok {
+		r1 = rf(wltID, hash, label)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // StartedAt provides a mock function with given fields:
 func (_m *MockGatewayer) StartedAt() time.Time {
 	ret := _m.Called()
@@ -1338,6 +1680,22 @@ func (_m *MockGatewayer) StartedAt() time.Time {
 	return r0
 }
 
+// SubscribeEvents provides a mock function with given fields:
+func (_m *MockGatewayer) SubscribeEvents() *visor.EventSubscription {
+	ret := _m.Called()
+
+	var r0 *visor.EventSubscription
+	if rf, ok := ret.Get(0).(func() *visor.EventSubscription); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*visor.EventSubscription)
+		}
+	}
+
+	return r0
+}
+
 // TransactionsFinder provides a mock function with given fields:
 func (_m *MockGatewayer) TransactionsFinder() wallet.TransactionsFinder {
 	ret := _m.Called()
@@ -1354,6 +1712,20 @@ func (_m *MockGatewayer) TransactionsFinder() wallet.TransactionsFinder {
 	return r0
 }
 
+// UnbanPeer provides a mock function with given fields: addr
+func (_m *MockGatewayer) UnbanPeer(addr string) error {
+	ret := _m.Called(addr)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(addr)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // UnloadWallet provides a mock function with given fields: wltID
 func (_m *MockGatewayer) UnloadWallet(wltID string) error {
 	ret := _m.Called(wltID)
@@ -1426,6 +1798,66 @@ func (_m *MockGatewayer) VisorConfig() visor.Config {
 	return r0
 }
 
+// WalkBalancesOfAddresses provides a mock function with given fields: addrs, f
+func (_m *MockGatewayer) WalkBalancesOfAddresses(addrs []cipher.Address, f func(uint64, visor.AddressBalancePair) error) error {
+	ret := _m.Called(addrs, f)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]cipher.Address, func(uint64, visor.AddressBalancePair) error) error); ok {
+		r0 = rf(addrs, f)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// WalkUnspentOutputsOfAddresses provides a mock function with given fields: addrs, f
+func (_m *MockGatewayer) WalkUnspentOutputsOfAddresses(addrs []cipher.Address, f func(uint64, visor.AddressUnspentOutputs) error) error {
+	ret := _m.Called(addrs, f)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func([]cipher.Address, func(uint64, visor.AddressUnspentOutputs) error) error); ok {
+		r0 = rf(addrs, f)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// WalletBumpFee provides a mock function with given fields: wltID, password, txid, newFee
+func (_m *MockGatewayer) WalletBumpFee(wltID string, password []byte, txid cipher.SHA256, newFee uint64) (*coin.Transaction, []visor.TransactionInput, error) {
+	ret := _m.Called(wltID, password, txid, newFee)
+
+	var r0 *coin.Transaction
+	if rf, ok := ret.Get(0).(func(string, []byte, cipher.SHA256, uint64) *coin.Transaction); ok {
+		r0 = rf(wltID, password, txid, newFee)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*coin.Transaction)
+		}
+	}
+
+	var r1 []visor.TransactionInput
+	if rf, ok := ret.Get(1).(func(string, []byte, cipher.SHA256, uint64) []visor.TransactionInput); ok {
+		r1 = rf(wltID, password, txid, newFee)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]visor.TransactionInput)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, []byte, cipher.SHA256, uint64) error); ok {
+		r2 = rf(wltID, password, txid, newFee)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // WalletCreateTransaction provides a mock function with given fields: wltID, p, wp
 func (_m *MockGatewayer) WalletCreateTransaction(wltID string, p transaction.Params, wp visor.CreateTransactionParams) (*coin.Transaction, []visor.TransactionInput, error) {
 	ret := _m.Called(wltID, p, wp)
@@ -1511,6 +1943,29 @@ func (_m *MockGatewayer) WalletDir() (string, error) {
 	return r0, r1
 }
 
+// WalletSignPacket provides a mock function with given fields: wltID, password, p
+func (_m *MockGatewayer) WalletSignPacket(wltID string, password []byte, p *psbt.Packet) (*psbt.Packet, error) {
+	ret := _m.Called(wltID, password, p)
+
+	var r0 *psbt.Packet
+	if rf, ok := ret.Get(0).(func(string, []byte, *psbt.Packet) *psbt.Packet); ok {
+		r0 = rf(wltID, password, p)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*psbt.Packet)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, []byte, *psbt.Packet) error); ok {
+		r1 = rf(wltID, password, p)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // WalletSignTransaction provides a mock function with given fields: wltID, password, txn, signIndexes
 func (_m *MockGatewayer) WalletSignTransaction(wltID string, password []byte, txn *coin.Transaction, signIndexes []int) (*coin.Transaction, []visor.TransactionInput, error) {
 	ret := _m.Called(wltID, password, txn, signIndexes)
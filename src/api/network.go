@@ -8,9 +8,11 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
-	"github.com/skycoin/skycoin/src/daemon"
-	"github.com/skycoin/skycoin/src/readable"
+	"github.com/ness-network/ness/src/daemon"
+	"github.com/ness-network/ness/src/daemon/pex"
+	"github.com/ness-network/ness/src/readable"
 	wh "github.com/skycoin/skycoin/src/util/http"
 )
 
@@ -226,3 +228,114 @@ func disconnectHandler(gateway Gatewayer) http.HandlerFunc {
 		wh.SendJSONOr500(logger, w, struct{}{})
 	}
 }
+
+// Bans wraps []readable.Ban
+type Bans struct {
+	Bans []readable.Ban `json:"bans"`
+}
+
+// bansHandler returns the banned hosts that have not expired
+// URI: /api/v1/network/bans
+// Method: GET
+func bansHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			wh.Error405(w)
+			return
+		}
+
+		bans := gateway.GetBans()
+		rbans := make([]readable.Ban, len(bans))
+		for i, b := range bans {
+			rbans[i] = readable.NewBan(b)
+		}
+
+		wh.SendJSONOr500(logger, w, Bans{
+			Bans: rbans,
+		})
+	}
+}
+
+// banHandler bans a host and disconnects its connections
+// URI: /api/v1/network/bans/add
+// Method: POST
+// Args:
+//	addr: IP:Port, IP address, onion address or IPv6 /64 prefix to ban. IPv6 addresses are banned by their /64 prefix
+//	duration: [optional] duration of the ban, e.g. "1h30m". Defaults to the node's ban duration
+//	reason: [optional] reason for the ban
+func banHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			wh.Error405(w)
+			return
+		}
+
+		addr := r.FormValue("addr")
+		if addr == "" {
+			wh.Error400(w, "addr is required")
+			return
+		}
+
+		duration := gateway.DaemonConfig().BanDuration
+		if formDuration := r.FormValue("duration"); formDuration != "" {
+			var err error
+			duration, err = time.ParseDuration(formDuration)
+			if err != nil {
+				wh.Error400(w, "invalid duration")
+				return
+			}
+		}
+
+		reason := r.FormValue("reason")
+		if reason == "" {
+			reason = "Banned by API"
+		}
+
+		b, err := gateway.BanPeer(addr, duration, reason)
+		if err != nil {
+			switch err {
+			case pex.ErrInvalidAddress, pex.ErrInvalidBanDuration:
+				wh.Error400(w, err.Error())
+			default:
+				wh.Error500(w, err.Error())
+			}
+			return
+		}
+
+		wh.SendJSONOr500(logger, w, readable.NewBan(b))
+	}
+}
+
+// unbanHandler removes the ban of a host
+// URI: /api/v1/network/bans/remove
+// Method: POST
+// Args:
+//	addr: IP:Port, IP address, onion address or IPv6 /64 prefix to unban
+func unbanHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			wh.Error405(w)
+			return
+		}
+
+		addr := r.FormValue("addr")
+		if addr == "" {
+			wh.Error400(w, "addr is required")
+			return
+		}
+
+		if err := gateway.UnbanPeer(addr); err != nil {
+			switch err {
+			case pex.ErrInvalidAddress:
+				wh.Error400(w, err.Error())
+			case pex.ErrNotBanned:
+				wh.Error404(w, "")
+			default:
+				wh.Error500(w, err.Error())
+			}
+			return
+		}
+
+		wh.SendJSONOr500(logger, w, struct{}{})
+	}
+}
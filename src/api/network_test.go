@@ -13,9 +13,10 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
-	"github.com/skycoin/skycoin/src/daemon"
-	"github.com/skycoin/skycoin/src/daemon/pex"
-	"github.com/skycoin/skycoin/src/readable"
+	"github.com/ness-network/ness/src/daemon"
+	"github.com/ness-network/ness/src/daemon/gnet"
+	"github.com/ness-network/ness/src/daemon/pex"
+	"github.com/ness-network/ness/src/readable"
 	"github.com/skycoin/skycoin/src/util/useragent"
 )
 
@@ -57,6 +58,20 @@ func TestConnection(t *testing.T) {
 					ID:           1,
 					LastSent:     time.Unix(99999, 0),
 					LastReceived: time.Unix(1111111, 0),
+					Stats: gnet.ConnectionStats{
+						BytesReceived: 4096,
+						BytesSent:     1024,
+						MessagesReceived: map[string]uint64{
+							"GETB": 12,
+							"ANNT": 3,
+						},
+						MessagesSent: map[string]uint64{
+							"GIVB": 10,
+						},
+					},
+				},
+				MessagesThrottled: map[string]uint64{
+					"GETB": 2,
 				},
 				ConnectionDetails: daemon.ConnectionDetails{
 					Outgoing:    true,
@@ -84,6 +99,18 @@ func TestConnection(t *testing.T) {
 				Height:        1234,
 				UserAgent:     useragent.MustParse("skycoin:0.25.1(foo)"),
 				IsTrustedPeer: false,
+				BytesReceived: 4096,
+				BytesSent:     1024,
+				MessagesReceived: map[string]uint64{
+					"GETB": 12,
+					"ANNT": 3,
+				},
+				MessagesSent: map[string]uint64{
+					"GIVB": 10,
+				},
+				MessagesThrottled: map[string]uint64{
+					"GETB": 2,
+				},
 			},
 		},
 
@@ -628,3 +655,316 @@ func TestDisconnect(t *testing.T) {
 		})
 	}
 }
+
+func TestBans(t *testing.T) {
+	bans := []pex.Ban{
+		{
+			Host:    "112.32.32.14",
+			Reason:  "Invalid block headers",
+			Created: 1500000000,
+			Expires: 1500086400,
+		},
+		{
+			Host:    "2001:db8::/64",
+			Reason:  "Banned by API",
+			Created: 1500000000,
+			Expires: 1500003600,
+		},
+	}
+
+	tt := []struct {
+		name   string
+		method string
+		status int
+		err    string
+		bans   []pex.Ban
+		result Bans
+	}{
+		{
+			name:   "405",
+			method: http.MethodPost,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "200 no bans",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			result: Bans{
+				Bans: []readable.Ban{},
+			},
+		},
+		{
+			name:   "200",
+			method: http.MethodGet,
+			status: http.StatusOK,
+			bans:   bans,
+			result: Bans{
+				Bans: []readable.Ban{
+					{
+						Host:    "112.32.32.14",
+						Reason:  "Invalid block headers",
+						Created: 1500000000,
+						Expires: 1500086400,
+					},
+					{
+						Host:    "2001:db8::/64",
+						Reason:  "Banned by API",
+						Created: 1500000000,
+						Expires: 1500003600,
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetBans").Return(tc.bans)
+
+			endpoint := "/api/v1/network/bans"
+
+			req, err := http.NewRequest(tc.method, endpoint, nil)
+			require.NoError(t, err)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()), "got `%v`| %d, want `%v`",
+					strings.TrimSpace(rr.Body.String()), status, tc.err)
+			} else {
+				var msg Bans
+				err = json.Unmarshal(rr.Body.Bytes(), &msg)
+				require.NoError(t, err)
+				require.Equal(t, tc.result, msg)
+			}
+		})
+	}
+}
+
+func TestBan(t *testing.T) {
+	ban := pex.Ban{
+		Host:    "112.32.32.14",
+		Reason:  "Banned by API",
+		Created: 1500000000,
+		Expires: 1500086400,
+	}
+
+	tt := []struct {
+		name     string
+		method   string
+		status   int
+		err      string
+		addr     string
+		duration string
+		reason   string
+		banArgs  []interface{}
+		banErr   error
+		result   readable.Ban
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "400 missing addr",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - addr is required",
+		},
+		{
+			name:     "400 invalid duration",
+			method:   http.MethodPost,
+			status:   http.StatusBadRequest,
+			err:      "400 Bad Request - invalid duration",
+			addr:     "112.32.32.14:6000",
+			duration: "1 day",
+		},
+		{
+			name:    "400 invalid address",
+			method:  http.MethodPost,
+			status:  http.StatusBadRequest,
+			err:     "400 Bad Request - Invalid address",
+			addr:    "example.com:6000",
+			banArgs: []interface{}{"example.com:6000", 24 * time.Hour, "Banned by API"},
+			banErr:  pex.ErrInvalidAddress,
+		},
+		{
+			name:     "400 invalid duration negative",
+			method:   http.MethodPost,
+			status:   http.StatusBadRequest,
+			err:      "400 Bad Request - Ban duration must be positive",
+			addr:     "112.32.32.14:6000",
+			duration: "-1h",
+			banArgs:  []interface{}{"112.32.32.14:6000", -time.Hour, "Banned by API"},
+			banErr:   pex.ErrInvalidBanDuration,
+		},
+		{
+			name:    "500 BanPeer error",
+			method:  http.MethodPost,
+			status:  http.StatusInternalServerError,
+			err:     "500 Internal Server Error - foo",
+			addr:    "112.32.32.14:6000",
+			banArgs: []interface{}{"112.32.32.14:6000", 24 * time.Hour, "Banned by API"},
+			banErr:  errors.New("foo"),
+		},
+		{
+			name:    "200 default duration",
+			method:  http.MethodPost,
+			status:  http.StatusOK,
+			addr:    "112.32.32.14:6000",
+			banArgs: []interface{}{"112.32.32.14:6000", 24 * time.Hour, "Banned by API"},
+			result:  readable.NewBan(ban),
+		},
+		{
+			name:     "200",
+			method:   http.MethodPost,
+			status:   http.StatusOK,
+			addr:     "112.32.32.14",
+			duration: "1h30m",
+			reason:   "spam",
+			banArgs:  []interface{}{"112.32.32.14", 90 * time.Minute, "spam"},
+			result:   readable.NewBan(ban),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("DaemonConfig").Return(daemon.DaemonConfig{
+				BanDuration: 24 * time.Hour,
+			})
+			if tc.banArgs != nil {
+				gateway.On("BanPeer", tc.banArgs...).Return(ban, tc.banErr)
+			}
+
+			endpoint := "/api/v1/network/bans/add"
+			v := url.Values{}
+			if tc.addr != "" {
+				v.Add("addr", tc.addr)
+			}
+			if tc.duration != "" {
+				v.Add("duration", tc.duration)
+			}
+			if tc.reason != "" {
+				v.Add("reason", tc.reason)
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, strings.NewReader(v.Encode()))
+			require.NoError(t, err)
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()), "got `%v`| %d, want `%v`",
+					strings.TrimSpace(rr.Body.String()), status, tc.err)
+			} else {
+				var msg readable.Ban
+				err = json.Unmarshal(rr.Body.Bytes(), &msg)
+				require.NoError(t, err)
+				require.Equal(t, tc.result, msg)
+			}
+		})
+	}
+}
+
+func TestUnban(t *testing.T) {
+	tt := []struct {
+		name     string
+		method   string
+		status   int
+		err      string
+		addr     string
+		unbanErr error
+	}{
+		{
+			name:   "405",
+			method: http.MethodGet,
+			status: http.StatusMethodNotAllowed,
+			err:    "405 Method Not Allowed",
+		},
+		{
+			name:   "400 missing addr",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - addr is required",
+		},
+		{
+			name:     "400 invalid address",
+			method:   http.MethodPost,
+			status:   http.StatusBadRequest,
+			err:      "400 Bad Request - Invalid address",
+			addr:     "example.com",
+			unbanErr: pex.ErrInvalidAddress,
+		},
+		{
+			name:     "404 not banned",
+			method:   http.MethodPost,
+			status:   http.StatusNotFound,
+			err:      "404 Not Found",
+			addr:     "112.32.32.14",
+			unbanErr: pex.ErrNotBanned,
+		},
+		{
+			name:     "500 UnbanPeer error",
+			method:   http.MethodPost,
+			status:   http.StatusInternalServerError,
+			err:      "500 Internal Server Error - foo",
+			addr:     "112.32.32.14",
+			unbanErr: errors.New("foo"),
+		},
+		{
+			name:   "200",
+			method: http.MethodPost,
+			status: http.StatusOK,
+			addr:   "112.32.32.14",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("UnbanPeer", tc.addr).Return(tc.unbanErr)
+
+			endpoint := "/api/v1/network/bans/remove"
+			v := url.Values{}
+			if tc.addr != "" {
+				v.Add("addr", tc.addr)
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, strings.NewReader(v.Encode()))
+			require.NoError(t, err)
+			req.Header.Add("Content-Type", "application/x-www-form-urlencoded")
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			if status != http.StatusOK {
+				require.Equal(t, tc.err, strings.TrimSpace(rr.Body.String()), "got `%v`| %d, want `%v`",
+					strings.TrimSpace(rr.Body.String()), status, tc.err)
+			} else {
+				var obj struct{}
+				err = json.Unmarshal(rr.Body.Bytes(), &obj)
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -9,6 +9,10 @@ import (
 
 	"github.com/shopspring/decimal"
 
+	"github.com/ness-network/ness/src/psbt"
+	nesstransaction "github.com/ness-network/ness/src/transaction"
+	"github.com/ness-network/ness/src/visor"
+	"github.com/ness-network/ness/src/visor/blockdb"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/params"
@@ -17,8 +21,6 @@ import (
 	"github.com/skycoin/skycoin/src/util/fee"
 	wh "github.com/skycoin/skycoin/src/util/http"
 	"github.com/skycoin/skycoin/src/util/mathutil"
-	"github.com/skycoin/skycoin/src/visor"
-	"github.com/skycoin/skycoin/src/visor/blockdb"
 	"github.com/skycoin/skycoin/src/wallet"
 )
 
@@ -268,6 +270,7 @@ type createTransactionRequest struct {
 	To                []receiver     `json:"to"`
 	UxOuts            []wh.SHA256    `json:"unspents,omitempty"`
 	Addresses         []wh.Address   `json:"addresses,omitempty"`
+	SpendStrategy     string         `json:"spend_strategy,omitempty"`
 }
 
 // hoursSelection defines options for hours distribution
@@ -340,6 +343,12 @@ func (r createTransactionRequest) Validate() error {
 		}
 	}
 
+	switch r.SpendStrategy {
+	case "", nesstransaction.SpendStrategyMinimizeUxOuts, nesstransaction.SpendStrategyPrivacy:
+	default:
+		return errors.New("invalid spend_strategy")
+	}
+
 	if len(r.UxOuts) != 0 && len(r.Addresses) != 0 {
 		return errors.New("unspents and addresses cannot be combined")
 	}
@@ -450,6 +459,7 @@ func (r createTransactionRequest) VisorParams() visor.CreateTransactionParams {
 		IgnoreUnconfirmed: r.IgnoreUnconfirmed,
 		Addresses:         r.addresses(),
 		UxOuts:            r.uxOuts(),
+		SpendStrategy:     r.SpendStrategy,
 	}
 }
 
@@ -542,9 +552,10 @@ func transactionHandlerV2(gateway Gatewayer) http.HandlerFunc {
 
 // walletCreateTransactionRequest is sent to POST /api/v1/wallet/transaction
 type walletCreateTransactionRequest struct {
-	Unsigned bool   `json:"unsigned"`
-	WalletID string `json:"wallet_id"`
-	Password string `json:"password"`
+	Unsigned bool    `json:"unsigned"`
+	WalletID string  `json:"wallet_id"`
+	Password string  `json:"password"`
+	Account  *uint32 `json:"account,omitempty"`
 	createTransactionRequest
 }
 
@@ -561,6 +572,14 @@ func (r walletCreateTransactionRequest) Validate() error {
 	return r.createTransactionRequest.Validate()
 }
 
+// VisorParams returns the visor.CreateTransactionParams of the request, spending from
+// the bip44 account of the request if set
+func (r walletCreateTransactionRequest) VisorParams() visor.CreateTransactionParams {
+	p := r.createTransactionRequest.VisorParams()
+	p.Account = r.Account
+	return p
+}
+
 // walletCreateTransactionHandler creates a transaction
 // Method: POST
 // URI: /api/v1/wallet/transaction
@@ -746,3 +765,185 @@ func walletSignTransactionHandler(gateway Gatewayer) http.HandlerFunc {
 		})
 	}
 }
+
+// WalletSignPacketRequest is the request body object for /api/v2/wallet/psbt/sign
+type WalletSignPacketRequest struct {
+	WalletID string       `json:"wallet_id"`
+	Password string       `json:"password"`
+	Packet   *psbt.Packet `json:"packet"`
+}
+
+// WalletSignPacketResponse is returned by /api/v2/wallet/psbt/sign
+type WalletSignPacketResponse struct {
+	Packet      *psbt.Packet `json:"packet"`
+	FullySigned bool         `json:"fully_signed"`
+}
+
+// walletSignPacketHandler signs the inputs of a partially signed transaction that belong to a wallet.
+// The packet holds the outputs spent by the transaction, so this does not depend on the blockchain
+// and can be used on a node that is not connected to the network.
+// Method: POST
+// URI: /api/v2/wallet/psbt/sign
+// Args: JSON body
+func walletSignPacketHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req WalletSignPacketRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.WalletID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "wallet_id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.Packet == nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "packet is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if err := req.Packet.Validate(); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("Invalid packet: %v", err))
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		pkt, err := gateway.WalletSignPacket(req.WalletID, []byte(req.Password), req.Packet)
+		if err != nil {
+			var resp HTTPResponse
+			switch err.(type) {
+			case wallet.Error:
+				switch err {
+				case wallet.ErrWalletNotExist:
+					resp = NewHTTPErrorResponse(http.StatusNotFound, err.Error())
+				case wallet.ErrWalletAPIDisabled:
+					resp = NewHTTPErrorResponse(http.StatusForbidden, err.Error())
+				default:
+					resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				}
+			case visor.UserError:
+				resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			default:
+				resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			}
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: WalletSignPacketResponse{
+				Packet:      pkt,
+				FullySigned: pkt.IsFullySigned(),
+			},
+		})
+	}
+}
+
+// WalletBumpFeeRequest is the request body object for /api/v2/wallet/transaction/bump_fee
+type WalletBumpFeeRequest struct {
+	WalletID string `json:"wallet_id"`
+	Password string `json:"password"`
+	TxID     string `json:"txid"`
+	Fee      string `json:"fee"`
+}
+
+// walletBumpFeeHandler creates a signed transaction that replaces an unconfirmed transaction
+// of the wallet and burns more coin hours
+// Method: POST
+// URI: /api/v2/wallet/transaction/bump_fee
+// Args: JSON body
+func walletBumpFeeHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req WalletBumpFeeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.WalletID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "wallet_id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.TxID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "txid is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		txid, err := cipher.SHA256FromHex(req.TxID)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("Invalid txid: %v", err))
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.Fee == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "fee is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		newFee, err := strconv.ParseUint(req.Fee, 10, 64)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "Invalid fee")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		txn, inputs, err := gateway.WalletBumpFee(req.WalletID, []byte(req.Password), txid, newFee)
+		if err != nil {
+			var resp HTTPResponse
+			switch err.(type) {
+			case wallet.Error:
+				switch err {
+				case wallet.ErrWalletNotExist:
+					resp = NewHTTPErrorResponse(http.StatusNotFound, err.Error())
+				case wallet.ErrWalletAPIDisabled:
+					resp = NewHTTPErrorResponse(http.StatusForbidden, err.Error())
+				default:
+					resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+				}
+			case transaction.ErrTxnViolatesSoftConstraint,
+				transaction.ErrTxnViolatesHardConstraint,
+				transaction.ErrTxnViolatesUserConstraint,
+				blockdb.ErrUnspentNotExist,
+				visor.UserError:
+				resp = NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			default:
+				resp = NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			}
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		txnResp, err := NewCreateTransactionResponse(txn, inputs)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: txnResp,
+		})
+	}
+}
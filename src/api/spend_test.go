@@ -13,13 +13,14 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/ness-network/ness/src/psbt"
+	"github.com/ness-network/ness/src/visor"
+	"github.com/ness-network/ness/src/visor/blockdb"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/testutil"
 	"github.com/skycoin/skycoin/src/transaction"
 	"github.com/skycoin/skycoin/src/util/fee"
-	"github.com/skycoin/skycoin/src/visor"
-	"github.com/skycoin/skycoin/src/visor/blockdb"
 	"github.com/skycoin/skycoin/src/wallet"
 )
 
@@ -42,6 +43,7 @@ type rawCreateTxnRequest struct {
 	ChangeAddress  string            `json:"change_address,omitempty"`
 	To             []rawReceiver     `json:"to"`
 	Password       string            `json:"password"`
+	SpendStrategy  string            `json:"spend_strategy,omitempty"`
 }
 
 func TestCreateTransaction(t *testing.T) {
@@ -1566,6 +1568,55 @@ func TestWalletCreateTransaction(t *testing.T) {
 			err:    "400 Bad Request - addresses contains duplicate values",
 		},
 
+		{
+			name:   "400 - invalid spend strategy",
+			method: http.MethodPost,
+			body: rawWalletCreateTxnRequest{
+				rawCreateTxnRequest: rawCreateTxnRequest{
+					HoursSelection: rawHoursSelection{
+						Type: transaction.HoursSelectionTypeManual,
+					},
+					To: []rawReceiver{
+						{
+							Address: destinationAddress.String(),
+							Coins:   "100",
+							Hours:   "10",
+						},
+					},
+					SpendStrategy: "foo",
+				},
+				WalletID: "foo.wlt",
+			},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - invalid spend_strategy",
+		},
+
+		{
+			name:   "200 - privacy spend strategy",
+			method: http.MethodPost,
+			body: rawWalletCreateTxnRequest{
+				rawCreateTxnRequest: rawCreateTxnRequest{
+					HoursSelection: rawHoursSelection{
+						Type: transaction.HoursSelectionTypeManual,
+					},
+					To: []rawReceiver{
+						{
+							Address: destinationAddress.String(),
+							Coins:   "100",
+							Hours:   "10",
+						},
+					},
+					ChangeAddress: changeAddress.String(),
+					SpendStrategy: "privacy",
+				},
+				WalletID: "foo.wlt",
+			},
+			status:                         http.StatusOK,
+			gatewayCreateTransactionResult: txn,
+			gatewayCreateTransactionInputs: inputs,
+			createTransactionResponse:      createTxnResponse,
+		},
+
 		{
 			name:   "200 - auto type split even",
 			method: http.MethodPost,
@@ -1769,6 +1820,8 @@ func TestWalletCreateTransaction(t *testing.T) {
 			var body walletCreateTransactionRequest
 			err = json.Unmarshal(serializedBody, &body)
 			if err == nil {
+				require.Equal(t, tc.body.SpendStrategy, body.VisorParams().SpendStrategy)
+
 				if tc.body.Unsigned {
 					x := gateway.On("WalletCreateTransaction", body.WalletID, body.TransactionParams(), body.VisorParams())
 					x.Return(tc.gatewayCreateTransactionResult, tc.gatewayCreateTransactionInputs, tc.gatewayCreateTransactionErr)
@@ -2191,3 +2244,511 @@ func TestWalletSignTransaction(t *testing.T) {
 		})
 	}
 }
+
+func TestWalletBumpFee(t *testing.T) {
+	txid := testutil.RandSHA256(t)
+
+	signedTxn := coin.Transaction{
+		Length:    100,
+		Type:      0,
+		InnerHash: testutil.RandSHA256(t),
+		Sigs:      []cipher.Sig{testutil.RandSig(t)},
+		In:        []cipher.SHA256{testutil.RandSHA256(t)},
+		Out: []coin.TransactionOutput{
+			{
+				Address: testutil.MakeAddress(),
+				Coins:   1e6,
+				Hours:   50,
+			},
+		},
+	}
+
+	inputs := []visor.TransactionInput{
+		{
+			UxOut: coin.UxOut{
+				Head: coin.UxHead{
+					Time:  uint64(time.Now().UTC().Unix()),
+					BkSeq: 9999,
+				},
+				Body: coin.UxBody{
+					SrcTransaction: testutil.RandSHA256(t),
+					Address:        testutil.MakeAddress(),
+					Coins:          1e6,
+					Hours:          100,
+				},
+			},
+			CalculatedHours: 200,
+		},
+	}
+
+	signedTxnResp, err := NewCreateTransactionResponse(&signedTxn, inputs)
+	require.NoError(t, err)
+
+	validBody := &WalletBumpFeeRequest{
+		WalletID: "foo.wlt",
+		TxID:     txid.Hex(),
+		Fee:      "150",
+	}
+
+	tt := []struct {
+		name                 string
+		method               string
+		body                 *WalletBumpFeeRequest
+		rawBody              string
+		status               int
+		gatewayBumpFeeResult *coin.Transaction
+		gatewayBumpFeeInputs []visor.TransactionInput
+		gatewayBumpFeeErr    error
+		contentType          string
+		httpResponse         HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodGet,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+
+		{
+			name:         "415",
+			method:       http.MethodPost,
+			status:       http.StatusUnsupportedMediaType,
+			contentType:  ContentTypeForm,
+			httpResponse: NewHTTPErrorResponse(http.StatusUnsupportedMediaType, ""),
+		},
+
+		{
+			name:         "400 - invalid json",
+			method:       http.MethodPost,
+			rawBody:      "{ca",
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "invalid character 'c' looking for beginning of object key string"),
+		},
+
+		{
+			name:   "400 wallet ID required",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+			body: &WalletBumpFeeRequest{
+				TxID: validBody.TxID,
+				Fee:  validBody.Fee,
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "wallet_id is required"),
+		},
+
+		{
+			name:   "400 txid required",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+			body: &WalletBumpFeeRequest{
+				WalletID: "foo.wlt",
+				Fee:      validBody.Fee,
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "txid is required"),
+		},
+
+		{
+			name:   "400 invalid txid",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+			body: &WalletBumpFeeRequest{
+				WalletID: "foo.wlt",
+				TxID:     "abc",
+				Fee:      validBody.Fee,
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "Invalid txid: encoding/hex: odd length hex string"),
+		},
+
+		{
+			name:   "400 fee required",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+			body: &WalletBumpFeeRequest{
+				WalletID: "foo.wlt",
+				TxID:     validBody.TxID,
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "fee is required"),
+		},
+
+		{
+			name:   "400 invalid fee",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+			body: &WalletBumpFeeRequest{
+				WalletID: "foo.wlt",
+				TxID:     validBody.TxID,
+				Fee:      "1.5",
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "Invalid fee"),
+		},
+
+		{
+			name:              "500 - misc error",
+			method:            http.MethodPost,
+			body:              validBody,
+			status:            http.StatusInternalServerError,
+			gatewayBumpFeeErr: errors.New("unhandled error"),
+			httpResponse:      NewHTTPErrorResponse(http.StatusInternalServerError, "unhandled error"),
+		},
+
+		{
+			name:              "400 - txn not unconfirmed",
+			method:            http.MethodPost,
+			body:              validBody,
+			status:            http.StatusBadRequest,
+			gatewayBumpFeeErr: visor.ErrTxnNotUnconfirmed,
+			httpResponse:      NewHTTPErrorResponse(http.StatusBadRequest, "Transaction is not in the unconfirmed pool"),
+		},
+
+		{
+			name:              "400 - fee too low",
+			method:            http.MethodPost,
+			body:              validBody,
+			status:            http.StatusBadRequest,
+			gatewayBumpFeeErr: visor.ErrBumpFeeTooLow,
+			httpResponse:      NewHTTPErrorResponse(http.StatusBadRequest, "Fee must be higher than the fee of the transaction"),
+		},
+
+		{
+			name:              "400 - wallet encrypted",
+			method:            http.MethodPost,
+			body:              validBody,
+			status:            http.StatusBadRequest,
+			gatewayBumpFeeErr: wallet.ErrWalletEncrypted,
+			httpResponse:      NewHTTPErrorResponse(http.StatusBadRequest, "wallet is encrypted"),
+		},
+
+		{
+			name:              "400 - violates soft constraint",
+			method:            http.MethodPost,
+			body:              validBody,
+			status:            http.StatusBadRequest,
+			gatewayBumpFeeErr: transaction.NewErrTxnViolatesSoftConstraint(errors.New("bad txn")),
+			httpResponse:      NewHTTPErrorResponse(http.StatusBadRequest, "Transaction violates soft constraint: bad txn"),
+		},
+
+		{
+			name:              "404 - wallet not found",
+			method:            http.MethodPost,
+			body:              validBody,
+			status:            http.StatusNotFound,
+			gatewayBumpFeeErr: wallet.ErrWalletNotExist,
+			httpResponse:      NewHTTPErrorResponse(http.StatusNotFound, "wallet doesn't exist"),
+		},
+
+		{
+			name:              "403 - wallet API disabled",
+			method:            http.MethodPost,
+			body:              validBody,
+			status:            http.StatusForbidden,
+			gatewayBumpFeeErr: wallet.ErrWalletAPIDisabled,
+			httpResponse:      NewHTTPErrorResponse(http.StatusForbidden, "wallet api is disabled"),
+		},
+
+		{
+			name:                 "200",
+			method:               http.MethodPost,
+			body:                 validBody,
+			status:               http.StatusOK,
+			gatewayBumpFeeResult: &signedTxn,
+			gatewayBumpFeeInputs: inputs,
+			httpResponse: HTTPResponse{
+				Data: *signedTxnResp,
+			},
+		},
+
+		{
+			name:   "200 - password",
+			method: http.MethodPost,
+			body: &WalletBumpFeeRequest{
+				WalletID: "foo.wlt",
+				Password: "foo",
+				TxID:     validBody.TxID,
+				Fee:      validBody.Fee,
+			},
+			status:               http.StatusOK,
+			gatewayBumpFeeResult: &signedTxn,
+			gatewayBumpFeeInputs: inputs,
+			httpResponse: HTTPResponse{
+				Data: *signedTxnResp,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+
+			if tc.body != nil {
+				gateway.On("WalletBumpFee", tc.body.WalletID, []byte(tc.body.Password), txid, uint64(150)).Return(tc.gatewayBumpFeeResult, tc.gatewayBumpFeeInputs, tc.gatewayBumpFeeErr)
+			}
+
+			endpoint := "/api/v2/wallet/transaction/bump_fee"
+
+			bodyText := []byte(tc.rawBody)
+			if len(bodyText) == 0 {
+				var err error
+				bodyText, err = json.Marshal(tc.body)
+				require.NoError(t, err)
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, bytes.NewBuffer(bodyText))
+			require.NoError(t, err)
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+
+			req.Header.Add("Content-Type", contentType)
+
+			setCSRFParameters(t, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.Unmarshal(rr.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data == nil {
+				require.Nil(t, tc.httpResponse.Data)
+			} else {
+				require.NotNil(t, tc.httpResponse.Data)
+
+				var cRsp CreateTransactionResponse
+				err := json.Unmarshal(rsp.Data, &cRsp)
+				require.NoError(t, err)
+
+				require.Equal(t, tc.httpResponse.Data.(CreateTransactionResponse), cRsp)
+			}
+		})
+	}
+}
+
+func TestWalletSignPacket(t *testing.T) {
+	p, s := cipher.GenerateKeyPair()
+	ux := coin.UxOut{
+		Head: coin.UxHead{
+			Time:  uint64(time.Now().UTC().Unix()),
+			BkSeq: 9999,
+		},
+		Body: coin.UxBody{
+			SrcTransaction: testutil.RandSHA256(t),
+			Address:        cipher.AddressFromPubKey(p),
+			Coins:          1e6,
+			Hours:          100,
+		},
+	}
+
+	var txn coin.Transaction
+	require.NoError(t, txn.PushInput(ux.Hash()))
+	require.NoError(t, txn.PushOutput(testutil.MakeAddress(), 1e6, 50))
+	require.NoError(t, txn.UpdateHeader())
+
+	pkt, err := psbt.New(txn, []coin.UxOut{ux})
+	require.NoError(t, err)
+
+	signedPkt := pkt.Clone()
+	require.NoError(t, signedPkt.Sign(0, s))
+	signedPkt.Inputs[0].WalletHint = "foo.wlt"
+
+	invalidPkt := pkt.Clone()
+	invalidPkt.Inputs[0].UxOut.Body.Coins++
+
+	validBody := &WalletSignPacketRequest{
+		WalletID: "foo.wlt",
+		Packet:   pkt,
+	}
+
+	tt := []struct {
+		name                    string
+		method                  string
+		body                    *WalletSignPacketRequest
+		rawBody                 string
+		status                  int
+		gatewaySignPacketResult *psbt.Packet
+		gatewaySignPacketErr    error
+		contentType             string
+		httpResponse            HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodGet,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+
+		{
+			name:         "415",
+			method:       http.MethodPost,
+			status:       http.StatusUnsupportedMediaType,
+			contentType:  ContentTypeForm,
+			httpResponse: NewHTTPErrorResponse(http.StatusUnsupportedMediaType, ""),
+		},
+
+		{
+			name:         "400 - invalid json",
+			method:       http.MethodPost,
+			rawBody:      "{ca",
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "invalid character 'c' looking for beginning of object key string"),
+		},
+
+		{
+			name:   "400 wallet ID required",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+			body: &WalletSignPacketRequest{
+				Packet: pkt,
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "wallet_id is required"),
+		},
+
+		{
+			name:   "400 packet required",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+			body: &WalletSignPacketRequest{
+				WalletID: "foo.wlt",
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "packet is required"),
+		},
+
+		{
+			name:   "400 invalid packet",
+			method: http.MethodPost,
+			status: http.StatusBadRequest,
+			body: &WalletSignPacketRequest{
+				WalletID: "foo.wlt",
+				Packet:   invalidPkt,
+			},
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "Invalid packet: psbt: input 0 does not match the output it spends"),
+		},
+
+		{
+			name:                 "500 - misc error",
+			method:               http.MethodPost,
+			body:                 validBody,
+			status:               http.StatusInternalServerError,
+			gatewaySignPacketErr: errors.New("unhandled error"),
+			httpResponse:         NewHTTPErrorResponse(http.StatusInternalServerError, "unhandled error"),
+		},
+
+		{
+			name:                 "400 - inputs not in wallet",
+			method:               http.MethodPost,
+			body:                 validBody,
+			status:               http.StatusBadRequest,
+			gatewaySignPacketErr: visor.ErrPacketInputsNotInWallet,
+			httpResponse:         NewHTTPErrorResponse(http.StatusBadRequest, "No unsigned input of the packet belongs to the wallet"),
+		},
+
+		{
+			name:                 "400 - wallet can't sign",
+			method:               http.MethodPost,
+			body:                 validBody,
+			status:               http.StatusBadRequest,
+			gatewaySignPacketErr: wallet.ErrWalletCantSign,
+			httpResponse:         NewHTTPErrorResponse(http.StatusBadRequest, wallet.ErrWalletCantSign.Error()),
+		},
+
+		{
+			name:                 "404 - wallet not found",
+			method:               http.MethodPost,
+			body:                 validBody,
+			status:               http.StatusNotFound,
+			gatewaySignPacketErr: wallet.ErrWalletNotExist,
+			httpResponse:         NewHTTPErrorResponse(http.StatusNotFound, "wallet doesn't exist"),
+		},
+
+		{
+			name:                 "403 - wallet API disabled",
+			method:               http.MethodPost,
+			body:                 validBody,
+			status:               http.StatusForbidden,
+			gatewaySignPacketErr: wallet.ErrWalletAPIDisabled,
+			httpResponse:         NewHTTPErrorResponse(http.StatusForbidden, "wallet api is disabled"),
+		},
+
+		{
+			name:   "200",
+			method: http.MethodPost,
+			body: &WalletSignPacketRequest{
+				WalletID: "foo.wlt",
+				Password: "foo",
+				Packet:   pkt,
+			},
+			status:                  http.StatusOK,
+			gatewaySignPacketResult: signedPkt,
+			httpResponse: HTTPResponse{
+				Data: WalletSignPacketResponse{
+					Packet:      signedPkt,
+					FullySigned: true,
+				},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+
+			if tc.body != nil {
+				gateway.On("WalletSignPacket", tc.body.WalletID, []byte(tc.body.Password), tc.body.Packet).Return(tc.gatewaySignPacketResult, tc.gatewaySignPacketErr)
+			}
+
+			endpoint := "/api/v2/wallet/psbt/sign"
+
+			bodyText := []byte(tc.rawBody)
+			if len(bodyText) == 0 {
+				var err error
+				bodyText, err = json.Marshal(tc.body)
+				require.NoError(t, err)
+			}
+
+			req, err := http.NewRequest(tc.method, endpoint, bytes.NewBuffer(bodyText))
+			require.NoError(t, err)
+
+			contentType := tc.contentType
+			if contentType == "" {
+				contentType = ContentTypeJSON
+			}
+
+			req.Header.Add("Content-Type", contentType)
+
+			setCSRFParameters(t, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			status := rr.Code
+			require.Equal(t, tc.status, status, "got `%v` want `%v`", status, tc.status)
+
+			var rsp ReceivedHTTPResponse
+			err = json.Unmarshal(rr.Body.Bytes(), &rsp)
+			require.NoError(t, err)
+
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data == nil {
+				require.Nil(t, tc.httpResponse.Data)
+			} else {
+				require.NotNil(t, tc.httpResponse.Data)
+
+				var sRsp WalletSignPacketResponse
+				err := json.Unmarshal(rsp.Data, &sRsp)
+				require.NoError(t, err)
+
+				require.Equal(t, tc.httpResponse.Data.(WalletSignPacketResponse), sRsp)
+			}
+		})
+	}
+}
@@ -10,14 +10,15 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/ness-network/ness/src/daemon"
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/visor"
+	"github.com/ness-network/ness/src/visor/historydb"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
-	"github.com/skycoin/skycoin/src/daemon"
-	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/transaction"
 	wh "github.com/skycoin/skycoin/src/util/http"
 	"github.com/skycoin/skycoin/src/util/mathutil"
-	"github.com/skycoin/skycoin/src/visor"
 )
 
 // pendingTxnsHandler returns pending (unconfirmed) transactions
@@ -364,6 +365,11 @@ func transactionsNumHandler(gateway Gatewayer) http.Handler {
 //     limit: the number of transactions per page [optional, default to 10, must be <= 100]
 //     sort: Sort the transactions by block seq. [optional, must be desc or asc]; if not provided, return
 //     in asc order.
+//     cursor: Walk the confirmed transactions after this cursor, empty for the first page [optional, can't be used with page]
+//     from_seq, to_seq: Inclusive range of block seqs of the transactions [optional]
+//     from_time, to_time: Inclusive range of block times of the transactions [optional]
+// If cursor or any of the ranges is provided, the confirmed transactions are paged through with cursors,
+// which are returned as next_cursor with each page.
 func transactionsHandlerV2(gateway Gatewayer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -419,6 +425,11 @@ func transactionsHandlerV2(gateway Gatewayer) http.HandlerFunc {
 			}
 		}
 
+		if isTxnsCursorRequest(r) {
+			transactionsCursorV2(w, r, gateway, addrs, order, pageSize, verbose)
+			return
+		}
+
 		var pageIndex *visor.PageIndex
 		var currentPage = uint64(1)
 		pageStr := r.FormValue("page")
@@ -491,6 +502,121 @@ func transactionsHandlerV2(gateway Gatewayer) http.HandlerFunc {
 	}
 }
 
+// txnsCursorParams are the /api/v2/transactions parameters that select cursor pagination
+var txnsCursorParams = []string{"cursor", "from_seq", "to_seq", "from_time", "to_time"}
+
+// isTxnsCursorRequest returns true if a /api/v2/transactions request pages through transactions with cursors
+func isTxnsCursorRequest(r *http.Request) bool {
+	for _, k := range txnsCursorParams {
+		if _, ok := r.Form[k]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// transactionsCursorV2 writes a page of the confirmed transactions walked with cursors, for transactionsHandlerV2
+func transactionsCursorV2(w http.ResponseWriter, r *http.Request, gateway Gatewayer, addrs []cipher.Address, order visor.SortOrder, pageSize uint64, verbose bool) {
+	if r.FormValue("page") != "" {
+		writeError400Response(w, "'page' can't be used with 'cursor', 'from_seq', 'to_seq', 'from_time' or 'to_time'")
+		return
+	}
+
+	if confirmedStr := r.FormValue("confirmed"); confirmedStr != "" {
+		if confirmed, err := strconv.ParseBool(confirmedStr); err == nil && !confirmed {
+			writeError400Response(w, "cursor pagination only returns confirmed transactions")
+			return
+		}
+	}
+
+	if _, err := visor.NewPageIndex(pageSize, 1); err != nil {
+		writeError400Response(w, err.Error())
+		return
+	}
+
+	q := visor.TransactionsCursorQuery{
+		Addrs: addrs,
+		Order: order,
+		Limit: pageSize,
+	}
+
+	if cursorStr := r.FormValue("cursor"); cursorStr != "" {
+		cursor, err := historydb.ParseTxnCursor(cursorStr)
+		if err != nil {
+			writeError400Response(w, fmt.Sprintf("invalid 'cursor' value: %v", err))
+			return
+		}
+		q.After = &cursor
+	}
+
+	br, err := parseBlockRange(r)
+	if err != nil {
+		writeError400Response(w, err.Error())
+		return
+	}
+	q.FromSeq = br.fromSeq
+	q.ToSeq = br.toSeq
+	q.FromTime = br.fromTime
+	q.ToTime = br.toTime
+
+	cursorInfo := readable.CursorPageInfo{
+		PageSize: pageSize,
+	}
+
+	var resp HTTPResponse
+	if verbose {
+		txns, inputs, next, err := gateway.GetTransactionsByCursorWithInputs(q)
+		if err != nil {
+			writeError500Response(w, err.Error())
+			return
+		}
+
+		rTxns, err := NewTransactionsWithStatusVerbose(txns, inputs)
+		if err != nil {
+			writeError500Response(w, err.Error())
+			return
+		}
+
+		if next != nil {
+			cursorInfo.NextCursor = next.String()
+		}
+
+		resp.Data = struct {
+			CursorInfo readable.CursorPageInfo                 `json:"cursor_info"`
+			Txns       []readable.TransactionWithStatusVerbose `json:"txns"`
+		}{
+			CursorInfo: cursorInfo,
+			Txns:       rTxns.Transactions,
+		}
+	} else {
+		txns, next, err := gateway.GetTransactionsByCursor(q)
+		if err != nil {
+			writeError500Response(w, err.Error())
+			return
+		}
+
+		rTxns, err := NewTransactionsWithStatus(txns)
+		if err != nil {
+			writeError500Response(w, err.Error())
+			return
+		}
+
+		if next != nil {
+			cursorInfo.NextCursor = next.String()
+		}
+
+		resp.Data = struct {
+			CursorInfo readable.CursorPageInfo          `json:"cursor_info"`
+			Txns       []readable.TransactionWithStatus `json:"txns"`
+		}{
+			CursorInfo: cursorInfo,
+			Txns:       rTxns.Transactions,
+		}
+	}
+
+	writeHTTPResponse(w, resp)
+}
+
 // InjectTransactionRequest is sent to POST /api/v1/injectTransaction
 type InjectTransactionRequest struct {
 	RawTxn      string `json:"rawtx"`
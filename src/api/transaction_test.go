@@ -19,14 +19,15 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ness-network/ness/src/daemon"
+	"github.com/ness-network/ness/src/daemon/gnet"
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/visor"
+	"github.com/ness-network/ness/src/visor/historydb"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
-	"github.com/skycoin/skycoin/src/daemon"
-	"github.com/skycoin/skycoin/src/daemon/gnet"
-	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/testutil"
 	"github.com/skycoin/skycoin/src/transaction"
-	"github.com/skycoin/skycoin/src/visor"
 )
 
 func createUnconfirmedTxn(t *testing.T) visor.UnconfirmedTransaction {
@@ -1593,6 +1594,206 @@ func TestTransactionsHandlerV2(t *testing.T) {
 	}
 }
 
+func TestTransactionsHandlerV2Cursor(t *testing.T) {
+	addr := makeAddress()
+
+	var txns []visor.Transaction
+	var txnsInputs [][]visor.TransactionInput
+	for i := 0; i < 3; i++ {
+		txnAndInputs := prepareTxnAndInputs(t)
+		txns = append(txns, visor.Transaction{
+			Transaction: txnAndInputs.txn,
+			Status:      visor.TransactionStatus{Confirmed: true, BlockSeq: uint64(i + 100)},
+		})
+		txnsInputs = append(txnsInputs, txnAndInputs.inputs)
+	}
+
+	after := historydb.TxnCursor{
+		BlockSeq: 99,
+		TxnIndex: 2,
+	}
+	next := historydb.TxnCursor{
+		BlockSeq: 102,
+	}
+
+	u64 := func(v uint64) *uint64 {
+		return &v
+	}
+
+	tt := []struct {
+		name             string
+		args             []string
+		verbose          bool
+		query            visor.TransactionsCursorQuery
+		next             *historydb.TxnCursor
+		gatewayErr       error
+		expectStatusCode int
+		expectErrMsg     string
+		expectCursorInfo readable.CursorPageInfo
+	}{
+		{
+			name:             "page with cursor",
+			args:             []string{"cursor=", "page=2"},
+			expectStatusCode: http.StatusBadRequest,
+			expectErrMsg:     "'page' can't be used with 'cursor', 'from_seq', 'to_seq', 'from_time' or 'to_time'",
+		},
+		{
+			name:             "unconfirmed",
+			args:             []string{"from_seq=10", "confirmed=0"},
+			expectStatusCode: http.StatusBadRequest,
+			expectErrMsg:     "cursor pagination only returns confirmed transactions",
+		},
+		{
+			name:             "invalid cursor",
+			args:             []string{"cursor=abc"},
+			expectStatusCode: http.StatusBadRequest,
+			expectErrMsg:     "invalid 'cursor' value: invalid transaction cursor",
+		},
+		{
+			name:             "invalid to_time",
+			args:             []string{"to_time=abc"},
+			expectStatusCode: http.StatusBadRequest,
+			expectErrMsg:     "invalid 'to_time' value: strconv.ParseUint: parsing \"abc\": invalid syntax",
+		},
+		{
+			name:             "limit too large",
+			args:             []string{"cursor=", "limit=101"},
+			expectStatusCode: http.StatusBadRequest,
+			expectErrMsg:     "transaction page size must be not greater than 100",
+		},
+		{
+			name: "first page",
+			args: []string{"cursor=", "limit=3"},
+			query: visor.TransactionsCursorQuery{
+				Addrs: []cipher.Address{},
+				Order: visor.AscOrder,
+				Limit: 3,
+			},
+			next:             &next,
+			expectStatusCode: http.StatusOK,
+			expectCursorInfo: readable.CursorPageInfo{
+				PageSize:   3,
+				NextCursor: next.String(),
+			},
+		},
+		{
+			name: "next page with ranges",
+			args: []string{
+				"cursor=" + after.String(),
+				"addrs=" + addr.String(),
+				"confirmed=1",
+				"sort=desc",
+				"from_seq=5",
+				"to_seq=500",
+				"from_time=1000",
+				"to_time=2000",
+			},
+			query: visor.TransactionsCursorQuery{
+				Addrs:    []cipher.Address{addr},
+				After:    &after,
+				Order:    visor.DescOrder,
+				FromSeq:  u64(5),
+				ToSeq:    u64(500),
+				FromTime: u64(1000),
+				ToTime:   u64(2000),
+				Limit:    visor.DefaultTxnPageSize,
+			},
+			expectStatusCode: http.StatusOK,
+			expectCursorInfo: readable.CursorPageInfo{
+				PageSize: visor.DefaultTxnPageSize,
+			},
+		},
+		{
+			name:    "verbose",
+			args:    []string{"from_time=1000", "verbose=1"},
+			verbose: true,
+			query: visor.TransactionsCursorQuery{
+				Addrs:    []cipher.Address{},
+				Order:    visor.AscOrder,
+				FromTime: u64(1000),
+				Limit:    visor.DefaultTxnPageSize,
+			},
+			next:             &next,
+			expectStatusCode: http.StatusOK,
+			expectCursorInfo: readable.CursorPageInfo{
+				PageSize:   visor.DefaultTxnPageSize,
+				NextCursor: next.String(),
+			},
+		},
+		{
+			name: "gateway error",
+			args: []string{"to_seq=10"},
+			query: visor.TransactionsCursorQuery{
+				Addrs: []cipher.Address{},
+				Order: visor.AscOrder,
+				ToSeq: u64(10),
+				Limit: visor.DefaultTxnPageSize,
+			},
+			gatewayErr:       errors.New("GetTransactionsByCursor failed"),
+			expectStatusCode: http.StatusInternalServerError,
+			expectErrMsg:     "GetTransactionsByCursor failed",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			endpoint := "/api/v2/transactions?" + strings.Join(tc.args, "&")
+			req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+			require.NoError(t, err)
+
+			gateway := &MockGatewayer{}
+			if tc.gatewayErr != nil {
+				gateway.On("GetTransactionsByCursor", tc.query).Return(nil, nil, tc.gatewayErr)
+			} else {
+				gateway.On("GetTransactionsByCursor", tc.query).Return(txns, tc.next, nil)
+				gateway.On("GetTransactionsByCursorWithInputs", tc.query).Return(txns, txnsInputs, tc.next, nil)
+			}
+
+			setCSRFParameters(t, tokenValid, req)
+
+			rec := httptest.NewRecorder()
+			srv := newServerMux(defaultMuxConfig(), gateway)
+			srv.ServeHTTP(rec, req)
+
+			require.Equal(t, tc.expectStatusCode, rec.Code)
+
+			var rsp ReceivedHTTPResponse
+			err = json.NewDecoder(rec.Body).Decode(&rsp)
+			require.NoError(t, err)
+			if rec.Code != http.StatusOK {
+				require.Equal(t, tc.expectErrMsg, rsp.Error.Message)
+				return
+			}
+
+			if tc.verbose {
+				var txnRsp struct {
+					CursorInfo readable.CursorPageInfo                 `json:"cursor_info"`
+					Txns       []readable.TransactionWithStatusVerbose `json:"txns"`
+				}
+				err = json.Unmarshal(rsp.Data, &txnRsp)
+				require.NoError(t, err)
+				require.Equal(t, tc.expectCursorInfo, txnRsp.CursorInfo)
+
+				expectTxns, err := NewTransactionsWithStatusVerbose(txns, txnsInputs)
+				require.NoError(t, err)
+				require.Equal(t, expectTxns.Transactions, txnRsp.Txns)
+			} else {
+				var txnRsp struct {
+					CursorInfo readable.CursorPageInfo          `json:"cursor_info"`
+					Txns       []readable.TransactionWithStatus `json:"txns"`
+				}
+				err = json.Unmarshal(rsp.Data, &txnRsp)
+				require.NoError(t, err)
+				require.Equal(t, tc.expectCursorInfo, txnRsp.CursorInfo)
+
+				expectTxns, err := NewTransactionsWithStatus(txns)
+				require.NoError(t, err)
+				require.Equal(t, expectTxns.Transactions, txnRsp.Txns)
+			}
+		})
+	}
+}
+
 type transactionAndInputs struct {
 	txn    coin.Transaction
 	inputs []visor.TransactionInput
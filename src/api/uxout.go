@@ -3,8 +3,8 @@ package api
 import (
 	"net/http"
 
+	"github.com/ness-network/ness/src/readable"
 	"github.com/skycoin/skycoin/src/cipher"
-	"github.com/skycoin/skycoin/src/readable"
 	wh "github.com/skycoin/skycoin/src/util/http"
 )
 
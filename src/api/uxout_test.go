@@ -14,10 +14,10 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/visor/historydb"
 	"github.com/skycoin/skycoin/src/cipher"
-	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/testutil"
-	"github.com/skycoin/skycoin/src/visor/historydb"
 )
 
 func TestGetUxOutByID(t *testing.T) {
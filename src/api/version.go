@@ -3,7 +3,7 @@ package api
 import (
 	"net/http"
 
-	"github.com/skycoin/skycoin/src/readable"
+	"github.com/ness-network/ness/src/readable"
 	wh "github.com/skycoin/skycoin/src/util/http"
 )
 
@@ -10,10 +10,11 @@ import (
 	"sort"
 	"strconv"
 
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/wallet/watch"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/cipher/bip39"
 	"github.com/skycoin/skycoin/src/cipher/bip44"
-	"github.com/skycoin/skycoin/src/readable"
 	wh "github.com/skycoin/skycoin/src/util/http"
 	"github.com/skycoin/skycoin/src/wallet"
 )
@@ -78,7 +79,11 @@ func NewWalletResponse(w wallet.Wallet) (*WalletResponse, error) {
 	for i, e := range entries {
 		wr.Entries[i] = readable.WalletEntry{
 			Address: e.Address.String(),
-			Public:  e.Public.Hex(),
+		}
+
+		// Entries of watch wallets have no public key
+		if !e.Public.Null() {
+			wr.Entries[i].Public = e.Public.Hex()
 		}
 
 		switch w.Type() {
@@ -207,9 +212,10 @@ func balanceHandler(gateway Gatewayer) http.HandlerFunc {
 // Args:
 //     seed: wallet seed [required]
 //     seed-passphrase: wallet seed passphrase [optional, bip44 type wallet only]
-//     type: wallet type [required, one of "deterministic", "bip44" or "xpub"]
+//     type: wallet type [required, one of "deterministic", "bip44", "xpub", "collection" or "watch"]
 //     bip44-coin: BIP44 coin type [optional, defaults to 8000 (skycoin's coin type), only valid if type is "bip44"]
 //     xpub: xpub key [required for xpub wallets]
+//     addresses: addresses of a watch wallet [required for watch wallets, multiple addresses must be joined with commas]
 //     label: wallet label [required]
 //     scan: the number of addresses to scan ahead for balances [optional, must be > 0]
 //     encrypt: bool value, whether encrypt the wallet [optional]
@@ -232,6 +238,24 @@ func walletCreateHandler(gateway Gatewayer) http.HandlerFunc {
 		label := r.FormValue("label")
 		password := r.FormValue("password")
 
+		addresses := r.FormValue("addresses")
+		if walletType == watch.WalletType {
+			if seed != "" {
+				wh.Error400(w, "seed is not used by watch wallets")
+				return
+			}
+			if addresses == "" {
+				wh.Error400(w, "missing addresses")
+				return
+			}
+
+			// The watch wallet creator takes the address list in place of the seed
+			seed = addresses
+		} else if addresses != "" {
+			wh.Error400(w, "addresses is only valid for watch type wallets")
+			return
+		}
+
 		defer func() {
 			password = ""
 		}()
@@ -449,6 +473,8 @@ func walletCreateTempHandler(gateway Gatewayer) http.HandlerFunc {
 //     id: wallet id [required]
 //     num: number of address need to create [optional, if not set the default value is 1]
 //     password: wallet password [optional, must be provided if the wallet is encrypted]
+//     account: bip44 account of the addresses [optional, bip44 wallets only, default is 0]
+//     change: generate addresses on the change chain of the account [optional, bip44 wallets only]
 func walletNewAddressesHandler(gateway Gatewayer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
@@ -488,6 +514,49 @@ func walletNewAddressesHandler(gateway Gatewayer) http.HandlerFunc {
 			opts = append(opts, wallet.OptionCollectionPrivateKeys(privateKeys))
 		}
 
+		var bip44Opts []wallet.Option
+		if account := r.FormValue("account"); account != "" {
+			n, err := strconv.ParseUint(account, 10, 32)
+			if err != nil {
+				wh.Error400(w, "invalid account value")
+				return
+			}
+			bip44Opts = append(bip44Opts, wallet.OptionAccount(uint32(n)))
+		}
+
+		if change := r.FormValue("change"); change != "" {
+			c, err := strconv.ParseBool(change)
+			if err != nil {
+				wh.Error400(w, "invalid change value")
+				return
+			}
+			if c {
+				bip44Opts = append(bip44Opts, wallet.OptionChange())
+			}
+		}
+
+		if len(bip44Opts) > 0 {
+			wlt, err := gateway.GetWallet(wltID)
+			if err != nil {
+				switch err {
+				case wallet.ErrWalletNotExist:
+					wh.Error404(w, "")
+				case wallet.ErrWalletAPIDisabled:
+					wh.Error403(w, "")
+				default:
+					wh.Error500(w, err.Error())
+				}
+				return
+			}
+
+			if wlt.Type() != wallet.WalletTypeBip44 {
+				wh.Error400(w, "account and change are only valid for bip44 wallets")
+				return
+			}
+
+			opts = append(opts, bip44Opts...)
+		}
+
 		addrs, err := gateway.NewAddresses(wltID, []byte(password), opts...)
 		if err != nil {
 			switch err {
@@ -1089,6 +1158,7 @@ type WalletRecoverRequest struct {
 	Seed           string `json:"seed"`
 	SeedPassphrase string `json:"seed_passphrase"`
 	Password       string `json:"password"`
+	GapLimit       uint64 `json:"gap_limit,omitempty"`
 }
 
 // URI: /api/v2/wallet/recover
@@ -1097,10 +1167,13 @@ type WalletRecoverRequest struct {
 //  id: wallet id
 //  seed: wallet seed
 //  password: [optional] new password
+//  gap_limit: [optional] number of unused addresses to discover after the last used address of bip44 wallets, defaults to 20
 // Recovers an encrypted wallet by providing the seed.
 // The first address will be generated from seed and compared to the first address
 // of the specified wallet. If they match, the wallet will be regenerated
 // with an optional password.
+// The addresses and the accounts of bip44 wallets are then discovered until gap_limit
+// consecutive addresses without transactions are found.
 // If the wallet is not encrypted, an error is returned.
 func walletRecoverHandler(gateway Gatewayer) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -1161,6 +1234,19 @@ func walletRecoverHandler(gateway Gatewayer) http.HandlerFunc {
 			return
 		}
 
+		if wlt.Type() == wallet.WalletTypeBip44 {
+			if _, err := gateway.DiscoverWalletAddresses(req.ID, password, req.GapLimit); err != nil {
+				writeHTTPResponse(w, walletErrorResponse(err))
+				return
+			}
+
+			wlt, err = gateway.GetWallet(req.ID)
+			if err != nil {
+				writeHTTPResponse(w, walletErrorResponse(err))
+				return
+			}
+		}
+
 		rlt, err := NewWalletResponse(wlt)
 		if err != nil {
 			resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
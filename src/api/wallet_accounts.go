@@ -0,0 +1,171 @@
+package api
+
+// APIs for the accounts of bip44 wallets
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/visor"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// WalletAccount is an account of a bip44 wallet with its addresses and balance
+type WalletAccount struct {
+	Index uint32 `json:"index"`
+	Name  string `json:"name"`
+	readable.BalancePair
+	ExternalAddresses []string `json:"external_addresses"`
+	ChangeAddresses   []string `json:"change_addresses"`
+}
+
+// NewWalletAccount creates a WalletAccount from a visor.WalletAccount
+func NewWalletAccount(a visor.WalletAccount) WalletAccount {
+	return WalletAccount{
+		Index:             a.Index,
+		Name:              a.Name,
+		BalancePair:       readable.NewBalancePair(a.Balance),
+		ExternalAddresses: addressesToStrings(a.ExternalAddresses),
+		ChangeAddresses:   addressesToStrings(a.ChangeAddresses),
+	}
+}
+
+func addressesToStrings(addrs []cipher.Address) []string {
+	s := make([]string, len(addrs))
+	for i, a := range addrs {
+		s[i] = a.String()
+	}
+	return s
+}
+
+// WalletAccountsResponse is returned by GET /api/v2/wallet/accounts
+type WalletAccountsResponse struct {
+	Accounts []WalletAccount `json:"accounts"`
+}
+
+// Returns the accounts of a bip44 wallet with the addresses and the balance of each account
+// URI: /api/v2/wallet/accounts
+// Method: GET
+// Args:
+//     id: wallet id [required]
+func walletAccountsHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wltID := r.FormValue("id")
+		if wltID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		accounts, err := gateway.GetWalletAccounts(wltID)
+		if err != nil {
+			writeHTTPResponse(w, walletErrorResponse(err))
+			return
+		}
+
+		rsp := WalletAccountsResponse{
+			Accounts: make([]WalletAccount, len(accounts)),
+		}
+		for i, a := range accounts {
+			rsp.Accounts[i] = NewWalletAccount(a)
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: rsp,
+		})
+	}
+}
+
+// WalletAccountCreateRequest is the request data for POST /api/v2/wallet/account/create
+type WalletAccountCreateRequest struct {
+	ID       string `json:"id"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+}
+
+// WalletAccountCreateResponse is returned by POST /api/v2/wallet/account/create
+type WalletAccountCreateResponse struct {
+	Index uint32 `json:"index"`
+	Name  string `json:"name"`
+}
+
+// Creates an account in a bip44 wallet
+// URI: /api/v2/wallet/account/create
+// Method: POST
+// Args: JSON body
+//     id: wallet id [required]
+//     name: account name [required]
+//     password: wallet password [optional, must be provided if the wallet is encrypted]
+func walletAccountCreateHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req WalletAccountCreateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.ID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.Name == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "name is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		password := []byte(req.Password)
+		defer func() {
+			req.Password = ""
+			password = nil
+		}()
+
+		index, err := gateway.NewWalletAccount(req.ID, password, req.Name)
+		if err != nil {
+			writeHTTPResponse(w, walletErrorResponse(err))
+			return
+		}
+
+		writeHTTPResponse(w, HTTPResponse{
+			Data: WalletAccountCreateResponse{
+				Index: index,
+				Name:  req.Name,
+			},
+		})
+	}
+}
+
+func walletErrorResponse(err error) HTTPResponse {
+	switch err.(type) {
+	case wallet.Error:
+		switch err {
+		case wallet.ErrWalletNotExist:
+			return NewHTTPErrorResponse(http.StatusNotFound, "")
+		case wallet.ErrWalletAPIDisabled:
+			return NewHTTPErrorResponse(http.StatusForbidden, "")
+		default:
+			return NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+		}
+	case visor.UserError:
+		return NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+	default:
+		return NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+	}
+}
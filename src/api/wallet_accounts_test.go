@@ -0,0 +1,296 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/visor"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func TestWalletAccountsHandler(t *testing.T) {
+	external := testutil.MakeAddress()
+	change := testutil.MakeAddress()
+
+	accounts := []visor.WalletAccount{
+		{
+			Bip44Account: wallet.Bip44Account{
+				Name:  "default",
+				Index: 0,
+			},
+			ExternalAddresses: []cipher.Address{external},
+			ChangeAddresses:   []cipher.Address{change},
+			Balance: wallet.BalancePair{
+				Confirmed: wallet.Balance{Coins: 2e6, Hours: 10},
+				Predicted: wallet.Balance{Coins: 1e6, Hours: 5},
+			},
+		},
+		{
+			Bip44Account: wallet.Bip44Account{
+				Name:  "savings",
+				Index: 1,
+			},
+		},
+	}
+
+	cases := []struct {
+		name            string
+		method          string
+		id              string
+		status          int
+		gatewayAccounts []visor.WalletAccount
+		gatewayErr      error
+		httpResponse    HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodPost,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:         "400 - missing id",
+			method:       http.MethodGet,
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "id is required"),
+		},
+		{
+			name:         "404 - wallet does not exist",
+			method:       http.MethodGet,
+			id:           "foo.wlt",
+			status:       http.StatusNotFound,
+			gatewayErr:   wallet.ErrWalletNotExist,
+			httpResponse: NewHTTPErrorResponse(http.StatusNotFound, ""),
+		},
+		{
+			name:         "403 - wallet API disabled",
+			method:       http.MethodGet,
+			id:           "foo.wlt",
+			status:       http.StatusForbidden,
+			gatewayErr:   wallet.ErrWalletAPIDisabled,
+			httpResponse: NewHTTPErrorResponse(http.StatusForbidden, ""),
+		},
+		{
+			name:         "400 - not a bip44 wallet",
+			method:       http.MethodGet,
+			id:           "foo.wlt",
+			status:       http.StatusBadRequest,
+			gatewayErr:   visor.ErrWalletNotBip44,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, visor.ErrWalletNotBip44.Error()),
+		},
+		{
+			name:         "500 - other error",
+			method:       http.MethodGet,
+			id:           "foo.wlt",
+			status:       http.StatusInternalServerError,
+			gatewayErr:   errors.New("balance failed"),
+			httpResponse: NewHTTPErrorResponse(http.StatusInternalServerError, "balance failed"),
+		},
+		{
+			name:            "200",
+			method:          http.MethodGet,
+			id:              "foo.wlt",
+			status:          http.StatusOK,
+			gatewayAccounts: accounts,
+			httpResponse: HTTPResponse{
+				Data: WalletAccountsResponse{
+					Accounts: []WalletAccount{
+						{
+							Index: 0,
+							Name:  "default",
+							BalancePair: readable.BalancePair{
+								Confirmed: readable.Balance{Coins: 2e6, Hours: 10},
+								Predicted: readable.Balance{Coins: 1e6, Hours: 5},
+							},
+							ExternalAddresses: []string{external.String()},
+							ChangeAddresses:   []string{change.String()},
+						},
+						{
+							Index:             1,
+							Name:              "savings",
+							ExternalAddresses: []string{},
+							ChangeAddresses:   []string{},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetWalletAccounts", tc.id).Return(tc.gatewayAccounts, tc.gatewayErr)
+
+			v := url.Values{}
+			if tc.id != "" {
+				v.Add("id", tc.id)
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/wallet/accounts?"+v.Encode(), nil)
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", ContentTypeJSON)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code)
+
+			var rsp ReceivedHTTPResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rsp))
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data == nil {
+				require.Nil(t, tc.httpResponse.Data)
+			} else {
+				var accountsRsp WalletAccountsResponse
+				require.NoError(t, json.Unmarshal(rsp.Data, &accountsRsp))
+				require.Equal(t, tc.httpResponse.Data, accountsRsp)
+			}
+		})
+	}
+}
+
+func TestWalletAccountCreateHandler(t *testing.T) {
+	cases := []struct {
+		name         string
+		method       string
+		body         string
+		req          *WalletAccountCreateRequest
+		status       int
+		gatewayIndex uint32
+		gatewayErr   error
+		httpResponse HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodGet,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:         "400 - invalid json",
+			method:       http.MethodPost,
+			body:         "{",
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "unexpected EOF"),
+		},
+		{
+			name:   "400 - missing id",
+			method: http.MethodPost,
+			req: &WalletAccountCreateRequest{
+				Name: "savings",
+			},
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "id is required"),
+		},
+		{
+			name:   "400 - missing name",
+			method: http.MethodPost,
+			req: &WalletAccountCreateRequest{
+				ID: "foo.wlt",
+			},
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "name is required"),
+		},
+		{
+			name:   "400 - missing password",
+			method: http.MethodPost,
+			req: &WalletAccountCreateRequest{
+				ID:   "foo.wlt",
+				Name: "savings",
+			},
+			status:       http.StatusBadRequest,
+			gatewayErr:   wallet.ErrMissingPassword,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, wallet.ErrMissingPassword.Error()),
+		},
+		{
+			name:   "404 - wallet does not exist",
+			method: http.MethodPost,
+			req: &WalletAccountCreateRequest{
+				ID:   "foo.wlt",
+				Name: "savings",
+			},
+			status:       http.StatusNotFound,
+			gatewayErr:   wallet.ErrWalletNotExist,
+			httpResponse: NewHTTPErrorResponse(http.StatusNotFound, ""),
+		},
+		{
+			name:   "400 - not a bip44 wallet",
+			method: http.MethodPost,
+			req: &WalletAccountCreateRequest{
+				ID:   "foo.wlt",
+				Name: "savings",
+			},
+			status:       http.StatusBadRequest,
+			gatewayErr:   visor.ErrWalletNotBip44,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, visor.ErrWalletNotBip44.Error()),
+		},
+		{
+			name:   "200",
+			method: http.MethodPost,
+			req: &WalletAccountCreateRequest{
+				ID:       "foo.wlt",
+				Name:     "savings",
+				Password: "pwd",
+			},
+			status:       http.StatusOK,
+			gatewayIndex: 1,
+			httpResponse: HTTPResponse{
+				Data: WalletAccountCreateResponse{
+					Index: 1,
+					Name:  "savings",
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			if tc.req != nil {
+				gateway.On("NewWalletAccount", tc.req.ID, []byte(tc.req.Password), tc.req.Name).Return(tc.gatewayIndex, tc.gatewayErr)
+			}
+
+			if tc.body == "" && tc.req != nil {
+				tc.body = toJSON(t, tc.req)
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/wallet/account/create", strings.NewReader(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", ContentTypeJSON)
+			setCSRFParameters(t, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			cfg := defaultMuxConfig()
+			cfg.disableCSRF = false
+			handler := newServerMux(cfg, gateway)
+			handler.ServeHTTP(rr, req)
+
+			require.Equal(t, tc.status, rr.Code)
+
+			var rsp ReceivedHTTPResponse
+			require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rsp))
+			require.Equal(t, tc.httpResponse.Error, rsp.Error)
+
+			if rsp.Data == nil {
+				require.Nil(t, tc.httpResponse.Data)
+			} else {
+				var createRsp WalletAccountCreateResponse
+				require.NoError(t, json.Unmarshal(rsp.Data, &createRsp))
+				require.Equal(t, tc.httpResponse.Data, createRsp)
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package api
+
+// APIs for the coin control of wallets
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/visor"
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// WalletOutput is an unspent output of a wallet with its label and freeze flag
+type WalletOutput struct {
+	readable.UnspentOutput
+	Label  string `json:"label"`
+	Frozen bool   `json:"frozen"`
+}
+
+// NewWalletOutput creates a WalletOutput from a visor.WalletOutput
+func NewWalletOutput(o visor.WalletOutput) (WalletOutput, error) {
+	uo, err := readable.NewUnspentOutput(o.UnspentOutput)
+	if err != nil {
+		return WalletOutput{}, err
+	}
+
+	return WalletOutput{
+		UnspentOutput: uo,
+		Label:         o.Label,
+		Frozen:        o.Frozen,
+	}, nil
+}
+
+// NewWalletOutputs creates []WalletOutput from []visor.WalletOutput
+func NewWalletOutputs(outputs []visor.WalletOutput) ([]WalletOutput, error) {
+	rOutputs := make([]WalletOutput, len(outputs))
+	for i, o := range outputs {
+		var err error
+		rOutputs[i], err = NewWalletOutput(o)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return rOutputs, nil
+}
+
+// WalletOutputsResponse is returned by the /api/v2/wallet/outputs endpoints
+type WalletOutputsResponse struct {
+	Outputs []WalletOutput `json:"outputs"`
+}
+
+// Returns the confirmed unspent outputs of a wallet with their labels and freeze flags
+// URI: /api/v2/wallet/outputs
+// Method: GET
+// Args:
+//     id: wallet id [required]
+func walletOutputsHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		wltID := r.FormValue("id")
+		if wltID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		outputs, err := gateway.GetWalletOutputs(wltID)
+		if err != nil {
+			writeHTTPResponse(w, walletErrorResponse(err))
+			return
+		}
+
+		writeWalletOutputsResponse(w, outputs)
+	}
+}
+
+// WalletOutputsFreezeRequest is the request data for POST /api/v2/wallet/outputs/freeze and /api/v2/wallet/outputs/unfreeze
+type WalletOutputsFreezeRequest struct {
+	ID     string   `json:"id"`
+	Hashes []string `json:"hashes"`
+}
+
+// Freezes or unfreezes unspent outputs of a wallet. Frozen outputs are not spent by the
+// transactions created by the wallet, and can not be chosen explicitly until they are unfrozen.
+// URI: /api/v2/wallet/outputs/freeze, /api/v2/wallet/outputs/unfreeze
+// Method: POST
+// Args: JSON body
+//     id: wallet id [required]
+//     hashes: hashes of the unspent outputs [required]
+func walletOutputsFreezeHandler(gateway Gatewayer, frozen bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req WalletOutputsFreezeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.ID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if len(req.Hashes) == 0 {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "hashes is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		hashes := make([]cipher.SHA256, len(req.Hashes))
+		for i, h := range req.Hashes {
+			var err error
+			hashes[i], err = cipher.SHA256FromHex(h)
+			if err != nil {
+				resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("invalid hash %q: %v", h, err))
+				writeHTTPResponse(w, resp)
+				return
+			}
+		}
+
+		outputs, err := gateway.FreezeWalletOutputs(req.ID, hashes, frozen)
+		if err != nil {
+			writeHTTPResponse(w, walletErrorResponse(err))
+			return
+		}
+
+		writeWalletOutputsResponse(w, outputs)
+	}
+}
+
+// WalletOutputLabelRequest is the request data for POST /api/v2/wallet/outputs/label
+type WalletOutputLabelRequest struct {
+	ID    string `json:"id"`
+	Hash  string `json:"hash"`
+	Label string `json:"label"`
+}
+
+// Sets the label of an unspent output of a wallet
+// URI: /api/v2/wallet/outputs/label
+// Method: POST
+// Args: JSON body
+//     id: wallet id [required]
+//     hash: hash of the unspent output [required]
+//     label: label of the output [optional, an empty label removes it]
+func walletOutputLabelHandler(gateway Gatewayer) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			resp := NewHTTPErrorResponse(http.StatusMethodNotAllowed, "")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		var req WalletOutputLabelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, err.Error())
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.ID == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "id is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		if req.Hash == "" {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, "hash is required")
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		hash, err := cipher.SHA256FromHex(req.Hash)
+		if err != nil {
+			resp := NewHTTPErrorResponse(http.StatusBadRequest, fmt.Sprintf("invalid hash: %v", err))
+			writeHTTPResponse(w, resp)
+			return
+		}
+
+		output, err := gateway.SetWalletOutputLabel(req.ID, hash, req.Label)
+		if err != nil {
+			writeHTTPResponse(w, walletErrorResponse(err))
+			return
+		}
+
+		writeWalletOutputsResponse(w, []visor.WalletOutput{*output})
+	}
+}
+
+func writeWalletOutputsResponse(w http.ResponseWriter, outputs []visor.WalletOutput) {
+	rOutputs, err := NewWalletOutputs(outputs)
+	if err != nil {
+		resp := NewHTTPErrorResponse(http.StatusInternalServerError, err.Error())
+		writeHTTPResponse(w, resp)
+		return
+	}
+
+	writeHTTPResponse(w, HTTPResponse{
+		Data: WalletOutputsResponse{
+			Outputs: rOutputs,
+		},
+	})
+}
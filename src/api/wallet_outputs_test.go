@@ -0,0 +1,413 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/visor"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func makeWalletOutput(t *testing.T, label string, frozen bool) (visor.WalletOutput, WalletOutput) {
+	o := visor.WalletOutput{
+		UnspentOutput: visor.UnspentOutput{
+			UxOut: coin.UxOut{
+				Head: coin.UxHead{
+					Time:  1000,
+					BkSeq: 2,
+				},
+				Body: coin.UxBody{
+					SrcTransaction: testutil.RandSHA256(t),
+					Address:        testutil.MakeAddress(),
+					Coins:          2e6,
+					Hours:          10,
+				},
+			},
+			CalculatedHours: 12,
+		},
+		WalletOutputMeta: visor.WalletOutputMeta{
+			Label:  label,
+			Frozen: frozen,
+		},
+	}
+
+	return o, WalletOutput{
+		UnspentOutput: readable.UnspentOutput{
+			Hash:              o.Hash().Hex(),
+			Time:              1000,
+			BkSeq:             2,
+			SourceTransaction: o.Body.SrcTransaction.Hex(),
+			Address:           o.Body.Address.String(),
+			Coins:             "2.000000",
+			Hours:             10,
+			CalculatedHours:   12,
+		},
+		Label:  label,
+		Frozen: frozen,
+	}
+}
+
+func requireWalletOutputsResponse(t *testing.T, rr *httptest.ResponseRecorder, status int, httpResponse HTTPResponse) {
+	require.Equal(t, status, rr.Code)
+
+	var rsp ReceivedHTTPResponse
+	require.NoError(t, json.Unmarshal(rr.Body.Bytes(), &rsp))
+	require.Equal(t, httpResponse.Error, rsp.Error)
+
+	if rsp.Data == nil {
+		require.Nil(t, httpResponse.Data)
+	} else {
+		var outputsRsp WalletOutputsResponse
+		require.NoError(t, json.Unmarshal(rsp.Data, &outputsRsp))
+		require.Equal(t, httpResponse.Data, outputsRsp)
+	}
+}
+
+func TestWalletOutputsHandler(t *testing.T) {
+	o1, r1 := makeWalletOutput(t, "cold storage", true)
+	o2, r2 := makeWalletOutput(t, "", false)
+
+	cases := []struct {
+		name           string
+		method         string
+		id             string
+		status         int
+		gatewayOutputs []visor.WalletOutput
+		gatewayErr     error
+		httpResponse   HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodPost,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:         "400 - missing id",
+			method:       http.MethodGet,
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "id is required"),
+		},
+		{
+			name:         "404 - wallet does not exist",
+			method:       http.MethodGet,
+			id:           "foo.wlt",
+			status:       http.StatusNotFound,
+			gatewayErr:   wallet.ErrWalletNotExist,
+			httpResponse: NewHTTPErrorResponse(http.StatusNotFound, ""),
+		},
+		{
+			name:         "403 - wallet API disabled",
+			method:       http.MethodGet,
+			id:           "foo.wlt",
+			status:       http.StatusForbidden,
+			gatewayErr:   wallet.ErrWalletAPIDisabled,
+			httpResponse: NewHTTPErrorResponse(http.StatusForbidden, ""),
+		},
+		{
+			name:         "500 - other error",
+			method:       http.MethodGet,
+			id:           "foo.wlt",
+			status:       http.StatusInternalServerError,
+			gatewayErr:   errors.New("unspent failed"),
+			httpResponse: NewHTTPErrorResponse(http.StatusInternalServerError, "unspent failed"),
+		},
+		{
+			name:           "200",
+			method:         http.MethodGet,
+			id:             "foo.wlt",
+			status:         http.StatusOK,
+			gatewayOutputs: []visor.WalletOutput{o1, o2},
+			httpResponse: HTTPResponse{
+				Data: WalletOutputsResponse{
+					Outputs: []WalletOutput{r1, r2},
+				},
+			},
+		},
+		{
+			name:   "200 - no outputs",
+			method: http.MethodGet,
+			id:     "foo.wlt",
+			status: http.StatusOK,
+			httpResponse: HTTPResponse{
+				Data: WalletOutputsResponse{
+					Outputs: []WalletOutput{},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			gateway.On("GetWalletOutputs", tc.id).Return(tc.gatewayOutputs, tc.gatewayErr)
+
+			v := url.Values{}
+			if tc.id != "" {
+				v.Add("id", tc.id)
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/wallet/outputs?"+v.Encode(), nil)
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", ContentTypeJSON)
+
+			rr := httptest.NewRecorder()
+			handler := newServerMux(defaultMuxConfig(), gateway)
+			handler.ServeHTTP(rr, req)
+
+			requireWalletOutputsResponse(t, rr, tc.status, tc.httpResponse)
+		})
+	}
+}
+
+func TestWalletOutputsFreezeHandler(t *testing.T) {
+	o, r := makeWalletOutput(t, "", true)
+	hash := o.Hash()
+
+	cases := []struct {
+		name           string
+		endpoint       string
+		method         string
+		body           string
+		req            *WalletOutputsFreezeRequest
+		frozen         bool
+		status         int
+		gatewayOutputs []visor.WalletOutput
+		gatewayErr     error
+		httpResponse   HTTPResponse
+	}{
+		{
+			name:         "405",
+			endpoint:     "/api/v2/wallet/outputs/freeze",
+			method:       http.MethodGet,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:         "400 - invalid json",
+			endpoint:     "/api/v2/wallet/outputs/freeze",
+			method:       http.MethodPost,
+			body:         "{",
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "unexpected EOF"),
+		},
+		{
+			name:     "400 - missing id",
+			endpoint: "/api/v2/wallet/outputs/freeze",
+			method:   http.MethodPost,
+			req: &WalletOutputsFreezeRequest{
+				Hashes: []string{hash.Hex()},
+			},
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "id is required"),
+		},
+		{
+			name:     "400 - missing hashes",
+			endpoint: "/api/v2/wallet/outputs/freeze",
+			method:   http.MethodPost,
+			req: &WalletOutputsFreezeRequest{
+				ID: "foo.wlt",
+			},
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "hashes is required"),
+		},
+		{
+			name:     "400 - invalid hash",
+			endpoint: "/api/v2/wallet/outputs/freeze",
+			method:   http.MethodPost,
+			req: &WalletOutputsFreezeRequest{
+				ID:     "foo.wlt",
+				Hashes: []string{"abcd"},
+			},
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, `invalid hash "abcd": Invalid hex length`),
+		},
+		{
+			name:     "400 - output not found",
+			endpoint: "/api/v2/wallet/outputs/freeze",
+			method:   http.MethodPost,
+			req: &WalletOutputsFreezeRequest{
+				ID:     "foo.wlt",
+				Hashes: []string{hash.Hex()},
+			},
+			frozen:       true,
+			status:       http.StatusBadRequest,
+			gatewayErr:   visor.ErrWalletOutputNotFound,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, visor.ErrWalletOutputNotFound.Error()),
+		},
+		{
+			name:     "404 - wallet does not exist",
+			endpoint: "/api/v2/wallet/outputs/unfreeze",
+			method:   http.MethodPost,
+			req: &WalletOutputsFreezeRequest{
+				ID:     "foo.wlt",
+				Hashes: []string{hash.Hex()},
+			},
+			status:       http.StatusNotFound,
+			gatewayErr:   wallet.ErrWalletNotExist,
+			httpResponse: NewHTTPErrorResponse(http.StatusNotFound, ""),
+		},
+		{
+			name:     "200 - freeze",
+			endpoint: "/api/v2/wallet/outputs/freeze",
+			method:   http.MethodPost,
+			req: &WalletOutputsFreezeRequest{
+				ID:     "foo.wlt",
+				Hashes: []string{hash.Hex()},
+			},
+			frozen:         true,
+			status:         http.StatusOK,
+			gatewayOutputs: []visor.WalletOutput{o},
+			httpResponse: HTTPResponse{
+				Data: WalletOutputsResponse{
+					Outputs: []WalletOutput{r},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			if tc.req != nil {
+				gateway.On("FreezeWalletOutputs", tc.req.ID, []cipher.SHA256{hash}, tc.frozen).Return(tc.gatewayOutputs, tc.gatewayErr)
+			}
+
+			if tc.body == "" && tc.req != nil {
+				tc.body = toJSON(t, tc.req)
+			}
+
+			req, err := http.NewRequest(tc.method, tc.endpoint, strings.NewReader(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", ContentTypeJSON)
+			setCSRFParameters(t, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			cfg := defaultMuxConfig()
+			cfg.disableCSRF = false
+			handler := newServerMux(cfg, gateway)
+			handler.ServeHTTP(rr, req)
+
+			requireWalletOutputsResponse(t, rr, tc.status, tc.httpResponse)
+		})
+	}
+}
+
+func TestWalletOutputLabelHandler(t *testing.T) {
+	o, r := makeWalletOutput(t, "savings", false)
+	hash := o.Hash()
+
+	cases := []struct {
+		name          string
+		method        string
+		body          string
+		req           *WalletOutputLabelRequest
+		status        int
+		gatewayOutput *visor.WalletOutput
+		gatewayErr    error
+		httpResponse  HTTPResponse
+	}{
+		{
+			name:         "405",
+			method:       http.MethodGet,
+			status:       http.StatusMethodNotAllowed,
+			httpResponse: NewHTTPErrorResponse(http.StatusMethodNotAllowed, ""),
+		},
+		{
+			name:   "400 - missing id",
+			method: http.MethodPost,
+			req: &WalletOutputLabelRequest{
+				Hash:  hash.Hex(),
+				Label: "savings",
+			},
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "id is required"),
+		},
+		{
+			name:   "400 - missing hash",
+			method: http.MethodPost,
+			req: &WalletOutputLabelRequest{
+				ID:    "foo.wlt",
+				Label: "savings",
+			},
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "hash is required"),
+		},
+		{
+			name:   "400 - invalid hash",
+			method: http.MethodPost,
+			req: &WalletOutputLabelRequest{
+				ID:    "foo.wlt",
+				Hash:  "abcd",
+				Label: "savings",
+			},
+			status:       http.StatusBadRequest,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, "invalid hash: Invalid hex length"),
+		},
+		{
+			name:   "400 - output not found",
+			method: http.MethodPost,
+			req: &WalletOutputLabelRequest{
+				ID:    "foo.wlt",
+				Hash:  hash.Hex(),
+				Label: "savings",
+			},
+			status:       http.StatusBadRequest,
+			gatewayErr:   visor.ErrWalletOutputNotFound,
+			httpResponse: NewHTTPErrorResponse(http.StatusBadRequest, visor.ErrWalletOutputNotFound.Error()),
+		},
+		{
+			name:   "200",
+			method: http.MethodPost,
+			req: &WalletOutputLabelRequest{
+				ID:    "foo.wlt",
+				Hash:  hash.Hex(),
+				Label: "savings",
+			},
+			status:        http.StatusOK,
+			gatewayOutput: &o,
+			httpResponse: HTTPResponse{
+				Data: WalletOutputsResponse{
+					Outputs: []WalletOutput{r},
+				},
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			gateway := &MockGatewayer{}
+			if tc.req != nil {
+				gateway.On("SetWalletOutputLabel", tc.req.ID, hash, tc.req.Label).Return(tc.gatewayOutput, tc.gatewayErr)
+			}
+
+			if tc.body == "" && tc.req != nil {
+				tc.body = toJSON(t, tc.req)
+			}
+
+			req, err := http.NewRequest(tc.method, "/api/v2/wallet/outputs/label", strings.NewReader(tc.body))
+			require.NoError(t, err)
+			req.Header.Set("Content-Type", ContentTypeJSON)
+			setCSRFParameters(t, tokenValid, req)
+
+			rr := httptest.NewRecorder()
+			cfg := defaultMuxConfig()
+			cfg.disableCSRF = false
+			handler := newServerMux(cfg, gateway)
+			handler.ServeHTTP(rr, req)
+
+			requireWalletOutputsResponse(t, rr, tc.status, tc.httpResponse)
+		})
+	}
+}
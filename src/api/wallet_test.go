@@ -17,14 +17,16 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/visor"
+	"github.com/ness-network/ness/src/wallet/watch"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/cipher/bip39"
 	"github.com/skycoin/skycoin/src/cipher/crypto"
 	"github.com/skycoin/skycoin/src/coin"
-	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/testutil"
-	"github.com/skycoin/skycoin/src/visor"
 	"github.com/skycoin/skycoin/src/wallet"
+	"github.com/skycoin/skycoin/src/wallet/bip44wallet"
 	"github.com/skycoin/skycoin/src/wallet/deterministic"
 )
 
@@ -873,6 +875,7 @@ func TestWalletTransactionsHandler(t *testing.T) {
 
 func TestWalletCreateHandler(t *testing.T) {
 	_, responseEntries := makeEntries([]byte("seed"), 5)
+	watchAddrs := responseEntries[0].Address + "," + responseEntries[1].Address
 	type httpBody struct {
 		Seed           string
 		Label          string
@@ -883,6 +886,7 @@ func TestWalletCreateHandler(t *testing.T) {
 		SeedPassphrase string
 		Bip44Coin      string
 		XPub           string
+		Addresses      string
 	}
 	tt := []struct {
 		name                      string
@@ -1213,6 +1217,78 @@ func TestWalletCreateHandler(t *testing.T) {
 				Entries: responseEntries[:],
 			},
 		},
+		{
+			name:   "200 - OK - watch",
+			method: http.MethodPost,
+			body: &httpBody{
+				Type:      watch.WalletType,
+				Label:     "bar",
+				Addresses: watchAddrs,
+			},
+			status:  http.StatusOK,
+			wltName: "filename",
+			options: wallet.Options{
+				Type:     watch.WalletType,
+				Label:    "bar",
+				Seed:     watchAddrs,
+				Password: []byte{},
+			},
+			gatewayCreateWalletResult: func(_ string, opts wallet.Options) wallet.Wallet {
+				addrs, err := watch.ParseAddresses(wallet.CoinTypeSkycoin, opts.Seed)
+				require.NoError(t, err)
+				w, err := watch.NewWallet("filename", opts.Label, addrs)
+				require.NoError(t, err)
+				w.SetTimestamp(0)
+				return w
+			},
+			responseBody: WalletResponse{
+				Meta: readable.WalletMeta{
+					Coin:     "skycoin",
+					Label:    "bar",
+					Filename: "filename",
+					Type:     watch.WalletType,
+					Version:  "0.4",
+				},
+				Entries: []readable.WalletEntry{
+					{Address: responseEntries[0].Address},
+					{Address: responseEntries[1].Address},
+				},
+			},
+		},
+		{
+			name:   "400 - watch - missing addresses",
+			method: http.MethodPost,
+			body: &httpBody{
+				Type:  watch.WalletType,
+				Label: "bar",
+			},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - missing addresses",
+		},
+		{
+			name:   "400 - watch - seed",
+			method: http.MethodPost,
+			body: &httpBody{
+				Type:      watch.WalletType,
+				Label:     "bar",
+				Seed:      "foo",
+				Addresses: watchAddrs,
+			},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - seed is not used by watch wallets",
+		},
+		{
+			name:   "400 - addresses with other wallet type",
+			method: http.MethodPost,
+			body: &httpBody{
+				Type:      wallet.WalletTypeDeterministic,
+				Label:     "bar",
+				Seed:      "foo",
+				Addresses: watchAddrs,
+			},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - addresses is only valid for watch type wallets",
+		},
 		// CSRF Tests
 		{
 			name:   "200 - OK - CSRF disabled",
@@ -1347,6 +1423,10 @@ func TestWalletCreateHandler(t *testing.T) {
 				if tc.body.XPub != "" {
 					v.Add("xpub", tc.body.XPub)
 				}
+
+				if tc.body.Addresses != "" {
+					v.Add("addresses", tc.body.Addresses)
+				}
 			}
 
 			req, err := http.NewRequest(tc.method, endpoint, strings.NewReader(v.Encode()))
@@ -1794,6 +1874,8 @@ func TestWalletNewAddressesHandler(t *testing.T) {
 		ID       string
 		Num      string
 		Password string
+		Account  string
+		Change   string
 	}
 	type Addresses struct {
 		Address []string `json:"addresses"`
@@ -1811,6 +1893,11 @@ func TestWalletNewAddressesHandler(t *testing.T) {
 		responseAddresses.Address = append(responseAddresses.Address, addrs[i].String())
 	}
 
+	bip44Wlt, err := bip44wallet.NewWallet("bip44.wlt", "bip44", bip39.MustNewDefaultMnemonic(), "")
+	require.NoError(t, err)
+	detWlt, err := deterministic.NewWallet("foo", "foo", "seed")
+	require.NoError(t, err)
+
 	tt := []struct {
 		name                      string
 		method                    string
@@ -1822,6 +1909,10 @@ func TestWalletNewAddressesHandler(t *testing.T) {
 		password                  string
 		gatewayNewAddressesResult []cipher.Address
 		gatewayNewAddressesErr    error
+		gatewayGetWalletResult    wallet.Wallet
+		gatewayGetWalletErr       error
+		account                   *uint32
+		change                    bool
 		responseBody              Addresses
 		csrfDisabled              bool
 	}{
@@ -1966,6 +2057,68 @@ func TestWalletNewAddressesHandler(t *testing.T) {
 			responseBody:              responseAddresses,
 			csrfDisabled:              true,
 		},
+		{
+			name:   "400 - invalid account value",
+			method: http.MethodPost,
+			body: &httpBody{
+				ID:      "foo",
+				Account: "-1",
+			},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - invalid account value",
+		},
+		{
+			name:   "400 - invalid change value",
+			method: http.MethodPost,
+			body: &httpBody{
+				ID:     "foo",
+				Change: "bar",
+			},
+			status: http.StatusBadRequest,
+			err:    "400 Bad Request - invalid change value",
+		},
+		{
+			name:   "400 - account of a deterministic wallet",
+			method: http.MethodPost,
+			body: &httpBody{
+				ID:      "foo",
+				Account: "1",
+			},
+			status:                 http.StatusBadRequest,
+			err:                    "400 Bad Request - account and change are only valid for bip44 wallets",
+			walletID:               "foo",
+			gatewayGetWalletResult: detWlt,
+		},
+		{
+			name:   "404 - account of a missing wallet",
+			method: http.MethodPost,
+			body: &httpBody{
+				ID:     "foo",
+				Change: "true",
+			},
+			status:              http.StatusNotFound,
+			err:                 "404 Not Found",
+			walletID:            "foo",
+			gatewayGetWalletErr: wallet.ErrWalletNotExist,
+		},
+		{
+			name:   "200 - OK - account change addresses",
+			method: http.MethodPost,
+			body: &httpBody{
+				ID:      "foo",
+				Num:     "1",
+				Account: "1",
+				Change:  "true",
+			},
+			status:                    http.StatusOK,
+			walletID:                  "foo",
+			n:                         1,
+			account:                   func() *uint32 { a := uint32(1); return &a }(),
+			change:                    true,
+			gatewayGetWalletResult:    bip44Wlt,
+			gatewayNewAddressesResult: addrs,
+			responseBody:              responseAddresses,
+		},
 	}
 
 	for _, tc := range tt {
@@ -1975,8 +2128,24 @@ func TestWalletNewAddressesHandler(t *testing.T) {
 				return tc.n == n
 			})
 			gateway := &MockGatewayer{}
-			gateway.On("NewAddresses", tc.walletID,
-				[]byte(tc.password), mb).Return(tc.gatewayNewAddressesResult, tc.gatewayNewAddressesErr)
+			gateway.On("GetWallet", tc.walletID).Return(tc.gatewayGetWalletResult, tc.gatewayGetWalletErr)
+
+			args := []interface{}{tc.walletID, []byte(tc.password), mb}
+			if tc.account != nil {
+				args = append(args, mock.MatchedBy(func(option wallet.Option) bool {
+					var opts wallet.Bip44EntriesOptions
+					option(&opts)
+					return opts.Account == *tc.account
+				}))
+			}
+			if tc.change {
+				args = append(args, mock.MatchedBy(func(option wallet.Option) bool {
+					var opts wallet.Bip44EntriesOptions
+					option(&opts)
+					return opts.ChainMode == wallet.ChangeChain
+				}))
+			}
+			gateway.On("NewAddresses", args...).Return(tc.gatewayNewAddressesResult, tc.gatewayNewAddressesErr)
 
 			endpoint := "/api/v1/wallet/newAddress"
 
@@ -1988,6 +2157,12 @@ func TestWalletNewAddressesHandler(t *testing.T) {
 				if tc.body.Num != "" {
 					v.Add("num", tc.body.Num)
 				}
+				if tc.body.Account != "" {
+					v.Add("account", tc.body.Account)
+				}
+				if tc.body.Change != "" {
+					v.Add("change", tc.body.Change)
+				}
 			}
 
 			req, err := http.NewRequest(tc.method, endpoint, strings.NewReader(v.Encode()))
@@ -3030,6 +3205,23 @@ func TestWalletRecover(t *testing.T) {
 	okWalletEncryptedResponse, err := NewWalletResponse(okWalletEncrypted)
 	require.NoError(t, err)
 
+	bip44Seed := "voyage say extend find sheriff surge priority merit ignore maple cash argue"
+	okWalletBip44, err := wallet.NewWallet(
+		"foo",
+		"foolabel",
+		bip44Seed,
+		wallet.Options{
+			Type:      wallet.WalletTypeBip44,
+			Coin:      wallet.CoinTypeSkycoin,
+			GenerateN: 1,
+		})
+	require.NoError(t, err)
+	discoveredWalletBip44 := okWalletBip44.Clone()
+	_, err = discoveredWalletBip44.GenerateAddresses(wallet.OptionGenerateN(30))
+	require.NoError(t, err)
+	discoveredWalletBip44Response, err := NewWalletResponse(discoveredWalletBip44)
+	require.NoError(t, err)
+
 	cases := []struct {
 		name          string
 		method        string
@@ -3039,6 +3231,8 @@ func TestWalletRecover(t *testing.T) {
 		httpBody      string
 		httpResponse  HTTPResponse
 		gatewayReturn gatewayReturnPair
+		discoverErr   error
+		discovered    wallet.Wallet
 	}{
 		{
 			name:         "method not allowed",
@@ -3204,6 +3398,39 @@ func TestWalletRecover(t *testing.T) {
 				Data: *okWalletEncryptedResponse,
 			},
 		},
+		{
+			name:        "bip44 discovery error",
+			method:      http.MethodPost,
+			status:      http.StatusInternalServerError,
+			contentType: ContentTypeJSON,
+			req: &WalletRecoverRequest{
+				ID:   "foo",
+				Seed: bip44Seed,
+			},
+			gatewayReturn: gatewayReturnPair{
+				w: okWalletBip44,
+			},
+			discoverErr:  errors.New("discovery failed"),
+			httpResponse: NewHTTPErrorResponse(http.StatusInternalServerError, "discovery failed"),
+		},
+		{
+			name:        "ok, bip44 gap limit",
+			method:      http.MethodPost,
+			status:      http.StatusOK,
+			contentType: ContentTypeJSON,
+			req: &WalletRecoverRequest{
+				ID:       "foo",
+				Seed:     bip44Seed,
+				GapLimit: 30,
+			},
+			gatewayReturn: gatewayReturnPair{
+				w: okWalletBip44,
+			},
+			discovered: discoveredWalletBip44,
+			httpResponse: HTTPResponse{
+				Data: *discoveredWalletBip44Response,
+			},
+		},
 	}
 
 	for _, tc := range cases {
@@ -3215,6 +3442,8 @@ func TestWalletRecover(t *testing.T) {
 					password = []byte(tc.req.Password)
 				}
 				gateway.On("RecoverWallet", tc.req.ID, tc.req.Seed, tc.req.SeedPassphrase, password).Return(tc.gatewayReturn.w, tc.gatewayReturn.err)
+				gateway.On("DiscoverWalletAddresses", tc.req.ID, password, tc.req.GapLimit).Return(nil, tc.discoverErr)
+				gateway.On("GetWallet", tc.req.ID).Return(tc.discovered, nil)
 			}
 
 			if tc.httpBody == "" && tc.req != nil {
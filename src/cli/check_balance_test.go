@@ -5,7 +5,7 @@ import (
 
 	"github.com/stretchr/testify/require"
 
-	"github.com/skycoin/skycoin/src/readable"
+	"github.com/ness-network/ness/src/readable"
 	"github.com/skycoin/skycoin/src/testutil"
 )
 
@@ -1,16 +1,18 @@
 package cli
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/spf13/cobra"
 
+	"github.com/ness-network/ness/src/visor"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/util/apputil"
-	"github.com/skycoin/skycoin/src/visor"
 	"github.com/skycoin/skycoin/src/visor/dbutil"
 )
 
@@ -30,19 +32,55 @@ func wrapDB(db *bolt.DB) *dbutil.DB {
 }
 
 func checkDBCmd() *cobra.Command {
-	return &cobra.Command{
+	checkDBCmd := &cobra.Command{
 		Short: "Verify the database",
 		Use:   "checkdb [db path]",
 		Long: `Checks if the given database file contains valid skycoin blockchain data.
-    If no argument is specificed, the default data.db in $HOME/.$COIN/ will be checked.`,
-		Args:                  cobra.MaximumNArgs(1),
-		DisableFlagsInUseLine: true,
-		SilenceUsage:          true,
-		RunE:                  checkDB,
+    If no argument is specificed, the default data.db in $HOME/.$COIN/ will be checked.
+    Blocks signed by block publishers are checked against the publisher public keys and quorum
+    that the node runs with.`,
+		Args:         cobra.MaximumNArgs(1),
+		SilenceUsage: true,
+		RunE:         checkDB,
+	}
+
+	checkDBCmd.Flags().String("publisher-public-keys", "", "Comma separated list of the public keys of the block publishers")
+	checkDBCmd.Flags().Int("publisher-quorum", 0, "Number of block publishers that must sign a block. 0 means a majority of the publishers")
+
+	return checkDBCmd
+}
+
+// parsePublisherFlags parses the block publisher public keys and quorum flags
+func parsePublisherFlags(c *cobra.Command) ([]cipher.PubKey, int, error) {
+	pubkeysStr, err := c.Flags().GetString("publisher-public-keys")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var pubkeys []cipher.PubKey
+	if pubkeysStr != "" {
+		for _, pk := range strings.Split(pubkeysStr, ",") {
+			pubkey, err := cipher.PubKeyFromHex(strings.TrimSpace(pk))
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid publisher public key %q: %v", pk, err)
+			}
+			pubkeys = append(pubkeys, pubkey)
+		}
+	}
+
+	quorum, err := c.Flags().GetInt("publisher-quorum")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	if quorum < 0 || quorum > len(pubkeys) {
+		return nil, 0, errors.New("publisher-quorum must be between 0 and the number of publisher-public-keys")
 	}
+
+	return pubkeys, quorum, nil
 }
 
-func checkDB(_ *cobra.Command, args []string) error {
+func checkDB(c *cobra.Command, args []string) error {
 	// get db path
 	dbPath := ""
 	if len(args) > 0 {
@@ -71,11 +109,16 @@ func checkDB(_ *cobra.Command, args []string) error {
 		return fmt.Errorf("decode blockchain pubkey failed: %v", err)
 	}
 
+	publisherPubkeys, publisherQuorum, err := parsePublisherFlags(c)
+	if err != nil {
+		return err
+	}
+
 	go func() {
 		apputil.CatchInterrupt(quitChan)
 	}()
 
-	if err := visor.CheckDatabase(wrapDB(db), pubkey, quitChan); err != nil {
+	if err := visor.CheckDatabase(wrapDB(db), pubkey, publisherPubkeys, publisherQuorum, quitChan); err != nil {
 		if err == visor.ErrVerifyStopped {
 			return nil
 		}
@@ -21,7 +21,7 @@ import (
 	"github.com/spf13/pflag"
 	"golang.org/x/crypto/ssh/terminal"
 
-	"github.com/skycoin/skycoin/src/api"
+	"github.com/ness-network/ness/src/api"
 	"github.com/skycoin/skycoin/src/util/file"
 	"github.com/skycoin/skycoin/src/wallet"
 )
@@ -195,6 +195,9 @@ func NewCLI(cfg Config) (*cobra.Command, error) {
 		lastBlocksCmd(),
 		listAddressesCmd(),
 		listWalletsCmd(),
+		networkBansCmd(),
+		networkBanCmd(),
+		networkUnbanCmd(),
 		sendCmd(),
 		showConfigCmd(),
 		showSeedCmd(),
@@ -203,17 +206,28 @@ func NewCLI(cfg Config) (*cobra.Command, error) {
 		verifyTransactionCmd(),
 		verifyAddressCmd(),
 		versionCmd(),
+		walletBumpFeeCmd(),
 		walletCreateCmd(),
 		walletCreateTempCmd(),
+		walletAccountsCmd(),
+		walletAccountCreateCmd(),
 		walletAddAddressesCmd(),
 		walletScanAddressesCmd(),
 		walletKeyExportCmd(),
 		walletBalanceCmd(),
 		walletHisCmd(),
 		walletOutputsCmd(),
+		walletCoinControlCmd(),
+		walletFreezeOutputsCmd(),
+		walletUnfreezeOutputsCmd(),
+		walletLabelOutputCmd(),
 		richlistCmd(),
 		addressTransactionsCmd(),
 		pendingTransactionsCmd(),
+		psbtCreateCmd(),
+		psbtSignCmd(),
+		psbtCombineCmd(),
+		psbtFinalizeCmd(),
 		addresscountCmd(),
 		distributeGenesisCmd(),
 	}
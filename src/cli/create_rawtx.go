@@ -10,11 +10,11 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"github.com/skycoin/skycoin/src/api"
+	"github.com/ness-network/ness/src/api"
+	"github.com/ness-network/ness/src/readable"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/params"
-	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/transaction"
 	"github.com/skycoin/skycoin/src/util/droplet"
 	"github.com/skycoin/skycoin/src/util/fee"
@@ -149,6 +149,8 @@ func createRawTxnV2Cmd() *cobra.Command {
 	createRawTxnCmd.Flags().String("csv", "", "CSV file containing addresses and amounts to send")
 	createRawTxnCmd.Flags().StringP("password", "p", "", "Wallet password")
 	createRawTxnCmd.Flags().BoolP("unsign", "", false, "Do not sign the transaction")
+	createRawTxnCmd.Flags().Uint32P("account", "", 0, `Spend the coins of this bip44 account.
+	The change is returned to the change chain of the account.`)
 	createRawTxnCmd.Flags().BoolP("json", "j", false, "Returns the results in JSON format.")
 
 	createRawTxnCmd.Flags().BoolP("ignore-unconfirmed", "", false, "Ignore unconfirmed transactions")
@@ -176,10 +178,21 @@ func makeWalletCreateTxnRequest(c *cobra.Command, args []string) (*api.WalletCre
 		return nil, err
 	}
 
+	var account *uint32
+	if c.Flags().Changed("account") {
+		a, err := c.Flags().GetUint32("account")
+		if err != nil {
+			return nil, err
+		}
+		account = &a
+	}
+
+	// The wallet entries are the addresses of account 0 only,
+	// the node spends from all addresses of the account if none are set
 	var addrs []string
 	if wltAddr.Address != "" {
 		addrs = append(addrs, wltAddr.Address)
-	} else {
+	} else if account == nil {
 		for _, e := range w.Entries {
 			addrs = append(addrs, e.Address)
 		}
@@ -193,6 +206,7 @@ func makeWalletCreateTxnRequest(c *cobra.Command, args []string) (*api.WalletCre
 	req := api.WalletCreateTransactionRequest{
 		Unsigned:                 unsign,
 		WalletID:                 w.Meta.Filename,
+		Account:                  account,
 		CreateTransactionRequest: *ctr,
 	}
 
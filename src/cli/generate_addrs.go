@@ -25,7 +25,9 @@ func walletAddAddressesCmd() *cobra.Command {
     if you load the wallet from seed elsewhere. In that case, you'll have to manually
     generate addresses to cover the gap of unused addresses in the sequence.
 
-    BIP44 wallets generate their addresses on the external (0'/0) chain.
+    BIP44 wallets generate their addresses on the external (0'/0) chain of account 0.
+    Use --account to generate addresses of another account and --change to generate
+    addresses on the change chain of the account.
 
     Use caution when using the "-p" command. If you have command
     history enabled your wallet encryption password can be recovered from the
@@ -38,6 +40,8 @@ func walletAddAddressesCmd() *cobra.Command {
 	walletAddAddressesCmd.Flags().StringP("password", "p", "", "wallet password")
 	walletAddAddressesCmd.Flags().BoolP("json", "j", false, "Returns the results in JSON format")
 	walletAddAddressesCmd.Flags().StringP("private-keys", "", "", "wallet private keys for collection wallet")
+	walletAddAddressesCmd.Flags().Uint32P("account", "", 0, "bip44 account of the addresses")
+	walletAddAddressesCmd.Flags().BoolP("change", "", false, "Generate addresses on the change chain of the bip44 account")
 
 	return walletAddAddressesCmd
 }
@@ -82,6 +86,27 @@ func generateAddrs(c *cobra.Command, args []string) error {
 		opts = append(opts, wallet.OptionGenerateN(num))
 	}
 
+	account, err := c.Flags().GetUint32("account")
+	if err != nil {
+		return err
+	}
+
+	change, err := c.Flags().GetBool("change")
+	if err != nil {
+		return err
+	}
+
+	if c.Flags().Changed("account") || change {
+		if wlt.Meta.Type != wallet.WalletTypeBip44 {
+			return errors.New("--account and --change are only valid for bip44 wallets")
+		}
+
+		opts = append(opts, wallet.OptionAccount(account))
+		if change {
+			opts = append(opts, wallet.OptionChange())
+		}
+	}
+
 	var pwd []byte
 	pr := NewPasswordReader([]byte(c.Flag("password").Value.String()))
 	if wlt.Meta.Encrypted && wlt.Meta.Type != wallet.WalletTypeBip44 {
@@ -7,7 +7,8 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"github.com/skycoin/skycoin/src/api"
+	"github.com/ness-network/ness/src/api"
+	"github.com/ness-network/ness/src/wallet/watch"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/cipher/bip39"
 	"github.com/skycoin/skycoin/src/cipher/bip44"
@@ -45,11 +46,12 @@ func walletCreateCmd() *cobra.Command {
 	walletCreateCmd.Flags().Uint32P("bip44-coin", "", uint32(bip44.CoinTypeSkycoin), "BIP44 coin type")
 	walletCreateCmd.Flags().Uint64P("num", "n", 1, `Number of addresses to generate.`)
 	walletCreateCmd.Flags().Uint64P("scan", "", 1, `Number of addresses to scan ahead for balances.`)
-	walletCreateCmd.Flags().StringP("type", "t", wallet.WalletTypeDeterministic, "Wallet type. Types are \"collection\", \"deterministic\", \"bip44\" or \"xpub\"")
+	walletCreateCmd.Flags().StringP("type", "t", wallet.WalletTypeDeterministic, "Wallet type. Types are \"collection\", \"deterministic\", \"bip44\", \"xpub\" or \"watch\"")
 	walletCreateCmd.Flags().BoolP("encrypt", "e", true, "Create encrypted wallet.")
 	walletCreateCmd.Flags().StringP("password", "p", "", "Wallet password")
 	walletCreateCmd.Flags().StringP("xpub", "", "", "xpub key for \"xpub\" type wallets")
 	walletCreateCmd.Flags().StringP("private-keys", "", "", "Collection private keys")
+	walletCreateCmd.Flags().StringP("addresses", "", "", "Addresses of \"watch\" type wallets, joined with commas")
 
 	return walletCreateCmd
 }
@@ -116,7 +118,7 @@ func generateWalletHandler(c *cobra.Command, _ []string) error {
 	if err != nil {
 		return err
 	}
-	if !wallet.IsValidWalletType(walletType) {
+	if !wallet.IsValidWalletType(walletType) && walletType != watch.WalletType {
 		return wallet.ErrInvalidWalletType
 	}
 
@@ -136,6 +138,14 @@ func generateWalletHandler(c *cobra.Command, _ []string) error {
 		return err
 	}
 
+	addresses, err := c.Flags().GetString("addresses")
+	if err != nil {
+		return err
+	}
+	if addresses != "" && walletType != watch.WalletType {
+		return fmt.Errorf("--addresses is only valid for %q type wallets", watch.WalletType)
+	}
+
 	var (
 		sd                    string
 		collectionPrivateKeys string
@@ -182,6 +192,21 @@ func generateWalletHandler(c *cobra.Command, _ []string) error {
 			return fmt.Errorf("%q type wallets do not use seeds", walletType)
 		}
 
+	case watch.WalletType:
+		// watch wallet does not support encryption
+		encrypt = false
+		if s != "" || random || mnemonic {
+			return fmt.Errorf("%q type wallets do not use seeds", walletType)
+		}
+		if c.Flags().Changed("num") {
+			return fmt.Errorf("%q type wallets do not support address generation", walletType)
+		}
+		num = 0
+
+		if _, err := watch.ParseAddresses(wallet.CoinTypeSkycoin, addresses); err != nil {
+			return err
+		}
+
 	default:
 		return fmt.Errorf("unhandled wallet type %q", walletType)
 	}
@@ -212,6 +237,7 @@ func generateWalletHandler(c *cobra.Command, _ []string) error {
 		ScanN:                 scan,
 		XPub:                  xpub,
 		CollectionPrivateKeys: collectionPrivateKeys,
+		Addresses:             addresses,
 	}
 
 	wlt, err := apiClient.CreateWallet(opts)
@@ -231,9 +257,8 @@ func generateWalletHandler(c *cobra.Command, _ []string) error {
 		}
 	}
 
-	n := num - uint64(addrN)
-	if n > 0 {
-		_, err := apiClient.NewWalletAddress(id, string(password), wallet.OptionGenerateN(n))
+	if num > uint64(addrN) {
+		_, err := apiClient.NewWalletAddress(id, string(password), wallet.OptionGenerateN(num-uint64(addrN)))
 		if err != nil {
 			return err
 		}
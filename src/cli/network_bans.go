@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+func networkBansCmd() *cobra.Command {
+	return &cobra.Command{
+		Args:  cobra.NoArgs,
+		Short: "List banned peers",
+		Use:   "networkBans",
+		Long: `List the hosts banned by the node, with the reason for the ban and the
+    unix time when it expires. Peers are banned automatically when their
+    misbehavior score reaches the node's ban threshold.`,
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		RunE: func(c *cobra.Command, args []string) error {
+			rsp, err := apiClient.NetworkBans()
+			if err != nil {
+				return err
+			}
+
+			return printJSON(rsp)
+		},
+	}
+}
+
+func networkBanCmd() *cobra.Command {
+	networkBanCmd := &cobra.Command{
+		Args:  cobra.ExactArgs(1),
+		Short: "Ban a peer",
+		Use:   "networkBan [address]",
+		Long: `Ban a host and disconnect its connections. The address can be an ip:port,
+    an IP address, an onion address or an IPv6 /64 prefix. IPv6 addresses are
+    banned by their /64 prefix.
+
+    This command requires the NET_CTRL API set to be enabled on the node.`,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			duration, err := c.Flags().GetDuration("duration")
+			if err != nil {
+				return err
+			}
+
+			reason, err := c.Flags().GetString("reason")
+			if err != nil {
+				return err
+			}
+
+			rsp, err := apiClient.BanPeer(args[0], duration, reason)
+			if err != nil {
+				return err
+			}
+
+			return printJSON(rsp)
+		},
+	}
+
+	networkBanCmd.Flags().DurationP("duration", "d", 0, "Duration of the ban, e.g. 12h. Defaults to the node's ban duration")
+	networkBanCmd.Flags().StringP("reason", "r", "", "Reason for the ban")
+
+	return networkBanCmd
+}
+
+func networkUnbanCmd() *cobra.Command {
+	return &cobra.Command{
+		Args:  cobra.ExactArgs(1),
+		Short: "Remove the ban of a peer",
+		Use:   "networkUnban [address]",
+		Long: `Remove the ban of a host. The address can be an ip:port, an IP address,
+    an onion address or an IPv6 /64 prefix.
+
+    This command requires the NET_CTRL API set to be enabled on the node.`,
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		RunE: func(c *cobra.Command, args []string) error {
+			if err := apiClient.UnbanPeer(args[0]); err != nil {
+				return err
+			}
+
+			fmt.Println("success")
+			return nil
+		},
+	}
+}
@@ -0,0 +1,350 @@
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ness-network/ness/src/api"
+	"github.com/ness-network/ness/src/psbt"
+	"github.com/ness-network/ness/src/readable"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/transaction"
+	"github.com/skycoin/skycoin/src/util/droplet"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func psbtCreateCmd() *cobra.Command {
+	psbtCreateCmd := &cobra.Command{
+		Short: "Create a partially signed transaction to be signed offline",
+		Use:   "psbtCreate [wallet] [to address] [amount]",
+		Long: `Create an unsigned transaction spending outputs of a wallet and write it
+    as a partially signed transaction packet.
+
+    The packet holds the outputs spent by the transaction and, for each input, the
+    bip44 derivation path of its address and a hint of the wallet that owns it.
+    It can be created by a node with a watch or xpub wallet, signed with psbtSign
+    on nodes that hold the keys, merged with psbtCombine and turned into a raw
+    transaction with psbtFinalize.
+
+    Note: The [amount] argument is the coins you will spend, with decimal formatting, e.g. 1, 1.001 or 1.000000.
+
+    The [to address] and [amount] arguments can be replaced with the --csv option.`,
+		SilenceUsage: true,
+		Args:         cobra.MinimumNArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			w, err := apiClient.Wallet(args[0])
+			if err != nil {
+				return err
+			}
+
+			wltAddr, err := fromWalletOrAddress(c, args[0])
+			if err != nil {
+				return err
+			}
+
+			var addrs []string
+			if wltAddr.Address != "" {
+				addrs = append(addrs, wltAddr.Address)
+			} else {
+				for _, e := range w.Entries {
+					addrs = append(addrs, e.Address)
+				}
+			}
+
+			ctr, err := makeCreateTransactionRequest(c, args, addrs)
+			if err != nil {
+				return err
+			}
+
+			rsp, err := apiClient.WalletCreateTransaction(api.WalletCreateTransactionRequest{
+				Unsigned:                 true,
+				WalletID:                 w.Meta.Filename,
+				CreateTransactionRequest: *ctr,
+			})
+			if err != nil {
+				return err
+			}
+
+			pkt, err := makePacket(w, rsp)
+			if err != nil {
+				return err
+			}
+
+			return writePacket(c, pkt)
+		},
+	}
+
+	psbtCreateCmd.Flags().StringP("from-address", "a", "", "From address in wallet")
+	psbtCreateCmd.Flags().StringP("change-address", "c", "", `Specify the change address.
+	Defaults to one of the spending addresses (deterministic wallets) or to a new change address (bip44 wallets).`)
+	psbtCreateCmd.Flags().String("csv", "", "CSV file containing addresses and amounts to send")
+	psbtCreateCmd.Flags().StringP("output", "o", "", "Write the packet to this file instead of the standard output")
+
+	psbtCreateCmd.Flags().BoolP("ignore-unconfirmed", "", false, "Ignore unconfirmed transactions")
+	psbtCreateCmd.Flags().StringP("hours-selection-type", "", transaction.HoursSelectionTypeAuto, "Hours selection type")
+	psbtCreateCmd.Flags().StringP("hours-selection-mode", "", transaction.HoursSelectionModeShare, "Hours selection mode")
+	psbtCreateCmd.Flags().StringP("hours-selection-share-factor", "", "0.5", "Hour selection share factor")
+
+	return psbtCreateCmd
+}
+
+func psbtSignCmd() *cobra.Command {
+	psbtSignCmd := &cobra.Command{
+		Args:  cobra.ExactArgs(2),
+		Short: "Sign the inputs of a partially signed transaction that belong to a wallet",
+		Use:   "psbtSign [wallet] [packet file]",
+		Long: `Sign the unsigned inputs of a partially signed transaction packet that spend
+    outputs of a wallet, and write the signed packet.
+
+    The packet holds the outputs being spent, so the node does not need to be
+    synchronized or connected to the network.
+
+    Use caution when using the "-p" command. If you have command history enabled
+    your wallet encryption password can be recovered from the history log.
+    If you do not include the "-p" option you will be prompted to enter your password
+    after you enter your command.`,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			pkt, err := readPacket(args[1])
+			if err != nil {
+				return err
+			}
+
+			w, err := apiClient.Wallet(args[0])
+			if err != nil {
+				return err
+			}
+
+			req := api.WalletSignPacketRequest{
+				WalletID: w.Meta.Filename,
+				Packet:   pkt,
+			}
+
+			if w.Meta.Encrypted {
+				p, err := getPassword(c)
+				if err != nil {
+					return err
+				}
+				defer func() {
+					p = nil
+				}()
+				req.Password = string(p)
+			}
+
+			rsp, err := apiClient.WalletSignPacket(req)
+			if err != nil {
+				return err
+			}
+
+			return writePacket(c, rsp.Packet)
+		},
+	}
+
+	psbtSignCmd.Flags().StringP("password", "p", "", "Wallet password")
+	psbtSignCmd.Flags().StringP("output", "o", "", "Write the packet to this file instead of the standard output")
+
+	return psbtSignCmd
+}
+
+func psbtCombineCmd() *cobra.Command {
+	psbtCombineCmd := &cobra.Command{
+		Args:  cobra.MinimumNArgs(2),
+		Short: "Combine the signatures of partially signed transactions",
+		Use:   "psbtCombine [packet file] [packet file]...",
+		Long: `Combine partially signed transaction packets of the same transaction, signed
+    by different wallets, into one packet holding all of their signatures.`,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			pkts := make([]*psbt.Packet, len(args))
+			for i, f := range args {
+				pkt, err := readPacket(f)
+				if err != nil {
+					return err
+				}
+				pkts[i] = pkt
+			}
+
+			pkt, err := psbt.Combine(pkts...)
+			if err != nil {
+				return err
+			}
+
+			return writePacket(c, pkt)
+		},
+	}
+
+	psbtCombineCmd.Flags().StringP("output", "o", "", "Write the packet to this file instead of the standard output")
+
+	return psbtCombineCmd
+}
+
+func psbtFinalizeCmd() *cobra.Command {
+	psbtFinalizeCmd := &cobra.Command{
+		Args:  cobra.ExactArgs(1),
+		Short: "Create a raw transaction from a fully signed partially signed transaction",
+		Use:   "psbtFinalize [packet file]",
+		Long: `Create a raw transaction from a partially signed transaction packet whose inputs
+    are all signed. The raw transaction can be broadcast with broadcastTransaction.`,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			pkt, err := readPacket(args[0])
+			if err != nil {
+				return err
+			}
+
+			txn, err := pkt.Finalize()
+			if err != nil {
+				return err
+			}
+
+			rawTxn, err := txn.SerializeHex()
+			if err != nil {
+				return err
+			}
+
+			jsonOutput, err := c.Flags().GetBool("json")
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				return printJSON(struct {
+					RawTx string `json:"rawtx"`
+				}{
+					RawTx: rawTxn,
+				})
+			}
+
+			fmt.Println(rawTxn)
+			return nil
+		},
+	}
+
+	psbtFinalizeCmd.Flags().BoolP("json", "j", false, "Returns the results in JSON format.")
+
+	return psbtFinalizeCmd
+}
+
+// makePacket creates a packet from an unsigned transaction of the wallet w
+func makePacket(w *api.WalletResponse, rsp *api.CreateTransactionResponse) (*psbt.Packet, error) {
+	txn, err := coin.DeserializeTransactionHex(rsp.EncodedTransaction)
+	if err != nil {
+		return nil, err
+	}
+
+	uxOuts := make([]coin.UxOut, len(rsp.Transaction.In))
+	for i, in := range rsp.Transaction.In {
+		ux, err := createdInputToUxOut(in)
+		if err != nil {
+			return nil, err
+		}
+		uxOuts[i] = ux
+	}
+
+	pkt, err := psbt.New(txn, uxOuts)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make(map[string]readable.WalletEntry, len(w.Entries))
+	for _, e := range w.Entries {
+		entries[e.Address] = e
+	}
+
+	for i := range pkt.Inputs {
+		in := &pkt.Inputs[i]
+		e, ok := entries[in.UxOut.Body.Address.String()]
+		if !ok {
+			continue
+		}
+
+		in.WalletHint = w.Meta.Filename
+		switch w.Meta.Type {
+		case wallet.WalletTypeBip44:
+			if w.Meta.Bip44Coin != nil && e.ChildNumber != nil && e.Change != nil {
+				in.Bip44Path = fmt.Sprintf("m/44'/%d'/0'/%d/%d", *w.Meta.Bip44Coin, *e.Change, *e.ChildNumber)
+			}
+		case wallet.WalletTypeXPub:
+			in.WalletHint = w.Meta.XPub
+			if e.ChildNumber != nil {
+				in.Bip44Path = fmt.Sprintf("M/%d", *e.ChildNumber)
+			}
+		}
+	}
+
+	return pkt, nil
+}
+
+// createdInputToUxOut recovers the UxOut of an input of a created transaction
+func createdInputToUxOut(in api.CreatedTransactionInput) (coin.UxOut, error) {
+	addr, err := cipher.DecodeBase58Address(in.Address)
+	if err != nil {
+		return coin.UxOut{}, err
+	}
+
+	coins, err := droplet.FromString(in.Coins)
+	if err != nil {
+		return coin.UxOut{}, err
+	}
+
+	hours, err := strconv.ParseUint(in.Hours, 10, 64)
+	if err != nil {
+		return coin.UxOut{}, err
+	}
+
+	srcTxn, err := cipher.SHA256FromHex(in.TxID)
+	if err != nil {
+		return coin.UxOut{}, err
+	}
+
+	ux := coin.UxOut{
+		Head: coin.UxHead{
+			Time:  in.Time,
+			BkSeq: in.Block,
+		},
+		Body: coin.UxBody{
+			SrcTransaction: srcTxn,
+			Address:        addr,
+			Coins:          coins,
+			Hours:          hours,
+		},
+	}
+
+	if ux.Hash().Hex() != in.UxID {
+		return coin.UxOut{}, fmt.Errorf("input %s does not match the output returned by the node", in.UxID)
+	}
+
+	return ux, nil
+}
+
+func readPacket(filename string) (*psbt.Packet, error) {
+	b, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	return psbt.Deserialize(b)
+}
+
+// writePacket writes the packet to the file set by the --output flag, or to the standard output
+func writePacket(c *cobra.Command, pkt *psbt.Packet) error {
+	b, err := pkt.Serialize()
+	if err != nil {
+		return err
+	}
+
+	output, err := c.Flags().GetString("output")
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		fmt.Println(string(b))
+		return nil
+	}
+
+	return ioutil.WriteFile(output, b, 0600)
+}
@@ -0,0 +1,89 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ness-network/ness/src/api"
+)
+
+func walletAccountsCmd() *cobra.Command {
+	return &cobra.Command{
+		Args:  cobra.ExactArgs(1),
+		Short: "List the accounts of a bip44 wallet",
+		Use:   "walletAccounts [wallet]",
+		Long: `List the accounts of a bip44 wallet with the addresses of their external
+    and change chains and the balance of each account.`,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			rsp, err := apiClient.WalletAccounts(args[0])
+			if err != nil {
+				return err
+			}
+
+			return printJSON(rsp)
+		},
+	}
+}
+
+func walletAccountCreateCmd() *cobra.Command {
+	walletAccountCreateCmd := &cobra.Command{
+		Args:  cobra.ExactArgs(2),
+		Short: "Create an account in a bip44 wallet",
+		Use:   "walletAccountCreate [wallet] [name]",
+		Long: `Create a new account in a bip44 wallet and print its index.
+
+    Addresses of the account are generated with walletAddAddresses --account
+    and its coins are spent with createRawTransactionV2 --account.
+
+    Use caution when using the "-p" command. If you have command history enabled
+    your wallet encryption password can be recovered from the history log.
+    If you do not include the "-p" option you will be prompted to enter your password
+    after you enter your command.`,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			w, err := apiClient.Wallet(args[0])
+			if err != nil {
+				return err
+			}
+
+			req := api.WalletAccountCreateRequest{
+				ID:   w.Meta.Filename,
+				Name: args[1],
+			}
+
+			if w.Meta.Encrypted {
+				p, err := getPassword(c)
+				if err != nil {
+					return err
+				}
+				defer func() {
+					p = nil
+				}()
+				req.Password = string(p)
+			}
+
+			rsp, err := apiClient.CreateWalletAccount(req)
+			if err != nil {
+				return err
+			}
+
+			jsonOutput, err := c.Flags().GetBool("json")
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				return printJSON(rsp)
+			}
+
+			fmt.Println(rsp.Index)
+			return nil
+		},
+	}
+
+	walletAccountCreateCmd.Flags().StringP("password", "p", "", "Wallet password")
+	walletAccountCreateCmd.Flags().BoolP("json", "j", false, "Returns the results in JSON format.")
+
+	return walletAccountCreateCmd
+}
@@ -0,0 +1,91 @@
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/ness-network/ness/src/api"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+func walletBumpFeeCmd() *cobra.Command {
+	walletBumpFeeCmd := &cobra.Command{
+		Args:  cobra.ExactArgs(3),
+		Short: "Replace a stuck unconfirmed transaction of a wallet by one that burns more coin hours",
+		Use:   "walletBumpFee [wallet] [transaction id] [fee]",
+		Long: `Replace an unconfirmed transaction of a wallet by a transaction that spends
+    the same inputs and burns [fee] coin hours, then broadcast it.
+
+    The [fee] is the total coin hour fee of the new transaction and must be higher
+    than the fee of the unconfirmed transaction. The extra coin hours are taken from
+    the outputs to addresses of the wallet, usually the change output.
+
+    The new transaction only replaces the unconfirmed transaction on nodes that run
+    with the -enable-replace-by-fee option.
+
+    Use caution when using the "-p" command. If you have command history enabled
+    your wallet encryption password can be recovered from the history log.
+    If you do not include the "-p" option you will be prompted to enter your password
+    after you enter your command.`,
+		SilenceUsage: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			w, err := apiClient.Wallet(args[0])
+			if err != nil {
+				return err
+			}
+
+			req := api.WalletBumpFeeRequest{
+				WalletID: w.Meta.Filename,
+				TxID:     args[1],
+				Fee:      args[2],
+			}
+
+			if w.Meta.Encrypted {
+				p, err := getPassword(c)
+				if err != nil {
+					return err
+				}
+				defer func() {
+					p = nil
+				}()
+				req.Password = string(p)
+			}
+
+			rsp, err := apiClient.WalletBumpFee(req)
+			if err != nil {
+				return err
+			}
+
+			txn, err := coin.DeserializeTransactionHex(rsp.EncodedTransaction)
+			if err != nil {
+				return err
+			}
+
+			txid, err := apiClient.InjectTransaction(&txn)
+			if err != nil {
+				return err
+			}
+
+			jsonOutput, err := c.Flags().GetBool("json")
+			if err != nil {
+				return err
+			}
+			if jsonOutput {
+				return printJSON(struct {
+					Txid string `json:"txid"`
+				}{
+					Txid: txid,
+				})
+			}
+
+			fmt.Printf("txid:%s\n", txid)
+			return nil
+		},
+	}
+
+	walletBumpFeeCmd.Flags().StringP("password", "p", "", "Wallet password")
+	walletBumpFeeCmd.Flags().BoolP("json", "j", false, "Returns the results in JSON format.")
+
+	return walletBumpFeeCmd
+}
@@ -0,0 +1,101 @@
+package cli
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/ness-network/ness/src/api"
+)
+
+func walletCoinControlCmd() *cobra.Command {
+	return &cobra.Command{
+		Args:  cobra.ExactArgs(1),
+		Short: "List the outputs of a wallet with their labels and freeze flags",
+		Use:   "walletCoinControl [wallet]",
+		Long: `List the confirmed unspent outputs of a wallet with their labels and
+    freeze flags. Frozen outputs are not spent by the transactions created by
+    the wallet until they are unfrozen.`,
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		RunE: func(c *cobra.Command, args []string) error {
+			rsp, err := apiClient.WalletOutputs(args[0])
+			if err != nil {
+				return err
+			}
+
+			return printJSON(rsp)
+		},
+	}
+}
+
+func walletFreezeOutputsCmd() *cobra.Command {
+	return &cobra.Command{
+		Args:  cobra.MinimumNArgs(2),
+		Short: "Freeze outputs of a wallet",
+		Use:   "walletFreezeOutputs [wallet] [output hash list]",
+		Long: `Freeze unspent outputs of a wallet, join multiple output hashes with space.
+    Frozen outputs are not spent by the transactions created by the wallet,
+    and can not be chosen as the unspents of a transaction until they are unfrozen.`,
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		RunE: func(c *cobra.Command, args []string) error {
+			rsp, err := apiClient.FreezeWalletOutputs(api.WalletOutputsFreezeRequest{
+				ID:     args[0],
+				Hashes: args[1:],
+			})
+			if err != nil {
+				return err
+			}
+
+			return printJSON(rsp)
+		},
+	}
+}
+
+func walletUnfreezeOutputsCmd() *cobra.Command {
+	return &cobra.Command{
+		Args:                  cobra.MinimumNArgs(2),
+		Short:                 "Unfreeze outputs of a wallet",
+		Use:                   "walletUnfreezeOutputs [wallet] [output hash list]",
+		Long:                  "Unfreeze unspent outputs of a wallet, join multiple output hashes with space.",
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		RunE: func(c *cobra.Command, args []string) error {
+			rsp, err := apiClient.UnfreezeWalletOutputs(api.WalletOutputsFreezeRequest{
+				ID:     args[0],
+				Hashes: args[1:],
+			})
+			if err != nil {
+				return err
+			}
+
+			return printJSON(rsp)
+		},
+	}
+}
+
+func walletLabelOutputCmd() *cobra.Command {
+	return &cobra.Command{
+		Args:                  cobra.RangeArgs(2, 3),
+		Short:                 "Set the label of an output of a wallet",
+		Use:                   "walletLabelOutput [wallet] [output hash] [label]",
+		Long:                  "Set the label of an unspent output of a wallet. Omit the label to remove it.",
+		DisableFlagsInUseLine: true,
+		SilenceUsage:          true,
+		RunE: func(c *cobra.Command, args []string) error {
+			req := api.WalletOutputLabelRequest{
+				ID:   args[0],
+				Hash: args[1],
+			}
+			if len(args) == 3 {
+				req.Label = args[2]
+			}
+
+			rsp, err := apiClient.SetWalletOutputLabel(req)
+			if err != nil {
+				return err
+			}
+
+			return printJSON(rsp)
+		},
+	}
+}
@@ -10,8 +10,8 @@ import (
 
 	"github.com/spf13/cobra"
 
-	"github.com/skycoin/skycoin/src/api"
-	"github.com/skycoin/skycoin/src/readable"
+	"github.com/ness-network/ness/src/api"
+	"github.com/ness-network/ness/src/readable"
 	"github.com/skycoin/skycoin/src/util/droplet"
 )
 
@@ -372,6 +372,18 @@ func (self *BlockStat) GetBestHashPubkeySig() (
 	return best_h, best_p, best_s
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// The number of unique pubkeys that signed 'hash'. It is compared to
+// a quorum before the hash is settled, see
+// ConsensusParticipant::SettleBlock().
+func (self *BlockStat) GetHashSignerCount(hash cipher.SHA256) int {
+	info, have := self.hash2info[hash]
+	if !have {
+		return 0
+	}
+	return len(info.pubkey2sig)
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 func (self *BlockStat) Print() {
 
@@ -467,6 +479,27 @@ func (self *BlockStatQueue) Print() {
 	fmt.Printf("}")
 }
 
+////////////////////////////////////////////////////////////////////////////////
+func (self *BlockStatQueue) find_BlockStat(seqno uint64) *BlockStat {
+	for i := range self.queue {
+		if self.queue[i].seqno == seqno {
+			return self.queue[i]
+		}
+	}
+	return nil
+}
+
+////////////////////////////////////////////////////////////////////////////////
+// Removes the BlockStat entries with seqno less or equal to 'seqno'.
+// Once a block is settled, the statistics of the blocks before it are
+// of no use, and keeping them would make
+// Cfg_consensus_candidate_max_seqno_gap reject the blocks after it.
+func (self *BlockStatQueue) remove_through_seqno(seqno uint64) {
+	for len(self.queue) > 0 && self.queue[0].seqno <= seqno {
+		heap.Pop(&self.queue)
+	}
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 func (self *BlockStatQueue) try_append_to_BlockStatQueue(
 	blockPtr *BlockBase) int {
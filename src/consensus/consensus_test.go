@@ -206,3 +206,82 @@ func TestBlockStat_02(t *testing.T) {
 		t.Fail()
 	}
 }
+
+////////////////////////////////////////////////////////////////////////////////
+type test_connection_manager struct {
+	send_count int
+}
+
+func (self *test_connection_manager) SendBlockToAllMySubscriber(
+	blockPtr *BlockBase) {
+
+	self.send_count += 1
+}
+
+func (self *test_connection_manager) Print() {}
+
+////////////////////////////////////////////////////////////////////////////////
+func TestConsensusParticipant_SettleBlock(t *testing.T) {
+	man := &test_connection_manager{}
+	cp := NewConsensusParticipantPtr(man)
+
+	hash1 := cipher.SumSHA256(secp256k1.RandByte(888))
+	hash2 := cipher.SumSHA256(secp256k1.RandByte(888))
+	var seqno uint64 = 5
+
+	sign := func(hash cipher.SHA256) *BlockBase {
+		_, seckey := cipher.GenerateKeyPair()
+		return &BlockBase{
+			Sig:   cipher.MustSignHash(hash, seckey),
+			Hash:  hash,
+			Seqno: seqno,
+		}
+	}
+
+	cp.OnBlockHeaderArrived(sign(hash1))
+	cp.OnBlockHeaderArrived(sign(hash2))
+
+	if cp.SettleBlock(seqno, 2) != nil {
+		t.Log("ConsensusParticipant::SettleBlock() settled without quorum.")
+		t.Fail()
+	}
+
+	b1 := sign(hash1)
+	cp.OnBlockHeaderArrived(b1)
+	cp.OnBlockHeaderArrived(b1) // Duplicate, not forwarded.
+	if man.send_count != 3 {
+		t.Log("ConsensusParticipant::OnBlockHeaderArrived() forwarded a duplicate.")
+		t.Fail()
+	}
+
+	settled := cp.SettleBlock(seqno, 2)
+	if settled == nil || settled.Hash != hash1 || settled.Seqno != seqno {
+		t.Log("ConsensusParticipant::SettleBlock() failed to settle.")
+		t.Fail()
+	}
+
+	if cp.SettleBlock(seqno, 1) != nil {
+		t.Log("ConsensusParticipant::SettleBlock() settled the same seqno twice.")
+		t.Fail()
+	}
+
+	if cp.GetNextBlockSeqNo() != seqno+1 {
+		t.Log("ConsensusParticipant::SettleBlock() did not append to BlockchainTail.")
+		t.Fail()
+	}
+
+	if cp.Get_block_stat_queue_Len() != 0 {
+		t.Log("ConsensusParticipant::SettleBlock() did not remove the BlockStat.")
+		t.Fail()
+	}
+
+	// Blocks far beyond the first candidate are accepted once the
+	// earlier ones are settled.
+	seqno += Cfg_consensus_candidate_max_seqno_gap + 1
+	cp.OnBlockHeaderArrived(sign(hash2))
+	settled = cp.SettleBlock(seqno, 1)
+	if settled == nil || settled.Hash != hash2 {
+		t.Log("ConsensusParticipant::SettleBlock() failed to settle after a gap.")
+		t.Fail()
+	}
+}
@@ -112,6 +112,15 @@ func (self *ConsensusParticipant) Get_block_stat_queue_element_at(
 	return self.block_stat_queue.queue[j] // A pointer, BTW
 }
 
+////////////////////////////////////////////////////////////////////////////////
+// Drops the candidates with seqno less or equal to 'seqno', e.g. after
+// the blocks were obtained without consensus.
+func (self *ConsensusParticipant) Remove_block_stat_queue_through(
+	seqno uint64) {
+
+	self.block_stat_queue.remove_through_seqno(seqno)
+}
+
 ////////////////////////////////////////////////////////////////////////////////
 func (self *ConsensusParticipant) OnBlockHeaderArrived(blockPtr *BlockBase) {
 
@@ -178,3 +187,51 @@ func (self *ConsensusParticipant) harvest_ripe_BlockStat() {
 }
 
 ////////////////////////////////////////////////////////////////////////////////
+// Settles the block with sequence number 'seqno' without waiting for
+// Cfg_consensus_waiting_time_as_seqno_diff more blocks, which suits a
+// blockchain that is extended one block at a time. The best hash is
+// settled once it has been signed by at least 'quorum' unique
+// pubkeys. The settled block is appended to BlockchainTail, and the
+// BlockStat entries up to 'seqno' are dropped so that no other hash
+// can be settled for the same seqno.
+//
+// Returns nil if the block cannot be settled (yet).
+func (self *ConsensusParticipant) SettleBlock(
+	seqno uint64,
+	quorum int) *BlockBase {
+
+	statPtr := self.block_stat_queue.find_BlockStat(seqno)
+	if statPtr == nil || statPtr.frozen {
+		return nil
+	}
+
+	hash, _, sig := statPtr.GetBestHashPubkeySig()
+	if hash == all_zero_hash || statPtr.GetHashSignerCount(hash) < quorum {
+		return nil
+	}
+
+	blockPtr := &BlockBase{
+		Sig:   sig,
+		Hash:  hash,
+		Seqno: seqno,
+	}
+
+	res := self.block_queue.try_append_to_BlockchainTail(blockPtr)
+	if res == 1 || res == 2 {
+		// Duplicate or already settled.
+		return nil
+	}
+	if res == 3 {
+		// The blocks in between were obtained without consensus,
+		// e.g. downloaded from peers when catching up, so accept the
+		// gap.
+		self.block_queue.append_nocheck(blockPtr)
+	}
+
+	statPtr.frozen = true
+	self.block_stat_queue.remove_through_seqno(seqno)
+
+	return blockPtr
+}
+
+////////////////////////////////////////////////////////////////////////////////
@@ -0,0 +1,164 @@
+package daemon
+
+import (
+	"sync"
+
+	"github.com/ness-network/ness/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+const (
+	// blockCertificatesProtocolVersion is the minimum protocol version of peers that support GiveBlockCertificatesMessage
+	blockCertificatesProtocolVersion int32 = 8
+	// maxPendingBlockCertificates is the maximum number of blocks that received block publisher signatures are kept for
+	maxPendingBlockCertificates = 1024
+)
+
+// blockCertificates holds the block publisher signatures received from peers for blocks that are not executed yet.
+// Only valid signatures by block publishers are kept. The oldest blocks are dropped beyond maxPendingBlockCertificates.
+type blockCertificates struct {
+	sync.Mutex
+	publishers []cipher.PubKey
+	// signatures by block hash and publisher
+	sigs map[cipher.SHA256]map[cipher.PubKey]cipher.Sig
+	// block hashes in the order they were added
+	order []cipher.SHA256
+}
+
+func newBlockCertificates(publishers []cipher.PubKey) *blockCertificates {
+	return &blockCertificates{
+		publishers: publishers,
+		sigs:       make(map[cipher.SHA256]map[cipher.PubKey]cipher.Sig),
+	}
+}
+
+// add stores the signatures of a certificate that are by a block publisher. Returns the number of new signatures
+func (bcs *blockCertificates) add(cert blockdb.BlockCertificate) int {
+	bcs.Lock()
+	defer bcs.Unlock()
+
+	n := 0
+	for _, sig := range cert.Sigs {
+		pubkey, err := cipher.PubKeyFromSig(sig, cert.Hash)
+		if err != nil || !containsPubkey(bcs.publishers, pubkey) {
+			continue
+		}
+
+		if _, ok := bcs.sigs[cert.Hash][pubkey]; ok {
+			continue
+		}
+
+		if err := cipher.VerifyPubKeySignedHash(pubkey, sig, cert.Hash); err != nil {
+			continue
+		}
+
+		sigs, ok := bcs.sigs[cert.Hash]
+		if !ok {
+			if len(bcs.order) >= maxPendingBlockCertificates {
+				delete(bcs.sigs, bcs.order[0])
+				bcs.order = bcs.order[1:]
+			}
+
+			sigs = make(map[cipher.PubKey]cipher.Sig)
+			bcs.sigs[cert.Hash] = sigs
+			bcs.order = append(bcs.order, cert.Hash)
+		}
+
+		sigs[pubkey] = sig
+		n++
+	}
+
+	return n
+}
+
+// get returns the signatures received for the block with hash
+func (bcs *blockCertificates) get(hash cipher.SHA256) []cipher.Sig {
+	bcs.Lock()
+	defer bcs.Unlock()
+
+	sigs := make([]cipher.Sig, 0, len(bcs.sigs[hash]))
+	for _, sig := range bcs.sigs[hash] {
+		sigs = append(sigs, sig)
+	}
+
+	return sigs
+}
+
+// remove drops the signatures received for the block with hash
+func (bcs *blockCertificates) remove(hash cipher.SHA256) {
+	bcs.Lock()
+	defer bcs.Unlock()
+
+	if _, ok := bcs.sigs[hash]; !ok {
+		return
+	}
+
+	delete(bcs.sigs, hash)
+	for i, h := range bcs.order {
+		if h == hash {
+			bcs.order = append(bcs.order[:i], bcs.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// addBlockCertificates stores the block publisher signatures received from a peer until their blocks are executed.
+// Returns the number of new signatures
+func (dm *Daemon) addBlockCertificates(certs []blockdb.BlockCertificate) int {
+	n := 0
+	for _, cert := range certs {
+		n += dm.blockCertificates.add(cert)
+	}
+	return n
+}
+
+// sendBlockCertificates sends the certificates of blocks to addr, if there are any and the connection supports them
+func (dm *Daemon) sendBlockCertificates(addr string, blocks []coin.SignedBlock) error {
+	if c := dm.connections.get(addr); c == nil || !c.SupportsBlockCertificates() {
+		return nil
+	}
+
+	certs, err := dm.getBlockCertificates(blocks)
+	if err != nil || len(certs) == 0 {
+		return err
+	}
+
+	return dm.sendMessage(addr, NewGiveBlockCertificatesMessage(certs))
+}
+
+// broadcastBlockCertificates sends the certificates of blocks to the addrs that support them
+func (dm *Daemon) broadcastBlockCertificates(addrs []string, blocks []coin.SignedBlock) error {
+	var certAddrs []string
+	for _, addr := range addrs {
+		if c := dm.connections.get(addr); c != nil && c.SupportsBlockCertificates() {
+			certAddrs = append(certAddrs, addr)
+		}
+	}
+
+	if len(certAddrs) == 0 {
+		return nil
+	}
+
+	certs, err := dm.getBlockCertificates(blocks)
+	if err != nil || len(certs) == 0 {
+		return err
+	}
+
+	_, err = dm.pool.Pool.BroadcastMessage(NewGiveBlockCertificatesMessage(certs), certAddrs)
+	return err
+}
+
+// getBlockCertificates returns the stored certificates of blocks
+func (dm *Daemon) getBlockCertificates(blocks []coin.SignedBlock) ([]blockdb.BlockCertificate, error) {
+	if len(dm.config.PublisherPubkeys) == 0 {
+		return nil, nil
+	}
+
+	hashes := make([]cipher.SHA256, len(blocks))
+	for i, b := range blocks {
+		hashes[i] = b.HashHeader()
+	}
+
+	return dm.visor.GetBlockCertificates(hashes)
+}
@@ -0,0 +1,73 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ness-network/ness/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+func TestBlockCertificates(t *testing.T) {
+	pubkey1, seckey1 := cipher.GenerateKeyPair()
+	pubkey2, seckey2 := cipher.GenerateKeyPair()
+	_, otherSeckey := cipher.GenerateKeyPair()
+
+	bcs := newBlockCertificates([]cipher.PubKey{pubkey1, pubkey2})
+
+	hash := cipher.SumSHA256([]byte("block"))
+	sig1 := cipher.MustSignHash(hash, seckey1)
+	sig2 := cipher.MustSignHash(hash, seckey2)
+
+	// Signatures by other keys, of another hash or invalid ones are dropped
+	n := bcs.add(blockdb.BlockCertificate{
+		Hash: hash,
+		Sigs: []cipher.Sig{
+			cipher.MustSignHash(hash, otherSeckey),
+			cipher.MustSignHash(cipher.SumSHA256([]byte("other block")), seckey1),
+			{},
+		},
+	})
+	require.Equal(t, 0, n)
+	require.Empty(t, bcs.get(hash))
+	require.Empty(t, bcs.sigs)
+
+	n = bcs.add(blockdb.BlockCertificate{
+		Hash: hash,
+		Sigs: []cipher.Sig{sig1},
+	})
+	require.Equal(t, 1, n)
+	require.Equal(t, []cipher.Sig{sig1}, bcs.get(hash))
+
+	// Signatures of the same publisher are only kept once
+	n = bcs.add(blockdb.BlockCertificate{
+		Hash: hash,
+		Sigs: []cipher.Sig{cipher.MustSignHash(hash, seckey1), sig2},
+	})
+	require.Equal(t, 1, n)
+	require.ElementsMatch(t, []cipher.Sig{sig1, sig2}, bcs.get(hash))
+
+	bcs.remove(hash)
+	require.Empty(t, bcs.get(hash))
+	require.Empty(t, bcs.order)
+
+	// The oldest blocks are dropped beyond maxPendingBlockCertificates
+	var first cipher.SHA256
+	for i := 0; i <= maxPendingBlockCertificates; i++ {
+		h := cipher.SumSHA256([]byte{byte(i), byte(i >> 8)})
+		if i == 0 {
+			first = h
+		}
+
+		n := bcs.add(blockdb.BlockCertificate{
+			Hash: h,
+			Sigs: []cipher.Sig{cipher.MustSignHash(h, seckey1)},
+		})
+		require.Equal(t, 1, n)
+	}
+
+	require.Len(t, bcs.sigs, maxPendingBlockCertificates)
+	require.Len(t, bcs.order, maxPendingBlockCertificates)
+	require.Empty(t, bcs.get(first))
+}
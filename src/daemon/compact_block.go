@@ -0,0 +1,173 @@
+package daemon
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+const (
+	// compactBlocksProtocolVersion is the minimum protocol version of peers that support CompactBlockMessage
+	compactBlocksProtocolVersion int32 = 4
+	// maxCompactBlockTxns is the maximum number of transactions in a CompactBlockMessage.
+	// Blocks with more transactions are sent in full.
+	maxCompactBlockTxns = 4096
+	// maxPendingCompactBlocks is the maximum number of compact blocks waiting for missing transactions
+	maxPendingCompactBlocks = 8
+)
+
+var (
+	// errCompactBlockMismatch is returned if a block rebuilt from a compact block does not match its header.
+	// This can happen if two transactions share a short ID, the full block must be downloaded instead.
+	errCompactBlockMismatch = errors.New("Compact block transactions do not match the block header")
+	// errCompactBlockRequested is returned if the missing transactions of a compact block were already requested
+	errCompactBlockRequested = errors.New("Compact block transactions were already requested")
+)
+
+// compactTxnID returns the short ID of a transaction in a compact block.
+// The ID is salted with the block hash, so that colliding transactions can't be precomputed.
+func compactTxnID(blockHash, txnHash cipher.SHA256) uint64 {
+	h := cipher.AddSHA256(blockHash, txnHash)
+	return binary.LittleEndian.Uint64(h[:8])
+}
+
+// pendingCompactBlock is a compact block waiting for the transactions that were missing
+// from the unconfirmed pool
+type pendingCompactBlock struct {
+	addr    string
+	header  SignedBlockHeader
+	txns    coin.Transactions
+	missing []uint64
+}
+
+// compactBlocks rebuilds blocks received in a CompactBlockMessage
+type compactBlocks struct {
+	sync.Mutex
+	// compact blocks waiting for missing transactions, by block hash
+	pending map[cipher.SHA256]*pendingCompactBlock
+}
+
+func newCompactBlocks() *compactBlocks {
+	return &compactBlocks{
+		pending: make(map[cipher.SHA256]*pendingCompactBlock),
+	}
+}
+
+// reconstruct rebuilds a block from its short transaction IDs and the transactions of the unconfirmed pool.
+// If all transactions are known, the block is returned. Otherwise the block is kept pending and the indexes
+// of the missing transactions are returned.
+func (cb *compactBlocks) reconstruct(addr string, header SignedBlockHeader, shortIDs []uint64, pool coin.Transactions) (*coin.SignedBlock, []uint64, error) {
+	hash := header.Head.Hash()
+
+	// Transactions that share a short ID are ambiguous, treat them as missing
+	byID := make(map[uint64]int, len(pool))
+	for i, txn := range pool {
+		id := compactTxnID(hash, txn.Hash())
+		if _, ok := byID[id]; ok {
+			byID[id] = -1
+			continue
+		}
+		byID[id] = i
+	}
+
+	txns := make(coin.Transactions, len(shortIDs))
+	var missing []uint64
+	for i, id := range shortIDs {
+		j, ok := byID[id]
+		if !ok || j < 0 {
+			missing = append(missing, uint64(i))
+			continue
+		}
+		txns[i] = pool[j]
+	}
+
+	if len(missing) == 0 {
+		return assembleCompactBlock(header, txns)
+	}
+
+	cb.Lock()
+	defer cb.Unlock()
+
+	if _, ok := cb.pending[hash]; ok {
+		return nil, nil, errCompactBlockRequested
+	}
+
+	cb.prune(header.Head.BkSeq)
+
+	cb.pending[hash] = &pendingCompactBlock{
+		addr:    addr,
+		header:  header,
+		txns:    txns,
+		missing: missing,
+	}
+
+	return nil, missing, nil
+}
+
+// complete fills in the missing transactions of a pending compact block received from addr.
+// Returns nil if there is no such pending compact block.
+func (cb *compactBlocks) complete(addr string, hash cipher.SHA256, txns coin.Transactions) (*coin.SignedBlock, error) {
+	cb.Lock()
+	p, ok := cb.pending[hash]
+	if ok && p.addr == addr {
+		delete(cb.pending, hash)
+	}
+	cb.Unlock()
+
+	if !ok || p.addr != addr {
+		return nil, nil
+	}
+
+	if len(txns) != len(p.missing) {
+		return nil, errCompactBlockMismatch
+	}
+
+	for i, idx := range p.missing {
+		p.txns[idx] = txns[i]
+	}
+
+	b, _, err := assembleCompactBlock(p.header, p.txns)
+	return b, err
+}
+
+// prune removes pending compact blocks that precede seq, and the lowest pending compact block
+// if the limit is reached. Must be called with the lock held.
+func (cb *compactBlocks) prune(seq uint64) {
+	var lowest *cipher.SHA256
+	for hash, p := range cb.pending {
+		if p.header.Head.BkSeq < seq {
+			delete(cb.pending, hash)
+			continue
+		}
+		if lowest == nil || p.header.Head.BkSeq < cb.pending[*lowest].header.Head.BkSeq {
+			h := hash
+			lowest = &h
+		}
+	}
+
+	if lowest != nil && len(cb.pending) >= maxPendingCompactBlocks {
+		delete(cb.pending, *lowest)
+	}
+}
+
+// assembleCompactBlock creates the block and checks that its transactions match the header
+func assembleCompactBlock(header SignedBlockHeader, txns coin.Transactions) (*coin.SignedBlock, []uint64, error) {
+	b := coin.SignedBlock{
+		Block: coin.Block{
+			Head: header.Head,
+			Body: coin.BlockBody{
+				Transactions: txns,
+			},
+		},
+		Sig: header.Sig,
+	}
+
+	if b.Block.Body.Hash() != header.Head.BodyHash {
+		return nil, nil, errCompactBlockMismatch
+	}
+
+	return &b, nil, nil
+}
@@ -0,0 +1,150 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+func TestNewCompactBlockMessage(t *testing.T) {
+	_, seckey := cipher.GenerateKeyPair()
+	b := makeTestSignedChain(t, coin.BlockHeader{Version: 1}, 1, seckey)[0]
+
+	m := NewCompactBlockMessage(b)
+	require.Equal(t, NewSignedBlockHeader(b), m.Header)
+	require.Len(t, m.ShortIDs, len(b.Body.Transactions))
+	for i, txn := range b.Body.Transactions {
+		require.Equal(t, compactTxnID(b.HashHeader(), txn.Hash()), m.ShortIDs[i])
+	}
+
+	// Short IDs are salted with the block hash
+	require.NotEqual(t, compactTxnID(cipher.SHA256{}, b.Body.Transactions[0].Hash()), m.ShortIDs[0])
+}
+
+func makeTestCompactBlock(t *testing.T, nTxns int) (coin.SignedBlock, *CompactBlockMessage) {
+	_, seckey := cipher.GenerateKeyPair()
+
+	txns := make(coin.Transactions, nTxns)
+	for i := range txns {
+		txns[i] = coin.Transaction{
+			Length:    uint32(i),
+			InnerHash: testutil.RandSHA256(t),
+		}
+	}
+
+	body := coin.BlockBody{
+		Transactions: txns,
+	}
+
+	b := coin.Block{
+		Head: coin.BlockHeader{
+			Version:  1,
+			Time:     100,
+			BkSeq:    10,
+			BodyHash: body.Hash(),
+		},
+		Body: body,
+	}
+
+	sb := coin.SignedBlock{
+		Block: b,
+		Sig:   cipher.MustSignHash(b.HashHeader(), seckey),
+	}
+
+	return sb, NewCompactBlockMessage(sb)
+}
+
+func TestCompactBlocksReconstruct(t *testing.T) {
+	sb, m := makeTestCompactBlock(t, 4)
+	txns := sb.Body.Transactions
+	hash := sb.HashHeader()
+
+	other := coin.Transaction{
+		InnerHash: testutil.RandSHA256(t),
+	}
+
+	// All transactions are in the pool
+	cb := newCompactBlocks()
+	pool := coin.Transactions{txns[3], other, txns[1], txns[0], txns[2]}
+	b, missing, err := cb.reconstruct("1.1.1.1:6000", m.Header, m.ShortIDs, pool)
+	require.NoError(t, err)
+	require.Empty(t, missing)
+	require.Equal(t, sb, *b)
+
+	// Some transactions are missing
+	pool = coin.Transactions{txns[3], other, txns[0]}
+	b, missing, err = cb.reconstruct("1.1.1.1:6000", m.Header, m.ShortIDs, pool)
+	require.NoError(t, err)
+	require.Nil(t, b)
+	require.Equal(t, []uint64{1, 2}, missing)
+
+	// The missing transactions were already requested
+	_, _, err = cb.reconstruct("2.2.2.2:6000", m.Header, m.ShortIDs, pool)
+	require.Equal(t, errCompactBlockRequested, err)
+
+	// Transactions from another peer are ignored
+	b, err = cb.complete("2.2.2.2:6000", hash, coin.Transactions{txns[1], txns[2]})
+	require.NoError(t, err)
+	require.Nil(t, b)
+
+	b, err = cb.complete("1.1.1.1:6000", hash, coin.Transactions{txns[1], txns[2]})
+	require.NoError(t, err)
+	require.Equal(t, sb, *b)
+
+	// The pending block was removed
+	b, err = cb.complete("1.1.1.1:6000", hash, coin.Transactions{txns[1], txns[2]})
+	require.NoError(t, err)
+	require.Nil(t, b)
+
+	// Wrong transactions
+	_, _, err = cb.reconstruct("1.1.1.1:6000", m.Header, m.ShortIDs, pool)
+	require.NoError(t, err)
+	_, err = cb.complete("1.1.1.1:6000", hash, coin.Transactions{txns[2], txns[1]})
+	require.Equal(t, errCompactBlockMismatch, err)
+
+	// Wrong number of transactions
+	_, _, err = cb.reconstruct("1.1.1.1:6000", m.Header, m.ShortIDs, pool)
+	require.NoError(t, err)
+	_, err = cb.complete("1.1.1.1:6000", hash, coin.Transactions{txns[1]})
+	require.Equal(t, errCompactBlockMismatch, err)
+
+	// Transactions that share a short ID are treated as missing
+	shortIDs := append([]uint64{}, m.ShortIDs...)
+	shortIDs[0] = compactTxnID(hash, other.Hash())
+	b, missing, err = newCompactBlocks().reconstruct("1.1.1.1:6000", m.Header, shortIDs, coin.Transactions{other, other, txns[1], txns[2], txns[3]})
+	require.NoError(t, err)
+	require.Nil(t, b)
+	require.Equal(t, []uint64{0}, missing)
+
+	// A rebuilt block that does not match the header is rejected
+	b, _, err = newCompactBlocks().reconstruct("1.1.1.1:6000", m.Header, shortIDs, coin.Transactions{other, txns[1], txns[2], txns[3]})
+	require.Equal(t, errCompactBlockMismatch, err)
+	require.Nil(t, b)
+}
+
+func TestCompactBlocksPrune(t *testing.T) {
+	cb := newCompactBlocks()
+
+	// Competing blocks with the same seq are limited
+	for i := 0; i < maxPendingCompactBlocks+1; i++ {
+		_, m := makeTestCompactBlock(t, 1)
+		m.Header.Head.Time += uint64(i)
+
+		_, missing, err := cb.reconstruct("1.1.1.1:6000", m.Header, m.ShortIDs, nil)
+		require.NoError(t, err)
+		require.Equal(t, []uint64{0}, missing)
+	}
+	require.Len(t, cb.pending, maxPendingCompactBlocks)
+
+	// Blocks that precede a new compact block are dropped
+	_, m := makeTestCompactBlock(t, 1)
+	m.Header.Head.BkSeq++
+	_, _, err := cb.reconstruct("1.1.1.1:6000", m.Header, m.ShortIDs, nil)
+	require.NoError(t, err)
+	require.Len(t, cb.pending, 1)
+	require.Contains(t, cb.pending, m.Header.Head.Hash())
+}
@@ -2,15 +2,14 @@ package daemon
 
 import (
 	"errors"
-	"fmt"
 	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/ness-network/ness/src/util/iputil"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/params"
-	"github.com/skycoin/skycoin/src/util/iputil"
 	"github.com/skycoin/skycoin/src/util/useragent"
 )
 
@@ -65,6 +64,38 @@ type ConnectionDetails struct {
 	UserAgent            useragent.Data
 	UnconfirmedVerifyTxn params.VerifyTxn
 	GenesisHash          cipher.SHA256
+	// OnionAddress is the onion address that an incoming peer listens on, if it advertised one
+	OnionAddress string
+}
+
+// SupportsHeadersSync returns true if the peer can serve GetHeadersMessage
+func (c ConnectionDetails) SupportsHeadersSync() bool {
+	return c.HasIntroduced() && c.ProtocolVersion >= headersSyncProtocolVersion
+}
+
+// SupportsCompactBlocks returns true if the peer can rebuild blocks from CompactBlockMessage
+func (c ConnectionDetails) SupportsCompactBlocks() bool {
+	return c.HasIntroduced() && c.ProtocolVersion >= compactBlocksProtocolVersion
+}
+
+// SupportsPublisherConsensus returns true if the peer can receive BlockCandidateMessage
+func (c ConnectionDetails) SupportsPublisherConsensus() bool {
+	return c.HasIntroduced() && c.ProtocolVersion >= publisherConsensusProtocolVersion
+}
+
+// SupportsKeyCheckpoints returns true if the peer can receive GiveKeyCheckpointsMessage
+func (c ConnectionDetails) SupportsKeyCheckpoints() bool {
+	return c.HasIntroduced() && c.ProtocolVersion >= keyCheckpointsProtocolVersion
+}
+
+// SupportsBlockCertificates returns true if the peer can receive GiveBlockCertificatesMessage
+func (c ConnectionDetails) SupportsBlockCertificates() bool {
+	return c.HasIntroduced() && c.ProtocolVersion >= blockCertificatesProtocolVersion
+}
+
+// SupportsPeerAddrs returns true if the peer can receive GivePeerAddrsMessage
+func (c ConnectionDetails) SupportsPeerAddrs() bool {
+	return c.HasIntroduced() && c.ProtocolVersion >= peerAddrsProtocolVersion
 }
 
 // HasIntroduced returns true if the connection has introduced
@@ -83,8 +114,13 @@ type connection struct {
 	gnetID uint64
 }
 
-// ListenAddr returns the addr that connection listens on, if available
+// ListenAddr returns the addr that connection listens on, if available.
+// This is the onion address advertised by the peer, if any.
 func (c *connection) ListenAddr() string {
+	if c.OnionAddress != "" {
+		return c.OnionAddress
+	}
+
 	if c.ListenPort == 0 {
 		return ""
 	}
@@ -95,7 +131,7 @@ func (c *connection) ListenAddr() string {
 		return ""
 	}
 
-	return fmt.Sprintf("%s:%d", ip, c.ListenPort)
+	return iputil.JoinAddr(ip, c.ListenPort)
 }
 
 // Connections manages a collection of Connection
@@ -134,7 +170,7 @@ func (c *Connections) pending(addr string) (*connection, error) {
 		return nil, ErrConnectionExists
 	}
 
-	c.ipCounts[ip]++
+	c.ipCounts[iputil.IPGroup(ip)]++
 
 	conn := &connection{
 		Addr: addr,
@@ -177,7 +213,7 @@ func (c *Connections) connected(addr string, gnetID uint64) (*connection, error)
 	conn := c.conns[addr]
 
 	if conn == nil {
-		c.ipCounts[ip]++
+		c.ipCounts[iputil.IPGroup(ip)]++
 
 		conn = &connection{
 			Addr: addr,
@@ -302,6 +338,9 @@ func (c *Connections) introduced(addr string, gnetID uint64, m *IntroductionMess
 	conn.GenesisHash = m.GenesisHash
 
 	if !conn.Outgoing {
+		// The onion address of outgoing connections is already their addr
+		conn.OnionAddress = m.OnionAddress
+
 		listenAddr := conn.ListenAddr()
 		c.listenAddrs[listenAddr] = append(c.listenAddrs[listenAddr], addr)
 	}
@@ -430,11 +469,12 @@ func (c *Connections) canUpdateMirror(ip string, mirror uint32) error {
 	return nil
 }
 
-// IPCount returns the number of connections for a given base IP (without port)
+// IPCount returns the number of connections for a given base IP (without port).
+// IPv6 addresses are counted by their /64 prefix, see iputil.IPGroup.
 func (c *Connections) IPCount(ip string) int {
 	c.Lock()
 	defer c.Unlock()
-	return c.ipCounts[ip]
+	return c.ipCounts[iputil.IPGroup(ip)]
 }
 
 // Len returns number of connections
@@ -512,8 +552,8 @@ func (c *Connections) remove(addr string, gnetID uint64) error {
 		delete(c.mirrors, conn.Mirror)
 	}
 
-	if c.ipCounts[ip] > 0 {
-		c.ipCounts[ip]--
+	if ipGroup := iputil.IPGroup(ip); c.ipCounts[ipGroup] > 0 {
+		c.ipCounts[ipGroup]--
 	} else {
 		logger.Critical().WithFields(fields).Warning("ipCount was already 0 when removing existing address")
 	}
@@ -400,6 +400,45 @@ func TestConnectionsMultipleSameListenPort(t *testing.T) {
 	require.Len(t, conns.listenAddrs, 0)
 }
 
+func TestConnectionsIPv6(t *testing.T) {
+	conns := NewConnections()
+
+	addr1 := "[2001:db8::1]:6060"
+	addr2 := "[2001:db8::2]:6060"
+	addr3 := "[2001:db8:0:1::1]:6060"
+
+	_, err := conns.pending(addr1)
+	require.NoError(t, err)
+	_, err = conns.connected(addr2, 2)
+	require.NoError(t, err)
+	_, err = conns.connected(addr3, 3)
+	require.NoError(t, err)
+
+	// IPv6 addresses in the same /64 are counted together
+	require.Equal(t, 2, conns.IPCount("2001:db8::1"))
+	require.Equal(t, 2, conns.IPCount("2001:db8::ffff"))
+	require.Equal(t, 1, conns.IPCount("2001:db8:0:1::2"))
+
+	c, err := conns.introduced(addr2, 2, &IntroductionMessage{
+		Mirror:          6,
+		ListenPort:      6061,
+		ProtocolVersion: 2,
+		UserAgent:       userAgent,
+	})
+	require.NoError(t, err)
+	require.Equal(t, "[2001:db8::2]:6061", c.ListenAddr())
+	require.Equal(t, []*connection{c}, conns.getByListenAddr("[2001:db8::2]:6061"))
+
+	err = conns.remove(addr1, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, conns.IPCount("2001:db8::1"))
+
+	err = conns.remove(addr2, 2)
+	require.NoError(t, err)
+	require.Equal(t, 0, conns.IPCount("2001:db8::1"))
+	require.Equal(t, 1, conns.IPCount("2001:db8:0:1::1"))
+}
+
 func TestConnectionsErrors(t *testing.T) {
 	conns := NewConnections()
 
@@ -511,3 +550,43 @@ func TestConnectionsStateTransitionErrors(t *testing.T) {
 	_, err = conns.introduced(addr, 1, &IntroductionMessage{})
 	require.Equal(t, ErrConnectionAlreadyIntroduced, err)
 }
+
+func TestConnectionsOnion(t *testing.T) {
+	conns := NewConnections()
+
+	onionAddr := "vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd.onion:6000"
+	incomingAddr := "127.0.0.1:50000"
+
+	// Outgoing connections to onion peers are made through the proxy and use the onion address as addr
+	_, err := conns.pending(onionAddr)
+	require.NoError(t, err)
+	_, err = conns.connected(onionAddr, 1)
+	require.NoError(t, err)
+	require.Equal(t, 1, conns.IPCount("vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd.onion"))
+
+	c, err := conns.introduced(onionAddr, 1, &IntroductionMessage{
+		Mirror:          6,
+		ListenPort:      6000,
+		ProtocolVersion: 2,
+		UserAgent:       userAgent,
+	})
+	require.NoError(t, err)
+	require.Equal(t, onionAddr, c.ListenAddr())
+
+	// Incoming connections from the onion service report their onion address in the introduction
+	_, err = conns.connected(incomingAddr, 2)
+	require.NoError(t, err)
+
+	otherOnionAddr := "pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd.onion:6000"
+	c, err = conns.introduced(incomingAddr, 2, &IntroductionMessage{
+		Mirror:          7,
+		ListenPort:      6000,
+		ProtocolVersion: 2,
+		UserAgent:       userAgent,
+		OnionAddress:    otherOnionAddr,
+	})
+	require.NoError(t, err)
+	require.Equal(t, otherOnionAddr, c.OnionAddress)
+	require.Equal(t, otherOnionAddr, c.ListenAddr())
+	require.Equal(t, []*connection{c}, conns.getByListenAddr(otherOnionAddr))
+}
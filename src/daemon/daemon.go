@@ -16,17 +16,19 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/skycoin/skycoin/src/transaction"
 
+	"github.com/ness-network/ness/src/consensus"
+	"github.com/ness-network/ness/src/daemon/gnet"
+	"github.com/ness-network/ness/src/daemon/pex"
+	"github.com/ness-network/ness/src/util/iputil"
+	"github.com/ness-network/ness/src/visor"
+	"github.com/ness-network/ness/src/visor/blockdb"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
-	"github.com/skycoin/skycoin/src/daemon/gnet"
-	"github.com/skycoin/skycoin/src/daemon/pex"
 	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/util/elapse"
 	"github.com/skycoin/skycoin/src/util/fee"
-	"github.com/skycoin/skycoin/src/util/iputil"
 	"github.com/skycoin/skycoin/src/util/logging"
 	"github.com/skycoin/skycoin/src/util/useragent"
-	"github.com/skycoin/skycoin/src/visor"
 	"github.com/skycoin/skycoin/src/visor/dbutil"
 )
 
@@ -92,6 +94,27 @@ func (cfg *Config) preprocess() (Config, error) {
 		}
 		config.Pex.AllowLocalhost = true
 	}
+
+	if config.Daemon.OnionAddress != "" {
+		host, port, err := iputil.SplitAddr(config.Daemon.OnionAddress)
+		if err != nil || !iputil.IsOnion(host) {
+			return Config{}, fmt.Errorf("invalid onion address %q", config.Daemon.OnionAddress)
+		}
+		// The onion address is sent after the node pubkey in the introduction message
+		if config.Daemon.EncryptionPolicy == EncryptionPolicyDisabled {
+			return Config{}, errors.New("OnionAddress requires an EncryptionPolicy other than disabled")
+		}
+		config.Daemon.OnionAddress = iputil.JoinAddr(strings.ToLower(host), port)
+
+		if config.Daemon.Address == "" {
+			config.Daemon.Address = "127.0.0.1"
+		}
+		logger.WithField("onionAddress", config.Daemon.OnionAddress).Info("Listening for connections through the onion service only")
+	}
+
+	// Onion peers can only be reached through a Tor SOCKS5 proxy
+	config.Pex.OnionPeers = config.Pool.ProxyAddress != ""
+
 	config.Pool.port = config.Daemon.Port
 	config.Pool.address = config.Daemon.Address
 
@@ -125,6 +148,21 @@ func (cfg *Config) preprocess() (Config, error) {
 		return Config{}, fmt.Errorf("MaxOutgoingMessageLength must be >= %d", maxSizeGBM)
 	}
 
+	// Block publishers also send blocks in a BlockCandidateMessage
+	if len(config.Daemon.PublisherPubkeys) != 0 {
+		maxSizeBCM := maxSizeBlockCandidateMessage(config.Daemon.MaxBlockTransactionsSize)
+		if config.Daemon.MaxOutgoingMessageLength < maxSizeBCM {
+			return Config{}, fmt.Errorf("MaxOutgoingMessageLength must be >= %d", maxSizeBCM)
+		}
+	}
+
+	if config.Daemon.PublisherQuorum < 0 || config.Daemon.PublisherQuorum > len(config.Daemon.PublisherPubkeys) {
+		return Config{}, errors.New("PublisherQuorum must be between 0 and the number of PublisherPubkeys")
+	}
+	if len(config.Daemon.PublisherPubkeys) != 0 && config.Daemon.PublisherQuorum == 0 {
+		config.Daemon.PublisherQuorum = len(config.Daemon.PublisherPubkeys)/2 + 1
+	}
+
 	userAgent, err := config.Daemon.UserAgent.Build()
 	if err != nil {
 		return Config{}, err
@@ -134,6 +172,34 @@ func (cfg *Config) preprocess() (Config, error) {
 	}
 	config.Daemon.userAgent = userAgent
 
+	switch config.Daemon.EncryptionPolicy {
+	case EncryptionPolicyDisabled:
+		config.Daemon.NodeSeckey = cipher.SecKey{}
+	case EncryptionPolicyPreferred, EncryptionPolicyRequired:
+		if config.Daemon.NodeSeckey.Null() {
+			if config.Daemon.DataDirectory != "" {
+				sk, err := loadOrCreateNodeSeckey(config.Daemon.DataDirectory)
+				if err != nil {
+					return Config{}, err
+				}
+				config.Daemon.NodeSeckey = sk
+			} else {
+				_, config.Daemon.NodeSeckey = cipher.GenerateKeyPair()
+			}
+		}
+		if err := config.Daemon.NodeSeckey.Verify(); err != nil {
+			return Config{}, fmt.Errorf("invalid node seckey: %v", err)
+		}
+		pubkey, err := cipher.PubKeyFromSecKey(config.Daemon.NodeSeckey)
+		if err != nil {
+			return Config{}, fmt.Errorf("invalid node seckey: %v", err)
+		}
+		config.Daemon.nodePubkey = pubkey
+	default:
+		return Config{}, fmt.Errorf("invalid encryption policy %q", config.Daemon.EncryptionPolicy)
+	}
+	config.Pool.nodeSeckey = config.Daemon.NodeSeckey
+
 	return config, nil
 }
 
@@ -147,6 +213,27 @@ func maxSizeGiveBlocksMessage(maxBlockSize uint32) uint64 {
 	return size
 }
 
+// maxSizeBlockCandidateMessage return the encoded size of a BlockCandidateMessage
+// with a block of the largest possible size
+func maxSizeBlockCandidateMessage(maxBlockSize uint32) uint64 {
+	size := uint64(4)                                                 // message type prefix
+	size += encodeSizeBlockCandidateMessage(&BlockCandidateMessage{}) // size of a BlockCandidateMessage with an empty block
+	size += uint64(maxBlockSize)                                      // maximum size of all transactions in a block
+	return size
+}
+
+// EncryptionPolicy controls whether peer connections are upgraded to encrypted sessions
+type EncryptionPolicy string
+
+const (
+	// EncryptionPolicyDisabled never encrypts peer connections
+	EncryptionPolicyDisabled EncryptionPolicy = "disabled"
+	// EncryptionPolicyPreferred encrypts connections to peers that support it and keeps plaintext connections to peers that don't
+	EncryptionPolicyPreferred EncryptionPolicy = "preferred"
+	// EncryptionPolicyRequired disconnects peers that cannot establish an encrypted session
+	EncryptionPolicyRequired EncryptionPolicy = "required"
+)
+
 // DaemonConfig configuration for the Daemon
 type DaemonConfig struct { //nolint:golint
 	// Protocol version. TODO -- manage version better
@@ -157,6 +244,11 @@ type DaemonConfig struct { //nolint:golint
 	Address string
 	// BlockchainPubkey blockchain pubkey string
 	BlockchainPubkey cipher.PubKey
+	// Pubkeys of the block publishers that settle blocks through the consensus package.
+	// If empty, blocks are created by the single block publisher with BlockchainPubkey
+	PublisherPubkeys []cipher.PubKey
+	// Number of block publishers that must sign a block before it is executed. Defaults to a majority of PublisherPubkeys
+	PublisherQuorum int
 	// GenesisHash genesis block hash
 	GenesisHash cipher.SHA256
 	// TCP/UDP port for connections
@@ -179,8 +271,17 @@ type DaemonConfig struct { //nolint:golint
 	CullInvalidRate time.Duration
 	// How often to update the database with transaction announcement timestamps
 	FlushAnnouncedTxnsRate time.Duration
-	// How many connections are allowed from the same base IP
+	// How many connections are allowed from the same base IP.
+	// IPv6 addresses are counted by their /64 prefix.
 	IPCountsMax int
+	// Misbehavior score at which a peer is banned. Use 0 to disable banning misbehaving peers
+	BanThreshold int
+	// How long a misbehaving peer is banned for.
+	// Misbehavior scores are forgotten when a peer has not misbehaved for this long
+	BanDuration time.Duration
+	// Token bucket limits of the messages received from a peer, by message type, e.g. "GETB".
+	// Messages over the limit are dropped, and each dropped message increases the peer's misbehavior score
+	MessageRateLimits map[string]RateLimit
 	// Disable all networking activity
 	DisableNetworking bool
 	// Don't make outgoing connections
@@ -199,6 +300,20 @@ type DaemonConfig struct { //nolint:golint
 	GetBlocksRequestCount uint64
 	// Maximum number of blocks to respond with to a GetBlocksMessage
 	MaxGetBlocksResponseCount uint64
+	// How many headers to request in a GetHeadersMessage
+	GetHeadersRequestCount uint64
+	// Maximum number of headers to respond with to a GetHeadersMessage
+	MaxGetHeadersResponseCount uint64
+	// How often to schedule block downloads during headers-first sync
+	BlockDownloadRate time.Duration
+	// How long to wait for a block download before requesting the blocks from another peer
+	BlockDownloadTimeout time.Duration
+	// Maximum number of block downloads in flight to a single peer
+	MaxBlockDownloadsPerPeer int
+	// How many blocks ahead of the head block to download during headers-first sync
+	BlockDownloadWindow uint64
+	// How many block headers ahead of the head block to keep during headers-first sync
+	MaxHeadersAhead uint64
 	// Max announce txns hash number
 	MaxTxnAnnounceNum int
 	// How often new blocks are created by the signing node, in seconds
@@ -224,12 +339,27 @@ type DaemonConfig struct { //nolint:golint
 	MaxBlockTransactionsSize uint32
 	// Maximum number of blocks to response on /api/v1/last_blocks API
 	MaxLastBlocksCount uint64
+	// Onion address of the Tor onion service that forwards to this node, e.g. "xxx.onion:6677".
+	// It is advertised to peers in the introduction message instead of the node's IP.
+	// The node listens on localhost if Address is not set, so it is only reachable through the onion service
+	OnionAddress string
+	// Whether to upgrade peer connections to encrypted sessions
+	EncryptionPolicy EncryptionPolicy
+	// Node identity secret key for encrypted sessions. If not set, it is loaded from NodeKeyFilename in DataDirectory
+	// in preprocess(), or generated and saved there. Without a DataDirectory, a random key is generated on every start
+	NodeSeckey cipher.SecKey
+	nodePubkey cipher.PubKey // derived from NodeSeckey in preprocess(), sent in introduction messages
+}
+
+// blockSigners returns the pubkeys that may sign blocks
+func (dc DaemonConfig) blockSigners() []cipher.PubKey {
+	return append([]cipher.PubKey{dc.BlockchainPubkey}, dc.PublisherPubkeys...)
 }
 
 // NewDaemonConfig creates daemon config
 func NewDaemonConfig() DaemonConfig {
 	return DaemonConfig{
-		ProtocolVersion:              2,
+		ProtocolVersion:              8,
 		MinProtocolVersion:           2,
 		Address:                      "",
 		Port:                         6677,
@@ -242,6 +372,9 @@ func NewDaemonConfig() DaemonConfig {
 		CullInvalidRate:              time.Second * 3,
 		FlushAnnouncedTxnsRate:       time.Second * 3,
 		IPCountsMax:                  3,
+		BanThreshold:                 100,
+		BanDuration:                  time.Hour * 24,
+		MessageRateLimits:            defaultMessageRateLimits(),
 		DisableNetworking:            false,
 		DisableOutgoingConnections:   false,
 		DisableIncomingConnections:   false,
@@ -251,6 +384,13 @@ func NewDaemonConfig() DaemonConfig {
 		BlocksAnnounceRate:           time.Second * 60,
 		GetBlocksRequestCount:        20,
 		MaxGetBlocksResponseCount:    20,
+		GetHeadersRequestCount:       1024,
+		MaxGetHeadersResponseCount:   1024,
+		BlockDownloadRate:            time.Second,
+		BlockDownloadTimeout:         time.Second * 30,
+		MaxBlockDownloadsPerPeer:     4,
+		BlockDownloadWindow:          1024,
+		MaxHeadersAhead:              16384,
 		MaxTxnAnnounceNum:            16,
 		BlockCreationInterval:        10,
 		UnconfirmedRefreshRate:       time.Minute,
@@ -260,6 +400,7 @@ func NewDaemonConfig() DaemonConfig {
 		MaxOutgoingMessageLength:     256 * 1024,
 		MaxIncomingMessageLength:     1024 * 1024,
 		MaxBlockTransactionsSize:     32768,
+		EncryptionPolicy:             EncryptionPolicyPreferred,
 	}
 }
 
@@ -272,11 +413,12 @@ type daemoner interface {
 	sendMessage(addr string, msg gnet.Message) error
 	broadcastMessage(msg gnet.Message) ([]uint64, error)
 	disconnectNow(addr string, r gnet.DisconnectReason) error
-	addPeers(addrs []string) int
+	addPeers(addrs []string, source string) int
 	recordPeerHeight(addr string, gnetID, height uint64)
 	getSignedBlocksSince(seq, count uint64) ([]coin.SignedBlock, error)
 	headBkSeq() (uint64, bool, error)
 	executeSignedBlock(b coin.SignedBlock) error
+	addSideBlock(b coin.SignedBlock) error
 	filterKnownUnconfirmed(txns []cipher.SHA256) ([]cipher.SHA256, error)
 	getKnownUnconfirmed(txns []cipher.SHA256) (coin.Transactions, error)
 	requestBlocksFromAddr(addr string) error
@@ -286,6 +428,24 @@ type daemoner interface {
 	recordMessageEvent(m asyncMessage, c *gnet.MessageContext) error
 	connectionIntroduced(addr string, gnetID uint64, m *IntroductionMessage) (*connection, error)
 	sendRandomPeers(addr string) error
+	secureConnection(addr string, m *IntroductionMessage) error
+	getBlockHeadersSince(seq, count uint64) ([]SignedBlockHeader, error)
+	addBlockHeaders(headers []SignedBlockHeader) (int, error)
+	addSyncBlocks(addr string, blocks []coin.SignedBlock) ([]coin.SignedBlock, bool, error)
+	downloadBlocks() error
+	reconstructCompactBlock(addr string, header SignedBlockHeader, shortIDs []uint64) (*coin.SignedBlock, []uint64, error)
+	completeCompactBlock(addr string, blockHash cipher.SHA256, txns coin.Transactions) (*coin.SignedBlock, error)
+	getBlockTxns(blockHash cipher.SHA256, indexes []uint64) (coin.Transactions, error)
+	relayCompactBlock(addr string, b coin.SignedBlock) error
+	addBlockCandidate(candidate consensus.BlockBase, b coin.Block) error
+	blockchainPubkeys() []cipher.PubKey
+	blockSigners() []cipher.PubKey
+	addKeyCheckpoint(kc blockdb.KeyCheckpoint) (bool, error)
+	sendKeyCheckpoints(addr string) error
+	addBlockCertificates(certs []blockdb.BlockCertificate) int
+	sendBlockCertificates(addr string, blocks []coin.SignedBlock) error
+	relayKeyCheckpoints(exclude string, checkpoints []blockdb.KeyCheckpoint) error
+	misbehaved(addr string, reason error)
 }
 
 // Daemon stateful properties of the daemon
@@ -303,6 +463,18 @@ type Daemon struct {
 	announcedTxns *announcedTxnsCache
 	// Cache of connection metadata
 	connections *Connections
+	// Misbehavior scores of peers
+	misbehavior *misbehaviors
+	// Rate limits of the messages received from peers
+	rateLimiter *messageRateLimiter
+	// Headers-first block synchronization state
+	headerSync *headerSync
+	// Compact blocks waiting for missing transactions
+	compactBlocks *compactBlocks
+	// Block publisher signatures received for blocks that are not executed yet
+	blockCertificates *blockCertificates
+	// Block candidates of the block publishers, nil if PublisherPubkeys is not configured
+	publisherConsensus *publisherConsensus
 	// connect, disconnect, message, error events channel
 	events chan interface{}
 	// quit channel
@@ -332,11 +504,16 @@ func New(config Config, v *visor.Visor) (*Daemon, error) {
 		pex:      pex,
 		visor:    v,
 
-		announcedTxns: newAnnouncedTxnsCache(),
-		connections:   NewConnections(),
-		events:        make(chan interface{}, config.Pool.EventChannelSize),
-		quit:          make(chan struct{}),
-		done:          make(chan struct{}),
+		announcedTxns:     newAnnouncedTxnsCache(),
+		connections:       NewConnections(),
+		misbehavior:       newMisbehaviors(),
+		rateLimiter:       newMessageRateLimiter(config.Daemon.MessageRateLimits),
+		headerSync:        newHeaderSync(config.Daemon.blockSigners()...),
+		compactBlocks:     newCompactBlocks(),
+		blockCertificates: newBlockCertificates(config.Daemon.PublisherPubkeys),
+		events:            make(chan interface{}, config.Pool.EventChannelSize),
+		quit:              make(chan struct{}),
+		done:              make(chan struct{}),
 	}
 
 	d.pool, err = NewPool(config.Pool, d)
@@ -344,6 +521,23 @@ func New(config Config, v *visor.Visor) (*Daemon, error) {
 		return nil, err
 	}
 
+	if len(config.Daemon.PublisherPubkeys) != 0 {
+		var seckey cipher.SecKey
+		if v.Config.IsBlockPublisher {
+			seckey = v.Config.BlockchainSeckey
+		}
+
+		manager := newPublisherConnectionManager(d.connections, func(m gnet.Message, addrs []string) error {
+			if d.config.DisableNetworking {
+				return ErrNetworkingDisabled
+			}
+			_, err := d.pool.Pool.BroadcastMessage(m, addrs)
+			return err
+		})
+
+		d.publisherConsensus = newPublisherConsensus(config.Daemon.PublisherPubkeys, config.Daemon.PublisherQuorum, seckey, manager)
+	}
+
 	return d, nil
 }
 
@@ -398,10 +592,17 @@ func (dm *Daemon) Run() error {
 	defer close(dm.done)
 
 	logger.Infof("Daemon UserAgent is %s", dm.config.userAgent)
+	logger.Infof("Daemon EncryptionPolicy is %s", dm.config.EncryptionPolicy)
+	if !dm.config.nodePubkey.Null() {
+		logger.Infof("Daemon node pubkey is %s", dm.config.nodePubkey.Hex())
+	}
 	logger.Infof("Daemon unconfirmed BurnFactor is %d", dm.config.UnconfirmedVerifyTxn.BurnFactor)
 	logger.Infof("Daemon unconfirmed MaxTransactionSize is %d", dm.config.UnconfirmedVerifyTxn.MaxTransactionSize)
 	logger.Infof("Daemon unconfirmed MaxDropletPrecision is %d", dm.config.UnconfirmedVerifyTxn.MaxDropletPrecision)
 
+	// Block headers may be signed by the pubkeys of the key checkpoints stored in the db
+	dm.headerSync.setPubkeys(dm.blockSigners()...)
+
 	errC := make(chan error, 5)
 	var wg sync.WaitGroup
 	wg.Add(1)
@@ -419,6 +620,8 @@ func (dm *Daemon) Run() error {
 	defer blocksRequestTicker.Stop()
 	blocksAnnounceTicker := time.NewTicker(dm.config.BlocksAnnounceRate)
 	defer blocksAnnounceTicker.Stop()
+	blockDownloadTicker := time.NewTicker(dm.config.BlockDownloadRate)
+	defer blockDownloadTicker.Stop()
 
 	flushAnnouncedTxnsTicker := time.NewTicker(dm.config.FlushAnnouncedTxnsRate)
 	defer flushAnnouncedTxnsTicker.Stop()
@@ -440,6 +643,11 @@ func (dm *Daemon) Run() error {
 	go dm.startMessageSendResultProcess(&wg)
 	wg.Add(1)
 	go dm.startUnconfirmedTxnsProcess(&wg)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		dm.visor.RunWalletAddressDiscovery(dm.quit)
+	}()
 
 loop:
 	for {
@@ -467,6 +675,13 @@ loop:
 		case <-blockCreationTicker.C:
 			// Create blocks, if block publisher
 			elapser.Register("blockCreationTicker.C")
+			if dm.publisherConsensus != nil {
+				if err := dm.proposeBlock(); err != nil {
+					logger.WithError(err).Error("Failed to propose block")
+				}
+				continue
+			}
+
 			if dm.visor.Config.IsBlockPublisher {
 				sb, err := dm.createAndPublishBlock()
 				if err != nil {
@@ -495,6 +710,15 @@ loop:
 				logger.WithError(err).Warning("announceBlocks failed")
 			}
 
+		case <-blockDownloadTicker.C:
+			elapser.Register("blockDownloadTicker")
+			if dm.config.DisableNetworking {
+				continue
+			}
+			if err := dm.downloadBlocks(); err != nil {
+				logger.WithError(err).Warning("downloadBlocks failed")
+			}
+
 		case setupErr = <-errC:
 			logger.WithError(setupErr).Error("read from errc")
 			break loop
@@ -643,6 +867,9 @@ func (dm *Daemon) startConnPool(wg *sync.WaitGroup, errC chan error) {
 					}
 				}
 			}
+
+			// Forget the misbehavior of peers that have behaved for BanDuration
+			dm.misbehavior.clearOld(time.Now().Add(-dm.config.BanDuration))
 		}
 	}
 }
@@ -805,8 +1032,16 @@ func (dm *Daemon) connectToRandomPeer() {
 		return
 	}
 
-	// Make a connection to a random (public) peer
-	peers := dm.pex.Random(dm.config.MaxOutgoingConnections - dm.connections.OutgoingLen())
+	// Make a connection to a random (public) peer, preferring network groups
+	// that we don't have an outgoing connection to yet
+	var outgoing []string
+	for _, c := range dm.connections.all() {
+		if c.Outgoing {
+			outgoing = append(outgoing, c.Addr)
+		}
+	}
+
+	peers := dm.pex.Random(dm.config.MaxOutgoingConnections-dm.connections.OutgoingLen(), outgoing)
 	for _, p := range peers {
 		if err := dm.connectToPeer(p); err != nil {
 			logger.WithError(err).WithField("addr", p.Addr).Warning("connectToPeer failed")
@@ -912,6 +1147,19 @@ func (dm *Daemon) onMessageEvent(e messageEvent) {
 		}
 	}
 
+	// Drop messages that exceed the rate limit of their type
+	msgType := gnet.MessageType(e.Message)
+	if allowed, overrun := dm.rateLimiter.allow(e.Context.Addr, msgType, time.Now()); !allowed {
+		logger.WithFields(logrus.Fields{
+			"addr":        e.Context.Addr,
+			"messageType": msgType,
+		}).Debug("Message rate limit exceeded, dropping message")
+		if overrun {
+			dm.misbehaved(e.Context.Addr, errMisbehaviorRateLimited)
+		}
+		return
+	}
+
 	e.Message.process(dm)
 }
 
@@ -939,6 +1187,14 @@ func (dm *Daemon) onConnectEvent(e ConnectEvent) {
 		logger.Critical().WithFields(fields).Warning("Connection.Outgoing does not match ConnectEvent.Solicited state")
 	}
 
+	if dm.pex.IsBanned(e.Addr) {
+		logger.WithFields(fields).Info("Peer is banned, disconnecting")
+		if err := dm.Disconnect(e.Addr, ErrDisconnectIsBlacklisted); err != nil {
+			logger.WithError(err).WithFields(fields).Error("Disconnect")
+		}
+		return
+	}
+
 	if dm.ipCountMaxed(e.Addr) {
 		logger.WithFields(fields).Info("Max connections for this IP address reached, disconnecting")
 		if err := dm.Disconnect(e.Addr, ErrDisconnectIPLimitReached); err != nil {
@@ -957,6 +1213,8 @@ func (dm *Daemon) onConnectEvent(e ConnectEvent) {
 		dm.config.userAgent,
 		dm.config.UnconfirmedVerifyTxn,
 		dm.config.GenesisHash,
+		dm.config.nodePubkey,
+		dm.config.OnionAddress,
 	)); err != nil {
 		logger.WithFields(fields).WithError(err).Error("Send IntroductionMessage failed")
 		return
@@ -976,12 +1234,22 @@ func (dm *Daemon) onDisconnectEvent(e DisconnectEvent) {
 		return
 	}
 
-	// TODO -- blacklist peer for certain reasons, not just remove
+	// Request the blocks that were being downloaded from this peer from other peers
+	dm.headerSync.removePeer(e.Addr)
+
+	dm.rateLimiter.remove(e.Addr)
+
+	// Ban peers whose misbehavior score reaches the BanThreshold
+	dm.misbehaved(e.Addr, e.Reason)
+
 	switch e.Reason {
 	case ErrDisconnectIntroductionTimeout,
 		ErrDisconnectBlockchainPubkeyNotMatched,
 		ErrDisconnectInvalidExtraData,
-		ErrDisconnectInvalidUserAgent:
+		ErrDisconnectInvalidUserAgent,
+		ErrDisconnectEncryptionRequired,
+		ErrDisconnectInvalidBlockHeaders,
+		ErrDisconnectBlockHeaderMismatch:
 		if !dm.isTrustedPeer(e.Addr) {
 			dm.pex.RemovePeer(e.Addr)
 		}
@@ -1051,6 +1319,11 @@ func (dm *Daemon) ipCountMaxed(addr string) bool {
 		return true
 	}
 
+	// Connections through the onion service all come from the Tor daemon on localhost
+	if dm.config.OnionAddress != "" && iputil.IsLocalhost(ip) {
+		return false
+	}
+
 	return !dm.config.LocalhostOnly && dm.connections.IPCount(ip) >= dm.config.IPCountsMax
 }
 
@@ -1084,7 +1357,8 @@ func (dm *Daemon) handleMessageSendResult(r gnet.SendResult) {
 	}
 }
 
-// requestBlocks sends a GetBlocksMessage to all connections
+// requestBlocks sends a GetHeadersMessage to connections that support headers-first sync
+// and a GetBlocksMessage to all other connections
 func (dm *Daemon) requestBlocks() error {
 	if dm.config.DisableNetworking {
 		return ErrNetworkingDisabled
@@ -1098,9 +1372,30 @@ func (dm *Daemon) requestBlocks() error {
 		return errors.New("Cannot request blocks, there is no head block")
 	}
 
+	var addrs []string
+	for _, c := range dm.connections.all() {
+		if !c.HasIntroduced() {
+			continue
+		}
+
+		if c.SupportsHeadersSync() {
+			m := NewGetHeadersMessage(dm.headerSync.tipSeq(headSeq), dm.config.GetHeadersRequestCount)
+			if err := dm.sendMessage(c.Addr, m); err != nil {
+				logger.WithError(err).WithField("addr", c.Addr).Debug("Send GetHeadersMessage failed")
+			}
+			continue
+		}
+
+		addrs = append(addrs, c.Addr)
+	}
+
+	if len(addrs) == 0 {
+		return nil
+	}
+
 	m := NewGetBlocksMessage(headSeq, dm.config.GetBlocksRequestCount)
 
-	if _, err := dm.broadcastMessage(m); err != nil {
+	if _, err := dm.pool.Pool.BroadcastMessage(m, addrs); err != nil {
 		logger.WithError(err).Debug("Broadcast GetBlocksMessage failed")
 		return err
 	}
@@ -1108,6 +1403,47 @@ func (dm *Daemon) requestBlocks() error {
 	return nil
 }
 
+// downloadBlocks requests the blocks of the verified header chain from peers that support headers-first sync
+func (dm *Daemon) downloadBlocks() error {
+	if dm.config.DisableNetworking {
+		return ErrNetworkingDisabled
+	}
+
+	headSeq, ok, err := dm.visor.HeadBkSeq()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("Cannot download blocks, there is no head block")
+	}
+
+	var peers []syncPeer
+	for _, c := range dm.connections.all() {
+		if c.SupportsHeadersSync() {
+			peers = append(peers, syncPeer{
+				addr:   c.Addr,
+				height: c.Height,
+			})
+		}
+	}
+
+	downloads := dm.headerSync.schedule(headSeq, peers, time.Now(), headerSyncConfig{
+		requestCount:        dm.config.GetBlocksRequestCount,
+		maxDownloadsPerPeer: dm.config.MaxBlockDownloadsPerPeer,
+		window:              dm.config.BlockDownloadWindow,
+		timeout:             dm.config.BlockDownloadTimeout,
+	})
+
+	for _, d := range downloads {
+		m := NewGetBlocksMessage(d.start-1, d.count)
+		if err := dm.sendMessage(d.addr, m); err != nil {
+			logger.WithError(err).WithField("addr", d.addr).Debug("Send GetBlocksMessage failed")
+		}
+	}
+
+	return nil
+}
+
 // announceBlocks sends an AnnounceBlocksMessage to all connections
 func (dm *Daemon) announceBlocks() error {
 	if dm.config.DisableNetworking {
@@ -1154,6 +1490,72 @@ func (dm *Daemon) createAndPublishBlock() (*coin.SignedBlock, error) {
 	return &sb, err
 }
 
+// proposeBlock creates a block from unconfirmed transactions and sends it to the other block publishers as a block candidate.
+// The block is executed once the block publishers settle on it.
+// Will panic if not running as a block publisher.
+func (dm *Daemon) proposeBlock() error {
+	if dm.config.DisableNetworking {
+		return ErrNetworkingDisabled
+	}
+
+	b, err := dm.visor.CreateBlock()
+	if err != nil {
+		return err
+	}
+
+	if _, err := dm.publisherConsensus.propose(b); err != nil {
+		return err
+	}
+
+	logger.WithFields(logrus.Fields{
+		"seq":  b.Head.BkSeq,
+		"hash": b.HashHeader().Hex(),
+	}).Info("Proposed a new block candidate")
+
+	return dm.settleBlockCandidates()
+}
+
+// settleBlockCandidates executes the next block once the block publishers settled on it, and sends it to peers
+func (dm *Daemon) settleBlockCandidates() error {
+	if dm.publisherConsensus == nil {
+		return nil
+	}
+
+	headSeq, ok, err := dm.visor.HeadBkSeq()
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return nil
+	}
+
+	dm.publisherConsensus.pruneThrough(headSeq)
+
+	sb, sigs := dm.publisherConsensus.settle(headSeq + 1)
+	if sb == nil {
+		return nil
+	}
+
+	if err := dm.visor.ExecuteCertifiedBlock(*sb, sigs); err != nil {
+		return err
+	}
+
+	// Not a critical error, but we want it visible in logs
+	head := sb.Block.Head
+	logger.Critical().WithFields(logrus.Fields{
+		"version": head.Version,
+		"seq":     head.BkSeq,
+		"time":    head.Time,
+	}).Info("Added new block settled by the block publishers")
+
+	if dm.config.DisableNetworking {
+		return nil
+	}
+
+	return dm.relayCompactBlock("", *sb)
+}
+
 // ResendUnconfirmedTxns resends all unconfirmed transactions and returns the hashes that were successfully rebroadcast.
 // It does not return an error if broadcasting fails.
 func (dm *Daemon) ResendUnconfirmedTxns() ([]cipher.SHA256, error) {
@@ -1317,7 +1719,8 @@ func (dm *Daemon) Disconnect(addr string, r gnet.DisconnectReason) error {
 
 // Implements private daemoner interface methods:
 
-// requestBlocksFromAddr sends a GetBlocksMessage to one connected address
+// requestBlocksFromAddr sends a GetHeadersMessage to one connected address if it supports
+// headers-first sync, otherwise a GetBlocksMessage
 func (dm *Daemon) requestBlocksFromAddr(addr string) error {
 	if dm.config.DisableNetworking {
 		return ErrNetworkingDisabled
@@ -1331,25 +1734,92 @@ func (dm *Daemon) requestBlocksFromAddr(addr string) error {
 		return errors.New("Cannot request blocks from addr, there is no head block")
 	}
 
+	if c := dm.connections.get(addr); c != nil && c.SupportsHeadersSync() {
+		m := NewGetHeadersMessage(dm.headerSync.tipSeq(headSeq), dm.config.GetHeadersRequestCount)
+		return dm.sendMessage(addr, m)
+	}
+
 	m := NewGetBlocksMessage(headSeq, dm.config.GetBlocksRequestCount)
 	return dm.sendMessage(addr, m)
 }
 
-// broadcastBlock sends a signed block to all connections
+// broadcastBlock sends a signed block to all connections.
+// Connections that support compact blocks receive a CompactBlockMessage instead of the full block.
 func (dm *Daemon) broadcastBlock(sb coin.SignedBlock) error {
 	if dm.config.DisableNetworking {
 		return ErrNetworkingDisabled
 	}
 
+	compactAddrs, addrs := dm.compactBlockAddrs("", sb)
+
+	if len(compactAddrs) != 0 {
+		if _, err := dm.pool.Pool.BroadcastMessage(NewCompactBlockMessage(sb), compactAddrs); err != nil {
+			logger.WithError(err).Warning("Broadcast CompactBlockMessage failed")
+		}
+	}
+
+	if len(addrs) == 0 {
+		return nil
+	}
+
 	m := NewGiveBlocksMessage([]coin.SignedBlock{sb}, dm.config.MaxOutgoingMessageLength)
 	if len(m.Blocks) != 1 {
 		logger.Critical().Error("NewGiveBlocksMessage truncated its only block")
 	}
 
-	_, err := dm.broadcastMessage(m)
+	_, err := dm.pool.Pool.BroadcastMessage(m, addrs)
+	return err
+}
+
+// relayCompactBlock sends a CompactBlockMessage for a block received from addr to the other connections
+// that support compact blocks, and announces the block to the rest
+func (dm *Daemon) relayCompactBlock(addr string, sb coin.SignedBlock) error {
+	if dm.config.DisableNetworking {
+		return ErrNetworkingDisabled
+	}
+
+	compactAddrs, addrs := dm.compactBlockAddrs(addr, sb)
+
+	if len(compactAddrs) != 0 {
+		// The certificate goes first, so that the block can be executed once it is rebuilt
+		if err := dm.broadcastBlockCertificates(compactAddrs, []coin.SignedBlock{sb}); err != nil {
+			logger.WithError(err).Warning("broadcastBlockCertificates failed")
+		}
+
+		if _, err := dm.pool.Pool.BroadcastMessage(NewCompactBlockMessage(sb), compactAddrs); err != nil {
+			logger.WithError(err).Warning("Broadcast CompactBlockMessage failed")
+		}
+	}
+
+	if len(addrs) == 0 {
+		return nil
+	}
+
+	_, err := dm.pool.Pool.BroadcastMessage(NewAnnounceBlocksMessage(sb.Seq()), addrs)
 	return err
 }
 
+// compactBlockAddrs splits the introduced connections other than exclude into the connections
+// that can receive the block as a CompactBlockMessage and the rest
+func (dm *Daemon) compactBlockAddrs(exclude string, sb coin.SignedBlock) ([]string, []string) {
+	compact := len(sb.Body.Transactions) <= maxCompactBlockTxns
+
+	var compactAddrs, addrs []string
+	for _, c := range dm.connections.all() {
+		if !c.HasIntroduced() || c.Addr == exclude {
+			continue
+		}
+
+		if compact && c.SupportsCompactBlocks() {
+			compactAddrs = append(compactAddrs, c.Addr)
+		} else {
+			addrs = append(addrs, c.Addr)
+		}
+	}
+
+	return compactAddrs, addrs
+}
+
 // DaemonConfig returns the daemon config
 func (dm *Daemon) DaemonConfig() DaemonConfig {
 	return dm.config
@@ -1373,13 +1843,44 @@ func (dm *Daemon) connectionIntroduced(addr string, gnetID uint64, m *Introducti
 		"listenAddr": listenAddr,
 	}
 
+	// Incoming connections through our onion service come from the Tor daemon on localhost.
+	// A peer that did not advertise an onion address has no address to add to the peerlist
+	if !c.Outgoing && c.OnionAddress == "" && dm.isOnionServiceConnection(addr) {
+		logger.WithFields(fields).Debug("Incoming connection through the onion service")
+		return c, nil
+	}
+
+	// An onion address advertised by an incoming peer could belong to anyone, so it is not added
+	// to the peerlist until an outgoing connection to it succeeds
+	if !c.Outgoing && c.OnionAddress != "" {
+		if _, ok := dm.pex.GetPeer(listenAddr); !ok {
+			dm.verifyOnionAddress(listenAddr)
+			return c, nil
+		}
+	}
+
 	if c.Outgoing {
+		// The peer is not in the peerlist if it was dialed to verify an advertised onion address.
+		// The successful connection verifies the address, so add it now
+		if _, ok := dm.pex.GetPeer(listenAddr); !ok {
+			if err := dm.pex.AddPeer(listenAddr); err != nil {
+				logger.Critical().WithError(err).WithFields(fields).Error("pex.AddPeer failed")
+				return nil, err
+			}
+		}
+
 		// For successful outgoing connections, mark the peer as having an incoming port in the pex peerlist
 		// The peer should already be in the peerlist, since we use the peerlist to choose an outgoing connection to make
 		if err := dm.pex.SetHasIncomingPort(listenAddr, true); err != nil {
 			logger.Critical().WithError(err).WithFields(fields).Error("pex.SetHasIncomingPort failed")
 			return nil, err
 		}
+
+		// The peer is reachable, move it to the tried table
+		if err := dm.pex.MarkTried(listenAddr); err != nil {
+			logger.Critical().WithError(err).WithFields(fields).Error("pex.MarkTried failed")
+			return nil, err
+		}
 	} else {
 		// For successful incoming connections, add the peer to the peer list, with their self-reported listen port
 		if err := dm.pex.AddPeer(listenAddr); err != nil {
@@ -1398,6 +1899,34 @@ func (dm *Daemon) connectionIntroduced(addr string, gnetID uint64, m *Introducti
 	return c, nil
 }
 
+// verifyOnionAddress dials an onion address advertised by an incoming peer.
+// The address is added to the peerlist in connectionIntroduced if the connection succeeds
+func (dm *Daemon) verifyOnionAddress(addr string) {
+	fields := logrus.Fields{
+		"onionAddress": addr,
+	}
+
+	if !dm.pex.Config.OnionPeers {
+		logger.WithFields(fields).Debug("Not verifying advertised onion address, onion peers can not be dialed")
+		return
+	}
+
+	if err := dm.connectToPeer(pex.Peer{Addr: addr}); err != nil {
+		logger.WithError(err).WithFields(fields).Debug("Could not verify advertised onion address")
+	}
+}
+
+// isOnionServiceConnection returns true if the node listens through an onion service
+// and addr is a connection from localhost, which is where the Tor daemon connects from
+func (dm *Daemon) isOnionServiceConnection(addr string) bool {
+	if dm.config.OnionAddress == "" {
+		return false
+	}
+
+	ip, _, err := iputil.SplitAddr(addr)
+	return err == nil && iputil.IsLocalhost(ip)
+}
+
 // sendRandomPeers sends a random sample of peers to another peer
 func (dm *Daemon) sendRandomPeers(addr string) error {
 	peers := dm.pex.RandomExchangeable(dm.pex.Config.ReplyCount)
@@ -1406,11 +1935,37 @@ func (dm *Daemon) sendRandomPeers(addr string) error {
 		return errors.New("No peers available")
 	}
 
+	// Peers that don't support GivePeerAddrsMessage only receive the IPv4 peers
+	if c := dm.connections.get(addr); c != nil && c.SupportsPeerAddrs() {
+		m := NewGivePeerAddrsMessage(peers, dm.config.MaxOutgoingMessageLength)
+		return dm.sendMessage(addr, m)
+	}
+
 	m := NewGivePeersMessage(peers, dm.config.MaxOutgoingMessageLength)
 
 	return dm.sendMessage(addr, m)
 }
 
+// secureConnection upgrades a connection to an encrypted session, according to the EncryptionPolicy
+// and the node pubkey advertised by the peer in its introduction message
+func (dm *Daemon) secureConnection(addr string, m *IntroductionMessage) error {
+	switch dm.config.EncryptionPolicy {
+	case EncryptionPolicyDisabled:
+		return nil
+	case EncryptionPolicyPreferred:
+		if m.NodePubkey.Null() {
+			logger.WithField("addr", addr).Debug("Peer does not support encrypted sessions, continuing in plaintext")
+			return nil
+		}
+	case EncryptionPolicyRequired:
+		if m.NodePubkey.Null() {
+			return ErrDisconnectEncryptionRequired
+		}
+	}
+
+	return dm.pool.Pool.SecureConnection(addr, m.NodePubkey)
+}
+
 // announceAllValidTxns broadcasts valid unconfirmed transactions
 func (dm *Daemon) announceAllValidTxns() error {
 	if dm.config.DisableNetworking {
@@ -1510,9 +2065,9 @@ func (dm *Daemon) pexConfig() pex.Config {
 	return dm.pex.Config
 }
 
-// addPeers adds peers to the pex
-func (dm *Daemon) addPeers(addrs []string) int {
-	return dm.pex.AddPeers(addrs)
+// addPeers adds peers announced by the source address to the pex
+func (dm *Daemon) addPeers(addrs []string, source string) int {
+	return dm.pex.AddPeers(addrs, source)
 }
 
 // recordPeerHeight records the height of specific peer
@@ -1527,14 +2082,144 @@ func (dm *Daemon) getSignedBlocksSince(seq, count uint64) ([]coin.SignedBlock, e
 	return dm.visor.GetSignedBlocksSince(seq, count)
 }
 
+// getBlockHeadersSince returns N signed block headers since given seq
+func (dm *Daemon) getBlockHeadersSince(seq, count uint64) ([]SignedBlockHeader, error) {
+	blocks, err := dm.visor.GetSignedBlocksSince(seq, count)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := make([]SignedBlockHeader, len(blocks))
+	for i, b := range blocks {
+		headers[i] = NewSignedBlockHeader(b)
+	}
+
+	return headers, nil
+}
+
+// addBlockHeaders verifies block headers and adds them to the headers-first sync
+func (dm *Daemon) addBlockHeaders(headers []SignedBlockHeader) (int, error) {
+	head, err := dm.visor.GetHeadBlock()
+	if err != nil {
+		return 0, err
+	}
+
+	return dm.headerSync.addHeaders(head.Block.Head, headers, dm.config.MaxHeadersAhead)
+}
+
+// addSyncBlocks adds blocks downloaded by the headers-first sync.
+// If any of the blocks belong to the sync, returns the downloaded blocks that follow the head block
+// and true. Otherwise returns the blocks unchanged and false.
+func (dm *Daemon) addSyncBlocks(addr string, blocks []coin.SignedBlock) ([]coin.SignedBlock, bool, error) {
+	n, err := dm.headerSync.addBlocks(addr, blocks)
+	if err != nil {
+		return nil, false, err
+	}
+	if n == 0 {
+		return blocks, false, nil
+	}
+
+	headSeq, ok, err := dm.visor.HeadBkSeq()
+	if err != nil {
+		return nil, false, err
+	}
+	if !ok {
+		return nil, false, errors.New("Cannot execute blocks, there is no head block")
+	}
+
+	return dm.headerSync.executable(headSeq), true, nil
+}
+
+// reconstructCompactBlock rebuilds a block received in a CompactBlockMessage from the unconfirmed pool.
+// If transactions are missing, the block is kept pending and the indexes of the missing transactions are returned.
+func (dm *Daemon) reconstructCompactBlock(addr string, header SignedBlockHeader, shortIDs []uint64) (*coin.SignedBlock, []uint64, error) {
+	unconfirmed, err := dm.visor.GetAllUnconfirmedTransactions()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pool := make(coin.Transactions, len(unconfirmed))
+	for i, ut := range unconfirmed {
+		pool[i] = ut.Transaction
+	}
+
+	return dm.compactBlocks.reconstruct(addr, header, shortIDs, pool)
+}
+
+// completeCompactBlock fills in the missing transactions of a compact block received from addr
+func (dm *Daemon) completeCompactBlock(addr string, blockHash cipher.SHA256, txns coin.Transactions) (*coin.SignedBlock, error) {
+	return dm.compactBlocks.complete(addr, blockHash, txns)
+}
+
+// getBlockTxns returns the transactions at the given indexes of a block
+func (dm *Daemon) getBlockTxns(blockHash cipher.SHA256, indexes []uint64) (coin.Transactions, error) {
+	b, err := dm.visor.GetSignedBlockByHash(blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if b == nil {
+		return nil, fmt.Errorf("Block %s not found", blockHash.Hex())
+	}
+
+	txns := make(coin.Transactions, len(indexes))
+	for i, idx := range indexes {
+		if idx >= uint64(len(b.Body.Transactions)) {
+			return nil, fmt.Errorf("Block %s has no transaction at index %d", blockHash.Hex(), idx)
+		}
+		txns[i] = b.Body.Transactions[idx]
+	}
+
+	return txns, nil
+}
+
+// addBlockCandidate verifies a block candidate and adds it to the block candidates of the block publishers.
+// The next block is executed if the block publishers settled on it.
+func (dm *Daemon) addBlockCandidate(candidate consensus.BlockBase, b coin.Block) error {
+	if dm.publisherConsensus == nil {
+		return errPublisherConsensusDisabled
+	}
+
+	if !dm.publisherConsensus.hasBlock(b.HashHeader()) {
+		if err := dm.visor.VerifyBlock(coin.SignedBlock{Block: b, Sig: candidate.Sig}); err != nil {
+			return err
+		}
+	}
+
+	if err := dm.publisherConsensus.addCandidate(candidate, b); err != nil {
+		return err
+	}
+
+	return dm.settleBlockCandidates()
+}
+
 // headBkSeq returns the head block sequence
 func (dm *Daemon) headBkSeq() (uint64, bool, error) {
 	return dm.visor.HeadBkSeq()
 }
 
-// executeSignedBlock executes the signed block
+// executeSignedBlock executes the signed block, with the block publisher signatures received for it from peers.
+// A block that does not extend the head block is stored as a side block, and the chain is reorganized to its branch
+// if the branch is longer than the main chain
 func (dm *Daemon) executeSignedBlock(b coin.SignedBlock) error {
-	return dm.visor.ExecuteSignedBlock(b)
+	hash := b.HashHeader()
+	if err := dm.visor.ExecuteCertifiedBlock(b, dm.blockCertificates.get(hash)); err != nil {
+		return err
+	}
+
+	dm.blockCertificates.remove(hash)
+	return nil
+}
+
+// addSideBlock stores a block of a competing branch, with the block publisher signatures received for it from peers.
+// Blocks that are stored already are ignored
+func (dm *Daemon) addSideBlock(b coin.SignedBlock) error {
+	hash := b.HashHeader()
+	if err := dm.visor.AddSideBlock(b, dm.blockCertificates.get(hash)); err != nil {
+		return err
+	}
+
+	dm.blockCertificates.remove(hash)
+	return nil
 }
 
 // filterKnownUnconfirmed returns unconfirmed txn hashes with known ones removed
@@ -1563,6 +2248,8 @@ type Connection struct {
 	Addr string
 	Pex  pex.Peer
 	Gnet GnetConnectionDetails
+	// Number of messages of each type dropped for exceeding their rate limit
+	MessagesThrottled map[string]uint64
 	ConnectionDetails
 }
 
@@ -1571,6 +2258,8 @@ type GnetConnectionDetails struct {
 	ID           uint64
 	LastSent     time.Time
 	LastReceived time.Time
+	Encrypted    bool
+	Stats        gnet.ConnectionStats
 }
 
 func newConnection(dc *connection, gc *gnet.Connection, pp *pex.Peer) Connection {
@@ -1586,6 +2275,8 @@ func newConnection(dc *connection, gc *gnet.Connection, pp *pex.Peer) Connection
 			ID:           gc.ID,
 			LastSent:     gc.LastSent,
 			LastReceived: gc.LastReceived,
+			Encrypted:    gc.Encrypted(),
+			Stats:        gc.Stats(),
 		}
 	}
 
@@ -1617,6 +2308,7 @@ func (dm *Daemon) newConnection(c *connection) (*Connection, error) {
 	}
 
 	cc := newConnection(c, gc, pp)
+	cc.MessagesThrottled = dm.rateLimiter.throttledMessages(c.Addr)
 	return &cc, nil
 }
 
@@ -1700,6 +2392,12 @@ type BlockchainProgress struct {
 	Highest uint64
 	// Individual blockchain length reports from peers
 	Peers []PeerBlockchainHeight
+	// Height of the verified header chain of the headers-first sync
+	Headers uint64
+	// Number of block downloads in flight
+	BlocksInFlight uint64
+	// Number of downloaded blocks waiting to be executed
+	BlocksDownloaded uint64
 }
 
 // newBlockchainProgress creates BlockchainProgress from the local head blockchain sequence number
@@ -1711,6 +2409,7 @@ func newBlockchainProgress(headSeq uint64, conns []connection) *BlockchainProgre
 		Current: headSeq,
 		Highest: EstimateBlockchainHeight(headSeq, peers),
 		Peers:   peers,
+		Headers: headSeq,
 	}
 }
 
@@ -1747,7 +2446,19 @@ func EstimateBlockchainHeight(headSeq uint64, peers []PeerBlockchainHeight) uint
 // GetBlockchainProgress returns a *BlockchainProgress
 func (dm *Daemon) GetBlockchainProgress(headSeq uint64) *BlockchainProgress {
 	conns := dm.connections.all()
-	return newBlockchainProgress(headSeq, conns)
+	progress := newBlockchainProgress(headSeq, conns)
+
+	tip, inFlight, downloaded := dm.headerSync.progress()
+	if tip > progress.Headers {
+		progress.Headers = tip
+	}
+	if progress.Headers > progress.Highest {
+		progress.Highest = progress.Headers
+	}
+	progress.BlocksInFlight = uint64(inFlight)
+	progress.BlocksDownloaded = uint64(downloaded)
+
+	return progress
 }
 
 // InjectBroadcastTransaction injects transaction to the unconfirmed pool and broadcasts it.
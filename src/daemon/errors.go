@@ -3,7 +3,7 @@ package daemon
 import (
 	"errors"
 
-	"github.com/skycoin/skycoin/src/daemon/gnet"
+	"github.com/ness-network/ness/src/daemon/gnet"
 )
 
 var (
@@ -47,6 +47,12 @@ var (
 	ErrDisconnectInvalidMaxTransactionSize gnet.DisconnectReason = errors.New("Invalid max transaction size in introduction message")
 	// ErrDisconnectInvalidMaxDropletPrecision invalid max droplet precision in introduction message
 	ErrDisconnectInvalidMaxDropletPrecision gnet.DisconnectReason = errors.New("Invalid max droplet precision in introduction message")
+	// ErrDisconnectEncryptionRequired the peer cannot establish an encrypted session, which our policy requires
+	ErrDisconnectEncryptionRequired gnet.DisconnectReason = errors.New("Encrypted session required")
+	// ErrDisconnectInvalidBlockHeaders the peer sent block headers that are not signed by the blockchain pubkey
+	ErrDisconnectInvalidBlockHeaders gnet.DisconnectReason = errors.New("Invalid block headers")
+	// ErrDisconnectBlockHeaderMismatch the peer sent a block that does not match its verified header
+	ErrDisconnectBlockHeaderMismatch gnet.DisconnectReason = errors.New("Block does not match its header")
 
 	// ErrDisconnectUnknownReason used when mapping an unknown reason code to an error. Is not sent over the network.
 	ErrDisconnectUnknownReason gnet.DisconnectReason = errors.New("Unknown DisconnectReason")
@@ -73,17 +79,24 @@ var (
 		ErrDisconnectInvalidBurnFactor:             17,
 		ErrDisconnectInvalidMaxTransactionSize:     18,
 		ErrDisconnectInvalidMaxDropletPrecision:    19,
+		ErrDisconnectEncryptionRequired:            20,
+		ErrDisconnectInvalidBlockHeaders:           21,
+		ErrDisconnectBlockHeaderMismatch:           22,
 
 		// gnet codes are registered here, but they are not sent in a DISC
 		// message by gnet. Only daemon sends a DISC packet.
 		// If gnet chooses to disconnect it will not send a DISC packet.
-		gnet.ErrDisconnectSetReadDeadlineFailed:  1001,
-		gnet.ErrDisconnectInvalidMessageLength:   1002,
-		gnet.ErrDisconnectMalformedMessage:       1003,
-		gnet.ErrDisconnectUnknownMessage:         1004,
-		gnet.ErrDisconnectShutdown:               1005,
-		gnet.ErrDisconnectMessageDecodeUnderflow: 1006,
-		gnet.ErrDisconnectTruncatedMessageID:     1007,
+		gnet.ErrDisconnectSetReadDeadlineFailed:    1001,
+		gnet.ErrDisconnectInvalidMessageLength:     1002,
+		gnet.ErrDisconnectMalformedMessage:         1003,
+		gnet.ErrDisconnectUnknownMessage:           1004,
+		gnet.ErrDisconnectShutdown:                 1005,
+		gnet.ErrDisconnectMessageDecodeUnderflow:   1006,
+		gnet.ErrDisconnectTruncatedMessageID:       1007,
+		gnet.ErrDisconnectSecureHandshakeFailed:    1008,
+		gnet.ErrDisconnectSecureHandshakeTimeout:   1009,
+		gnet.ErrDisconnectSecureNodePubKeyMismatch: 1010,
+		gnet.ErrDisconnectDecryptFailed:            1011,
 	}
 
 	disconnectCodeReasons map[uint16]gnet.DisconnectReason
@@ -46,6 +46,22 @@ func sendMessage(conn net.Conn, msg Message, timeout time.Duration, maxMsgLength
 	return sendByteMessage(conn, m, timeout)
 }
 
+// Serializes a Message over a net.Conn, encrypted with a secure session
+func sendSecureMessage(conn net.Conn, s *secureSession, msg Message, timeout time.Duration, maxMsgLength int) error {
+	m, err := EncodeMessage(msg)
+	if err != nil {
+		return err
+	}
+	if len(m) > maxMsgLength {
+		return ErrMsgExceedsMaxLen
+	}
+	frame, err := s.seal(m)
+	if err != nil {
+		return err
+	}
+	return sendByteMessage(conn, frame, timeout)
+}
+
 // msgIDStringSafe formats msgID bytes to a string that is safe for logging (e.g. not impacted by ascii control chars)
 func msgIDStringSafe(msgID [4]byte) string {
 	x := fmt.Sprintf("%q", msgID)
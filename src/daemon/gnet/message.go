@@ -2,6 +2,7 @@ package gnet
 
 import (
 	"reflect"
+	"strings"
 )
 
 const messagePrefixLength = 4
@@ -66,11 +67,33 @@ var MessageIDReverseMap = make(map[MessagePrefix]reflect.Type)
 
 var registeredMsgsCount = 0
 
+// MessageType returns the prefix of a registered message as a string, e.g. "GETB".
+// Returns an empty string if the message type is not registered
+func MessageType(m interface{}) string {
+	t := reflect.TypeOf(m)
+	if t == nil {
+		return ""
+	}
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	id, ok := MessageIDMap[t]
+	if !ok {
+		return ""
+	}
+
+	return strings.TrimRight(string(id[:]), "\x00")
+}
+
 // RegisterMessage registers a message struct for recognition by the message handlers.
 func RegisterMessage(prefix MessagePrefix, msg interface{}) {
 	t := reflect.TypeOf(msg)
 	id := MessagePrefix{}
 	copy(id[:], prefix[:])
+	if id == secureHelloPrefix {
+		logger.Panicf("Attempted to register reserved message prefix %s", string(id[:]))
+	}
 	_, exists := MessageIDReverseMap[id]
 	if exists {
 		logger.Panicf("Attempted to register message prefix %s twice", string(id[:]))
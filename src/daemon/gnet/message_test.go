@@ -129,6 +129,19 @@ func TestMessagePrefixFromString(t *testing.T) {
 	require.Equal(t, MessagePrefixFromString("a"), MessagePrefix{'a', 0x00, 0x00, 0x00})
 }
 
+func TestMessageType(t *testing.T) {
+	EraseMessages()
+	RegisterMessage(BytePrefix, ByteMessage{})
+	RegisterMessage(ErrorPrefix, ErrorMessage{})
+	VerifyMessages()
+
+	require.Equal(t, "BYTE", MessageType(&ByteMessage{}))
+	require.Equal(t, "BYTE", MessageType(ByteMessage{}))
+	require.Equal(t, "ERR", MessageType(&ErrorMessage{}))
+	require.Equal(t, "", MessageType(&DummyMessage{}))
+	require.Equal(t, "", MessageType(nil))
+}
+
 /* Helpers */
 
 type Nothing struct{}
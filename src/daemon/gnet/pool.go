@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"net"
 	"reflect"
+	"strconv"
 	"sync"
 	"time"
 
@@ -17,6 +18,8 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/ness-network/ness/src/util/socks5"
+	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/cipher/encoder"
 	"github.com/skycoin/skycoin/src/daemon/strand"
 	"github.com/skycoin/skycoin/src/util/elapse"
@@ -134,6 +137,17 @@ type Config struct {
 	DebugPrint bool
 	// Default "trusted" peers
 	DefaultConnections []string
+	// Node identity secret key used to authenticate encrypted sessions.
+	// Encrypted sessions are disabled if not set
+	NodeSecKey cipher.SecKey
+	// Timeout for the peer to answer an encrypted session handshake
+	SecureHandshakeTimeout time.Duration
+	// Address of a SOCKS5 proxy, such as Tor, that all outgoing connections are made through.
+	// Outgoing connections are made directly if not set
+	ProxyAddress string
+	// Username and password for the SOCKS5 proxy, if it requires authentication
+	ProxyUsername string
+	ProxyPassword string
 	// Default connections map
 	defaultConnections map[string]struct{}
 }
@@ -154,6 +168,7 @@ func NewConfig() Config {
 		WriteTimeout:                      time.Second * 30,
 		SendResultsSize:                   2048,
 		ConnectionWriteQueueSize:          128,
+		SecureHandshakeTimeout:            time.Second * 30,
 		DisconnectCallback:                nil,
 		ConnectCallback:                   nil,
 		DebugPrint:                        false,
@@ -183,6 +198,10 @@ type Connection struct {
 	// Message send queue.
 	WriteQueue chan Message
 	Solicited  bool
+	// Encrypted session state, nil if encrypted sessions are disabled
+	secure *secureSession
+	// Traffic counters, shared by copies of the connection
+	stats *connectionStats
 }
 
 // NewConnection creates a new Connection tied to a ConnectionPool
@@ -196,6 +215,7 @@ func NewConnection(pool *ConnectionPool, id uint64, conn net.Conn, writeQueueSiz
 		LastSent:       Now(),
 		WriteQueue:     make(chan Message, writeQueueSize),
 		Solicited:      solicited,
+		stats:          newConnectionStats(),
 	}
 }
 
@@ -209,6 +229,16 @@ func (conn *Connection) String() string {
 	return conn.Addr()
 }
 
+// Encrypted returns true if an encrypted session has been established with the peer
+func (conn *Connection) Encrypted() bool {
+	return conn.secure != nil && conn.secure.established()
+}
+
+// Stats returns the bytes and the number of messages of each type sent and received
+func (conn *Connection) Stats() ConnectionStats {
+	return conn.stats.snapshot()
+}
+
 // Close close the connection and write queue
 func (conn *Connection) Close() error {
 	err := conn.Conn.Close()
@@ -244,6 +274,8 @@ type ConnectionPool struct {
 	incomingConnections map[string]struct{}
 	// User-defined state to be passed into message handlers
 	messageState interface{}
+	// Node identity pubkey derived from Config.NodeSecKey
+	nodePubKey cipher.PubKey
 	// Connection ID counter
 	connID uint64
 	// Listening connection
@@ -269,6 +301,18 @@ func NewConnectionPool(c Config, state interface{}) (*ConnectionPool, error) {
 		return nil, errors.New("MaxConnections must be >= MaxOutgoingConnections + MaxIncomingConnections")
 	}
 
+	var nodePubKey cipher.PubKey
+	if !c.NodeSecKey.Null() {
+		if err := c.NodeSecKey.Verify(); err != nil {
+			return nil, fmt.Errorf("Invalid NodeSecKey: %v", err)
+		}
+		var err error
+		nodePubKey, err = cipher.PubKeyFromSecKey(c.NodeSecKey)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid NodeSecKey: %v", err)
+		}
+	}
+
 	return &ConnectionPool{
 		Config:                     c,
 		pool:                       make(map[uint64]*Connection),
@@ -278,6 +322,7 @@ func NewConnectionPool(c Config, state interface{}) (*ConnectionPool, error) {
 		incomingConnections:        make(map[string]struct{}),
 		SendResults:                make(chan SendResult, c.SendResultsSize),
 		messageState:               state,
+		nodePubKey:                 nodePubKey,
 		quit:                       make(chan struct{}),
 		done:                       make(chan struct{}),
 		strandDone:                 make(chan struct{}),
@@ -299,7 +344,7 @@ func (pool *ConnectionPool) Run() error {
 	}()
 
 	// start the connection accept loop
-	addr := fmt.Sprintf("%s:%v", pool.Config.Address, pool.Config.Port)
+	addr := net.JoinHostPort(pool.Config.Address, strconv.Itoa(int(pool.Config.Port)))
 	logger.Infof("Listening for connections on %s...", addr)
 
 	ln, err := net.Listen("tcp", addr)
@@ -466,6 +511,9 @@ func (pool *ConnectionPool) newConnection(conn net.Conn, solicited bool) (*Conne
 	}
 
 	nc := NewConnection(pool, pool.connID, conn, pool.Config.ConnectionWriteQueueSize, solicited)
+	if !pool.Config.NodeSecKey.Null() {
+		nc.secure = newSecureSession(pool.nodePubKey, pool.Config.NodeSecKey)
+	}
 
 	pool.pool[nc.ID] = nc
 	pool.addresses[a] = nc
@@ -630,16 +678,32 @@ func (pool *ConnectionPool) readLoop(conn *Connection, msgChan chan []byte, qc c
 			continue
 		}
 
+		conn.stats.received(len(data))
+
 		// write data to buffer
 		if _, err := conn.Buffer.Write(data); err != nil {
 			return err
 		}
 		// decode data
-		datas, err := decodeData(conn.Buffer, pool.Config.MaxIncomingMessageLength)
+		maxMsgLength := pool.Config.MaxIncomingMessageLength
+		if conn.secure != nil {
+			maxMsgLength += secureOverhead
+		}
+		datas, err := decodeData(conn.Buffer, maxMsgLength)
 		if err != nil {
 			return err
 		}
 		for _, d := range datas {
+			if conn.secure != nil {
+				d, err = pool.unwrapSecureFrame(conn, d)
+				if err != nil {
+					return err
+				}
+				if d == nil {
+					continue
+				}
+			}
+
 			// use select to avoid the goroutine leak,
 			// because if msgChan has no receiver this goroutine will leak
 			select {
@@ -673,12 +737,27 @@ func (pool *ConnectionPool) sendLoop(conn *Connection, timeout time.Duration, ma
 				continue
 			}
 
-			err := sendMessage(conn.Conn, m, timeout, maxMsgLength)
+			if h, ok := m.(*secureHelloMessage); ok {
+				if err := pool.sendSecureHello(conn, h, timeout, qc); err != nil {
+					return err
+				}
+				continue
+			}
+
+			var err error
+			n := messageLengthPrefixSize + messagePrefixLength + int(m.EncodeSize())
+			if conn.secure != nil && conn.secure.isSending() {
+				err = sendSecureMessage(conn.Conn, conn.secure, m, timeout, maxMsgLength)
+				n += secureOverhead
+			} else {
+				err = sendMessage(conn.Conn, m, timeout, maxMsgLength)
+			}
 
 			// Update last sent before writing to SendResult,
 			// this allows a write to SendResult to be used as a sync marker,
 			// since no further action in this block will happen after the write.
 			if err == nil {
+				conn.stats.sent(MessageType(m), n)
 				if err := pool.updateLastSent(conn.Addr(), Now()); err != nil {
 					logger.WithField("addr", conn.Addr()).WithError(err).Warning("updateLastSent failed")
 				}
@@ -700,6 +779,105 @@ func (pool *ConnectionPool) sendLoop(conn *Connection, timeout time.Duration, ma
 	}
 }
 
+// sendSecureHello writes our encrypted session handshake, then waits for the peer's handshake
+// before anything else is written, so that the peer knows all following frames are encrypted
+func (pool *ConnectionPool) sendSecureHello(conn *Connection, h *secureHelloMessage, timeout time.Duration, qc chan struct{}) error {
+	frame := h.encodeFrame()
+	if err := sendByteMessage(conn.Conn, frame, timeout); err != nil {
+		return err
+	}
+	conn.stats.sent("", len(frame))
+
+	var timeoutC <-chan time.Time
+	if pool.Config.SecureHandshakeTimeout != 0 {
+		timer := time.NewTimer(pool.Config.SecureHandshakeTimeout)
+		defer timer.Stop()
+		timeoutC = timer.C
+	}
+
+	select {
+	case <-pool.quit:
+		return nil
+	case <-qc:
+		return nil
+	case <-timeoutC:
+		return ErrDisconnectSecureHandshakeTimeout
+	case <-conn.secure.ready:
+	}
+
+	conn.secure.startSending()
+	logger.WithField("addr", conn.Addr()).Debug("Encrypted session established")
+
+	return nil
+}
+
+// unwrapSecureFrame decrypts a frame read from a connection that may have an encrypted session.
+// Handshake frames are processed and nil is returned for them.
+func (pool *ConnectionPool) unwrapSecureFrame(conn *Connection, data []byte) ([]byte, error) {
+	if conn.secure.receiving() {
+		data, err := conn.secure.open(data)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) > pool.Config.MaxIncomingMessageLength {
+			return nil, ErrDisconnectInvalidMessageLength
+		}
+		return data, nil
+	}
+
+	if len(data) > pool.Config.MaxIncomingMessageLength {
+		return nil, ErrDisconnectInvalidMessageLength
+	}
+
+	if !isSecureHelloFrame(data) {
+		return data, nil
+	}
+
+	h, err := decodeSecureHelloFrame(data)
+	if err != nil {
+		logger.WithError(err).WithField("addr", conn.Addr()).Warning("decodeSecureHelloFrame failed")
+		return nil, ErrDisconnectSecureHandshakeFailed
+	}
+
+	if err := conn.secure.receiveHello(h); err != nil {
+		logger.WithError(err).WithField("addr", conn.Addr()).Warning("Secure session handshake rejected")
+		return nil, err
+	}
+
+	// Answer the handshake if we have not initiated one ourselves
+	if err := pool.strand("queueSecureHello", func() error {
+		c, ok := pool.pool[conn.ID]
+		if !ok {
+			return fmt.Errorf("Tried to queue secure hello for %s, but we are not connected", conn.Addr())
+		}
+		return pool.queueSecureHello(c)
+	}); err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// queueSecureHello places our encrypted session handshake on the write queue, once.
+// Must be called from the strand.
+func (pool *ConnectionPool) queueSecureHello(conn *Connection) error {
+	h, err := conn.secure.queueHello()
+	if err != nil {
+		return err
+	}
+	if h == nil {
+		return nil
+	}
+
+	select {
+	case conn.WriteQueue <- h:
+	default:
+		logger.Critical().WithField("addr", conn.Addr()).Info("Write queue full")
+		return ErrWriteQueueFull
+	}
+	return nil
+}
+
 func readData(reader io.Reader, buf []byte) ([]byte, error) {
 	c, err := reader.Read(buf)
 	if err != nil {
@@ -827,7 +1005,7 @@ func (pool *ConnectionPool) Connect(address string) error {
 	}
 
 	logger.WithField("addr", address).Debugf("Making TCP connection")
-	conn, err := net.DialTimeout("tcp", address, pool.Config.DialTimeout)
+	conn, err := pool.dial(address)
 	if err != nil {
 		return err
 	}
@@ -845,6 +1023,44 @@ func (pool *ConnectionPool) Connect(address string) error {
 	return nil
 }
 
+// dial connects to address, through the SOCKS5 proxy if Config.ProxyAddress is set
+func (pool *ConnectionPool) dial(address string) (net.Conn, error) {
+	if pool.Config.ProxyAddress == "" {
+		return net.DialTimeout("tcp", address, pool.Config.DialTimeout)
+	}
+
+	d := socks5.Dialer{
+		ProxyAddress: pool.Config.ProxyAddress,
+		Username:     pool.Config.ProxyUsername,
+		Password:     pool.Config.ProxyPassword,
+		Timeout:      pool.Config.DialTimeout,
+	}
+	return d.Dial(address)
+}
+
+// SecureConnection upgrades the connection to addr to an encrypted session.
+// nodePubKey is the pubkey that the peer advertised, which must match the key the peer
+// authenticates the session with. The handshake completes asynchronously; if it fails,
+// the connection is disconnected.
+func (pool *ConnectionPool) SecureConnection(addr string, nodePubKey cipher.PubKey) error {
+	if pool.Config.NodeSecKey.Null() {
+		return ErrSecureSessionsDisabled
+	}
+
+	return pool.strand("SecureConnection", func() error {
+		conn, ok := pool.addresses[addr]
+		if !ok {
+			return fmt.Errorf("Tried to secure connection to %s, but we are not connected", addr)
+		}
+
+		if err := conn.secure.expect(nodePubKey); err != nil {
+			return err
+		}
+
+		return pool.queueSecureHello(conn)
+	})
+}
+
 // Disconnect removes a connection from the pool by address and invokes DisconnectCallback
 func (pool *ConnectionPool) Disconnect(addr string, r DisconnectReason) error {
 	return pool.strand("Disconnect", func() error {
@@ -1022,6 +1238,7 @@ func (pool *ConnectionPool) receiveMessage(c *Connection, msg []byte) error {
 	if err != nil {
 		return err
 	}
+	c.stats.receivedMessage(MessageType(m))
 	if err := pool.updateLastRecv(c.Addr(), Now()); err != nil {
 		return err
 	}
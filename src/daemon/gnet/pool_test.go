@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"strings"
 	"sync"
@@ -375,6 +376,90 @@ func TestConnectNoTimeout(t *testing.T) {
 	require.Error(t, err)
 }
 
+// runTestProxy runs a SOCKS5 stand-in that accepts a connection without authentication
+// and forwards it to target, whatever the requested destination. The destination is sent to dests.
+func runTestProxy(t *testing.T, target string, dests chan<- string) net.Listener {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Greeting with a single auth method, then the CONNECT request with a domain name
+		buf := make([]byte, 8)
+		if _, err := io.ReadFull(conn, buf[:3]); err != nil {
+			return
+		}
+		if _, err := conn.Write([]byte{5, 0}); err != nil {
+			return
+		}
+		if _, err := io.ReadFull(conn, buf[:5]); err != nil {
+			return
+		}
+		host := make([]byte, int(buf[4])+2)
+		if _, err := io.ReadFull(conn, host); err != nil {
+			return
+		}
+		dests <- string(host[:len(host)-2])
+
+		tc, err := net.Dial("tcp", target)
+		if err != nil {
+			return
+		}
+		defer tc.Close()
+
+		if _, err := conn.Write([]byte{5, 0, 0, 1, 127, 0, 0, 1, 0, 0}); err != nil {
+			return
+		}
+
+		go io.Copy(tc, conn) //nolint:errcheck
+		io.Copy(conn, tc)    //nolint:errcheck
+	}()
+
+	return ln
+}
+
+func TestConnectProxy(t *testing.T) {
+	onion := "vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd.onion"
+	onionAddr := onion + ":6000"
+
+	cfg := newTestConfig()
+	cfg.Port += 2
+	dests := make(chan string, 1)
+	ln := runTestProxy(t, fmt.Sprintf("%s:%d", address, cfg.Port), dests)
+	defer ln.Close()
+	cfg.ProxyAddress = ln.Addr().String()
+
+	p, err := NewConnectionPool(cfg, nil)
+	require.NoError(t, err)
+
+	q := make(chan struct{})
+	go func() {
+		defer close(q)
+		err := p.Run()
+		require.NoError(t, err)
+	}()
+	wait()
+
+	err = p.Connect(onionAddr)
+	require.NoError(t, err)
+	require.Equal(t, onion, <-dests)
+	wait()
+
+	// The outgoing connection is identified by the onion address, not the proxy address
+	c, err := p.GetConnection(onionAddr)
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	require.True(t, c.Solicited)
+
+	p.Shutdown()
+	<-q
+}
+
 func TestDisconnect(t *testing.T) {
 	cfg := newTestConfig()
 	p, err := NewConnectionPool(cfg, nil)
@@ -923,6 +1008,11 @@ func TestConnectionWriteLoop(t *testing.T) {
 	lastSent := c.LastSent
 	require.False(t, lastSent.IsZero())
 
+	// The length prefix, message prefix and message body are counted
+	stats := c.Stats()
+	require.Equal(t, uint64(9), stats.BytesSent)
+	require.Equal(t, map[string]uint64{"BYTE": 1}, stats.MessagesSent)
+
 	// Send a failed message to c
 	sendByteMessage = failingSendByteMessage
 
@@ -945,6 +1035,10 @@ func TestConnectionWriteLoop(t *testing.T) {
 	// c.LastSent should not have changed
 	require.Equal(t, lastSent, c.LastSent)
 
+	// Failed messages are not counted
+	require.Equal(t, stats.BytesSent, c.Stats().BytesSent)
+	require.Equal(t, stats.MessagesSent, c.Stats().MessagesSent)
+
 	p.Shutdown()
 	<-q
 }
@@ -1174,17 +1268,20 @@ func TestPoolReceiveMessage(t *testing.T) {
 	err = p.receiveMessage(c, b)
 	require.NoError(t, err)
 	require.False(t, c.LastReceived.IsZero())
+	require.Equal(t, map[string]uint64{"BYTE": 1}, c.Stats().MessagesReceived)
 
 	// Invalid byte message received
 	b = []byte{1}
 	err = p.receiveMessage(c, b)
 	require.Error(t, err)
+	require.Equal(t, map[string]uint64{"BYTE": 1}, c.Stats().MessagesReceived)
 
 	// Valid message, but handler returns a DisconnectReason
 	b = make([]byte, 0)
 	b = append(b, ErrorPrefix[:]...)
 	err = p.receiveMessage(c, b)
 	require.Equal(t, err, ErrErrorMessageHandler)
+	require.Equal(t, map[string]uint64{"BYTE": 1, "ERR": 1}, c.Stats().MessagesReceived)
 
 	p.Shutdown()
 	<-q
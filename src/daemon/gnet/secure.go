@@ -0,0 +1,357 @@
+package gnet
+
+import (
+	stdcipher "crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sync"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/chacha20poly1305"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+)
+
+// Encrypted sessions
+//
+// A connection starts in plaintext. Once both ends know each other's node pubkey
+// (exchanged by the application, e.g. in an introduction message), either end may call
+// ConnectionPool.SecureConnection. Each end then sends a single plaintext SECH frame
+// carrying its node pubkey, a fresh ephemeral pubkey and a signature of the ephemeral
+// pubkey made with the node seckey. A SECH frame received before the local end has
+// sent its own is answered automatically.
+//
+// The session keys are derived from the ECDH of the two ephemeral keys, one key per
+// direction. All frames that follow a SECH frame in the same direction are sealed with
+// chacha20poly1305. The sender does not write anything after its SECH frame until it has
+// received the remote SECH frame, so the switch point is unambiguous on both ends.
+//
+// Encrypted frame layout:
+//   length     uint32 // length of the ciphertext, also used as additional data
+//   ciphertext []byte // chacha20poly1305 seal of the message ID and message body
+
+const (
+	// secureOverhead is the number of bytes added to a message by encryption
+	secureOverhead = 16
+	// secureHelloSize is the encoded size of a secureHelloMessage
+	secureHelloSize = len(cipher.PubKey{})*2 + len(cipher.Sig{})
+	// secureKeyDomain is mixed into the session key derivation and the hello signature
+	secureKeyDomain = "gnet-secure-session-v1"
+)
+
+var (
+	// ErrDisconnectSecureHandshakeFailed the encrypted session handshake could not be completed
+	ErrDisconnectSecureHandshakeFailed DisconnectReason = errors.New("Secure session handshake failed")
+	// ErrDisconnectSecureHandshakeTimeout the peer did not answer the encrypted session handshake in time
+	ErrDisconnectSecureHandshakeTimeout DisconnectReason = errors.New("Secure session handshake timed out")
+	// ErrDisconnectSecureNodePubKeyMismatch the peer authenticated its session with a different node pubkey than expected
+	ErrDisconnectSecureNodePubKeyMismatch DisconnectReason = errors.New("Secure session node pubkey does not match")
+	// ErrDisconnectDecryptFailed a message failed authentication
+	ErrDisconnectDecryptFailed DisconnectReason = errors.New("Message decryption failed")
+
+	// ErrSecureSessionsDisabled secure sessions are not enabled for this pool
+	ErrSecureSessionsDisabled = errors.New("Secure sessions are disabled")
+
+	// secureHelloPrefix is the message prefix of the encrypted session handshake.
+	// It is reserved and cannot be registered with RegisterMessage.
+	secureHelloPrefix = MessagePrefix{'S', 'E', 'C', 'H'}
+)
+
+// secureHelloMessage is exchanged by both ends of a connection to establish an encrypted session.
+// It is processed by the ConnectionPool and is never passed to a message handler.
+type secureHelloMessage struct {
+	// NodePubKey is the long-lived identity of the sender
+	NodePubKey cipher.PubKey
+	// EphemeralPubKey is generated for this connection only
+	EphemeralPubKey cipher.PubKey
+	// Sig is a signature of EphemeralPubKey by NodePubKey
+	Sig cipher.Sig
+}
+
+// EncodeSize implements gnet.Serializer
+func (h *secureHelloMessage) EncodeSize() uint64 {
+	return uint64(secureHelloSize)
+}
+
+// Encode implements gnet.Serializer
+func (h *secureHelloMessage) Encode(buf []byte) error {
+	if len(buf) < secureHelloSize {
+		return encoder.ErrBufferOverflow
+	}
+	i := copy(buf, h.NodePubKey[:])
+	i += copy(buf[i:], h.EphemeralPubKey[:])
+	copy(buf[i:], h.Sig[:])
+	return nil
+}
+
+// Decode implements gnet.Serializer
+func (h *secureHelloMessage) Decode(buf []byte) (uint64, error) {
+	if len(buf) < secureHelloSize {
+		return 0, encoder.ErrBufferUnderflow
+	}
+	i := copy(h.NodePubKey[:], buf)
+	i += copy(h.EphemeralPubKey[:], buf[i:])
+	i += copy(h.Sig[:], buf[i:])
+	return uint64(i), nil
+}
+
+// Handle implements gnet.Handler. The handshake is consumed by the ConnectionPool, so this is never called.
+func (h *secureHelloMessage) Handle(mc *MessageContext, state interface{}) error {
+	return ErrDisconnectSecureHandshakeFailed
+}
+
+// encodeFrame returns the plaintext wire frame of the hello message
+func (h *secureHelloMessage) encodeFrame() []byte {
+	n := len(secureHelloPrefix) + secureHelloSize
+	frame := make([]byte, messageLengthPrefixSize+n)
+	copy(frame, encoder.SerializeUint32(uint32(n)))
+	copy(frame[messageLengthPrefixSize:], secureHelloPrefix[:])
+	if err := h.Encode(frame[messageLengthPrefixSize+len(secureHelloPrefix):]); err != nil {
+		logger.WithError(err).Panic("secureHelloMessage.Encode failed unexpectedly")
+	}
+	return frame
+}
+
+// verify checks that the ephemeral key is signed by the node key
+func (h *secureHelloMessage) verify() error {
+	if err := h.NodePubKey.Verify(); err != nil {
+		return err
+	}
+	if err := h.EphemeralPubKey.Verify(); err != nil {
+		return err
+	}
+	return cipher.VerifyPubKeySignedHash(h.NodePubKey, h.Sig, secureHelloHash(h.EphemeralPubKey))
+}
+
+func secureHelloHash(ephemeral cipher.PubKey) cipher.SHA256 {
+	b := make([]byte, 0, len(secureKeyDomain)+len(ephemeral))
+	b = append(b, secureKeyDomain...)
+	b = append(b, ephemeral[:]...)
+	return cipher.SumSHA256(b)
+}
+
+// isSecureHelloFrame returns true if a decoded frame (message ID and body) is a handshake
+func isSecureHelloFrame(data []byte) bool {
+	if len(data) < len(secureHelloPrefix) {
+		return false
+	}
+	var id MessagePrefix
+	copy(id[:], data)
+	return id == secureHelloPrefix
+}
+
+// decodeSecureHelloFrame decodes a handshake from a frame (message ID and body)
+func decodeSecureHelloFrame(data []byte) (*secureHelloMessage, error) {
+	var h secureHelloMessage
+	n, err := h.Decode(data[len(secureHelloPrefix):])
+	if err != nil {
+		return nil, err
+	}
+	if n != uint64(len(data)-len(secureHelloPrefix)) {
+		return nil, ErrDisconnectMessageDecodeUnderflow
+	}
+	return &h, nil
+}
+
+// secureSession tracks the encrypted session state of a Connection.
+// The send half is only used by the connection's send loop and the receive half
+// only by its read loop; the mutex guards the handshake state shared by both.
+type secureSession struct {
+	sync.Mutex
+
+	nodePubKey cipher.PubKey
+	nodeSecKey cipher.SecKey
+	ephPubKey  cipher.PubKey
+	ephSecKey  cipher.SecKey
+
+	// expected is the node pubkey the application expects the peer to authenticate with
+	expected cipher.PubKey
+	// remote is the handshake received from the peer
+	remote *secureHelloMessage
+	// helloQueued is set once our handshake has been placed on the write queue
+	helloQueued bool
+	// ready is closed when the session keys have been derived
+	ready chan struct{}
+
+	send      stdcipher.AEAD
+	sendNonce uint64
+	sending   bool
+
+	recv      stdcipher.AEAD
+	recvNonce uint64
+}
+
+func newSecureSession(pubkey cipher.PubKey, seckey cipher.SecKey) *secureSession {
+	return &secureSession{
+		nodePubKey: pubkey,
+		nodeSecKey: seckey,
+		ready:      make(chan struct{}),
+	}
+}
+
+// initEphemeral generates the ephemeral key pair. Must be called with the lock held.
+func (s *secureSession) initEphemeral() {
+	if s.ephSecKey.Null() {
+		s.ephPubKey, s.ephSecKey = cipher.GenerateKeyPair()
+	}
+}
+
+// queueHello returns our handshake message if it has not been queued yet
+func (s *secureSession) queueHello() (*secureHelloMessage, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.helloQueued {
+		return nil, nil
+	}
+
+	s.initEphemeral()
+	sig, err := cipher.SignHash(secureHelloHash(s.ephPubKey), s.nodeSecKey)
+	if err != nil {
+		return nil, err
+	}
+
+	s.helloQueued = true
+	return &secureHelloMessage{
+		NodePubKey:      s.nodePubKey,
+		EphemeralPubKey: s.ephPubKey,
+		Sig:             sig,
+	}, nil
+}
+
+// expect records the node pubkey the peer must authenticate with
+func (s *secureSession) expect(pubkey cipher.PubKey) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.remote != nil && s.remote.NodePubKey != pubkey {
+		return ErrDisconnectSecureNodePubKeyMismatch
+	}
+	s.expected = pubkey
+	return nil
+}
+
+// receiveHello processes the peer's handshake and derives the session keys
+func (s *secureSession) receiveHello(h *secureHelloMessage) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.remote != nil {
+		return ErrDisconnectSecureHandshakeFailed
+	}
+
+	if err := h.verify(); err != nil {
+		logger.WithError(err).Debug("secureHelloMessage.verify failed")
+		return ErrDisconnectSecureHandshakeFailed
+	}
+
+	if !s.expected.Null() && s.expected != h.NodePubKey {
+		return ErrDisconnectSecureNodePubKeyMismatch
+	}
+
+	s.initEphemeral()
+	shared, err := cipher.ECDH(h.EphemeralPubKey, s.ephSecKey)
+	if err != nil {
+		logger.WithError(err).Debug("cipher.ECDH failed")
+		return ErrDisconnectSecureHandshakeFailed
+	}
+
+	send, err := chacha20poly1305.New(deriveSessionKey(shared, s.ephPubKey, h.EphemeralPubKey))
+	if err != nil {
+		return err
+	}
+	recv, err := chacha20poly1305.New(deriveSessionKey(shared, h.EphemeralPubKey, s.ephPubKey))
+	if err != nil {
+		return err
+	}
+
+	s.remote = h
+	s.send = send
+	s.recv = recv
+	close(s.ready)
+
+	return nil
+}
+
+// established returns true if the session keys have been derived
+func (s *secureSession) established() bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.remote != nil
+}
+
+// receiving returns true if incoming frames must be decrypted
+func (s *secureSession) receiving() bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.recv != nil
+}
+
+// startSending switches the send half to encryption. Only called by the send loop.
+func (s *secureSession) startSending() {
+	s.Lock()
+	defer s.Unlock()
+	s.sending = true
+}
+
+// isSending returns true if outgoing frames must be encrypted. Only called by the send loop.
+func (s *secureSession) isSending() bool {
+	s.Lock()
+	defer s.Unlock()
+	return s.sending
+}
+
+// seal encrypts an encoded message (length prefix, message ID and body) into an encrypted frame
+func (s *secureSession) seal(msg []byte) ([]byte, error) {
+	nonce, err := nextNonce(&s.sendNonce)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext := msg[messageLengthPrefixSize:]
+	n := len(plaintext) + s.send.Overhead()
+	if uint64(n) > math.MaxUint32 {
+		return nil, errors.New("Encrypted message length exceeds math.MaxUint32")
+	}
+
+	ad := encoder.SerializeUint32(uint32(n))
+	frame := make([]byte, 0, messageLengthPrefixSize+n)
+	frame = append(frame, ad...)
+	return s.send.Seal(frame, nonce, plaintext, ad), nil
+}
+
+// open decrypts an encrypted frame with its length prefix stripped, returning the message ID and body
+func (s *secureSession) open(data []byte) ([]byte, error) {
+	nonce, err := nextNonce(&s.recvNonce)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := s.recv.Open(nil, nonce, data, encoder.SerializeUint32(uint32(len(data))))
+	if err != nil {
+		return nil, ErrDisconnectDecryptFailed
+	}
+	return plaintext, nil
+}
+
+// nextNonce returns the nonce for the counter value and advances the counter
+func nextNonce(counter *uint64) ([]byte, error) {
+	if *counter == math.MaxUint64 {
+		return nil, errors.New("Secure session nonce exhausted")
+	}
+	nonce := make([]byte, chacha20poly1305.NonceSize)
+	binary.LittleEndian.PutUint64(nonce[chacha20poly1305.NonceSize-8:], *counter)
+	*counter++
+	return nonce, nil
+}
+
+// deriveSessionKey derives the key for the direction from -> to
+func deriveSessionKey(shared []byte, from, to cipher.PubKey) []byte {
+	b := make([]byte, 0, len(secureKeyDomain)+len(shared)+len(from)+len(to))
+	b = append(b, secureKeyDomain...)
+	b = append(b, shared...)
+	b = append(b, from[:]...)
+	b = append(b, to[:]...)
+	h := cipher.SumSHA256(b)
+	return h[:]
+}
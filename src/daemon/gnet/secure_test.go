@@ -0,0 +1,359 @@
+package gnet
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+)
+
+type echoMessage struct {
+	X byte
+}
+
+var echoPrefix = MessagePrefix{'E', 'C', 'H', 'O'}
+
+// EncodeSize implements gnet.Serializer
+func (em *echoMessage) EncodeSize() uint64 {
+	return uint64(encoder.Size(em))
+}
+
+// Encode implements gnet.Serializer
+func (em *echoMessage) Encode(buf []byte) error {
+	buf2 := encoder.Serialize(em)
+	if len(buf) < len(buf2) {
+		return errors.New("Not enough buffer data to encode")
+	}
+	copy(buf[:], buf2[:])
+	return nil
+}
+
+// Decode implements gnet.Serializer
+func (em *echoMessage) Decode(buf []byte) (uint64, error) {
+	return encoder.DeserializeRaw(buf, em)
+}
+
+// Handle pushes the received message onto the chan passed as the pool's message state
+func (em *echoMessage) Handle(c *MessageContext, state interface{}) error {
+	state.(chan *echoMessage) <- em
+	return nil
+}
+
+func newTestSecureSessionPair(t *testing.T) (*secureSession, *secureSession) {
+	pa, sa := cipher.GenerateKeyPair()
+	pb, sb := cipher.GenerateKeyPair()
+	a := newSecureSession(pa, sa)
+	b := newSecureSession(pb, sb)
+
+	require.NoError(t, a.expect(pb))
+	require.NoError(t, b.expect(pa))
+
+	ha, err := a.queueHello()
+	require.NoError(t, err)
+	require.NotNil(t, ha)
+	hb, err := b.queueHello()
+	require.NoError(t, err)
+	require.NotNil(t, hb)
+
+	// The hello is only produced once
+	h, err := a.queueHello()
+	require.NoError(t, err)
+	require.Nil(t, h)
+
+	require.NoError(t, a.receiveHello(hb))
+	require.NoError(t, b.receiveHello(ha))
+	require.True(t, a.established())
+	require.True(t, b.established())
+
+	a.startSending()
+	b.startSending()
+
+	return a, b
+}
+
+func TestSecureSessionSealOpen(t *testing.T) {
+	EraseMessages()
+	RegisterMessage(echoPrefix, echoMessage{})
+	VerifyMessages()
+
+	a, b := newTestSecureSessionPair(t)
+
+	for i := 0; i < 3; i++ {
+		msg, err := EncodeMessage(&echoMessage{X: byte(i)})
+		require.NoError(t, err)
+
+		frame, err := a.seal(msg)
+		require.NoError(t, err)
+		require.Len(t, frame, len(msg)+secureOverhead)
+
+		n, _, err := encoder.DeserializeUint32(frame[:messageLengthPrefixSize])
+		require.NoError(t, err)
+		require.Equal(t, len(frame)-messageLengthPrefixSize, int(n))
+
+		plaintext, err := b.open(frame[messageLengthPrefixSize:])
+		require.NoError(t, err)
+		require.Equal(t, msg[messageLengthPrefixSize:], plaintext)
+	}
+
+	// Replayed or tampered frames are rejected
+	msg, err := EncodeMessage(&echoMessage{X: 9})
+	require.NoError(t, err)
+	frame, err := b.seal(msg)
+	require.NoError(t, err)
+
+	tampered := append([]byte{}, frame[messageLengthPrefixSize:]...)
+	tampered[0] ^= 0xFF
+	_, err = a.open(tampered)
+	require.Equal(t, ErrDisconnectDecryptFailed, err)
+
+	// The nonce advanced on the failed open, so the untampered frame no longer authenticates
+	_, err = a.open(frame[messageLengthPrefixSize:])
+	require.Equal(t, ErrDisconnectDecryptFailed, err)
+}
+
+func TestSecureSessionReceiveHello(t *testing.T) {
+	pa, sa := cipher.GenerateKeyPair()
+	pb, sb := cipher.GenerateKeyPair()
+	pc, _ := cipher.GenerateKeyPair()
+
+	newHello := func() *secureHelloMessage {
+		h, err := newSecureSession(pb, sb).queueHello()
+		require.NoError(t, err)
+		return h
+	}
+
+	// Unexpected node pubkey
+	s := newSecureSession(pa, sa)
+	require.NoError(t, s.expect(pc))
+	require.Equal(t, ErrDisconnectSecureNodePubKeyMismatch, s.receiveHello(newHello()))
+	require.False(t, s.established())
+
+	// Expectation set after the hello was received
+	s = newSecureSession(pa, sa)
+	require.NoError(t, s.receiveHello(newHello()))
+	require.Equal(t, ErrDisconnectSecureNodePubKeyMismatch, s.expect(pc))
+	require.NoError(t, s.expect(pb))
+
+	// Second hello
+	require.Equal(t, ErrDisconnectSecureHandshakeFailed, s.receiveHello(newHello()))
+
+	// Invalid signature
+	s = newSecureSession(pa, sa)
+	h := newHello()
+	h.EphemeralPubKey = pc
+	require.Equal(t, ErrDisconnectSecureHandshakeFailed, s.receiveHello(h))
+
+	// Frame roundtrip
+	h = newHello()
+	frame := h.encodeFrame()
+	require.True(t, isSecureHelloFrame(frame[messageLengthPrefixSize:]))
+	h2, err := decodeSecureHelloFrame(frame[messageLengthPrefixSize:])
+	require.NoError(t, err)
+	require.Equal(t, h, h2)
+
+	_, err = decodeSecureHelloFrame(append(frame[messageLengthPrefixSize:], 0))
+	require.Equal(t, ErrDisconnectMessageDecodeUnderflow, err)
+}
+
+func TestRegisterMessageReservedPrefix(t *testing.T) {
+	EraseMessages()
+	require.Panics(t, func() { RegisterMessage(secureHelloPrefix, DummyMessage{}) })
+}
+
+func TestNewConnectionPoolInvalidNodeSecKey(t *testing.T) {
+	cfg := newTestConfig()
+	for i := range cfg.NodeSecKey {
+		cfg.NodeSecKey[i] = 0xFF
+	}
+	_, err := NewConnectionPool(cfg, nil)
+	require.Error(t, err)
+}
+
+func TestSecureConnectionDisabled(t *testing.T) {
+	cfg := newTestConfig()
+	p, err := NewConnectionPool(cfg, nil)
+	require.NoError(t, err)
+
+	pk, _ := cipher.GenerateKeyPair()
+	err = p.SecureConnection(addr, pk)
+	require.Equal(t, ErrSecureSessionsDisabled, err)
+}
+
+type testSecurePeer struct {
+	pool        *ConnectionPool
+	pubkey      cipher.PubKey
+	received    chan *echoMessage
+	connected   chan string
+	disconnects chan DisconnectReason
+	done        chan struct{}
+}
+
+func newTestSecurePeer(t *testing.T, port uint16, secure bool) *testSecurePeer {
+	cfg := newTestConfig()
+	cfg.Port = port
+	cfg.WriteTimeout = time.Second
+	cfg.SecureHandshakeTimeout = time.Second * 2
+
+	var pubkey cipher.PubKey
+	if secure {
+		pubkey, cfg.NodeSecKey = cipher.GenerateKeyPair()
+	}
+
+	peer := &testSecurePeer{
+		pubkey:      pubkey,
+		received:    make(chan *echoMessage, 8),
+		connected:   make(chan string, 1),
+		disconnects: make(chan DisconnectReason, 1),
+		done:        make(chan struct{}),
+	}
+
+	cfg.ConnectCallback = func(addr string, id uint64, solicited bool) {
+		peer.connected <- addr
+	}
+	cfg.DisconnectCallback = func(addr string, id uint64, reason DisconnectReason) {
+		peer.disconnects <- reason
+	}
+
+	p, err := NewConnectionPool(cfg, peer.received)
+	require.NoError(t, err)
+	peer.pool = p
+
+	go func() {
+		defer close(peer.done)
+		err := p.Run()
+		require.NoError(t, err)
+	}()
+
+	return peer
+}
+
+func (peer *testSecurePeer) shutdown() {
+	peer.pool.Shutdown()
+	<-peer.done
+}
+
+func setupSecurePeers(t *testing.T, secureA, secureB bool) (a, b *testSecurePeer, addrA, addrB string) {
+	resetHandler()
+	EraseMessages()
+	RegisterMessage(echoPrefix, echoMessage{})
+	VerifyMessages()
+
+	a = newTestSecurePeer(t, port, secureA)
+	b = newTestSecurePeer(t, port+1, secureB)
+	wait()
+
+	require.NoError(t, b.pool.Connect(addr))
+
+	select {
+	case addrB = <-a.connected:
+	case <-time.After(time.Second * 2):
+		t.Fatal("Timed out waiting for connection")
+	}
+	select {
+	case addrA = <-b.connected:
+	case <-time.After(time.Second * 2):
+		t.Fatal("Timed out waiting for connection")
+	}
+	require.Equal(t, addr, addrA)
+
+	return
+}
+
+func requireEcho(t *testing.T, peer *testSecurePeer, x byte) {
+	select {
+	case m := <-peer.received:
+		require.Equal(t, x, m.X)
+	case <-time.After(time.Second * 2):
+		t.Fatal("Timed out waiting for message")
+	}
+}
+
+func TestSecureConnection(t *testing.T) {
+	cases := []struct {
+		name      string
+		secure    bool
+		encrypted bool
+	}{
+		{
+			name:      "plaintext",
+			secure:    false,
+			encrypted: false,
+		},
+		{
+			name:      "encrypted",
+			secure:    true,
+			encrypted: true,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			a, b, addrA, addrB := setupSecurePeers(t, tc.secure, tc.secure)
+			defer b.shutdown()
+			defer a.shutdown()
+
+			// Plaintext messages sent before the handshake are delivered
+			require.NoError(t, b.pool.SendMessage(addrA, &echoMessage{X: 1}))
+			requireEcho(t, a, 1)
+
+			if tc.secure {
+				// Only one side initiates, the other side answers automatically
+				require.NoError(t, b.pool.SecureConnection(addrA, a.pubkey))
+			}
+
+			for i := byte(2); i < 5; i++ {
+				require.NoError(t, b.pool.SendMessage(addrA, &echoMessage{X: i}))
+				require.NoError(t, a.pool.SendMessage(addrB, &echoMessage{X: i}))
+				requireEcho(t, a, i)
+				requireEcho(t, b, i)
+			}
+
+			ca, err := a.pool.GetConnection(addrB)
+			require.NoError(t, err)
+			require.NotNil(t, ca)
+			require.Equal(t, tc.encrypted, ca.Encrypted())
+
+			cb, err := b.pool.GetConnection(addrA)
+			require.NoError(t, err)
+			require.NotNil(t, cb)
+			require.Equal(t, tc.encrypted, cb.Encrypted())
+		})
+	}
+}
+
+func TestSecureConnectionNodePubKeyMismatch(t *testing.T) {
+	a, b, addrA, _ := setupSecurePeers(t, true, true)
+	defer b.shutdown()
+	defer a.shutdown()
+
+	wrong, _ := cipher.GenerateKeyPair()
+	require.NoError(t, b.pool.SecureConnection(addrA, wrong))
+
+	select {
+	case reason := <-b.disconnects:
+		require.Equal(t, ErrDisconnectSecureNodePubKeyMismatch, reason)
+	case <-time.After(time.Second * 2):
+		t.Fatal("Timed out waiting for disconnect")
+	}
+}
+
+func TestSecureConnectionPeerDisabled(t *testing.T) {
+	a, b, addrA, _ := setupSecurePeers(t, false, true)
+	defer b.shutdown()
+	defer a.shutdown()
+
+	// The peer does not understand the handshake and drops the connection
+	pk, _ := cipher.GenerateKeyPair()
+	require.NoError(t, b.pool.SecureConnection(addrA, pk))
+
+	select {
+	case reason := <-a.disconnects:
+		require.Error(t, reason)
+	case <-time.After(time.Second * 2):
+		t.Fatal("Timed out waiting for disconnect")
+	}
+}
@@ -0,0 +1,93 @@
+package gnet
+
+import (
+	"sync"
+)
+
+// ConnectionStats is the traffic of a connection
+type ConnectionStats struct {
+	// Bytes read from the connection, including framing and encryption overhead
+	BytesReceived uint64
+	// Bytes written to the connection, including framing and encryption overhead
+	BytesSent uint64
+	// Number of messages received, by message type
+	MessagesReceived map[string]uint64
+	// Number of messages sent, by message type
+	MessagesSent map[string]uint64
+}
+
+// connectionStats counts the traffic of a connection.
+// It is shared by the copies of a Connection and is safe for concurrent use.
+// A nil connectionStats records nothing
+type connectionStats struct {
+	sync.Mutex
+	stats ConnectionStats
+}
+
+func newConnectionStats() *connectionStats {
+	return &connectionStats{
+		stats: ConnectionStats{
+			MessagesReceived: make(map[string]uint64),
+			MessagesSent:     make(map[string]uint64),
+		},
+	}
+}
+
+// received records n bytes read from the connection
+func (cs *connectionStats) received(n int) {
+	if cs == nil {
+		return
+	}
+
+	cs.Lock()
+	defer cs.Unlock()
+	cs.stats.BytesReceived += uint64(n)
+}
+
+// receivedMessage records a message received from the connection
+func (cs *connectionStats) receivedMessage(msgType string) {
+	if cs == nil {
+		return
+	}
+
+	cs.Lock()
+	defer cs.Unlock()
+	cs.stats.MessagesReceived[msgType]++
+}
+
+// sent records a message of n bytes written to the connection.
+// msgType is empty for handshake frames that are not messages
+func (cs *connectionStats) sent(msgType string, n int) {
+	if cs == nil {
+		return
+	}
+
+	cs.Lock()
+	defer cs.Unlock()
+	cs.stats.BytesSent += uint64(n)
+	if msgType != "" {
+		cs.stats.MessagesSent[msgType]++
+	}
+}
+
+// snapshot returns a copy of the stats
+func (cs *connectionStats) snapshot() ConnectionStats {
+	if cs == nil {
+		return ConnectionStats{}
+	}
+
+	cs.Lock()
+	defer cs.Unlock()
+
+	s := cs.stats
+	s.MessagesReceived = make(map[string]uint64, len(cs.stats.MessagesReceived))
+	for k, v := range cs.stats.MessagesReceived {
+		s.MessagesReceived[k] = v
+	}
+	s.MessagesSent = make(map[string]uint64, len(cs.stats.MessagesSent))
+	for k, v := range cs.stats.MessagesSent {
+		s.MessagesSent[k] = v
+	}
+
+	return s
+}
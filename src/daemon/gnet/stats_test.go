@@ -0,0 +1,44 @@
+package gnet
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectionStats(t *testing.T) {
+	cs := newConnectionStats()
+	cs.received(100)
+	cs.received(20)
+	cs.receivedMessage("GETB")
+	cs.receivedMessage("GETB")
+	cs.receivedMessage("ANNT")
+	cs.sent("GIVB", 50)
+	cs.sent("", 10)
+
+	s := cs.snapshot()
+	require.Equal(t, ConnectionStats{
+		BytesReceived: 120,
+		BytesSent:     60,
+		MessagesReceived: map[string]uint64{
+			"GETB": 2,
+			"ANNT": 1,
+		},
+		MessagesSent: map[string]uint64{
+			"GIVB": 1,
+		},
+	}, s)
+
+	// The snapshot is not modified by later traffic
+	cs.receivedMessage("GETB")
+	cs.sent("GIVB", 50)
+	require.Equal(t, uint64(2), s.MessagesReceived["GETB"])
+	require.Equal(t, uint64(1), s.MessagesSent["GIVB"])
+	require.Equal(t, uint64(3), cs.snapshot().MessagesReceived["GETB"])
+
+	// A connection without stats records nothing
+	c := &Connection{}
+	c.stats.received(10)
+	c.stats.sent("GIVB", 10)
+	require.Equal(t, ConnectionStats{}, c.Stats())
+}
@@ -0,0 +1,345 @@
+package daemon
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+// headersSyncProtocolVersion is the minimum protocol version of peers that support GetHeadersMessage
+const headersSyncProtocolVersion int32 = 3
+
+var (
+	// errBlockHeadersNotContiguous is returned if headers do not extend our best known header chain.
+	// This can happen if a peer replies late, it is not a reason to disconnect.
+	errBlockHeadersNotContiguous = errors.New("Block headers do not extend the known header chain")
+	// errBlockHeaderSigner is returned if a block header is not signed by any of the block signers
+	errBlockHeaderSigner = errors.New("Block header is not signed by a block signer")
+)
+
+// blockDownload is a request for a range of blocks sent to a peer during headers-first sync
+type blockDownload struct {
+	addr        string
+	start       uint64
+	count       uint64
+	requestedAt time.Time
+}
+
+// syncPeer is a peer that blocks can be downloaded from
+type syncPeer struct {
+	addr   string
+	height uint64
+}
+
+// headerSyncConfig configures the block download scheduling of headerSync
+type headerSyncConfig struct {
+	// Number of blocks requested in a single GetBlocksMessage
+	requestCount uint64
+	// Maximum number of downloads in flight to a single peer
+	maxDownloadsPerPeer int
+	// How many blocks ahead of the head block can be downloaded
+	window uint64
+	// How long to wait for a download before requesting the blocks from another peer
+	timeout time.Duration
+}
+
+// headerSync tracks the state of headers-first block synchronization.
+// Signed block headers are downloaded first and checked against the pubkeys of the block signers.
+// Block bodies are then downloaded from several peers in parallel, checked against their
+// header and handed back in order for execution.
+type headerSync struct {
+	sync.Mutex
+	pubkeys []cipher.PubKey
+	// verified headers above the head block, by seq
+	headers map[uint64]SignedBlockHeader
+	// highest verified header
+	tip SignedBlockHeader
+	// downloaded blocks waiting to be executed, by seq
+	blocks map[uint64]coin.SignedBlock
+	// downloads in flight, by each seq they cover
+	downloads map[uint64]*blockDownload
+}
+
+func newHeaderSync(pubkeys ...cipher.PubKey) *headerSync {
+	return &headerSync{
+		pubkeys:   pubkeys,
+		headers:   make(map[uint64]SignedBlockHeader),
+		blocks:    make(map[uint64]coin.SignedBlock),
+		downloads: make(map[uint64]*blockDownload),
+	}
+}
+
+// setPubkeys sets the pubkeys that may sign block headers
+func (s *headerSync) setPubkeys(pubkeys ...cipher.PubKey) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.pubkeys = pubkeys
+}
+
+// addHeaders verifies headers and appends them to the header chain, which starts at the head block.
+// Headers more than maxAhead blocks above the head block are not added.
+// Returns the number of headers added.
+func (s *headerSync) addHeaders(head coin.BlockHeader, headers []SignedBlockHeader, maxAhead uint64) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.prune(head.BkSeq)
+
+	// The head block changed to a block of another branch, e.g. by a reorg,
+	// so the header chain no longer starts at the head block
+	if len(s.headers) != 0 {
+		if next, ok := s.headers[head.BkSeq+1]; !ok || next.Head.PrevHash != head.Hash() {
+			logger.WithField("seq", head.BkSeq).Debug("Header chain does not extend the head block, dropping it")
+			s.reset()
+		}
+	}
+
+	prev := head
+	if len(s.headers) != 0 {
+		prev = s.tip.Head
+	}
+
+	n := 0
+	for _, h := range headers {
+		if h.Head.BkSeq <= prev.BkSeq {
+			continue
+		}
+
+		if h.Head.BkSeq-head.BkSeq > maxAhead {
+			break
+		}
+
+		if h.Head.BkSeq != prev.BkSeq+1 || h.Head.PrevHash != prev.Hash() {
+			return n, errBlockHeadersNotContiguous
+		}
+
+		if err := h.Verify(s.pubkeys...); err != nil {
+			logger.WithError(err).WithField("seq", h.Head.BkSeq).Warning("Block header signature is invalid")
+			return n, ErrDisconnectInvalidBlockHeaders
+		}
+
+		s.headers[h.Head.BkSeq] = h
+		s.tip = h
+		prev = h.Head
+		n++
+	}
+
+	return n, nil
+}
+
+// addBlocks checks blocks downloaded from addr against the header chain and stores them for execution.
+// Blocks that are not part of the header chain are ignored.
+// Returns the number of blocks stored.
+func (s *headerSync) addBlocks(addr string, blocks []coin.SignedBlock) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	n := 0
+	for _, b := range blocks {
+		h, ok := s.headers[b.Seq()]
+		if !ok {
+			continue
+		}
+
+		if b.Block.Head != h.Head || b.Sig != h.Sig {
+			return n, ErrDisconnectBlockHeaderMismatch
+		}
+
+		if b.Block.Body.Hash() != h.Head.BodyHash {
+			logger.WithField("seq", b.Seq()).Warning("Block body hash does not match its header")
+			return n, ErrDisconnectBlockHeaderMismatch
+		}
+
+		s.blocks[b.Seq()] = b
+		n++
+
+		// Release the download that covered this block, so that any blocks missing
+		// from the response are requested again
+		if d := s.downloads[b.Seq()]; d != nil && d.addr == addr {
+			s.release(d)
+		}
+	}
+
+	return n, nil
+}
+
+// executable returns the downloaded blocks that follow the head block, in order
+func (s *headerSync) executable(headSeq uint64) []coin.SignedBlock {
+	s.Lock()
+	defer s.Unlock()
+
+	s.prune(headSeq)
+
+	var blocks []coin.SignedBlock
+	for seq := headSeq + 1; ; seq++ {
+		b, ok := s.blocks[seq]
+		if !ok {
+			break
+		}
+		blocks = append(blocks, b)
+	}
+
+	return blocks
+}
+
+// schedule assigns the blocks between the head block and the header chain tip to peers.
+// Downloads that timed out are assigned again. Returns the new downloads to request.
+func (s *headerSync) schedule(headSeq uint64, peers []syncPeer, now time.Time, cfg headerSyncConfig) []blockDownload {
+	s.Lock()
+	defer s.Unlock()
+
+	s.prune(headSeq)
+
+	inFlight := make(map[string]int, len(peers))
+	for _, d := range s.inFlight() {
+		if now.Sub(d.requestedAt) > cfg.timeout {
+			logger.WithField("addr", d.addr).WithField("start", d.start).Debug("Block download timed out")
+			s.release(d)
+			continue
+		}
+		inFlight[d.addr]++
+	}
+
+	if len(s.headers) == 0 {
+		return nil
+	}
+
+	end := s.tip.Head.BkSeq
+	if end-headSeq > cfg.window {
+		end = headSeq + cfg.window
+	}
+
+	var downloads []blockDownload
+	seq := headSeq + 1
+	for seq <= end {
+		if s.covered(seq) {
+			seq++
+			continue
+		}
+
+		// Pick the least busy peer that has the block
+		var peer *syncPeer
+		for i, p := range peers {
+			if p.height < seq || inFlight[p.addr] >= cfg.maxDownloadsPerPeer {
+				continue
+			}
+			if peer == nil || inFlight[p.addr] < inFlight[peer.addr] {
+				peer = &peers[i]
+			}
+		}
+		if peer == nil {
+			break
+		}
+
+		d := &blockDownload{
+			addr:        peer.addr,
+			start:       seq,
+			requestedAt: now,
+		}
+		for seq <= end && seq <= peer.height && d.count < cfg.requestCount && !s.covered(seq) {
+			s.downloads[seq] = d
+			d.count++
+			seq++
+		}
+
+		inFlight[peer.addr]++
+		downloads = append(downloads, *d)
+	}
+
+	return downloads
+}
+
+// removePeer releases the downloads assigned to a peer
+func (s *headerSync) removePeer(addr string) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, d := range s.inFlight() {
+		if d.addr == addr {
+			s.release(d)
+		}
+	}
+}
+
+// tipSeq returns the seq of the highest verified header, or headSeq if there is no header above it
+func (s *headerSync) tipSeq(headSeq uint64) uint64 {
+	s.Lock()
+	defer s.Unlock()
+
+	if len(s.headers) != 0 && s.tip.Head.BkSeq > headSeq {
+		return s.tip.Head.BkSeq
+	}
+	return headSeq
+}
+
+// progress returns the seq of the highest verified header, the number of downloads in flight
+// and the number of downloaded blocks waiting to be executed
+func (s *headerSync) progress() (uint64, int, int) {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.tip.Head.BkSeq, len(s.inFlight()), len(s.blocks)
+}
+
+// inFlight returns each download in flight once. Must be called with the lock held.
+func (s *headerSync) inFlight() []*blockDownload {
+	seen := make(map[*blockDownload]struct{})
+	var downloads []*blockDownload
+	for _, d := range s.downloads {
+		if _, ok := seen[d]; ok {
+			continue
+		}
+		seen[d] = struct{}{}
+		downloads = append(downloads, d)
+	}
+	return downloads
+}
+
+// covered returns true if a block has been downloaded or is being downloaded. Must be called with the lock held.
+func (s *headerSync) covered(seq uint64) bool {
+	if _, ok := s.blocks[seq]; ok {
+		return true
+	}
+	_, ok := s.downloads[seq]
+	return ok
+}
+
+// release removes a download, so that its blocks can be requested again. Must be called with the lock held.
+func (s *headerSync) release(d *blockDownload) {
+	for seq := d.start; seq < d.start+d.count; seq++ {
+		if s.downloads[seq] == d {
+			delete(s.downloads, seq)
+		}
+	}
+}
+
+// reset drops the header chain and the blocks downloaded for it. Must be called with the lock held.
+func (s *headerSync) reset() {
+	s.headers = make(map[uint64]SignedBlockHeader)
+	s.tip = SignedBlockHeader{}
+	s.blocks = make(map[uint64]coin.SignedBlock)
+	s.downloads = make(map[uint64]*blockDownload)
+}
+
+// prune removes state for blocks that have been executed. Must be called with the lock held.
+func (s *headerSync) prune(headSeq uint64) {
+	for seq := range s.headers {
+		if seq <= headSeq {
+			delete(s.headers, seq)
+		}
+	}
+	for seq := range s.blocks {
+		if seq <= headSeq {
+			delete(s.blocks, seq)
+		}
+	}
+	for seq := range s.downloads {
+		if seq <= headSeq {
+			delete(s.downloads, seq)
+		}
+	}
+}
@@ -0,0 +1,309 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+// makeTestSignedChain creates n signed blocks that follow head
+func makeTestSignedChain(t *testing.T, head coin.BlockHeader, n int, seckey cipher.SecKey) []coin.SignedBlock {
+	blocks := make([]coin.SignedBlock, n)
+	prev := head
+	for i := range blocks {
+		body := coin.BlockBody{
+			Transactions: coin.Transactions{
+				{
+					Length: uint32(i),
+				},
+			},
+		}
+
+		b := coin.Block{
+			Head: coin.BlockHeader{
+				Version:  prev.Version,
+				Time:     prev.Time + 10,
+				BkSeq:    prev.BkSeq + 1,
+				PrevHash: prev.Hash(),
+				BodyHash: body.Hash(),
+			},
+			Body: body,
+		}
+
+		blocks[i] = coin.SignedBlock{
+			Block: b,
+			Sig:   cipher.MustSignHash(b.HashHeader(), seckey),
+		}
+		prev = b.Head
+	}
+
+	require.Len(t, blocks, n)
+	return blocks
+}
+
+func signedBlockHeaders(blocks []coin.SignedBlock) []SignedBlockHeader {
+	headers := make([]SignedBlockHeader, len(blocks))
+	for i, b := range blocks {
+		headers[i] = NewSignedBlockHeader(b)
+	}
+	return headers
+}
+
+func TestHeaderSyncAddHeaders(t *testing.T) {
+	pubkey, seckey := cipher.GenerateKeyPair()
+	_, badSeckey := cipher.GenerateKeyPair()
+
+	head := coin.BlockHeader{
+		Version: 1,
+		Time:    100,
+		BkSeq:   5,
+	}
+	blocks := makeTestSignedChain(t, head, 10, seckey)
+	headers := signedBlockHeaders(blocks)
+
+	// Headers can be added in several batches
+	s := newHeaderSync(pubkey)
+	n, err := s.addHeaders(head, headers[:4], 100)
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+	require.Equal(t, uint64(9), s.tipSeq(head.BkSeq))
+
+	// Already known headers are skipped
+	n, err = s.addHeaders(head, headers[2:], 100)
+	require.NoError(t, err)
+	require.Equal(t, 6, n)
+	require.Equal(t, uint64(15), s.tipSeq(head.BkSeq))
+
+	// Headers that do not extend the chain are ignored
+	s = newHeaderSync(pubkey)
+	n, err = s.addHeaders(head, headers[1:], 100)
+	require.Equal(t, errBlockHeadersNotContiguous, err)
+	require.Equal(t, 0, n)
+	require.Equal(t, head.BkSeq, s.tipSeq(head.BkSeq))
+
+	// Headers with the wrong previous hash are ignored
+	bad := append([]SignedBlockHeader{}, headers...)
+	bad[3].Head.PrevHash = cipher.SHA256{}
+	n, err = s.addHeaders(head, bad, 100)
+	require.Equal(t, errBlockHeadersNotContiguous, err)
+	require.Equal(t, 3, n)
+
+	// Headers that are not signed by the blockchain pubkey are rejected
+	s = newHeaderSync(pubkey)
+	bad = signedBlockHeaders(makeTestSignedChain(t, head, 2, badSeckey))
+	n, err = s.addHeaders(head, bad, 100)
+	require.Equal(t, ErrDisconnectInvalidBlockHeaders, err)
+	require.Equal(t, 0, n)
+
+	// Headers signed by any of the block publishers are accepted
+	publisher, publisherSeckey := cipher.GenerateKeyPair()
+	s = newHeaderSync(pubkey, publisher)
+	n, err = s.addHeaders(head, signedBlockHeaders(makeTestSignedChain(t, head, 2, publisherSeckey)), 100)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	s = newHeaderSync(pubkey, publisher)
+	n, err = s.addHeaders(head, bad, 100)
+	require.Equal(t, ErrDisconnectInvalidBlockHeaders, err)
+	require.Equal(t, 0, n)
+
+	// Headers signed by the pubkey of a key checkpoint are accepted once the pubkeys are updated
+	rotated, rotatedSeckey := cipher.GenerateKeyPair()
+	rotatedHeaders := signedBlockHeaders(makeTestSignedChain(t, head, 2, rotatedSeckey))
+	s = newHeaderSync(pubkey)
+	n, err = s.addHeaders(head, rotatedHeaders, 100)
+	require.Equal(t, ErrDisconnectInvalidBlockHeaders, err)
+	require.Equal(t, 0, n)
+
+	s.setPubkeys(pubkey, rotated)
+	n, err = s.addHeaders(head, rotatedHeaders, 100)
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	// Headers more than maxAhead blocks above the head block are not added
+	s = newHeaderSync(pubkey)
+	n, err = s.addHeaders(head, headers, 4)
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+	require.Equal(t, uint64(9), s.tipSeq(head.BkSeq))
+
+	// They are added once the head block moves up
+	n, err = s.addHeaders(blocks[3].Head, headers, 4)
+	require.NoError(t, err)
+	require.Equal(t, 4, n)
+	require.Equal(t, uint64(13), s.tipSeq(blocks[3].Head.BkSeq))
+
+	// The header chain is dropped when the head block changes to a block of another branch
+	s = newHeaderSync(pubkey)
+	n, err = s.addHeaders(head, headers, 100)
+	require.NoError(t, err)
+	require.Equal(t, 10, n)
+
+	sideBlocks := makeTestSignedChain(t, blocks[0].Head, 2, seckey)
+	sideBlocks[0].Block.Head.Time++
+	sideHead := sideBlocks[0].Block.Head
+	require.NotEqual(t, blocks[1].Block.Head, sideHead)
+	require.Equal(t, blocks[1].Seq(), sideHead.BkSeq)
+
+	_, err = s.addBlocks("1.1.1.1:6000", blocks[2:4])
+	require.NoError(t, err)
+
+	n, err = s.addHeaders(sideHead, nil, 100)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+	require.Equal(t, sideHead.BkSeq, s.tipSeq(sideHead.BkSeq))
+	require.Empty(t, s.executable(sideHead.BkSeq))
+
+	sideHeaders := signedBlockHeaders(makeTestSignedChain(t, sideHead, 3, seckey))
+	n, err = s.addHeaders(sideHead, sideHeaders, 100)
+	require.NoError(t, err)
+	require.Equal(t, 3, n)
+	require.Equal(t, sideHead.BkSeq+3, s.tipSeq(sideHead.BkSeq))
+}
+
+func TestHeaderSyncAddBlocks(t *testing.T) {
+	pubkey, seckey := cipher.GenerateKeyPair()
+
+	head := coin.BlockHeader{
+		Version: 1,
+		Time:    100,
+		BkSeq:   5,
+	}
+	blocks := makeTestSignedChain(t, head, 4, seckey)
+
+	s := newHeaderSync(pubkey)
+	_, err := s.addHeaders(head, signedBlockHeaders(blocks), 100)
+	require.NoError(t, err)
+
+	// Blocks without a verified header are ignored
+	extra := makeTestSignedChain(t, blocks[3].Block.Head, 1, seckey)
+	n, err := s.addBlocks("1.1.1.1:6000", extra)
+	require.NoError(t, err)
+	require.Equal(t, 0, n)
+
+	// A block whose body does not match the header is rejected
+	bad := blocks[1]
+	bad.Block.Body = coin.BlockBody{}
+	n, err = s.addBlocks("1.1.1.1:6000", []coin.SignedBlock{blocks[0], bad})
+	require.Equal(t, ErrDisconnectBlockHeaderMismatch, err)
+	require.Equal(t, 1, n)
+
+	// A block whose header differs from the verified header is rejected
+	bad = blocks[2]
+	bad.Block.Head.Fee = 1
+	n, err = s.addBlocks("1.1.1.1:6000", []coin.SignedBlock{bad})
+	require.Equal(t, ErrDisconnectBlockHeaderMismatch, err)
+	require.Equal(t, 0, n)
+
+	// Only the blocks following the head block are executable
+	require.Equal(t, blocks[:1], s.executable(head.BkSeq))
+
+	n, err = s.addBlocks("1.1.1.1:6000", blocks[2:])
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+	require.Equal(t, blocks[:1], s.executable(head.BkSeq))
+
+	n, err = s.addBlocks("1.1.1.1:6000", blocks[1:2])
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+	require.Equal(t, blocks, s.executable(head.BkSeq))
+
+	// Executed blocks are pruned
+	require.Equal(t, blocks[2:], s.executable(blocks[1].Seq()))
+	tip, inFlight, downloaded := s.progress()
+	require.Equal(t, blocks[3].Seq(), tip)
+	require.Equal(t, 0, inFlight)
+	require.Equal(t, 2, downloaded)
+}
+
+func TestHeaderSyncSchedule(t *testing.T) {
+	pubkey, seckey := cipher.GenerateKeyPair()
+
+	head := coin.BlockHeader{
+		Version: 1,
+		Time:    100,
+		BkSeq:   0,
+	}
+	blocks := makeTestSignedChain(t, head, 20, seckey)
+
+	cfg := headerSyncConfig{
+		requestCount:        4,
+		maxDownloadsPerPeer: 2,
+		window:              16,
+		timeout:             time.Second * 30,
+	}
+
+	now := time.Now()
+
+	// Nothing to schedule without headers
+	s := newHeaderSync(pubkey)
+	require.Empty(t, s.schedule(head.BkSeq, []syncPeer{{addr: "1.1.1.1:6000", height: 20}}, now, cfg))
+
+	_, err := s.addHeaders(head, signedBlockHeaders(blocks), 100)
+	require.NoError(t, err)
+
+	peers := []syncPeer{
+		{
+			addr:   "1.1.1.1:6000",
+			height: 20,
+		},
+		{
+			addr:   "2.2.2.2:6000",
+			height: 6,
+		},
+	}
+
+	// Downloads are spread across peers, limited by peer height, per-peer limit and window
+	downloads := s.schedule(head.BkSeq, peers, now, cfg)
+	require.Equal(t, []blockDownload{
+		{addr: "1.1.1.1:6000", start: 1, count: 4, requestedAt: now},
+		{addr: "2.2.2.2:6000", start: 5, count: 2, requestedAt: now},
+		{addr: "1.1.1.1:6000", start: 7, count: 4, requestedAt: now},
+	}, downloads)
+
+	_, inFlight, _ := s.progress()
+	require.Equal(t, 3, inFlight)
+
+	// Nothing new while downloads are in flight and peers are busy
+	require.Empty(t, s.schedule(head.BkSeq, peers, now, cfg))
+
+	// Receiving blocks releases the download
+	n, err := s.addBlocks("2.2.2.2:6000", blocks[4:6])
+	require.NoError(t, err)
+	require.Equal(t, 2, n)
+
+	downloads = s.schedule(head.BkSeq, peers, now, cfg)
+	require.Empty(t, downloads, "2.2.2.2:6000 does not have more blocks and 1.1.1.1:6000 is busy")
+
+	// A disconnected peer's downloads are released
+	s.removePeer("1.1.1.1:6000")
+	_, inFlight, _ = s.progress()
+	require.Equal(t, 0, inFlight)
+
+	// Released downloads are assigned again
+	downloads = s.schedule(head.BkSeq, peers[:1], now, cfg)
+	require.Equal(t, []blockDownload{
+		{addr: "1.1.1.1:6000", start: 1, count: 4, requestedAt: now},
+		{addr: "1.1.1.1:6000", start: 7, count: 4, requestedAt: now},
+	}, downloads)
+
+	// Timed out downloads are assigned again
+	later := now.Add(cfg.timeout * 2)
+	downloads = s.schedule(head.BkSeq, peers[:1], later, cfg)
+	require.Equal(t, []blockDownload{
+		{addr: "1.1.1.1:6000", start: 1, count: 4, requestedAt: later},
+		{addr: "1.1.1.1:6000", start: 7, count: 4, requestedAt: later},
+	}, downloads)
+
+	// The window moves with the head block
+	_, err = s.addBlocks("1.1.1.1:6000", blocks[:4])
+	require.NoError(t, err)
+	downloads = s.schedule(blocks[5].Seq(), peers[:1], later, cfg)
+	require.Equal(t, []blockDownload{
+		{addr: "1.1.1.1:6000", start: 11, count: 4, requestedAt: later},
+	}, downloads)
+}
@@ -0,0 +1,107 @@
+package daemon
+
+import (
+	"github.com/ness-network/ness/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/cipher"
+)
+
+// keyCheckpointsProtocolVersion is the minimum protocol version of peers that support GiveKeyCheckpointsMessage
+const keyCheckpointsProtocolVersion int32 = 6
+
+// CreateBroadcastKeyCheckpoint creates a key checkpoint that hands the block signing authority to pubkey
+// from the block with seq onward, and sends it to all connections that support key checkpoints.
+// Only a block publisher whose seckey belongs to the blockchain pubkey in effect can create it.
+func (dm *Daemon) CreateBroadcastKeyCheckpoint(seq uint64, pubkey cipher.PubKey) (*blockdb.KeyCheckpoint, error) {
+	kc, err := dm.visor.CreateKeyCheckpoint(seq, pubkey)
+	if err != nil {
+		return nil, err
+	}
+
+	dm.headerSync.setPubkeys(dm.blockSigners()...)
+
+	if err := dm.relayKeyCheckpoints("", []blockdb.KeyCheckpoint{kc}); err != nil && err != ErrNetworkingDisabled {
+		logger.WithError(err).Error("relayKeyCheckpoints failed")
+		return nil, err
+	}
+
+	return &kc, nil
+}
+
+// blockchainPubkeys returns the genesis blockchain pubkey followed by the pubkeys of the key checkpoints
+func (dm *Daemon) blockchainPubkeys() []cipher.PubKey {
+	pubkeys, err := dm.visor.BlockchainPubkeys()
+	if err != nil {
+		logger.WithError(err).Error("visor.BlockchainPubkeys failed")
+		return []cipher.PubKey{dm.config.BlockchainPubkey}
+	}
+
+	return pubkeys
+}
+
+// blockSigners returns the pubkeys that may sign blocks: the blockchain pubkeys of the key history
+// and the block publishers. Which blockchain pubkey is in effect for a block is checked when it is executed.
+func (dm *Daemon) blockSigners() []cipher.PubKey {
+	return append(dm.blockchainPubkeys(), dm.config.PublisherPubkeys...)
+}
+
+// addKeyCheckpoint adds a key checkpoint received from a peer.
+// Returns false if the checkpoint is known already.
+func (dm *Daemon) addKeyCheckpoint(kc blockdb.KeyCheckpoint) (bool, error) {
+	added, err := dm.visor.AddKeyCheckpoint(kc)
+	if err != nil || !added {
+		return added, err
+	}
+
+	dm.headerSync.setPubkeys(dm.blockSigners()...)
+
+	return true, nil
+}
+
+// sendKeyCheckpoints sends all key checkpoints to addr, if there are any and the connection supports them
+func (dm *Daemon) sendKeyCheckpoints(addr string) error {
+	if c := dm.connections.get(addr); c == nil || !c.SupportsKeyCheckpoints() {
+		return nil
+	}
+
+	checkpoints, err := dm.visor.GetKeyCheckpoints()
+	if err != nil {
+		return err
+	}
+
+	if len(checkpoints) == 0 {
+		return nil
+	}
+
+	return dm.sendMessage(addr, NewGiveKeyCheckpointsMessage(checkpoints))
+}
+
+// relayKeyCheckpoints sends key checkpoints to the connections other than exclude that support them
+func (dm *Daemon) relayKeyCheckpoints(exclude string, checkpoints []blockdb.KeyCheckpoint) error {
+	if dm.config.DisableNetworking {
+		return ErrNetworkingDisabled
+	}
+
+	var addrs []string
+	for _, c := range dm.connections.all() {
+		if c.Addr != exclude && c.SupportsKeyCheckpoints() {
+			addrs = append(addrs, c.Addr)
+		}
+	}
+
+	if len(addrs) == 0 {
+		return nil
+	}
+
+	_, err := dm.pool.Pool.BroadcastMessage(NewGiveKeyCheckpointsMessage(checkpoints), addrs)
+	return err
+}
+
+// containsPubkey returns true if pubkeys contains pubkey
+func containsPubkey(pubkeys []cipher.PubKey, pubkey cipher.PubKey) bool {
+	for _, p := range pubkeys {
+		if p == pubkey {
+			return true
+		}
+	}
+	return false
+}
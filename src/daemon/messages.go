@@ -9,13 +9,17 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/ness-network/ness/src/consensus"
+	"github.com/ness-network/ness/src/daemon/gnet"
+	"github.com/ness-network/ness/src/daemon/pex"
+	"github.com/ness-network/ness/src/util/iputil"
+	"github.com/ness-network/ness/src/visor"
+	"github.com/ness-network/ness/src/visor/blockdb"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/cipher/encoder"
 	"github.com/skycoin/skycoin/src/coin"
-	"github.com/skycoin/skycoin/src/daemon/gnet"
-	"github.com/skycoin/skycoin/src/daemon/pex"
 	"github.com/skycoin/skycoin/src/params"
-	"github.com/skycoin/skycoin/src/util/iputil"
+	"github.com/skycoin/skycoin/src/transaction"
 	"github.com/skycoin/skycoin/src/util/useragent"
 )
 
@@ -53,7 +57,17 @@ func NewMessageConfig(prefix string, m interface{}) MessageConfig {
 //go:generate skyencoder -unexported -struct GiveTxnsMessage
 //go:generate skyencoder -unexported -struct AnnounceTxnsMessage
 //go:generate skyencoder -unexported -struct DisconnectMessage
+//go:generate skyencoder -unexported -struct GetHeadersMessage
+//go:generate skyencoder -unexported -struct GiveHeadersMessage
+//go:generate skyencoder -unexported -struct CompactBlockMessage
+//go:generate skyencoder -unexported -struct GetBlockTxnsMessage
+//go:generate skyencoder -unexported -struct GiveBlockTxnsMessage
+//go:generate skyencoder -unexported -struct BlockCandidateMessage
+//go:generate skyencoder -unexported -struct GiveKeyCheckpointsMessage
+//go:generate skyencoder -unexported -struct GivePeerAddrsMessage
+//go:generate skyencoder -unexported -struct GiveBlockCertificatesMessage
 //go:generate skyencoder -unexported -struct IPAddr
+//go:generate skyencoder -unexported -struct PeerAddr
 //go:generate skyencoder -unexported -output-path . -package daemon -struct SignedBlock github.com/skycoin/skycoin/src/coin
 //go:generate skyencoder -unexported -output-path . -package daemon -struct Transaction github.com/skycoin/skycoin/src/coin
 
@@ -72,6 +86,15 @@ func getMessageConfigs() []MessageConfig {
 		NewMessageConfig("GIVT", GiveTxnsMessage{}),
 		NewMessageConfig("ANNT", AnnounceTxnsMessage{}),
 		NewMessageConfig("DISC", DisconnectMessage{}),
+		NewMessageConfig("GETH", GetHeadersMessage{}),
+		NewMessageConfig("GIVH", GiveHeadersMessage{}),
+		NewMessageConfig("CMPB", CompactBlockMessage{}),
+		NewMessageConfig("GETX", GetBlockTxnsMessage{}),
+		NewMessageConfig("GIVX", GiveBlockTxnsMessage{}),
+		NewMessageConfig("CNDB", BlockCandidateMessage{}),
+		NewMessageConfig("GIVK", GiveKeyCheckpointsMessage{}),
+		NewMessageConfig("GIVA", GivePeerAddrsMessage{}),
+		NewMessageConfig("GIVC", GiveBlockCertificatesMessage{}),
 	}
 }
 
@@ -108,6 +131,9 @@ func NewMessages(c MessagesConfig) *Messages {
 	}
 }
 
+// ErrIPAddrNotIPv4 the address can not be represented by IPAddr because it is not an IPv4 address
+var ErrIPAddrNotIPv4 = errors.New("IPAddr only supports IPv4 addresses")
+
 // IPAddr compact representation of IP:Port
 type IPAddr struct {
 	IP   uint32
@@ -115,16 +141,16 @@ type IPAddr struct {
 }
 
 // NewIPAddr returns an IPAddr from an ip:port string.
+// IPAddr can only hold IPv4 addresses, use PeerAddr for IPv6 addresses.
 func NewIPAddr(addr string) (ipaddr IPAddr, err error) {
 	ips, port, err := iputil.SplitAddr(addr)
 	if err != nil {
 		return
 	}
 
-	// TODO -- support ipv6
 	ipb := net.ParseIP(ips).To4()
 	if ipb == nil {
-		err = errors.New("Ignoring IPv6 address")
+		err = ErrIPAddrNotIPv4
 		return
 	}
 
@@ -199,6 +225,7 @@ type GivePeersMessage struct {
 
 // NewGivePeersMessage []*pex.Peer is converted to []IPAddr for binary transmission
 // If the size of the message would exceed maxMsgLength, the IPAddr slice is truncated.
+// IPv6 peers are skipped, they are only sent to peers that support GivePeerAddrsMessage.
 func NewGivePeersMessage(peers []pex.Peer, maxMsgLength uint64) *GivePeersMessage {
 	if len(peers) > 512 {
 		peers = peers[:512]
@@ -207,6 +234,9 @@ func NewGivePeersMessage(peers []pex.Peer, maxMsgLength uint64) *GivePeersMessag
 	ipaddrs := make([]IPAddr, 0, len(peers))
 	for _, ps := range peers {
 		ipaddr, err := NewIPAddr(ps.Addr)
+		if err == ErrIPAddrNotIPv4 {
+			continue
+		}
 		if err != nil {
 			logger.WithError(err).WithField("addr", ps.Addr).Warning("GivePeersMessage skipping invalid address")
 			continue
@@ -293,12 +323,15 @@ func (gpm *GivePeersMessage) Handle(mc *gnet.MessageContext, daemon interface{})
 
 // process Notifies the Pex instance that peers were received
 func (gpm *GivePeersMessage) process(d daemoner) {
+	processGivenPeers(d, gpm.c, gpm.GetPeers())
+}
+
+// processGivenPeers adds the peers received via PEX from the connection of mc
+func processGivenPeers(d daemoner, mc *gnet.MessageContext, peers []string) {
 	if d.pexConfig().Disabled {
 		return
 	}
 
-	peers := gpm.GetPeers()
-
 	if len(peers) == 0 {
 		return
 	}
@@ -314,13 +347,122 @@ func (gpm *GivePeersMessage) process(d daemoner) {
 	}
 
 	logger.WithFields(logrus.Fields{
-		"addr":   gpm.c.Addr,
-		"gnetID": gpm.c.ConnID,
+		"addr":   mc.Addr,
+		"gnetID": mc.ConnID,
 		"peers":  peersStr,
 		"count":  len(peers),
 	}).Debug("Received peers via PEX")
 
-	d.addPeers(peers)
+	d.addPeers(peers, mc.Addr)
+}
+
+// GivePeerAddrsMessage sent in response to GetPeersMessage by peers with a protocol version
+// of at least peerAddrsProtocolVersion. Unlike GivePeersMessage it can carry IPv6 peers.
+type GivePeerAddrsMessage struct {
+	Peers []PeerAddr           `enc:",maxlen=512"`
+	c     *gnet.MessageContext `enc:"-"`
+}
+
+// NewGivePeerAddrsMessage []*pex.Peer is converted to []PeerAddr for binary transmission
+// If the size of the message would exceed maxMsgLength, the PeerAddr slice is truncated.
+func NewGivePeerAddrsMessage(peers []pex.Peer, maxMsgLength uint64) *GivePeerAddrsMessage {
+	if len(peers) > 512 {
+		peers = peers[:512]
+	}
+
+	addrs := make([]PeerAddr, 0, len(peers))
+	for _, ps := range peers {
+		pa, err := NewPeerAddr(ps.Addr)
+		if err != nil {
+			logger.WithError(err).WithField("addr", ps.Addr).Warning("GivePeerAddrsMessage skipping invalid address")
+			continue
+		}
+		addrs = append(addrs, pa)
+	}
+
+	m := &GivePeerAddrsMessage{
+		Peers: addrs,
+	}
+	truncateGivePeerAddrsMessage(m, maxMsgLength)
+	return m
+}
+
+// truncateGivePeerAddrsMessage truncates the peers in GivePeerAddrsMessage to fit inside of MaxOutgoingMessageLength
+func truncateGivePeerAddrsMessage(m *GivePeerAddrsMessage, maxMsgLength uint64) {
+	// The message length will include a 4 byte message type prefix.
+	// Panic if the prefix can't fit, otherwise we can't adjust the uint64 safely
+	if maxMsgLength < 4 {
+		logger.Panic("maxMsgLength must be >= 4")
+	}
+
+	maxMsgLength -= 4
+
+	// Measure the current message size, if it fits, return
+	n := m.EncodeSize()
+	if n <= maxMsgLength {
+		return
+	}
+
+	// Measure the size of an empty message
+	var mm GivePeerAddrsMessage
+	size := mm.EncodeSize()
+
+	// Measure the size of the peers, advancing the slice index until it reaches capacity
+	index := -1
+	for i, pa := range m.Peers {
+		x := encodeSizePeerAddr(&pa)
+		if size+x > maxMsgLength {
+			break
+		}
+		size += x
+		index = i
+	}
+
+	m.Peers = m.Peers[:index+1]
+
+	if len(m.Peers) == 0 {
+		logger.Critical().Error("truncateGivePeerAddrsMessage truncated peers to an empty slice")
+	}
+}
+
+// EncodeSize implements gnet.Serializer
+func (gpm *GivePeerAddrsMessage) EncodeSize() uint64 {
+	return encodeSizeGivePeerAddrsMessage(gpm)
+}
+
+// Encode implements gnet.Serializer
+func (gpm *GivePeerAddrsMessage) Encode(buf []byte) error {
+	return encodeGivePeerAddrsMessageToBuffer(buf, gpm)
+}
+
+// Decode implements gnet.Serializer
+func (gpm *GivePeerAddrsMessage) Decode(buf []byte) (uint64, error) {
+	return decodeGivePeerAddrsMessage(buf, gpm)
+}
+
+// GetPeers returns the peers contained in the message as an array of "ip:port" strings.
+// Peers with an unknown address family or an invalid IP are skipped.
+func (gpm *GivePeerAddrsMessage) GetPeers() []string {
+	peers := make([]string, 0, len(gpm.Peers))
+	for _, pa := range gpm.Peers {
+		addr, err := pa.Addr()
+		if err != nil {
+			continue
+		}
+		peers = append(peers, addr)
+	}
+	return peers
+}
+
+// Handle handle message
+func (gpm *GivePeerAddrsMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	gpm.c = mc
+	return daemon.(daemoner).recordMessageEvent(gpm, mc)
+}
+
+// process Notifies the Pex instance that peers were received
+func (gpm *GivePeerAddrsMessage) process(d daemoner) {
+	processGivenPeers(d, gpm.c, gpm.GetPeers())
 }
 
 // IntroductionMessage is sent on first connect by both parties
@@ -329,6 +471,8 @@ type IntroductionMessage struct {
 	UserAgent            useragent.Data       `enc:"-"`
 	UnconfirmedVerifyTxn params.VerifyTxn     `enc:"-"`
 	GenesisHash          cipher.SHA256        `enc:"-"`
+	NodePubkey           cipher.PubKey        `enc:"-"`
+	OnionAddress         string               `enc:"-"`
 
 	// Mirror is a random value generated on client startup that is used to identify self-connections
 	Mirror uint32
@@ -348,16 +492,29 @@ type IntroductionMessage struct {
 	// MaxDropletPrecision uint8 // maximum number of decimal places for announced txns
 	// UserAgent           string `enc:",maxlen=256"`
 	// GenesisHash         cipher.SHA256 // genesis block hash
+	// NodePubkey          cipher.PubKey // node identity for encrypted sessions, omitted if encryption is disabled
+	// OnionAddress        string `enc:",maxlen=128"` // onion address the node listens on, omitted if not set. Only sent after a NodePubkey
 	Extra []byte `enc:",omitempty"`
 }
 
+// maxOnionAddressLen is the maximum length of the onion address in the introduction message
+const maxOnionAddressLen = 128
+
 // NewIntroductionMessage creates introduction message
-func NewIntroductionMessage(mirror uint32, version int32, port uint16, pubkey cipher.PubKey, userAgent string, verifyParams params.VerifyTxn, genesisHash cipher.SHA256) *IntroductionMessage {
+func NewIntroductionMessage(mirror uint32, version int32, port uint16, pubkey cipher.PubKey, userAgent string, verifyParams params.VerifyTxn, genesisHash cipher.SHA256, nodePubkey cipher.PubKey, onionAddress string) *IntroductionMessage {
+	extra := newIntroductionMessageExtra(pubkey, userAgent, verifyParams, genesisHash)
+	if !nodePubkey.Null() {
+		extra = append(extra, nodePubkey[:]...)
+		if onionAddress != "" {
+			extra = append(extra, encoder.SerializeString(onionAddress)...)
+		}
+	}
+
 	return &IntroductionMessage{
 		Mirror:          mirror,
 		ProtocolVersion: version,
 		ListenPort:      port,
-		Extra:           newIntroductionMessageExtra(pubkey, userAgent, verifyParams, genesisHash),
+		Extra:           extra,
 	}
 }
 
@@ -426,7 +583,7 @@ func (intro *IntroductionMessage) process(d daemoner) {
 
 	logger.WithFields(fields).Debug("IntroductionMessage.process")
 
-	if err := intro.Verify(d.DaemonConfig(), logrus.Fields{
+	if err := intro.Verify(d.DaemonConfig(), d.blockchainPubkeys(), logrus.Fields{
 		"addr":   addr,
 		"gnetID": intro.c.ConnID,
 	}); err != nil {
@@ -470,6 +627,29 @@ func (intro *IntroductionMessage) process(d daemoner) {
 		return
 	}
 
+	// Upgrade to an encrypted session before exchanging blocks and transactions
+	if err := d.secureConnection(addr, intro); err != nil {
+		logger.WithError(err).WithFields(fields).Warning("secureConnection failed")
+		var reason gnet.DisconnectReason
+		switch err {
+		case ErrDisconnectEncryptionRequired, gnet.ErrDisconnectSecureNodePubKeyMismatch:
+			reason = err
+		default:
+			reason = ErrDisconnectUnexpectedError
+		}
+
+		if err := d.Disconnect(addr, reason); err != nil {
+			logger.WithError(err).WithFields(fields).Warning("Disconnect")
+		}
+
+		return
+	}
+
+	// Send the key checkpoints, so that the peer can verify the blocks signed by a rotated blockchain pubkey
+	if err := d.sendKeyCheckpoints(addr); err != nil {
+		logger.WithError(err).WithFields(fields).Warning("sendKeyCheckpoints failed")
+	}
+
 	// Request blocks immediately after they're confirmed
 	if err := d.requestBlocksFromAddr(addr); err != nil {
 		logger.WithError(err).WithFields(fields).Warning("requestBlocksFromAddr")
@@ -483,8 +663,10 @@ func (intro *IntroductionMessage) process(d daemoner) {
 	}
 }
 
-// Verify checks if the introduction message is valid returning the appropriate error
-func (intro *IntroductionMessage) Verify(dc DaemonConfig, logFields logrus.Fields) error {
+// Verify checks if the introduction message is valid returning the appropriate error.
+// The blockchain pubkey of the peer must be dc.BlockchainPubkey or one of blockchainPubkeys,
+// which are the pubkeys that key checkpoints handed the block signing authority to.
+func (intro *IntroductionMessage) Verify(dc DaemonConfig, blockchainPubkeys []cipher.PubKey, logFields logrus.Fields) error {
 	// Disconnect if this is a self connection (we have the same mirror value)
 	if intro.Mirror == dc.Mirror {
 		logger.WithFields(logFields).WithField("mirror", intro.Mirror).Info("Remote mirror value matches ours")
@@ -521,7 +703,7 @@ func (intro *IntroductionMessage) Verify(dc DaemonConfig, logFields logrus.Field
 	}
 	copy(bcPubKey[:], intro.Extra[:len(bcPubKey)])
 
-	if dc.BlockchainPubkey != bcPubKey {
+	if dc.BlockchainPubkey != bcPubKey && !containsPubkey(blockchainPubkeys, bcPubKey) {
 		logger.WithFields(logFields).WithFields(logrus.Fields{
 			"pubkey":       bcPubKey.Hex(),
 			"daemonPubkey": dc.BlockchainPubkey.Hex(),
@@ -579,6 +761,33 @@ func (intro *IntroductionMessage) Verify(dc DaemonConfig, logFields logrus.Field
 		return ErrDisconnectInvalidExtraData
 	}
 	copy(intro.GenesisHash[:], intro.Extra[i:])
+	i += len(intro.GenesisHash)
+
+	// The node pubkey for encrypted sessions is optional
+	if extraLen-i >= len(intro.NodePubkey) {
+		copy(intro.NodePubkey[:], intro.Extra[i:])
+		if err := intro.NodePubkey.Verify(); err != nil {
+			logger.WithError(err).WithFields(logFields).Warning("Extra data node pubkey is invalid")
+			return ErrDisconnectInvalidExtraData
+		}
+		i += len(intro.NodePubkey)
+
+		// The onion address is optional and follows the node pubkey
+		if extraLen > i {
+			onionAddress, _, err := encoder.DeserializeString(intro.Extra[i:], maxOnionAddressLen)
+			if err != nil {
+				logger.WithError(err).WithFields(logFields).Warning("Extra data onion address could not be deserialized")
+				return ErrDisconnectInvalidExtraData
+			}
+
+			host, port, err := iputil.SplitAddr(onionAddress)
+			if err != nil || !iputil.IsOnion(host) {
+				logger.WithFields(logFields).WithField("onionAddress", onionAddress).Warning("Extra data onion address is invalid")
+				return ErrDisconnectInvalidExtraData
+			}
+			intro.OnionAddress = iputil.JoinAddr(strings.ToLower(host), port)
+		}
+	}
 
 	return nil
 }
@@ -791,6 +1000,11 @@ func (gbm *GetBlocksMessage) process(d daemoner) {
 		logger.WithField("startBlockSeq", blocks[0].Head.BkSeq).WithFields(fields).Warningf("NewGiveBlocksMessage truncated %d blocks to %d blocks", len(blocks), len(m.Blocks))
 	}
 
+	// The certificates go first, so that the blocks signed by a block publisher can be executed
+	if err := d.sendBlockCertificates(gbm.c.Addr, m.Blocks); err != nil {
+		logger.WithFields(fields).WithError(err).Error("sendBlockCertificates failed")
+	}
+
 	if err := d.sendMessage(gbm.c.Addr, m); err != nil {
 		logger.WithFields(fields).WithError(err).Error("Send GiveBlocksMessage failed")
 	}
@@ -881,6 +1095,24 @@ func (m *GiveBlocksMessage) process(d daemoner) {
 		return
 	}
 
+	// Blocks downloaded by the headers-first sync are checked against the header chain.
+	// They can arrive out of order from several peers, so only the blocks that follow
+	// the head block are executed.
+	blocks, synced, err := d.addSyncBlocks(m.c.Addr, m.Blocks)
+	if err != nil {
+		logger.WithError(err).WithField("addr", m.c.Addr).Warning("addSyncBlocks failed")
+		if err := d.Disconnect(m.c.Addr, err); err != nil {
+			logger.WithError(err).WithField("addr", m.c.Addr).Warning("Disconnect")
+		}
+		return
+	}
+
+	if synced {
+		if err := d.downloadBlocks(); err != nil {
+			logger.WithError(err).Warning("downloadBlocks failed")
+		}
+	}
+
 	// These DB queries are not performed in a transaction for performance reasons.
 	// It is not necessary that the blocks be executed together in a single transaction.
 
@@ -895,23 +1127,29 @@ func (m *GiveBlocksMessage) process(d daemoner) {
 		return
 	}
 
-	for _, b := range m.Blocks {
+	for _, b := range blocks {
 		// To minimize waste when receiving multiple responses from peers
 		// we only break out of the loop if the block itself is invalid.
 		// E.g. if we request 20 blocks since 0 from 2 peers, and one peer
 		// replies with 15 and the other 20, if we did not do this check and
 		// the reply with 15 was received first, we would toss the one with 20
 		// even though we could process it at the time.
+		// Blocks that are not above the head block are stored if they belong to a competing branch,
+		// blocks that are stored already are ignored.
+		var err error
 		if b.Seq() <= maxSeq {
-			continue
-		}
-
-		err := d.executeSignedBlock(b)
-		if err == nil {
+			err = d.addSideBlock(b)
+		} else if err = d.executeSignedBlock(b); err == nil {
 			logger.Critical().WithField("seq", b.Block.Head.BkSeq).Info("Added new block")
 			processed++
-		} else {
+		}
+
+		if err == visor.ErrSideBlockNoParent {
+			requestBranchBlocks(d, m.c.Addr, b.Seq())
+			break
+		} else if err != nil {
 			logger.Critical().WithError(err).WithField("seq", b.Block.Head.BkSeq).Error("Failed to execute received block")
+			d.misbehaved(m.c.Addr, errMisbehaviorInvalidBlock)
 			// Blocks must be received in order, so if one fails its assumed
 			// the rest are failing
 			break
@@ -943,6 +1181,11 @@ func (m *GiveBlocksMessage) process(d daemoner) {
 		logger.WithError(err).Warning("Broadcast AnnounceBlocksMessage failed")
 	}
 
+	// The headers-first sync requests more blocks itself
+	if synced {
+		return
+	}
+
 	// Request more blocks
 	gbm := NewGetBlocksMessage(headBkSeq, d.DaemonConfig().GetBlocksRequestCount)
 	if _, err := d.broadcastMessage(gbm); err != nil {
@@ -950,6 +1193,27 @@ func (m *GiveBlocksMessage) process(d daemoner) {
 	}
 }
 
+// requestBranchBlocks requests the blocks below seq from addr, after it sent a block of a competing branch
+// whose parent is not stored. The blocks of the branch are requested further down until the common ancestor is reached.
+func requestBranchBlocks(d daemoner, addr string, seq uint64) {
+	count := d.DaemonConfig().GetBlocksRequestCount
+
+	var lastBlock uint64
+	if seq > count+1 {
+		lastBlock = seq - count - 1
+	}
+
+	logger.WithFields(logrus.Fields{
+		"addr":      addr,
+		"seq":       seq,
+		"lastBlock": lastBlock,
+	}).Debug("Received a block of a branch without a stored parent, requesting the blocks below it")
+
+	if err := d.sendMessage(addr, NewGetBlocksMessage(lastBlock, count)); err != nil {
+		logger.WithError(err).WithField("addr", addr).Warning("Send GetBlocksMessage failed")
+	}
+}
+
 // AnnounceBlocksMessage tells a peer our highest known BkSeq. The receiving peer can choose
 // to send GetBlocksMessage in response
 type AnnounceBlocksMessage struct {
@@ -1012,168 +1276,150 @@ func (abm *AnnounceBlocksMessage) process(d daemoner) {
 
 	// TODO: Should this be block get request for current sequence?
 	// If client is not caught up, won't attempt to get block
-	m := NewGetBlocksMessage(headBkSeq, d.DaemonConfig().GetBlocksRequestCount)
-	if err := d.sendMessage(abm.c.Addr, m); err != nil {
-		logger.WithError(err).WithFields(fields).Error("Send GetBlocksMessage")
+	if err := d.requestBlocksFromAddr(abm.c.Addr); err != nil {
+		logger.WithError(err).WithFields(fields).Error("requestBlocksFromAddr failed")
 	}
 }
 
-// SendingTxnsMessage send transaction message interface
-type SendingTxnsMessage interface {
-	GetFiltered() []cipher.SHA256
-}
-
-// AnnounceTxnsMessage tells a peer that we have these transactions
-type AnnounceTxnsMessage struct {
-	Transactions []cipher.SHA256      `enc:",maxlen=256"`
-	c            *gnet.MessageContext `enc:"-"`
+// SignedBlockHeader is a block header with the block signature, without the block body
+type SignedBlockHeader struct {
+	Head coin.BlockHeader
+	Sig  cipher.Sig
 }
 
-// NewAnnounceTxnsMessage creates announce txns message.
-// If the size of the message would exceed maxMsgLength, the hashes slice is truncated.
-func NewAnnounceTxnsMessage(txns []cipher.SHA256, maxMsgLength uint64) *AnnounceTxnsMessage {
-	if len(txns) > 256 {
-		txns = txns[:256]
-	}
-	m := &AnnounceTxnsMessage{
-		Transactions: txns,
+// NewSignedBlockHeader creates a SignedBlockHeader from a coin.SignedBlock
+func NewSignedBlockHeader(b coin.SignedBlock) SignedBlockHeader {
+	return SignedBlockHeader{
+		Head: b.Block.Head,
+		Sig:  b.Sig,
 	}
-	hashes := truncateAnnounceTxnsHashes(m, maxMsgLength)
-	m.Transactions = hashes
-	return m
 }
 
-// truncateAnnounceTxnsHashes truncates the hashes in AnnounceTxnsMessage to fit inside of MaxOutgoingMessageLength
-func truncateAnnounceTxnsHashes(m *AnnounceTxnsMessage, maxMsgLength uint64) []cipher.SHA256 {
-	// The message length will include a 4 byte message type prefix.
-	// Panic if the prefix can't fit, otherwise we can't adjust the uint64 safely
-	if maxMsgLength < 4 {
-		logger.Panic("maxMsgLength must be >= 4")
-	}
-
-	maxMsgLength -= 4
-
-	// Measure the current message size, if it fits, return
-	n := m.EncodeSize()
-	if n <= maxMsgLength {
-		return m.Transactions
+// Verify checks that the header was signed by one of pubkeys
+func (h *SignedBlockHeader) Verify(pubkeys ...cipher.PubKey) error {
+	if len(pubkeys) == 1 {
+		return cipher.VerifyPubKeySignedHash(pubkeys[0], h.Sig, h.Head.Hash())
 	}
 
-	// Measure the size of an empty message
-	var mm AnnounceTxnsMessage
-	size := mm.EncodeSize()
-
-	if maxMsgLength < size {
-		logger.Panic("maxMsgLength must be <= 4 + sizeof(empty AnnounceTxnsMessage)")
+	signer, err := cipher.PubKeyFromSig(h.Sig, h.Head.Hash())
+	if err != nil {
+		return err
 	}
 
-	maxMsgLength -= size
-
-	hashes := truncateSHA256Slice(m.Transactions, maxMsgLength)
-
-	if len(hashes) == 0 {
-		logger.Critical().Error("truncateAnnounceTxnsHashes truncated hashes to an empty slice")
+	for _, pk := range pubkeys {
+		if pk == signer {
+			return cipher.VerifyPubKeySignedHash(signer, h.Sig, h.Head.Hash())
+		}
 	}
 
-	return hashes
+	return errBlockHeaderSigner
 }
 
-func truncateSHA256Slice(hashes []cipher.SHA256, maxLength uint64) []cipher.SHA256 {
-	if len(hashes) == 0 {
-		return hashes
-	}
-
-	size := len(hashes[0])
-
-	n := maxLength / uint64(size)
+// GetHeadersMessage sent to request signed block headers since LastBlock.
+// Only sent to peers with a protocol version of at least headersSyncProtocolVersion.
+type GetHeadersMessage struct {
+	LastBlock        uint64
+	RequestedHeaders uint64
+	c                *gnet.MessageContext `enc:"-"`
+}
 
-	if n > uint64(len(hashes)) {
-		return hashes
+// NewGetHeadersMessage creates GetHeadersMessage
+func NewGetHeadersMessage(lastBlock, requestedHeaders uint64) *GetHeadersMessage {
+	return &GetHeadersMessage{
+		LastBlock:        lastBlock,
+		RequestedHeaders: requestedHeaders,
 	}
-
-	return hashes[:n]
 }
 
 // EncodeSize implements gnet.Serializer
-func (atm *AnnounceTxnsMessage) EncodeSize() uint64 {
-	return encodeSizeAnnounceTxnsMessage(atm)
+func (ghm *GetHeadersMessage) EncodeSize() uint64 {
+	return encodeSizeGetHeadersMessage(ghm)
 }
 
 // Encode implements gnet.Serializer
-func (atm *AnnounceTxnsMessage) Encode(buf []byte) error {
-	return encodeAnnounceTxnsMessageToBuffer(buf, atm)
+func (ghm *GetHeadersMessage) Encode(buf []byte) error {
+	return encodeGetHeadersMessageToBuffer(buf, ghm)
 }
 
 // Decode implements gnet.Serializer
-func (atm *AnnounceTxnsMessage) Decode(buf []byte) (uint64, error) {
-	return decodeAnnounceTxnsMessage(buf, atm)
-}
-
-// GetFiltered returns txns
-func (atm *AnnounceTxnsMessage) GetFiltered() []cipher.SHA256 {
-	return atm.Transactions
+func (ghm *GetHeadersMessage) Decode(buf []byte) (uint64, error) {
+	return decodeGetHeadersMessage(buf, ghm)
 }
 
-// Handle handle message
-func (atm *AnnounceTxnsMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
-	atm.c = mc
-	return daemon.(daemoner).recordMessageEvent(atm, mc)
+// Handle handles message
+func (ghm *GetHeadersMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	ghm.c = mc
+	return daemon.(daemoner).recordMessageEvent(ghm, mc)
 }
 
-// process process message
-func (atm *AnnounceTxnsMessage) process(d daemoner) {
+// process replies with the signed block headers since LastBlock
+func (ghm *GetHeadersMessage) process(d daemoner) {
 	dc := d.DaemonConfig()
 	if dc.DisableNetworking {
 		return
 	}
 
 	fields := logrus.Fields{
-		"addr":   atm.c.Addr,
-		"gnetID": atm.c.ConnID,
+		"addr":   ghm.c.Addr,
+		"gnetID": ghm.c.ConnID,
 	}
 
-	unknown, err := d.filterKnownUnconfirmed(atm.Transactions)
+	// LastBlock is the requester's header tip, which can be behind its block height
+	// while it is syncing headers, so it is not recorded as the peer's height.
+	// The height is recorded from the GiveHeadersMessages and CompactBlockMessages it sends
+
+	requestedHeaders := ghm.RequestedHeaders
+	if requestedHeaders > dc.MaxGetHeadersResponseCount {
+		logger.WithFields(logrus.Fields{
+			"requestedHeaders":    requestedHeaders,
+			"maxRequestedHeaders": dc.MaxGetHeadersResponseCount,
+		}).WithFields(fields).Debug("GetHeadersMessage.RequestedHeaders value exceeds configured limit, reducing")
+		requestedHeaders = dc.MaxGetHeadersResponseCount
+	}
+
+	headers, err := d.getBlockHeadersSince(ghm.LastBlock, requestedHeaders)
 	if err != nil {
-		logger.WithError(err).Error("AnnounceTxnsMessage d.filterKnownUnconfirmed failed")
+		logger.WithFields(fields).WithError(err).Error("getBlockHeadersSince failed")
 		return
 	}
 
-	if len(unknown) == 0 {
+	if len(headers) == 0 {
 		return
 	}
 
-	m := NewGetTxnsMessage(unknown, dc.MaxOutgoingMessageLength)
-	if len(m.Transactions) != len(unknown) {
-		logger.Warningf("NewGetTxnsMessage truncated %d hashes to %d hashes", len(unknown), len(m.Transactions))
+	logger.WithFields(fields).Debugf("GetHeadersMessage: replying with %d headers after block %d", len(headers), ghm.LastBlock)
+
+	m := NewGiveHeadersMessage(headers, dc.MaxOutgoingMessageLength)
+	if len(m.Headers) != len(headers) {
+		logger.WithField("startBlockSeq", headers[0].Head.BkSeq).WithFields(fields).Warningf("NewGiveHeadersMessage truncated %d headers to %d headers", len(headers), len(m.Headers))
 	}
 
-	if err := d.sendMessage(atm.c.Addr, m); err != nil {
-		logger.WithFields(fields).WithError(err).Error("Send GetTxnsMessage failed")
+	if err := d.sendMessage(ghm.c.Addr, m); err != nil {
+		logger.WithFields(fields).WithError(err).Error("Send GiveHeadersMessage failed")
 	}
 }
 
-// GetTxnsMessage request transactions of given hash
-type GetTxnsMessage struct {
-	Transactions []cipher.SHA256      `enc:",maxlen=256"`
-	c            *gnet.MessageContext `enc:"-"`
+// GiveHeadersMessage announces signed block headers, in response to GetHeadersMessage
+type GiveHeadersMessage struct {
+	Headers []SignedBlockHeader  `enc:",maxlen=1024"`
+	c       *gnet.MessageContext `enc:"-"`
 }
 
-// NewGetTxnsMessage creates GetTxnsMessage.
-// If the size of the message would exceed maxMsgLength, the hashes slice is truncated.
-func NewGetTxnsMessage(txns []cipher.SHA256, maxMsgLength uint64) *GetTxnsMessage {
-	if len(txns) > 256 {
-		txns = txns[:256]
+// NewGiveHeadersMessage creates GiveHeadersMessage.
+// If the size of message would exceed maxMsgLength, the header slice is truncated.
+func NewGiveHeadersMessage(headers []SignedBlockHeader, maxMsgLength uint64) *GiveHeadersMessage {
+	if len(headers) > 1024 {
+		headers = headers[:1024]
 	}
-	m := &GetTxnsMessage{
-		Transactions: txns,
+	m := &GiveHeadersMessage{
+		Headers: headers,
 	}
-	hashes := truncateGetTxnsHashes(m, maxMsgLength)
-	m.Transactions = hashes
+	truncateGiveHeadersMessage(m, maxMsgLength)
 	return m
 }
 
-// truncateGetTxnsHashes truncates the hashes in GetTxnsMessage to fit inside of MaxOutgoingMessageLength
-func truncateGetTxnsHashes(m *GetTxnsMessage, maxMsgLength uint64) []cipher.SHA256 {
+// truncateGiveHeadersMessage truncates the headers in GiveHeadersMessage to fit inside of MaxOutgoingMessageLength
+func truncateGiveHeadersMessage(m *GiveHeadersMessage, maxMsgLength uint64) {
 	// The message length will include a 4 byte message type prefix.
 	// Panic if the prefix can't fit, otherwise we can't adjust the uint64 safely
 	if maxMsgLength < 4 {
@@ -1185,31 +1431,763 @@ func truncateGetTxnsHashes(m *GetTxnsMessage, maxMsgLength uint64) []cipher.SHA2
 	// Measure the current message size, if it fits, return
 	n := m.EncodeSize()
 	if n <= maxMsgLength {
-		return m.Transactions
+		return
 	}
 
 	// Measure the size of an empty message
-	var mm GetTxnsMessage
+	var mm GiveHeadersMessage
 	size := mm.EncodeSize()
 
-	if maxMsgLength < size {
-		logger.Panic("maxMsgLength must be <= 4 + sizeof(empty GetTxnsMessage)")
-	}
-
-	maxMsgLength -= size
+	// Headers have a fixed size
+	mm.Headers = []SignedBlockHeader{{}}
+	headerSize := mm.EncodeSize() - size
 
-	hashes := truncateSHA256Slice(m.Transactions, maxMsgLength)
+	m.Headers = m.Headers[:(maxMsgLength-size)/headerSize]
 
-	if len(hashes) == 0 {
-		logger.Critical().Error("truncateGetTxnsHashes truncated hashes to an empty slice")
+	if len(m.Headers) == 0 {
+		logger.Critical().Error("truncateGiveHeadersMessage truncated headers to an empty slice")
 	}
-
-	return hashes
 }
 
 // EncodeSize implements gnet.Serializer
-func (gtm *GetTxnsMessage) EncodeSize() uint64 {
-	return encodeSizeGetTxnsMessage(gtm)
+func (m *GiveHeadersMessage) EncodeSize() uint64 {
+	return encodeSizeGiveHeadersMessage(m)
+}
+
+// Encode implements gnet.Serializer
+func (m *GiveHeadersMessage) Encode(buf []byte) error {
+	return encodeGiveHeadersMessageToBuffer(buf, m)
+}
+
+// Decode implements gnet.Serializer
+func (m *GiveHeadersMessage) Decode(buf []byte) (uint64, error) {
+	return decodeGiveHeadersMessage(buf, m)
+}
+
+// Handle handle message
+func (m *GiveHeadersMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	m.c = mc
+	return daemon.(daemoner).recordMessageEvent(m, mc)
+}
+
+// process verifies the headers and schedules the download of their blocks
+func (m *GiveHeadersMessage) process(d daemoner) {
+	if d.DaemonConfig().DisableNetworking {
+		return
+	}
+
+	if len(m.Headers) == 0 {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":   m.c.Addr,
+		"gnetID": m.c.ConnID,
+	}
+
+	d.recordPeerHeight(m.c.Addr, m.c.ConnID, m.Headers[len(m.Headers)-1].Head.BkSeq)
+
+	added, err := d.addBlockHeaders(m.Headers)
+	switch err {
+	case nil:
+	case errBlockHeadersNotContiguous:
+		logger.WithError(err).WithFields(fields).Debug("addBlockHeaders ignored headers")
+	default:
+		logger.WithError(err).WithFields(fields).Warning("addBlockHeaders failed")
+		if err := d.Disconnect(m.c.Addr, err); err != nil {
+			logger.WithError(err).WithFields(fields).Warning("Disconnect")
+		}
+		return
+	}
+
+	if added == 0 {
+		return
+	}
+
+	logger.WithFields(fields).Debugf("Added %d block headers", added)
+
+	// Request the next headers from the same peer
+	if err := d.requestBlocksFromAddr(m.c.Addr); err != nil {
+		logger.WithError(err).WithFields(fields).Warning("requestBlocksFromAddr failed")
+	}
+
+	if err := d.downloadBlocks(); err != nil {
+		logger.WithError(err).Warning("downloadBlocks failed")
+	}
+}
+
+// CompactBlockMessage announces a newly published block with its signed header and the short IDs
+// of its transactions instead of the full block. The receiver rebuilds the block from its unconfirmed
+// transaction pool and requests the missing transactions with GetBlockTxnsMessage.
+// Only sent to peers with a protocol version of at least compactBlocksProtocolVersion.
+type CompactBlockMessage struct {
+	Header   SignedBlockHeader
+	ShortIDs []uint64             `enc:",maxlen=4096"`
+	c        *gnet.MessageContext `enc:"-"`
+}
+
+// NewCompactBlockMessage creates CompactBlockMessage
+func NewCompactBlockMessage(b coin.SignedBlock) *CompactBlockMessage {
+	hash := b.HashHeader()
+	shortIDs := make([]uint64, len(b.Body.Transactions))
+	for i, txn := range b.Body.Transactions {
+		shortIDs[i] = compactTxnID(hash, txn.Hash())
+	}
+
+	return &CompactBlockMessage{
+		Header:   NewSignedBlockHeader(b),
+		ShortIDs: shortIDs,
+	}
+}
+
+// EncodeSize implements gnet.Serializer
+func (m *CompactBlockMessage) EncodeSize() uint64 {
+	return encodeSizeCompactBlockMessage(m)
+}
+
+// Encode implements gnet.Serializer
+func (m *CompactBlockMessage) Encode(buf []byte) error {
+	return encodeCompactBlockMessageToBuffer(buf, m)
+}
+
+// Decode implements gnet.Serializer
+func (m *CompactBlockMessage) Decode(buf []byte) (uint64, error) {
+	return decodeCompactBlockMessage(buf, m)
+}
+
+// Handle handles message
+func (m *CompactBlockMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	m.c = mc
+	return daemon.(daemoner).recordMessageEvent(m, mc)
+}
+
+// process rebuilds the block from the unconfirmed pool and executes it,
+// or requests the missing transactions
+func (m *CompactBlockMessage) process(d daemoner) {
+	dc := d.DaemonConfig()
+	if dc.DisableNetworking {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":   m.c.Addr,
+		"gnetID": m.c.ConnID,
+		"seq":    m.Header.Head.BkSeq,
+	}
+
+	// Verify the header before trusting its seq, an unsigned header could claim any height
+	if err := m.Header.Verify(d.blockSigners()...); err != nil {
+		logger.WithError(err).WithFields(fields).Warning("CompactBlockMessage header signature is invalid")
+		if err := d.Disconnect(m.c.Addr, ErrDisconnectInvalidBlockHeaders); err != nil {
+			logger.WithError(err).WithFields(fields).Warning("Disconnect")
+		}
+		return
+	}
+
+	d.recordPeerHeight(m.c.Addr, m.c.ConnID, m.Header.Head.BkSeq)
+
+	headBkSeq, ok, err := d.headBkSeq()
+	if err != nil {
+		logger.WithError(err).Error("d.headBkSeq failed")
+		return
+	}
+	if !ok {
+		logger.Error("No HeadBkSeq found, cannot process CompactBlockMessage")
+		return
+	}
+
+	if m.Header.Head.BkSeq <= headBkSeq {
+		return
+	}
+
+	// We are behind, download the preceding blocks first
+	if m.Header.Head.BkSeq > headBkSeq+1 {
+		if err := d.requestBlocksFromAddr(m.c.Addr); err != nil {
+			logger.WithError(err).WithFields(fields).Error("requestBlocksFromAddr failed")
+		}
+		return
+	}
+
+	b, missing, err := d.reconstructCompactBlock(m.c.Addr, m.Header, m.ShortIDs)
+	switch err {
+	case nil:
+	case errCompactBlockRequested:
+		logger.WithFields(fields).Debug("CompactBlockMessage transactions were already requested")
+		return
+	case errCompactBlockMismatch:
+		logger.WithError(err).WithFields(fields).Info("Downloading the full block")
+		if err := d.requestBlocksFromAddr(m.c.Addr); err != nil {
+			logger.WithError(err).WithFields(fields).Error("requestBlocksFromAddr failed")
+		}
+		return
+	default:
+		logger.WithError(err).WithFields(fields).Error("reconstructCompactBlock failed")
+		return
+	}
+
+	if b == nil {
+		logger.WithFields(fields).Debugf("CompactBlockMessage: requesting %d of %d transactions", len(missing), len(m.ShortIDs))
+		gm := NewGetBlockTxnsMessage(m.Header.Head.Hash(), missing)
+		if err := d.sendMessage(m.c.Addr, gm); err != nil {
+			logger.WithError(err).WithFields(fields).Error("Send GetBlockTxnsMessage failed")
+		}
+		return
+	}
+
+	executeCompactBlock(d, m.c.Addr, *b)
+}
+
+// executeCompactBlock executes a block rebuilt from a CompactBlockMessage and relays it to peers
+func executeCompactBlock(d daemoner, addr string, b coin.SignedBlock) {
+	if err := d.executeSignedBlock(b); err == visor.ErrSideBlockNoParent {
+		requestBranchBlocks(d, addr, b.Seq())
+		return
+	} else if err != nil {
+		logger.Critical().WithError(err).WithField("seq", b.Block.Head.BkSeq).Error("Failed to execute received block")
+		d.misbehaved(addr, errMisbehaviorInvalidBlock)
+		return
+	}
+
+	logger.Critical().WithField("seq", b.Block.Head.BkSeq).Info("Added new block")
+
+	if err := d.relayCompactBlock(addr, b); err != nil {
+		logger.WithError(err).Warning("relayCompactBlock failed")
+	}
+}
+
+// GetBlockTxnsMessage requests the transactions of a block that were missing from the
+// unconfirmed pool when rebuilding a CompactBlockMessage
+type GetBlockTxnsMessage struct {
+	BlockHash cipher.SHA256
+	Indexes   []uint64             `enc:",maxlen=4096"`
+	c         *gnet.MessageContext `enc:"-"`
+}
+
+// NewGetBlockTxnsMessage creates GetBlockTxnsMessage
+func NewGetBlockTxnsMessage(blockHash cipher.SHA256, indexes []uint64) *GetBlockTxnsMessage {
+	return &GetBlockTxnsMessage{
+		BlockHash: blockHash,
+		Indexes:   indexes,
+	}
+}
+
+// EncodeSize implements gnet.Serializer
+func (m *GetBlockTxnsMessage) EncodeSize() uint64 {
+	return encodeSizeGetBlockTxnsMessage(m)
+}
+
+// Encode implements gnet.Serializer
+func (m *GetBlockTxnsMessage) Encode(buf []byte) error {
+	return encodeGetBlockTxnsMessageToBuffer(buf, m)
+}
+
+// Decode implements gnet.Serializer
+func (m *GetBlockTxnsMessage) Decode(buf []byte) (uint64, error) {
+	return decodeGetBlockTxnsMessage(buf, m)
+}
+
+// Handle handles message
+func (m *GetBlockTxnsMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	m.c = mc
+	return daemon.(daemoner).recordMessageEvent(m, mc)
+}
+
+// process replies with the requested transactions of the block
+func (m *GetBlockTxnsMessage) process(d daemoner) {
+	dc := d.DaemonConfig()
+	if dc.DisableNetworking {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":      m.c.Addr,
+		"gnetID":    m.c.ConnID,
+		"blockHash": m.BlockHash.Hex(),
+	}
+
+	txns, err := d.getBlockTxns(m.BlockHash, m.Indexes)
+	if err != nil {
+		logger.WithError(err).WithFields(fields).Warning("getBlockTxns failed")
+		return
+	}
+
+	gm := NewGiveBlockTxnsMessage(m.BlockHash, txns)
+	if err := d.sendMessage(m.c.Addr, gm); err != nil {
+		logger.WithError(err).WithFields(fields).Error("Send GiveBlockTxnsMessage failed")
+	}
+}
+
+// GiveBlockTxnsMessage sends the transactions of a block in response to GetBlockTxnsMessage
+type GiveBlockTxnsMessage struct {
+	BlockHash    cipher.SHA256
+	Transactions []coin.Transaction   `enc:",maxlen=4096"`
+	c            *gnet.MessageContext `enc:"-"`
+}
+
+// NewGiveBlockTxnsMessage creates GiveBlockTxnsMessage
+func NewGiveBlockTxnsMessage(blockHash cipher.SHA256, txns []coin.Transaction) *GiveBlockTxnsMessage {
+	return &GiveBlockTxnsMessage{
+		BlockHash:    blockHash,
+		Transactions: txns,
+	}
+}
+
+// EncodeSize implements gnet.Serializer
+func (m *GiveBlockTxnsMessage) EncodeSize() uint64 {
+	return encodeSizeGiveBlockTxnsMessage(m)
+}
+
+// Encode implements gnet.Serializer
+func (m *GiveBlockTxnsMessage) Encode(buf []byte) error {
+	return encodeGiveBlockTxnsMessageToBuffer(buf, m)
+}
+
+// Decode implements gnet.Serializer
+func (m *GiveBlockTxnsMessage) Decode(buf []byte) (uint64, error) {
+	return decodeGiveBlockTxnsMessage(buf, m)
+}
+
+// Handle handles message
+func (m *GiveBlockTxnsMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	m.c = mc
+	return daemon.(daemoner).recordMessageEvent(m, mc)
+}
+
+// process completes a pending compact block and executes it
+func (m *GiveBlockTxnsMessage) process(d daemoner) {
+	if d.DaemonConfig().DisableNetworking {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":      m.c.Addr,
+		"gnetID":    m.c.ConnID,
+		"blockHash": m.BlockHash.Hex(),
+	}
+
+	b, err := d.completeCompactBlock(m.c.Addr, m.BlockHash, m.Transactions)
+	if err != nil {
+		logger.WithError(err).WithFields(fields).Info("completeCompactBlock failed, downloading the full block")
+		if err := d.requestBlocksFromAddr(m.c.Addr); err != nil {
+			logger.WithError(err).WithFields(fields).Error("requestBlocksFromAddr failed")
+		}
+		return
+	}
+
+	if b == nil {
+		logger.WithFields(fields).Debug("GiveBlockTxnsMessage does not match a pending compact block")
+		return
+	}
+
+	executeCompactBlock(d, m.c.Addr, *b)
+}
+
+// BlockCandidateMessage sends a block proposed for the next seq with the signature of a block publisher.
+// Block publishers sign the first valid candidate they receive for a seq, and nodes execute the block
+// once its hash is signed by a quorum of block publishers.
+// Only sent to peers with a protocol version of at least publisherConsensusProtocolVersion.
+type BlockCandidateMessage struct {
+	Block     coin.Block
+	Candidate consensus.BlockBase
+	c         *gnet.MessageContext `enc:"-"`
+}
+
+// NewBlockCandidateMessage creates BlockCandidateMessage
+func NewBlockCandidateMessage(candidate consensus.BlockBase, b coin.Block) *BlockCandidateMessage {
+	return &BlockCandidateMessage{
+		Block:     b,
+		Candidate: candidate,
+	}
+}
+
+// EncodeSize implements gnet.Serializer
+func (m *BlockCandidateMessage) EncodeSize() uint64 {
+	return encodeSizeBlockCandidateMessage(m)
+}
+
+// Encode implements gnet.Serializer
+func (m *BlockCandidateMessage) Encode(buf []byte) error {
+	return encodeBlockCandidateMessageToBuffer(buf, m)
+}
+
+// Decode implements gnet.Serializer
+func (m *BlockCandidateMessage) Decode(buf []byte) (uint64, error) {
+	return decodeBlockCandidateMessage(buf, m)
+}
+
+// Handle handles message
+func (m *BlockCandidateMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	m.c = mc
+	return daemon.(daemoner).recordMessageEvent(m, mc)
+}
+
+// process adds the block candidate to the candidates of the block publishers
+func (m *BlockCandidateMessage) process(d daemoner) {
+	dc := d.DaemonConfig()
+	if dc.DisableNetworking || len(dc.PublisherPubkeys) == 0 {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":   m.c.Addr,
+		"gnetID": m.c.ConnID,
+		"seq":    m.Block.Head.BkSeq,
+		"hash":   m.Candidate.Hash.Hex(),
+	}
+
+	headBkSeq, ok, err := d.headBkSeq()
+	if err != nil {
+		logger.WithError(err).Error("d.headBkSeq failed")
+		return
+	}
+	if !ok {
+		logger.Error("No HeadBkSeq found, cannot process BlockCandidateMessage")
+		return
+	}
+
+	if m.Block.Head.BkSeq <= headBkSeq {
+		return
+	}
+
+	// We are behind, download the preceding blocks first
+	if m.Block.Head.BkSeq > headBkSeq+1 {
+		if err := d.requestBlocksFromAddr(m.c.Addr); err != nil {
+			logger.WithError(err).WithFields(fields).Error("requestBlocksFromAddr failed")
+		}
+		return
+	}
+
+	if err := d.addBlockCandidate(m.Candidate, m.Block); err != nil {
+		logger.WithError(err).WithFields(fields).Warning("addBlockCandidate failed")
+	}
+}
+
+// GiveKeyCheckpointsMessage sends the block publisher key checkpoints, ordered by seq.
+// It is sent after the introduction and relayed when a peer learns of new key checkpoints.
+// Only sent to peers with a protocol version of at least keyCheckpointsProtocolVersion.
+type GiveKeyCheckpointsMessage struct {
+	KeyCheckpoints []blockdb.KeyCheckpoint `enc:",maxlen=256"`
+	c              *gnet.MessageContext    `enc:"-"`
+}
+
+// NewGiveKeyCheckpointsMessage creates GiveKeyCheckpointsMessage
+func NewGiveKeyCheckpointsMessage(checkpoints []blockdb.KeyCheckpoint) *GiveKeyCheckpointsMessage {
+	if len(checkpoints) > 256 {
+		checkpoints = checkpoints[len(checkpoints)-256:]
+	}
+	return &GiveKeyCheckpointsMessage{
+		KeyCheckpoints: checkpoints,
+	}
+}
+
+// EncodeSize implements gnet.Serializer
+func (m *GiveKeyCheckpointsMessage) EncodeSize() uint64 {
+	return encodeSizeGiveKeyCheckpointsMessage(m)
+}
+
+// Encode implements gnet.Serializer
+func (m *GiveKeyCheckpointsMessage) Encode(buf []byte) error {
+	return encodeGiveKeyCheckpointsMessageToBuffer(buf, m)
+}
+
+// Decode implements gnet.Serializer
+func (m *GiveKeyCheckpointsMessage) Decode(buf []byte) (uint64, error) {
+	return decodeGiveKeyCheckpointsMessage(buf, m)
+}
+
+// Handle handles message
+func (m *GiveKeyCheckpointsMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	m.c = mc
+	return daemon.(daemoner).recordMessageEvent(m, mc)
+}
+
+// process adds the key checkpoints and relays the new ones to the other peers
+func (m *GiveKeyCheckpointsMessage) process(d daemoner) {
+	dc := d.DaemonConfig()
+	if dc.DisableNetworking {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":   m.c.Addr,
+		"gnetID": m.c.ConnID,
+	}
+
+	var added []blockdb.KeyCheckpoint
+	for _, kc := range m.KeyCheckpoints {
+		ok, err := d.addKeyCheckpoint(kc)
+		if err != nil {
+			logger.WithError(err).WithFields(fields).WithField("seq", kc.Seq).Warning("addKeyCheckpoint failed")
+			continue
+		}
+
+		if ok {
+			logger.WithFields(fields).WithFields(logrus.Fields{
+				"seq":    kc.Seq,
+				"pubkey": kc.Pubkey.Hex(),
+			}).Info("Added key checkpoint")
+			added = append(added, kc)
+		}
+	}
+
+	if len(added) == 0 {
+		return
+	}
+
+	if err := d.relayKeyCheckpoints(m.c.Addr, added); err != nil {
+		logger.WithError(err).WithFields(fields).Warning("relayKeyCheckpoints failed")
+	}
+}
+
+// GiveBlockCertificatesMessage sends the signatures of the block publishers that settled blocks.
+// A block signed by a block publisher is only valid if it is signed by a quorum of block publishers,
+// so the certificates are sent before the blocks.
+// Only sent to peers with a protocol version of at least blockCertificatesProtocolVersion.
+type GiveBlockCertificatesMessage struct {
+	Certificates []blockdb.BlockCertificate `enc:",maxlen=128"`
+	c            *gnet.MessageContext       `enc:"-"`
+}
+
+// NewGiveBlockCertificatesMessage creates GiveBlockCertificatesMessage
+func NewGiveBlockCertificatesMessage(certs []blockdb.BlockCertificate) *GiveBlockCertificatesMessage {
+	if len(certs) > 128 {
+		certs = certs[:128]
+	}
+	return &GiveBlockCertificatesMessage{
+		Certificates: certs,
+	}
+}
+
+// EncodeSize implements gnet.Serializer
+func (m *GiveBlockCertificatesMessage) EncodeSize() uint64 {
+	return encodeSizeGiveBlockCertificatesMessage(m)
+}
+
+// Encode implements gnet.Serializer
+func (m *GiveBlockCertificatesMessage) Encode(buf []byte) error {
+	return encodeGiveBlockCertificatesMessageToBuffer(buf, m)
+}
+
+// Decode implements gnet.Serializer
+func (m *GiveBlockCertificatesMessage) Decode(buf []byte) (uint64, error) {
+	return decodeGiveBlockCertificatesMessage(buf, m)
+}
+
+// Handle handles message
+func (m *GiveBlockCertificatesMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	m.c = mc
+	return daemon.(daemoner).recordMessageEvent(m, mc)
+}
+
+// process stores the block publisher signatures until their blocks are executed
+func (m *GiveBlockCertificatesMessage) process(d daemoner) {
+	dc := d.DaemonConfig()
+	if dc.DisableNetworking {
+		return
+	}
+
+	n := d.addBlockCertificates(m.Certificates)
+
+	logger.WithFields(logrus.Fields{
+		"addr":         m.c.Addr,
+		"gnetID":       m.c.ConnID,
+		"certificates": len(m.Certificates),
+		"sigs":         n,
+	}).Debug("Received block certificates")
+}
+
+// SendingTxnsMessage send transaction message interface
+type SendingTxnsMessage interface {
+	GetFiltered() []cipher.SHA256
+}
+
+// AnnounceTxnsMessage tells a peer that we have these transactions
+type AnnounceTxnsMessage struct {
+	Transactions []cipher.SHA256      `enc:",maxlen=256"`
+	c            *gnet.MessageContext `enc:"-"`
+}
+
+// NewAnnounceTxnsMessage creates announce txns message.
+// If the size of the message would exceed maxMsgLength, the hashes slice is truncated.
+func NewAnnounceTxnsMessage(txns []cipher.SHA256, maxMsgLength uint64) *AnnounceTxnsMessage {
+	if len(txns) > 256 {
+		txns = txns[:256]
+	}
+	m := &AnnounceTxnsMessage{
+		Transactions: txns,
+	}
+	hashes := truncateAnnounceTxnsHashes(m, maxMsgLength)
+	m.Transactions = hashes
+	return m
+}
+
+// truncateAnnounceTxnsHashes truncates the hashes in AnnounceTxnsMessage to fit inside of MaxOutgoingMessageLength
+func truncateAnnounceTxnsHashes(m *AnnounceTxnsMessage, maxMsgLength uint64) []cipher.SHA256 {
+	// The message length will include a 4 byte message type prefix.
+	// Panic if the prefix can't fit, otherwise we can't adjust the uint64 safely
+	if maxMsgLength < 4 {
+		logger.Panic("maxMsgLength must be >= 4")
+	}
+
+	maxMsgLength -= 4
+
+	// Measure the current message size, if it fits, return
+	n := m.EncodeSize()
+	if n <= maxMsgLength {
+		return m.Transactions
+	}
+
+	// Measure the size of an empty message
+	var mm AnnounceTxnsMessage
+	size := mm.EncodeSize()
+
+	if maxMsgLength < size {
+		logger.Panic("maxMsgLength must be <= 4 + sizeof(empty AnnounceTxnsMessage)")
+	}
+
+	maxMsgLength -= size
+
+	hashes := truncateSHA256Slice(m.Transactions, maxMsgLength)
+
+	if len(hashes) == 0 {
+		logger.Critical().Error("truncateAnnounceTxnsHashes truncated hashes to an empty slice")
+	}
+
+	return hashes
+}
+
+func truncateSHA256Slice(hashes []cipher.SHA256, maxLength uint64) []cipher.SHA256 {
+	if len(hashes) == 0 {
+		return hashes
+	}
+
+	size := len(hashes[0])
+
+	n := maxLength / uint64(size)
+
+	if n > uint64(len(hashes)) {
+		return hashes
+	}
+
+	return hashes[:n]
+}
+
+// EncodeSize implements gnet.Serializer
+func (atm *AnnounceTxnsMessage) EncodeSize() uint64 {
+	return encodeSizeAnnounceTxnsMessage(atm)
+}
+
+// Encode implements gnet.Serializer
+func (atm *AnnounceTxnsMessage) Encode(buf []byte) error {
+	return encodeAnnounceTxnsMessageToBuffer(buf, atm)
+}
+
+// Decode implements gnet.Serializer
+func (atm *AnnounceTxnsMessage) Decode(buf []byte) (uint64, error) {
+	return decodeAnnounceTxnsMessage(buf, atm)
+}
+
+// GetFiltered returns txns
+func (atm *AnnounceTxnsMessage) GetFiltered() []cipher.SHA256 {
+	return atm.Transactions
+}
+
+// Handle handle message
+func (atm *AnnounceTxnsMessage) Handle(mc *gnet.MessageContext, daemon interface{}) error {
+	atm.c = mc
+	return daemon.(daemoner).recordMessageEvent(atm, mc)
+}
+
+// process process message
+func (atm *AnnounceTxnsMessage) process(d daemoner) {
+	dc := d.DaemonConfig()
+	if dc.DisableNetworking {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":   atm.c.Addr,
+		"gnetID": atm.c.ConnID,
+	}
+
+	unknown, err := d.filterKnownUnconfirmed(atm.Transactions)
+	if err != nil {
+		logger.WithError(err).Error("AnnounceTxnsMessage d.filterKnownUnconfirmed failed")
+		return
+	}
+
+	if len(unknown) == 0 {
+		return
+	}
+
+	m := NewGetTxnsMessage(unknown, dc.MaxOutgoingMessageLength)
+	if len(m.Transactions) != len(unknown) {
+		logger.Warningf("NewGetTxnsMessage truncated %d hashes to %d hashes", len(unknown), len(m.Transactions))
+	}
+
+	if err := d.sendMessage(atm.c.Addr, m); err != nil {
+		logger.WithFields(fields).WithError(err).Error("Send GetTxnsMessage failed")
+	}
+}
+
+// GetTxnsMessage request transactions of given hash
+type GetTxnsMessage struct {
+	Transactions []cipher.SHA256      `enc:",maxlen=256"`
+	c            *gnet.MessageContext `enc:"-"`
+}
+
+// NewGetTxnsMessage creates GetTxnsMessage.
+// If the size of the message would exceed maxMsgLength, the hashes slice is truncated.
+func NewGetTxnsMessage(txns []cipher.SHA256, maxMsgLength uint64) *GetTxnsMessage {
+	if len(txns) > 256 {
+		txns = txns[:256]
+	}
+	m := &GetTxnsMessage{
+		Transactions: txns,
+	}
+	hashes := truncateGetTxnsHashes(m, maxMsgLength)
+	m.Transactions = hashes
+	return m
+}
+
+// truncateGetTxnsHashes truncates the hashes in GetTxnsMessage to fit inside of MaxOutgoingMessageLength
+func truncateGetTxnsHashes(m *GetTxnsMessage, maxMsgLength uint64) []cipher.SHA256 {
+	// The message length will include a 4 byte message type prefix.
+	// Panic if the prefix can't fit, otherwise we can't adjust the uint64 safely
+	if maxMsgLength < 4 {
+		logger.Panic("maxMsgLength must be >= 4")
+	}
+
+	maxMsgLength -= 4
+
+	// Measure the current message size, if it fits, return
+	n := m.EncodeSize()
+	if n <= maxMsgLength {
+		return m.Transactions
+	}
+
+	// Measure the size of an empty message
+	var mm GetTxnsMessage
+	size := mm.EncodeSize()
+
+	if maxMsgLength < size {
+		logger.Panic("maxMsgLength must be <= 4 + sizeof(empty GetTxnsMessage)")
+	}
+
+	maxMsgLength -= size
+
+	hashes := truncateSHA256Slice(m.Transactions, maxMsgLength)
+
+	if len(hashes) == 0 {
+		logger.Critical().Error("truncateGetTxnsHashes truncated hashes to an empty slice")
+	}
+
+	return hashes
+}
+
+// EncodeSize implements gnet.Serializer
+func (gtm *GetTxnsMessage) EncodeSize() uint64 {
+	return encodeSizeGetTxnsMessage(gtm)
 }
 
 // Encode implements gnet.Serializer
@@ -1360,6 +2338,9 @@ func (gtm *GiveTxnsMessage) process(d daemoner) {
 		known, softErr, err := d.injectTransaction(txn)
 		if err != nil {
 			logger.WithError(err).WithField("txid", txn.Hash().Hex()).Warning("Failed to record transaction")
+			if _, ok := err.(transaction.ErrTxnViolatesHardConstraint); ok {
+				d.misbehaved(gtm.c.Addr, errMisbehaviorInvalidTransaction)
+			}
 			continue
 		} else if softErr != nil {
 			logger.WithError(softErr).WithField("txid", txn.Hash().Hex()).Warning("Transaction soft violation")
@@ -1,19 +1,24 @@
 package daemon
 
 import (
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ness-network/ness/src/consensus"
+	"github.com/ness-network/ness/src/daemon/gnet"
+	"github.com/ness-network/ness/src/daemon/pex"
+	"github.com/ness-network/ness/src/visor"
+	"github.com/ness-network/ness/src/visor/blockdb"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/cipher/encoder"
 	"github.com/skycoin/skycoin/src/coin"
-	"github.com/skycoin/skycoin/src/daemon/gnet"
-	"github.com/skycoin/skycoin/src/daemon/pex"
 	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/testutil"
 	"github.com/skycoin/skycoin/src/util/useragent"
@@ -25,7 +30,9 @@ func TestIntroductionMessage(t *testing.T) {
 
 	pubkey, _ := cipher.GenerateKeyPair()
 	pubkey2, _ := cipher.GenerateKeyPair()
+	nodePubkey, _ := cipher.GenerateKeyPair()
 	genesisHash := testutil.RandSHA256(t)
+	onionAddress := "vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd.onion:6000"
 
 	invalidGenesisHashExtra := newIntroductionMessageExtra(pubkey, "skycoin:0.26.0", params.VerifyTxn{
 		BurnFactor:          4,
@@ -40,6 +47,7 @@ func TestIntroductionMessage(t *testing.T) {
 		mirror                   uint32
 		recordMessageEventErr    error
 		pubkey                   cipher.PubKey
+		blockchainPubkeys        []cipher.PubKey
 		disconnectReason         gnet.DisconnectReason
 		disconnectErr            error
 		connectionIntroduced     *connection
@@ -47,6 +55,7 @@ func TestIntroductionMessage(t *testing.T) {
 		requestBlocksFromAddrErr error
 		announceAllTxnsErr       error
 		sendRandomPeersErr       error
+		secureConnectionErr      error
 	}
 
 	tt := []struct {
@@ -56,6 +65,8 @@ func TestIntroductionMessage(t *testing.T) {
 		mockValue            daemonMockValue
 		userAgent            useragent.Data
 		unconfirmedVerifyTxn params.VerifyTxn
+		nodePubkey           cipher.PubKey
+		onionAddress         string
 		intro                *IntroductionMessage
 	}{
 		{
@@ -190,6 +201,161 @@ func TestIntroductionMessage(t *testing.T) {
 				}, genesisHash), []byte("additional data")...),
 			},
 		},
+		{
+			name: "INTR message with node pubkey",
+			addr: "121.121.121.121:6000",
+			mockValue: daemonMockValue{
+				mirror:          10000,
+				protocolVersion: 1,
+				pubkey:          pubkey,
+				connectionIntroduced: &connection{
+					Addr: "121.121.121.121:6000",
+					ConnectionDetails: ConnectionDetails{
+						ListenPort: 6000,
+						UserAgent: useragent.Data{
+							Coin:    "skycoin",
+							Version: "0.26.0",
+						},
+						UnconfirmedVerifyTxn: params.VerifyTxn{
+							BurnFactor:          4,
+							MaxTransactionSize:  32768,
+							MaxDropletPrecision: 3,
+						},
+					},
+				},
+			},
+			userAgent: useragent.Data{
+				Coin:    "skycoin",
+				Version: "0.26.0",
+			},
+			unconfirmedVerifyTxn: params.VerifyTxn{
+				BurnFactor:          4,
+				MaxTransactionSize:  32768,
+				MaxDropletPrecision: 3,
+			},
+			nodePubkey: nodePubkey,
+			intro: NewIntroductionMessage(10001, 1, 6000, pubkey, "skycoin:0.26.0", params.VerifyTxn{
+				BurnFactor:          4,
+				MaxTransactionSize:  32768,
+				MaxDropletPrecision: 3,
+			}, genesisHash, nodePubkey, ""),
+		},
+		{
+			name: "INTR message with onion address",
+			addr: "127.0.0.1:50000",
+			mockValue: daemonMockValue{
+				mirror:          10000,
+				protocolVersion: 1,
+				pubkey:          pubkey,
+				connectionIntroduced: &connection{
+					Addr: "127.0.0.1:50000",
+					ConnectionDetails: ConnectionDetails{
+						ListenPort:   6000,
+						OnionAddress: onionAddress,
+						UserAgent: useragent.Data{
+							Coin:    "skycoin",
+							Version: "0.26.0",
+						},
+						UnconfirmedVerifyTxn: params.VerifyTxn{
+							BurnFactor:          4,
+							MaxTransactionSize:  32768,
+							MaxDropletPrecision: 3,
+						},
+					},
+				},
+			},
+			userAgent: useragent.Data{
+				Coin:    "skycoin",
+				Version: "0.26.0",
+			},
+			unconfirmedVerifyTxn: params.VerifyTxn{
+				BurnFactor:          4,
+				MaxTransactionSize:  32768,
+				MaxDropletPrecision: 3,
+			},
+			nodePubkey:   nodePubkey,
+			onionAddress: onionAddress,
+			intro: NewIntroductionMessage(10001, 1, 6000, pubkey, "skycoin:0.26.0", params.VerifyTxn{
+				BurnFactor:          4,
+				MaxTransactionSize:  32768,
+				MaxDropletPrecision: 3,
+			}, genesisHash, nodePubkey, strings.ToUpper(onionAddress)),
+		},
+		{
+			name: "INTR message with invalid onion address",
+			addr: "127.0.0.1:50000",
+			mockValue: daemonMockValue{
+				mirror:           10000,
+				protocolVersion:  1,
+				pubkey:           pubkey,
+				disconnectReason: ErrDisconnectInvalidExtraData,
+			},
+			intro: NewIntroductionMessage(10001, 1, 6000, pubkey, "skycoin:0.26.0", params.VerifyTxn{
+				BurnFactor:          4,
+				MaxTransactionSize:  32768,
+				MaxDropletPrecision: 3,
+			}, genesisHash, nodePubkey, "example.com:6000"),
+		},
+		{
+			name: "INTR message with invalid node pubkey",
+			addr: "121.121.121.121:6000",
+			mockValue: daemonMockValue{
+				mirror:           10000,
+				protocolVersion:  1,
+				pubkey:           pubkey,
+				disconnectReason: ErrDisconnectInvalidExtraData,
+			},
+			intro: &IntroductionMessage{
+				Mirror:          10001,
+				ListenPort:      6000,
+				ProtocolVersion: 1,
+				Extra: append(newIntroductionMessageExtra(pubkey, "skycoin:0.26.0", params.VerifyTxn{
+					BurnFactor:          4,
+					MaxTransactionSize:  32768,
+					MaxDropletPrecision: 3,
+				}, genesisHash), make([]byte, len(cipher.PubKey{}))...),
+			},
+		},
+		{
+			name: "INTR message without node pubkey, encryption required",
+			addr: "121.121.121.121:6000",
+			mockValue: daemonMockValue{
+				mirror:          10000,
+				protocolVersion: 1,
+				pubkey:          pubkey,
+				connectionIntroduced: &connection{
+					Addr: "121.121.121.121:6000",
+					ConnectionDetails: ConnectionDetails{
+						ListenPort: 6000,
+						UserAgent: useragent.Data{
+							Coin:    "skycoin",
+							Version: "0.26.0",
+						},
+						UnconfirmedVerifyTxn: params.VerifyTxn{
+							BurnFactor:          4,
+							MaxTransactionSize:  32768,
+							MaxDropletPrecision: 3,
+						},
+					},
+				},
+				secureConnectionErr: ErrDisconnectEncryptionRequired,
+				disconnectReason:    ErrDisconnectEncryptionRequired,
+			},
+			userAgent: useragent.Data{
+				Coin:    "skycoin",
+				Version: "0.26.0",
+			},
+			unconfirmedVerifyTxn: params.VerifyTxn{
+				BurnFactor:          4,
+				MaxTransactionSize:  32768,
+				MaxDropletPrecision: 3,
+			},
+			intro: NewIntroductionMessage(10001, 1, 6000, pubkey, "skycoin:0.26.0", params.VerifyTxn{
+				BurnFactor:          4,
+				MaxTransactionSize:  32768,
+				MaxDropletPrecision: 3,
+			}, genesisHash, cipher.PubKey{}, ""),
+		},
 		{
 			name: "INTR message with extra fields but invalid genesis hash data",
 			addr: "121.121.121.121:6000",
@@ -235,6 +401,50 @@ func TestIntroductionMessage(t *testing.T) {
 				}, genesisHash),
 			},
 		},
+		{
+			name: "INTR message with the pubkey of a key checkpoint",
+			addr: "121.121.121.121:6000",
+			mockValue: daemonMockValue{
+				mirror:            10000,
+				protocolVersion:   1,
+				pubkey:            pubkey,
+				blockchainPubkeys: []cipher.PubKey{pubkey, pubkey2},
+				connectionIntroduced: &connection{
+					Addr: "121.121.121.121:6000",
+					ConnectionDetails: ConnectionDetails{
+						ListenPort: 6000,
+						UserAgent: useragent.Data{
+							Coin:    "skycoin",
+							Version: "0.26.0",
+						},
+						UnconfirmedVerifyTxn: params.VerifyTxn{
+							BurnFactor:          4,
+							MaxTransactionSize:  32768,
+							MaxDropletPrecision: 3,
+						},
+					},
+				},
+			},
+			userAgent: useragent.Data{
+				Coin:    "skycoin",
+				Version: "0.26.0",
+			},
+			unconfirmedVerifyTxn: params.VerifyTxn{
+				BurnFactor:          4,
+				MaxTransactionSize:  32768,
+				MaxDropletPrecision: 3,
+			},
+			intro: &IntroductionMessage{
+				Mirror:          10001,
+				ListenPort:      6000,
+				ProtocolVersion: 1,
+				Extra: newIntroductionMessageExtra(pubkey2, "skycoin:0.26.0", params.VerifyTxn{
+					BurnFactor:          4,
+					MaxTransactionSize:  32768,
+					MaxDropletPrecision: 3,
+				}, genesisHash),
+			},
+		},
 		{
 			name: "INTR message with invalid pubkey",
 			addr: "121.121.121.121:6000",
@@ -521,6 +731,9 @@ func TestIntroductionMessage(t *testing.T) {
 			d.On("requestBlocksFromAddr", tc.addr).Return(tc.mockValue.requestBlocksFromAddrErr)
 			d.On("announceAllValidTxns").Return(tc.mockValue.announceAllTxnsErr)
 			d.On("sendRandomPeers", tc.addr).Return(tc.mockValue.sendRandomPeersErr)
+			d.On("secureConnection", tc.addr, tc.intro).Return(tc.mockValue.secureConnectionErr)
+			d.On("blockchainPubkeys").Return(tc.mockValue.blockchainPubkeys)
+			d.On("sendKeyCheckpoints", tc.addr).Return(nil)
 
 			err := tc.intro.Handle(mc, d)
 			require.NoError(t, err)
@@ -532,6 +745,8 @@ func TestIntroductionMessage(t *testing.T) {
 			} else {
 				d.AssertNotCalled(t, "Disconnect", mock.Anything, mock.Anything)
 				require.Equal(t, genesisHash, tc.intro.GenesisHash)
+				require.Equal(t, tc.nodePubkey, tc.intro.NodePubkey)
+				require.Equal(t, tc.onionAddress, tc.intro.OnionAddress)
 			}
 		})
 	}
@@ -754,6 +969,175 @@ func TestMessageEncodeDecode(t *testing.T) {
 				},
 			},
 		},
+		{
+			goldenFile: "get-headers-msg.golden",
+			obj:        &GetHeadersMessage{},
+			msg: &GetHeadersMessage{
+				LastBlock:        999988887777,
+				RequestedHeaders: 1024,
+			},
+		},
+		{
+			goldenFile: "give-headers-msg.golden",
+			obj:        &GiveHeadersMessage{},
+			msg: &GiveHeadersMessage{
+				Headers: []SignedBlockHeader{
+					{
+						Sig: cipher.MustSigFromHex("8cf145e9ef4a4a5254bc57798a7a61dfed238768f94edc5635175c6b91bccd8ec1555da603c5e31b018e135b82b1525be8a92973c468a74b5b40b8da189cb465eb"),
+						Head: coin.BlockHeader{
+							Version:  1,
+							Time:     1538036613,
+							BkSeq:    9999999999,
+							Fee:      1234123412341234,
+							PrevHash: cipher.MustSHA256FromHex("59cb7d0e2ce8a03d1054afcc28a22fe864a8813460d241db38c59d10e7c29132"),
+							BodyHash: cipher.MustSHA256FromHex("6eafd13ab6823223b714246b32c984b56e0043412950faf17defdbb2cbf3fe30"),
+						},
+					},
+				},
+			},
+		},
+		{
+			goldenFile: "compact-block-msg.golden",
+			obj:        &CompactBlockMessage{},
+			msg: &CompactBlockMessage{
+				Header: SignedBlockHeader{
+					Sig: cipher.MustSigFromHex("8cf145e9ef4a4a5254bc57798a7a61dfed238768f94edc5635175c6b91bccd8ec1555da603c5e31b018e135b82b1525be8a92973c468a74b5b40b8da189cb465eb"),
+					Head: coin.BlockHeader{
+						Version:  1,
+						Time:     1538036613,
+						BkSeq:    9999999999,
+						Fee:      1234123412341234,
+						PrevHash: cipher.MustSHA256FromHex("59cb7d0e2ce8a03d1054afcc28a22fe864a8813460d241db38c59d10e7c29132"),
+						BodyHash: cipher.MustSHA256FromHex("6eafd13ab6823223b714246b32c984b56e0043412950faf17defdbb2cbf3fe30"),
+					},
+				},
+				ShortIDs: []uint64{1, 18446744073709551615, 999988887777},
+			},
+		},
+		{
+			goldenFile: "get-block-txns-msg.golden",
+			obj:        &GetBlockTxnsMessage{},
+			msg: &GetBlockTxnsMessage{
+				BlockHash: cipher.MustSHA256FromHex("6eafd13ab6823223b714246b32c984b56e0043412950faf17defdbb2cbf3fe30"),
+				Indexes:   []uint64{0, 7, 4095},
+			},
+		},
+		{
+			goldenFile: "give-block-txns-msg.golden",
+			obj:        &GiveBlockTxnsMessage{},
+			msg: &GiveBlockTxnsMessage{
+				BlockHash: cipher.MustSHA256FromHex("6eafd13ab6823223b714246b32c984b56e0043412950faf17defdbb2cbf3fe30"),
+				Transactions: []coin.Transaction{
+					{
+						Length:    100,
+						Type:      0,
+						InnerHash: cipher.MustSHA256FromHex("59cb7d0e2ce8a03d1054afcc28a22fe864a8813460d241db38c59d10e7c29132"),
+						Sigs: []cipher.Sig{
+							cipher.MustSigFromHex("8cf145e9ef4a4a5254bc57798a7a61dfed238768f94edc5635175c6b91bccd8ec1555da603c5e31b018e135b82b1525be8a92973c468a74b5b40b8da189cb465eb"),
+						},
+						In: []cipher.SHA256{
+							cipher.MustSHA256FromHex("23cb7d0e2ce8a03d1054afcc28a22fe864a8813460d241db38c59d10e7c29132"),
+						},
+						Out: []coin.TransactionOutput{
+							{
+								Address: cipher.MustDecodeBase58Address("23FF4fshzD8tZk2d88P22WATfzUpNQF1x85"),
+								Coins:   1000000,
+								Hours:   100,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			goldenFile: "block-candidate-msg.golden",
+			obj:        &BlockCandidateMessage{},
+			msg: &BlockCandidateMessage{
+				Block: coin.Block{
+					Head: coin.BlockHeader{
+						Version:  1,
+						Time:     1538036613,
+						BkSeq:    9999999999,
+						Fee:      1234123412341234,
+						PrevHash: cipher.MustSHA256FromHex("59cb7d0e2ce8a03d1054afcc28a22fe864a8813460d241db38c59d10e7c29132"),
+						BodyHash: cipher.MustSHA256FromHex("6eafd13ab6823223b714246b32c984b56e0043412950faf17defdbb2cbf3fe30"),
+					},
+					Body: coin.BlockBody{
+						Transactions: coin.Transactions{
+							{
+								Length:    100,
+								Type:      0,
+								InnerHash: cipher.MustSHA256FromHex("59cb7d0e2ce8a03d1054afcc28a22fe864a8813460d241db38c59d10e7c29132"),
+								Sigs: []cipher.Sig{
+									cipher.MustSigFromHex("8cf145e9ef4a4a5254bc57798a7a61dfed238768f94edc5635175c6b91bccd8ec1555da603c5e31b018e135b82b1525be8a92973c468a74b5b40b8da189cb465eb"),
+								},
+								In: []cipher.SHA256{
+									cipher.MustSHA256FromHex("23cb7d0e2ce8a03d1054afcc28a22fe864a8813460d241db38c59d10e7c29132"),
+								},
+								Out: []coin.TransactionOutput{
+									{
+										Address: cipher.MustDecodeBase58Address("23FF4fshzD8tZk2d88P22WATfzUpNQF1x85"),
+										Coins:   1000000,
+										Hours:   100,
+									},
+								},
+							},
+						},
+					},
+				},
+				Candidate: consensus.BlockBase{
+					Sig:   cipher.MustSigFromHex("8cf145e9ef4a4a5254bc57798a7a61dfed238768f94edc5635175c6b91bccd8ec1555da603c5e31b018e135b82b1525be8a92973c468a74b5b40b8da189cb465eb"),
+					Hash:  cipher.MustSHA256FromHex("6eafd13ab6823223b714246b32c984b56e0043412950faf17defdbb2cbf3fe30"),
+					Seqno: 9999999999,
+				},
+			},
+		},
+		{
+			goldenFile: "give-key-checkpoints-msg.golden",
+			obj:        &GiveKeyCheckpointsMessage{},
+			msg: &GiveKeyCheckpointsMessage{
+				KeyCheckpoints: []blockdb.KeyCheckpoint{
+					{
+						Seq:    9999999999,
+						Pubkey: cipher.MustPubKeyFromHex("03cd7dfcd8c3452d1bb5d9d9e34dd95d6848cb9f66c2aad127b60578f4be7498f2"),
+						Sig:    cipher.MustSigFromHex("8cf145e9ef4a4a5254bc57798a7a61dfed238768f94edc5635175c6b91bccd8ec1555da603c5e31b018e135b82b1525be8a92973c468a74b5b40b8da189cb465eb"),
+					},
+				},
+			},
+		},
+		{
+			goldenFile: "give-block-certificates-msg.golden",
+			obj:        &GiveBlockCertificatesMessage{},
+			msg: &GiveBlockCertificatesMessage{
+				Certificates: []blockdb.BlockCertificate{
+					{
+						Hash: cipher.MustSHA256FromHex("6eafd13ab6823223b714246b32c984b56e0043412950faf17defdbb2cbf3fe30"),
+						Sigs: []cipher.Sig{
+							cipher.MustSigFromHex("8cf145e9ef4a4a5254bc57798a7a61dfed238768f94edc5635175c6b91bccd8ec1555da603c5e31b018e135b82b1525be8a92973c468a74b5b40b8da189cb465eb"),
+							cipher.MustSigFromHex("7a30f1d4f4ba7bd5bd2e87a4ca6e1aa0a0b1ce1cebe0a8c2e5e8b5b6eae4ef3a7b1b36a5d3d6e4d9d2c6b1c2d3e4f5a6b7c8d9e0f1a2b3c4d5e6f7a8b9c0d1e201"),
+						},
+					},
+				},
+			},
+		},
+		{
+			goldenFile: "give-peer-addrs-msg.golden",
+			obj:        &GivePeerAddrsMessage{},
+			msg: &GivePeerAddrsMessage{
+				Peers: []PeerAddr{
+					{
+						Family: PeerAddrFamilyIPv4,
+						IP:     []byte{1, 2, 3, 4},
+						Port:   1234,
+					},
+					{
+						Family: PeerAddrFamilyIPv6,
+						IP:     []byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+						Port:   4321,
+					},
+				},
+			},
+		},
 		{
 			goldenFile: "announce-blocks-msg.golden",
 			obj:        &AnnounceBlocksMessage{},
@@ -912,25 +1296,201 @@ func TestTruncateGivePeersMessage(t *testing.T) {
 	require.True(t, n <= maxLen)
 }
 
-func TestTruncateGiveBlocksMessage(t *testing.T) {
+func TestTruncateGivePeerAddrsMessage(t *testing.T) {
 	maxLen := uint64(1024)
-	m := &GiveBlocksMessage{}
+	m := &GivePeerAddrsMessage{}
 
 	// Empty message, no truncation
-	prevLen := len(m.Blocks)
-	truncateGiveBlocksMessage(m, maxLen)
-	require.Equal(t, prevLen, len(m.Blocks))
+	prevLen := len(m.Peers)
+	truncateGivePeerAddrsMessage(m, maxLen)
+	require.Equal(t, prevLen, len(m.Peers))
 
-	n := encodeSizeGiveBlocksMessage(m)
+	n := encodeSizeGivePeerAddrsMessage(m)
 	require.True(t, n <= maxLen)
 
-	// One block, no truncation
-	m.Blocks = append(m.Blocks, coin.SignedBlock{})
-	prevLen = len(m.Blocks)
-	truncateGiveBlocksMessage(m, maxLen)
-	require.Equal(t, prevLen, len(m.Blocks))
-
-	n = encodeSizeGiveBlocksMessage(m)
+	// One peer, no truncation
+	pa := PeerAddr{
+		Family: PeerAddrFamilyIPv6,
+		IP:     make([]byte, 16),
+	}
+	m.Peers = append(m.Peers, pa)
+	prevLen = len(m.Peers)
+	truncateGivePeerAddrsMessage(m, maxLen)
+	require.Equal(t, prevLen, len(m.Peers))
+
+	n = encodeSizeGivePeerAddrsMessage(m)
+	require.True(t, n <= maxLen)
+
+	// Too many peers, truncated
+	n = encodeSizePeerAddr(&pa)
+	m.Peers = make([]PeerAddr, (maxLen/n)*2)
+	for i := range m.Peers {
+		m.Peers[i] = pa
+	}
+	prevLen = len(m.Peers)
+	truncateGivePeerAddrsMessage(m, maxLen)
+	require.True(t, len(m.Peers) < prevLen)
+	require.NotEmpty(t, m.Peers)
+
+	n = encodeSizeGivePeerAddrsMessage(m)
+	require.True(t, n <= maxLen)
+}
+
+func TestNewPeerAddr(t *testing.T) {
+	cases := []struct {
+		addr   string
+		family uint8
+		ip     []byte
+		port   uint16
+		err    error
+	}{
+		{
+			addr:   "1.2.3.4:6000",
+			family: PeerAddrFamilyIPv4,
+			ip:     []byte{1, 2, 3, 4},
+			port:   6000,
+		},
+		{
+			addr:   "[2001:db8::1]:6000",
+			family: PeerAddrFamilyIPv6,
+			ip:     []byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+			port:   6000,
+		},
+		{
+			addr:   "[::ffff:1.2.3.4]:6000",
+			family: PeerAddrFamilyIPv4,
+			ip:     []byte{1, 2, 3, 4},
+			port:   6000,
+		},
+		{
+			addr:   "vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd.onion:6000",
+			family: PeerAddrFamilyOnion,
+			ip:     []byte("vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd.onion"),
+			port:   6000,
+		},
+		{
+			addr: "example.com:6000",
+			err:  ErrPeerAddrInvalidIP,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.addr, func(t *testing.T) {
+			pa, err := NewPeerAddr(tc.addr)
+			if tc.err != nil {
+				require.Equal(t, tc.err, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.family, pa.Family)
+			require.Equal(t, tc.ip, pa.IP)
+			require.Equal(t, tc.port, pa.Port)
+
+			addr, err := pa.Addr()
+			require.NoError(t, err)
+			if tc.addr == "[::ffff:1.2.3.4]:6000" {
+				require.Equal(t, "1.2.3.4:6000", addr)
+			} else {
+				require.Equal(t, tc.addr, addr)
+			}
+		})
+	}
+}
+
+func TestPeerAddrAddr(t *testing.T) {
+	_, err := PeerAddr{Family: 5, IP: []byte{1, 2, 3, 4}, Port: 6000}.Addr()
+	require.Equal(t, ErrPeerAddrUnknownFamily, err)
+
+	_, err = PeerAddr{Family: PeerAddrFamilyIPv6, IP: []byte{1, 2, 3, 4}, Port: 6000}.Addr()
+	require.Equal(t, ErrPeerAddrInvalidLength, err)
+
+	_, err = PeerAddr{Family: PeerAddrFamilyIPv4, IP: make([]byte, 16), Port: 6000}.Addr()
+	require.Equal(t, ErrPeerAddrInvalidLength, err)
+
+	_, err = PeerAddr{Family: PeerAddrFamilyOnion, IP: []byte("example.com"), Port: 6000}.Addr()
+	require.Equal(t, ErrPeerAddrInvalidOnion, err)
+}
+
+func TestNewGivePeersMessageSkipsIPv6(t *testing.T) {
+	peers := []pex.Peer{
+		{Addr: "1.2.3.4:6000"},
+		{Addr: "[2001:db8::1]:6000"},
+		{Addr: "vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd.onion:6000"},
+		{Addr: "5.6.7.8:6000"},
+	}
+
+	m := NewGivePeersMessage(peers, 1024)
+	require.Equal(t, []string{"1.2.3.4:6000", "5.6.7.8:6000"}, m.GetPeers())
+
+	ma := NewGivePeerAddrsMessage(peers, 1024)
+	require.Equal(t, []string{
+		"1.2.3.4:6000",
+		"[2001:db8::1]:6000",
+		"vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd.onion:6000",
+		"5.6.7.8:6000",
+	}, ma.GetPeers())
+}
+
+func TestGivePeerAddrsMessageProcess(t *testing.T) {
+	c := &gnet.MessageContext{
+		ConnID: 10,
+		Addr:   "127.0.0.1:1234",
+	}
+
+	m := &GivePeerAddrsMessage{
+		Peers: []PeerAddr{
+			{
+				Family: PeerAddrFamilyIPv6,
+				IP:     []byte{0x20, 0x01, 0x0d, 0xb8, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+				Port:   6000,
+			},
+			{
+				// Unknown address families are skipped
+				Family: 200,
+				IP:     []byte("abcdefghijklmnopqrstuvwxyz234567.onion"),
+				Port:   6000,
+			},
+			{
+				Family: PeerAddrFamilyIPv4,
+				IP:     []byte{1, 2, 3, 4},
+				Port:   6000,
+			},
+		},
+		c: c,
+	}
+
+	d := &mockDaemoner{}
+	d.On("pexConfig").Return(pex.Config{})
+	d.On("addPeers", []string{"[2001:db8::1]:6000", "1.2.3.4:6000"}, "127.0.0.1:1234").Return(2)
+	m.process(d)
+	d.AssertExpectations(t)
+
+	d = &mockDaemoner{}
+	d.On("pexConfig").Return(pex.Config{Disabled: true})
+	m.process(d)
+	d.AssertExpectations(t)
+}
+
+func TestTruncateGiveBlocksMessage(t *testing.T) {
+	maxLen := uint64(1024)
+	m := &GiveBlocksMessage{}
+
+	// Empty message, no truncation
+	prevLen := len(m.Blocks)
+	truncateGiveBlocksMessage(m, maxLen)
+	require.Equal(t, prevLen, len(m.Blocks))
+
+	n := encodeSizeGiveBlocksMessage(m)
+	require.True(t, n <= maxLen)
+
+	// One block, no truncation
+	m.Blocks = append(m.Blocks, coin.SignedBlock{})
+	prevLen = len(m.Blocks)
+	truncateGiveBlocksMessage(m, maxLen)
+	require.Equal(t, prevLen, len(m.Blocks))
+
+	n = encodeSizeGiveBlocksMessage(m)
 	require.True(t, n <= maxLen)
 
 	// Too many blocks, truncated
@@ -945,6 +1505,38 @@ func TestTruncateGiveBlocksMessage(t *testing.T) {
 	require.True(t, n <= maxLen)
 }
 
+func TestTruncateGiveHeadersMessage(t *testing.T) {
+	maxLen := uint64(1024)
+	m := &GiveHeadersMessage{}
+
+	// Empty message, no truncation
+	prevLen := len(m.Headers)
+	truncateGiveHeadersMessage(m, maxLen)
+	require.Equal(t, prevLen, len(m.Headers))
+
+	n := encodeSizeGiveHeadersMessage(m)
+	require.True(t, n <= maxLen)
+
+	// One header, no truncation
+	m.Headers = append(m.Headers, SignedBlockHeader{})
+	prevLen = len(m.Headers)
+	truncateGiveHeadersMessage(m, maxLen)
+	require.Equal(t, prevLen, len(m.Headers))
+
+	n = encodeSizeGiveHeadersMessage(m)
+	require.True(t, n <= maxLen)
+
+	// Too many headers, truncated
+	m.Headers = make([]SignedBlockHeader, (maxLen/n)*2)
+	prevLen = len(m.Headers)
+	truncateGiveHeadersMessage(m, maxLen)
+	require.True(t, len(m.Headers) < prevLen)
+	require.NotEmpty(t, m.Headers)
+
+	n = encodeSizeGiveHeadersMessage(m)
+	require.True(t, n <= maxLen)
+}
+
 func TestTruncateGiveTransactionsMessage(t *testing.T) {
 	maxLen := uint64(1024)
 	m := &GiveTxnsMessage{}
@@ -1079,6 +1671,7 @@ func TestGetBlocksMessageProcess(t *testing.T) {
 	d.On("DaemonConfig").Return(config)
 	d.On("recordPeerHeight", "127.0.0.1:1234", uint64(10), uint64(7)).Return()
 	d.On("getSignedBlocksSince", uint64(7), uint64(20)).Return(blocks, nil)
+	d.On("sendBlockCertificates", "127.0.0.1:1234", gbm.Blocks).Return(nil)
 	d.On("sendMessage", "127.0.0.1:1234", gbm).Return(nil)
 
 	m.process(d)
@@ -1086,8 +1679,585 @@ func TestGetBlocksMessageProcess(t *testing.T) {
 	d.AssertExpectations(t)
 }
 
+func TestGetHeadersMessageProcess(t *testing.T) {
+	d := &mockDaemoner{}
+
+	m := &GetHeadersMessage{
+		LastBlock: 7,
+		// request more headers than MaxGetHeadersResponseCount to verify capping
+		RequestedHeaders: 2000,
+		c: &gnet.MessageContext{
+			ConnID: 10,
+			Addr:   "127.0.0.1:1234",
+		},
+	}
+
+	config := DaemonConfig{
+		DisableNetworking:          false,
+		MaxGetHeadersResponseCount: 1024,
+		MaxOutgoingMessageLength:   1024,
+	}
+
+	// Have getBlockHeadersSince return a lot of headers to verify truncation
+	headers := make([]SignedBlockHeader, 256)
+
+	ghm := NewGiveHeadersMessage(headers, config.MaxOutgoingMessageLength)
+	require.True(t, len(ghm.Headers) < len(headers), "headers should be truncated")
+	require.NotEmpty(t, ghm.Headers)
+
+	d.On("DaemonConfig").Return(config)
+	d.On("getBlockHeadersSince", uint64(7), uint64(1024)).Return(headers, nil)
+	d.On("sendMessage", "127.0.0.1:1234", ghm).Return(nil)
+
+	m.process(d)
+
+	d.AssertExpectations(t)
+}
+
+func TestGiveHeadersMessageProcess(t *testing.T) {
+	_, s := cipher.GenerateKeyPair()
+	b := coin.SignedBlock{
+		Block: coin.Block{
+			Head: coin.BlockHeader{
+				BkSeq: 8,
+			},
+		},
+	}
+	b.Sig = cipher.MustSignHash(b.Block.HashHeader(), s)
+	headers := []SignedBlockHeader{NewSignedBlockHeader(b)}
+
+	c := &gnet.MessageContext{
+		ConnID: 10,
+		Addr:   "127.0.0.1:1234",
+	}
+
+	cases := []struct {
+		name    string
+		added   int
+		err     error
+		setupFn func(d *mockDaemoner)
+	}{
+		{
+			name:  "headers added",
+			added: 1,
+			setupFn: func(d *mockDaemoner) {
+				d.On("requestBlocksFromAddr", c.Addr).Return(nil)
+				d.On("downloadBlocks").Return(nil)
+			},
+		},
+		{
+			name: "no headers added",
+		},
+		{
+			name: "headers not contiguous",
+			err:  errBlockHeadersNotContiguous,
+		},
+		{
+			name: "invalid headers",
+			err:  ErrDisconnectInvalidBlockHeaders,
+			setupFn: func(d *mockDaemoner) {
+				d.On("Disconnect", c.Addr, ErrDisconnectInvalidBlockHeaders).Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &mockDaemoner{}
+			d.On("DaemonConfig").Return(DaemonConfig{})
+			d.On("recordPeerHeight", c.Addr, c.ConnID, uint64(8)).Return()
+			d.On("addBlockHeaders", headers).Return(tc.added, tc.err)
+			if tc.setupFn != nil {
+				tc.setupFn(d)
+			}
+
+			m := &GiveHeadersMessage{
+				Headers: headers,
+				c:       c,
+			}
+			m.process(d)
+
+			d.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGiveBlocksMessageProcess(t *testing.T) {
+	_, seckey := cipher.GenerateKeyPair()
+	blocks := makeTestSignedChain(t, coin.BlockHeader{Version: 1, BkSeq: 5}, 3, seckey)
+
+	c := &gnet.MessageContext{
+		ConnID: 10,
+		Addr:   "127.0.0.1:1234",
+	}
+
+	cases := []struct {
+		name    string
+		setupFn func(d *mockDaemoner)
+	}{
+		{
+			name: "side blocks and new block",
+			setupFn: func(d *mockDaemoner) {
+				d.On("addSideBlock", blocks[0]).Return(nil)
+				d.On("addSideBlock", blocks[1]).Return(nil)
+				d.On("executeSignedBlock", blocks[2]).Return(nil)
+				d.On("headBkSeq").Return(uint64(8), true, nil).Once()
+				d.On("broadcastMessage", NewAnnounceBlocksMessage(8)).Return([]uint64{}, nil)
+				d.On("broadcastMessage", NewGetBlocksMessage(8, 2)).Return([]uint64{}, nil)
+			},
+		},
+		{
+			name: "branch without a stored parent",
+			setupFn: func(d *mockDaemoner) {
+				d.On("addSideBlock", blocks[0]).Return(visor.ErrSideBlockNoParent)
+				d.On("sendMessage", c.Addr, NewGetBlocksMessage(3, 2)).Return(nil)
+			},
+		},
+		{
+			name: "new block without a stored parent",
+			setupFn: func(d *mockDaemoner) {
+				d.On("addSideBlock", blocks[0]).Return(nil)
+				d.On("addSideBlock", blocks[1]).Return(nil)
+				d.On("executeSignedBlock", blocks[2]).Return(visor.ErrSideBlockNoParent)
+				d.On("sendMessage", c.Addr, NewGetBlocksMessage(5, 2)).Return(nil)
+			},
+		},
+		{
+			name: "invalid side block",
+			setupFn: func(d *mockDaemoner) {
+				d.On("addSideBlock", blocks[0]).Return(visor.ErrBlockPublisherQuorum)
+				d.On("misbehaved", c.Addr, errMisbehaviorInvalidBlock).Return()
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &mockDaemoner{}
+			d.On("DaemonConfig").Return(DaemonConfig{
+				GetBlocksRequestCount: 2,
+			})
+			d.On("addSyncBlocks", c.Addr, blocks).Return(blocks, false, nil)
+			d.On("headBkSeq").Return(uint64(7), true, nil).Once()
+			tc.setupFn(d)
+
+			m := &GiveBlocksMessage{
+				Blocks: blocks,
+				c:      c,
+			}
+			m.process(d)
+
+			d.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCompactBlockMessageProcess(t *testing.T) {
+	pubkey, seckey := cipher.GenerateKeyPair()
+	_, badSeckey := cipher.GenerateKeyPair()
+
+	head := coin.BlockHeader{
+		Version: 1,
+		Time:    100,
+		BkSeq:   7,
+	}
+	b := makeTestSignedChain(t, head, 1, seckey)[0]
+	badBlock := makeTestSignedChain(t, head, 1, badSeckey)[0]
+
+	c := &gnet.MessageContext{
+		ConnID: 10,
+		Addr:   "127.0.0.1:1234",
+	}
+
+	cases := []struct {
+		name    string
+		block   coin.SignedBlock
+		headSeq uint64
+		invalid bool
+		setupFn func(d *mockDaemoner, m *CompactBlockMessage)
+	}{
+		{
+			name:    "block already known",
+			block:   b,
+			headSeq: 8,
+		},
+		{
+			name:    "behind",
+			block:   b,
+			headSeq: 6,
+			setupFn: func(d *mockDaemoner, m *CompactBlockMessage) {
+				d.On("requestBlocksFromAddr", c.Addr).Return(nil)
+			},
+		},
+		{
+			name:    "invalid signature",
+			block:   badBlock,
+			headSeq: 7,
+			invalid: true,
+			setupFn: func(d *mockDaemoner, m *CompactBlockMessage) {
+				d.On("Disconnect", c.Addr, ErrDisconnectInvalidBlockHeaders).Return(nil)
+			},
+		},
+		{
+			name:    "rebuilt",
+			block:   b,
+			headSeq: 7,
+			setupFn: func(d *mockDaemoner, m *CompactBlockMessage) {
+				d.On("reconstructCompactBlock", c.Addr, m.Header, m.ShortIDs).Return(&b, nil, nil)
+				d.On("executeSignedBlock", b).Return(nil)
+				d.On("relayCompactBlock", c.Addr, b).Return(nil)
+			},
+		},
+		{
+			name:    "rebuilt block invalid",
+			block:   b,
+			headSeq: 7,
+			setupFn: func(d *mockDaemoner, m *CompactBlockMessage) {
+				d.On("reconstructCompactBlock", c.Addr, m.Header, m.ShortIDs).Return(&b, nil, nil)
+				d.On("executeSignedBlock", b).Return(errors.New("invalid block"))
+				d.On("misbehaved", c.Addr, errMisbehaviorInvalidBlock).Return()
+			},
+		},
+		{
+			name:    "rebuilt block without a stored parent",
+			block:   b,
+			headSeq: 7,
+			setupFn: func(d *mockDaemoner, m *CompactBlockMessage) {
+				d.On("reconstructCompactBlock", c.Addr, m.Header, m.ShortIDs).Return(&b, nil, nil)
+				d.On("executeSignedBlock", b).Return(visor.ErrSideBlockNoParent)
+				d.On("sendMessage", c.Addr, NewGetBlocksMessage(7, 0)).Return(nil)
+			},
+		},
+		{
+			name:    "missing transactions",
+			block:   b,
+			headSeq: 7,
+			setupFn: func(d *mockDaemoner, m *CompactBlockMessage) {
+				d.On("reconstructCompactBlock", c.Addr, m.Header, m.ShortIDs).Return(nil, []uint64{0}, nil)
+				d.On("sendMessage", c.Addr, NewGetBlockTxnsMessage(b.HashHeader(), []uint64{0})).Return(nil)
+			},
+		},
+		{
+			name:    "transactions already requested",
+			block:   b,
+			headSeq: 7,
+			setupFn: func(d *mockDaemoner, m *CompactBlockMessage) {
+				d.On("reconstructCompactBlock", c.Addr, m.Header, m.ShortIDs).Return(nil, nil, errCompactBlockRequested)
+			},
+		},
+		{
+			name:    "rebuilt block mismatch",
+			block:   b,
+			headSeq: 7,
+			setupFn: func(d *mockDaemoner, m *CompactBlockMessage) {
+				d.On("reconstructCompactBlock", c.Addr, m.Header, m.ShortIDs).Return(nil, nil, errCompactBlockMismatch)
+				d.On("requestBlocksFromAddr", c.Addr).Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := NewCompactBlockMessage(tc.block)
+			m.c = c
+
+			d := &mockDaemoner{}
+			d.On("DaemonConfig").Return(DaemonConfig{})
+			d.On("blockSigners").Return([]cipher.PubKey{pubkey})
+			if !tc.invalid {
+				// The height of the peer is only recorded from a validly signed header
+				d.On("recordPeerHeight", c.Addr, c.ConnID, uint64(8)).Return()
+				d.On("headBkSeq").Return(tc.headSeq, true, nil)
+			}
+			if tc.setupFn != nil {
+				tc.setupFn(d, m)
+			}
+
+			m.process(d)
+
+			d.AssertExpectations(t)
+			if tc.invalid {
+				d.AssertNotCalled(t, "recordPeerHeight", c.Addr, c.ConnID, uint64(8))
+			}
+		})
+	}
+}
+
+func TestGetBlockTxnsMessageProcess(t *testing.T) {
+	hash := testutil.RandSHA256(t)
+	txns := coin.Transactions{
+		{
+			InnerHash: testutil.RandSHA256(t),
+		},
+	}
+
+	m := &GetBlockTxnsMessage{
+		BlockHash: hash,
+		Indexes:   []uint64{3},
+		c: &gnet.MessageContext{
+			ConnID: 10,
+			Addr:   "127.0.0.1:1234",
+		},
+	}
+
+	d := &mockDaemoner{}
+	d.On("DaemonConfig").Return(DaemonConfig{})
+	d.On("getBlockTxns", hash, []uint64{3}).Return(txns, nil)
+	d.On("sendMessage", "127.0.0.1:1234", NewGiveBlockTxnsMessage(hash, txns)).Return(nil)
+
+	m.process(d)
+
+	d.AssertExpectations(t)
+}
+
+func TestGiveBlockTxnsMessageProcess(t *testing.T) {
+	_, seckey := cipher.GenerateKeyPair()
+	b := makeTestSignedChain(t, coin.BlockHeader{Version: 1}, 1, seckey)[0]
+	hash := b.HashHeader()
+
+	c := &gnet.MessageContext{
+		ConnID: 10,
+		Addr:   "127.0.0.1:1234",
+	}
+
+	cases := []struct {
+		name    string
+		block   *coin.SignedBlock
+		err     error
+		setupFn func(d *mockDaemoner)
+	}{
+		{
+			name:  "completed",
+			block: &b,
+			setupFn: func(d *mockDaemoner) {
+				d.On("executeSignedBlock", b).Return(nil)
+				d.On("relayCompactBlock", c.Addr, b).Return(nil)
+			},
+		},
+		{
+			name: "no pending compact block",
+		},
+		{
+			name: "mismatch",
+			err:  errCompactBlockMismatch,
+			setupFn: func(d *mockDaemoner) {
+				d.On("requestBlocksFromAddr", c.Addr).Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &mockDaemoner{}
+			d.On("DaemonConfig").Return(DaemonConfig{})
+			d.On("completeCompactBlock", c.Addr, hash, b.Body.Transactions).Return(tc.block, tc.err)
+			if tc.setupFn != nil {
+				tc.setupFn(d)
+			}
+
+			m := &GiveBlockTxnsMessage{
+				BlockHash:    hash,
+				Transactions: b.Body.Transactions,
+				c:            c,
+			}
+			m.process(d)
+
+			d.AssertExpectations(t)
+		})
+	}
+}
+
+func TestBlockCandidateMessageProcess(t *testing.T) {
+	pubkey, seckey := cipher.GenerateKeyPair()
+	b := makeTestSignedChain(t, coin.BlockHeader{Version: 1, BkSeq: 7}, 1, seckey)[0]
+	candidate := consensus.BlockBase{
+		Sig:   b.Sig,
+		Hash:  b.HashHeader(),
+		Seqno: b.Seq(),
+	}
+
+	c := &gnet.MessageContext{
+		ConnID: 10,
+		Addr:   "127.0.0.1:1234",
+	}
+
+	cases := []struct {
+		name       string
+		publishers []cipher.PubKey
+		headSeq    uint64
+		setupFn    func(d *mockDaemoner)
+	}{
+		{
+			name:    "no block publishers",
+			headSeq: 7,
+		},
+		{
+			name:       "block already known",
+			publishers: []cipher.PubKey{pubkey},
+			headSeq:    8,
+		},
+		{
+			name:       "behind",
+			publishers: []cipher.PubKey{pubkey},
+			headSeq:    6,
+			setupFn: func(d *mockDaemoner) {
+				d.On("requestBlocksFromAddr", c.Addr).Return(nil)
+			},
+		},
+		{
+			name:       "added",
+			publishers: []cipher.PubKey{pubkey},
+			headSeq:    7,
+			setupFn: func(d *mockDaemoner) {
+				d.On("addBlockCandidate", candidate, b.Block).Return(nil)
+			},
+		},
+		{
+			name:       "rejected",
+			publishers: []cipher.PubKey{pubkey},
+			headSeq:    7,
+			setupFn: func(d *mockDaemoner) {
+				d.On("addBlockCandidate", candidate, b.Block).Return(errBlockCandidateSigner)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &mockDaemoner{}
+			d.On("DaemonConfig").Return(DaemonConfig{
+				PublisherPubkeys: tc.publishers,
+			})
+			if len(tc.publishers) != 0 {
+				d.On("headBkSeq").Return(tc.headSeq, true, nil)
+			}
+			if tc.setupFn != nil {
+				tc.setupFn(d)
+			}
+
+			m := NewBlockCandidateMessage(candidate, b.Block)
+			m.c = c
+			m.process(d)
+
+			d.AssertExpectations(t)
+		})
+	}
+}
+
 func setupMsgEncoding() {
 	gnet.EraseMessages()
 	var messagesConfig = NewMessagesConfig()
 	messagesConfig.Register()
 }
+
+func TestGiveBlockCertificatesMessageProcess(t *testing.T) {
+	_, seckey := cipher.GenerateKeyPair()
+	hash := cipher.SumSHA256([]byte("block"))
+	cert := blockdb.BlockCertificate{
+		Hash: hash,
+		Sigs: []cipher.Sig{cipher.MustSignHash(hash, seckey)},
+	}
+
+	cases := []struct {
+		name              string
+		disableNetworking bool
+		setupFn           func(d *mockDaemoner)
+	}{
+		{
+			name:              "networking disabled",
+			disableNetworking: true,
+		},
+		{
+			name: "certificates added",
+			setupFn: func(d *mockDaemoner) {
+				d.On("addBlockCertificates", []blockdb.BlockCertificate{cert}).Return(1)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &mockDaemoner{}
+			d.On("DaemonConfig").Return(DaemonConfig{
+				DisableNetworking: tc.disableNetworking,
+			})
+			if tc.setupFn != nil {
+				tc.setupFn(d)
+			}
+
+			m := NewGiveBlockCertificatesMessage([]blockdb.BlockCertificate{cert})
+			m.c = &gnet.MessageContext{
+				ConnID: 10,
+				Addr:   "127.0.0.1:1234",
+			}
+			m.process(d)
+
+			d.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGiveKeyCheckpointsMessageProcess(t *testing.T) {
+	_, seckey := cipher.GenerateKeyPair()
+	pubkey1, _ := cipher.GenerateKeyPair()
+	pubkey2, _ := cipher.GenerateKeyPair()
+	kc1 := blockdb.NewKeyCheckpoint(10, pubkey1, seckey)
+	kc2 := blockdb.NewKeyCheckpoint(20, pubkey2, seckey)
+
+	c := &gnet.MessageContext{
+		ConnID: 10,
+		Addr:   "127.0.0.1:1234",
+	}
+
+	cases := []struct {
+		name              string
+		disableNetworking bool
+		setupFn           func(d *mockDaemoner)
+	}{
+		{
+			name:              "networking disabled",
+			disableNetworking: true,
+		},
+		{
+			name: "new checkpoints relayed",
+			setupFn: func(d *mockDaemoner) {
+				d.On("addKeyCheckpoint", kc1).Return(true, nil)
+				d.On("addKeyCheckpoint", kc2).Return(true, nil)
+				d.On("relayKeyCheckpoints", c.Addr, []blockdb.KeyCheckpoint{kc1, kc2}).Return(nil)
+			},
+		},
+		{
+			name: "known and invalid checkpoints not relayed",
+			setupFn: func(d *mockDaemoner) {
+				d.On("addKeyCheckpoint", kc1).Return(false, nil)
+				d.On("addKeyCheckpoint", kc2).Return(false, errors.New("invalid"))
+			},
+		},
+		{
+			name: "only new checkpoints relayed",
+			setupFn: func(d *mockDaemoner) {
+				d.On("addKeyCheckpoint", kc1).Return(false, nil)
+				d.On("addKeyCheckpoint", kc2).Return(true, nil)
+				d.On("relayKeyCheckpoints", c.Addr, []blockdb.KeyCheckpoint{kc2}).Return(nil)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			d := &mockDaemoner{}
+			d.On("DaemonConfig").Return(DaemonConfig{
+				DisableNetworking: tc.disableNetworking,
+			})
+			if tc.setupFn != nil {
+				tc.setupFn(d)
+			}
+
+			m := NewGiveKeyCheckpointsMessage([]blockdb.KeyCheckpoint{kc1, kc2})
+			m.c = c
+			m.process(d)
+
+			d.AssertExpectations(t)
+		})
+	}
+}
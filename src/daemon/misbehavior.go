@@ -0,0 +1,189 @@
+package daemon
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ness-network/ness/src/daemon/gnet"
+	"github.com/ness-network/ness/src/daemon/pex"
+)
+
+var (
+	// errMisbehaviorInvalidTransaction the peer sent a transaction that violates hard constraints
+	errMisbehaviorInvalidTransaction = errors.New("Transaction violates hard constraints")
+	// errMisbehaviorInvalidBlock the peer sent a block that could not be executed
+	errMisbehaviorInvalidBlock = errors.New("Block is invalid")
+	// errMisbehaviorRateLimited the peer sent a full bucket of messages over the rate limit of their type
+	errMisbehaviorRateLimited = errors.New("Message rate limit exceeded")
+
+	// misbehaviorScores are the misbehavior scores of disconnect reasons and validation failures.
+	// A peer is banned when its accumulated score reaches DaemonConfig.BanThreshold.
+	// Reasons that a well-behaved peer can cause, such as a different blockchain or an older
+	// protocol version, are not scored.
+	misbehaviorScores = map[error]int{
+		ErrDisconnectInvalidBlockHeaders: 100,
+		ErrDisconnectBlockHeaderMismatch: 100,
+
+		gnet.ErrDisconnectInvalidMessageLength:     50,
+		gnet.ErrDisconnectMalformedMessage:         50,
+		gnet.ErrDisconnectMessageDecodeUnderflow:   50,
+		gnet.ErrDisconnectTruncatedMessageID:       50,
+		gnet.ErrDisconnectDecryptFailed:            50,
+		gnet.ErrDisconnectSecureNodePubKeyMismatch: 50,
+
+		errMisbehaviorInvalidBlock: 50,
+
+		ErrDisconnectInvalidExtraData: 20,
+		ErrDisconnectInvalidUserAgent: 20,
+		ErrDisconnectNoIntroduction:   20,
+
+		ErrDisconnectIntroductionTimeout:         10,
+		gnet.ErrDisconnectUnknownMessage:         10,
+		gnet.ErrDisconnectSecureHandshakeFailed:  10,
+		gnet.ErrDisconnectSecureHandshakeTimeout: 10,
+		errMisbehaviorInvalidTransaction:         10,
+
+		errMisbehaviorRateLimited: 1,
+	}
+)
+
+// misbehaviorScore is the accumulated misbehavior score of a host
+type misbehaviorScore struct {
+	score   int
+	updated time.Time
+}
+
+// misbehaviors records the misbehavior scores of peers, by the host that they would be banned by
+type misbehaviors struct {
+	sync.Mutex
+	scores map[string]misbehaviorScore
+}
+
+func newMisbehaviors() *misbehaviors {
+	return &misbehaviors{
+		scores: make(map[string]misbehaviorScore),
+	}
+}
+
+// add increases the score of host by n and returns the new score
+func (m *misbehaviors) add(host string, n int, now time.Time) int {
+	m.Lock()
+	defer m.Unlock()
+
+	s := m.scores[host]
+	s.score += n
+	s.updated = now
+	m.scores[host] = s
+
+	return s.score
+}
+
+// get returns the score of host
+func (m *misbehaviors) get(host string) int {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.scores[host].score
+}
+
+// remove removes the score of host
+func (m *misbehaviors) remove(host string) {
+	m.Lock()
+	defer m.Unlock()
+
+	delete(m.scores, host)
+}
+
+// clearOld removes the scores of hosts that have not misbehaved since t
+func (m *misbehaviors) clearOld(t time.Time) {
+	m.Lock()
+	defer m.Unlock()
+
+	for host, s := range m.scores {
+		if s.updated.Before(t) {
+			delete(m.scores, host)
+		}
+	}
+}
+
+// misbehaved increases the misbehavior score of the peer at addr by the score of reason,
+// and bans the peer when its score reaches BanThreshold.
+// Trusted peers and connections through our own onion service are not scored.
+func (dm *Daemon) misbehaved(addr string, reason error) {
+	n := misbehaviorScores[reason]
+	if n == 0 || dm.config.BanThreshold <= 0 {
+		return
+	}
+
+	fields := logrus.Fields{
+		"addr":   addr,
+		"reason": reason,
+	}
+
+	if dm.isTrustedPeer(addr) || dm.isOnionServiceConnection(addr) {
+		logger.WithFields(fields).Debug("Not scoring misbehavior of trusted peer or onion service connection")
+		return
+	}
+
+	host, err := pex.BanHost(addr)
+	if err != nil {
+		logger.Critical().WithError(err).WithFields(fields).Error("misbehaved called with invalid addr")
+		return
+	}
+
+	score := dm.misbehavior.add(host, n, time.Now())
+	logger.WithFields(fields).WithField("score", score).Info("Peer misbehaved")
+
+	if score < dm.config.BanThreshold {
+		return
+	}
+
+	if _, err := dm.BanPeer(addr, dm.config.BanDuration, reason.Error()); err != nil {
+		logger.WithError(err).WithFields(fields).Error("BanPeer failed")
+	}
+}
+
+// misbehaviorScore returns the misbehavior score of the peer at addr
+func (dm *Daemon) misbehaviorScore(addr string) int {
+	host, err := pex.BanHost(addr)
+	if err != nil {
+		return 0
+	}
+	return dm.misbehavior.get(host)
+}
+
+// BanPeer bans the host of addr for duration and disconnects the connections from that host.
+// addr is an ip:port, an onion:port, an IP address, an onion address or an IPv6 /64 prefix.
+func (dm *Daemon) BanPeer(addr string, duration time.Duration, reason string) (pex.Ban, error) {
+	b, err := dm.pex.Ban(addr, duration, reason)
+	if err != nil {
+		return pex.Ban{}, err
+	}
+
+	dm.misbehavior.remove(b.Host)
+
+	for _, c := range dm.connections.all() {
+		if host, err := pex.BanHost(c.Addr); err != nil || host != b.Host {
+			continue
+		}
+
+		if err := dm.Disconnect(c.Addr, ErrDisconnectIsBlacklisted); err != nil {
+			logger.WithError(err).WithField("addr", c.Addr).Error("Disconnect")
+		}
+	}
+
+	return b, nil
+}
+
+// UnbanPeer removes the ban of the host of addr
+func (dm *Daemon) UnbanPeer(addr string) error {
+	return dm.pex.Unban(addr)
+}
+
+// GetBans returns the bans that have not expired
+func (dm *Daemon) GetBans() []pex.Ban {
+	return dm.pex.Bans()
+}
@@ -0,0 +1,87 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ness-network/ness/src/daemon/gnet"
+	"github.com/ness-network/ness/src/daemon/pex"
+)
+
+func TestMisbehaviors(t *testing.T) {
+	m := newMisbehaviors()
+	now := time.Now()
+
+	require.Equal(t, 10, m.add("112.32.32.14", 10, now.Add(-time.Hour)))
+	require.Equal(t, 30, m.add("112.32.32.14", 20, now.Add(-time.Hour)))
+	require.Equal(t, 50, m.add("112.32.32.15", 50, now))
+	require.Equal(t, 30, m.get("112.32.32.14"))
+	require.Equal(t, 0, m.get("112.32.32.16"))
+
+	m.clearOld(now.Add(-time.Minute))
+	require.Equal(t, 0, m.get("112.32.32.14"))
+	require.Equal(t, 50, m.get("112.32.32.15"))
+
+	m.remove("112.32.32.15")
+	require.Equal(t, 0, m.get("112.32.32.15"))
+	require.Empty(t, m.scores)
+}
+
+func TestDaemonMisbehaved(t *testing.T) {
+	dir, err := ioutil.TempDir("", "misbehavior")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	pc := pex.NewConfig()
+	pc.DataDirectory = dir
+	pc.DefaultConnections = []string{"112.32.32.99:6000"}
+	px, err := pex.New(pc)
+	require.NoError(t, err)
+
+	dc := NewDaemonConfig()
+	dm := &Daemon{
+		config:      dc,
+		pex:         px,
+		connections: NewConnections(),
+		misbehavior: newMisbehaviors(),
+	}
+
+	// Reasons that are not scored are ignored
+	dm.misbehaved("112.32.32.14:6000", ErrDisconnectVersionNotSupported)
+	require.Equal(t, 0, dm.misbehaviorScore("112.32.32.14:6000"))
+
+	// Scores accumulate per host, regardless of port
+	dm.misbehaved("112.32.32.14:6000", gnet.ErrDisconnectMalformedMessage)
+	require.Equal(t, 50, dm.misbehaviorScore("112.32.32.14:6001"))
+	dm.misbehaved("112.32.32.14:6001", ErrDisconnectIntroductionTimeout)
+	require.Equal(t, 60, dm.misbehaviorScore("112.32.32.14:6000"))
+	require.Empty(t, dm.GetBans())
+
+	// Trusted peers are not scored
+	dm.misbehaved("112.32.32.99:6000", ErrDisconnectInvalidBlockHeaders)
+	require.Equal(t, 0, dm.misbehaviorScore("112.32.32.99:6000"))
+
+	// Reaching the threshold bans the host and resets its score
+	dm.misbehaved("112.32.32.14:6000", gnet.ErrDisconnectDecryptFailed)
+	require.Equal(t, 0, dm.misbehaviorScore("112.32.32.14:6000"))
+	require.True(t, px.IsBanned("112.32.32.14:7000"))
+
+	bans := dm.GetBans()
+	require.Len(t, bans, 1)
+	require.Equal(t, "112.32.32.14", bans[0].Host)
+	require.Equal(t, gnet.ErrDisconnectDecryptFailed.Error(), bans[0].Reason)
+	require.Equal(t, bans[0].Created+int64(dc.BanDuration/time.Second), bans[0].Expires)
+
+	require.NoError(t, dm.UnbanPeer("112.32.32.14"))
+	require.Empty(t, dm.GetBans())
+
+	// A BanThreshold of 0 disables scoring
+	dm.config.BanThreshold = 0
+	dm.misbehaved("112.32.32.14:6000", ErrDisconnectInvalidBlockHeaders)
+	require.Equal(t, 0, dm.misbehaviorScore("112.32.32.14:6000"))
+	require.Empty(t, dm.GetBans())
+}
@@ -6,13 +6,17 @@ import (
 	cipher "github.com/skycoin/skycoin/src/cipher"
 	coin "github.com/skycoin/skycoin/src/coin"
 
-	gnet "github.com/skycoin/skycoin/src/daemon/gnet"
+	consensus "github.com/ness-network/ness/src/consensus"
+
+	gnet "github.com/ness-network/ness/src/daemon/gnet"
 
 	mock "github.com/stretchr/testify/mock"
 
-	pex "github.com/skycoin/skycoin/src/daemon/pex"
+	pex "github.com/ness-network/ness/src/daemon/pex"
 
 	transaction "github.com/skycoin/skycoin/src/transaction"
+
+	blockdb "github.com/ness-network/ness/src/visor/blockdb"
 )
 
 // mockDaemoner is an autogenerated mock type for the daemoner type
@@ -48,13 +52,83 @@ func (_m *mockDaemoner) Disconnect(addr string, r gnet.DisconnectReason) error {
 	return r0
 }
 
-// addPeers provides a mock function with given fields: addrs
-func (_m *mockDaemoner) addPeers(addrs []string) int {
-	ret := _m.Called(addrs)
+// addBlockCandidate provides a mock function with given fields: candidate, b
+func (_m *mockDaemoner) addBlockCandidate(candidate consensus.BlockBase, b coin.Block) error {
+	ret := _m.Called(candidate, b)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(consensus.BlockBase, coin.Block) error); ok {
+		r0 = rf(candidate, b)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// addBlockHeaders provides a mock function with given fields: headers
+func (_m *mockDaemoner) addBlockHeaders(headers []SignedBlockHeader) (int, error) {
+	ret := _m.Called(headers)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func([]SignedBlockHeader) int); ok {
+		r0 = rf(headers)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func([]SignedBlockHeader) error); ok {
+		r1 = rf(headers)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// addBlockCertificates provides a mock function with given fields: certs
+func (_m *mockDaemoner) addBlockCertificates(certs []blockdb.BlockCertificate) int {
+	ret := _m.Called(certs)
+
+	var r0 int
+	if rf, ok := ret.Get(0).(func([]blockdb.BlockCertificate) int); ok {
+		r0 = rf(certs)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	return r0
+}
+
+// addKeyCheckpoint provides a mock function with given fields: kc
+func (_m *mockDaemoner) addKeyCheckpoint(kc blockdb.KeyCheckpoint) (bool, error) {
+	ret := _m.Called(kc)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(blockdb.KeyCheckpoint) bool); ok {
+		r0 = rf(kc)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(blockdb.KeyCheckpoint) error); ok {
+		r1 = rf(kc)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// addPeers provides a mock function with given fields: addrs, source
+func (_m *mockDaemoner) addPeers(addrs []string, source string) int {
+	ret := _m.Called(addrs, source)
 
 	var r0 int
-	if rf, ok := ret.Get(0).(func([]string) int); ok {
-		r0 = rf(addrs)
+	if rf, ok := ret.Get(0).(func([]string, string) int); ok {
+		r0 = rf(addrs, source)
 	} else {
 		r0 = ret.Get(0).(int)
 	}
@@ -62,6 +136,50 @@ func (_m *mockDaemoner) addPeers(addrs []string) int {
 	return r0
 }
 
+// addSideBlock provides a mock function with given fields: b
+func (_m *mockDaemoner) addSideBlock(b coin.SignedBlock) error {
+	ret := _m.Called(b)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(coin.SignedBlock) error); ok {
+		r0 = rf(b)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// addSyncBlocks provides a mock function with given fields: addr, blocks
+func (_m *mockDaemoner) addSyncBlocks(addr string, blocks []coin.SignedBlock) ([]coin.SignedBlock, bool, error) {
+	ret := _m.Called(addr, blocks)
+
+	var r0 []coin.SignedBlock
+	if rf, ok := ret.Get(0).(func(string, []coin.SignedBlock) []coin.SignedBlock); ok {
+		r0 = rf(addr, blocks)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]coin.SignedBlock)
+		}
+	}
+
+	var r1 bool
+	if rf, ok := ret.Get(1).(func(string, []coin.SignedBlock) bool); ok {
+		r1 = rf(addr, blocks)
+	} else {
+		r1 = ret.Get(1).(bool)
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, []coin.SignedBlock) error); ok {
+		r2 = rf(addr, blocks)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // announceAllValidTxns provides a mock function with given fields:
 func (_m *mockDaemoner) announceAllValidTxns() error {
 	ret := _m.Called()
@@ -76,6 +194,38 @@ func (_m *mockDaemoner) announceAllValidTxns() error {
 	return r0
 }
 
+// blockSigners provides a mock function with given fields:
+func (_m *mockDaemoner) blockSigners() []cipher.PubKey {
+	ret := _m.Called()
+
+	var r0 []cipher.PubKey
+	if rf, ok := ret.Get(0).(func() []cipher.PubKey); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]cipher.PubKey)
+		}
+	}
+
+	return r0
+}
+
+// blockchainPubkeys provides a mock function with given fields:
+func (_m *mockDaemoner) blockchainPubkeys() []cipher.PubKey {
+	ret := _m.Called()
+
+	var r0 []cipher.PubKey
+	if rf, ok := ret.Get(0).(func() []cipher.PubKey); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]cipher.PubKey)
+		}
+	}
+
+	return r0
+}
+
 // broadcastMessage provides a mock function with given fields: msg
 func (_m *mockDaemoner) broadcastMessage(msg gnet.Message) ([]uint64, error) {
 	ret := _m.Called(msg)
@@ -99,6 +249,29 @@ func (_m *mockDaemoner) broadcastMessage(msg gnet.Message) ([]uint64, error) {
 	return r0, r1
 }
 
+// completeCompactBlock provides a mock function with given fields: addr, blockHash, txns
+func (_m *mockDaemoner) completeCompactBlock(addr string, blockHash cipher.SHA256, txns coin.Transactions) (*coin.SignedBlock, error) {
+	ret := _m.Called(addr, blockHash, txns)
+
+	var r0 *coin.SignedBlock
+	if rf, ok := ret.Get(0).(func(string, cipher.SHA256, coin.Transactions) *coin.SignedBlock); ok {
+		r0 = rf(addr, blockHash, txns)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*coin.SignedBlock)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, cipher.SHA256, coin.Transactions) error); ok {
+		r1 = rf(addr, blockHash, txns)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // connectionIntroduced provides a mock function with given fields: addr, gnetID, m
 func (_m *mockDaemoner) connectionIntroduced(addr string, gnetID uint64, m *IntroductionMessage) (*connection, error) {
 	ret := _m.Called(addr, gnetID, m)
@@ -136,6 +309,20 @@ func (_m *mockDaemoner) disconnectNow(addr string, r gnet.DisconnectReason) erro
 	return r0
 }
 
+// downloadBlocks provides a mock function with given fields:
+func (_m *mockDaemoner) downloadBlocks() error {
+	ret := _m.Called()
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func() error); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // executeSignedBlock provides a mock function with given fields: b
 func (_m *mockDaemoner) executeSignedBlock(b coin.SignedBlock) error {
 	ret := _m.Called(b)
@@ -173,6 +360,52 @@ func (_m *mockDaemoner) filterKnownUnconfirmed(txns []cipher.SHA256) ([]cipher.S
 	return r0, r1
 }
 
+// getBlockHeadersSince provides a mock function with given fields: seq, count
+func (_m *mockDaemoner) getBlockHeadersSince(seq uint64, count uint64) ([]SignedBlockHeader, error) {
+	ret := _m.Called(seq, count)
+
+	var r0 []SignedBlockHeader
+	if rf, ok := ret.Get(0).(func(uint64, uint64) []SignedBlockHeader); ok {
+		r0 = rf(seq, count)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]SignedBlockHeader)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(uint64, uint64) error); ok {
+		r1 = rf(seq, count)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// getBlockTxns provides a mock function with given fields: blockHash, indexes
+func (_m *mockDaemoner) getBlockTxns(blockHash cipher.SHA256, indexes []uint64) (coin.Transactions, error) {
+	ret := _m.Called(blockHash, indexes)
+
+	var r0 coin.Transactions
+	if rf, ok := ret.Get(0).(func(cipher.SHA256, []uint64) coin.Transactions); ok {
+		r0 = rf(blockHash, indexes)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(coin.Transactions)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(cipher.SHA256, []uint64) error); ok {
+		r1 = rf(blockHash, indexes)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // getKnownUnconfirmed provides a mock function with given fields: txns
 func (_m *mockDaemoner) getKnownUnconfirmed(txns []cipher.SHA256) (coin.Transactions, error) {
 	ret := _m.Called(txns)
@@ -277,6 +510,11 @@ func (_m *mockDaemoner) injectTransaction(txn coin.Transaction) (bool, *transact
 	return r0, r1, r2
 }
 
+// misbehaved provides a mock function with given fields: addr, reason
+func (_m *mockDaemoner) misbehaved(addr string, reason error) {
+	_m.Called(addr, reason)
+}
+
 // pexConfig provides a mock function with given fields:
 func (_m *mockDaemoner) pexConfig() pex.Config {
 	ret := _m.Called()
@@ -291,6 +529,38 @@ func (_m *mockDaemoner) pexConfig() pex.Config {
 	return r0
 }
 
+// reconstructCompactBlock provides a mock function with given fields: addr, header, shortIDs
+func (_m *mockDaemoner) reconstructCompactBlock(addr string, header SignedBlockHeader, shortIDs []uint64) (*coin.SignedBlock, []uint64, error) {
+	ret := _m.Called(addr, header, shortIDs)
+
+	var r0 *coin.SignedBlock
+	if rf, ok := ret.Get(0).(func(string, SignedBlockHeader, []uint64) *coin.SignedBlock); ok {
+		r0 = rf(addr, header, shortIDs)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*coin.SignedBlock)
+		}
+	}
+
+	var r1 []uint64
+	if rf, ok := ret.Get(1).(func(string, SignedBlockHeader, []uint64) []uint64); ok {
+		r1 = rf(addr, header, shortIDs)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).([]uint64)
+		}
+	}
+
+	var r2 error
+	if rf, ok := ret.Get(2).(func(string, SignedBlockHeader, []uint64) error); ok {
+		r2 = rf(addr, header, shortIDs)
+	} else {
+		r2 = ret.Error(2)
+	}
+
+	return r0, r1, r2
+}
+
 // recordMessageEvent provides a mock function with given fields: m, c
 func (_m *mockDaemoner) recordMessageEvent(m asyncMessage, c *gnet.MessageContext) error {
 	ret := _m.Called(m, c)
@@ -310,6 +580,34 @@ func (_m *mockDaemoner) recordPeerHeight(addr string, gnetID uint64, height uint
 	_m.Called(addr, gnetID, height)
 }
 
+// relayCompactBlock provides a mock function with given fields: addr, b
+func (_m *mockDaemoner) relayCompactBlock(addr string, b coin.SignedBlock) error {
+	ret := _m.Called(addr, b)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, coin.SignedBlock) error); ok {
+		r0 = rf(addr, b)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// relayKeyCheckpoints provides a mock function with given fields: exclude, checkpoints
+func (_m *mockDaemoner) relayKeyCheckpoints(exclude string, checkpoints []blockdb.KeyCheckpoint) error {
+	ret := _m.Called(exclude, checkpoints)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []blockdb.KeyCheckpoint) error); ok {
+		r0 = rf(exclude, checkpoints)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // requestBlocksFromAddr provides a mock function with given fields: addr
 func (_m *mockDaemoner) requestBlocksFromAddr(addr string) error {
 	ret := _m.Called(addr)
@@ -324,6 +622,48 @@ func (_m *mockDaemoner) requestBlocksFromAddr(addr string) error {
 	return r0
 }
 
+// secureConnection provides a mock function with given fields: addr, m
+func (_m *mockDaemoner) secureConnection(addr string, m *IntroductionMessage) error {
+	ret := _m.Called(addr, m)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, *IntroductionMessage) error); ok {
+		r0 = rf(addr, m)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// sendBlockCertificates provides a mock function with given fields: addr, blocks
+func (_m *mockDaemoner) sendBlockCertificates(addr string, blocks []coin.SignedBlock) error {
+	ret := _m.Called(addr, blocks)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string, []coin.SignedBlock) error); ok {
+		r0 = rf(addr, blocks)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// sendKeyCheckpoints provides a mock function with given fields: addr
+func (_m *mockDaemoner) sendKeyCheckpoints(addr string) error {
+	ret := _m.Called(addr)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(string) error); ok {
+		r0 = rf(addr)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // sendMessage provides a mock function with given fields: addr, msg
 func (_m *mockDaemoner) sendMessage(addr string, msg gnet.Message) error {
 	ret := _m.Called(addr, msg)
@@ -0,0 +1,44 @@
+package daemon
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/util/file"
+)
+
+// NodeKeyFilename is the file in the data directory where a generated node secret key is saved,
+// so that the node keeps the same identity across restarts and peers can pin its pubkey
+const NodeKeyFilename = "node.key"
+
+// loadOrCreateNodeSeckey loads the hex encoded node secret key saved in dir.
+// If the file does not exist, a new key is generated and saved
+func loadOrCreateNodeSeckey(dir string) (cipher.SecKey, error) {
+	fn := filepath.Join(dir, NodeKeyFilename)
+
+	b, err := ioutil.ReadFile(fn)
+	switch {
+	case err == nil:
+		sk, err := cipher.SecKeyFromHex(strings.TrimSpace(string(b)))
+		if err != nil {
+			return cipher.SecKey{}, fmt.Errorf("invalid node seckey in %s: %v", fn, err)
+		}
+		logger.WithField("path", fn).Info("Loaded node seckey")
+		return sk, nil
+	case os.IsNotExist(err):
+	default:
+		return cipher.SecKey{}, err
+	}
+
+	_, sk := cipher.GenerateKeyPair()
+	if err := file.SaveBinary(fn, []byte(sk.Hex()), 0600); err != nil {
+		return cipher.SecKey{}, fmt.Errorf("save node seckey failed: %v", err)
+	}
+	logger.WithField("path", fn).Info("Generated a new node seckey")
+
+	return sk, nil
+}
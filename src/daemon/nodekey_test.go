@@ -0,0 +1,35 @@
+package daemon
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadOrCreateNodeSeckey(t *testing.T) {
+	dir, err := ioutil.TempDir("", "nodekey")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	sk, err := loadOrCreateNodeSeckey(dir)
+	require.NoError(t, err)
+	require.False(t, sk.Null())
+
+	fi, err := os.Stat(filepath.Join(dir, NodeKeyFilename))
+	require.NoError(t, err)
+	require.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+
+	// The saved key is reloaded
+	sk2, err := loadOrCreateNodeSeckey(dir)
+	require.NoError(t, err)
+	require.Equal(t, sk, sk2)
+
+	// An invalid key is an error, not silently replaced
+	err = ioutil.WriteFile(filepath.Join(dir, NodeKeyFilename), []byte("foo"), 0600)
+	require.NoError(t, err)
+	_, err = loadOrCreateNodeSeckey(dir)
+	require.Error(t, err)
+}
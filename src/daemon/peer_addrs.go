@@ -0,0 +1,103 @@
+package daemon
+
+import (
+	"errors"
+	"net"
+	"strings"
+
+	"github.com/ness-network/ness/src/util/iputil"
+)
+
+// peerAddrsProtocolVersion is the minimum protocol version of peers that support GivePeerAddrsMessage
+const peerAddrsProtocolVersion int32 = 7
+
+const (
+	// PeerAddrFamilyIPv4 is the address family of a PeerAddr with a 4 byte IPv4 address
+	PeerAddrFamilyIPv4 uint8 = 4
+	// PeerAddrFamilyIPv6 is the address family of a PeerAddr with a 16 byte IPv6 address
+	PeerAddrFamilyIPv6 uint8 = 6
+	// PeerAddrFamilyOnion is the address family of a PeerAddr with a Tor v3 onion address,
+	// such as "xxx.onion", encoded as a string
+	PeerAddrFamilyOnion uint8 = 10
+)
+
+var (
+	// ErrPeerAddrInvalidIP the address is not an IP address
+	ErrPeerAddrInvalidIP = errors.New("PeerAddr IP is not an IP address")
+	// ErrPeerAddrUnknownFamily the address family of a PeerAddr is not known
+	ErrPeerAddrUnknownFamily = errors.New("PeerAddr has an unknown address family")
+	// ErrPeerAddrInvalidLength the IP of a PeerAddr does not have the length of its address family
+	ErrPeerAddrInvalidLength = errors.New("PeerAddr IP length does not match its address family")
+	// ErrPeerAddrInvalidOnion the IP of a PeerAddr of the onion family is not an onion address
+	ErrPeerAddrInvalidOnion = errors.New("PeerAddr IP is not an onion address")
+)
+
+// PeerAddr is a versioned representation of IP:Port that carries IPv4, IPv6 and onion addresses.
+// The address family determines the encoding of IP. Addresses of unknown families are skipped
+// by the receiver, so new families can be added without changing GivePeerAddrsMessage.
+type PeerAddr struct {
+	Family uint8
+	IP     []byte `enc:",maxlen=64"`
+	Port   uint16
+}
+
+// NewPeerAddr returns a PeerAddr from an ip:port or onion:port string.
+// IPv6 addresses must be enclosed in square brackets.
+func NewPeerAddr(addr string) (PeerAddr, error) {
+	ips, port, err := iputil.SplitAddr(addr)
+	if err != nil {
+		return PeerAddr{}, err
+	}
+
+	if iputil.IsOnion(ips) {
+		return PeerAddr{
+			Family: PeerAddrFamilyOnion,
+			IP:     []byte(strings.ToLower(ips)),
+			Port:   port,
+		}, nil
+	}
+
+	ip := net.ParseIP(ips)
+	if ip == nil {
+		return PeerAddr{}, ErrPeerAddrInvalidIP
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return PeerAddr{
+			Family: PeerAddrFamilyIPv4,
+			IP:     []byte(ip4),
+			Port:   port,
+		}, nil
+	}
+
+	return PeerAddr{
+		Family: PeerAddrFamilyIPv6,
+		IP:     []byte(ip.To16()),
+		Port:   port,
+	}, nil
+}
+
+// Addr returns PeerAddr as "ip:port" or "onion:port", with IPv6 addresses enclosed in square brackets
+func (pa PeerAddr) Addr() (string, error) {
+	var n int
+	switch pa.Family {
+	case PeerAddrFamilyIPv4:
+		n = net.IPv4len
+	case PeerAddrFamilyIPv6:
+		n = net.IPv6len
+	case PeerAddrFamilyOnion:
+		host := string(pa.IP)
+		if !iputil.IsOnion(host) {
+			return "", ErrPeerAddrInvalidOnion
+		}
+		return iputil.JoinAddr(strings.ToLower(host), pa.Port), nil
+	default:
+		return "", ErrPeerAddrUnknownFamily
+	}
+
+	if len(pa.IP) != n {
+		return "", ErrPeerAddrInvalidLength
+	}
+
+	return iputil.JoinAddr(net.IP(pa.IP).String(), pa.Port), nil
+}
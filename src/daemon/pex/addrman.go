@@ -0,0 +1,208 @@
+package pex
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+
+	"github.com/ness-network/ness/src/util/iputil"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/util/file"
+	"github.com/skycoin/skycoin/src/util/useragent"
+)
+
+const (
+	// peerCacheVersion is the version of the peer cache file format
+	peerCacheVersion = 1
+
+	// newBucketCount is the number of buckets in the new table, which holds the peers we have not connected to
+	newBucketCount = 1024
+	// triedBucketCount is the number of buckets in the tried table, which holds the peers we have connected to
+	triedBucketCount = 256
+	// bucketSize is the maximum number of peers in a bucket
+	bucketSize = 64
+	// newBucketsPerSourceGroup is the number of new buckets that the peers announced from one network group are spread over
+	newBucketsPerSourceGroup = 64
+	// triedBucketsPerGroup is the number of tried buckets that the peers of one network group are spread over
+	triedBucketsPerGroup = 8
+	// bucketKeySize is the size of the secret key that bucket placement is hashed with
+	bucketKeySize = 32
+)
+
+// hostGroup returns the network group of a host. Peers in the same network group are likely to be run by the same operator.
+// IPv4 addresses are grouped by their /16 prefix and IPv6 addresses by their /32 prefix.
+// Onion addresses are all in one group, since anyone can create any number of them.
+// host can also be an IPv6 /64 prefix, as returned by BanHost
+func hostGroup(host string) string {
+	if iputil.IsOnion(host) {
+		return "onion"
+	}
+
+	ip := net.ParseIP(host)
+	if _, ipnet, err := net.ParseCIDR(host); err == nil {
+		ip = ipnet.IP
+	}
+
+	if ip == nil {
+		return host
+	}
+
+	if ip.IsLoopback() {
+		return "local"
+	}
+
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(16, 32)).String() + "/16"
+	}
+
+	return ip.Mask(net.CIDRMask(32, 128)).String() + "/32"
+}
+
+// addrGroup returns the network group of an ip:port or onion:port address
+func addrGroup(addr string) string {
+	host, _, err := iputil.SplitAddr(addr)
+	if err != nil {
+		return addr
+	}
+	return hostGroup(host)
+}
+
+// newBucketKey returns a random key for bucket placement
+func newBucketKey() []byte {
+	return cipher.RandByte(bucketKeySize)
+}
+
+// hash hashes the data with the peerlist's bucket key
+func (pl *peerlist) hash(data ...string) uint64 {
+	h := sha256.New()
+	h.Write(pl.key) //nolint:errcheck
+	for _, d := range data {
+		h.Write([]byte(d)) //nolint:errcheck
+		h.Write([]byte{0}) //nolint:errcheck
+	}
+	return binary.BigEndian.Uint64(h.Sum(nil)[:8])
+}
+
+// newBucket returns the new table bucket of addr announced by source.
+// The peers announced by sources in one network group are spread over newBucketsPerSourceGroup buckets,
+// so that a single announcer can only fill a small part of the new table.
+// Addresses added locally are bucketed as if they announced themselves
+func (pl *peerlist) newBucket(addr, source string) int {
+	group := addrGroup(addr)
+	sourceGroup := group
+	if source != "" {
+		sourceGroup = hostGroup(source)
+	}
+
+	i := pl.hash(group, sourceGroup) % newBucketsPerSourceGroup
+	return int(pl.hash(sourceGroup, strconv.FormatUint(i, 10)) % newBucketCount)
+}
+
+// triedBucket returns the tried table bucket of addr.
+// The peers of one network group are spread over triedBucketsPerGroup buckets
+func (pl *peerlist) triedBucket(addr string) int {
+	i := pl.hash(addr) % triedBucketsPerGroup
+	return int(pl.hash(addrGroup(addr), strconv.FormatUint(i, 10)) % triedBucketCount)
+}
+
+// ErrUnsupportedPeerCacheVersion is returned when the peer cache file was saved in an unknown format, e.g. by a newer version
+type ErrUnsupportedPeerCacheVersion struct {
+	Version int
+	Path    string
+}
+
+func (e ErrUnsupportedPeerCacheVersion) Error() string {
+	return fmt.Sprintf("Unsupported peer cache version %d in %s", e.Version, e.Path)
+}
+
+// peerCacheJSON is the versioned peer cache file saved in the data directory
+type peerCacheJSON struct {
+	Version int `json:"version"`
+	// Hex encoded bucket key, so that peers are placed in the same buckets after a restart
+	Key   string           `json:"key"`
+	Peers []cachedPeerJSON `json:"peers"`
+}
+
+// cachedPeerJSON is a peer in the peer cache file
+type cachedPeerJSON struct {
+	Addr            string         `json:"addr"`
+	LastSeen        int64          `json:"last_seen"`
+	Trusted         bool           `json:"trusted"`
+	HasIncomingPort bool           `json:"has_incoming_port"`
+	UserAgent       useragent.Data `json:"user_agent"`
+	// Host of the peer that announced the address, empty if it was added locally
+	Source string `json:"source,omitempty"`
+	// Whether the peer is in the tried table
+	Tried bool `json:"tried"`
+}
+
+// loadPeerCacheFile loads the versioned peer cache file. Returns nil if the file does not exist
+func loadPeerCacheFile(path string) (*peerCacheJSON, error) {
+	var pc peerCacheJSON
+	err := file.LoadJSON(path, &pc)
+
+	if os.IsNotExist(err) {
+		logger.WithField("path", path).Info("File does not exist")
+		return nil, nil
+	} else if err == io.EOF {
+		logger.WithField("path", path).Error("Corrupt or empty file")
+		return nil, nil
+	}
+
+	if err != nil {
+		logger.WithField("path", path).WithError(err).Error("Failed to load peer cache file")
+		return nil, err
+	}
+
+	if pc.Version != peerCacheVersion {
+		return nil, ErrUnsupportedPeerCacheVersion{
+			Version: pc.Version,
+			Path:    path,
+		}
+	}
+
+	return &pc, nil
+}
+
+// newPeerCacheJSON returns the peer cache of the peerlist.
+// Peers that have been retried more than MaxPeerRetryTimes are omitted
+func newPeerCacheJSON(pl *peerlist) peerCacheJSON {
+	pc := peerCacheJSON{
+		Version: peerCacheVersion,
+		Key:     hex.EncodeToString(pl.key),
+		Peers:   make([]cachedPeerJSON, 0, len(pl.peers)),
+	}
+
+	for addr, p := range pl.peers {
+		if p.RetryTimes > MaxPeerRetryTimes {
+			continue
+		}
+
+		info := pl.addrs[addr]
+		pc.Peers = append(pc.Peers, cachedPeerJSON{
+			Addr:            p.Addr,
+			LastSeen:        p.LastSeen,
+			Trusted:         p.Trusted,
+			HasIncomingPort: p.HasIncomingPort,
+			UserAgent:       p.UserAgent,
+			Source:          info.source,
+			Tried:           info.tried,
+		})
+	}
+
+	// Tried peers come first, so that they are kept if the cache is loaded with a smaller peerlist size
+	sort.Slice(pc.Peers, func(i, j int) bool {
+		if pc.Peers[i].Tried != pc.Peers[j].Tried {
+			return pc.Peers[i].Tried
+		}
+		return pc.Peers[i].Addr < pc.Peers[j].Addr
+	})
+
+	return pc
+}
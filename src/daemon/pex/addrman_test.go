@@ -0,0 +1,393 @@
+package pex
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/util/file"
+)
+
+func TestAddrGroup(t *testing.T) {
+	tt := []struct {
+		addr  string
+		group string
+	}{
+		{"112.32.32.14:7200", "112.32.0.0/16"},
+		{"112.32.200.1:6000", "112.32.0.0/16"},
+		{"112.33.32.14:7200", "112.33.0.0/16"},
+		{"[2001:db8:1:2::1]:6000", "2001:db8::/32"},
+		{"[2001:db9::1]:6000", "2001:db9::/32"},
+		{"127.0.0.1:6000", "local"},
+		{"vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd.onion:6000", "onion"},
+		{"2vakhwh2rvmxwj6f3lspqrkkfcmqekdxd45j4srkzhlzbvsk7ztavoad.onion:6000", "onion"},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.addr, func(t *testing.T) {
+			require.Equal(t, tc.group, addrGroup(tc.addr))
+		})
+	}
+
+	// Source hosts are grouped the same way
+	require.Equal(t, "112.32.0.0/16", hostGroup("112.32.32.14"))
+	require.Equal(t, "2001:db8::/32", hostGroup("2001:db8:1:2::/64"))
+}
+
+func TestPeerlistNewBuckets(t *testing.T) {
+	pl := newPeerlist()
+	source := "5.6.7.8"
+
+	// The peers announced by one source are spread over a limited number of buckets
+	var addrs []string
+	for i := 0; i < 200; i++ {
+		for j := 0; j < 50; j++ {
+			addrs = append(addrs, fmt.Sprintf("%d.%d.1.1:6000", i+1, j+1))
+		}
+	}
+	require.Equal(t, len(addrs), pl.addPeersFromSource(addrs, source, 0))
+	n := pl.len()
+	require.True(t, n <= newBucketsPerSourceGroup*bucketSize)
+	require.Equal(t, n, pl.sources[source])
+
+	buckets := make(map[int]struct{})
+	for _, info := range pl.addrs {
+		require.False(t, info.tried)
+		require.Equal(t, source, info.source)
+		buckets[info.bucket] = struct{}{}
+	}
+	require.True(t, len(buckets) <= newBucketsPerSourceGroup)
+
+	// Peers announced by a source in another network group can still be added
+	require.True(t, pl.addPeerFromSource("220.1.1.1:6000", "9.9.9.9", 0))
+	require.True(t, pl.hasPeer("220.1.1.1:6000"))
+	require.Equal(t, 1, pl.sources["9.9.9.9"])
+}
+
+func TestPeerlistNewBucketEviction(t *testing.T) {
+	pl := newPeerlist()
+	source := "5.6.7.8"
+
+	// Peers of one network group announced by one source are placed in the same bucket
+	var addrs []string
+	for i := 0; i < bucketSize; i++ {
+		addrs = append(addrs, fmt.Sprintf("1.2.3.%d:6000", i+1))
+	}
+	require.Equal(t, bucketSize, pl.addPeersFromSource(addrs, source, 0))
+
+	b := pl.newBucket(addrs[0], source)
+	require.Len(t, pl.newBuckets[b], bucketSize)
+
+	// Adding to a full bucket evicts the untrusted peer seen least recently
+	require.NoError(t, pl.setTrusted(addrs[0], true))
+	pl.peers[addrs[0]].LastSeen = 1
+	pl.peers[addrs[1]].LastSeen = 2
+
+	require.True(t, pl.addPeerFromSource("1.2.4.1:6000", source, 0))
+	require.Len(t, pl.newBuckets[b], bucketSize)
+	require.Equal(t, bucketSize, pl.len())
+	require.True(t, pl.hasPeer(addrs[0]))
+	require.False(t, pl.hasPeer(addrs[1]))
+	require.Equal(t, bucketSize, pl.sources[source])
+
+	// A bucket full of trusted peers has no room
+	for addr := range pl.newBuckets[b] {
+		require.NoError(t, pl.setTrusted(addr, true))
+	}
+	require.False(t, pl.addPeerFromSource("1.2.5.1:6000", source, 0))
+	require.Equal(t, bucketSize, pl.len())
+}
+
+func TestPeerlistMaxPerSource(t *testing.T) {
+	pl := newPeerlist()
+
+	var addrs []string
+	for i := 0; i < 20; i++ {
+		addrs = append(addrs, fmt.Sprintf("%d.1.1.1:6000", i+1))
+	}
+
+	require.Equal(t, 10, pl.addPeersFromSource(addrs, "5.6.7.8", 10))
+	require.Equal(t, 10, pl.len())
+	require.Equal(t, 10, pl.sources["5.6.7.8"])
+
+	// Known peers are seen, but not counted again
+	require.Equal(t, 10, pl.addPeersFromSource(addrs, "5.6.7.8", 10))
+	require.Equal(t, 10, pl.len())
+
+	// Other sources and local additions are counted separately
+	require.Equal(t, 20, pl.addPeersFromSource(addrs, "5.6.7.9", 10))
+	require.Equal(t, 20, pl.len())
+	require.Equal(t, 10, pl.sources["5.6.7.9"])
+	require.True(t, pl.addPeer("100.1.1.1:6000"))
+
+	// Peers that move to the tried table or are removed no longer count
+	var tried, removed string
+	for addr, info := range pl.addrs {
+		if info.source != "5.6.7.8" {
+			continue
+		}
+		if tried == "" {
+			tried = addr
+		} else if removed == "" {
+			removed = addr
+		}
+	}
+	require.NoError(t, pl.markTried(tried))
+	pl.removePeer(removed)
+	require.Equal(t, 8, pl.sources["5.6.7.8"])
+	require.Equal(t, 2, pl.addPeersFromSource([]string{"200.1.1.1:6000", "201.1.1.1:6000", "202.1.1.1:6000"}, "5.6.7.8", 10))
+}
+
+func TestPeerlistMax(t *testing.T) {
+	pl := newPeerlist()
+	pl.max = 5
+
+	var addrs []string
+	for i := 0; i < 10; i++ {
+		addrs = append(addrs, fmt.Sprintf("%d.1.1.1:6000", i+1))
+	}
+
+	require.Equal(t, 5, pl.addPeersFromSource(addrs, "5.6.7.8", 0))
+	require.Equal(t, 5, pl.len())
+
+	// Known peers are seen once the peerlist is full, new peers are not added
+	require.Equal(t, 5, pl.addPeersFromSource(addrs, "5.6.7.9", 0))
+	require.False(t, pl.addPeer("100.1.1.1:6000"))
+	require.Equal(t, 5, pl.len())
+
+	// Peers moving between the tables are kept
+	for addr := range pl.peers {
+		require.NoError(t, pl.markTried(addr))
+	}
+	require.Equal(t, 5, pl.len())
+
+	// Removing a peer makes room for a new peer
+	pl.removePeer(addrs[0])
+	pl.removePeer(addrs[1])
+	require.True(t, pl.addPeer("100.1.1.1:6000"))
+
+	// The 3 known peers and 1 new peer are added
+	require.Equal(t, 4, pl.addPeersFromSource(addrs, "5.6.7.8", 0))
+	require.Equal(t, 5, pl.len())
+}
+
+func TestPeerlistMarkTried(t *testing.T) {
+	pl := newPeerlist()
+
+	require.Error(t, pl.markTried(testPeers[0]))
+
+	require.True(t, pl.addPeerFromSource(testPeers[0], "5.6.7.8", 0))
+	require.False(t, pl.isTried(testPeers[0]))
+	require.NoError(t, pl.markTried(testPeers[0]))
+	require.True(t, pl.isTried(testPeers[0]))
+	require.Empty(t, pl.sources)
+	require.Equal(t, "5.6.7.8", pl.addrs[testPeers[0]].source)
+
+	b := pl.triedBucket(testPeers[0])
+	require.Equal(t, b, pl.addrs[testPeers[0]].bucket)
+	require.Len(t, pl.triedBuckets[b], 1)
+
+	// Marking a tried peer again does nothing
+	require.NoError(t, pl.markTried(testPeers[0]))
+	require.Len(t, pl.triedBuckets[b], 1)
+
+	// Fill the peer's tried bucket
+	var addrs []string
+	for i := 0; len(addrs) < bucketSize; i++ {
+		addr := fmt.Sprintf("112.32.%d.%d:6000", i/250, i%250+1)
+		if addr == testPeers[0] || pl.triedBucket(addr) != b {
+			continue
+		}
+		addrs = append(addrs, addr)
+	}
+
+	for _, addr := range addrs[:bucketSize-1] {
+		require.True(t, pl.addPeer(addr))
+		require.NoError(t, pl.markTried(addr))
+	}
+	require.Len(t, pl.triedBuckets[b], bucketSize)
+
+	// Moving a peer to a full tried bucket moves the peer seen least recently back to the new table
+	pl.peers[testPeers[0]].LastSeen = 1
+	require.True(t, pl.addPeer(addrs[bucketSize-1]))
+	require.NoError(t, pl.markTried(addrs[bucketSize-1]))
+	require.Len(t, pl.triedBuckets[b], bucketSize)
+	require.True(t, pl.isTried(addrs[bucketSize-1]))
+	require.False(t, pl.isTried(testPeers[0]))
+	require.True(t, pl.hasPeer(testPeers[0]))
+	require.Equal(t, 1, pl.sources["5.6.7.8"])
+	require.Equal(t, bucketSize+1, pl.len())
+}
+
+func TestPeerlistRandomGroups(t *testing.T) {
+	pl := newPeerlist()
+	pl.setPeers([]Peer{
+		{Addr: "112.32.1.1:6000"},
+		{Addr: "112.32.1.2:6000"},
+		{Addr: "112.32.1.3:6000"},
+		{Addr: "113.32.1.1:6000"},
+		{Addr: "114.32.1.1:6000"},
+	})
+
+	groups := func(ps Peers) map[string]int {
+		g := make(map[string]int)
+		for _, p := range ps {
+			g[addrGroup(p.Addr)]++
+		}
+		return g
+	}
+
+	// Peers are picked from distinct network groups first
+	for i := 0; i < 20; i++ {
+		ps := pl.random(3, nil, nil)
+		require.Len(t, ps, 3)
+		require.Len(t, groups(ps), 3)
+	}
+
+	// Peers in the groups of the excluded addresses are picked last
+	for i := 0; i < 20; i++ {
+		ps := pl.random(2, nil, []string{"112.32.200.1:6000"})
+		require.Equal(t, map[string]int{
+			"113.32.0.0/16": 1,
+			"114.32.0.0/16": 1,
+		}, groups(ps))
+	}
+
+	// The remaining peers fill up the result
+	ps := pl.random(0, nil, []string{"112.32.200.1:6000"})
+	require.Len(t, ps, 5)
+	ps = pl.random(4, nil, nil)
+	require.Len(t, ps, 4)
+	require.Len(t, groups(ps), 3)
+
+	// Tried peers are picked alternately with new peers
+	require.NoError(t, pl.markTried("112.32.1.1:6000"))
+	for i := 0; i < 20; i++ {
+		ps := pl.random(1, nil, []string{"113.32.1.1:6000", "114.32.1.1:6000"})
+		require.Equal(t, []string{"112.32.1.1:6000"}, ps.ToAddrs())
+	}
+}
+
+func TestPeerlistSaveLoadCache(t *testing.T) {
+	pl := newPeerlist()
+	require.True(t, pl.addPeer(testPeers[0]))
+	require.True(t, pl.addPeerFromSource(testPeers[1], "5.6.7.8", 0))
+	require.True(t, pl.addPeerFromSource(testPeers[2], "2001:db8::/64", 0))
+	require.True(t, pl.addPeerFromSource(testPeers[3], "5.6.7.8", 0))
+	require.NoError(t, pl.markTried(testPeers[3]))
+	require.NoError(t, pl.setHasIncomingPort(testPeers[3], true))
+
+	f, removeFile := preparePeerlistFile(t)
+	defer removeFile()
+	require.NoError(t, pl.save(f))
+
+	pc, err := loadPeerCacheFile(f)
+	require.NoError(t, err)
+	require.Equal(t, peerCacheVersion, pc.Version)
+	require.Len(t, pc.Peers, 4)
+	require.Equal(t, testPeers[3], pc.Peers[0].Addr)
+	require.True(t, pc.Peers[0].Tried)
+
+	loaded := newPeerlist()
+	loaded.setPeerCache(pc, false, 0)
+	require.Equal(t, pl.key, loaded.key)
+	require.Equal(t, pl.sources, loaded.sources)
+	require.Len(t, loaded.peers, 4)
+	for addr, p := range pl.peers {
+		require.Equal(t, *p, *loaded.peers[addr])
+		require.Equal(t, *pl.addrs[addr], *loaded.addrs[addr])
+	}
+
+	// Loading is limited to max peers, keeping tried peers first
+	loaded = newPeerlist()
+	loaded.setPeerCache(pc, false, 1)
+	require.Len(t, loaded.peers, 1)
+	require.True(t, loaded.isTried(testPeers[3]))
+
+	// An invalid key places the peers with a new key
+	pc.Key = "abcd"
+	loaded = newPeerlist()
+	key := loaded.key
+	loaded.setPeerCache(pc, false, 0)
+	require.Equal(t, key, loaded.key)
+	require.Len(t, loaded.peers, 4)
+
+	// Unknown versions are not loaded
+	pc.Version = peerCacheVersion + 1
+	require.NoError(t, file.SaveJSON(f, pc, 0600))
+	_, err = loadPeerCacheFile(f)
+	require.Equal(t, ErrUnsupportedPeerCacheVersion{
+		Version: peerCacheVersion + 1,
+		Path:    f,
+	}, err)
+}
+
+func TestPexLoadLegacyPeerCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peerlist")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	persistPeers(t, filepath.Join(dir, legacyPeerCacheFilename), testPeers)
+
+	cfg := NewConfig()
+	cfg.DataDirectory = dir
+	cfg.DefaultConnections = nil
+
+	_, err = New(cfg)
+	require.NoError(t, err)
+
+	// The peers of peers.json are migrated to the new table of addrbook.json
+	pc, err := loadPeerCacheFile(filepath.Join(dir, PeerCacheFilename))
+	require.NoError(t, err)
+	require.NotNil(t, pc)
+	require.Len(t, pc.Peers, len(testPeers))
+	for _, p := range pc.Peers {
+		require.False(t, p.Tried)
+		require.Empty(t, p.Source)
+	}
+
+	// addrbook.json takes precedence over peers.json
+	persistPeers(t, filepath.Join(dir, legacyPeerCacheFilename), []string{"11.22.33.44:6000"})
+	px, err := New(cfg)
+	require.NoError(t, err)
+	require.Equal(t, len(testPeers), px.peerlist.len())
+	_, ok := px.GetPeer("11.22.33.44:6000")
+	require.False(t, ok)
+
+	// An addrbook.json of an unknown version is not a startup error, the node starts with an empty peerlist
+	pc.Version = peerCacheVersion + 1
+	require.NoError(t, file.SaveJSON(filepath.Join(dir, PeerCacheFilename), pc, 0600))
+	px, err = New(cfg)
+	require.NoError(t, err)
+	require.Equal(t, 0, px.peerlist.len())
+}
+
+func TestPexAddPeersSource(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peerlist")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	cfg := NewConfig()
+	cfg.DataDirectory = dir
+	cfg.DefaultConnections = nil
+	cfg.MaxPeersPerSource = 2
+
+	px, err := New(cfg)
+	require.NoError(t, err)
+
+	require.Equal(t, 0, px.AddPeers(testPeers, "example.com:6000"))
+	require.Equal(t, 2, px.AddPeers(testPeers, "5.6.7.8:6000"))
+	require.Equal(t, 2, px.peerlist.len())
+
+	// The limit applies to the announcer's host, regardless of its port
+	require.Equal(t, 2, px.AddPeers(testPeers, "5.6.7.8:7000"))
+	require.Equal(t, 2, px.peerlist.len())
+
+	// Peers added locally are not limited
+	require.Equal(t, len(testPeers), px.AddPeers(testPeers, ""))
+	require.Equal(t, len(testPeers), px.peerlist.len())
+}
@@ -0,0 +1,295 @@
+package pex
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/ness-network/ness/src/util/iputil"
+	"github.com/skycoin/skycoin/src/util/file"
+)
+
+// BanCacheFilename filename for the disk-cached ban list, saved next to the peers file
+const BanCacheFilename = "bans.json"
+
+var (
+	// ErrNotBanned is returned when removing a ban of a host that is not banned
+	ErrNotBanned = errors.New("Address is not banned")
+	// ErrInvalidBanDuration is returned when banning a host for a duration that is not positive
+	ErrInvalidBanDuration = errors.New("Ban duration must be positive")
+)
+
+// Ban is a banned host. Peers with the host are not added to the peer list and are not connected to
+type Ban struct {
+	// IP address, IPv6 /64 prefix or onion address
+	Host string `json:"host"`
+	// Reason for the ban
+	Reason string `json:"reason"`
+	// Unix timestamp when the ban was created
+	Created int64 `json:"created"`
+	// Unix timestamp when the ban expires
+	Expires int64 `json:"expires"`
+}
+
+// Expired returns true if the ban has expired at time t
+func (b Ban) Expired(t time.Time) bool {
+	return b.Expires <= t.Unix()
+}
+
+// BanHost returns the host that is banned for addr. addr is an ip:port, an onion:port,
+// an IP address, an onion address or an IPv6 /64 prefix.
+// IPv6 addresses are banned by their /64 prefix, which is how connections per IP are counted.
+func BanHost(addr string) (string, error) {
+	addr = strings.TrimSpace(addr)
+
+	host := addr
+	if _, ipnet, err := net.ParseCIDR(addr); err == nil {
+		ones, bits := ipnet.Mask.Size()
+		if bits != net.IPv6len*8 || ones != iputil.IPv6GroupPrefixLen || ipnet.IP.To4() != nil {
+			return "", ErrInvalidAddress
+		}
+		host = ipnet.IP.String()
+	} else if net.ParseIP(addr) == nil && !iputil.IsOnion(addr) {
+		h, _, err := iputil.SplitAddr(addr)
+		if err != nil {
+			return "", ErrInvalidAddress
+		}
+		host = h
+	}
+
+	if iputil.IsOnion(host) {
+		return strings.ToLower(host), nil
+	}
+
+	if net.ParseIP(host) == nil {
+		return "", ErrInvalidAddress
+	}
+
+	return iputil.IPGroup(host), nil
+}
+
+// banlist is a map of banned hosts to Bans
+type banlist struct {
+	bans map[string]Ban
+}
+
+func newBanlist() banlist {
+	return banlist{
+		bans: make(map[string]Ban),
+	}
+}
+
+// loadCachedBansFile loads bans from the cached bans.json file
+func loadCachedBansFile(path string) (map[string]Ban, error) {
+	var bans []Ban
+	err := file.LoadJSON(path, &bans)
+
+	if os.IsNotExist(err) {
+		logger.WithField("path", path).Info("File does not exist")
+		return nil, nil
+	} else if err == io.EOF {
+		logger.WithField("path", path).Error("Corrupt or empty file")
+		return nil, nil
+	}
+
+	if err != nil {
+		logger.WithField("path", path).WithError(err).Error("Failed to load bans file")
+		return nil, err
+	}
+
+	m := make(map[string]Ban, len(bans))
+	for _, b := range bans {
+		host, err := BanHost(b.Host)
+		if err != nil {
+			logger.WithError(err).WithFields(logrus.Fields{
+				"host": b.Host,
+				"path": path,
+			}).Error("Invalid host in bans JSON file")
+			continue
+		}
+
+		b.Host = host
+		m[host] = b
+	}
+
+	return m, nil
+}
+
+func (bl *banlist) setBans(bans map[string]Ban) {
+	for host, b := range bans {
+		bl.bans[host] = b
+	}
+}
+
+func (bl *banlist) ban(b Ban) {
+	bl.bans[b.Host] = b
+}
+
+func (bl *banlist) unban(host string) bool {
+	if _, ok := bl.bans[host]; !ok {
+		return false
+	}
+	delete(bl.bans, host)
+	return true
+}
+
+func (bl *banlist) isBanned(host string, now time.Time) bool {
+	b, ok := bl.bans[host]
+	return ok && !b.Expired(now)
+}
+
+// all returns the bans that have not expired, sorted by host
+func (bl *banlist) all(now time.Time) []Ban {
+	bans := make([]Ban, 0, len(bl.bans))
+	for _, b := range bl.bans {
+		if !b.Expired(now) {
+			bans = append(bans, b)
+		}
+	}
+
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].Host < bans[j].Host
+	})
+
+	return bans
+}
+
+// clearExpired removes the expired bans and returns the number of bans removed
+func (bl *banlist) clearExpired(now time.Time) int {
+	n := 0
+	for host, b := range bl.bans {
+		if b.Expired(now) {
+			delete(bl.bans, host)
+			n++
+		}
+	}
+	return n
+}
+
+func (bl *banlist) save(fn string) error {
+	if err := file.SaveJSON(fn, bl.all(time.Now()), 0600); err != nil {
+		return fmt.Errorf("save ban list failed: %s", err)
+	}
+	return nil
+}
+
+func (px *Pex) loadBans() error {
+	px.Lock()
+	defer px.Unlock()
+
+	fp := filepath.Join(px.Config.DataDirectory, BanCacheFilename)
+	bans, err := loadCachedBansFile(fp)
+	if err != nil {
+		return err
+	}
+
+	px.banlist.setBans(bans)
+	return nil
+}
+
+// saveBans persists the banlist. The caller must hold the lock
+func (px *Pex) saveBans() error {
+	fn := filepath.Join(px.Config.DataDirectory, BanCacheFilename)
+	return px.banlist.save(fn)
+}
+
+// Ban bans the host of addr for duration and removes the peers with that host from the peer list.
+// addr is an ip:port, an onion:port, an IP address, an onion address or an IPv6 /64 prefix.
+// Banning a host that is already banned replaces the ban.
+func (px *Pex) Ban(addr string, duration time.Duration, reason string) (Ban, error) {
+	if duration <= 0 {
+		return Ban{}, ErrInvalidBanDuration
+	}
+
+	host, err := BanHost(addr)
+	if err != nil {
+		return Ban{}, err
+	}
+
+	px.Lock()
+	defer px.Unlock()
+
+	now := time.Now().UTC()
+	b := Ban{
+		Host:    host,
+		Reason:  reason,
+		Created: now.Unix(),
+		Expires: now.Add(duration).Unix(),
+	}
+	px.banlist.ban(b)
+
+	for a := range px.peerlist.peers {
+		if h, err := BanHost(a); err == nil && h == host {
+			px.peerlist.removePeer(a)
+		}
+	}
+
+	logger.WithFields(logrus.Fields{
+		"host":     host,
+		"duration": duration,
+		"reason":   reason,
+	}).Info("Banned host")
+
+	return b, px.saveBans()
+}
+
+// Unban removes the ban of the host of addr. Returns ErrNotBanned if the host is not banned
+func (px *Pex) Unban(addr string) error {
+	host, err := BanHost(addr)
+	if err != nil {
+		return err
+	}
+
+	px.Lock()
+	defer px.Unlock()
+
+	if !px.banlist.unban(host) {
+		return ErrNotBanned
+	}
+
+	logger.WithField("host", host).Info("Unbanned host")
+
+	return px.saveBans()
+}
+
+// Bans returns the bans that have not expired, sorted by host
+func (px *Pex) Bans() []Ban {
+	px.RLock()
+	defer px.RUnlock()
+	return px.banlist.all(time.Now())
+}
+
+// IsBanned returns true if the host of addr is banned
+func (px *Pex) IsBanned(addr string) bool {
+	px.RLock()
+	defer px.RUnlock()
+	return px.isBanned(addr)
+}
+
+func (px *Pex) isBanned(addr string) bool {
+	host, err := BanHost(addr)
+	if err != nil {
+		return false
+	}
+	return px.banlist.isBanned(host, time.Now())
+}
+
+// clearExpiredBans removes the expired bans and saves the banlist if any were removed
+func (px *Pex) clearExpiredBans() error {
+	px.Lock()
+	defer px.Unlock()
+
+	if n := px.banlist.clearExpired(time.Now()); n == 0 {
+		return nil
+	}
+
+	return px.saveBans()
+}
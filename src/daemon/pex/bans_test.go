@@ -0,0 +1,190 @@
+package pex
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/util/file"
+)
+
+func TestBanHost(t *testing.T) {
+	onion := "vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd.onion"
+
+	cases := []struct {
+		addr string
+		host string
+		err  error
+	}{
+		{
+			addr: "112.32.32.14:6000",
+			host: "112.32.32.14",
+		},
+		{
+			addr: "112.32.32.14",
+			host: "112.32.32.14",
+		},
+		{
+			addr: " 112.32.32.14 ",
+			host: "112.32.32.14",
+		},
+		{
+			addr: "[2001:db8::1]:6000",
+			host: "2001:db8::/64",
+		},
+		{
+			addr: "2001:db8::ffff",
+			host: "2001:db8::/64",
+		},
+		{
+			addr: "2001:db8::/64",
+			host: "2001:db8::/64",
+		},
+		{
+			addr: "2001:db8::/48",
+			err:  ErrInvalidAddress,
+		},
+		{
+			addr: "112.32.32.0/24",
+			err:  ErrInvalidAddress,
+		},
+		{
+			addr: onion + ":6000",
+			host: onion,
+		},
+		{
+			addr: "VWW6YBAL4BD7SZMGNCYRUUCPGFKQAHZDDI37KTCEO3AH7NGMCOPNPYYD.ONION",
+			host: onion,
+		},
+		{
+			addr: "example.com:6000",
+			err:  ErrInvalidAddress,
+		},
+		{
+			addr: "",
+			err:  ErrInvalidAddress,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.addr, func(t *testing.T) {
+			host, err := BanHost(tc.addr)
+			if tc.err != nil {
+				require.Equal(t, tc.err, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.host, host)
+		})
+	}
+}
+
+func TestPexBan(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peerlist")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	config := NewConfig()
+	config.DataDirectory = dir
+	config.DefaultConnections = testPeers[:2]
+
+	pex, err := New(config)
+	require.NoError(t, err)
+
+	require.NoError(t, pex.AddPeer("121.121.121.121:6000"))
+	require.NoError(t, pex.AddPeer("121.121.121.121:7000"))
+	require.NoError(t, pex.AddPeer("[2001:db8::1]:6000"))
+
+	_, err = pex.Ban("121.121.121.121:6000", 0, "")
+	require.Equal(t, ErrInvalidBanDuration, err)
+
+	_, err = pex.Ban("example.com:6000", time.Hour, "")
+	require.Equal(t, ErrInvalidAddress, err)
+
+	// Banning a host removes all of its peers
+	b, err := pex.Ban("121.121.121.121:6000", time.Hour, "Invalid block headers")
+	require.NoError(t, err)
+	require.Equal(t, "121.121.121.121", b.Host)
+	require.Equal(t, "Invalid block headers", b.Reason)
+	require.Equal(t, b.Created+3600, b.Expires)
+
+	_, ok := pex.GetPeer("121.121.121.121:6000")
+	require.False(t, ok)
+	_, ok = pex.GetPeer("121.121.121.121:7000")
+	require.False(t, ok)
+
+	require.True(t, pex.IsBanned("121.121.121.121:8000"))
+	require.Equal(t, ErrBlacklistedAddress, pex.AddPeer("121.121.121.121:6000"))
+	require.Equal(t, 0, pex.AddPeers([]string{"121.121.121.121:6000"}, ""))
+
+	// IPv6 addresses are banned by their /64 prefix
+	_, err = pex.Ban("[2001:db8::2]:6000", time.Hour, "")
+	require.NoError(t, err)
+	_, ok = pex.GetPeer("[2001:db8::1]:6000")
+	require.False(t, ok)
+	require.True(t, pex.IsBanned("[2001:db8::ffff]:6000"))
+	require.False(t, pex.IsBanned("[2001:db8:0:1::1]:6000"))
+
+	// Banned default peers are removed and skipped when the pex is created
+	_, err = pex.Ban(testPeers[0], time.Hour, "")
+	require.NoError(t, err)
+
+	bans := pex.Bans()
+	require.Len(t, bans, 3)
+	require.Equal(t, "112.32.32.14", bans[0].Host)
+	require.Equal(t, "121.121.121.121", bans[1].Host)
+	require.Equal(t, "2001:db8::/64", bans[2].Host)
+
+	// Bans are persisted next to the peers file
+	pex, err = New(config)
+	require.NoError(t, err)
+	require.Equal(t, bans, pex.Bans())
+	require.Equal(t, []string{testPeers[1]}, pex.AllTrusted().ToAddrs())
+
+	require.Equal(t, ErrInvalidAddress, pex.Unban("example.com"))
+	require.NoError(t, pex.Unban("121.121.121.121"))
+	require.Equal(t, ErrNotBanned, pex.Unban("121.121.121.121"))
+	require.False(t, pex.IsBanned("121.121.121.121:6000"))
+	require.NoError(t, pex.AddPeer("121.121.121.121:6000"))
+
+	require.NoError(t, pex.Unban("2001:db8::/64"))
+
+	var saved []Ban
+	require.NoError(t, file.LoadJSON(filepath.Join(dir, BanCacheFilename), &saved))
+	require.Equal(t, bans[:1], saved)
+}
+
+func TestPexClearExpiredBans(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peerlist")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	now := time.Now().Unix()
+	pex := &Pex{
+		Config: Config{
+			DataDirectory: dir,
+		},
+		peerlist: newPeerlist(),
+		banlist:  newBanlist(),
+	}
+	pex.banlist.ban(Ban{Host: "112.32.32.14", Created: now - 20, Expires: now - 10})
+	pex.banlist.ban(Ban{Host: "112.32.32.15", Created: now - 20, Expires: now + 3600})
+
+	// Expired bans are not reported before they are cleared
+	require.False(t, pex.IsBanned("112.32.32.14:6000"))
+	require.True(t, pex.IsBanned("112.32.32.15:6000"))
+	require.Len(t, pex.Bans(), 1)
+
+	require.NoError(t, pex.clearExpiredBans())
+	require.Len(t, pex.banlist.bans, 1)
+
+	bans, err := loadCachedBansFile(filepath.Join(dir, BanCacheFilename))
+	require.NoError(t, err)
+	require.Len(t, bans, 1)
+	require.Equal(t, "112.32.32.15", bans["112.32.32.15"].Host)
+}
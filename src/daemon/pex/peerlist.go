@@ -1,6 +1,7 @@
 package pex
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -10,6 +11,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/ness-network/ness/src/util/iputil"
 	"github.com/skycoin/skycoin/src/util/file"
 	"github.com/skycoin/skycoin/src/util/useragent"
 )
@@ -26,21 +28,48 @@ func (ps Peers) ToAddrs() []string {
 	return addrs
 }
 
-// peerlist is a map of addresses to *PeerStates
+// peerlist is an address manager of the known peers.
+// Peers we have not connected to are kept in the new table, bucketed by the network groups
+// of the peer and of the source that announced it, so that one source can't fill the table.
+// Peers we have connected to are moved to the tried table, bucketed by their own network group
 type peerlist struct {
 	peers map[string]*Peer
+	// Table placement of each peer in peers
+	addrs map[string]*addrInfo
+	// Buckets of the new and tried tables, by bucket index
+	newBuckets   map[int]map[string]struct{}
+	triedBuckets map[int]map[string]struct{}
+	// Number of peers in the new table announced by each source host
+	sources map[string]int
+	// Maximum number of peers that new peers are added up to. 0 means no limit
+	max int
+	// Secret key that bucket placement is hashed with, so that other peers can't predict it
+	key []byte
+}
+
+// addrInfo is the table placement of a peer
+type addrInfo struct {
+	// Host of the peer that announced the address, empty if it was added locally
+	source string
+	tried  bool
+	bucket int
 }
 
 func newPeerlist() peerlist {
 	return peerlist{
-		peers: make(map[string]*Peer),
+		peers:        make(map[string]*Peer),
+		addrs:        make(map[string]*addrInfo),
+		newBuckets:   make(map[int]map[string]struct{}),
+		triedBuckets: make(map[int]map[string]struct{}),
+		sources:      make(map[string]int),
+		key:          newBucketKey(),
 	}
 }
 
 // Filter peers filter
 type Filter func(peer Peer) bool
 
-// loadCachedPeersFile loads peers from the cached peers.json file
+// loadCachedPeersFile loads peers from the peers.json file saved by older versions
 func loadCachedPeersFile(path string) (map[string]*Peer, error) {
 	peersJSON := make(map[string]PeerJSON)
 	err := file.LoadJSON(path, &peersJSON)
@@ -90,11 +119,214 @@ func loadCachedPeersFile(path string) (map[string]*Peer, error) {
 	return peers, nil
 }
 
+// setPeers adds peers to the new table, as if they were added locally
 func (pl *peerlist) setPeers(peers []Peer) {
 	for _, p := range peers {
-		np := p
-		pl.peers[p.Addr] = &np
+		if !pl.setPeer(p, "", false) {
+			logger.WithField("addr", p.Addr).Warning("No room for peer in its bucket")
+		}
+	}
+}
+
+// setPeer adds or replaces a peer in the new or tried table.
+// Returns false if there is no room for the peer
+func (pl *peerlist) setPeer(p Peer, source string, tried bool) bool {
+	pl.removePeer(p.Addr)
+
+	if tried && pl.insertTried(p.Addr, source) {
+		pl.peers[p.Addr] = &p
+		return true
+	}
+
+	if !pl.insertNew(p.Addr, source) {
+		return false
+	}
+
+	pl.peers[p.Addr] = &p
+	return true
+}
+
+// setPeerCache restores the bucket key and the peers of a peer cache file, up to max peers
+func (pl *peerlist) setPeerCache(pc *peerCacheJSON, allowLocalhost bool, max int) {
+	if key, err := hex.DecodeString(pc.Key); err != nil || len(key) != bucketKeySize {
+		logger.Error("Invalid bucket key in peer cache, peers will be placed in new buckets")
+	} else {
+		pl.key = key
+	}
+
+	for _, cp := range pc.Peers {
+		fields := logrus.Fields{
+			"addr":   cp.Addr,
+			"source": cp.Source,
+		}
+
+		addr, err := validateAddress(cp.Addr, allowLocalhost)
+		if err != nil {
+			logger.WithError(err).WithFields(fields).Error("Invalid peer address")
+			continue
+		}
+
+		source := cp.Source
+		if source != "" {
+			if source, err = BanHost(source); err != nil {
+				logger.WithError(err).WithFields(fields).Error("Invalid peer source")
+				continue
+			}
+		}
+
+		p := Peer{
+			Addr:            addr,
+			LastSeen:        cp.LastSeen,
+			Trusted:         cp.Trusted,
+			HasIncomingPort: cp.HasIncomingPort,
+			UserAgent:       cp.UserAgent,
+		}
+
+		if !pl.setPeer(p, source, cp.Tried) {
+			logger.WithFields(fields).Warning("No room for peer in its bucket")
+			continue
+		}
+
+		if max > 0 && len(pl.peers) >= max {
+			break
+		}
+	}
+}
+
+// insertNew places addr in its new table bucket. If the bucket is full,
+// the untrusted peer seen least recently is removed to make room.
+// Returns false if the bucket is full of trusted peers
+func (pl *peerlist) insertNew(addr, source string) bool {
+	b := pl.newBucket(addr, source)
+	bucket, ok := pl.newBuckets[b]
+	if !ok {
+		bucket = make(map[string]struct{})
+		pl.newBuckets[b] = bucket
+	}
+
+	if len(bucket) >= bucketSize {
+		oldest := pl.oldestUntrustedInBucket(bucket)
+		if oldest == "" {
+			return false
+		}
+		pl.removePeer(oldest)
+	}
+
+	bucket[addr] = struct{}{}
+	pl.addrs[addr] = &addrInfo{
+		source: source,
+		bucket: b,
+	}
+	if source != "" {
+		pl.sources[source]++
+	}
+
+	return true
+}
+
+// insertTried places addr in its tried table bucket. If the bucket is full,
+// the untrusted peer seen least recently is moved back to the new table to make room.
+// Returns false if the bucket is full of trusted peers
+func (pl *peerlist) insertTried(addr, source string) bool {
+	b := pl.triedBucket(addr)
+	bucket, ok := pl.triedBuckets[b]
+	if !ok {
+		bucket = make(map[string]struct{})
+		pl.triedBuckets[b] = bucket
+	}
+
+	if len(bucket) >= bucketSize {
+		oldest := pl.oldestUntrustedInBucket(bucket)
+		if oldest == "" {
+			return false
+		}
+
+		info := pl.addrs[oldest]
+		pl.unplace(oldest)
+		if !pl.insertNew(oldest, info.source) {
+			delete(pl.peers, oldest)
+		}
+	}
+
+	bucket[addr] = struct{}{}
+	pl.addrs[addr] = &addrInfo{
+		source: source,
+		tried:  true,
+		bucket: b,
+	}
+
+	return true
+}
+
+// unplace removes addr from its bucket, without removing the peer
+func (pl *peerlist) unplace(addr string) {
+	info, ok := pl.addrs[addr]
+	if !ok {
+		return
 	}
+
+	if info.tried {
+		delete(pl.triedBuckets[info.bucket], addr)
+	} else {
+		delete(pl.newBuckets[info.bucket], addr)
+		if info.source != "" {
+			pl.sources[info.source]--
+			if pl.sources[info.source] <= 0 {
+				delete(pl.sources, info.source)
+			}
+		}
+	}
+
+	delete(pl.addrs, addr)
+}
+
+// oldestUntrustedInBucket returns the address of the untrusted peer in the bucket that was seen least recently.
+// Returns an empty string if all of the peers are trusted
+func (pl *peerlist) oldestUntrustedInBucket(bucket map[string]struct{}) string {
+	var oldest *Peer
+	for addr := range bucket {
+		p := pl.peers[addr]
+		if p == nil || p.Trusted {
+			continue
+		}
+
+		if oldest == nil || p.LastSeen < oldest.LastSeen {
+			oldest = p
+		}
+	}
+
+	if oldest == nil {
+		return ""
+	}
+	return oldest.Addr
+}
+
+// markTried moves a peer to the tried table
+func (pl *peerlist) markTried(addr string) error {
+	p, ok := pl.peers[addr]
+	if !ok {
+		return fmt.Errorf("mark peer tried failed: %v does not exist in peer list", addr)
+	}
+
+	p.Seen()
+
+	info := pl.addrs[addr]
+	if info.tried {
+		return nil
+	}
+
+	pl.unplace(addr)
+	if !pl.insertTried(addr, info.source) && !pl.insertNew(addr, info.source) {
+		delete(pl.peers, addr)
+	}
+
+	return nil
+}
+
+// isTried returns true if the peer is in the tried table
+func (pl *peerlist) isTried(addr string) bool {
+	info, ok := pl.addrs[addr]
+	return ok && info.tried
 }
 
 func (pl *peerlist) hasPeer(addr string) bool {
@@ -102,20 +334,54 @@ func (pl *peerlist) hasPeer(addr string) bool {
 	return ok && p != nil
 }
 
-func (pl *peerlist) addPeer(addr string) {
+// addPeer adds a peer that was added locally to the new table.
+// Returns false if there is no room for the peer
+func (pl *peerlist) addPeer(addr string) bool {
+	return pl.addPeerFromSource(addr, "", 0)
+}
+
+// addPeerFromSource adds a peer announced by the source host to the new table.
+// Peers added locally have an empty source and are not limited by maxPerSource.
+// Returns false if the peerlist has max peers, if the source already announced maxPerSource peers
+// in the new table, or if there is no room for the peer
+func (pl *peerlist) addPeerFromSource(addr, source string, maxPerSource int) bool {
 	if p, ok := pl.peers[addr]; ok && p != nil {
 		p.Seen()
-		return
+		return true
+	}
+
+	if pl.max > 0 && len(pl.peers) >= pl.max {
+		return false
+	}
+
+	if source != "" && maxPerSource > 0 && pl.sources[source] >= maxPerSource {
+		return false
+	}
+
+	if !pl.insertNew(addr, source) {
+		return false
 	}
 
-	peer := NewPeer(addr)
-	pl.peers[addr] = peer
+	pl.peers[addr] = NewPeer(addr)
+	return true
 }
 
-func (pl *peerlist) addPeers(addrs []string) {
+// addPeers adds peers that were added locally to the new table.
+// Returns the number of peers that were added
+func (pl *peerlist) addPeers(addrs []string) int {
+	return pl.addPeersFromSource(addrs, "", 0)
+}
+
+// addPeersFromSource adds peers announced by the source host to the new table.
+// Returns the number of peers that were added
+func (pl *peerlist) addPeersFromSource(addrs []string, source string, maxPerSource int) int {
+	n := 0
 	for _, addr := range addrs {
-		pl.addPeer(addr)
+		if pl.addPeerFromSource(addr, source, maxPerSource) {
+			n++
+		}
 	}
+	return n
 }
 
 func (pl *peerlist) seen(addr string) {
@@ -172,11 +438,17 @@ func canTry(p Peer) bool {
 	return p.CanTry()
 }
 
+func isOnion(p Peer) bool {
+	host, _, err := iputil.SplitAddr(p.Addr)
+	return err == nil && iputil.IsOnion(host)
+}
+
 // isExchangeable filters exchangeable peers
 var isExchangeable = []Filter{hasIncomingPort}
 
 // removePeer removes peer
 func (pl *peerlist) removePeer(addr string) {
+	pl.unplace(addr)
 	delete(pl.peers, addr)
 }
 
@@ -239,44 +511,87 @@ func (pl *peerlist) clearOld(timeAgo time.Duration) {
 	for addr, peer := range pl.peers {
 		lastSeen := time.Unix(peer.LastSeen, 0)
 		if !peer.Trusted && t.Sub(lastSeen) > timeAgo {
-			delete(pl.peers, addr)
+			pl.removePeer(addr)
 		}
 	}
 }
 
 // Returns n random peers, or all of the peers, whichever is lower.
 // If count is 0, all of the peers are returned, shuffled.
-func (pl *peerlist) random(count int, flts []Filter) Peers {
-	keys := pl.getCanTryPeers(flts).ToAddrs()
-	if len(keys) == 0 {
+// Peers are picked alternately from the tried and new tables. Peers in network groups
+// that were not picked yet, and that are not the groups of the exclude addresses, are picked first
+func (pl *peerlist) random(count int, flts []Filter, exclude []string) Peers {
+	var tried, fresh Peers
+	for _, p := range pl.getCanTryPeers(flts) {
+		if pl.isTried(p.Addr) {
+			tried = append(tried, p)
+		} else {
+			fresh = append(fresh, p)
+		}
+	}
+
+	if len(tried)+len(fresh) == 0 {
 		return Peers{}
 	}
 
+	rand.Shuffle(len(tried), func(i, j int) {
+		tried[i], tried[j] = tried[j], tried[i]
+	})
+	rand.Shuffle(len(fresh), func(i, j int) {
+		fresh[i], fresh[j] = fresh[j], fresh[i]
+	})
+
+	candidates := make(Peers, 0, len(tried)+len(fresh))
+	for i := 0; i < len(tried) || i < len(fresh); i++ {
+		if i < len(tried) {
+			candidates = append(candidates, tried[i])
+		}
+		if i < len(fresh) {
+			candidates = append(candidates, fresh[i])
+		}
+	}
+
 	max := count
-	if max == 0 || max > len(keys) {
-		max = len(keys)
+	if max == 0 || max > len(candidates) {
+		max = len(candidates)
 	}
 
-	ps := make(Peers, max)
-	perm := rand.Perm(len(keys))
-	for i, j := range perm[:max] {
-		ps[i] = *pl.peers[keys[j]]
+	groups := make(map[string]struct{}, len(exclude)+max)
+	for _, addr := range exclude {
+		groups[addrGroup(addr)] = struct{}{}
 	}
-	return ps
-}
 
-// save saves known peers to disk as a newline delimited list of addresses to
-// <dir><PeerCacheFilename>
-func (pl *peerlist) save(fn string) error {
-	// filter the peers that has retrytime > MaxPeerRetryTimes
-	peers := make(map[string]PeerJSON)
-	for k, p := range pl.peers {
-		if p.RetryTimes <= MaxPeerRetryTimes {
-			peers[k] = newPeerJSON(*p)
+	// Pick one peer per network group, then fill up with the peers that were skipped
+	ps := make(Peers, 0, max)
+	var skipped Peers
+	for _, p := range candidates {
+		if len(ps) == max {
+			break
 		}
+
+		group := addrGroup(p.Addr)
+		if _, ok := groups[group]; ok {
+			skipped = append(skipped, p)
+			continue
+		}
+
+		groups[group] = struct{}{}
+		ps = append(ps, p)
+	}
+
+	for _, p := range skipped {
+		if len(ps) == max {
+			break
+		}
+		ps = append(ps, p)
 	}
 
-	if err := file.SaveJSON(fn, peers, 0600); err != nil {
+	return ps
+}
+
+// save saves known peers to disk in the versioned peer cache format
+func (pl *peerlist) save(fn string) error {
+	if err := file.SaveJSON(fn, newPeerCacheJSON(pl), 0600); err != nil {
 		return fmt.Errorf("save peer list failed: %s", err)
 	}
 	return nil
@@ -327,7 +642,7 @@ func (pl *peerlist) findOldestUntrustedPeer() *Peer {
 	return nil
 }
 
-// PeerJSON is for saving and loading peers to disk. Some fields are strange,
+// PeerJSON is for loading peers from the peers.json file saved by older versions. Some fields are strange,
 // to be backwards compatible due to variable name changes
 type PeerJSON struct {
 	Addr string // An address of the form ip:port
@@ -341,17 +656,6 @@ type PeerJSON struct {
 	UserAgent       useragent.Data
 }
 
-// newPeerJSON returns a PeerJSON from a Peer
-func newPeerJSON(p Peer) PeerJSON {
-	return PeerJSON{
-		Addr:            p.Addr,
-		LastSeen:        p.LastSeen,
-		Trusted:         p.Trusted,
-		HasIncomingPort: &p.HasIncomingPort,
-		UserAgent:       p.UserAgent,
-	}
-}
-
 // newPeerFromJSON converts a PeerJSON to a Peer
 func newPeerFromJSON(p PeerJSON) (*Peer, error) {
 	hasIncomingPort := false
@@ -496,8 +496,8 @@ func TestPeerlistSave(t *testing.T) {
 			defer removeFile()
 			require.NoError(t, pl.save(f))
 
-			psMap, err := loadCachedPeersFile(f)
-			require.NoError(t, err)
+			psMap := loadSavedPeers(t, f)
+			require.Len(t, psMap, len(tc.expect))
 			for k, v := range tc.expect {
 				p, ok := psMap[k]
 				require.True(t, ok)
@@ -581,7 +581,19 @@ func peersEqualWithSeenAllowedDiff(t *testing.T, expected Peer, actual Peer) {
 	require.Equal(t, expected, actual)
 }
 
-// preparePeerlistFile makes peers.json in temporary dir,
+// loadSavedPeers loads the peers of a peer cache file
+func loadSavedPeers(t *testing.T, path string) map[string]*Peer {
+	t.Helper()
+	pc, err := loadPeerCacheFile(path)
+	require.NoError(t, err)
+	require.NotNil(t, pc)
+
+	pl := newPeerlist()
+	pl.setPeerCache(pc, true, 0)
+	return pl.peers
+}
+
+// preparePeerlistFile makes addrbook.json in temporary dir,
 func preparePeerlistFile(t *testing.T) (string, func()) {
 	f, err := ioutil.TempFile("", PeerCacheFilename)
 	require.NoError(t, err)
@@ -12,7 +12,6 @@ import (
 	"os"
 	"path/filepath"
 	"regexp"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -20,6 +19,7 @@ import (
 	"github.com/cenkalti/backoff"
 	"github.com/sirupsen/logrus"
 
+	"github.com/ness-network/ness/src/util/iputil"
 	"github.com/skycoin/skycoin/src/util/logging"
 	"github.com/skycoin/skycoin/src/util/useragent"
 )
@@ -35,7 +35,10 @@ const (
 	// DefaultPeerListURL is the default URL to download remote peers list from, if enabled
 	DefaultPeerListURL = "https://downloads.skycoin.com/blockchain/peers.txt"
 	// PeerCacheFilename filename for disk-cached peers
-	PeerCacheFilename = "peers.json"
+	PeerCacheFilename = "addrbook.json"
+	// legacyPeerCacheFilename previous filename for disk-cached peers, without the new and tried tables.
+	// The cache loader will fall back onto this filename if it can't load addrbook.json
+	legacyPeerCacheFilename = "peers.json"
 	// oldPeerCacheFilename previous filename for disk-cached peers. The cache loader will fall back onto this filename if it can't load peers.json
 	oldPeerCacheFilename = "peers.txt"
 	// MaxPeerRetryTimes is the maximum number of times to retry a peer
@@ -65,15 +68,25 @@ var (
 	whitespaceFilter = regexp.MustCompile(`\s`)
 )
 
-// validateAddress returns a sanitized address if valid, otherwise an error
+// validateAddress returns a sanitized address if valid, otherwise an error.
+// IPv6 addresses must be enclosed in square brackets, e.g. [2001:db8::1]:6000,
+// and are returned in their canonical form.
+// Tor v3 onion addresses are accepted and returned in lower case.
 func validateAddress(ipPort string, allowLocalhost bool) (string, error) {
 	ipPort = whitespaceFilter.ReplaceAllString(ipPort, "")
-	pts := strings.Split(ipPort, ":")
-	if len(pts) != 2 {
+	host, port, err := iputil.SplitAddr(ipPort)
+	if err != nil {
 		return "", ErrInvalidAddress
 	}
 
-	ip := net.ParseIP(pts[0])
+	if iputil.IsOnion(host) {
+		if port < 1024 {
+			return "", ErrPortTooLow
+		}
+		return iputil.JoinAddr(strings.ToLower(host), port), nil
+	}
+
+	ip := net.ParseIP(host)
 	if ip == nil {
 		return "", ErrInvalidAddress
 	} else if ip.IsLoopback() {
@@ -84,21 +97,20 @@ func validateAddress(ipPort string, allowLocalhost bool) (string, error) {
 		return "", ErrNotExternalIP
 	}
 
-	port, err := strconv.ParseUint(pts[1], 10, 16)
-	if err != nil {
-		return "", ErrInvalidAddress
-	}
-
 	if port < 1024 {
 		return "", ErrPortTooLow
 	}
 
+	if ip.To4() == nil {
+		return iputil.JoinAddr(ip.String(), port), nil
+	}
+
 	return ipPort, nil
 }
 
 // Peer represents a known peer
 type Peer struct {
-	Addr            string         // An address of the form ip:port
+	Addr            string         // An address of the form ip:port or onion:port
 	LastSeen        int64          // Unix timestamp when this peer was last seen
 	Trusted         bool           // Whether this peer is trusted
 	HasIncomingPort bool           // Whether this peer has accessible public port
@@ -160,6 +172,8 @@ type Config struct {
 	DataDirectory string
 	// Maximum number of peers to keep account of in the PeerList
 	Max int
+	// Maximum number of peers in the new table announced by a single peer. 0 means no limit
+	MaxPeersPerSource int
 	// Cull peers after they havent been seen in this much time
 	Expiration time.Duration
 	// Cull expired peers on this interval
@@ -188,6 +202,9 @@ type Config struct {
 	CustomPeersFile string
 	// Default "trusted" connections
 	DefaultConnections []string
+	// Connect to onion peers. Requires outgoing connections through a Tor SOCKS5 proxy.
+	// Onion peers are kept in the peer list and exchanged with other peers either way
+	OnionPeers bool
 }
 
 // NewConfig creates default pex config.
@@ -195,6 +212,7 @@ func NewConfig() Config {
 	return Config{
 		DataDirectory:       "./",
 		Max:                 65535,
+		MaxPeersPerSource:   1024,
 		Expiration:          time.Hour * 24 * 7,
 		CullRate:            time.Minute * 10,
 		ClearOldRate:        time.Minute * 10,
@@ -216,9 +234,11 @@ type Pex struct {
 	sync.RWMutex
 	// All known peers
 	peerlist peerlist
-	Config   Config
-	quit     chan struct{}
-	done     chan struct{}
+	// Banned hosts
+	banlist banlist
+	Config  Config
+	quit    chan struct{}
+	done    chan struct{}
 }
 
 // New creates pex
@@ -226,9 +246,11 @@ func New(cfg Config) (*Pex, error) {
 	pex := &Pex{
 		Config:   cfg,
 		peerlist: newPeerlist(),
+		banlist:  newBanlist(),
 		quit:     make(chan struct{}),
 		done:     make(chan struct{}),
 	}
+	pex.peerlist.max = cfg.Max
 
 	// Load peers from disk
 	if err := pex.loadCache(); err != nil {
@@ -236,12 +258,23 @@ func New(cfg Config) (*Pex, error) {
 		return nil, err
 	}
 
+	// Load bans from disk
+	if err := pex.loadBans(); err != nil {
+		logger.Critical().WithError(err).Error("pex.loadBans failed")
+		return nil, err
+	}
+
 	// Unset trusted status from any existing peers, regenerate
 	// them from the DefaultConnections
 	pex.setAllUntrusted()
 
 	// Load default hardcoded peers, mark them as trusted
 	for _, addr := range cfg.DefaultConnections {
+		if pex.IsBanned(addr) {
+			logger.WithField("addr", addr).Warning("Default peer is banned, skipping")
+			continue
+		}
+
 		// Default peers will mark as trusted peers.
 		if err := pex.AddPeer(addr); err != nil {
 			logger.Critical().WithError(err).Error("Add default peer failed")
@@ -298,6 +331,7 @@ func (px *Pex) Run() error {
 	}()
 
 	clearOldTicker := time.NewTicker(px.Config.ClearOldRate)
+	clearBansTicker := time.NewTicker(px.Config.UpdateBlacklistRate)
 
 	for {
 		select {
@@ -310,6 +344,10 @@ func (px *Pex) Run() error {
 					px.peerlist.clearOld(px.Config.Expiration)
 				}()
 			}
+		case <-clearBansTicker.C:
+			if err := px.clearExpiredBans(); err != nil {
+				logger.WithError(err).Error("clearExpiredBans failed")
+			}
 		case <-px.quit:
 			return nil
 		}
@@ -334,7 +372,7 @@ func (px *Pex) downloadPeers() error {
 	peers := parseRemotePeerList(body)
 	logger.WithField("url", px.Config.PeerListURL).Infof("Downloaded peers list, got %d peers", len(peers))
 
-	n := px.AddPeers(peers)
+	n := px.AddPeers(peers, "")
 	logger.WithField("url", px.Config.PeerListURL).Infof("Added %d/%d peers from downloaded peers list", n, len(peers))
 
 	return nil
@@ -345,15 +383,35 @@ func (px *Pex) loadCache() error {
 	defer px.Unlock()
 
 	fp := filepath.Join(px.Config.DataDirectory, PeerCacheFilename)
-	peers, err := loadCachedPeersFile(fp)
+	pc, err := loadPeerCacheFile(fp)
+	switch err.(type) {
+	case nil:
+	case ErrUnsupportedPeerCacheVersion:
+		// The peers are rediscovered, so the node can start without them
+		logger.WithError(err).Error("Peer cache can't be loaded, starting with an empty peerlist")
+		return nil
+	default:
+		return err
+	}
+
+	if pc != nil {
+		px.peerlist.setPeerCache(pc, px.Config.AllowLocalhost, px.Config.Max)
+		return nil
+	}
+
+	// If the PeerCacheFilename addrbook.json file does not exist, try to load the legacy peers.json file.
+	// Its peers are added to the new table
+	logger.Infof("Peer cache %s not found, falling back on %s", PeerCacheFilename, legacyPeerCacheFilename)
 
+	fp = filepath.Join(px.Config.DataDirectory, legacyPeerCacheFilename)
+	peers, err := loadCachedPeersFile(fp)
 	if err != nil {
 		return err
 	}
 
-	// If the PeerCacheFilename peers.json file does not exist, try to load the old peers.txt file
+	// If the legacy peers.json file does not exist, try to load the old peers.txt file
 	if peers == nil {
-		logger.Infof("Peer cache %s not found, falling back on %s", PeerCacheFilename, oldPeerCacheFilename)
+		logger.Infof("Peer cache %s not found, falling back on %s", legacyPeerCacheFilename, oldPeerCacheFilename)
 
 		fp := filepath.Join(px.Config.DataDirectory, oldPeerCacheFilename)
 		peers, err = loadCachedPeersFile(fp)
@@ -434,6 +492,10 @@ func (px *Pex) AddPeer(addr string) error {
 		return ErrInvalidAddress
 	}
 
+	if px.isBanned(cleanAddr) {
+		return ErrBlacklistedAddress
+	}
+
 	if px.peerlist.hasPeer(cleanAddr) {
 		px.peerlist.seen(cleanAddr)
 		return nil
@@ -448,30 +510,43 @@ func (px *Pex) AddPeer(addr string) error {
 		px.peerlist.removePeer(oldestPeer.Addr)
 
 		if px.isFull() {
-			// This can happen if the node is run with a peers.json file that has more peers
-			// than the max peerlist size, then the peers.json file isn't truncated to the max peerlist size.
+			// This can happen if the node is run with a peer cache file that has more peers
+			// than the max peerlist size, then the peer cache file isn't truncated to the max peerlist size.
 			// It is not an error.
 			// The max is a soft limit; exceeding the max will not crash the program.
 			logger.Critical().Error("AddPeer: after removing the worst peer, the peerlist was still full")
 		}
 	}
 
-	px.peerlist.addPeer(cleanAddr)
+	if !px.peerlist.addPeer(cleanAddr) {
+		return ErrPeerlistFull
+	}
 	return nil
 }
 
 // AddPeers add multiple peers at once. Any errors will be logged, but not returned
 // Returns the number of peers that were added without error. Note that
 // adding a duplicate peer will not cause an error.
-func (px *Pex) AddPeers(addrs []string) int {
+// source is the address of the peer that announced the addresses, or empty if they were added locally.
+// A source can't add more than Config.MaxPeersPerSource peers to the new table
+func (px *Pex) AddPeers(addrs []string, source string) int {
 	px.Lock()
 	defer px.Unlock()
 
-	if px.Config.Max > 0 && px.peerlist.len() >= px.Config.Max {
+	if px.isFull() {
 		logger.Warning("Add peers failed, peer list is full")
 		return 0
 	}
 
+	if source != "" {
+		host, err := BanHost(source)
+		if err != nil {
+			logger.WithField("source", source).WithError(err).Info("Add peers sees an invalid source")
+			return 0
+		}
+		source = host
+	}
+
 	// validate the addresses
 	var validAddrs []string
 	for _, addr := range addrs {
@@ -480,24 +555,42 @@ func (px *Pex) AddPeers(addrs []string) int {
 			logger.WithField("addr", addr).WithError(err).Info("Add peers sees an invalid address")
 			continue
 		}
+		if px.isBanned(a) {
+			logger.WithField("addr", a).Debug("Add peers sees a banned address")
+			continue
+		}
 		validAddrs = append(validAddrs, a)
 	}
 	addrs = validAddrs
 
-	// Shuffle the addresses before capping them
+	// Shuffle the addresses, so that the addresses kept when the peerlist, the source or a bucket is full are random
 	rand.Shuffle(len(addrs), func(i, j int) {
 		addrs[i], addrs[j] = addrs[j], addrs[i]
 	})
 
-	if px.Config.Max > 0 {
-		rcap := px.Config.Max - px.peerlist.len()
-		if len(addrs) > rcap {
-			addrs = addrs[:rcap]
-		}
+	n := px.peerlist.addPeersFromSource(addrs, source, px.Config.MaxPeersPerSource)
+	if n < len(addrs) {
+		logger.WithFields(logrus.Fields{
+			"source": source,
+			"added":  n,
+			"count":  len(addrs),
+		}).Debug("Add peers could not add all peers of the source")
+	}
+	return n
+}
+
+// MarkTried moves a peer that we connected to successfully to the tried table
+func (px *Pex) MarkTried(addr string) error {
+	px.Lock()
+	defer px.Unlock()
+
+	cleanAddr, err := validateAddress(addr, px.Config.AllowLocalhost)
+	if err != nil {
+		logger.WithError(err).WithField("addr", addr).Error("Invalid address")
+		return ErrInvalidAddress
 	}
 
-	px.peerlist.addPeers(addrs)
-	return len(addrs)
+	return px.peerlist.markTried(cleanAddr)
 }
 
 // setTrusted marks a peer as a default peer by setting its trusted flag to true
@@ -581,23 +674,29 @@ func (px *Pex) AllTrusted() Peers {
 func (px *Pex) Trusted() Peers {
 	px.RLock()
 	defer px.RUnlock()
-	return px.peerlist.getCanTryPeers([]Filter{isTrusted})
+	return px.peerlist.getCanTryPeers([]Filter{isTrusted, px.canDial})
 }
 
-// Random returns N random untrusted peers
-func (px *Pex) Random(n int) Peers {
+// Random returns N random untrusted peers. Peers in network groups other than
+// those of the connected addresses, and of the other returned peers, are preferred
+func (px *Pex) Random(n int, connected []string) Peers {
 	px.RLock()
 	defer px.RUnlock()
 	return px.peerlist.random(n, []Filter{func(p Peer) bool {
 		return !p.Trusted
-	}})
+	}, px.canDial}, connected)
+}
+
+// canDial returns false for onion peers, unless Config.OnionPeers is set
+func (px *Pex) canDial(p Peer) bool {
+	return px.Config.OnionPeers || !isOnion(p)
 }
 
 // RandomExchangeable returns N random exchangeable peers
 func (px *Pex) RandomExchangeable(n int) Peers {
 	px.RLock()
 	defer px.RUnlock()
-	return px.peerlist.random(n, isExchangeable)
+	return px.peerlist.random(n, isExchangeable, nil)
 }
 
 // IncreaseRetryTimes increases retry times
@@ -680,8 +779,8 @@ func backoffDownloadText(url string) (string, error) {
 // The peers list format is newline separated list of ip:port strings
 // Any lines that don't parse to an ip:port are skipped, otherwise they return an error
 // Localhost ip:port addresses are ignored
-// NOTE: this does not parse the cached peers.json file in the data directory, which is a JSON file
-// and is loaded by loadCachedPeersFile
+// NOTE: this does not parse the cached addrbook.json file in the data directory, which is a JSON file
+// and is loaded by loadPeerCacheFile
 func parseRemotePeerList(body string) []string {
 	var peers []string
 	for _, addr := range strings.Split(body, "\n") {
@@ -710,8 +809,8 @@ func parseRemotePeerList(body string) []string {
 // Otherwise, the line is parsed as an ip:port
 // If the line fails to parse, an error is returned
 // Localhost addresses are allowed if allowLocalhost is true
-// NOTE: this does not parse the cached peers.json file in the data directory, which is a JSON file
-// and is loaded by loadCachedPeersFile
+// NOTE: this does not parse the cached addrbook.json file in the data directory, which is a JSON file
+// and is loaded by loadPeerCacheFile
 func parseLocalPeerList(body string, allowLocalhost bool) ([]string, error) {
 	var peers []string
 	for _, addr := range strings.Split(body, "\n") {
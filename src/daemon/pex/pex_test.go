@@ -140,6 +140,63 @@ func TestValidateAddress(t *testing.T) {
 			allowLocalhost: false,
 			cleanAddr:      "11.22.33.44:8080",
 		},
+		{
+			addr:           "[2001:db8:85a3::8a2e:370:7334]:6000",
+			allowLocalhost: false,
+		},
+		{
+			addr:           "[2001:0DB8:85A3:0000:0000:8A2E:0370:7334]:6000",
+			allowLocalhost: false,
+			cleanAddr:      "[2001:db8:85a3::8a2e:370:7334]:6000",
+		},
+		{
+			addr:           "2001:db8:85a3::8a2e:370:7334:6000",
+			allowLocalhost: false,
+			err:            ErrInvalidAddress,
+		},
+		{
+			addr:           "[::1]:6000",
+			allowLocalhost: true,
+		},
+		{
+			addr:           "[::1]:6000",
+			allowLocalhost: false,
+			err:            ErrNoLocalhost,
+		},
+		{
+			addr:           "[fe80::1]:6000",
+			allowLocalhost: false,
+			err:            ErrNotExternalIP,
+		},
+		{
+			addr:           "[2001:db8::1]:80",
+			allowLocalhost: false,
+			err:            ErrPortTooLow,
+		},
+		{
+			addr:           "vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd.onion:6000",
+			allowLocalhost: false,
+		},
+		{
+			addr:           "VWW6YBAL4BD7SZMGNCYRUUCPGFKQAHZDDI37KTCEO3AH7NGMCOPNPYYD.onion:6000",
+			allowLocalhost: false,
+			cleanAddr:      "vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd.onion:6000",
+		},
+		{
+			addr:           "vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd.onion:80",
+			allowLocalhost: false,
+			err:            ErrPortTooLow,
+		},
+		{
+			addr:           "expyuzz4wqqyqhjn.onion:6000",
+			allowLocalhost: false,
+			err:            ErrInvalidAddress,
+		},
+		{
+			addr:           "example.com:6000",
+			allowLocalhost: false,
+			err:            ErrInvalidAddress,
+		},
 	}
 
 	for _, tc := range cases {
@@ -180,8 +237,7 @@ func TestNewPex(t *testing.T) {
 	require.NoError(t, err)
 
 	// check if peers are saved to disk
-	peers, err := loadCachedPeersFile(filepath.Join(dir, PeerCacheFilename))
-	require.NoError(t, err)
+	peers := loadSavedPeers(t, filepath.Join(dir, PeerCacheFilename))
 
 	require.Equal(t, len(testPeers)+1, len(peers))
 
@@ -196,8 +252,7 @@ func TestNewPex(t *testing.T) {
 	_, err = New(config)
 	require.NoError(t, err)
 
-	peers, err = loadCachedPeersFile(filepath.Join(dir, PeerCacheFilename))
-	require.NoError(t, err)
+	peers = loadSavedPeers(t, filepath.Join(dir, PeerCacheFilename))
 
 	require.Equal(t, len(testPeers)+1, len(peers))
 
@@ -226,8 +281,7 @@ func TestNewPexDisableTrustedPeers(t *testing.T) {
 	require.NoError(t, err)
 
 	// check if peers are saved to disk
-	peers, err := loadCachedPeersFile(filepath.Join(dir, PeerCacheFilename))
-	require.NoError(t, err)
+	peers := loadSavedPeers(t, filepath.Join(dir, PeerCacheFilename))
 
 	for _, p := range testPeers {
 		v, ok := peers[p]
@@ -262,8 +316,7 @@ func TestNewPexLoadCustomPeers(t *testing.T) {
 	require.NoError(t, err)
 
 	// check if peers are saved to disk
-	peers, err := loadCachedPeersFile(filepath.Join(dir, PeerCacheFilename))
-	require.NoError(t, err)
+	peers := loadSavedPeers(t, filepath.Join(dir, PeerCacheFilename))
 
 	expectedPeers := []string{
 		"123.45.67.89:2020",
@@ -290,7 +343,7 @@ func TestPexLoadPeers(t *testing.T) {
 	}{
 		{
 			name:     "load all",
-			filename: PeerCacheFilename,
+			filename: legacyPeerCacheFilename,
 			peers: []Peer{
 				Peer{Addr: testPeers[0]},
 				Peer{Addr: testPeers[1]},
@@ -304,7 +357,7 @@ func TestPexLoadPeers(t *testing.T) {
 		},
 		{
 			name:     "reach max",
-			filename: PeerCacheFilename,
+			filename: legacyPeerCacheFilename,
 			peers: []Peer{
 				Peer{Addr: testPeers[0]},
 				Peer{Addr: testPeers[1]},
@@ -320,7 +373,7 @@ func TestPexLoadPeers(t *testing.T) {
 		},
 		{
 			name:     "including invalid addr",
-			filename: PeerCacheFilename,
+			filename: legacyPeerCacheFilename,
 			peers: []Peer{
 				Peer{Addr: wrongPortPeer},
 				Peer{Addr: testPeers[1]},
@@ -587,7 +640,7 @@ func TestPexAddPeers(t *testing.T) {
 			2,
 			testPeers[1:3],
 			1,
-			testPeers[1:2],
+			nil,
 		},
 		{
 			"already full",
@@ -623,8 +676,9 @@ func TestPexAddPeers(t *testing.T) {
 			px, err := New(cfg)
 			require.NoError(t, err)
 
-			n := px.AddPeers(tc.addPeers)
+			n := px.AddPeers(tc.addPeers, "")
 			require.Equal(t, tc.addN, n)
+			require.True(t, px.peerlist.len() <= tc.max)
 
 			for _, p := range tc.expectPeers {
 				_, ok := px.peerlist.peers[p]
@@ -921,7 +975,7 @@ func TestPexRandomPublic(t *testing.T) {
 			pex.peerlist.setPeers(tc.peers)
 
 			// get N random public
-			peers := pex.Random(tc.n)
+			peers := pex.Random(tc.n, nil)
 
 			require.Len(t, peers, tc.expectN)
 
@@ -941,6 +995,29 @@ func TestPexRandomPublic(t *testing.T) {
 	}
 }
 
+func TestPexOnionPeers(t *testing.T) {
+	onion := "vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd.onion:6000"
+	peers := []Peer{
+		Peer{Addr: testPeers[0]},
+		Peer{Addr: onion},
+		Peer{Addr: testPeers[1], Trusted: true},
+		Peer{Addr: "2vakhwh2rvmxwj6f3lspqrkkfcmqekdxd45j4srkzhlzbvsk7ztavoad.onion:6000", Trusted: true},
+	}
+
+	pex := &Pex{
+		peerlist: newPeerlist(),
+	}
+	pex.peerlist.setPeers(peers)
+
+	// Onion peers are not dialed without a proxy
+	require.Equal(t, []string{testPeers[0]}, pex.Random(10, nil).ToAddrs())
+	require.Equal(t, []string{testPeers[1]}, pex.Trusted().ToAddrs())
+
+	pex.Config.OnionPeers = true
+	require.Len(t, pex.Random(10, nil), 2)
+	require.Len(t, pex.Trusted(), 2)
+}
+
 func TestPexAllTrusted(t *testing.T) {
 	tt := []struct {
 		name   string
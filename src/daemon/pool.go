@@ -3,7 +3,9 @@ package daemon
 import (
 	"time"
 
-	"github.com/skycoin/skycoin/src/daemon/gnet"
+	"github.com/skycoin/skycoin/src/cipher"
+
+	"github.com/ness-network/ness/src/daemon/gnet"
 )
 
 // PoolConfig pool config
@@ -36,9 +38,17 @@ type PoolConfig struct {
 	MaxIncomingMessageLength int
 	// Maximum length of outgoing messages in bytes
 	MaxOutgoingMessageLength int
+	// How long to wait for a peer to answer an encrypted session handshake
+	SecureHandshakeTimeout time.Duration
+	// Address of a SOCKS5 proxy, such as Tor, for all outgoing connections
+	ProxyAddress string
+	// Username and password for the SOCKS5 proxy, if it requires authentication
+	ProxyUsername string
+	ProxyPassword string
 	// These should be assigned by the controlling daemon
-	address string
-	port    int
+	address    string
+	port       int
+	nodeSeckey cipher.SecKey
 }
 
 // NewPoolConfig creates pool config
@@ -59,6 +69,7 @@ func NewPoolConfig() PoolConfig {
 		MaxDefaultPeerOutgoingConnections: 2,
 		MaxOutgoingMessageLength:          256 * 1024,
 		MaxIncomingMessageLength:          1024 * 1024,
+		SecureHandshakeTimeout:            time.Second * 30,
 	}
 }
 
@@ -84,6 +95,11 @@ func NewPool(cfg PoolConfig, d *Daemon) (*Pool, error) {
 	gnetCfg.DefaultConnections = cfg.DefaultConnections
 	gnetCfg.MaxIncomingMessageLength = cfg.MaxIncomingMessageLength
 	gnetCfg.MaxOutgoingMessageLength = cfg.MaxOutgoingMessageLength
+	gnetCfg.NodeSecKey = cfg.nodeSeckey
+	gnetCfg.SecureHandshakeTimeout = cfg.SecureHandshakeTimeout
+	gnetCfg.ProxyAddress = cfg.ProxyAddress
+	gnetCfg.ProxyUsername = cfg.ProxyUsername
+	gnetCfg.ProxyPassword = cfg.ProxyPassword
 
 	pool, err := gnet.NewConnectionPool(gnetCfg, d)
 	if err != nil {
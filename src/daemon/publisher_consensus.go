@@ -0,0 +1,309 @@
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ness-network/ness/src/consensus"
+	"github.com/ness-network/ness/src/daemon/gnet"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+// publisherConsensusProtocolVersion is the minimum protocol version of peers that support BlockCandidateMessage
+const publisherConsensusProtocolVersion int32 = 5
+
+var (
+	// errBlockCandidateMismatch is returned if the hash or seq of a block candidate does not match its block
+	errBlockCandidateMismatch = errors.New("Block candidate does not match its block")
+	// errBlockCandidateSigner is returned if a block candidate is not signed by a block publisher
+	errBlockCandidateSigner = errors.New("Block candidate is not signed by a block publisher")
+	// errBlockCandidateSigned is returned if this node already signed a block candidate with the same seq
+	errBlockCandidateSigned = errors.New("A block candidate with the same seq was already signed")
+	// errBlockCandidateEquivocation is returned if a block publisher signed another block candidate with the same seq
+	errBlockCandidateEquivocation = errors.New("Block publisher signed another block candidate with the same seq")
+	// errPublisherConsensusDisabled is returned if block candidates are received without configured block publishers
+	errPublisherConsensusDisabled = errors.New("Block publishers are not configured")
+)
+
+// publisherConnectionManager implements consensus.ConnectionManagerInterface on top of the daemon connections.
+// The block candidates accepted by the consensus participant are sent with their block to the connections
+// that support BlockCandidateMessage.
+type publisherConnectionManager struct {
+	connections *Connections
+	// broadcast sends a message to addrs
+	broadcast func(m gnet.Message, addrs []string) error
+	// candidate blocks, by hash
+	blocks map[cipher.SHA256]coin.Block
+}
+
+func newPublisherConnectionManager(connections *Connections, broadcast func(m gnet.Message, addrs []string) error) *publisherConnectionManager {
+	return &publisherConnectionManager{
+		connections: connections,
+		broadcast:   broadcast,
+		blocks:      make(map[cipher.SHA256]coin.Block),
+	}
+}
+
+// SendBlockToAllMySubscriber implements consensus.ConnectionManagerInterface
+func (m *publisherConnectionManager) SendBlockToAllMySubscriber(blockPtr *consensus.BlockBase) {
+	b, ok := m.blocks[blockPtr.Hash]
+	if !ok {
+		logger.Critical().WithField("hash", blockPtr.Hash.Hex()).Error("Block of the block candidate is unknown")
+		return
+	}
+
+	var addrs []string
+	for _, c := range m.connections.all() {
+		if c.SupportsPublisherConsensus() {
+			addrs = append(addrs, c.Addr)
+		}
+	}
+
+	if len(addrs) == 0 {
+		return
+	}
+
+	if err := m.broadcast(NewBlockCandidateMessage(*blockPtr, b), addrs); err != nil {
+		logger.WithError(err).Warning("Broadcast BlockCandidateMessage failed")
+	}
+}
+
+// Print implements consensus.ConnectionManagerInterface
+func (m *publisherConnectionManager) Print() {
+	fmt.Printf("publisherConnectionManager={blocks=%d}", len(m.blocks))
+}
+
+// publisherConsensus settles the blocks created by a set of block publishers.
+// A block publisher signs the hash of the first valid block candidate it receives for the next seq,
+// or creates and signs its own candidate if it hasn't received one.
+// The consensus.ConsensusParticipant counts the unique publishers that signed each hash, and the block is
+// executed once its hash is signed by a quorum of publishers. The signatures of the quorum are kept with the block
+// as its certificate, so that other nodes can verify that the block was settled.
+type publisherConsensus struct {
+	sync.Mutex
+	participant *consensus.ConsensusParticipant
+	manager     *publisherConnectionManager
+	// pubkeys of the block publishers
+	publishers map[cipher.PubKey]struct{}
+	// number of publishers that must sign a hash to settle it
+	quorum int
+	// true if this node is one of the block publishers and signs candidates
+	signer bool
+	// pubkey of this node, if it signs candidates
+	pubkey cipher.PubKey
+	// hash signed by this node, by seq
+	signed map[uint64]cipher.SHA256
+	// hash signed by each publisher, by seq. A publisher may only sign one candidate per seq,
+	// so that a publisher can't fill the memory with candidates
+	candidates map[uint64]map[cipher.PubKey]cipher.SHA256
+	// verified signatures of the candidates, by hash and publisher
+	sigs map[cipher.SHA256]map[cipher.PubKey]cipher.Sig
+}
+
+// newPublisherConsensus creates a publisherConsensus. If seckey belongs to one of the publishers,
+// the node signs block candidates.
+func newPublisherConsensus(publishers []cipher.PubKey, quorum int, seckey cipher.SecKey, manager *publisherConnectionManager) *publisherConsensus {
+	pc := &publisherConsensus{
+		participant: consensus.NewConsensusParticipantPtr(manager),
+		manager:     manager,
+		publishers:  make(map[cipher.PubKey]struct{}, len(publishers)),
+		quorum:      quorum,
+		signed:      make(map[uint64]cipher.SHA256),
+		candidates:  make(map[uint64]map[cipher.PubKey]cipher.SHA256),
+		sigs:        make(map[cipher.SHA256]map[cipher.PubKey]cipher.Sig),
+	}
+
+	for _, pk := range publishers {
+		pc.publishers[pk] = struct{}{}
+	}
+
+	if !seckey.Null() {
+		pubkey := cipher.MustPubKeyFromSecKey(seckey)
+		if _, ok := pc.publishers[pubkey]; ok {
+			pc.participant.SetPubkeySeckey(pubkey, seckey)
+			pc.pubkey = pubkey
+			pc.signer = true
+		}
+	}
+
+	return pc
+}
+
+// hasBlock returns true if the block with hash is a known candidate
+func (pc *publisherConsensus) hasBlock(hash cipher.SHA256) bool {
+	pc.Lock()
+	defer pc.Unlock()
+
+	_, ok := pc.manager.blocks[hash]
+	return ok
+}
+
+// propose signs a block created by this node and sends it to peers
+func (pc *publisherConsensus) propose(b coin.Block) (*consensus.BlockBase, error) {
+	pc.Lock()
+	defer pc.Unlock()
+
+	if !pc.signer {
+		return nil, errBlockCandidateSigner
+	}
+
+	seq := b.Head.BkSeq
+	if _, ok := pc.signed[seq]; ok {
+		return nil, errBlockCandidateSigned
+	}
+
+	pc.addBlock(b)
+	return pc.sign(b.HashHeader(), seq), nil
+}
+
+// addCandidate adds a block candidate signed by a block publisher and sends it to peers if it is new.
+// The block must have been verified against the head block.
+// Only the first candidate signed by a publisher for a seq is kept, errBlockCandidateEquivocation is returned for
+// a candidate with another hash.
+// If this node is a block publisher that hasn't signed a candidate with the same seq, it signs the candidate too.
+func (pc *publisherConsensus) addCandidate(candidate consensus.BlockBase, b coin.Block) error {
+	if b.HashHeader() != candidate.Hash || b.Head.BkSeq != candidate.Seqno {
+		return errBlockCandidateMismatch
+	}
+
+	signer, err := cipher.PubKeyFromSig(candidate.Sig, candidate.Hash)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := pc.publishers[signer]; !ok {
+		return errBlockCandidateSigner
+	}
+
+	if err := cipher.VerifyPubKeySignedHash(signer, candidate.Sig, candidate.Hash); err != nil {
+		return err
+	}
+
+	pc.Lock()
+	defer pc.Unlock()
+
+	if hash, ok := pc.candidates[candidate.Seqno][signer]; ok {
+		if hash != candidate.Hash {
+			return errBlockCandidateEquivocation
+		}
+		return nil
+	}
+
+	pc.addCandidateSigner(candidate.Seqno, signer, candidate.Hash)
+	pc.addBlock(b)
+	pc.addSig(candidate.Hash, signer, candidate.Sig)
+	pc.participant.OnBlockHeaderArrived(&candidate)
+
+	if pc.signer {
+		if _, ok := pc.signed[candidate.Seqno]; !ok {
+			pc.sign(candidate.Hash, candidate.Seqno)
+		}
+	}
+
+	return nil
+}
+
+// settle returns the block with seq once a quorum of block publishers signed its hash, with the signatures
+// of the publishers that signed it. The block is signed by one of them.
+func (pc *publisherConsensus) settle(seq uint64) (*coin.SignedBlock, []cipher.Sig) {
+	pc.Lock()
+	defer pc.Unlock()
+
+	settled := pc.participant.SettleBlock(seq, pc.quorum)
+	if settled == nil {
+		return nil, nil
+	}
+
+	sb := &coin.SignedBlock{
+		Block: pc.manager.blocks[settled.Hash],
+		Sig:   settled.Sig,
+	}
+
+	sigs := make([]cipher.Sig, 0, len(pc.sigs[settled.Hash]))
+	for _, sig := range pc.sigs[settled.Hash] {
+		sigs = append(sigs, sig)
+	}
+
+	pc.prune(seq)
+
+	return sb, sigs
+}
+
+// pruneThrough removes the candidates with seq up to headSeq, which were settled or obtained from peers
+func (pc *publisherConsensus) pruneThrough(headSeq uint64) {
+	pc.Lock()
+	defer pc.Unlock()
+
+	pc.participant.Remove_block_stat_queue_through(headSeq)
+	pc.prune(headSeq)
+}
+
+// addBlock stores a candidate block. Must be called with the lock held.
+func (pc *publisherConsensus) addBlock(b coin.Block) {
+	pc.manager.blocks[b.HashHeader()] = b
+}
+
+// addCandidateSigner records that a block publisher signed hash for seq. Must be called with the lock held.
+func (pc *publisherConsensus) addCandidateSigner(seq uint64, pubkey cipher.PubKey, hash cipher.SHA256) {
+	signers, ok := pc.candidates[seq]
+	if !ok {
+		signers = make(map[cipher.PubKey]cipher.SHA256)
+		pc.candidates[seq] = signers
+	}
+
+	signers[pubkey] = hash
+}
+
+// addSig stores the verified signature of hash by a block publisher. Must be called with the lock held.
+func (pc *publisherConsensus) addSig(hash cipher.SHA256, pubkey cipher.PubKey, sig cipher.Sig) {
+	sigs, ok := pc.sigs[hash]
+	if !ok {
+		sigs = make(map[cipher.PubKey]cipher.Sig)
+		pc.sigs[hash] = sigs
+	}
+
+	if _, ok := sigs[pubkey]; !ok {
+		sigs[pubkey] = sig
+	}
+}
+
+// sign signs hash for seq and hands the signature to the participant, which sends it to peers.
+// Must be called with the lock held.
+func (pc *publisherConsensus) sign(hash cipher.SHA256, seq uint64) *consensus.BlockBase {
+	candidate := &consensus.BlockBase{
+		Sig:   pc.participant.SignatureOf(hash),
+		Hash:  hash,
+		Seqno: seq,
+	}
+
+	pc.signed[seq] = hash
+	pc.addCandidateSigner(seq, pc.pubkey, hash)
+	pc.addSig(hash, pc.pubkey, candidate.Sig)
+	pc.participant.OnBlockHeaderArrived(candidate)
+
+	return candidate
+}
+
+// prune removes the state of the candidates with seq up to seq. Must be called with the lock held.
+func (pc *publisherConsensus) prune(seq uint64) {
+	for hash, b := range pc.manager.blocks {
+		if b.Head.BkSeq <= seq {
+			delete(pc.manager.blocks, hash)
+			delete(pc.sigs, hash)
+		}
+	}
+
+	for s := range pc.signed {
+		if s <= seq {
+			delete(pc.signed, s)
+		}
+	}
+
+	for s := range pc.candidates {
+		if s <= seq {
+			delete(pc.candidates, s)
+		}
+	}
+}
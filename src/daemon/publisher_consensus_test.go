@@ -0,0 +1,386 @@
+package daemon
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ness-network/ness/src/consensus"
+	"github.com/ness-network/ness/src/daemon/gnet"
+	"github.com/ness-network/ness/src/visor/blockdb"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+// testPublisherNode is a node of testPublisherNetwork
+type testPublisherNode struct {
+	addr string
+	pc   *publisherConsensus
+	// executed blocks
+	chain []coin.SignedBlock
+	// certificates of the executed blocks
+	certs []blockdb.BlockCertificate
+	// number of BlockCandidateMessages sent
+	sent int
+}
+
+// testPublisherDelivery is a BlockCandidateMessage in flight
+type testPublisherDelivery struct {
+	to string
+	m  *BlockCandidateMessage
+}
+
+// testPublisherNetwork connects publisherConsensus instances in process.
+// Messages are queued by the broadcast function and delivered by deliver, so that a node
+// never handles a message while it holds its own lock.
+type testPublisherNetwork struct {
+	t       *testing.T
+	genesis coin.BlockHeader
+	nodes   map[string]*testPublisherNode
+	queue   []testPublisherDelivery
+	// errors returned by addCandidate, by node
+	errs map[string][]error
+}
+
+func newTestPublisherNetwork(t *testing.T, genesis coin.BlockHeader, publishers []cipher.PubKey, quorum int, seckeys []cipher.SecKey) *testPublisherNetwork {
+	n := &testPublisherNetwork{
+		t:       t,
+		genesis: genesis,
+		nodes:   make(map[string]*testPublisherNode),
+		errs:    make(map[string][]error),
+	}
+
+	addrs := make([]string, len(seckeys))
+	for i := range seckeys {
+		addrs[i] = fmt.Sprintf("127.0.0.%d:6000", i+1)
+	}
+
+	for i, seckey := range seckeys {
+		addr := addrs[i]
+
+		conns := NewConnections()
+		for j, peer := range addrs {
+			if peer == addr {
+				continue
+			}
+
+			gnetID := uint64(j + 1)
+			_, err := conns.pending(peer)
+			require.NoError(t, err)
+			_, err = conns.connected(peer, gnetID)
+			require.NoError(t, err)
+			_, err = conns.introduced(peer, gnetID, &IntroductionMessage{
+				Mirror:          uint32(j + 1),
+				ListenPort:      6000,
+				ProtocolVersion: publisherConsensusProtocolVersion,
+			})
+			require.NoError(t, err)
+		}
+
+		node := &testPublisherNode{
+			addr: addr,
+		}
+
+		manager := newPublisherConnectionManager(conns, func(m gnet.Message, addrs []string) error {
+			node.sent++
+			for _, to := range addrs {
+				n.queue = append(n.queue, testPublisherDelivery{
+					to: to,
+					m:  m.(*BlockCandidateMessage),
+				})
+			}
+			return nil
+		})
+
+		node.pc = newPublisherConsensus(publishers, quorum, seckey, manager)
+		n.nodes[addr] = node
+	}
+
+	return n
+}
+
+// node returns the i-th node
+func (n *testPublisherNetwork) node(i int) *testPublisherNode {
+	return n.nodes[fmt.Sprintf("127.0.0.%d:6000", i+1)]
+}
+
+// head returns the head block header of a node
+func (n *testPublisherNetwork) head(node *testPublisherNode) coin.BlockHeader {
+	if len(node.chain) == 0 {
+		return n.genesis
+	}
+	return node.chain[len(node.chain)-1].Head
+}
+
+// settle executes the next block of a node if the block publishers settled on it, like Daemon.settleBlockCandidates
+func (n *testPublisherNetwork) settle(node *testPublisherNode) {
+	headSeq := n.head(node).BkSeq
+	node.pc.pruneThrough(headSeq)
+
+	sb, sigs := node.pc.settle(headSeq + 1)
+	if sb == nil {
+		return
+	}
+
+	head := n.head(node)
+	require.Equal(n.t, head.Hash(), sb.Head.PrevHash)
+	node.chain = append(node.chain, *sb)
+	node.certs = append(node.certs, blockdb.BlockCertificate{
+		Hash: sb.HashHeader(),
+		Sigs: sigs,
+	})
+}
+
+// receive handles a block candidate, like BlockCandidateMessage.process and Daemon.addBlockCandidate
+func (n *testPublisherNetwork) receive(node *testPublisherNode, m *BlockCandidateMessage) {
+	if m.Block.Head.BkSeq != n.head(node).BkSeq+1 {
+		return
+	}
+
+	if err := node.pc.addCandidate(m.Candidate, m.Block); err != nil {
+		n.errs[node.addr] = append(n.errs[node.addr], err)
+		return
+	}
+
+	n.settle(node)
+}
+
+// propose makes a node create and sign a block candidate
+func (n *testPublisherNetwork) propose(node *testPublisherNode, b coin.Block) {
+	_, err := node.pc.propose(b)
+	require.NoError(n.t, err)
+	n.settle(node)
+}
+
+// deliver delivers the queued messages until there are none left
+func (n *testPublisherNetwork) deliver() {
+	for len(n.queue) > 0 {
+		d := n.queue[0]
+		n.queue = n.queue[1:]
+		n.receive(n.nodes[d.to], d.m)
+	}
+}
+
+// makeTestBlockCandidate creates a block on top of head. Blocks with a different nonce have a different hash.
+func makeTestBlockCandidate(head coin.BlockHeader, nonce uint64) coin.Block {
+	body := coin.BlockBody{
+		Transactions: coin.Transactions{
+			{
+				Length: uint32(nonce),
+			},
+		},
+	}
+
+	return coin.Block{
+		Head: coin.BlockHeader{
+			Version:  head.Version,
+			Time:     head.Time + 10,
+			BkSeq:    head.BkSeq + 1,
+			PrevHash: head.Hash(),
+			BodyHash: body.Hash(),
+		},
+		Body: body,
+	}
+}
+
+// signTestBlockCandidate signs a block candidate with seckey
+func signTestBlockCandidate(b coin.Block, seckey cipher.SecKey) consensus.BlockBase {
+	return consensus.BlockBase{
+		Sig:   cipher.MustSignHash(b.HashHeader(), seckey),
+		Hash:  b.HashHeader(),
+		Seqno: b.Head.BkSeq,
+	}
+}
+
+func TestPublisherConsensus(t *testing.T) {
+	genesis := coin.BlockHeader{
+		Version: 1,
+		Time:    100,
+	}
+
+	var publishers []cipher.PubKey
+	var seckeys []cipher.SecKey
+	for i := 0; i < 3; i++ {
+		pubkey, seckey := cipher.GenerateKeyPair()
+		publishers = append(publishers, pubkey)
+		seckeys = append(seckeys, seckey)
+	}
+	// The last node is an observer that does not sign block candidates
+	seckeys = append(seckeys, cipher.SecKey{})
+
+	_, outsiderSeckey := cipher.GenerateKeyPair()
+
+	requireSettled := func(t *testing.T, n *testPublisherNetwork, b coin.Block) {
+		for i := range seckeys {
+			node := n.node(i)
+			require.Len(t, node.chain, 1, node.addr)
+			require.Equal(t, b, node.chain[0].Block, node.addr)
+
+			signer, err := cipher.PubKeyFromSig(node.chain[0].Sig, b.HashHeader())
+			require.NoError(t, err)
+			require.Contains(t, publishers, signer)
+			require.NoError(t, node.chain[0].VerifySignature(signer))
+
+			// The block carries the signatures of the quorum
+			require.Equal(t, b.HashHeader(), node.certs[0].Hash)
+			signers := node.certs[0].Signers()
+			require.Len(t, signers, len(node.certs[0].Sigs))
+			require.True(t, len(signers) >= node.pc.quorum, node.addr)
+			require.Contains(t, signers, signer)
+			for _, pk := range signers {
+				require.Contains(t, publishers, pk)
+			}
+
+			require.Empty(t, node.pc.manager.blocks)
+			require.Empty(t, node.pc.signed)
+			require.Empty(t, node.pc.candidates)
+			require.Empty(t, node.pc.sigs)
+			require.Equal(t, 0, node.pc.participant.Get_block_stat_queue_Len())
+		}
+	}
+
+	t.Run("honest", func(t *testing.T) {
+		n := newTestPublisherNetwork(t, genesis, publishers, 2, seckeys)
+		require.False(t, n.node(3).pc.signer)
+
+		b := makeTestBlockCandidate(genesis, 0)
+		n.propose(n.node(0), b)
+
+		// A single signature is not a quorum
+		require.Empty(t, n.node(0).chain)
+		require.Len(t, n.queue, 3)
+
+		n.deliver()
+		requireSettled(t, n, b)
+
+		// The next block is settled on top of the first
+		b2 := makeTestBlockCandidate(b.Head, 0)
+		n.propose(n.node(1), b2)
+		n.deliver()
+		for i := range seckeys {
+			require.Len(t, n.node(i).chain, 2)
+			require.Equal(t, b2, n.node(i).chain[1].Block)
+		}
+
+		require.Empty(t, n.errs)
+	})
+
+	t.Run("competing proposals", func(t *testing.T) {
+		n := newTestPublisherNetwork(t, genesis, publishers, 2, seckeys)
+
+		b := makeTestBlockCandidate(genesis, 0)
+		other := makeTestBlockCandidate(genesis, 1)
+		require.NotEqual(t, b.HashHeader(), other.HashHeader())
+
+		// Both proposals are in flight before either is delivered.
+		// Each publisher signs only one candidate per seq, so one of them reaches the quorum.
+		n.propose(n.node(0), b)
+		n.propose(n.node(1), other)
+		n.deliver()
+
+		requireSettled(t, n, n.node(0).chain[0].Block)
+		require.Empty(t, n.errs)
+	})
+
+	t.Run("malicious", func(t *testing.T) {
+		n := newTestPublisherNetwork(t, genesis, publishers, 2, seckeys)
+		observer := n.node(3)
+
+		b := makeTestBlockCandidate(genesis, 0)
+
+		// A block candidate signed by a key that is not a block publisher is rejected and not relayed
+		err := observer.pc.addCandidate(signTestBlockCandidate(b, outsiderSeckey), b)
+		require.Equal(t, errBlockCandidateSigner, err)
+
+		// A block candidate that does not match its block is rejected
+		other := makeTestBlockCandidate(genesis, 1)
+		err = observer.pc.addCandidate(signTestBlockCandidate(other, seckeys[0]), b)
+		require.Equal(t, errBlockCandidateMismatch, err)
+
+		candidate := signTestBlockCandidate(b, seckeys[0])
+		candidate.Seqno++
+		err = observer.pc.addCandidate(candidate, b)
+		require.Equal(t, errBlockCandidateMismatch, err)
+
+		// A block candidate with an invalid signature is rejected
+		candidate = signTestBlockCandidate(b, seckeys[0])
+		candidate.Sig = cipher.Sig{}
+		err = observer.pc.addCandidate(candidate, b)
+		require.Error(t, err)
+
+		require.Equal(t, 0, observer.sent)
+		require.Empty(t, observer.pc.manager.blocks)
+		require.Empty(t, n.queue)
+
+		// A block publisher that signs a second block for the same seq does not change the outcome
+		n.propose(n.node(0), b)
+		n.deliver()
+		requireSettled(t, n, b)
+
+		n.receive(observer, NewBlockCandidateMessage(signTestBlockCandidate(other, seckeys[2]), other))
+		require.Len(t, observer.chain, 1)
+		require.Equal(t, b, observer.chain[0].Block)
+	})
+
+	t.Run("equivocating publisher", func(t *testing.T) {
+		n := newTestPublisherNetwork(t, genesis, publishers, 3, seckeys)
+		observer := n.node(3)
+
+		b := makeTestBlockCandidate(genesis, 0)
+		other := makeTestBlockCandidate(genesis, 1)
+
+		// The first publisher signs several blocks for the same seq.
+		// Only its first candidate is kept, the others are rejected and not relayed.
+		n.receive(observer, NewBlockCandidateMessage(signTestBlockCandidate(b, seckeys[0]), b))
+		n.receive(observer, NewBlockCandidateMessage(signTestBlockCandidate(other, seckeys[0]), other))
+		for i := uint64(2); i < 10; i++ {
+			c := makeTestBlockCandidate(genesis, i)
+			n.receive(observer, NewBlockCandidateMessage(signTestBlockCandidate(c, seckeys[0]), c))
+		}
+		require.Empty(t, observer.chain)
+		require.Equal(t, 1, observer.sent)
+		require.Len(t, observer.pc.manager.blocks, 1)
+		require.Len(t, n.errs[observer.addr], 9)
+		for _, err := range n.errs[observer.addr] {
+			require.Equal(t, errBlockCandidateEquivocation, err)
+		}
+
+		n.receive(observer, NewBlockCandidateMessage(signTestBlockCandidate(b, seckeys[1]), b))
+		require.Empty(t, observer.chain)
+		n.receive(observer, NewBlockCandidateMessage(signTestBlockCandidate(b, seckeys[2]), b))
+		require.Len(t, observer.chain, 1)
+		require.Equal(t, b, observer.chain[0].Block)
+		require.Len(t, n.errs[observer.addr], 9)
+	})
+
+	t.Run("duplicates", func(t *testing.T) {
+		n := newTestPublisherNetwork(t, genesis, publishers, 2, seckeys)
+		observer := n.node(3)
+
+		b := makeTestBlockCandidate(genesis, 0)
+		m := NewBlockCandidateMessage(signTestBlockCandidate(b, seckeys[0]), b)
+
+		n.receive(observer, m)
+		require.Equal(t, 1, observer.sent)
+
+		// The same message is not relayed again
+		n.receive(observer, m)
+		require.Equal(t, 1, observer.sent)
+
+		// The same publisher signing the block again does not count as a second signer
+		n.receive(observer, NewBlockCandidateMessage(signTestBlockCandidate(b, seckeys[0]), b))
+		require.Equal(t, 1, observer.sent)
+		require.Empty(t, observer.chain)
+
+		// The block is not settled without a quorum, however long it waits
+		n.settle(observer)
+		require.Empty(t, observer.chain)
+
+		n.receive(observer, NewBlockCandidateMessage(signTestBlockCandidate(b, seckeys[1]), b))
+		require.Len(t, observer.chain, 1)
+		require.Equal(t, b, observer.chain[0].Block)
+		require.Len(t, observer.certs[0].Sigs, 2)
+		require.Empty(t, n.errs)
+	})
+}
@@ -0,0 +1,172 @@
+package daemon
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket limit of a message type received from a peer.
+// Up to Count messages can be received at once, and Count messages are allowed again every Interval
+type RateLimit struct {
+	Count    int
+	Interval time.Duration
+}
+
+// defaultMessageRateLimits returns the default rate limits of the messages received from a peer.
+// The limits leave room for the requests made by a peer that is syncing from us
+func defaultMessageRateLimits() map[string]RateLimit {
+	return map[string]RateLimit{
+		"GETB": {Count: 300, Interval: time.Minute},
+		"GETH": {Count: 60, Interval: time.Minute},
+		"GETP": {Count: 10, Interval: time.Minute},
+		"GETT": {Count: 20, Interval: time.Second},
+		"ANNT": {Count: announceTxnsRate, Interval: time.Second},
+	}
+}
+
+// announceTxnsRate is the number of AnnounceTxnsMessages per second allowed from a peer
+const announceTxnsRate = 20
+
+// AnnounceTxnsRateLimit returns the rate limit of AnnounceTxnsMessages for an unconfirmed pool of maxUnconfirmed transactions.
+// A peer announces its whole pool in chunks of maxAnnounceNum hashes after the introduction,
+// so the limit allows a burst of a full pool and announceTxnsRate messages per second after it.
+// Returns the default limit if maxUnconfirmed is 0, since the pool has no size limit
+func AnnounceTxnsRateLimit(maxUnconfirmed uint64, maxAnnounceNum int) RateLimit {
+	if maxUnconfirmed == 0 || maxAnnounceNum <= 0 {
+		return defaultMessageRateLimits()["ANNT"]
+	}
+
+	count := int(math.Ceil(float64(maxUnconfirmed) / float64(maxAnnounceNum)))
+	if count < announceTxnsRate {
+		count = announceTxnsRate
+	}
+
+	return RateLimit{
+		Count:    count,
+		Interval: time.Second * time.Duration(count) / announceTxnsRate,
+	}
+}
+
+// tokenBucket is the token bucket of a message type of a peer
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	// messages dropped since the bucket was last full
+	dropped int
+}
+
+// take refills the bucket for the time elapsed since it was last updated and takes a token.
+// The dropped messages are forgotten once the bucket is full again.
+// Returns false if there is no token to take
+func (b *tokenBucket) take(limit RateLimit, now time.Time) bool {
+	if elapsed := now.Sub(b.updated); elapsed > 0 {
+		b.tokens += float64(limit.Count) * float64(elapsed) / float64(limit.Interval)
+		if b.tokens >= float64(limit.Count) {
+			b.tokens = float64(limit.Count)
+			b.dropped = 0
+		}
+		b.updated = now
+	}
+
+	if b.tokens < 1 {
+		return false
+	}
+
+	b.tokens--
+	return true
+}
+
+// drop records a message dropped because there was no token to take.
+// Returns true when Count messages were dropped since the bucket was last full, and starts counting again
+func (b *tokenBucket) drop(limit RateLimit) bool {
+	b.dropped++
+	if b.dropped < limit.Count {
+		return false
+	}
+
+	b.dropped = 0
+	return true
+}
+
+// messageRateLimiter limits the rate of the messages received from each peer, by message type
+type messageRateLimiter struct {
+	sync.Mutex
+	limits  map[string]RateLimit
+	buckets map[string]map[string]*tokenBucket
+	// number of messages dropped from each peer, by message type, for reporting
+	throttled map[string]map[string]uint64
+}
+
+func newMessageRateLimiter(limits map[string]RateLimit) *messageRateLimiter {
+	return &messageRateLimiter{
+		limits:    limits,
+		buckets:   make(map[string]map[string]*tokenBucket),
+		throttled: make(map[string]map[string]uint64),
+	}
+}
+
+// allow takes a token from the bucket of msgType for addr.
+// Returns false if the message exceeds the rate limit and should be dropped.
+// Message types without a rate limit are always allowed.
+// overrun is true when Count messages of a type were dropped without the bucket refilling
+// in between, so that a peer is scored for exceeding the limit persistently rather than
+// for every message of a burst
+func (rl *messageRateLimiter) allow(addr, msgType string, now time.Time) (allowed, overrun bool) {
+	limit, ok := rl.limits[msgType]
+	if !ok || limit.Count <= 0 || limit.Interval <= 0 {
+		return true, false
+	}
+
+	rl.Lock()
+	defer rl.Unlock()
+
+	buckets, ok := rl.buckets[addr]
+	if !ok {
+		buckets = make(map[string]*tokenBucket)
+		rl.buckets[addr] = buckets
+	}
+
+	b, ok := buckets[msgType]
+	if !ok {
+		b = &tokenBucket{
+			tokens:  float64(limit.Count),
+			updated: now,
+		}
+		buckets[msgType] = b
+	}
+
+	if b.take(limit, now) {
+		return true, false
+	}
+
+	throttled, ok := rl.throttled[addr]
+	if !ok {
+		throttled = make(map[string]uint64)
+		rl.throttled[addr] = throttled
+	}
+	throttled[msgType]++
+
+	return false, b.drop(limit)
+}
+
+// throttledMessages returns the number of messages of each type dropped from addr
+func (rl *messageRateLimiter) throttledMessages(addr string) map[string]uint64 {
+	rl.Lock()
+	defer rl.Unlock()
+
+	throttled := make(map[string]uint64, len(rl.throttled[addr]))
+	for k, v := range rl.throttled[addr] {
+		throttled[k] = v
+	}
+	return throttled
+}
+
+// remove removes the buckets of addr
+func (rl *messageRateLimiter) remove(addr string) {
+	rl.Lock()
+	defer rl.Unlock()
+
+	delete(rl.buckets, addr)
+	delete(rl.throttled, addr)
+}
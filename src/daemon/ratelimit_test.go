@@ -0,0 +1,157 @@
+package daemon
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTokenBucketTake(t *testing.T) {
+	limit := RateLimit{
+		Count:    2,
+		Interval: time.Second,
+	}
+
+	now := time.Now()
+	b := &tokenBucket{
+		tokens:  2,
+		updated: now,
+	}
+
+	require.True(t, b.take(limit, now))
+	require.True(t, b.take(limit, now))
+	require.False(t, b.take(limit, now))
+
+	// One token is refilled every half second
+	require.False(t, b.take(limit, now.Add(time.Millisecond*400)))
+	require.True(t, b.take(limit, now.Add(time.Millisecond*500)))
+	require.False(t, b.take(limit, now.Add(time.Millisecond*500)))
+
+	// The bucket does not fill past Count
+	later := now.Add(time.Hour)
+	require.True(t, b.take(limit, later))
+	require.True(t, b.take(limit, later))
+	require.False(t, b.take(limit, later))
+
+	// Time going backwards does not refill the bucket
+	require.False(t, b.take(limit, now))
+}
+
+func TestMessageRateLimiter(t *testing.T) {
+	rl := newMessageRateLimiter(map[string]RateLimit{
+		"GETB": {Count: 2, Interval: time.Minute},
+		"ANNT": {Count: 1, Interval: time.Second},
+		"GETT": {Count: 0, Interval: time.Second},
+	})
+
+	now := time.Now()
+	a := "112.32.32.14:6000"
+	b := "112.32.32.15:6000"
+
+	allow := func(addr, msgType string, now time.Time) bool {
+		allowed, _ := rl.allow(addr, msgType, now)
+		return allowed
+	}
+
+	require.True(t, allow(a, "GETB", now))
+	require.True(t, allow(a, "GETB", now))
+	require.False(t, allow(a, "GETB", now))
+	require.False(t, allow(a, "GETB", now))
+
+	// Buckets are per peer and per message type
+	require.True(t, allow(b, "GETB", now))
+	require.True(t, allow(a, "ANNT", now))
+	require.False(t, allow(a, "ANNT", now))
+	require.True(t, allow(a, "ANNT", now.Add(time.Second)))
+
+	// Message types without a valid limit are not limited
+	for i := 0; i < 100; i++ {
+		require.True(t, allow(a, "GIVB", now))
+		require.True(t, allow(a, "GETT", now))
+	}
+
+	require.Equal(t, map[string]uint64{
+		"GETB": 2,
+		"ANNT": 1,
+	}, rl.throttledMessages(a))
+	require.Empty(t, rl.throttledMessages(b))
+
+	// Removing a peer resets its buckets
+	rl.remove(a)
+	require.Empty(t, rl.throttledMessages(a))
+	require.True(t, allow(a, "GETB", now))
+	require.Len(t, rl.buckets, 2)
+}
+
+func TestMessageRateLimiterOverrun(t *testing.T) {
+	rl := newMessageRateLimiter(map[string]RateLimit{
+		"ANNT": {Count: 3, Interval: time.Second},
+	})
+
+	now := time.Now()
+	a := "112.32.32.14:6000"
+
+	for i := 0; i < 3; i++ {
+		allowed, overrun := rl.allow(a, "ANNT", now)
+		require.True(t, allowed)
+		require.False(t, overrun)
+	}
+
+	// Every Count-th dropped message is an overrun while the bucket does not refill
+	var overruns int
+	for i := 0; i < 9; i++ {
+		allowed, overrun := rl.allow(a, "ANNT", now)
+		require.False(t, allowed)
+		if overrun {
+			overruns++
+		}
+	}
+	require.Equal(t, 3, overruns)
+
+	// Drops are forgotten once the bucket refills, so bursts separated by a full refill are not overruns
+	for burst := 1; burst <= 3; burst++ {
+		now = now.Add(time.Second)
+		for i := 0; i < 3; i++ {
+			allowed, _ := rl.allow(a, "ANNT", now)
+			require.True(t, allowed)
+		}
+
+		for i := 0; i < 2; i++ {
+			allowed, overrun := rl.allow(a, "ANNT", now)
+			require.False(t, allowed)
+			require.False(t, overrun)
+		}
+	}
+
+	// A partial refill keeps the count of dropped messages
+	now = now.Add(time.Millisecond * 400)
+	allowed, _ := rl.allow(a, "ANNT", now)
+	require.True(t, allowed)
+	allowed, overrun := rl.allow(a, "ANNT", now)
+	require.False(t, allowed)
+	require.True(t, overrun)
+
+	// The lifetime count of dropped messages is still reported
+	require.Equal(t, map[string]uint64{
+		"ANNT": 16,
+	}, rl.throttledMessages(a))
+}
+
+func TestAnnounceTxnsRateLimit(t *testing.T) {
+	require.Equal(t, defaultMessageRateLimits()["ANNT"], AnnounceTxnsRateLimit(0, 16))
+	require.Equal(t, RateLimit{Count: 20, Interval: time.Second}, AnnounceTxnsRateLimit(100, 16))
+
+	// A full pool can be announced at once
+	limit := AnnounceTxnsRateLimit(20000, 16)
+	require.Equal(t, RateLimit{Count: 1250, Interval: time.Millisecond * 62500}, limit)
+
+	rl := newMessageRateLimiter(map[string]RateLimit{
+		"ANNT": limit,
+	})
+	now := time.Now()
+	for i := 0; i < 1250; i++ {
+		allowed, _ := rl.allow("112.32.32.14:6000", "ANNT", now)
+		require.True(t, allowed)
+	}
+}
@@ -0,0 +1,343 @@
+/*
+Package psbt implements a container for partially signed transactions.
+
+A Packet holds an unsigned transaction together with the unspent outputs spent by its inputs,
+hints to find the key of each input and the signatures collected so far.
+It is created by a node that knows the unspent outputs, for example with a watch or xpub wallet,
+signed by one or more offline nodes that hold the keys, combined, and finalized into a
+transaction that can be broadcast.
+*/
+package psbt
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+// Version is the packet format version
+const Version = 1
+
+var (
+	// ErrNoInputs is returned if the transaction of a packet has no inputs
+	ErrNoInputs = errors.New("psbt: transaction has no inputs")
+	// ErrInputsMismatch is returned if the inputs of a packet do not match its transaction inputs
+	ErrInputsMismatch = errors.New("psbt: inputs do not match the transaction inputs")
+	// ErrTransactionMismatch is returned when combining packets of different transactions
+	ErrTransactionMismatch = errors.New("psbt: packets are for different transactions")
+	// ErrSignatureConflict is returned when combining packets with different signatures for the same input
+	ErrSignatureConflict = errors.New("psbt: packets have different signatures for the same input")
+	// ErrNotFullySigned is returned when finalizing a packet with unsigned inputs
+	ErrNotFullySigned = errors.New("psbt: not all inputs are signed")
+	// ErrInvalidSignature is returned if a signature does not match the address of the output being spent
+	ErrInvalidSignature = errors.New("psbt: signature not valid for output being spent")
+	// ErrInputAlreadySigned is returned when signing an input that is already signed
+	ErrInputAlreadySigned = errors.New("psbt: input already signed")
+)
+
+// Input is an input of a partially signed transaction
+type Input struct {
+	// UxOut is the unspent output spent by the input
+	UxOut coin.UxOut
+	// Bip44Path is the derivation path of the key of the input address, if the address was derived
+	// from a bip44 seed ("m/44'/8000'/0'/0/3") or from an xpub key ("M/3")
+	Bip44Path string
+	// WalletHint identifies the wallet that owns the input address, such as a wallet filename or an xpub key
+	WalletHint string
+	// Sig is the signature of the input, null if the input is not signed yet
+	Sig cipher.Sig
+}
+
+// IsSigned returns true if the input has a signature
+func (in Input) IsSigned() bool {
+	return !in.Sig.Null()
+}
+
+// Packet is a partially signed transaction
+type Packet struct {
+	// Transaction is the transaction without signatures
+	Transaction coin.Transaction
+	// Inputs are the inputs of the transaction, in the same order
+	Inputs []Input
+}
+
+// New creates a packet for the transaction txn spending uxOuts.
+// Signatures already in txn are moved to the inputs of the packet.
+func New(txn coin.Transaction, uxOuts []coin.UxOut) (*Packet, error) {
+	if len(txn.In) == 0 {
+		return nil, ErrNoInputs
+	}
+
+	if len(uxOuts) != len(txn.In) {
+		return nil, ErrInputsMismatch
+	}
+
+	if txn.InnerHash != txn.HashInner() {
+		return nil, errors.New("psbt: transaction inner hash does not match computed inner hash")
+	}
+
+	p := &Packet{
+		Transaction: txn,
+		Inputs:      make([]Input, len(uxOuts)),
+	}
+
+	p.Transaction.Sigs = make([]cipher.Sig, len(txn.In))
+	for i, ux := range uxOuts {
+		p.Inputs[i].UxOut = ux
+		if i < len(txn.Sigs) {
+			p.Inputs[i].Sig = txn.Sigs[i]
+		}
+	}
+
+	if err := p.Transaction.UpdateHeader(); err != nil {
+		return nil, err
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// Validate checks that the inputs match the transaction and that the signatures are valid
+func (p *Packet) Validate() error {
+	txn := p.Transaction
+
+	if len(txn.In) == 0 {
+		return ErrNoInputs
+	}
+
+	if len(p.Inputs) != len(txn.In) {
+		return ErrInputsMismatch
+	}
+
+	if txn.InnerHash != txn.HashInner() {
+		return errors.New("psbt: transaction inner hash does not match computed inner hash")
+	}
+
+	for i, in := range p.Inputs {
+		if in.UxOut.Hash() != txn.In[i] {
+			return fmt.Errorf("psbt: input %d does not match the output it spends", i)
+		}
+
+		if !in.IsSigned() {
+			continue
+		}
+
+		if err := cipher.VerifyAddressSignedHash(in.UxOut.Body.Address, in.Sig, p.signHash(i)); err != nil {
+			return ErrInvalidSignature
+		}
+	}
+
+	return nil
+}
+
+// signHash returns the hash signed by the input i
+func (p *Packet) signHash(i int) cipher.SHA256 {
+	return cipher.AddSHA256(p.Transaction.InnerHash, p.Transaction.In[i])
+}
+
+// TxID returns the ID of the unsigned transaction, which identifies packets of the same transaction
+func (p *Packet) TxID() cipher.SHA256 {
+	return p.Transaction.InnerHash
+}
+
+// IsFullySigned returns true if all inputs are signed
+func (p *Packet) IsFullySigned() bool {
+	for _, in := range p.Inputs {
+		if !in.IsSigned() {
+			return false
+		}
+	}
+	return true
+}
+
+// Sign signs the input i with the secret key
+func (p *Packet) Sign(i int, key cipher.SecKey) error {
+	if i < 0 || i >= len(p.Inputs) {
+		return fmt.Errorf("psbt: input index %d is out of range", i)
+	}
+
+	if p.Inputs[i].IsSigned() {
+		return ErrInputAlreadySigned
+	}
+
+	sig, err := cipher.SignHash(p.signHash(i), key)
+	if err != nil {
+		return err
+	}
+
+	if err := cipher.VerifyAddressSignedHash(p.Inputs[i].UxOut.Body.Address, sig, p.signHash(i)); err != nil {
+		return ErrInvalidSignature
+	}
+
+	p.Inputs[i].Sig = sig
+	return nil
+}
+
+// SignWithKeys signs the unsigned inputs whose address has a key in keys.
+// It returns the indexes of the inputs that were signed.
+func (p *Packet) SignWithKeys(keys map[cipher.Address]cipher.SecKey) ([]int, error) {
+	var signed []int
+	for i, in := range p.Inputs {
+		if in.IsSigned() {
+			continue
+		}
+
+		key, ok := keys[in.UxOut.Body.Address]
+		if !ok {
+			continue
+		}
+
+		if err := p.Sign(i, key); err != nil {
+			return nil, err
+		}
+
+		signed = append(signed, i)
+	}
+
+	return signed, nil
+}
+
+// Combine merges the signatures and hints of packets of the same transaction into a new packet
+func Combine(packets ...*Packet) (*Packet, error) {
+	if len(packets) == 0 {
+		return nil, errors.New("psbt: no packets to combine")
+	}
+
+	for _, p := range packets {
+		if err := p.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	first := packets[0]
+	combined := first.Clone()
+
+	for _, p := range packets[1:] {
+		if p.TxID() != first.TxID() || len(p.Inputs) != len(combined.Inputs) {
+			return nil, ErrTransactionMismatch
+		}
+
+		for i, in := range p.Inputs {
+			c := &combined.Inputs[i]
+
+			if in.IsSigned() {
+				switch {
+				case !c.IsSigned():
+					c.Sig = in.Sig
+				case c.Sig != in.Sig:
+					return nil, ErrSignatureConflict
+				}
+			}
+
+			if c.Bip44Path == "" {
+				c.Bip44Path = in.Bip44Path
+			}
+			if c.WalletHint == "" {
+				c.WalletHint = in.WalletHint
+			}
+		}
+	}
+
+	return combined, nil
+}
+
+// Finalize returns the signed transaction. All inputs must be signed.
+func (p *Packet) Finalize() (coin.Transaction, error) {
+	if err := p.Validate(); err != nil {
+		return coin.Transaction{}, err
+	}
+
+	if !p.IsFullySigned() {
+		return coin.Transaction{}, ErrNotFullySigned
+	}
+
+	txn := p.Clone().Transaction
+	for i, in := range p.Inputs {
+		txn.Sigs[i] = in.Sig
+	}
+
+	if err := txn.UpdateHeader(); err != nil {
+		return coin.Transaction{}, err
+	}
+
+	uxIn := make(coin.UxArray, len(p.Inputs))
+	for i, in := range p.Inputs {
+		uxIn[i] = in.UxOut
+	}
+
+	if err := txn.VerifyInputSignatures(uxIn); err != nil {
+		return coin.Transaction{}, err
+	}
+
+	return txn, nil
+}
+
+// Clone returns a copy of the packet
+func (p *Packet) Clone() *Packet {
+	txn := p.Transaction
+	txn.Sigs = make([]cipher.Sig, len(p.Transaction.Sigs))
+	copy(txn.Sigs, p.Transaction.Sigs)
+	txn.In = make([]cipher.SHA256, len(p.Transaction.In))
+	copy(txn.In, p.Transaction.In)
+	txn.Out = make([]coin.TransactionOutput, len(p.Transaction.Out))
+	copy(txn.Out, p.Transaction.Out)
+
+	inputs := make([]Input, len(p.Inputs))
+	copy(inputs, p.Inputs)
+
+	return &Packet{
+		Transaction: txn,
+		Inputs:      inputs,
+	}
+}
+
+// Serialize encodes the packet to JSON
+func (p *Packet) Serialize() ([]byte, error) {
+	return json.MarshalIndent(newReadablePacket(p), "", "    ")
+}
+
+// Deserialize decodes a packet from JSON and validates it
+func Deserialize(b []byte) (*Packet, error) {
+	var rp readablePacket
+	if err := json.Unmarshal(b, &rp); err != nil {
+		return nil, fmt.Errorf("psbt: invalid packet: %v", err)
+	}
+
+	p, err := rp.toPacket()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (p Packet) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newReadablePacket(&p))
+}
+
+// UnmarshalJSON implements json.Unmarshaler. The packet is not validated.
+func (p *Packet) UnmarshalJSON(b []byte) error {
+	var rp readablePacket
+	if err := json.Unmarshal(b, &rp); err != nil {
+		return err
+	}
+
+	p2, err := rp.toPacket()
+	if err != nil {
+		return err
+	}
+
+	*p = *p2
+	return nil
+}
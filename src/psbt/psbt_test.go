@@ -0,0 +1,169 @@
+package psbt
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+func makeUxOut(t *testing.T, addr cipher.Address, coins, hours uint64) coin.UxOut {
+	return coin.UxOut{
+		Head: coin.UxHead{
+			Time:  100,
+			BkSeq: 2,
+		},
+		Body: coin.UxBody{
+			SrcTransaction: testutil.RandSHA256(t),
+			Address:        addr,
+			Coins:          coins,
+			Hours:          hours,
+		},
+	}
+}
+
+// makeTestPacket creates a packet spending one output of each of the n keys
+func makeTestPacket(t *testing.T, n int) (*Packet, []cipher.SecKey) {
+	var txn coin.Transaction
+	var uxOuts []coin.UxOut
+	var keys []cipher.SecKey
+	for i := 0; i < n; i++ {
+		p, s := cipher.GenerateKeyPair()
+		ux := makeUxOut(t, cipher.AddressFromPubKey(p), 1e6, 100)
+		require.NoError(t, txn.PushInput(ux.Hash()))
+		uxOuts = append(uxOuts, ux)
+		keys = append(keys, s)
+	}
+
+	require.NoError(t, txn.PushOutput(testutil.MakeAddress(), uint64(n)*1e6, 50))
+	require.NoError(t, txn.UpdateHeader())
+
+	pkt, err := New(txn, uxOuts)
+	require.NoError(t, err)
+
+	return pkt, keys
+}
+
+func TestNew(t *testing.T) {
+	pkt, keys := makeTestPacket(t, 2)
+	require.Len(t, pkt.Inputs, 2)
+	require.Len(t, pkt.Transaction.Sigs, 2)
+	require.False(t, pkt.IsFullySigned())
+
+	// Signatures of a partially signed transaction are moved to the inputs
+	txn := pkt.Clone().Transaction
+	require.NoError(t, txn.SignInput(keys[1], 1))
+
+	uxOuts := []coin.UxOut{pkt.Inputs[0].UxOut, pkt.Inputs[1].UxOut}
+	pkt2, err := New(txn, uxOuts)
+	require.NoError(t, err)
+	require.False(t, pkt2.Inputs[0].IsSigned())
+	require.Equal(t, txn.Sigs[1], pkt2.Inputs[1].Sig)
+	require.True(t, pkt2.Transaction.Sigs[1].Null())
+
+	// Outputs must match the inputs
+	_, err = New(txn, uxOuts[:1])
+	require.Equal(t, ErrInputsMismatch, err)
+
+	_, err = New(txn, []coin.UxOut{uxOuts[1], uxOuts[0]})
+	require.EqualError(t, err, "psbt: input 0 does not match the output it spends")
+
+	_, err = New(coin.Transaction{}, nil)
+	require.Equal(t, ErrNoInputs, err)
+
+	// A signature by another key is rejected
+	txn = pkt.Clone().Transaction
+	require.NoError(t, txn.SignInput(keys[0], 1))
+	_, err = New(txn, uxOuts)
+	require.Equal(t, ErrInvalidSignature, err)
+}
+
+func TestSignCombineFinalize(t *testing.T) {
+	pkt, keys := makeTestPacket(t, 3)
+
+	_, err := pkt.Finalize()
+	require.Equal(t, ErrNotFullySigned, err)
+
+	// Each signer signs its own input on a copy of the packet
+	a := pkt.Clone()
+	signed, err := a.SignWithKeys(map[cipher.Address]cipher.SecKey{
+		pkt.Inputs[0].UxOut.Body.Address: keys[0],
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{0}, signed)
+	a.Inputs[0].WalletHint = "a.wlt"
+
+	b := pkt.Clone()
+	signed, err = b.SignWithKeys(map[cipher.Address]cipher.SecKey{
+		pkt.Inputs[1].UxOut.Body.Address: keys[1],
+		pkt.Inputs[2].UxOut.Body.Address: keys[2],
+	})
+	require.NoError(t, err)
+	require.Equal(t, []int{1, 2}, signed)
+
+	require.Equal(t, ErrInputAlreadySigned, b.Sign(1, keys[1]))
+	require.Equal(t, ErrInvalidSignature, pkt.Clone().Sign(0, keys[1]))
+
+	// The original packet is not modified
+	require.False(t, pkt.Inputs[0].IsSigned())
+
+	c, err := Combine(pkt, a, b)
+	require.NoError(t, err)
+	require.True(t, c.IsFullySigned())
+	require.Equal(t, "a.wlt", c.Inputs[0].WalletHint)
+
+	txn, err := c.Finalize()
+	require.NoError(t, err)
+	require.True(t, txn.IsFullySigned())
+	require.NoError(t, txn.Verify())
+	require.Equal(t, pkt.Transaction.InnerHash, txn.InnerHash)
+
+	uxIn := coin.UxArray{pkt.Inputs[0].UxOut, pkt.Inputs[1].UxOut, pkt.Inputs[2].UxOut}
+	require.NoError(t, txn.VerifyInputSignatures(uxIn))
+
+	// Packets of another transaction can't be combined
+	other, _ := makeTestPacket(t, 3)
+	_, err = Combine(a, other)
+	require.Equal(t, ErrTransactionMismatch, err)
+
+	// Different signatures for the same input are a conflict.
+	// Signing uses a random nonce so a second signature of the same input differs.
+	d := pkt.Clone()
+	require.NoError(t, d.Sign(0, keys[0]))
+	require.NotEqual(t, a.Inputs[0].Sig, d.Inputs[0].Sig)
+	_, err = Combine(a, d)
+	require.Equal(t, ErrSignatureConflict, err)
+}
+
+func TestSerialize(t *testing.T) {
+	pkt, keys := makeTestPacket(t, 2)
+	require.NoError(t, pkt.Sign(0, keys[0]))
+	pkt.Inputs[0].Bip44Path = "m/44'/8000'/0'/0/3"
+	pkt.Inputs[1].WalletHint = "xpub"
+
+	b, err := pkt.Serialize()
+	require.NoError(t, err)
+
+	pkt2, err := Deserialize(b)
+	require.NoError(t, err)
+	require.Equal(t, pkt, pkt2)
+
+	// Tampering with an output is detected
+	var rp readablePacket
+	require.NoError(t, json.Unmarshal(b, &rp))
+	rp.Inputs[1].Coins++
+	_, err = rp.toPacket()
+	require.Error(t, err)
+
+	rp.Inputs[1].Coins--
+	rp.Version = 2
+	_, err = rp.toPacket()
+	require.EqualError(t, err, "psbt: unsupported packet version 2")
+
+	_, err = Deserialize([]byte("{"))
+	require.Error(t, err)
+}
@@ -0,0 +1,126 @@
+package psbt
+
+import (
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+)
+
+// readablePacket is the JSON representation of a Packet
+type readablePacket struct {
+	Version     int             `json:"version"`
+	Transaction string          `json:"transaction"`
+	Inputs      []readableInput `json:"inputs"`
+}
+
+// readableInput is the JSON representation of an Input
+type readableInput struct {
+	Hash           string `json:"uxid"`
+	Time           uint64 `json:"time"`
+	BkSeq          uint64 `json:"block_seq"`
+	SrcTransaction string `json:"src_tx"`
+	Address        string `json:"address"`
+	Coins          uint64 `json:"coins"`
+	Hours          uint64 `json:"hours"`
+	Bip44Path      string `json:"bip44_path,omitempty"`
+	WalletHint     string `json:"wallet_hint,omitempty"`
+	Sig            string `json:"sig,omitempty"`
+}
+
+func newReadablePacket(p *Packet) readablePacket {
+	inputs := make([]readableInput, len(p.Inputs))
+	for i, in := range p.Inputs {
+		ri := readableInput{
+			Hash:           in.UxOut.Hash().Hex(),
+			Time:           in.UxOut.Head.Time,
+			BkSeq:          in.UxOut.Head.BkSeq,
+			SrcTransaction: in.UxOut.Body.SrcTransaction.Hex(),
+			Address:        in.UxOut.Body.Address.String(),
+			Coins:          in.UxOut.Body.Coins,
+			Hours:          in.UxOut.Body.Hours,
+			Bip44Path:      in.Bip44Path,
+			WalletHint:     in.WalletHint,
+		}
+
+		if in.IsSigned() {
+			ri.Sig = in.Sig.Hex()
+		}
+
+		inputs[i] = ri
+	}
+
+	return readablePacket{
+		Version:     Version,
+		Transaction: p.Transaction.MustSerializeHex(),
+		Inputs:      inputs,
+	}
+}
+
+func (rp readablePacket) toPacket() (*Packet, error) {
+	if rp.Version != Version {
+		return nil, fmt.Errorf("psbt: unsupported packet version %d", rp.Version)
+	}
+
+	txn, err := coin.DeserializeTransactionHex(rp.Transaction)
+	if err != nil {
+		return nil, fmt.Errorf("psbt: invalid transaction: %v", err)
+	}
+
+	inputs := make([]Input, len(rp.Inputs))
+	for i, ri := range rp.Inputs {
+		in, err := ri.toInput()
+		if err != nil {
+			return nil, fmt.Errorf("psbt: invalid input %d: %v", i, err)
+		}
+		inputs[i] = in
+	}
+
+	return &Packet{
+		Transaction: txn,
+		Inputs:      inputs,
+	}, nil
+}
+
+func (ri readableInput) toInput() (Input, error) {
+	addr, err := cipher.DecodeBase58Address(ri.Address)
+	if err != nil {
+		return Input{}, fmt.Errorf("invalid address: %v", err)
+	}
+
+	srcTxn, err := cipher.SHA256FromHex(ri.SrcTransaction)
+	if err != nil {
+		return Input{}, fmt.Errorf("invalid src_tx: %v", err)
+	}
+
+	in := Input{
+		UxOut: coin.UxOut{
+			Head: coin.UxHead{
+				Time:  ri.Time,
+				BkSeq: ri.BkSeq,
+			},
+			Body: coin.UxBody{
+				SrcTransaction: srcTxn,
+				Address:        addr,
+				Coins:          ri.Coins,
+				Hours:          ri.Hours,
+			},
+		},
+		Bip44Path:  ri.Bip44Path,
+		WalletHint: ri.WalletHint,
+	}
+
+	if ri.Hash != in.UxOut.Hash().Hex() {
+		return Input{}, fmt.Errorf("uxid %s does not match the output fields", ri.Hash)
+	}
+
+	if ri.Sig != "" {
+		sig, err := cipher.SigFromHex(ri.Sig)
+		if err != nil {
+			return Input{}, fmt.Errorf("invalid sig: %v", err)
+		}
+		in.Sig = sig
+	}
+
+	return in, nil
+}
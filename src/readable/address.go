@@ -0,0 +1,48 @@
+package readable
+
+import (
+	"github.com/ness-network/ness/src/visor"
+	"github.com/skycoin/skycoin/src/util/droplet"
+)
+
+// AddressBalanceSnapshot is the confirmed balance of an address after a block
+type AddressBalanceSnapshot struct {
+	BlockSeq uint64 `json:"block_seq"`
+	Time     uint64 `json:"time"`
+	Coins    string `json:"coins"`
+	Hours    uint64 `json:"hours"`
+	Received string `json:"received"`
+	Sent     string `json:"sent"`
+}
+
+// NewAddressBalanceSnapshots converts []visor.AddressBalanceSnapshot to []AddressBalanceSnapshot
+func NewAddressBalanceSnapshots(snapshots []visor.AddressBalanceSnapshot) ([]AddressBalanceSnapshot, error) {
+	rSnapshots := make([]AddressBalanceSnapshot, len(snapshots))
+	for i, s := range snapshots {
+		coins, err := droplet.ToString(s.Coins)
+		if err != nil {
+			return nil, err
+		}
+
+		received, err := droplet.ToString(s.Received)
+		if err != nil {
+			return nil, err
+		}
+
+		sent, err := droplet.ToString(s.Sent)
+		if err != nil {
+			return nil, err
+		}
+
+		rSnapshots[i] = AddressBalanceSnapshot{
+			BlockSeq: s.BlockSeq,
+			Time:     s.Time,
+			Coins:    coins,
+			Hours:    s.Hours,
+			Received: received,
+			Sent:     sent,
+		}
+	}
+
+	return rSnapshots, nil
+}
@@ -7,6 +7,7 @@ package readable
 import (
 	"errors"
 
+	"github.com/ness-network/ness/src/visor"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 )
@@ -143,3 +144,47 @@ func NewBlocks(blocks []coin.SignedBlock) (*Blocks, error) {
 		Blocks: rbs,
 	}, nil
 }
+
+// BlockTemplateTransaction represents a readable transaction of a block template
+type BlockTemplateTransaction struct {
+	Transaction Transaction `json:"transaction"`
+	Fee         uint64      `json:"fee"`
+	Size        uint32      `json:"size"`
+	PackageFee  uint64      `json:"package_fee"`
+	PackageSize uint32      `json:"package_size"`
+}
+
+// BlockTemplate represents a readable block template
+type BlockTemplate struct {
+	Head         BlockHeader                `json:"head"`
+	Fee          uint64                     `json:"fee"`
+	Size         uint32                     `json:"size"`
+	Transactions []BlockTemplateTransaction `json:"transactions"`
+}
+
+// NewBlockTemplate converts visor.BlockTemplate to BlockTemplate
+func NewBlockTemplate(bt *visor.BlockTemplate) (*BlockTemplate, error) {
+	txns := make([]BlockTemplateTransaction, len(bt.Transactions))
+	for i, t := range bt.Transactions {
+		isGenesis := false // block template transactions are never the genesis transaction
+		txn, err := NewTransaction(t.Transaction, isGenesis)
+		if err != nil {
+			return nil, err
+		}
+
+		txns[i] = BlockTemplateTransaction{
+			Transaction: *txn,
+			Fee:         t.Fee,
+			Size:        t.Size,
+			PackageFee:  t.PackageFee,
+			PackageSize: t.PackageSize,
+		}
+	}
+
+	return &BlockTemplate{
+		Head:         NewBlockHeader(bt.Head),
+		Fee:          bt.Fee,
+		Size:         bt.Size,
+		Transactions: txns,
+	}, nil
+}
@@ -1,8 +1,9 @@
 package readable
 
 import (
-	"github.com/skycoin/skycoin/src/daemon"
-	"github.com/skycoin/skycoin/src/visor"
+	"github.com/ness-network/ness/src/daemon"
+	"github.com/ness-network/ness/src/visor"
+	"github.com/ness-network/ness/src/visor/blockdb"
 )
 
 // BlockchainMetadata encapsulates useful information from the coin.Blockchain
@@ -13,17 +14,45 @@ type BlockchainMetadata struct {
 	Unspents uint64 `json:"unspents"`
 	// Number of known unconfirmed txns
 	Unconfirmed uint64 `json:"unconfirmed"`
+	// Block publisher key checkpoints, ordered by seq
+	KeyCheckpoints []KeyCheckpoint `json:"key_checkpoints"`
 }
 
 // NewBlockchainMetadata creates blockchain metadata
 func NewBlockchainMetadata(bm visor.BlockchainMetadata) BlockchainMetadata {
 	return BlockchainMetadata{
-		Head:        NewBlockHeader(bm.HeadBlock.Head),
-		Unspents:    bm.Unspents,
-		Unconfirmed: bm.Unconfirmed,
+		Head:           NewBlockHeader(bm.HeadBlock.Head),
+		Unspents:       bm.Unspents,
+		Unconfirmed:    bm.Unconfirmed,
+		KeyCheckpoints: NewKeyCheckpoints(bm.KeyCheckpoints),
 	}
 }
 
+// KeyCheckpoint hands the block signing authority to a new blockchain pubkey from a block seq onward
+type KeyCheckpoint struct {
+	Seq       uint64 `json:"seq"`
+	Pubkey    string `json:"pubkey"`
+	Signature string `json:"signature"`
+}
+
+// NewKeyCheckpoint creates KeyCheckpoint from blockdb.KeyCheckpoint
+func NewKeyCheckpoint(kc blockdb.KeyCheckpoint) KeyCheckpoint {
+	return KeyCheckpoint{
+		Seq:       kc.Seq,
+		Pubkey:    kc.Pubkey.Hex(),
+		Signature: kc.Sig.Hex(),
+	}
+}
+
+// NewKeyCheckpoints creates []KeyCheckpoint from []blockdb.KeyCheckpoint
+func NewKeyCheckpoints(checkpoints []blockdb.KeyCheckpoint) []KeyCheckpoint {
+	kcs := make([]KeyCheckpoint, len(checkpoints))
+	for i, kc := range checkpoints {
+		kcs[i] = NewKeyCheckpoint(kc)
+	}
+	return kcs
+}
+
 // BlockchainProgress is the current blockchain syncing status
 type BlockchainProgress struct {
 	// Our current blockchain length
@@ -32,6 +61,12 @@ type BlockchainProgress struct {
 	Highest uint64 `json:"highest"`
 	// Individual blockchain length reports from peers
 	Peers []PeerBlockchainHeight `json:"peers"`
+	// Height of the verified header chain of the headers-first sync
+	Headers uint64 `json:"headers"`
+	// Number of block downloads in flight
+	BlocksInFlight uint64 `json:"blocks_in_flight"`
+	// Number of downloaded blocks waiting to be executed
+	BlocksDownloaded uint64 `json:"blocks_downloaded"`
 }
 
 // PeerBlockchainHeight is a peer's IP address with their reported blockchain height
@@ -54,5 +89,9 @@ func NewBlockchainProgress(bp *daemon.BlockchainProgress) BlockchainProgress {
 		Current: bp.Current,
 		Highest: bp.Highest,
 		Peers:   peers,
+
+		Headers:          bp.Headers,
+		BlocksInFlight:   bp.BlocksInFlight,
+		BlocksDownloaded: bp.BlocksDownloaded,
 	}
 }
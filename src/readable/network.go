@@ -1,7 +1,8 @@
 package readable
 
 import (
-	"github.com/skycoin/skycoin/src/daemon"
+	"github.com/ness-network/ness/src/daemon"
+	"github.com/ness-network/ness/src/daemon/pex"
 	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/util/useragent"
 )
@@ -21,6 +22,11 @@ type Connection struct {
 	UserAgent            useragent.Data         `json:"user_agent"`
 	IsTrustedPeer        bool                   `json:"is_trusted_peer"`
 	UnconfirmedVerifyTxn VerifyTxn              `json:"unconfirmed_verify_transaction"`
+	BytesReceived        uint64                 `json:"bytes_received"`
+	BytesSent            uint64                 `json:"bytes_sent"`
+	MessagesReceived     map[string]uint64      `json:"messages_received"`
+	MessagesSent         map[string]uint64      `json:"messages_sent"`
+	MessagesThrottled    map[string]uint64      `json:"messages_throttled"`
 }
 
 // NewConnection copies daemon.Connection to a struct with json tags
@@ -53,6 +59,11 @@ func NewConnection(c *daemon.Connection) Connection {
 		UserAgent:            c.UserAgent,
 		IsTrustedPeer:        c.Pex.Trusted,
 		UnconfirmedVerifyTxn: NewVerifyTxn(c.UnconfirmedVerifyTxn),
+		BytesReceived:        c.Gnet.Stats.BytesReceived,
+		BytesSent:            c.Gnet.Stats.BytesSent,
+		MessagesReceived:     c.Gnet.Stats.MessagesReceived,
+		MessagesSent:         c.Gnet.Stats.MessagesSent,
+		MessagesThrottled:    c.MessagesThrottled,
 	}
 }
 
@@ -71,3 +82,21 @@ func NewVerifyTxn(p params.VerifyTxn) VerifyTxn {
 		MaxDropletPrecision: p.MaxDropletPrecision,
 	}
 }
+
+// Ban a banned host
+type Ban struct {
+	Host    string `json:"host"`
+	Reason  string `json:"reason"`
+	Created int64  `json:"created"`
+	Expires int64  `json:"expires"`
+}
+
+// NewBan copies pex.Ban to a struct with json tags
+func NewBan(b pex.Ban) Ban {
+	return Ban{
+		Host:    b.Host,
+		Reason:  b.Reason,
+		Created: b.Created,
+		Expires: b.Expires,
+	}
+}
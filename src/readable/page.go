@@ -6,3 +6,10 @@ type PageInfo struct {
 	PageSize    uint64 `json:"page_size"`
 	CurrentPage uint64 `json:"current_page"`
 }
+
+// CursorPageInfo represents the pagination info of pages walked with cursors
+type CursorPageInfo struct {
+	PageSize uint64 `json:"page_size"`
+	// NextCursor is the cursor of the next page, empty if it is the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
@@ -1,8 +1,8 @@
 package readable
 
 import (
+	"github.com/ness-network/ness/src/visor"
 	"github.com/skycoin/skycoin/src/util/droplet"
-	"github.com/skycoin/skycoin/src/visor"
 )
 
 // RichlistBalance holds info an address balance holder
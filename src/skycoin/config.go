@@ -18,10 +18,11 @@ import (
 
 	"log"
 
-	"github.com/skycoin/skycoin/src/api"
+	"github.com/ness-network/ness/src/api"
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/visor"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/params"
-	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/util/droplet"
 	"github.com/skycoin/skycoin/src/util/file"
 	"github.com/skycoin/skycoin/src/util/useragent"
@@ -81,6 +82,12 @@ type NodeConfig struct {
 	Address string
 	// gnet uses this for TCP incoming and outgoing
 	Port int
+	// SOCKS5 proxy address for outgoing peer connections, such as a Tor daemon.
+	// Onion peers are only connected to through the proxy
+	ProxyAddress string
+	// Onion address of the Tor onion service that forwards to this node.
+	// Advertised to peers instead of the IP address
+	OnionAddress string
 	// MaxConnections is the maximum number of total connections allowed
 	MaxConnections int
 	// Maximum outgoing connections to maintain
@@ -99,6 +106,12 @@ type NodeConfig struct {
 	MaxLastBlocksCount uint64
 	// PeerlistSize represents the maximum number of peers that the pex would maintain
 	PeerlistSize int
+	// PeerlistMaxPerSource is the maximum number of untried peers announced by a single peer. 0 means no limit
+	PeerlistMaxPerSource int
+	// BanThreshold is the misbehavior score at which a peer is banned. 0 disables banning misbehaving peers
+	BanThreshold int
+	// BanDuration is how long misbehaving peers are banned for
+	BanDuration time.Duration
 	// Wallet Address Version
 	// AddressVersion string
 	// Remote web interface
@@ -154,6 +167,15 @@ type NodeConfig struct {
 	CreateBlockVerifyTxn params.VerifyTxn
 	// Maximum total size of transactions in a block
 	MaxBlockTransactionsSize uint32
+	// Maximum number of unconfirmed transactions. 0 means no limit.
+	MaxUnconfirmedCount uint64
+	// Maximum total size of unconfirmed transactions, in bytes. 0 means no limit.
+	MaxUnconfirmedBytes uint64
+	// Replace unconfirmed transactions by transactions that spend the same inputs and burn more coin hours.
+	// Any unconfirmed transaction can be replaced, transactions do not signal whether they may be replaced.
+	EnableReplaceByFee bool
+	// Maximum number of main chain blocks rolled back to reorganize the chain to a side branch. 0 means no limit.
+	MaxReorgDepth uint64
 
 	unconfirmedBurnFactor          uint64
 	maxUnconfirmedTransactionSize  uint64
@@ -168,6 +190,9 @@ type NodeConfig struct {
 	WalletDirectory string
 	// Wallet crypto type
 	WalletCryptoType string
+	// Number of unused addresses kept after the last used address of each chain of the bip44 wallets.
+	// 0 disables the background address discovery
+	WalletGapLimit uint64
 
 	// Key-value storage
 	// Default to ${DataDirectory}/data
@@ -180,6 +205,11 @@ type NodeConfig struct {
 	CustomPeersFile string
 
 	RunBlockPublisher bool
+	// Comma separated list of the public keys of the block publishers that produce blocks through consensus
+	PublisherPubkeysStr string
+	publisherPubkeys    []cipher.PubKey
+	// Number of block publishers that must sign a block. 0 means a majority of the publishers
+	PublisherQuorum int
 
 	/* Developer options */
 
@@ -265,6 +295,9 @@ func NewNodeConfig(mode string, node fiber.NodeConfig) NodeConfig {
 		MaxIncomingMessageLength: 1024 * 1024,
 		MaxLastBlocksCount:       256,
 		PeerlistSize:             65535,
+		PeerlistMaxPerSource:     1024,
+		BanThreshold:             100,
+		BanDuration:              time.Hour * 24,
 		// Wallet Address Version
 		// AddressVersion: "test",
 		// Remote web interface
@@ -307,10 +340,14 @@ func NewNodeConfig(mode string, node fiber.NodeConfig) NodeConfig {
 			MaxDropletPrecision: node.CreateBlockMaxDropletPrecision,
 		},
 		MaxBlockTransactionsSize: node.MaxBlockTransactionsSize,
+		MaxUnconfirmedCount:      20000,
+		MaxUnconfirmedBytes:      32 * 1024 * 1024,
+		MaxReorgDepth:            visor.DefaultMaxReorgDepth,
 
 		// Wallets
 		WalletDirectory:  "",
 		WalletCryptoType: string(crypto.DefaultCryptoType),
+		WalletGapLimit:   visor.DefaultGapLimit,
 
 		// Key-value storage
 		KVStorageDirectory: "",
@@ -391,6 +428,14 @@ func (c *Config) postProcess() error {
 		c.Node.blockchainSeckey = cipher.SecKey{}
 	}
 
+	if c.Node.PublisherPubkeysStr != "" {
+		for _, pk := range strings.Split(c.Node.PublisherPubkeysStr, ",") {
+			pubkey, err := cipher.PubKeyFromHex(strings.TrimSpace(pk))
+			panicIfError(err, "Invalid publisher pubkey")
+			c.Node.publisherPubkeys = append(c.Node.publisherPubkeys, pubkey)
+		}
+	}
+
 	home := file.UserHome()
 	c.Node.DataDirectory, err = file.InitDataDir(replaceHome(c.Node.DataDirectory, home))
 	panicIfError(err, "Invalid DataDirectory")
@@ -475,6 +520,18 @@ func (c *Config) postProcess() error {
 		return errors.New("Web interface auth enabled but HTTPS is not enabled. Use -web-interface-plaintext-auth=true if this is desired")
 	}
 
+	if c.Node.PeerlistMaxPerSource < 0 {
+		return errors.New("-peerlist-max-per-source cannot be negative")
+	}
+
+	if c.Node.BanThreshold < 0 {
+		return errors.New("-ban-threshold cannot be negative")
+	}
+
+	if c.Node.BanDuration <= 0 {
+		return errors.New("-ban-duration must be positive")
+	}
+
 	if c.Node.MaxConnections < c.Node.MaxOutgoingConnections+c.Node.MaxIncomingConnections {
 		return errors.New("-max-connections must be >= -max-outgoing-connections + -max-incoming-connections")
 	}
@@ -545,6 +602,14 @@ func (c *Config) postProcess() error {
 		return errors.New("-max-block-size must be >= -max-txn-size-create-block")
 	}
 
+	if c.Node.MaxUnconfirmedBytes != 0 && c.Node.MaxUnconfirmedBytes < uint64(c.Node.UnconfirmedVerifyTxn.MaxTransactionSize) {
+		return errors.New("-max-unconfirmed-bytes must be >= -max-txn-size-unconfirmed")
+	}
+
+	if c.Node.PublisherQuorum < 0 || c.Node.PublisherQuorum > len(c.Node.publisherPubkeys) {
+		return errors.New("-publisher-quorum must be between 0 and the number of -publisher-public-keys")
+	}
+
 	if c.Node.UnconfirmedVerifyTxn.BurnFactor < params.MinBurnFactor {
 		return fmt.Errorf("-burn-factor-unconfirmed must be >= params.MinBurnFactor (%d)", params.MinBurnFactor)
 	}
@@ -600,6 +665,7 @@ func buildAPISets(c NodeConfig) (map[string]struct{}, error) {
 		api.EndpointsTransaction,
 		api.EndpointsNetCtrl,
 		api.EndpointsStorage,
+		api.EndpointsPublisher,
 		// Do not include insecure or deprecated API sets, they must always
 		// be explicitly enabled through -enable-api-sets
 	}
@@ -633,7 +699,8 @@ func validateAPISets(opt string, apiSets []string) error {
 			api.EndpointsWallet,
 			api.EndpointsInsecureWalletSeed,
 			api.EndpointsNetCtrl,
-			api.EndpointsStorage:
+			api.EndpointsStorage,
+			api.EndpointsPublisher:
 		case "":
 			continue
 		default:
@@ -658,6 +725,8 @@ func (c *NodeConfig) RegisterFlags() {
 	flag.BoolVar(&c.DisableCSP, "disable-csp", c.DisableCSP, "disable content-security-policy in http response")
 	flag.StringVar(&c.Address, "address", c.Address, "IP Address to run application on. Leave empty to default to a public interface")
 	flag.IntVar(&c.Port, "port", c.Port, "Port to run application on")
+	flag.StringVar(&c.ProxyAddress, "proxy", c.ProxyAddress, "SOCKS5 proxy address for all outgoing peer connections, such as a Tor daemon at 127.0.0.1:9050")
+	flag.StringVar(&c.OnionAddress, "onion-address", c.OnionAddress, "Onion address of the Tor onion service that forwards to this node, e.g. xxx.onion:6677. Listens on localhost and advertises the onion address to peers instead of the IP address")
 
 	flag.BoolVar(&c.WebInterface, "web-interface", c.WebInterface, "enable the web interface")
 	flag.IntVar(&c.WebInterfacePort, "web-interface-port", c.WebInterfacePort, "port to serve web interface on")
@@ -675,6 +744,7 @@ func (c *NodeConfig) RegisterFlags() {
 		api.EndpointsNetCtrl,
 		api.EndpointsInsecureWalletSeed,
 		api.EndpointsStorage,
+		api.EndpointsPublisher,
 	}
 	flag.StringVar(&c.EnabledAPISets, "enable-api-sets", c.EnabledAPISets, fmt.Sprintf("enable API set. Options are %s. Multiple values should be separated by comma", strings.Join(allAPISets, ", ")))
 	flag.StringVar(&c.DisabledAPISets, "disable-api-sets", c.DisabledAPISets, fmt.Sprintf("disable API set. Options are %s. Multiple values should be separated by comma", strings.Join(allAPISets, ", ")))
@@ -702,7 +772,7 @@ func (c *NodeConfig) RegisterFlags() {
 	flag.BoolVar(&c.ResetCorruptDB, "reset-corrupt-db", c.ResetCorruptDB, "reset the database if corrupted, and continue running instead of exiting")
 
 	flag.BoolVar(&c.DisableDefaultPeers, "disable-default-peers", c.DisableDefaultPeers, "disable the hardcoded default peers")
-	flag.StringVar(&c.CustomPeersFile, "custom-peers-file", c.CustomPeersFile, "load custom peers from a newline separate list of ip:port in a file. Note that this is different from the peers.json file in the data directory")
+	flag.StringVar(&c.CustomPeersFile, "custom-peers-file", c.CustomPeersFile, "load custom peers from a newline separate list of ip:port in a file. Note that this is different from the addrbook.json file in the data directory")
 
 	flag.StringVar(&c.UserAgentRemark, "user-agent-remark", c.UserAgentRemark, "additional remark to include in the user agent sent over the wire protocol")
 
@@ -713,11 +783,17 @@ func (c *NodeConfig) RegisterFlags() {
 	flag.Uint64Var(&c.createBlockMaxTransactionSize, "max-txn-size-create-block", uint64(c.CreateBlockVerifyTxn.MaxTransactionSize), "maximum size of a transaction applied when creating blocks")
 	flag.Uint64Var(&c.createBlockMaxDropletPrecision, "max-decimals-create-block", uint64(c.CreateBlockVerifyTxn.MaxDropletPrecision), "max number of decimal places applied when creating blocks")
 	flag.Uint64Var(&c.maxBlockSize, "max-block-size", uint64(c.MaxBlockTransactionsSize), "maximum total size of transactions in a block")
+	flag.Uint64Var(&c.MaxUnconfirmedCount, "max-unconfirmed-count", c.MaxUnconfirmedCount, "maximum number of unconfirmed transactions. Transactions with the lowest fee per byte are evicted when the pool is full. 0 means no limit")
+	flag.Uint64Var(&c.MaxUnconfirmedBytes, "max-unconfirmed-bytes", c.MaxUnconfirmedBytes, "maximum total size of unconfirmed transactions. Transactions with the lowest fee per byte are evicted when the pool is full. 0 means no limit")
+	flag.Uint64Var(&c.MaxReorgDepth, "max-reorg-depth", c.MaxReorgDepth, "maximum number of blocks rolled back to reorganize the chain to a side branch of the block publishers. 0 means no limit")
+	flag.BoolVar(&c.EnableReplaceByFee, "enable-replace-by-fee", c.EnableReplaceByFee, "replace any unconfirmed transaction by a transaction that spends the same inputs and burns more coin hours")
 	flag.Uint64Var(&c.MaxLastBlocksCount, "max-last-blocks-count", c.MaxLastBlocksCount, "Maximum number of blocks to response for API /api/v1/last_blocks")
 
 	flag.BoolVar(&c.RunBlockPublisher, "block-publisher", c.RunBlockPublisher, "run the daemon as a block publisher")
 	flag.StringVar(&c.BlockchainPubkeyStr, "blockchain-public-key", c.BlockchainPubkeyStr, "public key of the blockchain")
 	flag.StringVar(&c.BlockchainSeckeyStr, "blockchain-secret-key", c.BlockchainSeckeyStr, "secret key of the blockchain")
+	flag.StringVar(&c.PublisherPubkeysStr, "publisher-public-keys", c.PublisherPubkeysStr, "comma separated list of the public keys of the block publishers that produce blocks through consensus")
+	flag.IntVar(&c.PublisherQuorum, "publisher-quorum", c.PublisherQuorum, "number of block publishers that must sign a block. 0 means a majority of the publishers")
 
 	flag.StringVar(&c.GenesisAddressStr, "genesis-address", c.GenesisAddressStr, "genesis address")
 	flag.StringVar(&c.GenesisSignatureStr, "genesis-signature", c.GenesisSignatureStr, "genesis block signature")
@@ -730,11 +806,15 @@ func (c *NodeConfig) RegisterFlags() {
 	flag.IntVar(&c.MaxIncomingConnections, "max-incoming-connections", c.MaxIncomingConnections, "Maximum number of incoming connections allowd")
 	flag.IntVar(&c.MaxDefaultPeerOutgoingConnections, "max-default-peer-outgoing-connections", c.MaxDefaultPeerOutgoingConnections, "The maximum default peer outgoing connections allowed")
 	flag.IntVar(&c.PeerlistSize, "peerlist-size", c.PeerlistSize, "Max number of peers to track in peerlist")
+	flag.IntVar(&c.PeerlistMaxPerSource, "peerlist-max-per-source", c.PeerlistMaxPerSource, "Max number of untried peers in the peerlist announced by a single peer. 0 means no limit")
+	flag.IntVar(&c.BanThreshold, "ban-threshold", c.BanThreshold, "Misbehavior score at which a peer is banned. 0 disables banning misbehaving peers")
+	flag.DurationVar(&c.BanDuration, "ban-duration", c.BanDuration, "How long misbehaving peers are banned for")
 	flag.DurationVar(&c.OutgoingConnectionsRate, "connection-rate", c.OutgoingConnectionsRate, "How often to make an outgoing connection")
 	flag.IntVar(&c.MaxOutgoingMessageLength, "max-out-msg-len", c.MaxOutgoingMessageLength, "Maximum length of outgoing wire messages")
 	flag.IntVar(&c.MaxIncomingMessageLength, "max-in-msg-len", c.MaxIncomingMessageLength, "Maximum length of incoming wire messages")
 	flag.BoolVar(&c.LocalhostOnly, "localhost-only", c.LocalhostOnly, "Run on localhost and only connect to localhost peers")
 	flag.StringVar(&c.WalletCryptoType, "wallet-crypto-type", c.WalletCryptoType, "wallet crypto type. Can be sha256-xor or scrypt-chacha20poly1305")
+	flag.Uint64Var(&c.WalletGapLimit, "wallet-gap-limit", c.WalletGapLimit, "number of unused addresses kept after the last used address of each chain of the bip44 wallets. 0 disables the background address discovery")
 	flag.BoolVar(&c.Version, "version", false, "show node version")
 }
 
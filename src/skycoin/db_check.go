@@ -5,9 +5,9 @@ import (
 
 	"github.com/blang/semver"
 
+	"github.com/ness-network/ness/src/visor"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/util/logging"
-	"github.com/skycoin/skycoin/src/visor"
 	"github.com/skycoin/skycoin/src/visor/dbutil"
 )
 
@@ -41,12 +41,14 @@ type dbCheckCorruptResetter interface {
 
 type dbVerify struct {
 	blockchainPubkey cipher.PubKey
+	publisherPubkeys []cipher.PubKey
+	publisherQuorum  int
 	logger           *logging.Logger
 	quit             chan struct{}
 }
 
 func (dv dbVerify) CheckDatabase(db *dbutil.DB) error {
-	if err := visor.CheckDatabase(db, dv.blockchainPubkey, dv.quit); err != nil {
+	if err := visor.CheckDatabase(db, dv.blockchainPubkey, dv.publisherPubkeys, dv.publisherQuorum, dv.quit); err != nil {
 		if err != visor.ErrVerifyStopped {
 			dv.logger.WithError(err).Error("visor.CheckDatabase failed")
 		}
@@ -57,7 +59,7 @@ func (dv dbVerify) CheckDatabase(db *dbutil.DB) error {
 
 func (dv *dbVerify) ResetCorruptDB(db *dbutil.DB) (*dbutil.DB, error) {
 	dv.logger.Info("Checking database and resetting if corrupted")
-	newDB, err := visor.ResetCorruptDB(db, dv.blockchainPubkey, dv.quit)
+	newDB, err := visor.ResetCorruptDB(db, dv.blockchainPubkey, dv.publisherPubkeys, dv.publisherQuorum, dv.quit)
 	if err != nil {
 		if err != visor.ErrVerifyStopped {
 			dv.logger.WithError(err).Error("visor.ResetCorruptDB failed")
@@ -19,19 +19,19 @@ import (
 	"github.com/blang/semver"
 	"github.com/toqueteos/webbrowser"
 
-	"github.com/skycoin/skycoin/src/api"
+	"github.com/ness-network/ness/src/api"
+	"github.com/ness-network/ness/src/daemon"
+	"github.com/ness-network/ness/src/readable"
+	"github.com/ness-network/ness/src/visor"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/cipher/crypto"
 	"github.com/skycoin/skycoin/src/coin"
-	"github.com/skycoin/skycoin/src/daemon"
 	"github.com/skycoin/skycoin/src/kvstorage"
 	"github.com/skycoin/skycoin/src/params"
-	"github.com/skycoin/skycoin/src/readable"
 	"github.com/skycoin/skycoin/src/util/apputil"
 	"github.com/skycoin/skycoin/src/util/certutil"
 	"github.com/skycoin/skycoin/src/util/droplet"
 	"github.com/skycoin/skycoin/src/util/logging"
-	"github.com/skycoin/skycoin/src/visor"
 	"github.com/skycoin/skycoin/src/visor/dbutil"
 	"github.com/skycoin/skycoin/src/wallet"
 )
@@ -181,6 +181,8 @@ func (c *Coin) Run() error {
 
 	dv := dbVerify{
 		blockchainPubkey: c.config.Node.blockchainPubkey,
+		publisherPubkeys: c.config.Node.publisherPubkeys,
+		publisherQuorum:  c.config.Node.PublisherQuorum,
 		logger:           c.logger,
 		quit:             quit,
 	}
@@ -349,10 +351,17 @@ func (c *Coin) ConfigureVisor() visor.Config {
 
 	vc.BlockchainPubkey = c.config.Node.blockchainPubkey
 	vc.BlockchainSeckey = c.config.Node.blockchainSeckey
+	vc.PublisherPubkeys = c.config.Node.publisherPubkeys
+	vc.PublisherQuorum = c.config.Node.PublisherQuorum
 
 	vc.UnconfirmedVerifyTxn = c.config.Node.UnconfirmedVerifyTxn
 	vc.CreateBlockVerifyTxn = c.config.Node.CreateBlockVerifyTxn
 	vc.MaxBlockTransactionsSize = c.config.Node.MaxBlockTransactionsSize
+	vc.MaxUnconfirmedCount = c.config.Node.MaxUnconfirmedCount
+	vc.MaxUnconfirmedBytes = c.config.Node.MaxUnconfirmedBytes
+	vc.EnableReplaceByFee = c.config.Node.EnableReplaceByFee
+	vc.MaxReorgDepth = c.config.Node.MaxReorgDepth
+	vc.WalletGapLimit = c.config.Node.WalletGapLimit
 
 	vc.GenesisAddress = c.config.Node.genesisAddress
 	vc.GenesisSignature = c.config.Node.genesisSignature
@@ -406,11 +415,13 @@ func (c *Coin) ConfigureDaemon() daemon.Config {
 	dc.Pool.MaxIncomingConnections = c.config.Node.MaxIncomingConnections
 	dc.Pool.MaxIncomingMessageLength = c.config.Node.MaxIncomingMessageLength
 	dc.Pool.MaxOutgoingMessageLength = c.config.Node.MaxOutgoingMessageLength
+	dc.Pool.ProxyAddress = c.config.Node.ProxyAddress
 
 	dc.Pex.DataDirectory = c.config.Node.DataDirectory
 	dc.Pex.Disabled = c.config.Node.DisablePEX
 	dc.Pex.NetworkDisabled = c.config.Node.DisableNetworking
 	dc.Pex.Max = c.config.Node.PeerlistSize
+	dc.Pex.MaxPeersPerSource = c.config.Node.PeerlistMaxPerSource
 	dc.Pex.DownloadPeerList = c.config.Node.DownloadPeerList
 	dc.Pex.PeerListURL = c.config.Node.PeerListURL
 	dc.Pex.DisableTrustedPeers = c.config.Node.DisableDefaultPeers
@@ -427,15 +438,21 @@ func (c *Coin) ConfigureDaemon() daemon.Config {
 	dc.Daemon.DisableNetworking = c.config.Node.DisableNetworking
 	dc.Daemon.Port = c.config.Node.Port
 	dc.Daemon.Address = c.config.Node.Address
+	dc.Daemon.OnionAddress = c.config.Node.OnionAddress
 	dc.Daemon.LocalhostOnly = c.config.Node.LocalhostOnly
 	dc.Daemon.MaxConnections = c.config.Node.MaxConnections
 	dc.Daemon.MaxOutgoingConnections = c.config.Node.MaxOutgoingConnections
+	dc.Daemon.BanThreshold = c.config.Node.BanThreshold
+	dc.Daemon.BanDuration = c.config.Node.BanDuration
 	dc.Daemon.DataDirectory = c.config.Node.DataDirectory
 	dc.Daemon.LogPings = !c.config.Node.DisablePingPong
 	dc.Daemon.BlockchainPubkey = c.config.Node.blockchainPubkey
+	dc.Daemon.PublisherPubkeys = c.config.Node.publisherPubkeys
+	dc.Daemon.PublisherQuorum = c.config.Node.PublisherQuorum
 	dc.Daemon.GenesisHash = c.config.Node.genesisHash
 	dc.Daemon.UserAgent = c.config.Node.userAgent
 	dc.Daemon.UnconfirmedVerifyTxn = c.config.Node.UnconfirmedVerifyTxn
+	dc.Daemon.MessageRateLimits["ANNT"] = daemon.AnnounceTxnsRateLimit(c.config.Node.MaxUnconfirmedCount, dc.Daemon.MaxTxnAnnounceNum)
 
 	if c.config.Node.OutgoingConnectionsRate == 0 {
 		c.config.Node.OutgoingConnectionsRate = time.Millisecond
@@ -8,6 +8,7 @@ import (
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/util/droplet"
 	"github.com/skycoin/skycoin/src/util/fee"
 )
 
@@ -276,3 +277,160 @@ func ChooseSpends(uxa []UxBalance, coins, hours uint64, sortStrategy func([]UxBa
 
 	return nil, ErrInsufficientHours
 }
+
+// ChooseSpendsPrivacy chooses uxout spends to satisfy an amount, spending outputs from as few addresses as possible.
+// Spending outputs of different addresses in one transaction publicly links the addresses,
+// so the outputs of an address are always spent together, emptying the address.
+//     -- PRO: Links the least number of addresses of a wallet.
+//     -- PRO: Avoids round amounts of change, which mark which output of a transaction is the change.
+//     -- CON: May spend more uxouts than needed, which makes the transaction larger.
+// The addresses are chosen by the following procedure:
+//   - The least number of addresses whose outputs cover the amount is used. These are the addresses with the most coins,
+//     except for the last address, which is chosen among the remaining addresses.
+//   - The last address is the one that leaves no change, then the one whose change is not a whole number of coins,
+//     then the one with the least change, then the one with the fewest outputs.
+// Ties are broken by the address bytes, so the choice is deterministic.
+func ChooseSpendsPrivacy(uxa []UxBalance, coins, hours uint64) ([]UxBalance, error) {
+	if coins == 0 {
+		return nil, ErrZeroSpend
+	}
+
+	if len(uxa) == 0 {
+		return nil, ErrNoUnspents
+	}
+
+	var nonzero bool
+	for _, ux := range uxa {
+		if ux.Coins == 0 {
+			logger.Panic("UxOut coins are 0, can't spend")
+			return nil, errors.New("UxOut coins are 0, can't spend")
+		}
+
+		if ux.Hours != 0 {
+			nonzero = true
+		}
+	}
+
+	// Abort if there are no uxouts with non-zero coinhours, they can't be spent yet
+	if !nonzero {
+		return nil, fee.ErrTxnNoFee
+	}
+
+	groups := groupUxBalancesByAddress(uxa)
+
+	// The n addresses with the most coins have at least as many coins as any other n addresses,
+	// so n-1 of them are spent with the best remaining address that completes the amount
+	var haveCoins uint64
+	var haveHours uint64
+	for n := range groups {
+		if i := chooseLastSpendAddress(groups[n:], haveCoins, haveHours, coins, hours); i != -1 {
+			var spending []UxBalance
+			for _, g := range groups[:n] {
+				spending = append(spending, g.uxa...)
+			}
+			return append(spending, groups[n+i].uxa...), nil
+		}
+
+		haveCoins += groups[n].coins
+		haveHours += groups[n].hours
+	}
+
+	if haveCoins < coins {
+		return nil, ErrInsufficientBalance
+	}
+
+	return nil, ErrInsufficientHours
+}
+
+// addressUxBalances are the uxouts of an address, with their total coins and hours
+type addressUxBalances struct {
+	address cipher.Address
+	uxa     []UxBalance
+	coins   uint64
+	hours   uint64
+}
+
+// groupUxBalancesByAddress groups uxouts by address. The groups are sorted by coins highest, hours highest,
+// with the address bytes as a tiebreaker, and the uxouts of each group are sorted coins highest to lowest.
+func groupUxBalancesByAddress(uxa []UxBalance) []addressUxBalances {
+	index := make(map[cipher.Address]int)
+	var groups []addressUxBalances
+	for _, ux := range uxa {
+		i, ok := index[ux.Address]
+		if !ok {
+			i = len(groups)
+			index[ux.Address] = i
+			groups = append(groups, addressUxBalances{
+				address: ux.Address,
+			})
+		}
+
+		groups[i].uxa = append(groups[i].uxa, ux)
+		groups[i].coins += ux.Coins
+		groups[i].hours += ux.Hours
+	}
+
+	for _, g := range groups {
+		sortSpendsCoinsHighToLow(g.uxa)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		a := groups[i]
+		b := groups[j]
+
+		if a.coins == b.coins {
+			if a.hours == b.hours {
+				return bytes.Compare(a.address.Bytes(), b.address.Bytes()) < 0
+			}
+			return a.hours > b.hours
+		}
+		return a.coins > b.coins
+	})
+
+	return groups
+}
+
+// chooseLastSpendAddress returns the index of the address in groups that completes the amount
+// with haveCoins and haveHours best, or -1 if none of them completes it
+func chooseLastSpendAddress(groups []addressUxBalances, haveCoins, haveHours, coins, hours uint64) int {
+	best := -1
+	var bestChange uint64
+	for i, g := range groups {
+		totalCoins := haveCoins + g.coins
+		totalHours := haveHours + g.hours
+		if totalCoins < coins || totalHours == 0 || fee.RemainingHours(totalHours, params.UserVerifyTxn.BurnFactor) < hours {
+			continue
+		}
+
+		change := totalCoins - coins
+		if best == -1 || isBetterChange(change, bestChange, len(g.uxa), len(groups[best].uxa)) {
+			best = i
+			bestChange = change
+		}
+	}
+
+	return best
+}
+
+// isBetterChange returns true if change a, spending na uxouts of the last address,
+// is preferred to change b, spending nb uxouts of the last address
+func isBetterChange(a, b uint64, na, nb int) bool {
+	if (a == 0) != (b == 0) {
+		return a == 0
+	}
+
+	if isRoundCoins(a) != isRoundCoins(b) {
+		return !isRoundCoins(a)
+	}
+
+	if a != b {
+		return a < b
+	}
+
+	return na < nb
+}
+
+// isRoundCoins returns true if coins is a nonzero whole number of coins
+func isRoundCoins(coins uint64) bool {
+	return coins != 0 && coins%droplet.Multiplier == 0
+}
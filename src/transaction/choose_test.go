@@ -11,6 +11,7 @@ import (
 
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/util/droplet"
 	"github.com/skycoin/skycoin/src/util/fee"
 )
 
@@ -532,3 +533,242 @@ func verifySortedHoursLowToHigh(t *testing.T, uxb []UxBalance) {
 		return a.Hours <= b.Hours
 	})
 }
+
+func TestChooseSpendsPrivacy(t *testing.T) {
+	addr := func(seed string) cipher.Address {
+		pk, _ := cipher.MustGenerateDeterministicKeyPair([]byte(seed))
+		return cipher.AddressFromPubKey(pk)
+	}
+
+	ux := func(name string, a cipher.Address, coins, hours uint64) UxBalance {
+		return UxBalance{
+			Hash:    cipher.SumSHA256([]byte(name)),
+			Address: a,
+			Coins:   coins,
+			Hours:   hours,
+		}
+	}
+
+	addrA := addr("a")
+	addrB := addr("b")
+	addrC := addr("c")
+	addrD := addr("d")
+
+	a1 := ux("a1", addrA, 4e6, 10)
+	a2 := ux("a2", addrA, 3e6, 10)
+	b1 := ux("b1", addrB, 6e6, 5)
+	c1 := ux("c1", addrC, 2500e3, 2)
+	c2 := ux("c2", addrC, 500e3, 2)
+	d1 := ux("d1", addrD, 1500e3, 1)
+
+	uxa := []UxBalance{c2, a2, d1, b1, a1, c1}
+
+	cases := []struct {
+		name           string
+		uxa            []UxBalance
+		coins          uint64
+		hours          uint64
+		spends         []UxBalance
+		nonRoundChange bool
+		err            error
+	}{
+		{
+			name:  "zero coins",
+			uxa:   uxa,
+			coins: 0,
+			err:   ErrZeroSpend,
+		},
+		{
+			name:  "no unspents",
+			coins: 1e6,
+			err:   ErrNoUnspents,
+		},
+		{
+			name:  "no coin hours",
+			uxa:   []UxBalance{ux("z1", addrA, 1e6, 0), ux("z2", addrB, 1e6, 0)},
+			coins: 1e6,
+			err:   fee.ErrTxnNoFee,
+		},
+		{
+			name:   "one address without change",
+			uxa:    uxa,
+			coins:  6e6,
+			spends: []UxBalance{b1},
+		},
+		{
+			name:   "one address without change, smallest address",
+			uxa:    uxa,
+			coins:  1500e3,
+			spends: []UxBalance{d1},
+		},
+		{
+			name:   "one address emptied with the least change",
+			uxa:    uxa,
+			coins:  1700e3,
+			spends: []UxBalance{c1, c2},
+		},
+		{
+			name:           "change of whole coins avoided",
+			uxa:            uxa,
+			coins:          500e3,
+			spends:         []UxBalance{c1, c2},
+			nonRoundChange: true,
+		},
+		{
+			name:           "change of whole coins avoided with two addresses",
+			uxa:            uxa,
+			coins:          8e6,
+			spends:         []UxBalance{a1, a2, d1},
+			nonRoundChange: true,
+		},
+		{
+			name:   "change of whole coins if unavoidable",
+			uxa:    uxa,
+			coins:  5e6,
+			spends: []UxBalance{b1},
+		},
+		{
+			name:   "hours exclude addresses",
+			uxa:    uxa,
+			coins:  1500e3,
+			hours:  4,
+			spends: []UxBalance{b1},
+		},
+		{
+			name:   "two addresses without change",
+			uxa:    uxa,
+			coins:  10e6,
+			spends: []UxBalance{a1, a2, c1, c2},
+		},
+		{
+			name:   "two addresses with change",
+			uxa:    uxa,
+			coins:  12e6,
+			spends: []UxBalance{a1, a2, b1},
+		},
+		{
+			name:   "three addresses",
+			uxa:    uxa,
+			coins:  16e6,
+			spends: []UxBalance{a1, a2, b1, c1, c2},
+		},
+		{
+			name:   "all addresses",
+			uxa:    uxa,
+			coins:  17500e3,
+			spends: []UxBalance{a1, a2, b1, c1, c2, d1},
+		},
+		{
+			name:  "insufficient balance",
+			uxa:   uxa,
+			coins: 18e6,
+			err:   ErrInsufficientBalance,
+		},
+		{
+			name:  "insufficient hours",
+			uxa:   uxa,
+			coins: 1e6,
+			hours: 29,
+			err:   ErrInsufficientHours,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			uxa := make([]UxBalance, len(tc.uxa))
+			copy(uxa, tc.uxa)
+
+			spends, err := ChooseSpendsPrivacy(uxa, tc.coins, tc.hours)
+			if tc.err != nil {
+				require.Equal(t, tc.err, err)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.spends, spends)
+
+			if tc.nonRoundChange {
+				var total uint64
+				for _, ux := range spends {
+					total += ux.Coins
+				}
+				change := total - tc.coins
+				require.NotZero(t, change)
+				require.NotZero(t, change%droplet.Multiplier)
+			}
+		})
+	}
+
+	// 0 coins in a UxBalance (panic)
+	require.Panics(t, func() {
+		ChooseSpendsPrivacy([]UxBalance{ux("z", addrA, 0, 1)}, 1e6, 0) // nolint: errcheck
+	})
+}
+
+func TestIsBetterChange(t *testing.T) {
+	cases := []struct {
+		name   string
+		a, b   uint64
+		na, nb int
+		better bool
+	}{
+		{
+			name:   "no change is better than any change",
+			a:      0,
+			b:      1,
+			better: true,
+		},
+		{
+			name:   "any change is worse than no change",
+			a:      1,
+			b:      0,
+			better: false,
+		},
+		{
+			name:   "non-round change is better than less round change",
+			a:      2500e3,
+			b:      1e6,
+			better: true,
+		},
+		{
+			name:   "round change is worse than more non-round change",
+			a:      1e6,
+			b:      2500e3,
+			better: false,
+		},
+		{
+			name:   "less non-round change is better",
+			a:      500e3,
+			b:      2500e3,
+			better: true,
+		},
+		{
+			name:   "less round change is better",
+			a:      1e6,
+			b:      2e6,
+			better: true,
+		},
+		{
+			name:   "same change with fewer uxouts is better",
+			a:      500e3,
+			b:      500e3,
+			na:     1,
+			nb:     2,
+			better: true,
+		},
+		{
+			name:   "same change with as many uxouts is not better",
+			a:      500e3,
+			b:      500e3,
+			na:     2,
+			nb:     2,
+			better: false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.better, isBetterChange(tc.a, tc.b, tc.na, tc.nb))
+		})
+	}
+}
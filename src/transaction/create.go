@@ -36,6 +36,8 @@ var (
 //   - If the total amount of coins in the chosen outputs is exactly equal to the requested amount of coins,
 //     such that there would be no change output but hours remain as change, another output will be chosen to create change,
 //     if the coinhour cost of adding that output is less than the coinhours that would be lost as change
+// If params.SpendStrategy is SpendStrategyPrivacy, the outputs are chosen by ChooseSpendsPrivacy instead,
+// and no extra output is chosen to create change.
 // If receiving hours are not explicitly specified, hours are allocated amongst the receiving outputs proportional to the number of coins being sent to them.
 // If the change address is not specified, the address whose bytes are lexically sorted first is chosen from the owners of the outputs being spent.
 func Create(p Params, auxs coin.AddressUxOuts, headTime uint64) (*coin.Transaction, []UxBalance, error) {
@@ -88,10 +90,16 @@ func create(p Params, auxs coin.AddressUxOuts, headTime uint64, callCount int) (
 		}
 	}
 
-	// Use the MinimizeUxOuts strategy, to use least possible uxouts
+	// Use the MinimizeUxOuts strategy by default, to use least possible uxouts
 	// this will allow more frequent spending
 	// we don't need to check whether we have sufficient balance beforehand as ChooseSpends already checks that
-	spends, err := ChooseSpendsMinimizeUxOuts(uxb, totalOutCoins, requestedHours)
+	var spends []UxBalance
+	switch p.SpendStrategy {
+	case SpendStrategyPrivacy:
+		spends, err = ChooseSpendsPrivacy(uxb, totalOutCoins, requestedHours)
+	default:
+		spends, err = ChooseSpendsMinimizeUxOuts(uxb, totalOutCoins, requestedHours)
+	}
 	if err != nil {
 		return nil, nil, err
 	}
@@ -222,7 +230,8 @@ func create(p Params, auxs coin.AddressUxOuts, headTime uint64, callCount int) (
 	// This chooses an available input with the least number of coin hours;
 	// if the extra coin hour fee incurred by this additional input is less than
 	// the remaining coin hours, the input is added.
-	if changeCoins == 0 && changeHours > 0 {
+	// The privacy strategy doesn't add an input, it would link another address to the spent addresses.
+	if changeCoins == 0 && changeHours > 0 && p.SpendStrategy != SpendStrategyPrivacy {
 		logger.Info("Trying to recover change hours by forcing an extra input")
 		// Find the output with the least coin hours
 		// If size of the fee for this output is less than the changeHours, add it
@@ -469,6 +469,40 @@ func TestCreate(t *testing.T) {
 			},
 		},
 
+		{
+			name: "manual, multiple outputs, multiple addresses, privacy spend strategy",
+			params: Params{
+				ChangeAddress: &changeAddress,
+				HoursSelection: HoursSelection{
+					Type: HoursSelectionTypeManual,
+				},
+				To: []coin.TransactionOutput{
+					{
+						Address: addrs[0],
+						Hours:   50,
+						Coins:   1e6 + 1,
+					},
+					{
+						Address: addrs[1],
+						Hours:   70,
+						Coins:   2e6,
+					},
+				},
+				SpendStrategy: SpendStrategyPrivacy,
+			},
+			addressUnspents: coin.AddressUxOuts{
+				extraWalletAddrs[0]: []coin.UxOut{extraUxouts[0][0]},
+				extraWalletAddrs[3]: []coin.UxOut{extraUxouts[3][1], extraUxouts[3][2]},
+				extraWalletAddrs[5]: []coin.UxOut{extraUxouts[5][6]},
+			},
+			chosenUnspents: []coin.UxOut{extraUxouts[3][1], extraUxouts[3][2]},
+			changeOutput: &coin.TransactionOutput{
+				Address: changeAddress,
+				Hours:   62,
+				Coins:   1e6 - 1,
+			},
+		},
+
 		{
 			name: "auto, multiple outputs, share factor 0.5",
 			params: Params{
@@ -31,6 +31,12 @@ const (
 
 	// HoursSelectionModeShare will distribute coin hours equally amongst destinations
 	HoursSelectionModeShare = "share"
+
+	// SpendStrategyMinimizeUxOuts chooses the outputs to spend with ChooseSpendsMinimizeUxOuts.
+	// This is the default spend strategy.
+	SpendStrategyMinimizeUxOuts = "minimize_uxouts"
+	// SpendStrategyPrivacy chooses the outputs to spend with ChooseSpendsPrivacy
+	SpendStrategyPrivacy = "privacy"
 )
 
 var (
@@ -60,6 +66,8 @@ var (
 	ErrInvalidShareFactor = NewError(errors.New("HoursSelection.ShareFactor can only be used for share mode"))
 	// ErrShareFactorOutOfRange HoursSelection.ShareFactor must be >= 0 and <= 1
 	ErrShareFactorOutOfRange = NewError(errors.New("HoursSelection.ShareFactor must be >= 0 and <= 1"))
+	// ErrInvalidSpendStrategy Invalid SpendStrategy
+	ErrInvalidSpendStrategy = NewError(errors.New("Invalid SpendStrategy"))
 )
 
 // HoursSelection defines options for hours distribution
@@ -74,6 +82,9 @@ type Params struct {
 	HoursSelection HoursSelection
 	To             []coin.TransactionOutput
 	ChangeAddress  *cipher.Address
+	// SpendStrategy is the name of the strategy that chooses the outputs to spend.
+	// Defaults to SpendStrategyMinimizeUxOuts if empty.
+	SpendStrategy string
 }
 
 // Validate validates Params
@@ -135,6 +146,12 @@ func (c Params) Validate() error {
 		return ErrInvalidHoursSelectionType
 	}
 
+	switch c.SpendStrategy {
+	case "", SpendStrategyMinimizeUxOuts, SpendStrategyPrivacy:
+	default:
+		return ErrInvalidSpendStrategy
+	}
+
 	if c.HoursSelection.ShareFactor == nil {
 		if c.HoursSelection.Mode == HoursSelectionModeShare {
 			return ErrMissingShareFactor
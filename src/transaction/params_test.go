@@ -270,6 +270,43 @@ func TestCreateWalletParamsVerify(t *testing.T) {
 				},
 			},
 		},
+
+		{
+			name: "invalid spend strategy",
+			params: Params{
+				ChangeAddress: &changeAddress,
+				To:            toManual,
+				HoursSelection: HoursSelection{
+					Type: HoursSelectionTypeManual,
+				},
+				SpendStrategy: "foo",
+			},
+			err: "Invalid SpendStrategy",
+		},
+
+		{
+			name: "valid privacy spend strategy",
+			params: Params{
+				ChangeAddress: &changeAddress,
+				To:            toManual,
+				HoursSelection: HoursSelection{
+					Type: HoursSelectionTypeManual,
+				},
+				SpendStrategy: SpendStrategyPrivacy,
+			},
+		},
+
+		{
+			name: "valid minimize uxouts spend strategy",
+			params: Params{
+				ChangeAddress: &changeAddress,
+				To:            toManual,
+				HoursSelection: HoursSelection{
+					Type: HoursSelectionTypeManual,
+				},
+				SpendStrategy: SpendStrategyMinimizeUxOuts,
+			},
+		},
 	}
 
 	for _, tc := range cases {
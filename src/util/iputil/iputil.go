@@ -7,6 +7,7 @@ import (
 	"errors"
 	"net"
 	"strconv"
+	"strings"
 )
 
 var (
@@ -64,3 +65,68 @@ func SplitAddr(addr string) (string, uint16, error) {
 
 	return ip, uint16(port64), nil
 }
+
+// IPv6GroupPrefixLen is the length of the prefix that groups IPv6 addresses in IPGroup
+const IPv6GroupPrefixLen = 64
+
+// IsIPv6 returns true if ip is an IPv6 address that is not an IPv4-mapped address
+func IsIPv6(ip string) bool {
+	x := net.ParseIP(ip)
+	return x != nil && x.To4() == nil
+}
+
+// IPGroup returns the group of an IP address, used to limit the connections from a single host.
+// An IPv4 address is its own group. IPv6 addresses are grouped by their /64 prefix,
+// because a single host is usually assigned a whole /64 network.
+// If ip is not an IP address, it is returned unchanged.
+func IPGroup(ip string) string {
+	x := net.ParseIP(ip)
+	if x == nil {
+		return ip
+	}
+
+	if x4 := x.To4(); x4 != nil {
+		return x4.String()
+	}
+
+	prefix := net.IPNet{
+		IP:   x.Mask(net.CIDRMask(IPv6GroupPrefixLen, 128)),
+		Mask: net.CIDRMask(IPv6GroupPrefixLen, 128),
+	}
+	return prefix.String()
+}
+
+// JoinAddr joins an ip and port to an ip:port string.
+// IPv6 addresses are enclosed in square brackets.
+func JoinAddr(ip string, port uint16) string {
+	return net.JoinHostPort(ip, strconv.FormatUint(uint64(port), 10))
+}
+
+// OnionSuffix is the top level domain of Tor onion service addresses
+const OnionSuffix = ".onion"
+
+// onionV3Len is the length of a v3 onion service address without the OnionSuffix,
+// the base32 encoding of its pubkey, checksum and version
+const onionV3Len = 56
+
+// IsOnion returns true if host is a Tor v3 onion service address, e.g. "xxx.onion".
+// The check is case insensitive.
+func IsOnion(host string) bool {
+	host = strings.ToLower(host)
+	if !strings.HasSuffix(host, OnionSuffix) {
+		return false
+	}
+
+	name := strings.TrimSuffix(host, OnionSuffix)
+	if len(name) != onionV3Len {
+		return false
+	}
+
+	for _, c := range name {
+		if !(c >= 'a' && c <= 'z') && !(c >= '2' && c <= '7') {
+			return false
+		}
+	}
+
+	return true
+}
@@ -121,3 +121,84 @@ func TestSplitAddr(t *testing.T) {
 		})
 	}
 }
+
+func TestIPGroup(t *testing.T) {
+	testData := []struct {
+		ip    string
+		group string
+		ipv6  bool
+	}{
+		{
+			ip:    "85.56.12.34",
+			group: "85.56.12.34",
+		},
+		{
+			ip:    "::ffff:85.56.12.34",
+			group: "85.56.12.34",
+		},
+		{
+			ip:    "2001:0db8:85a3:0000:0000:8a2e:0370:7334",
+			group: "2001:db8:85a3::/64",
+			ipv6:  true,
+		},
+		{
+			ip:    "2001:db8:85a3::1",
+			group: "2001:db8:85a3::/64",
+			ipv6:  true,
+		},
+		{
+			ip:    "2001:db8:85a3:1::1",
+			group: "2001:db8:85a3:1::/64",
+			ipv6:  true,
+		},
+		{
+			ip:    "::1",
+			group: "::/64",
+			ipv6:  true,
+		},
+		{
+			ip:    "localhost",
+			group: "localhost",
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.ip, func(t *testing.T) {
+			require.Equal(t, tc.group, IPGroup(tc.ip))
+			require.Equal(t, tc.ipv6, IsIPv6(tc.ip))
+		})
+	}
+}
+
+func TestJoinAddr(t *testing.T) {
+	require.Equal(t, "85.56.12.34:6000", JoinAddr("85.56.12.34", 6000))
+	require.Equal(t, "[2001:db8::1]:6000", JoinAddr("2001:db8::1", 6000))
+
+	ip, port, err := SplitAddr(JoinAddr("2001:db8::1", 6000))
+	require.NoError(t, err)
+	require.Equal(t, "2001:db8::1", ip)
+	require.Equal(t, uint16(6000), port)
+}
+
+func TestIsOnion(t *testing.T) {
+	tt := []struct {
+		host  string
+		onion bool
+	}{
+		{"vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd.onion", true},
+		{"VWW6YBAL4BD7SZMGNCYRUUCPGFKQAHZDDI37KTCEO3AH7NGMCOPNPYYD.ONION", true},
+		{"vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd", false},
+		{"vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyy.onion", false},
+		{"vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyy1.onion", false},
+		{"expyuzz4wqqyqhjn.onion", false},
+		{"example.com", false},
+		{"1.2.3.4", false},
+		{"", false},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.host, func(t *testing.T) {
+			require.Equal(t, tc.onion, IsOnion(tc.host))
+		})
+	}
+}
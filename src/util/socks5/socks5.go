@@ -0,0 +1,265 @@
+/*
+Package socks5 implements a SOCKS5 client (RFC 1928) for dialing TCP connections through a proxy,
+such as the SOCKS5 port of a Tor daemon
+*/
+package socks5
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"time"
+
+	"github.com/ness-network/ness/src/util/iputil"
+)
+
+const (
+	socksVersion = 5
+
+	authMethodNone         = 0x00
+	authMethodUserPass     = 0x02
+	authMethodNoAcceptable = 0xff
+	authUserPassVersion    = 1
+
+	cmdConnect = 1
+
+	addrTypeIPv4   = 1
+	addrTypeDomain = 3
+	addrTypeIPv6   = 4
+)
+
+var (
+	// ErrInvalidVersion the proxy replied with a SOCKS version other than 5
+	ErrInvalidVersion = errors.New("SOCKS5 proxy replied with an invalid version")
+	// ErrNoAcceptableAuthMethod the proxy does not accept any of the offered authentication methods
+	ErrNoAcceptableAuthMethod = errors.New("SOCKS5 proxy does not accept the authentication methods")
+	// ErrAuthFailed the proxy rejected the username and password
+	ErrAuthFailed = errors.New("SOCKS5 proxy authentication failed")
+	// ErrCredentialsTooLong the username or password is longer than 255 bytes
+	ErrCredentialsTooLong = errors.New("SOCKS5 username and password must not be longer than 255 bytes")
+	// ErrHostTooLong the destination host name is longer than 255 bytes
+	ErrHostTooLong = errors.New("SOCKS5 destination host name must not be longer than 255 bytes")
+	// ErrInvalidAddrType the proxy replied with an unknown address type
+	ErrInvalidAddrType = errors.New("SOCKS5 proxy replied with an invalid address type")
+)
+
+var replyMessages = map[byte]string{
+	1: "general SOCKS server failure",
+	2: "connection not allowed by ruleset",
+	3: "network unreachable",
+	4: "host unreachable",
+	5: "connection refused",
+	6: "TTL expired",
+	7: "command not supported",
+	8: "address type not supported",
+}
+
+// ReplyError is returned when the proxy fails to connect to the destination
+type ReplyError struct {
+	Code byte
+}
+
+func (e ReplyError) Error() string {
+	if msg, ok := replyMessages[e.Code]; ok {
+		return fmt.Sprintf("SOCKS5 proxy: %s", msg)
+	}
+	return fmt.Sprintf("SOCKS5 proxy: unknown reply code %d", e.Code)
+}
+
+// Dialer dials TCP connections through a SOCKS5 proxy
+type Dialer struct {
+	// Address of the SOCKS5 proxy, e.g. 127.0.0.1:9050 for Tor
+	ProxyAddress string
+	// Username and Password for the username/password authentication method (RFC 1929).
+	// Leave empty to connect without authentication.
+	// Tor isolates the streams of different credentials on different circuits.
+	Username string
+	Password string
+	// Timeout for connecting to the destination through the proxy. Use 0 for no timeout
+	Timeout time.Duration
+}
+
+// Dial connects to address through the proxy. address is a host:port string, where host
+// is an IP address or a host name, such as an onion address, which is resolved by the proxy.
+// The RemoteAddr of the returned connection is address, not the proxy address.
+func (d Dialer) Dial(address string) (net.Conn, error) {
+	host, port, err := iputil.SplitAddr(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(host) > 255 {
+		return nil, ErrHostTooLong
+	}
+	if len(d.Username) > 255 || len(d.Password) > 255 {
+		return nil, ErrCredentialsTooLong
+	}
+
+	conn, err := net.DialTimeout("tcp", d.ProxyAddress, d.Timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if d.Timeout != 0 {
+		if err := conn.SetDeadline(time.Now().Add(d.Timeout)); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	if err := d.connect(conn, host, port); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if d.Timeout != 0 {
+		if err := conn.SetDeadline(time.Time{}); err != nil {
+			conn.Close()
+			return nil, err
+		}
+	}
+
+	return &proxiedConn{
+		Conn: conn,
+		remoteAddr: addr{
+			address: iputil.JoinAddr(host, port),
+		},
+	}, nil
+}
+
+// connect authenticates with the proxy and requests a connection to host:port
+func (d Dialer) connect(conn net.Conn, host string, port uint16) error {
+	if err := d.authenticate(conn); err != nil {
+		return err
+	}
+
+	req := []byte{socksVersion, cmdConnect, 0}
+	ip := net.ParseIP(host)
+	switch {
+	case ip == nil:
+		req = append(req, addrTypeDomain, byte(len(host)))
+		req = append(req, host...)
+	case ip.To4() != nil:
+		req = append(req, addrTypeIPv4)
+		req = append(req, ip.To4()...)
+	default:
+		req = append(req, addrTypeIPv6)
+		req = append(req, ip.To16()...)
+	}
+	req = append(req, byte(port>>8), byte(port))
+
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	// The reply is the version, reply code, a reserved byte and the address bound by the proxy
+	rsp := make([]byte, 4)
+	if _, err := io.ReadFull(conn, rsp); err != nil {
+		return err
+	}
+
+	if rsp[0] != socksVersion {
+		return ErrInvalidVersion
+	}
+	if rsp[1] != 0 {
+		return ReplyError{Code: rsp[1]}
+	}
+
+	var n int
+	switch rsp[3] {
+	case addrTypeIPv4:
+		n = net.IPv4len
+	case addrTypeIPv6:
+		n = net.IPv6len
+	case addrTypeDomain:
+		l := make([]byte, 1)
+		if _, err := io.ReadFull(conn, l); err != nil {
+			return err
+		}
+		n = int(l[0])
+	default:
+		return ErrInvalidAddrType
+	}
+
+	// Discard the bound address and port
+	_, err := io.ReadFull(conn, make([]byte, n+2))
+	return err
+}
+
+// authenticate negotiates the authentication method with the proxy
+func (d Dialer) authenticate(conn net.Conn) error {
+	method := byte(authMethodNone)
+	if d.Username != "" || d.Password != "" {
+		method = authMethodUserPass
+	}
+
+	if _, err := conn.Write([]byte{socksVersion, 1, method}); err != nil {
+		return err
+	}
+
+	rsp := make([]byte, 2)
+	if _, err := io.ReadFull(conn, rsp); err != nil {
+		return err
+	}
+
+	if rsp[0] != socksVersion {
+		return ErrInvalidVersion
+	}
+
+	switch rsp[1] {
+	case authMethodNone:
+		if method != authMethodNone {
+			return ErrNoAcceptableAuthMethod
+		}
+		return nil
+	case authMethodUserPass:
+		if method != authMethodUserPass {
+			return ErrNoAcceptableAuthMethod
+		}
+	default:
+		return ErrNoAcceptableAuthMethod
+	}
+
+	req := []byte{authUserPassVersion, byte(len(d.Username))}
+	req = append(req, d.Username...)
+	req = append(req, byte(len(d.Password)))
+	req = append(req, d.Password...)
+
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	if _, err := io.ReadFull(conn, rsp); err != nil {
+		return err
+	}
+
+	if rsp[1] != 0 {
+		return ErrAuthFailed
+	}
+
+	return nil
+}
+
+// addr is the net.Addr of the destination of a proxied connection
+type addr struct {
+	address string
+}
+
+func (a addr) Network() string {
+	return "tcp"
+}
+
+func (a addr) String() string {
+	return a.address
+}
+
+// proxiedConn is a connection through the proxy, which reports the destination as its RemoteAddr
+type proxiedConn struct {
+	net.Conn
+	remoteAddr addr
+}
+
+func (c *proxiedConn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
@@ -0,0 +1,255 @@
+package socks5
+
+import (
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testProxy is an in-process SOCKS5 stand-in. It accepts one CONNECT request per connection,
+// records the requested destination and echoes the data sent after the request.
+type testProxy struct {
+	ln       net.Listener
+	username string
+	password string
+	reply    byte
+	dests    chan string
+}
+
+func newTestProxy(t *testing.T, username, password string, reply byte) *testProxy {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	p := &testProxy{
+		ln:       ln,
+		username: username,
+		password: password,
+		reply:    reply,
+		dests:    make(chan string, 1),
+	}
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go p.serve(conn)
+		}
+	}()
+
+	return p
+}
+
+func (p *testProxy) Close() {
+	p.ln.Close()
+}
+
+func (p *testProxy) serve(conn net.Conn) {
+	defer conn.Close()
+
+	buf := make([]byte, 2)
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return
+	}
+	methods := make([]byte, buf[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return
+	}
+
+	method := byte(authMethodNone)
+	if p.username != "" {
+		method = authMethodUserPass
+	}
+	found := false
+	for _, m := range methods {
+		if m == method {
+			found = true
+		}
+	}
+	if !found {
+		conn.Write([]byte{socksVersion, authMethodNoAcceptable}) //nolint:errcheck
+		return
+	}
+	if _, err := conn.Write([]byte{socksVersion, method}); err != nil {
+		return
+	}
+
+	if method == authMethodUserPass {
+		username, err := readString(conn, 2)
+		if err != nil {
+			return
+		}
+		password, err := readString(conn, 1)
+		if err != nil {
+			return
+		}
+		if username != p.username || password != p.password {
+			conn.Write([]byte{authUserPassVersion, 1}) //nolint:errcheck
+			return
+		}
+		if _, err := conn.Write([]byte{authUserPassVersion, 0}); err != nil {
+			return
+		}
+	}
+
+	req := make([]byte, 4)
+	if _, err := io.ReadFull(conn, req); err != nil {
+		return
+	}
+
+	var host string
+	switch req[3] {
+	case addrTypeIPv4, addrTypeIPv6:
+		n := net.IPv4len
+		if req[3] == addrTypeIPv6 {
+			n = net.IPv6len
+		}
+		ip := make([]byte, n)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return
+		}
+		host = net.IP(ip).String()
+	case addrTypeDomain:
+		var err error
+		host, err = readString(conn, 1)
+		if err != nil {
+			return
+		}
+	}
+
+	port := make([]byte, 2)
+	if _, err := io.ReadFull(conn, port); err != nil {
+		return
+	}
+	p.dests <- net.JoinHostPort(host, strconv.Itoa(int(port[0])<<8|int(port[1])))
+
+	if _, err := conn.Write([]byte{socksVersion, p.reply, 0, addrTypeIPv4, 127, 0, 0, 1, 0x17, 0x70}); err != nil {
+		return
+	}
+	if p.reply != 0 {
+		return
+	}
+
+	io.Copy(conn, conn) //nolint:errcheck
+}
+
+// readString reads a length prefixed string, skipping the skip-1 bytes that precede the length
+func readString(r io.Reader, skip int) (string, error) {
+	buf := make([]byte, skip)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return "", err
+	}
+	s := make([]byte, buf[skip-1])
+	if _, err := io.ReadFull(r, s); err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
+
+func TestDialerDial(t *testing.T) {
+	onion := "vww6ybal4bd7szmgncyruucpgfkqahzddi37ktceo3ah7ngmcopnpyyd.onion:6000"
+
+	cases := []struct {
+		name          string
+		addr          string
+		dest          string
+		proxyUsername string
+		proxyPassword string
+		username      string
+		password      string
+		reply         byte
+		err           error
+	}{
+		{
+			name: "onion address",
+			addr: onion,
+			dest: onion,
+		},
+		{
+			name: "ipv4 address",
+			addr: "1.2.3.4:6000",
+			dest: "1.2.3.4:6000",
+		},
+		{
+			name: "ipv6 address",
+			addr: "[2001:db8::1]:6000",
+			dest: "[2001:db8::1]:6000",
+		},
+		{
+			name:          "username and password",
+			addr:          onion,
+			dest:          onion,
+			proxyUsername: "user",
+			proxyPassword: "pass",
+			username:      "user",
+			password:      "pass",
+		},
+		{
+			name:          "wrong password",
+			addr:          onion,
+			proxyUsername: "user",
+			proxyPassword: "pass",
+			username:      "user",
+			password:      "wrong",
+			err:           ErrAuthFailed,
+		},
+		{
+			name:          "missing credentials",
+			addr:          onion,
+			proxyUsername: "user",
+			proxyPassword: "pass",
+			err:           ErrNoAcceptableAuthMethod,
+		},
+		{
+			name:  "connection refused",
+			addr:  onion,
+			dest:  onion,
+			reply: 5,
+			err:   ReplyError{Code: 5},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			p := newTestProxy(t, tc.proxyUsername, tc.proxyPassword, tc.reply)
+			defer p.Close()
+
+			d := Dialer{
+				ProxyAddress: p.ln.Addr().String(),
+				Username:     tc.username,
+				Password:     tc.password,
+				Timeout:      time.Second * 5,
+			}
+
+			conn, err := d.Dial(tc.addr)
+			if tc.dest != "" {
+				require.Equal(t, tc.dest, <-p.dests)
+			}
+			if tc.err != nil {
+				require.Equal(t, tc.err, err)
+				return
+			}
+			require.NoError(t, err)
+			defer conn.Close()
+
+			require.Equal(t, tc.dest, conn.RemoteAddr().String())
+
+			_, err = conn.Write([]byte("ping"))
+			require.NoError(t, err)
+			buf := make([]byte, 4)
+			_, err = io.ReadFull(conn, buf)
+			require.NoError(t, err)
+			require.Equal(t, "ping", string(buf))
+		})
+	}
+}
+
+func TestReplyError(t *testing.T) {
+	require.Equal(t, "SOCKS5 proxy: host unreachable", ReplyError{Code: 4}.Error())
+	require.Equal(t, "SOCKS5 proxy: unknown reply code 42", ReplyError{Code: 42}.Error())
+}
@@ -0,0 +1,268 @@
+package visor
+
+import (
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// addressBatchSize is the number of addresses whose unspent outputs are read at a time
+// by WalkBalancesOfAddresses and WalkUnspentOutputsOfAddresses
+const addressBatchSize = 1000
+
+// AddressBalancePair is the confirmed and predicted balance of an address
+type AddressBalancePair struct {
+	Address cipher.Address
+	wallet.BalancePair
+}
+
+// AddressUnspentOutputs are the unspent outputs of an address
+type AddressUnspentOutputs struct {
+	Address cipher.Address
+	// Confirmed are the outputs of the address in the unspent pool
+	Confirmed []UnspentOutput
+	// Outgoing are the confirmed outputs of the address spent by unconfirmed transactions
+	Outgoing []UnspentOutput
+	// Incoming are the outputs to the address created by unconfirmed transactions
+	Incoming []UnspentOutput
+}
+
+// WalkBalancesOfAddresses computes the balances of addrs in a single db transaction and calls f
+// with the balance of each address, in the order of addrs. headSeq is the seq of the head block
+// that the balances are computed at. The walk stops at the first error returned by f.
+// f is called after the db transaction is closed, so a slow f, such as a write to a network client,
+// does not hold the transaction open.
+func (vs *Visor) WalkBalancesOfAddresses(addrs []cipher.Address, f func(headSeq uint64, b AddressBalancePair) error) error {
+	if len(addrs) == 0 {
+		return nil
+	}
+
+	var headSeq uint64
+	balances := make([]AddressBalancePair, 0, len(addrs))
+	if err := vs.db.View("WalkBalancesOfAddresses", func(tx *dbutil.Tx) error {
+		head, err := vs.blockchain.Head(tx)
+		if err != nil {
+			return err
+		}
+		headSeq = head.Seq()
+
+		// Get all transactions from the unconfirmed pool
+		txns, err := vs.unconfirmed.AllRawTransactions(tx)
+		if err != nil {
+			return err
+		}
+
+		spendUxs, err := vs.unconfirmedOutgoingOutputsOfAddrs(tx, txns, addrs)
+		if err != nil {
+			return err
+		}
+
+		headTime := head.Time()
+		for i := 0; i < len(addrs); i += addressBatchSize {
+			end := i + addressBatchSize
+			if end > len(addrs) {
+				end = len(addrs)
+			}
+			batch := addrs[i:end]
+
+			// Create predicted unspent outputs from the unconfirmed transactions
+			recvUxs, err := txnOutputsForAddrs(head.Head, batch, txns)
+			if err != nil {
+				return err
+			}
+
+			// Get unspents owned by the addresses
+			auxs, err := vs.blockchain.Unspent().GetUnspentsOfAddrs(tx, batch)
+			if err != nil {
+				return fmt.Errorf("GetUnspentsOfAddrs failed when checking addresses balance: %v", err)
+			}
+
+			for _, addr := range batch {
+				bp, err := newBalancePair(auxs[addr], spendUxs[addr], recvUxs[addr], headTime)
+				if err != nil {
+					return err
+				}
+
+				balances = append(balances, AddressBalancePair{
+					Address:     addr,
+					BalancePair: bp,
+				})
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, b := range balances {
+		if err := f(headSeq, b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// WalkUnspentOutputsOfAddresses reads the unspent outputs of addrs in a single db transaction and calls f
+// with the outputs of each address, in the order of addrs. headSeq is the seq of the head block
+// that the outputs are read at. The walk stops at the first error returned by f.
+// Like WalkBalancesOfAddresses, f is called after the db transaction is closed.
+func (vs *Visor) WalkUnspentOutputsOfAddresses(addrs []cipher.Address, f func(headSeq uint64, o AddressUnspentOutputs) error) error {
+	if len(addrs) == 0 {
+		return nil
+	}
+
+	var headSeq uint64
+	outputs := make([]AddressUnspentOutputs, 0, len(addrs))
+	if err := vs.db.View("WalkUnspentOutputsOfAddresses", func(tx *dbutil.Tx) error {
+		head, err := vs.blockchain.Head(tx)
+		if err != nil {
+			return err
+		}
+		headSeq = head.Seq()
+
+		txns, err := vs.unconfirmed.AllRawTransactions(tx)
+		if err != nil {
+			return err
+		}
+
+		outgoingUxs, err := vs.unconfirmedOutgoingOutputsOfAddrs(tx, txns, addrs)
+		if err != nil {
+			return err
+		}
+
+		headTime := head.Time()
+		for i := 0; i < len(addrs); i += addressBatchSize {
+			end := i + addressBatchSize
+			if end > len(addrs) {
+				end = len(addrs)
+			}
+			batch := addrs[i:end]
+
+			incomingUxs, err := txnOutputsForAddrs(head.Head, batch, txns)
+			if err != nil {
+				return err
+			}
+
+			auxs, err := vs.blockchain.Unspent().GetUnspentsOfAddrs(tx, batch)
+			if err != nil {
+				return fmt.Errorf("GetUnspentsOfAddrs failed: %v", err)
+			}
+
+			for _, addr := range batch {
+				o := AddressUnspentOutputs{
+					Address: addr,
+				}
+
+				o.Confirmed, err = NewUnspentOutputs(auxs[addr], headTime)
+				if err != nil {
+					return err
+				}
+
+				o.Outgoing, err = NewUnspentOutputs(outgoingUxs[addr], headTime)
+				if err != nil {
+					return err
+				}
+
+				o.Incoming, err = NewUnspentOutputs(incomingUxs[addr], headTime)
+				if err != nil {
+					return err
+				}
+
+				outputs = append(outputs, o)
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for _, o := range outputs {
+		if err := f(headSeq, o); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unconfirmedOutgoingOutputsOfAddrs returns the confirmed outputs of addrs spent by the unconfirmed transactions txns
+func (vs *Visor) unconfirmedOutgoingOutputsOfAddrs(tx *dbutil.Tx, txns coin.Transactions, addrs []cipher.Address) (coin.AddressUxOuts, error) {
+	var inputs []cipher.SHA256
+	for _, txn := range txns {
+		inputs = append(inputs, txn.In...)
+	}
+
+	// Get unspents for the inputs being spent
+	uxa, err := vs.blockchain.Unspent().GetArray(tx, inputs)
+	if err != nil {
+		return nil, fmt.Errorf("GetArray failed when checking addresses balance: %v", err)
+	}
+
+	addrm := newAddrSet(addrs)
+	spendUxs := make(coin.AddressUxOuts)
+	for _, ux := range uxa {
+		if _, ok := addrm[ux.Body.Address]; ok {
+			spendUxs[ux.Body.Address] = append(spendUxs[ux.Body.Address], ux)
+		}
+	}
+
+	return spendUxs, nil
+}
+
+// newBalancePair computes the balance of an address from its unspent outputs uxs,
+// the outputs outUxs spent by unconfirmed transactions and the outputs inUxs created by unconfirmed transactions.
+// An address without unspent outputs has an empty balance.
+func newBalancePair(uxs, outUxs, inUxs coin.UxArray, headTime uint64) (wallet.BalancePair, error) {
+	if len(uxs) == 0 {
+		return wallet.BalancePair{}, nil
+	}
+
+	predictedUxs := uxs.Sub(outUxs).Add(inUxs)
+
+	coins, err := uxs.Coins()
+	if err != nil {
+		return wallet.BalancePair{}, fmt.Errorf("uxs.Coins failed: %v", err)
+	}
+
+	coinHours, err := uxs.CoinHours(headTime)
+	if err != nil {
+		switch err {
+		case coin.ErrAddEarnedCoinHoursAdditionOverflow:
+			coinHours = 0
+		default:
+			return wallet.BalancePair{}, fmt.Errorf("uxs.CoinHours failed: %v", err)
+		}
+	}
+
+	pcoins, err := predictedUxs.Coins()
+	if err != nil {
+		return wallet.BalancePair{}, fmt.Errorf("predictedUxs.Coins failed: %v", err)
+	}
+
+	pcoinHours, err := predictedUxs.CoinHours(headTime)
+	if err != nil {
+		switch err {
+		case coin.ErrAddEarnedCoinHoursAdditionOverflow:
+			pcoinHours = 0
+		default:
+			return wallet.BalancePair{}, fmt.Errorf("predictedUxs.CoinHours failed: %v", err)
+		}
+	}
+
+	return wallet.BalancePair{
+		Confirmed: wallet.Balance{
+			Coins: coins,
+			Hours: coinHours,
+		},
+		Predicted: wallet.Balance{
+			Coins: pcoins,
+			Hours: pcoinHours,
+		},
+	}, nil
+}
@@ -0,0 +1,121 @@
+package visor
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ness-network/ness/src/visor/historydb"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func TestWalkBalancesOfAddresses(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, BlockchainConfig{
+		Pubkey: genPublic,
+	})
+	require.NoError(t, err)
+
+	unconfirmed, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{})
+	require.NoError(t, err)
+
+	cfg := NewConfig()
+	cfg.IsBlockPublisher = true
+	cfg.BlockchainPubkey = genPublic
+	cfg.BlockchainSeckey = genSecret
+	cfg.GenesisAddress = genAddress
+
+	v := &Visor{
+		Config:      cfg,
+		unconfirmed: unconfirmed,
+		blockchain:  bc,
+		db:          db,
+		history:     historydb.New(),
+	}
+
+	gb := addGenesisBlockToVisor(t, v)
+	genTxn := gb.Body.Transactions[0]
+
+	pubA, secA := cipher.GenerateKeyPair()
+	addrA := cipher.AddressFromPubKey(pubA)
+	addrB := testutil.MakeAddress()
+	addrC := testutil.MakeAddress()
+
+	txn1 := makeSpendTxn(t, coin.CreateUnspents(gb.Head, genTxn), []cipher.SecKey{genSecret}, addrA, 10e6)
+	b, err := v.CreateBlockFromTxns(coin.Transactions{txn1}, genTime+100)
+	require.NoError(t, err)
+	sb := coin.SignedBlock{
+		Block: b,
+		Sig:   cipher.MustSignHash(b.HashHeader(), genSecret),
+	}
+	require.NoError(t, v.ExecuteSignedBlock(sb))
+	b1Uxs := coin.CreateUnspents(sb.Head, txn1)
+
+	// addrA sends its coins to addrB in an unconfirmed transaction
+	txn2 := makeSpendTxn(t, b1Uxs[:1], []cipher.SecKey{secA}, addrB, 10e6)
+	_, softErr, err := v.InjectForeignTransaction(txn2)
+	require.NoError(t, err)
+	require.Nil(t, softErr)
+
+	addrs := []cipher.Address{addrB, genAddress, addrA, addrC}
+
+	expected, err := v.GetBalanceOfAddresses(addrs)
+	require.NoError(t, err)
+	require.Len(t, expected, len(addrs))
+
+	require.Equal(t, uint64(10e6), expected[2].Confirmed.Coins)
+	require.Equal(t, uint64(0), expected[2].Predicted.Coins)
+	require.Equal(t, wallet.BalancePair{}, expected[3])
+
+	var balances []AddressBalancePair
+	err = v.WalkBalancesOfAddresses(addrs, func(headSeq uint64, b AddressBalancePair) error {
+		require.Equal(t, uint64(1), headSeq)
+		balances = append(balances, b)
+		return nil
+	})
+	require.NoError(t, err)
+
+	require.Len(t, balances, len(addrs))
+	for i, b := range balances {
+		require.Equal(t, addrs[i], b.Address)
+		require.Equal(t, expected[i], b.BalancePair)
+	}
+
+	// The walk stops at the first error returned by f
+	errStop := errors.New("stop")
+	var n int
+	err = v.WalkBalancesOfAddresses(addrs, func(headSeq uint64, b AddressBalancePair) error {
+		n++
+		return errStop
+	})
+	require.Equal(t, errStop, err)
+	require.Equal(t, 1, n)
+
+	var outputs []AddressUnspentOutputs
+	err = v.WalkUnspentOutputsOfAddresses([]cipher.Address{addrA, addrB}, func(headSeq uint64, o AddressUnspentOutputs) error {
+		require.Equal(t, uint64(1), headSeq)
+		outputs = append(outputs, o)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, outputs, 2)
+
+	require.Equal(t, addrA, outputs[0].Address)
+	require.Len(t, outputs[0].Confirmed, 1)
+	require.Equal(t, b1Uxs[0].Hash(), outputs[0].Confirmed[0].Hash())
+	require.Len(t, outputs[0].Outgoing, 1)
+	require.Equal(t, b1Uxs[0].Hash(), outputs[0].Outgoing[0].Hash())
+	require.Empty(t, outputs[0].Incoming)
+
+	require.Equal(t, addrB, outputs[1].Address)
+	require.Empty(t, outputs[1].Confirmed)
+	require.Empty(t, outputs[1].Outgoing)
+	require.Len(t, outputs[1].Incoming, 1)
+	require.Equal(t, uint64(10e6), outputs[1].Incoming[0].Body.Coins)
+}
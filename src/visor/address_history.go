@@ -0,0 +1,362 @@
+package visor
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ness-network/ness/src/visor/historydb"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/util/mathutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+const (
+	// secondsPerDay is the length of a day of AddressHistoryDay snapshots
+	secondsPerDay = 24 * 60 * 60
+	// DefaultAddressHistoryLimit is the default maximum number of snapshots of an address balance history request
+	DefaultAddressHistoryLimit = 1000
+	// MaxAddressHistoryLimit is the maximum number of snapshots of an address balance history request
+	MaxAddressHistoryLimit = 10000
+)
+
+// AddressHistoryGranularity is the interval between the snapshots of an address balance history
+type AddressHistoryGranularity string
+
+const (
+	// AddressHistoryBlock takes a snapshot after each block that changes the address balance
+	AddressHistoryBlock AddressHistoryGranularity = "block"
+	// AddressHistoryDay takes a snapshot at the last block of each UTC day
+	AddressHistoryDay AddressHistoryGranularity = "day"
+)
+
+var (
+	// ErrInvalidAddressHistoryGranularity is returned for an unknown AddressHistoryGranularity
+	ErrInvalidAddressHistoryGranularity = errors.New("invalid address history granularity, must be 'block' or 'day'")
+)
+
+// AddressHistoryQuery selects the balance snapshots of an address
+type AddressHistoryQuery struct {
+	Address     cipher.Address
+	Granularity AddressHistoryGranularity
+	// FromSeq and ToSeq restrict the snapshots to an inclusive range of block seqs, if not nil
+	FromSeq *uint64
+	ToSeq   *uint64
+	// FromTime and ToTime restrict the snapshots to an inclusive range of block times, if not nil
+	FromTime *uint64
+	ToTime   *uint64
+	// After restricts the snapshots to the blocks after this block seq, if not nil.
+	// The seq of the last snapshot of a page is the After of the next page.
+	After *uint64
+	// Limit is the maximum number of snapshots, 0 for no limit
+	Limit uint64
+}
+
+// AddressBalanceSnapshot is the confirmed balance of an address after a block
+type AddressBalanceSnapshot struct {
+	BlockSeq uint64
+	Time     uint64
+	Coins    uint64
+	// Hours are the coin hours of the address's unspent outputs at the time of the block.
+	// They are computed from the total coin seconds of the outputs, so they can exceed the sum of the coin hours
+	// of the outputs by less than one hour per output.
+	Hours uint64
+	// Received and Sent are the coins received and sent since the previous snapshot,
+	// or since the start of the range for the first snapshot
+	Received uint64
+	Sent     uint64
+}
+
+// GetAddressBalanceHistory returns the balance snapshots of an address in blockchain order.
+// Snapshots start at the first block that touches the address.
+func (vs *Visor) GetAddressBalanceHistory(q AddressHistoryQuery) ([]AddressBalanceSnapshot, error) {
+	switch q.Granularity {
+	case AddressHistoryBlock, AddressHistoryDay:
+	default:
+		return nil, ErrInvalidAddressHistoryGranularity
+	}
+
+	var snapshots []AddressBalanceSnapshot
+	if err := vs.db.View("GetAddressBalanceHistory", func(tx *dbutil.Tx) error {
+		var err error
+		snapshots, err = vs.getAddressBalanceHistory(tx, q)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return snapshots, nil
+}
+
+func (vs *Visor) getAddressBalanceHistory(tx *dbutil.Tx, q AddressHistoryQuery) ([]AddressBalanceSnapshot, error) {
+	headSeq, ok, err := vs.blockchain.HeadSeq(tx)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+
+	fromSeq, toSeq, ok, err := vs.blockSeqRange(tx, headSeq, q.FromSeq, q.ToSeq, q.FromTime, q.ToTime)
+	if err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, nil
+	}
+
+	if q.After != nil {
+		if *q.After >= toSeq {
+			return nil, nil
+		}
+
+		if *q.After >= fromSeq {
+			fromSeq = *q.After + 1
+		}
+	}
+
+	outputs, err := vs.history.GetOutputsForAddress(tx, q.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(outputs) == 0 {
+		return nil, nil
+	}
+
+	sort.Slice(outputs, func(i, j int) bool {
+		return outputs[i].Out.Head.BkSeq < outputs[j].Out.Head.BkSeq
+	})
+
+	// The first block that touches the address creates its first output
+	if seq := outputs[0].Out.Head.BkSeq; seq > fromSeq {
+		fromSeq = seq
+	}
+
+	if fromSeq > toSeq {
+		return nil, nil
+	}
+
+	var seqs []uint64
+	switch q.Granularity {
+	case AddressHistoryBlock:
+		deltas, err := vs.history.GetAddressBalanceDeltas(tx, q.Address, fromSeq, toSeq)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, d := range deltas {
+			if q.Limit != 0 && uint64(len(seqs)) == q.Limit {
+				break
+			}
+			seqs = append(seqs, d.BlockSeq)
+		}
+	case AddressHistoryDay:
+		seqs, err = vs.lastBlockSeqsOfDays(tx, headSeq, fromSeq, toSeq, q.Limit)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(seqs) == 0 {
+		return nil, nil
+	}
+
+	deltas, err := vs.history.GetAddressBalanceDeltas(tx, q.Address, fromSeq, seqs[len(seqs)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	return vs.addressBalanceSnapshots(tx, seqs, deltas, outputs)
+}
+
+// lastBlockSeqsOfDays returns the seq of the last block of each UTC day between the blocks fromSeq and toSeq,
+// with toSeq as the last block of its day. At most limit seqs are returned, if limit is not 0.
+func (vs *Visor) lastBlockSeqsOfDays(tx *dbutil.Tx, headSeq, fromSeq, toSeq, limit uint64) ([]uint64, error) {
+	fromTime, err := vs.blockTimeBySeq(tx, fromSeq)
+	if err != nil {
+		return nil, err
+	}
+
+	var seqs []uint64
+	for dayEnd := fromTime - fromTime%secondsPerDay + secondsPerDay; ; dayEnd += secondsPerDay {
+		if limit != 0 && uint64(len(seqs)) == limit {
+			return seqs, nil
+		}
+
+		seq, err := vs.firstBlockSeqAtOrAfter(tx, headSeq, dayEnd)
+		if err != nil {
+			return nil, err
+		}
+
+		// seq is the first block of the next day
+		if seq > toSeq {
+			return append(seqs, toSeq), nil
+		}
+
+		// Days without blocks have no snapshot
+		if len(seqs) == 0 || seqs[len(seqs)-1] != seq-1 {
+			seqs = append(seqs, seq-1)
+		}
+	}
+}
+
+func (vs *Visor) blockTimeBySeq(tx *dbutil.Tx, seq uint64) (uint64, error) {
+	b, err := vs.blockchain.GetSignedBlockBySeq(tx, seq)
+	if err != nil {
+		return 0, err
+	}
+
+	if b == nil {
+		return 0, fmt.Errorf("block seq=%d doesn't exist", seq)
+	}
+
+	return b.Time(), nil
+}
+
+// unspentTotals are the totals of a set of unspent outputs that give their coins and coin hours at a time
+// without iterating over the outputs
+type unspentTotals struct {
+	coins uint64
+	hours uint64
+	// wholeCoins and droplets are the whole coins and the remaining droplets of the outputs, and
+	// wholeCoinTimes and dropletTimes their sums weighted by the time of the outputs
+	wholeCoins     uint64
+	wholeCoinTimes uint64
+	droplets       uint64
+	dropletTimes   uint64
+}
+
+func (ut *unspentTotals) add(ux coin.UxOut) error {
+	whole := ux.Body.Coins / 1e6
+	droplets := ux.Body.Coins % 1e6
+
+	wholeTime, err := mathutil.MultUint64(whole, ux.Head.Time)
+	if err != nil {
+		return err
+	}
+
+	dropletTime, err := mathutil.MultUint64(droplets, ux.Head.Time)
+	if err != nil {
+		return err
+	}
+
+	totals := *ut
+	for _, v := range []struct {
+		total *uint64
+		n     uint64
+	}{
+		{&totals.coins, ux.Body.Coins},
+		{&totals.hours, ux.Body.Hours},
+		{&totals.wholeCoins, whole},
+		{&totals.wholeCoinTimes, wholeTime},
+		{&totals.droplets, droplets},
+		{&totals.dropletTimes, dropletTime},
+	} {
+		*v.total, err = mathutil.AddUint64(*v.total, v.n)
+		if err != nil {
+			return err
+		}
+	}
+
+	*ut = totals
+	return nil
+}
+
+// remove subtracts an output that was added before
+func (ut *unspentTotals) remove(ux coin.UxOut) {
+	whole := ux.Body.Coins / 1e6
+	droplets := ux.Body.Coins % 1e6
+
+	ut.coins -= ux.Body.Coins
+	ut.hours -= ux.Body.Hours
+	ut.wholeCoins -= whole
+	ut.wholeCoinTimes -= whole * ux.Head.Time
+	ut.droplets -= droplets
+	ut.dropletTimes -= droplets * ux.Head.Time
+}
+
+// coinHours returns the coin hours of the outputs at time t, which is not before the time of any output
+func (ut *unspentTotals) coinHours(t uint64) (uint64, error) {
+	wholeCoinSeconds, err := mathutil.MultUint64(ut.wholeCoins, t)
+	if err != nil {
+		return 0, err
+	}
+
+	dropletSeconds, err := mathutil.MultUint64(ut.droplets, t)
+	if err != nil {
+		return 0, err
+	}
+
+	if wholeCoinSeconds < ut.wholeCoinTimes || dropletSeconds < ut.dropletTimes {
+		return 0, fmt.Errorf("coin hours time %d is before the time of an output", t)
+	}
+
+	coinSeconds := wholeCoinSeconds - ut.wholeCoinTimes + (dropletSeconds-ut.dropletTimes)/1e6
+	return mathutil.AddUint64(ut.hours, coinSeconds/3600)
+}
+
+// addressBalanceSnapshots takes the snapshots of an address balance after the blocks seqs, in increasing order.
+// deltas are the balance deltas of the address from the start of the range, and outputs all the outputs of the address
+// in block seq order. The coins and hours are kept as the totals of the unspent outputs, which are updated with the
+// outputs created and spent up to each snapshot.
+func (vs *Visor) addressBalanceSnapshots(tx *dbutil.Tx, seqs []uint64, deltas []historydb.AddressBalanceDelta, outputs []historydb.UxOut) ([]AddressBalanceSnapshot, error) {
+	var spends []historydb.UxOut
+	for _, o := range outputs {
+		if o.SpentBlockSeq != 0 {
+			spends = append(spends, o)
+		}
+	}
+
+	sort.Slice(spends, func(i, j int) bool {
+		return spends[i].SpentBlockSeq < spends[j].SpentBlockSeq
+	})
+
+	var unspent unspentTotals
+	var received, sent uint64
+	nextDelta := 0
+	nextOutput := 0
+	nextSpend := 0
+
+	snapshots := make([]AddressBalanceSnapshot, 0, len(seqs))
+	for _, seq := range seqs {
+		for ; nextDelta < len(deltas) && deltas[nextDelta].BlockSeq <= seq; nextDelta++ {
+			d := deltas[nextDelta]
+			received += d.Received
+			sent += d.Sent
+		}
+
+		for ; nextOutput < len(outputs) && outputs[nextOutput].Out.Head.BkSeq <= seq; nextOutput++ {
+			if err := unspent.add(outputs[nextOutput].Out); err != nil {
+				return nil, err
+			}
+		}
+
+		for ; nextSpend < len(spends) && spends[nextSpend].SpentBlockSeq <= seq; nextSpend++ {
+			unspent.remove(spends[nextSpend].Out)
+		}
+
+		t, err := vs.blockTimeBySeq(tx, seq)
+		if err != nil {
+			return nil, err
+		}
+
+		hours, err := unspent.coinHours(t)
+		if err != nil {
+			return nil, err
+		}
+
+		snapshots = append(snapshots, AddressBalanceSnapshot{
+			BlockSeq: seq,
+			Time:     t,
+			Coins:    unspent.coins,
+			Hours:    hours,
+			Received: received,
+			Sent:     sent,
+		})
+
+		received = 0
+		sent = 0
+	}
+
+	return snapshots, nil
+}
@@ -0,0 +1,356 @@
+package visor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ness-network/ness/src/visor/historydb"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+func TestGetAddressBalanceHistory(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, BlockchainConfig{
+		Pubkey: genPublic,
+	})
+	require.NoError(t, err)
+
+	unconfirmed, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{})
+	require.NoError(t, err)
+
+	cfg := NewConfig()
+	cfg.IsBlockPublisher = true
+	cfg.BlockchainPubkey = genPublic
+	cfg.BlockchainSeckey = genSecret
+	cfg.GenesisAddress = genAddress
+
+	v := &Visor{
+		Config:      cfg,
+		unconfirmed: unconfirmed,
+		blockchain:  bc,
+		db:          db,
+		history:     historydb.New(),
+	}
+
+	gb := addGenesisBlockToVisor(t, v)
+	genTxn := gb.Body.Transactions[0]
+
+	executeBlock := func(txn coin.Transaction, when uint64) coin.SignedBlock {
+		b, err := v.CreateBlockFromTxns(coin.Transactions{txn}, when)
+		require.NoError(t, err)
+		sb := coin.SignedBlock{
+			Block: b,
+			Sig:   cipher.MustSignHash(b.HashHeader(), genSecret),
+		}
+		require.NoError(t, v.ExecuteSignedBlock(sb))
+		return sb
+	}
+
+	pubA, secA := cipher.GenerateKeyPair()
+	addrA := cipher.AddressFromPubKey(pubA)
+	addrB := testutil.MakeAddress()
+
+	// Block 1 is on the genesis day, blocks 2 and 3 on the next day, and block 4 two days later
+	txn1 := makeSpendTxn(t, coin.CreateUnspents(gb.Head, genTxn), []cipher.SecKey{genSecret}, addrA, 10e6)
+	b1 := executeBlock(txn1, genTime+100)
+	b1Uxs := coin.CreateUnspents(b1.Head, txn1)
+
+	txn2 := makeSpendTxn(t, b1Uxs[1:], []cipher.SecKey{genSecret}, addrB, 10e6)
+	b2 := executeBlock(txn2, genTime+secondsPerDay+100)
+	b2Uxs := coin.CreateUnspents(b2.Head, txn2)
+
+	txn3 := makeSpendTxn(t, b1Uxs[:1], []cipher.SecKey{secA}, addrB, 10e6)
+	b3 := executeBlock(txn3, genTime+secondsPerDay+200)
+	b3Uxs := coin.CreateUnspents(b3.Head, txn3)
+
+	txn4 := makeSpendTxn(t, b2Uxs[1:], []cipher.SecKey{genSecret}, addrB, 10e6)
+	b4 := executeBlock(txn4, genTime+3*secondsPerDay)
+
+	seq := func(n uint64) *uint64 {
+		return &n
+	}
+
+	coinHours := func(t *testing.T, time uint64, uxs ...coin.UxOut) uint64 {
+		var hours uint64
+		for _, ux := range uxs {
+			h, err := ux.CoinHours(time)
+			require.NoError(t, err)
+			hours += h
+		}
+		return hours
+	}
+
+	cases := []struct {
+		name   string
+		q      AddressHistoryQuery
+		expect func(t *testing.T) []AddressBalanceSnapshot
+		err    error
+	}{
+		{
+			name: "block",
+			q: AddressHistoryQuery{
+				Address:     addrA,
+				Granularity: AddressHistoryBlock,
+			},
+			expect: func(t *testing.T) []AddressBalanceSnapshot {
+				return []AddressBalanceSnapshot{
+					{
+						BlockSeq: 1,
+						Time:     b1.Time(),
+						Coins:    10e6,
+						Hours:    coinHours(t, b1.Time(), b1Uxs[0]),
+						Received: 10e6,
+					},
+					{
+						BlockSeq: 3,
+						Time:     b3.Time(),
+						Sent:     10e6,
+					},
+				}
+			},
+		},
+		{
+			name: "block with range",
+			q: AddressHistoryQuery{
+				Address:     addrB,
+				Granularity: AddressHistoryBlock,
+				FromSeq:     seq(3),
+				ToTime:      seq(b4.Time() - 1),
+			},
+			expect: func(t *testing.T) []AddressBalanceSnapshot {
+				return []AddressBalanceSnapshot{
+					{
+						BlockSeq: 3,
+						Time:     b3.Time(),
+						Coins:    20e6,
+						Hours:    coinHours(t, b3.Time(), b2Uxs[0], b3Uxs[0]),
+						Received: 10e6,
+					},
+				}
+			},
+		},
+		{
+			name: "block with limit",
+			q: AddressHistoryQuery{
+				Address:     addrB,
+				Granularity: AddressHistoryBlock,
+				Limit:       1,
+			},
+			expect: func(t *testing.T) []AddressBalanceSnapshot {
+				return []AddressBalanceSnapshot{
+					{
+						BlockSeq: 2,
+						Time:     b2.Time(),
+						Coins:    10e6,
+						Hours:    coinHours(t, b2.Time(), b2Uxs[0]),
+						Received: 10e6,
+					},
+				}
+			},
+		},
+		{
+			name: "block after the previous page",
+			q: AddressHistoryQuery{
+				Address:     addrB,
+				Granularity: AddressHistoryBlock,
+				After:       seq(2),
+				Limit:       1,
+			},
+			expect: func(t *testing.T) []AddressBalanceSnapshot {
+				return []AddressBalanceSnapshot{
+					{
+						BlockSeq: 3,
+						Time:     b3.Time(),
+						Coins:    20e6,
+						Hours:    coinHours(t, b3.Time(), b2Uxs[0], b3Uxs[0]),
+						Received: 10e6,
+					},
+				}
+			},
+		},
+		{
+			name: "block after the last block",
+			q: AddressHistoryQuery{
+				Address:     addrB,
+				Granularity: AddressHistoryBlock,
+				After:       seq(4),
+			},
+			expect: func(t *testing.T) []AddressBalanceSnapshot {
+				return nil
+			},
+		},
+		{
+			name: "day",
+			q: AddressHistoryQuery{
+				Address:     genAddress,
+				Granularity: AddressHistoryDay,
+			},
+			expect: func(t *testing.T) []AddressBalanceSnapshot {
+				return []AddressBalanceSnapshot{
+					{
+						BlockSeq: 1,
+						Time:     b1.Time(),
+						Coins:    genCoins - 10e6,
+						Hours:    coinHours(t, b1.Time(), b1Uxs[1]),
+						Received: genCoins + genCoins - 10e6,
+						Sent:     genCoins,
+					},
+					{
+						BlockSeq: 3,
+						Time:     b3.Time(),
+						Coins:    genCoins - 20e6,
+						Hours:    coinHours(t, b3.Time(), b2Uxs[1]),
+						Received: genCoins - 20e6,
+						Sent:     genCoins - 10e6,
+					},
+					{
+						BlockSeq: 4,
+						Time:     b4.Time(),
+						Coins:    genCoins - 30e6,
+						Hours:    coinHours(t, b4.Time(), coin.CreateUnspents(b4.Head, txn4)[1]),
+						Received: genCoins - 30e6,
+						Sent:     genCoins - 20e6,
+					},
+				}
+			},
+		},
+		{
+			name: "day after the previous page",
+			q: AddressHistoryQuery{
+				Address:     genAddress,
+				Granularity: AddressHistoryDay,
+				After:       seq(1),
+				Limit:       1,
+			},
+			expect: func(t *testing.T) []AddressBalanceSnapshot {
+				return []AddressBalanceSnapshot{
+					{
+						BlockSeq: 3,
+						Time:     b3.Time(),
+						Coins:    genCoins - 20e6,
+						Hours:    coinHours(t, b3.Time(), b2Uxs[1]),
+						Received: genCoins - 20e6,
+						Sent:     genCoins - 10e6,
+					},
+				}
+			},
+		},
+		{
+			name: "day starts at the first block of the address",
+			q: AddressHistoryQuery{
+				Address:     addrB,
+				Granularity: AddressHistoryDay,
+				ToSeq:       seq(3),
+			},
+			expect: func(t *testing.T) []AddressBalanceSnapshot {
+				return []AddressBalanceSnapshot{
+					{
+						BlockSeq: 3,
+						Time:     b3.Time(),
+						Coins:    20e6,
+						Hours:    coinHours(t, b3.Time(), b2Uxs[0], b3Uxs[0]),
+						Received: 20e6,
+					},
+				}
+			},
+		},
+		{
+			name: "range before the first block of the address",
+			q: AddressHistoryQuery{
+				Address:     addrB,
+				Granularity: AddressHistoryDay,
+				ToSeq:       seq(1),
+			},
+			expect: func(t *testing.T) []AddressBalanceSnapshot {
+				return nil
+			},
+		},
+		{
+			name: "unknown address",
+			q: AddressHistoryQuery{
+				Address:     testutil.MakeAddress(),
+				Granularity: AddressHistoryBlock,
+			},
+			expect: func(t *testing.T) []AddressBalanceSnapshot {
+				return nil
+			},
+		},
+		{
+			name: "invalid granularity",
+			q: AddressHistoryQuery{
+				Address:     addrA,
+				Granularity: "week",
+			},
+			err: ErrInvalidAddressHistoryGranularity,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			snapshots, err := v.GetAddressBalanceHistory(tc.q)
+			require.Equal(t, tc.err, err)
+			if err != nil {
+				return
+			}
+
+			require.Equal(t, tc.expect(t), snapshots)
+		})
+	}
+}
+
+func TestUnspentTotals(t *testing.T) {
+	makeUx := func(coins, hours, time uint64) coin.UxOut {
+		return coin.UxOut{
+			Head: coin.UxHead{
+				Time: time,
+			},
+			Body: coin.UxBody{
+				Coins: coins,
+				Hours: hours,
+			},
+		}
+	}
+
+	ux1 := makeUx(10e6, 5, 1000)
+	ux2 := makeUx(2500000, 0, 4600)
+
+	var ut unspentTotals
+	require.NoError(t, ut.add(ux1))
+
+	// A single output has the coin hours of the output
+	for _, tm := range []uint64{1000, 4599, 4600, 1000000} {
+		expect, err := ux1.CoinHours(tm)
+		require.NoError(t, err)
+		hours, err := ut.coinHours(tm)
+		require.NoError(t, err)
+		require.Equal(t, expect, hours)
+	}
+
+	require.NoError(t, ut.add(ux2))
+	require.Equal(t, uint64(12500000), ut.coins)
+
+	// Whole coin hours add up
+	hours, err := ut.coinHours(4600 + 2*3600)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5+30+5), hours)
+
+	_, err = ut.coinHours(999)
+	require.Error(t, err)
+
+	ut.remove(ux1)
+	require.Equal(t, unspentTotals{
+		coins:          2500000,
+		wholeCoins:     2,
+		wholeCoinTimes: 2 * 4600,
+		droplets:       500000,
+		dropletTimes:   500000 * 4600,
+	}, ut)
+
+	ut.remove(ux2)
+	require.Equal(t, unspentTotals{}, ut)
+}
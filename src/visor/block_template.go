@@ -0,0 +1,347 @@
+package visor
+
+import (
+	"bytes"
+	"math"
+	"sort"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/transaction"
+	"github.com/skycoin/skycoin/src/util/fee"
+	"github.com/skycoin/skycoin/src/util/mathutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// BlockTemplateTransaction is a transaction selected for the next block
+type BlockTemplateTransaction struct {
+	Transaction coin.Transaction
+	// Fee is the coin hour fee burned by the transaction
+	Fee uint64
+	// Size is the encoded size of the transaction
+	Size uint32
+	// PackageFee and PackageSize include the unconfirmed descendants of the transaction.
+	// The transaction is ranked by the package fee per byte.
+	PackageFee  uint64
+	PackageSize uint32
+}
+
+// BlockTemplate is the set of transactions that the block publisher would put into the next block
+type BlockTemplate struct {
+	// Head is the header of the block that the template builds on
+	Head         coin.BlockHeader
+	Transactions []BlockTemplateTransaction
+	// Fee is the total fee of the transactions
+	Fee uint64
+	// Size is the total size of the transactions
+	Size uint32
+}
+
+// Txns returns the transactions of the template
+func (bt BlockTemplate) Txns() coin.Transactions {
+	txns := make(coin.Transactions, len(bt.Transactions))
+	for i, t := range bt.Transactions {
+		txns[i] = t.Transaction
+	}
+	return txns
+}
+
+// blockTemplateCandidate is an unconfirmed transaction considered for a block template
+type blockTemplateCandidate struct {
+	txn  coin.Transaction
+	hash cipher.SHA256
+	fee  uint64
+	size uint32
+	// parents are the indexes of the candidates whose outputs are spent by txn
+	parents []int
+}
+
+// blockTemplatePackage is a candidate ranked together with its descendants
+type blockTemplatePackage struct {
+	index int
+	UnconfirmedTxnFee
+}
+
+// selectBlockTemplateTxns picks the candidates that maximize the fee of a block,
+// within maxSize bytes and maxCount transactions.
+//
+// A block can only spend outputs confirmed by an earlier block, so a candidate that spends the
+// outputs of other candidates is never selected itself. Instead, it is kept with its ancestors:
+// each candidate is ranked by the fee per byte of the package made of itself and its descendants,
+// so that a parent with a well paying child is confirmed first and the child can follow in the next block.
+//
+// Packages are selected by fee per byte, ties are ordered by hash like coin.SortTransactions.
+// A candidate that doesn't fit in the remaining space is skipped so that smaller ones can fill it,
+// and a candidate that spends an output already spent by a selected candidate is skipped.
+func selectBlockTemplateTxns(candidates []blockTemplateCandidate, maxSize uint32, maxCount int) []BlockTemplateTransaction {
+	children := make([][]int, len(candidates))
+	for i, c := range candidates {
+		for _, p := range c.parents {
+			children[p] = append(children[p], i)
+		}
+	}
+
+	var pkgs []blockTemplatePackage
+	for i, c := range candidates {
+		if len(c.parents) != 0 {
+			continue
+		}
+
+		pkg := blockTemplatePackage{
+			index: i,
+			UnconfirmedTxnFee: UnconfirmedTxnFee{
+				Fee:  c.fee,
+				Size: c.size,
+			},
+		}
+
+		// Add the descendants of the candidate to its package
+		visited := map[int]struct{}{
+			i: {},
+		}
+		queue := children[i]
+		for len(queue) > 0 {
+			j := queue[0]
+			queue = queue[1:]
+			if _, ok := visited[j]; ok {
+				continue
+			}
+			visited[j] = struct{}{}
+			queue = append(queue, children[j]...)
+
+			var err error
+			if pkg.Fee, err = mathutil.AddUint64(pkg.Fee, candidates[j].fee); err != nil {
+				pkg.Fee = math.MaxUint64
+			}
+			if pkg.Size, err = mathutil.AddUint32(pkg.Size, candidates[j].size); err != nil {
+				pkg.Size = math.MaxUint32
+			}
+		}
+
+		pkgs = append(pkgs, pkg)
+	}
+
+	sort.Slice(pkgs, func(i, j int) bool {
+		a, b := pkgs[i], pkgs[j]
+		if b.Less(a.UnconfirmedTxnFee) {
+			return true
+		}
+		if a.Less(b.UnconfirmedTxnFee) {
+			return false
+		}
+		return bytes.Compare(candidates[a.index].hash[:], candidates[b.index].hash[:]) < 0
+	})
+
+	var selected []BlockTemplateTransaction
+	var size uint32
+	spent := make(map[cipher.SHA256]struct{})
+	for _, pkg := range pkgs {
+		if len(selected) >= maxCount {
+			break
+		}
+
+		c := candidates[pkg.index]
+
+		total, err := mathutil.AddUint32(size, c.size)
+		if err != nil || total > maxSize {
+			continue
+		}
+
+		conflict := false
+		for _, in := range c.txn.In {
+			if _, ok := spent[in]; ok {
+				conflict = true
+				break
+			}
+		}
+		if conflict {
+			continue
+		}
+
+		for _, in := range c.txn.In {
+			spent[in] = struct{}{}
+		}
+		size = total
+
+		selected = append(selected, BlockTemplateTransaction{
+			Transaction: c.txn,
+			Fee:         c.fee,
+			Size:        c.size,
+			PackageFee:  pkg.Fee,
+			PackageSize: pkg.Size,
+		})
+	}
+
+	return selected
+}
+
+// newBlockTemplate builds a BlockTemplate from txns on top of the head block.
+// Transactions that violate constraints are ignored, along with their descendants.
+func (vs *Visor) newBlockTemplate(tx *dbutil.Tx, txns coin.Transactions) (*BlockTemplate, error) {
+	head, err := vs.blockchain.Head(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates := make([]blockTemplateCandidate, 0, len(txns))
+	known := make(map[cipher.SHA256]struct{}, len(txns))
+	for _, txn := range txns {
+		hash := txn.Hash()
+		if _, ok := known[hash]; ok {
+			continue
+		}
+		known[hash] = struct{}{}
+
+		candidates = append(candidates, blockTemplateCandidate{
+			txn:  txn,
+			hash: hash,
+		})
+	}
+
+	// Index the outputs that the candidates would create, to find the candidates that spend them
+	outputs := make(map[cipher.SHA256]coin.UxOut)
+	creators := make(map[cipher.SHA256]int)
+	for i, c := range candidates {
+		for _, ux := range coin.CreateUnspents(head.Head, c.txn) {
+			h := ux.Hash()
+			outputs[h] = ux
+			creators[h] = i
+		}
+	}
+
+	for i := range candidates {
+		parents := make(map[int]struct{})
+		for _, in := range candidates[i].txn.In {
+			if j, ok := creators[in]; ok {
+				if _, ok := parents[j]; !ok {
+					parents[j] = struct{}{}
+					candidates[i].parents = append(candidates[i].parents, j)
+				}
+			}
+		}
+	}
+
+	valid := make([]bool, len(candidates))
+	for i := range candidates {
+		c := &candidates[i]
+
+		uxIn, err := vs.blockTemplateInputs(tx, head, c.txn, len(c.parents) != 0, outputs)
+		if err == nil {
+			c.fee, err = fee.TransactionFee(&c.txn, head.Time(), uxIn)
+			if err != nil {
+				err = transaction.NewErrTxnViolatesSoftConstraint(err)
+			}
+		}
+		if err == nil {
+			c.size, err = c.txn.Size()
+		}
+
+		if err != nil {
+			switch err.(type) {
+			case transaction.ErrTxnViolatesHardConstraint, transaction.ErrTxnViolatesSoftConstraint:
+				logger.Warningf("Transaction %s violates constraints: %v", c.hash.Hex(), err)
+			default:
+				return nil, err
+			}
+			continue
+		}
+
+		valid[i] = true
+	}
+
+	// Drop the descendants of invalid candidates
+	var usable func(i int) bool
+	usable = func(i int) bool {
+		if !valid[i] {
+			return false
+		}
+		for _, p := range candidates[i].parents {
+			if !usable(p) {
+				valid[i] = false
+				return false
+			}
+		}
+		return true
+	}
+
+	index := make([]int, len(candidates))
+	var filtered []blockTemplateCandidate
+	for i := range candidates {
+		if !usable(i) {
+			continue
+		}
+		index[i] = len(filtered)
+		filtered = append(filtered, candidates[i])
+	}
+
+	// Remap the parent indexes to the filtered candidates
+	for i := range filtered {
+		parents := filtered[i].parents
+		filtered[i].parents = make([]int, len(parents))
+		for j, p := range parents {
+			filtered[i].parents[j] = index[p]
+		}
+	}
+
+	nRemoved := len(candidates) - len(filtered)
+	if nRemoved > 0 {
+		logger.Infof("Block template ignored %d transactions violating constraints", nRemoved)
+	}
+
+	bt := &BlockTemplate{
+		Head:         head.Head,
+		Transactions: selectBlockTemplateTxns(filtered, vs.Config.MaxBlockTransactionsSize, coin.MaxBlockTransactions),
+	}
+
+	for _, t := range bt.Transactions {
+		var err error
+		if bt.Fee, err = mathutil.AddUint64(bt.Fee, t.Fee); err != nil {
+			bt.Fee = math.MaxUint64
+		}
+		bt.Size += t.Size
+	}
+
+	return bt, nil
+}
+
+// blockTemplateInputs returns the unspent outputs spent by a block template candidate,
+// after checking that the candidate does not violate constraints.
+// If the candidate spends the outputs of other candidates, they are looked up in outputs.
+func (vs *Visor) blockTemplateInputs(tx *dbutil.Tx, head *coin.SignedBlock, txn coin.Transaction, hasParents bool, outputs map[cipher.SHA256]coin.UxOut) (coin.UxArray, error) {
+	if !hasParents {
+		_, uxIn, err := vs.blockchain.VerifySingleTxnSoftHardConstraints(tx, txn, vs.Config.Distribution, vs.Config.CreateBlockVerifyTxn, transaction.TxnSigned)
+		return uxIn, err
+	}
+
+	var confirmed []cipher.SHA256
+	for _, in := range txn.In {
+		if _, ok := outputs[in]; !ok {
+			confirmed = append(confirmed, in)
+		}
+	}
+
+	confirmedUxs, err := vs.blockchain.Unspent().GetArray(tx, confirmed)
+	if err != nil {
+		return nil, transaction.NewErrTxnViolatesHardConstraint(err)
+	}
+
+	uxIn := make(coin.UxArray, len(txn.In))
+	for i, in := range txn.In {
+		if ux, ok := outputs[in]; ok {
+			uxIn[i] = ux
+			continue
+		}
+		uxIn[i] = confirmedUxs[0]
+		confirmedUxs = confirmedUxs[1:]
+	}
+
+	if err := transaction.VerifySingleTxnHardConstraints(txn, head.Head, uxIn, transaction.TxnSigned); err != nil {
+		return nil, err
+	}
+
+	if err := transaction.VerifySingleTxnSoftConstraints(txn, head.Time(), uxIn, vs.Config.Distribution, vs.Config.CreateBlockVerifyTxn); err != nil {
+		return nil, err
+	}
+
+	return uxIn, nil
+}
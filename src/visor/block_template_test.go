@@ -0,0 +1,187 @@
+package visor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestSelectBlockTemplateTxns(t *testing.T) {
+	makeCandidate := func(fee uint64, size uint32, in byte, parents ...int) blockTemplateCandidate {
+		txn := coin.Transaction{
+			In: []cipher.SHA256{{in}},
+		}
+		return blockTemplateCandidate{
+			txn:     txn,
+			hash:    cipher.SHA256{in, byte(fee)},
+			fee:     fee,
+			size:    size,
+			parents: parents,
+		}
+	}
+
+	candidates := []blockTemplateCandidate{
+		makeCandidate(100, 100, 1),
+		makeCandidate(300, 200, 2),
+		makeCandidate(50, 100, 3),
+		makeCandidate(1000, 100, 4, 2),
+		makeCandidate(400, 100, 5),
+		// Conflicts with candidates[4]
+		makeCandidate(200, 100, 5),
+	}
+
+	expect := func(idxs ...int) []BlockTemplateTransaction {
+		var txns []BlockTemplateTransaction
+		for _, i := range idxs {
+			c := candidates[i]
+			txns = append(txns, BlockTemplateTransaction{
+				Transaction: c.txn,
+				Fee:         c.fee,
+				Size:        c.size,
+				PackageFee:  c.fee,
+				PackageSize: c.size,
+			})
+		}
+		return txns
+	}
+
+	withChild := func(txns []BlockTemplateTransaction, i int) []BlockTemplateTransaction {
+		for j := range txns {
+			if txns[j].Transaction.In[0] == candidates[2].txn.In[0] {
+				txns[j].PackageFee += candidates[i].fee
+				txns[j].PackageSize += candidates[i].size
+			}
+		}
+		return txns
+	}
+
+	cases := []struct {
+		name     string
+		maxSize  uint32
+		maxCount int
+		expect   []BlockTemplateTransaction
+	}{
+		{
+			// candidates[2] is ranked first by its child's fee, the child is not selected
+			name:     "no limits",
+			maxSize:  1000,
+			maxCount: 100,
+			expect:   withChild(expect(2, 4, 1, 0), 3),
+		},
+		{
+			name:     "max count",
+			maxSize:  1000,
+			maxCount: 2,
+			expect:   withChild(expect(2, 4), 3),
+		},
+		{
+			// candidates[1] does not fit, the smaller candidates[0] is selected instead
+			name:     "max size",
+			maxSize:  350,
+			maxCount: 100,
+			expect:   withChild(expect(2, 4, 0), 3),
+		},
+		{
+			name:     "nothing fits",
+			maxSize:  99,
+			maxCount: 100,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			txns := selectBlockTemplateTxns(candidates, tc.maxSize, tc.maxCount)
+			require.Equal(t, tc.expect, txns)
+		})
+	}
+}
+
+func TestVisorNewBlockTemplate(t *testing.T) {
+	db, close := prepareDB(t)
+	defer close()
+
+	bc := MakeBlockchain(t, db, GenesisSecret)
+	makeUnconfirmedPoolTxns(t, db, bc, []uint64{0, 0, 0, 0})
+
+	var head *coin.SignedBlock
+	err := db.View("", func(tx *dbutil.Tx) error {
+		var err error
+		head, err = bc.Head(tx)
+		return err
+	})
+	require.NoError(t, err)
+
+	// The outputs created by makeUnconfirmedPoolTxns
+	uxs := coin.CreateUnspents(head.Head, head.Body.Transactions[0])
+	require.Len(t, uxs, 4)
+
+	spend := func(ux coin.UxOut, burned uint64) coin.Transaction {
+		return makeSpendTxWithHoursBurned(t, coin.UxArray{ux}, []cipher.SecKey{GenesisSecret}, GenesisAddress, ux.Body.Coins, burned)
+	}
+
+	a := spend(uxs[0], uxs[0].Body.Hours/5)
+	b := spend(uxs[1], uxs[1].Body.Hours/4)
+	parent := spend(uxs[2], uxs[2].Body.Hours/10+1)
+	parentOut := coin.CreateUnspents(head.Head, parent)[0]
+	child := spend(parentOut, parentOut.Body.Hours)
+	// Conflicts with parent
+	conflict := spend(uxs[2], uxs[2].Body.Hours/3)
+
+	size, err := a.Size()
+	require.NoError(t, err)
+	for _, txn := range []coin.Transaction{b, parent, child, conflict} {
+		s, err := txn.Size()
+		require.NoError(t, err)
+		require.Equal(t, size, s)
+	}
+
+	v := &Visor{
+		Config:     NewConfig(),
+		blockchain: bc,
+		db:         db,
+	}
+	v.Config.Distribution = params.MainNetDistribution
+
+	newTemplate := func(txns coin.Transactions) *BlockTemplate {
+		var bt *BlockTemplate
+		err := db.View("", func(tx *dbutil.Tx) error {
+			var err error
+			bt, err = v.newBlockTemplate(tx, txns)
+			return err
+		})
+		require.NoError(t, err)
+		return bt
+	}
+
+	bt := newTemplate(coin.Transactions{child, a, conflict, b, parent, a})
+	require.Equal(t, head.Head, bt.Head)
+
+	// The child pays for its parent, which is selected first
+	require.Equal(t, coin.Transactions{parent, b, a}, bt.Txns())
+	require.Equal(t, txnFeeOf(t, db, bc, parent), bt.Transactions[0].Fee)
+	require.Equal(t, txnFeeOf(t, db, bc, parent)+parentOut.Body.Hours, bt.Transactions[0].PackageFee)
+	require.Equal(t, size*2, bt.Transactions[0].PackageSize)
+	require.Equal(t, bt.Transactions[1].Fee, bt.Transactions[1].PackageFee)
+	require.Equal(t, txnFeeOf(t, db, bc, parent)+txnFeeOf(t, db, bc, b)+txnFeeOf(t, db, bc, a), bt.Fee)
+	require.Equal(t, size*3, bt.Size)
+
+	// Without its child, the parent loses to the conflicting txn
+	bt = newTemplate(coin.Transactions{a, conflict, b, parent})
+	require.Equal(t, coin.Transactions{conflict, b, a}, bt.Txns())
+
+	// An invalid child does not pay for its parent
+	badChild := child
+	badChild.Sigs = []cipher.Sig{{}}
+	bt = newTemplate(coin.Transactions{a, conflict, b, parent, badChild})
+	require.Equal(t, coin.Transactions{conflict, b, a}, bt.Txns())
+
+	// The block size limit is applied
+	v.Config.MaxBlockTransactionsSize = size * 2
+	bt = newTemplate(coin.Transactions{child, a, conflict, b, parent})
+	require.Equal(t, coin.Transactions{parent, b}, bt.Txns())
+}
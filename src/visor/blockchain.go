@@ -6,14 +6,14 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/ness-network/ness/src/visor/blockdb"
+	"github.com/ness-network/ness/src/visor/historydb"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/transaction"
 	"github.com/skycoin/skycoin/src/util/fee"
-	"github.com/skycoin/skycoin/src/visor/blockdb"
 	"github.com/skycoin/skycoin/src/visor/dbutil"
-	"github.com/skycoin/skycoin/src/visor/historydb"
 )
 
 const (
@@ -26,6 +26,22 @@ const (
 var (
 	// ErrVerifyStopped is returned when database verification is interrupted
 	ErrVerifyStopped = errors.New("database verification stopped")
+	// ErrBlockSignerNotPublisher is returned when a block is not signed by the blockchain pubkey or a block publisher
+	ErrBlockSignerNotPublisher = errors.New("Block is not signed by a block publisher")
+	// ErrBlockPublisherQuorum is returned when a block signed by a block publisher is not signed by a quorum of block publishers
+	ErrBlockPublisherQuorum = errors.New("Block is not signed by a quorum of block publishers")
+	// ErrSideBlockNoParent is returned when adding a side block whose parent is not stored
+	ErrSideBlockNoParent = errors.New("Parent of the side block is unknown")
+	// ErrReorgMainChain is returned when reorganizing the blockchain to a block of the main chain
+	ErrReorgMainChain = errors.New("Block is already in the main chain")
+	// ErrReorgTooDeep is returned when reorganizing the blockchain would roll back more blocks than the max reorg depth
+	ErrReorgTooDeep = errors.New("Side branch forks off deeper than the max reorg depth")
+	// ErrKeyCheckpointSeq is returned when a key checkpoint is not above the head block and the last key checkpoint
+	ErrKeyCheckpointSeq = errors.New("Key checkpoint seq must be above the head block and the last key checkpoint")
+	// ErrKeyCheckpointSigner is returned when a key checkpoint is not signed by the blockchain pubkey in effect
+	ErrKeyCheckpointSigner = errors.New("Key checkpoint is not signed by the blockchain pubkey in effect")
+	// ErrKeyCheckpointNotPublisher is returned when a node that is not a block publisher creates a key checkpoint
+	ErrKeyCheckpointNotPublisher = errors.New("Only a block publisher node can create key checkpoints")
 )
 
 // ErrBlockNotExist may be returned if a block is not found
@@ -68,6 +84,9 @@ type chainStore interface {
 	HeadSeq(*dbutil.Tx) (uint64, bool, error)
 	Len(*dbutil.Tx) (uint64, error)
 	AddBlock(*dbutil.Tx, *coin.SignedBlock) error
+	AddSideBlock(*dbutil.Tx, *coin.SignedBlock) error
+	ApplySideBlock(*dbutil.Tx, *coin.SignedBlock) error
+	RollbackHead(*dbutil.Tx, coin.UxArray) error
 	GetBlockByHash(*dbutil.Tx, cipher.SHA256) (*coin.Block, error)
 	GetSignedBlockByHash(*dbutil.Tx, cipher.SHA256) (*coin.SignedBlock, error)
 	GetSignedBlockBySeq(*dbutil.Tx, uint64) (*coin.SignedBlock, error)
@@ -75,6 +94,10 @@ type chainStore interface {
 	GetGenesisBlock(*dbutil.Tx) (*coin.SignedBlock, error)
 	GetBlockSignature(*dbutil.Tx, *coin.Block) (cipher.Sig, bool, error)
 	ForEachBlock(*dbutil.Tx, func(*coin.Block) error) error
+	AddKeyCheckpoint(*dbutil.Tx, blockdb.KeyCheckpoint) error
+	GetKeyCheckpoints(*dbutil.Tx) ([]blockdb.KeyCheckpoint, error)
+	AddBlockCertificate(*dbutil.Tx, blockdb.BlockCertificate) error
+	GetBlockCertificate(*dbutil.Tx, cipher.SHA256) (*blockdb.BlockCertificate, error)
 }
 
 // DefaultWalker default blockchain walker
@@ -99,6 +122,10 @@ func CreateBuckets(db *dbutil.DB) error {
 		return dbutil.CreateBuckets(tx, [][]byte{
 			UnconfirmedTxnsBkt,
 			UnconfirmedUnspentsBkt,
+			UnconfirmedTxnFeesBkt,
+			UnconfirmedTxnFeeRatesBkt,
+			UnconfirmedTxnInputsBkt,
+			UnconfirmedPoolSizeBkt,
 		})
 	})
 }
@@ -109,7 +136,13 @@ type BlockchainConfig struct {
 	// the invalid transaction will be skipped and continue the next; otherwise,
 	// node will throw the error and return.
 	Arbitrating bool
-	Pubkey      cipher.PubKey
+	// Pubkey is the genesis blockchain pubkey. Key checkpoints hand the block signing authority to other pubkeys
+	Pubkey cipher.PubKey
+	// PublisherPubkeys are accepted as block signers besides Pubkey.
+	// A block signed by one of them must carry a certificate signed by PublisherQuorum of them
+	PublisherPubkeys []cipher.PubKey
+	// PublisherQuorum is the number of PublisherPubkeys that must sign a block. 0 means a majority
+	PublisherQuorum int
 }
 
 // Blockchain maintains blockchain and provides apis for accessing the chain.
@@ -295,6 +328,58 @@ func (bc *Blockchain) ExecuteBlock(tx *dbutil.Tx, sb *coin.SignedBlock) error {
 	return nil
 }
 
+// AddSideBlock stores a block that does not extend the head block in a side branch.
+// The parent of the block must be stored already.
+func (bc *Blockchain) AddSideBlock(tx *dbutil.Tx, sb *coin.SignedBlock) error {
+	if sb.Seq() == 0 {
+		return errors.New("Genesis block cannot be a side block")
+	}
+
+	if sb.Body.Hash() != sb.Head.BodyHash {
+		return errors.New("Computed body hash does not match")
+	}
+
+	parent, err := bc.store.GetSignedBlockByHash(tx, sb.Head.PrevHash)
+	if err != nil {
+		return err
+	}
+
+	if parent == nil || parent.Seq()+1 != sb.Seq() {
+		return ErrSideBlockNoParent
+	}
+
+	return bc.store.AddSideBlock(tx, sb)
+}
+
+// ApplySideBlock makes a block stored by AddSideBlock the new head block.
+// The block must extend the head block and is verified like ExecuteBlock, but its transactions
+// must all be valid, since the stored block cannot be modified.
+func (bc *Blockchain) ApplySideBlock(tx *dbutil.Tx, sb *coin.SignedBlock) error {
+	nb, err := bc.processBlock(tx, *sb)
+	if err != nil {
+		return err
+	}
+
+	if nb.Body.Hash() != sb.Head.BodyHash {
+		return errors.New("Side block contains invalid transactions")
+	}
+
+	return bc.store.ApplySideBlock(tx, &nb)
+}
+
+// RollbackHead reverts the head block, which is kept as a side block.
+// spent must contain the outputs spent by the head block.
+func (bc *Blockchain) RollbackHead(tx *dbutil.Tx, spent coin.UxArray) error {
+	headSeq, ok, err := bc.HeadSeq(tx)
+	if err != nil {
+		return err
+	} else if !ok || headSeq == 0 {
+		return errors.New("Cannot roll back the genesis block")
+	}
+
+	return bc.store.RollbackHead(tx, spent)
+}
+
 // VerifyBlock verifies specified block against current state of blockchain.
 func (bc *Blockchain) VerifyBlock(tx *dbutil.Tx, sb *coin.SignedBlock) error {
 	_, err := bc.processBlock(tx, *sb)
@@ -700,14 +785,233 @@ func (bc Blockchain) TransactionFee(tx *dbutil.Tx, headTime uint64) coin.FeeCalc
 
 // VerifySignature checks that BlockSigs state correspond with coin.Blockchain state
 // and that all signatures are valid.
-func (bc *Blockchain) VerifySignature(block *coin.SignedBlock) error {
-	err := block.VerifySignature(bc.cfg.Pubkey)
+// The block must be signed by the blockchain pubkey in effect for its seq, or by a block publisher.
+// A block signed by a block publisher must have a stored certificate that makes it signed by a quorum of block publishers.
+func (bc *Blockchain) VerifySignature(tx *dbutil.Tx, block *coin.SignedBlock) error {
+	pubkey, err := bc.BlockSigningPubkey(tx, block.Seq())
+	if err != nil {
+		return err
+	}
+
+	err = bc.verifySignature(tx, block, pubkey)
 	if err != nil {
 		logger.Errorf("Blockchain signature verification failed for block %d: %v", block.Head.BkSeq, err)
 	}
 	return err
 }
 
+func (bc *Blockchain) verifySignature(tx *dbutil.Tx, block *coin.SignedBlock, pubkey cipher.PubKey) error {
+	signer, err := verifyBlockSignature(block, pubkey, bc.cfg.PublisherPubkeys)
+	if err != nil || signer == pubkey {
+		return err
+	}
+
+	cert, err := bc.store.GetBlockCertificate(tx, block.HashHeader())
+	if err != nil {
+		return err
+	}
+
+	signers := []cipher.PubKey{signer}
+	if cert != nil {
+		signers = append(signers, cert.Signers()...)
+	}
+
+	if countPublishers(signers, bc.cfg.PublisherPubkeys) < bc.PublisherQuorum() {
+		return ErrBlockPublisherQuorum
+	}
+
+	return nil
+}
+
+// PublisherQuorum returns the number of block publishers that must sign a block
+func (bc *Blockchain) PublisherQuorum() int {
+	return publisherQuorum(len(bc.cfg.PublisherPubkeys), bc.cfg.PublisherQuorum)
+}
+
+// AddBlockCertificate stores the signatures of block publishers for a block.
+// Signatures that are not by a block publisher are dropped, and the signatures are merged with the stored certificate.
+func (bc *Blockchain) AddBlockCertificate(tx *dbutil.Tx, hash cipher.SHA256, sigs []cipher.Sig) error {
+	cert, err := bc.store.GetBlockCertificate(tx, hash)
+	if err != nil {
+		return err
+	}
+
+	if cert == nil {
+		cert = &blockdb.BlockCertificate{
+			Hash: hash,
+		}
+	}
+
+	signers := make(map[cipher.PubKey]struct{}, len(cert.Sigs))
+	for _, pk := range cert.Signers() {
+		signers[pk] = struct{}{}
+	}
+
+	n := len(cert.Sigs)
+	for _, sig := range sigs {
+		pubkey, err := cipher.PubKeyFromSig(sig, hash)
+		if err != nil || !containsPubkey(bc.cfg.PublisherPubkeys, pubkey) {
+			continue
+		}
+
+		if _, ok := signers[pubkey]; ok {
+			continue
+		}
+
+		if err := cipher.VerifyPubKeySignedHash(pubkey, sig, hash); err != nil {
+			continue
+		}
+
+		signers[pubkey] = struct{}{}
+		cert.Sigs = append(cert.Sigs, sig)
+	}
+
+	if len(cert.Sigs) == n {
+		return nil
+	}
+
+	return bc.store.AddBlockCertificate(tx, *cert)
+}
+
+// GetBlockCertificate returns the block publisher signatures of the block with hash. Returns nil if there are none
+func (bc *Blockchain) GetBlockCertificate(tx *dbutil.Tx, hash cipher.SHA256) (*blockdb.BlockCertificate, error) {
+	return bc.store.GetBlockCertificate(tx, hash)
+}
+
+// GetKeyCheckpoints returns the block publisher key checkpoints, ordered by seq
+func (bc *Blockchain) GetKeyCheckpoints(tx *dbutil.Tx) ([]blockdb.KeyCheckpoint, error) {
+	return bc.store.GetKeyCheckpoints(tx)
+}
+
+// AddKeyCheckpoint adds a key checkpoint that hands the block signing authority to a new pubkey.
+// The checkpoint must be above the head block and the last key checkpoint, and be signed by the
+// blockchain pubkey in effect before it. Returns false if the checkpoint is known already.
+func (bc *Blockchain) AddKeyCheckpoint(tx *dbutil.Tx, kc blockdb.KeyCheckpoint) (bool, error) {
+	checkpoints, err := bc.store.GetKeyCheckpoints(tx)
+	if err != nil {
+		return false, err
+	}
+
+	for _, c := range checkpoints {
+		if c == kc {
+			return false, nil
+		}
+	}
+
+	if kc.Pubkey.Null() {
+		return false, errors.New("Key checkpoint pubkey is empty")
+	}
+
+	if kc.Seq == 0 || (len(checkpoints) != 0 && kc.Seq <= checkpoints[len(checkpoints)-1].Seq) {
+		return false, ErrKeyCheckpointSeq
+	}
+
+	headSeq, ok, err := bc.HeadSeq(tx)
+	if err != nil {
+		return false, err
+	}
+
+	if ok && kc.Seq <= headSeq {
+		return false, ErrKeyCheckpointSeq
+	}
+
+	if err := kc.Verify(blockSigningPubkey(bc.cfg.Pubkey, checkpoints, kc.Seq-1)); err != nil {
+		return false, ErrKeyCheckpointSigner
+	}
+
+	if err := bc.store.AddKeyCheckpoint(tx, kc); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// BlockSigningPubkey returns the blockchain pubkey in effect for the block with seq
+func (bc *Blockchain) BlockSigningPubkey(tx *dbutil.Tx, seq uint64) (cipher.PubKey, error) {
+	checkpoints, err := bc.store.GetKeyCheckpoints(tx)
+	if err != nil {
+		return cipher.PubKey{}, err
+	}
+
+	return blockSigningPubkey(bc.cfg.Pubkey, checkpoints, seq), nil
+}
+
+// BlockchainPubkeys returns the genesis blockchain pubkey followed by the pubkeys of the key checkpoints
+func (bc *Blockchain) BlockchainPubkeys(tx *dbutil.Tx) ([]cipher.PubKey, error) {
+	checkpoints, err := bc.store.GetKeyCheckpoints(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	pubkeys := make([]cipher.PubKey, 0, len(checkpoints)+1)
+	pubkeys = append(pubkeys, bc.cfg.Pubkey)
+	for _, kc := range checkpoints {
+		pubkeys = append(pubkeys, kc.Pubkey)
+	}
+
+	return pubkeys, nil
+}
+
+// blockSigningPubkey returns the pubkey of the last checkpoint at or below seq, or pubkey if there is none.
+// checkpoints must be ordered by seq.
+func blockSigningPubkey(pubkey cipher.PubKey, checkpoints []blockdb.KeyCheckpoint, seq uint64) cipher.PubKey {
+	for _, kc := range checkpoints {
+		if kc.Seq > seq {
+			break
+		}
+		pubkey = kc.Pubkey
+	}
+
+	return pubkey
+}
+
+// verifyBlockSignature checks that the block is signed by pubkey or by one of the publisherPubkeys, and returns the signer
+func verifyBlockSignature(block *coin.SignedBlock, pubkey cipher.PubKey, publisherPubkeys []cipher.PubKey) (cipher.PubKey, error) {
+	if len(publisherPubkeys) == 0 {
+		return pubkey, block.VerifySignature(pubkey)
+	}
+
+	signer, err := cipher.PubKeyFromSig(block.Sig, block.HashHeader())
+	if err != nil {
+		return cipher.PubKey{}, err
+	}
+
+	if signer != pubkey && !containsPubkey(publisherPubkeys, signer) {
+		return cipher.PubKey{}, ErrBlockSignerNotPublisher
+	}
+
+	return signer, block.VerifySignature(signer)
+}
+
+// publisherQuorum returns the number of the n block publishers that must sign a block. 0 means a majority
+func publisherQuorum(n, quorum int) int {
+	if quorum == 0 {
+		return n/2 + 1
+	}
+	return quorum
+}
+
+// countPublishers returns the number of distinct publisherPubkeys in signers
+func countPublishers(signers, publisherPubkeys []cipher.PubKey) int {
+	seen := make(map[cipher.PubKey]struct{}, len(signers))
+	for _, pk := range signers {
+		if containsPubkey(publisherPubkeys, pk) {
+			seen[pk] = struct{}{}
+		}
+	}
+	return len(seen)
+}
+
+// containsPubkey returns true if pubkeys contains pubkey
+func containsPubkey(pubkeys []cipher.PubKey, pubkey cipher.PubKey) bool {
+	for _, p := range pubkeys {
+		if p == pubkey {
+			return true
+		}
+	}
+	return false
+}
+
 // WalkChain walk through the blockchain concurrently
 // The quit channel is optional and if closed, this method still stop.
 func (bc *Blockchain) WalkChain(workers int, f func(*dbutil.Tx, *coin.SignedBlock) error, quit chan struct{}) error {
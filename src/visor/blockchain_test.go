@@ -6,11 +6,11 @@ import (
 
 	"github.com/stretchr/testify/require"
 
+	"github.com/ness-network/ness/src/visor/blockdb"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/testutil"
 	"github.com/skycoin/skycoin/src/transaction"
-	"github.com/skycoin/skycoin/src/visor/blockdb"
 	"github.com/skycoin/skycoin/src/visor/dbutil"
 )
 
@@ -107,6 +107,18 @@ func (fcs *fakeChainStore) AddBlock(tx *dbutil.Tx, b *coin.SignedBlock) error {
 	return nil
 }
 
+func (fcs *fakeChainStore) AddSideBlock(tx *dbutil.Tx, b *coin.SignedBlock) error {
+	return nil
+}
+
+func (fcs *fakeChainStore) ApplySideBlock(tx *dbutil.Tx, b *coin.SignedBlock) error {
+	return nil
+}
+
+func (fcs *fakeChainStore) RollbackHead(tx *dbutil.Tx, spent coin.UxArray) error {
+	return nil
+}
+
 func (fcs *fakeChainStore) GetBlockSignature(tx *dbutil.Tx, b *coin.Block) (cipher.Sig, bool, error) {
 	return cipher.Sig{}, false, nil
 }
@@ -143,6 +155,22 @@ func (fcs *fakeChainStore) ForEachBlock(tx *dbutil.Tx, f func(*coin.Block) error
 	return nil
 }
 
+func (fcs *fakeChainStore) AddKeyCheckpoint(tx *dbutil.Tx, kc blockdb.KeyCheckpoint) error {
+	return nil
+}
+
+func (fcs *fakeChainStore) GetKeyCheckpoints(tx *dbutil.Tx) ([]blockdb.KeyCheckpoint, error) {
+	return nil, nil
+}
+
+func (fcs *fakeChainStore) AddBlockCertificate(tx *dbutil.Tx, bc blockdb.BlockCertificate) error {
+	return nil
+}
+
+func (fcs *fakeChainStore) GetBlockCertificate(tx *dbutil.Tx, hash cipher.SHA256) (*blockdb.BlockCertificate, error) {
+	return nil, nil
+}
+
 func makeBlock(t *testing.T, preBlock coin.Block, tm uint64) *coin.Block {
 	uxHash := testutil.RandSHA256(t)
 	tx := coin.Transaction{}
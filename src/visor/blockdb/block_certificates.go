@@ -0,0 +1,72 @@
+package blockdb
+
+import (
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+var (
+	// BlockCertificatesBkt holds the block publisher signatures that settled a block, by block hash
+	BlockCertificatesBkt = []byte("block_certificates")
+)
+
+// BlockCertificate holds the signatures of the block header hash by the block publishers that settled the block.
+// A block signed by a block publisher is only valid with the signatures of a quorum of block publishers.
+type BlockCertificate struct {
+	Hash cipher.SHA256
+	Sigs []cipher.Sig `enc:",maxlen=256"`
+}
+
+// Signers returns the pubkeys that signed the block hash with the certificate signatures.
+// Signatures that are invalid are ignored.
+func (bc BlockCertificate) Signers() []cipher.PubKey {
+	signers := make([]cipher.PubKey, 0, len(bc.Sigs))
+	for _, sig := range bc.Sigs {
+		pubkey, err := cipher.PubKeyFromSig(sig, bc.Hash)
+		if err != nil {
+			continue
+		}
+
+		if err := cipher.VerifyPubKeySignedHash(pubkey, sig, bc.Hash); err != nil {
+			continue
+		}
+
+		signers = append(signers, pubkey)
+	}
+
+	return signers
+}
+
+// blockCertificates manages the block certificates
+type blockCertificates struct{}
+
+// Add adds a block certificate to the db, replacing the certificate of the same block
+func (bcs *blockCertificates) Add(tx *dbutil.Tx, bc BlockCertificate) error {
+	buf, err := encodeBlockCertificate(&bc)
+	if err != nil {
+		return err
+	}
+	return dbutil.PutBucketValue(tx, BlockCertificatesBkt, bc.Hash[:], buf)
+}
+
+// Get returns the certificate of the block with hash. Returns nil if there is none
+func (bcs *blockCertificates) Get(tx *dbutil.Tx, hash cipher.SHA256) (*BlockCertificate, error) {
+	// The bucket is missing from a read-only db that was created by an older version
+	if !dbutil.Exists(tx, BlockCertificatesBkt) {
+		return nil, nil
+	}
+
+	v, err := dbutil.GetBucketValueNoCopy(tx, BlockCertificatesBkt, hash[:])
+	if err != nil {
+		return nil, err
+	} else if v == nil {
+		return nil, nil
+	}
+
+	var bc BlockCertificate
+	if err := decodeBlockCertificateExact(v, &bc); err != nil {
+		return nil, err
+	}
+
+	return &bc, nil
+}
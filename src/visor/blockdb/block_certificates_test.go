@@ -0,0 +1,105 @@
+package blockdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestBlockCertificateSigners(t *testing.T) {
+	pubkey1, seckey1 := cipher.GenerateKeyPair()
+	pubkey2, seckey2 := cipher.GenerateKeyPair()
+
+	hash := cipher.SumSHA256([]byte("block"))
+	otherHash := cipher.SumSHA256([]byte("other block"))
+
+	bc := BlockCertificate{
+		Hash: hash,
+		Sigs: []cipher.Sig{
+			cipher.MustSignHash(hash, seckey1),
+			cipher.MustSignHash(otherHash, seckey2),
+			cipher.MustSignHash(hash, seckey2),
+		},
+	}
+
+	// The signature of another hash recovers a different pubkey
+	signers := bc.Signers()
+	require.Len(t, signers, 3)
+	require.Equal(t, pubkey1, signers[0])
+	require.NotEqual(t, pubkey2, signers[1])
+	require.Equal(t, pubkey2, signers[2])
+
+	// Invalid signatures are ignored
+	bc.Sigs = []cipher.Sig{{}}
+	require.Empty(t, bc.Signers())
+}
+
+func TestBlockCertificates(t *testing.T) {
+	db, closeDB := prepareDB(t)
+	defer closeDB()
+
+	_, seckey1 := cipher.GenerateKeyPair()
+	_, seckey2 := cipher.GenerateKeyPair()
+
+	hash := cipher.SumSHA256([]byte("block"))
+	bc1 := BlockCertificate{
+		Hash: hash,
+		Sigs: []cipher.Sig{cipher.MustSignHash(hash, seckey1)},
+	}
+	bc2 := BlockCertificate{
+		Hash: hash,
+		Sigs: []cipher.Sig{
+			cipher.MustSignHash(hash, seckey1),
+			cipher.MustSignHash(hash, seckey2),
+		},
+	}
+
+	bcs := &blockCertificates{}
+
+	err := db.View("", func(tx *dbutil.Tx) error {
+		cert, err := bcs.Get(tx, hash)
+		require.NoError(t, err)
+		require.Nil(t, cert)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return bcs.Add(tx, bc1)
+	})
+	require.NoError(t, err)
+
+	// The certificate is encoded like the encoder does
+	err = db.View("", func(tx *dbutil.Tx) error {
+		cert, err := bcs.Get(tx, hash)
+		require.NoError(t, err)
+		require.Equal(t, &bc1, cert)
+
+		v := tx.Bucket(BlockCertificatesBkt).Get(hash[:])
+		require.NotNil(t, v)
+		var bc BlockCertificate
+		require.NoError(t, encoder.DeserializeRawExact(v, &bc))
+		require.Equal(t, bc1, bc)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The certificate of the same block is replaced
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return bcs.Add(tx, bc2)
+	})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		cert, err := bcs.Get(tx, hash)
+		require.NoError(t, err)
+		require.Equal(t, &bc2, cert)
+
+		return VerifyDBSkyencoderSafe(tx, nil)
+	})
+	require.NoError(t, err)
+}
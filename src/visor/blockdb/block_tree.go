@@ -14,6 +14,7 @@ var (
 	errNoParent    = errors.New("block is not genesis and has no parent")
 	errWrongParent = errors.New("wrong parent")
 	errHasChild    = errors.New("remove block failed, it has children")
+	errNotInTree   = errors.New("block is not in the block tree")
 
 	// BlocksBkt holds coin.Blocks
 	BlocksBkt = []byte("blocks")
@@ -127,6 +128,28 @@ func (bt *blockTree) RemoveBlock(tx *dbutil.Tx, b *coin.Block) error {
 	return setHashPairInDepth(tx, b.Seq(), ps)
 }
 
+// SetMainBlock moves the hash pair of the block to the front of its depth.
+// Walkers that follow the first hash pair of each depth, like visor.DefaultWalker,
+// will pick this block instead of its siblings.
+func (bt *blockTree) SetMainBlock(tx *dbutil.Tx, b *coin.Block) error {
+	hashPairs, err := getHashPairInDepth(tx, b.Seq(), allPairs)
+	if err != nil {
+		return err
+	}
+
+	hp := coin.HashPair{
+		Hash:     b.HashHeader(),
+		PrevHash: b.Head.PrevHash,
+	}
+
+	if !containHash(hashPairs, hp) {
+		return errNotInTree
+	}
+
+	ps := append([]coin.HashPair{hp}, removePairs(hashPairs, hp)...)
+	return setHashPairInDepth(tx, b.Seq(), ps)
+}
+
 // GetBlock get block by hash, return nil on not found
 func (bt *blockTree) GetBlock(tx *dbutil.Tx, hash cipher.SHA256) (*coin.Block, error) {
 	var b coin.Block
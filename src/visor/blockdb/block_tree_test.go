@@ -226,3 +226,85 @@ func TestGetBlockInDepth(t *testing.T) {
 	require.NotNil(t, block)
 	require.Equal(t, blocks[2], *block)
 }
+
+func TestSetMainBlock(t *testing.T) {
+	db, teardown := prepareDB(t)
+	defer teardown()
+
+	bc := &blockTree{}
+	blocks := []coin.Block{
+		coin.Block{
+			Head: coin.BlockHeader{
+				BkSeq: 0,
+				Time:  0,
+			},
+		},
+		coin.Block{
+			Head: coin.BlockHeader{
+				BkSeq: 1,
+				Time:  1,
+			},
+		},
+		coin.Block{
+			Head: coin.BlockHeader{
+				BkSeq: 1,
+				Time:  2,
+			},
+		},
+		coin.Block{
+			Head: coin.BlockHeader{
+				BkSeq: 1,
+				Time:  3,
+			},
+		},
+	}
+
+	firstPair := func(tx *dbutil.Tx, hps []coin.HashPair) (cipher.SHA256, bool) {
+		return hps[0].Hash, true
+	}
+
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		err := bc.AddBlock(tx, &blocks[0])
+		require.NoError(t, err)
+
+		for i := range blocks[1:] {
+			blocks[i+1].Head.PrevHash = blocks[0].HashHeader()
+			err = bc.AddBlock(tx, &blocks[i+1])
+			require.NoError(t, err)
+		}
+
+		// the block added first is the main block
+		b, err := bc.GetBlockInDepth(tx, 1, firstPair)
+		require.NoError(t, err)
+		require.Equal(t, blocks[1], *b)
+
+		err = bc.SetMainBlock(tx, &blocks[2])
+		require.NoError(t, err)
+
+		b, err = bc.GetBlockInDepth(tx, 1, firstPair)
+		require.NoError(t, err)
+		require.Equal(t, blocks[2], *b)
+
+		// the siblings are kept
+		hps, err := getHashPairInDepth(tx, 1, allPairs)
+		require.NoError(t, err)
+		require.Len(t, hps, 3)
+		require.Equal(t, blocks[2].HashHeader(), hps[0].Hash)
+		require.Equal(t, blocks[1].HashHeader(), hps[1].Hash)
+		require.Equal(t, blocks[3].HashHeader(), hps[2].Hash)
+
+		// unknown block
+		unknown := coin.Block{
+			Head: coin.BlockHeader{
+				BkSeq:    1,
+				Time:     4,
+				PrevHash: blocks[0].HashHeader(),
+			},
+		}
+		err = bc.SetMainBlock(tx, &unknown)
+		require.Equal(t, errNotInTree, err)
+
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -25,6 +25,8 @@ var (
 //go:generate skyencoder -unexported -struct hashPairsWrapper
 //go:generate skyencoder -unexported -struct hashesWrapper
 //go:generate skyencoder -unexported -struct sigWrapper
+//go:generate skyencoder -unexported -struct KeyCheckpoint
+//go:generate skyencoder -unexported -struct BlockCertificate
 
 // hashesWrapper wraps []cipher.SHA256 so it can be used by skyencoder
 type hashesWrapper struct {
@@ -67,6 +69,10 @@ func CreateBuckets(tx *dbutil.Tx) error {
 		UnspentPoolBkt,
 		UnspentPoolAddrIndexBkt,
 		UnspentMetaBkt,
+		UnspentAddrBalanceBkt,
+		UnspentRichlistBkt,
+		KeyCheckpointsBkt,
+		BlockCertificatesBkt,
 	})
 }
 
@@ -75,6 +81,7 @@ type BlockTree interface {
 	AddBlock(*dbutil.Tx, *coin.Block) error
 	GetBlock(*dbutil.Tx, cipher.SHA256) (*coin.Block, error)
 	GetBlockInDepth(*dbutil.Tx, uint64, Walker) (*coin.Block, error)
+	SetMainBlock(*dbutil.Tx, *coin.Block) error
 	ForEachBlock(*dbutil.Tx, func(*coin.Block) error) error
 }
 
@@ -99,7 +106,23 @@ type UnspentPooler interface {
 	GetUnspentsOfAddrs(*dbutil.Tx, []cipher.Address) (coin.AddressUxOuts, error)
 	GetUnspentHashesOfAddrs(*dbutil.Tx, []cipher.Address) (AddressHashes, error)
 	ProcessBlock(*dbutil.Tx, *coin.SignedBlock) error
+	RollbackBlock(*dbutil.Tx, *coin.SignedBlock, coin.UxArray) error
 	AddressCount(*dbutil.Tx) (uint64, error)
+	WalkAddressBalances(*dbutil.Tx, func(AddressBalance) (bool, error)) error
+	VerifyAddressBalances(*dbutil.Tx) error
+	RebuildAddressBalances(*dbutil.Tx) error
+}
+
+// KeyCheckpoints block publisher key checkpoint storage
+type KeyCheckpoints interface {
+	Add(*dbutil.Tx, KeyCheckpoint) error
+	GetAll(*dbutil.Tx) ([]KeyCheckpoint, error)
+}
+
+// BlockCertificates block publisher signature storage
+type BlockCertificates interface {
+	Add(*dbutil.Tx, BlockCertificate) error
+	Get(*dbutil.Tx, cipher.SHA256) (*BlockCertificate, error)
 }
 
 // ChainMeta blockchain metadata
@@ -115,6 +138,8 @@ type Blockchain struct {
 	unspent UnspentPooler
 	tree    BlockTree
 	sigs    BlockSigs
+	keys    KeyCheckpoints
+	certs   BlockCertificates
 	walker  Walker
 }
 
@@ -134,6 +159,8 @@ func NewBlockchain(db *dbutil.DB, walker Walker) (*Blockchain, error) {
 		meta:    &chainMeta{},
 		tree:    &blockTree{},
 		sigs:    &blockSigs{},
+		keys:    &keyCheckpoints{},
+		certs:   &blockCertificates{},
 		walker:  walker,
 	}, nil
 }
@@ -161,6 +188,46 @@ func (bc *Blockchain) AddBlock(tx *dbutil.Tx, sb *coin.SignedBlock) error {
 	return nil
 }
 
+// AddSideBlock adds a signed block to a side branch of the block tree.
+// The parent of the block must be stored already. The unspent pool and the head block are not updated,
+// the block becomes part of the main chain once it is applied with ApplySideBlock.
+func (bc *Blockchain) AddSideBlock(tx *dbutil.Tx, sb *coin.SignedBlock) error {
+	if err := bc.sigs.Add(tx, sb.HashHeader(), sb.Sig); err != nil {
+		return fmt.Errorf("save signature failed: %v", err)
+	}
+
+	if err := bc.tree.AddBlock(tx, &sb.Block); err != nil {
+		return fmt.Errorf("save block failed: %v", err)
+	}
+
+	return nil
+}
+
+// ApplySideBlock makes a block stored by AddSideBlock the main chain block of its depth,
+// and updates the head block and the unspent pool. The block must extend the head block.
+func (bc *Blockchain) ApplySideBlock(tx *dbutil.Tx, sb *coin.SignedBlock) error {
+	if err := bc.tree.SetMainBlock(tx, &sb.Block); err != nil {
+		return fmt.Errorf("set main block failed: %v", err)
+	}
+
+	return bc.processBlock(tx, sb)
+}
+
+// RollbackHead reverts the head block, which remains stored as a side block.
+// spent must contain the outputs spent by the head block.
+func (bc *Blockchain) RollbackHead(tx *dbutil.Tx, spent coin.UxArray) error {
+	head, err := bc.Head(tx)
+	if err != nil {
+		return err
+	}
+
+	if err := bc.unspent.RollbackBlock(tx, head, spent); err != nil {
+		return err
+	}
+
+	return bc.meta.SetHeadSeq(tx, head.Seq()-1)
+}
+
 // processBlock processes a block and updates the db
 func (bc *Blockchain) processBlock(tx *dbutil.Tx, b *coin.SignedBlock) error {
 	if err := bc.unspent.ProcessBlock(tx, b); err != nil {
@@ -213,6 +280,26 @@ func (bc *Blockchain) GetBlockSignature(tx *dbutil.Tx, b *coin.Block) (cipher.Si
 	return bc.sigs.Get(tx, b.HashHeader())
 }
 
+// AddKeyCheckpoint adds a block publisher key checkpoint
+func (bc *Blockchain) AddKeyCheckpoint(tx *dbutil.Tx, kc KeyCheckpoint) error {
+	return bc.keys.Add(tx, kc)
+}
+
+// GetKeyCheckpoints returns the block publisher key checkpoints, ordered by seq
+func (bc *Blockchain) GetKeyCheckpoints(tx *dbutil.Tx) ([]KeyCheckpoint, error) {
+	return bc.keys.GetAll(tx)
+}
+
+// AddBlockCertificate adds the block publisher signatures of a block
+func (bc *Blockchain) AddBlockCertificate(tx *dbutil.Tx, cert BlockCertificate) error {
+	return bc.certs.Add(tx, cert)
+}
+
+// GetBlockCertificate returns the block publisher signatures of the block with hash. Returns nil if there are none
+func (bc *Blockchain) GetBlockCertificate(tx *dbutil.Tx, hash cipher.SHA256) (*BlockCertificate, error) {
+	return bc.certs.Get(tx, hash)
+}
+
 // GetBlockByHash returns block of given hash
 func (bc *Blockchain) GetBlockByHash(tx *dbutil.Tx, hash cipher.SHA256) (*coin.Block, error) {
 	b, err := bc.tree.GetBlock(tx, hash)
@@ -249,8 +336,16 @@ func (bc *Blockchain) GetSignedBlockByHash(tx *dbutil.Tx, hash cipher.SHA256) (*
 	}, nil
 }
 
-// GetSignedBlockBySeq returns signed block of given seq
+// GetSignedBlockBySeq returns signed block of given seq, return nil if seq is above the head block
 func (bc *Blockchain) GetSignedBlockBySeq(tx *dbutil.Tx, seq uint64) (*coin.SignedBlock, error) {
+	// Blocks above the head block may remain in the tree after a reorg
+	headSeq, ok, err := bc.meta.GetHeadSeq(tx)
+	if err != nil {
+		return nil, err
+	} else if !ok || seq > headSeq {
+		return nil, nil
+	}
+
 	b, err := bc.tree.GetBlockInDepth(tx, seq, bc.walker)
 	if err != nil {
 		return nil, fmt.Errorf("bc.tree.GetBlockInDepth failed: %v", err)
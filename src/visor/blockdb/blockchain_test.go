@@ -108,6 +108,10 @@ func (bt *fakeBlockTree) ForEachBlock(tx *dbutil.Tx, f func(*coin.Block) error)
 	return nil
 }
 
+func (bt *fakeBlockTree) SetMainBlock(tx *dbutil.Tx, b *coin.Block) error {
+	return nil
+}
+
 type fakeSignatureStore struct {
 	sigs       map[string]cipher.Sig
 	saveFailed bool
@@ -253,6 +257,10 @@ func (fup *fakeUnspentPool) ProcessBlock(tx *dbutil.Tx, b *coin.SignedBlock) err
 	return nil
 }
 
+func (fup *fakeUnspentPool) RollbackBlock(tx *dbutil.Tx, b *coin.SignedBlock, spent coin.UxArray) error {
+	return nil
+}
+
 func (fup *fakeUnspentPool) Contains(tx *dbutil.Tx, h cipher.SHA256) (bool, error) {
 	_, ok := fup.outs[h]
 	return ok, nil
@@ -267,6 +275,18 @@ func (fup *fakeUnspentPool) AddressCount(tx *dbutil.Tx) (uint64, error) {
 	return uint64(len(addrs)), nil
 }
 
+func (fup *fakeUnspentPool) WalkAddressBalances(tx *dbutil.Tx, f func(AddressBalance) (bool, error)) error {
+	return nil
+}
+
+func (fup *fakeUnspentPool) VerifyAddressBalances(tx *dbutil.Tx) error {
+	return nil
+}
+
+func (fup *fakeUnspentPool) RebuildAddressBalances(tx *dbutil.Tx) error {
+	return nil
+}
+
 type fakeChainMeta struct {
 	headSeq   uint64
 	didSetSeq bool
@@ -512,6 +532,114 @@ func TestBlockchainLen(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestBlockchainSideBlocks(t *testing.T) {
+	db, closeDB := prepareDB(t)
+	defer closeDB()
+
+	bc, err := NewBlockchain(db, DefaultWalker)
+	require.NoError(t, err)
+
+	gb := makeGenesisBlock(t)
+	genUx := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])[0]
+
+	// makeBlock creates a block that spends the genesis output to addr
+	makeBlock := func(tx *dbutil.Tx, addr cipher.Address, time uint64) coin.SignedBlock {
+		txn := coin.Transaction{}
+		err := txn.PushInput(genUx.Hash())
+		require.NoError(t, err)
+		err = txn.PushOutput(addr, genUx.Body.Coins, genUx.Body.Hours/2)
+		require.NoError(t, err)
+
+		uxHash, err := bc.UnspentPool().GetUxHash(tx)
+		require.NoError(t, err)
+
+		b, err := coin.NewBlock(gb.Block, time, uxHash, coin.Transactions{txn}, feeCalc)
+		require.NoError(t, err)
+
+		return coin.SignedBlock{
+			Block: *b,
+			Sig:   cipher.MustSignHash(b.HashHeader(), genSecret),
+		}
+	}
+
+	mainAddr := testutil.MakeAddress()
+	sideAddr := testutil.MakeAddress()
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		err := bc.AddBlock(tx, &gb)
+		require.NoError(t, err)
+
+		mb := makeBlock(tx, mainAddr, genTime+10)
+		sb := makeBlock(tx, sideAddr, genTime+20)
+
+		err = bc.AddBlock(tx, &mb)
+		require.NoError(t, err)
+
+		err = bc.AddSideBlock(tx, &sb)
+		require.NoError(t, err)
+
+		// the side block is stored but the main chain is unchanged
+		b, err := bc.GetSignedBlockByHash(tx, sb.HashHeader())
+		require.NoError(t, err)
+		require.Equal(t, sb, *b)
+
+		head, err := bc.Head(tx)
+		require.NoError(t, err)
+		require.Equal(t, mb, *head)
+
+		uxs, err := bc.UnspentPool().GetUnspentsOfAddrs(tx, []cipher.Address{mainAddr, sideAddr})
+		require.NoError(t, err)
+		require.Len(t, uxs[mainAddr], 1)
+		require.Empty(t, uxs[sideAddr])
+
+		// roll back the main block
+		err = bc.RollbackHead(tx, coin.UxArray{genUx})
+		require.NoError(t, err)
+
+		head, err = bc.Head(tx)
+		require.NoError(t, err)
+		require.Equal(t, gb, *head)
+
+		b, err = bc.GetSignedBlockBySeq(tx, 1)
+		require.NoError(t, err)
+		require.Nil(t, b)
+
+		ok, err := bc.UnspentPool().Contains(tx, genUx.Hash())
+		require.NoError(t, err)
+		require.True(t, ok)
+
+		uxs, err = bc.UnspentPool().GetUnspentsOfAddrs(tx, []cipher.Address{mainAddr, sideAddr})
+		require.NoError(t, err)
+		require.Empty(t, uxs[mainAddr])
+		require.Empty(t, uxs[sideAddr])
+
+		// apply the side block
+		err = bc.ApplySideBlock(tx, &sb)
+		require.NoError(t, err)
+
+		head, err = bc.Head(tx)
+		require.NoError(t, err)
+		require.Equal(t, sb, *head)
+
+		b, err = bc.GetSignedBlockBySeq(tx, 1)
+		require.NoError(t, err)
+		require.Equal(t, sb, *b)
+
+		uxs, err = bc.UnspentPool().GetUnspentsOfAddrs(tx, []cipher.Address{mainAddr, sideAddr})
+		require.NoError(t, err)
+		require.Empty(t, uxs[mainAddr])
+		require.Len(t, uxs[sideAddr], 1)
+
+		// the rolled back block is kept as a side block
+		b, err = bc.GetSignedBlockByHash(tx, mb.HashHeader())
+		require.NoError(t, err)
+		require.Equal(t, mb, *b)
+
+		return nil
+	})
+	require.NoError(t, err)
+}
+
 func TestBlockchainGetBlockByHash(t *testing.T) {
 	gb := makeGenesisBlock(t)
 
@@ -0,0 +1,79 @@
+package blockdb
+
+import (
+	"encoding/binary"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+var (
+	// KeyCheckpointsBkt holds the block publisher key checkpoints, by seq
+	KeyCheckpointsBkt = []byte("key_checkpoints")
+)
+
+// KeyCheckpoint hands the block signing authority to Pubkey from the block with seq Seq onward.
+// It is signed by the block publisher key in effect before Seq.
+type KeyCheckpoint struct {
+	Seq    uint64
+	Pubkey cipher.PubKey
+	Sig    cipher.Sig
+}
+
+// NewKeyCheckpoint creates a KeyCheckpoint signed with seckey
+func NewKeyCheckpoint(seq uint64, pubkey cipher.PubKey, seckey cipher.SecKey) KeyCheckpoint {
+	kc := KeyCheckpoint{
+		Seq:    seq,
+		Pubkey: pubkey,
+	}
+	kc.Sig = cipher.MustSignHash(kc.Hash(), seckey)
+	return kc
+}
+
+// Hash returns the hash of the seq and pubkey, which is signed by the checkpoint signature
+func (kc KeyCheckpoint) Hash() cipher.SHA256 {
+	b := make([]byte, 8+len(kc.Pubkey))
+	binary.LittleEndian.PutUint64(b[:8], kc.Seq)
+	copy(b[8:], kc.Pubkey[:])
+	return cipher.SumSHA256(b)
+}
+
+// Verify checks that the checkpoint is signed by pubkey
+func (kc KeyCheckpoint) Verify(pubkey cipher.PubKey) error {
+	return cipher.VerifyPubKeySignedHash(pubkey, kc.Sig, kc.Hash())
+}
+
+// keyCheckpoints manages the block publisher key checkpoints
+type keyCheckpoints struct{}
+
+// Add adds a key checkpoint to the db, replacing the checkpoint with the same seq
+func (kcs *keyCheckpoints) Add(tx *dbutil.Tx, kc KeyCheckpoint) error {
+	buf, err := encodeKeyCheckpoint(&kc)
+	if err != nil {
+		return err
+	}
+	return dbutil.PutBucketValue(tx, KeyCheckpointsBkt, dbutil.Itob(kc.Seq), buf)
+}
+
+// GetAll returns all key checkpoints, ordered by seq
+func (kcs *keyCheckpoints) GetAll(tx *dbutil.Tx) ([]KeyCheckpoint, error) {
+	// The bucket is missing from a read-only db that was created by an older version
+	if !dbutil.Exists(tx, KeyCheckpointsBkt) {
+		return nil, nil
+	}
+
+	var checkpoints []KeyCheckpoint
+	if err := dbutil.ForEach(tx, KeyCheckpointsBkt, func(_, v []byte) error {
+		var kc KeyCheckpoint
+		if err := decodeKeyCheckpointExact(v, &kc); err != nil {
+			return err
+		}
+
+		checkpoints = append(checkpoints, kc)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return checkpoints, nil
+}
@@ -0,0 +1,94 @@
+package blockdb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/encoder"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestKeyCheckpointVerify(t *testing.T) {
+	pubkey, seckey := cipher.GenerateKeyPair()
+	newPubkey, _ := cipher.GenerateKeyPair()
+
+	kc := NewKeyCheckpoint(10, newPubkey, seckey)
+	require.Equal(t, uint64(10), kc.Seq)
+	require.Equal(t, newPubkey, kc.Pubkey)
+	require.NoError(t, kc.Verify(pubkey))
+	require.Error(t, kc.Verify(newPubkey))
+
+	// The signature covers the seq and the pubkey
+	kc2 := kc
+	kc2.Seq = 11
+	require.NotEqual(t, kc.Hash(), kc2.Hash())
+	require.Error(t, kc2.Verify(pubkey))
+
+	kc3 := kc
+	kc3.Pubkey = pubkey
+	require.NotEqual(t, kc.Hash(), kc3.Hash())
+	require.Error(t, kc3.Verify(pubkey))
+}
+
+func TestKeyCheckpoints(t *testing.T) {
+	db, closeDB := prepareDB(t)
+	defer closeDB()
+
+	_, seckey := cipher.GenerateKeyPair()
+	pubkey1, _ := cipher.GenerateKeyPair()
+	pubkey2, _ := cipher.GenerateKeyPair()
+	pubkey3, _ := cipher.GenerateKeyPair()
+
+	kc1 := NewKeyCheckpoint(300, pubkey1, seckey)
+	kc2 := NewKeyCheckpoint(5, pubkey2, seckey)
+	kc3 := NewKeyCheckpoint(300, pubkey3, seckey)
+
+	kcs := &keyCheckpoints{}
+
+	err := db.View("", func(tx *dbutil.Tx) error {
+		checkpoints, err := kcs.GetAll(tx)
+		require.NoError(t, err)
+		require.Empty(t, checkpoints)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, kcs.Add(tx, kc1))
+		require.NoError(t, kcs.Add(tx, kc2))
+		return nil
+	})
+	require.NoError(t, err)
+
+	// The checkpoints are ordered by seq and encoded like the encoder does
+	err = db.View("", func(tx *dbutil.Tx) error {
+		checkpoints, err := kcs.GetAll(tx)
+		require.NoError(t, err)
+		require.Equal(t, []KeyCheckpoint{kc2, kc1}, checkpoints)
+
+		v := tx.Bucket(KeyCheckpointsBkt).Get(dbutil.Itob(300))
+		require.NotNil(t, v)
+		var kc KeyCheckpoint
+		require.NoError(t, encoder.DeserializeRawExact(v, &kc))
+		require.Equal(t, kc1, kc)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// A checkpoint with the same seq is replaced
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return kcs.Add(tx, kc3)
+	})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		checkpoints, err := kcs.GetAll(tx)
+		require.NoError(t, err)
+		require.Equal(t, []KeyCheckpoint{kc2, kc3}, checkpoints)
+
+		return VerifyDBSkyencoderSafe(tx, nil)
+	})
+	require.NoError(t, err)
+}
@@ -254,6 +254,7 @@ func (p poolAddrIndex) adjust(tx *dbutil.Tx, addr cipher.Address, addHashes, rmH
 type Unspents struct {
 	pool          *pool
 	poolAddrIndex *poolAddrIndex
+	addrBalances  *addrBalanceIndex
 	meta          *unspentMeta
 }
 
@@ -262,6 +263,7 @@ func NewUnspentPool() *Unspents {
 	return &Unspents{
 		pool:          &pool{},
 		poolAddrIndex: &poolAddrIndex{},
+		addrBalances:  &addrBalanceIndex{},
 		meta:          &unspentMeta{},
 	}
 }
@@ -278,7 +280,7 @@ func (up *Unspents) MaybeBuildIndexes(tx *dbutil.Tx, headSeq uint64) error {
 	}
 
 	if ok && addrIndexHeight == headSeq {
-		return nil
+		return up.maybeBuildAddrBalances(tx)
 	}
 
 	if addrIndexHeight > headSeq {
@@ -290,6 +292,19 @@ func (up *Unspents) MaybeBuildIndexes(tx *dbutil.Tx, headSeq uint64) error {
 	return up.buildAddrIndex(tx)
 }
 
+// maybeBuildAddrBalances builds the address balance index of a database created before the index existed
+func (up *Unspents) maybeBuildAddrBalances(tx *dbutil.Tx) error {
+	if _, ok, err := up.addrBalances.count(tx); err != nil {
+		return err
+	} else if ok {
+		return nil
+	}
+
+	logger.Info("Building unspent address balance index")
+
+	return up.addrBalances.build(tx)
+}
+
 func (up *Unspents) buildAddrIndex(tx *dbutil.Tx) error {
 	logger.Info("Building unspent address index")
 
@@ -323,6 +338,10 @@ func (up *Unspents) buildAddrIndex(tx *dbutil.Tx) error {
 		return err
 	}
 
+	if err := up.addrBalances.build(tx); err != nil {
+		return err
+	}
+
 	if len(addrHashes) == 0 {
 		logger.Infof("No unspents to index")
 		return nil
@@ -427,6 +446,10 @@ func (up *Unspents) ProcessBlock(tx *dbutil.Tx, b *coin.SignedBlock) error {
 		}
 	}
 
+	if err := up.addrBalances.adjustUxOuts(tx, txnUxs, uxs); err != nil {
+		return err
+	}
+
 	// Check that the addrIndexHeight is incremental
 	addrIndexHeight, ok, err := up.meta.getAddrIndexHeight(tx)
 	if err != nil {
@@ -449,6 +472,120 @@ func (up *Unspents) ProcessBlock(tx *dbutil.Tx, b *coin.SignedBlock) error {
 	return up.meta.setAddrIndexHeight(tx, b.Block.Head.BkSeq)
 }
 
+// RollbackBlock reverts ProcessBlock for the head block of the unspent pool.
+// The outputs created by the block are removed and the outputs spent by the block are restored.
+// spent must contain the outputs spent by the block, which are no longer in the pool.
+func (up *Unspents) RollbackBlock(tx *dbutil.Tx, b *coin.SignedBlock, spent coin.UxArray) error {
+	if b.Block.Head.BkSeq == 0 {
+		return errors.New("cannot roll back the genesis block")
+	}
+
+	// Check that the block is the last block processed by the pool
+	addrIndexHeight, ok, err := up.meta.getAddrIndexHeight(tx)
+	if err != nil {
+		return err
+	}
+
+	if !ok || addrIndexHeight != b.Block.Head.BkSeq {
+		return errors.New("unspent pool rolling back blocks out of order")
+	}
+
+	var inputs []cipher.SHA256
+	var txnUxs coin.UxArray
+	for _, txn := range b.Body.Transactions {
+		inputs = append(inputs, txn.In...)
+		txnUxs = append(txnUxs, coin.CreateUnspents(b.Head, txn)...)
+	}
+
+	if len(inputs) != len(spent) {
+		return fmt.Errorf("block spends %d outputs but %d spent outputs were provided", len(inputs), len(spent))
+	}
+
+	spentMap := make(map[cipher.SHA256]coin.UxOut, len(spent))
+	for _, ux := range spent {
+		spentMap[ux.Hash()] = ux
+	}
+
+	spentUxs := make(coin.UxArray, len(inputs))
+	for i, h := range inputs {
+		ux, ok := spentMap[h]
+		if !ok {
+			return fmt.Errorf("spent output %s of the block was not provided", h.Hex())
+		}
+		spentUxs[i] = ux
+	}
+
+	xorHash, err := up.meta.getXorHash(tx)
+	if err != nil {
+		return err
+	}
+
+	// Remove the outputs created by the block
+	rmAddrHashes := make(map[cipher.Address][]cipher.SHA256)
+	for _, ux := range txnUxs {
+		h := ux.Hash()
+
+		if hasKey, err := up.Contains(tx, h); err != nil {
+			return err
+		} else if !hasKey {
+			return NewErrUnspentNotExist(h.Hex())
+		}
+
+		if err := up.pool.delete(tx, h); err != nil {
+			return err
+		}
+
+		xorHash = xorHash.Xor(ux.SnapshotHash())
+		rmAddrHashes[ux.Body.Address] = append(rmAddrHashes[ux.Body.Address], h)
+	}
+
+	// Restore the outputs spent by the block
+	addAddrHashes := make(map[cipher.Address][]cipher.SHA256)
+	for _, ux := range spentUxs {
+		h := ux.Hash()
+
+		if hasKey, err := up.Contains(tx, h); err != nil {
+			return err
+		} else if hasKey {
+			return fmt.Errorf("attempted to insert uxout:%v twice into the unspent pool", h.Hex())
+		}
+
+		if err := up.pool.put(tx, h, ux); err != nil {
+			return err
+		}
+
+		xorHash = xorHash.Xor(ux.SnapshotHash())
+		addAddrHashes[ux.Body.Address] = append(addAddrHashes[ux.Body.Address], h)
+	}
+
+	if err := up.meta.setXorHash(tx, xorHash); err != nil {
+		return err
+	}
+
+	// Update indexes
+	for addr, rmHashes := range rmAddrHashes {
+		addHashes := addAddrHashes[addr]
+
+		if err := up.poolAddrIndex.adjust(tx, addr, addHashes, rmHashes); err != nil {
+			return err
+		}
+
+		delete(addAddrHashes, addr)
+	}
+
+	for addr, addHashes := range addAddrHashes {
+		if err := up.poolAddrIndex.adjust(tx, addr, addHashes, nil); err != nil {
+			return err
+		}
+	}
+
+	if err := up.addrBalances.adjustUxOuts(tx, spentUxs, txnUxs); err != nil {
+		return err
+	}
+
+	return up.meta.setAddrIndexHeight(tx, b.Block.Head.BkSeq-1)
+}
+
 // GetArray returns UxOut for a set of hashes, will return error if any of the hashes do not exist in the pool.
 func (up *Unspents) GetArray(tx *dbutil.Tx, hashes []cipher.SHA256) (coin.UxArray, error) {
 	var uxa coin.UxArray
@@ -537,5 +674,29 @@ func (up *Unspents) GetUxHash(tx *dbutil.Tx) (cipher.SHA256, error) {
 
 // AddressCount returns the total number of addresses with unspents
 func (up *Unspents) AddressCount(tx *dbutil.Tx) (uint64, error) {
-	return dbutil.Len(tx, UnspentPoolAddrIndexBkt)
+	n, ok, err := up.addrBalances.count(tx)
+	if err != nil {
+		return 0, err
+	} else if !ok {
+		return dbutil.Len(tx, UnspentPoolAddrIndexBkt)
+	}
+
+	return n, nil
+}
+
+// WalkAddressBalances calls f with the balances of the addresses with unspents,
+// in decreasing order of coins then increasing order of address bytes, until f returns false
+func (up *Unspents) WalkAddressBalances(tx *dbutil.Tx, f func(AddressBalance) (bool, error)) error {
+	return up.addrBalances.walk(tx, f)
+}
+
+// VerifyAddressBalances checks that the address balance index matches the unspent pool.
+// Returns ErrUnspentBalanceIndexCorrupted if it doesn't.
+func (up *Unspents) VerifyAddressBalances(tx *dbutil.Tx) error {
+	return up.addrBalances.verify(tx)
+}
+
+// RebuildAddressBalances rebuilds the address balance index from the unspent pool
+func (up *Unspents) RebuildAddressBalances(tx *dbutil.Tx) error {
+	return up.addrBalances.build(tx)
 }
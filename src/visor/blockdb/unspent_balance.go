@@ -0,0 +1,335 @@
+package blockdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/util/mathutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+var (
+	addrBalanceCountKey = []byte("addr_balance_count")
+
+	// UnspentAddrBalanceBkt maps addresses to the sum of the coins of their unspent outputs
+	UnspentAddrBalanceBkt = []byte("unspent_addr_balance")
+	// UnspentRichlistBkt indexes addresses by the sum of the coins of their unspent outputs, in decreasing order
+	UnspentRichlistBkt = []byte("unspent_richlist")
+)
+
+// ErrUnspentBalanceIndexCorrupted is returned when the address balance index doesn't match the unspent pool
+type ErrUnspentBalanceIndexCorrupted struct {
+	error
+}
+
+// NewErrUnspentBalanceIndexCorrupted creates ErrUnspentBalanceIndexCorrupted
+func NewErrUnspentBalanceIndexCorrupted(err error) ErrUnspentBalanceIndexCorrupted {
+	return ErrUnspentBalanceIndexCorrupted{err}
+}
+
+// AddressBalance is the sum of the coins of the unspent outputs of an address
+type AddressBalance struct {
+	Address cipher.Address
+	Coins   uint64
+}
+
+// richlistKey returns the key of an address in the richlist bucket.
+// Keys sort by decreasing coins, then by address bytes.
+func richlistKey(addr cipher.Address, coins uint64) []byte {
+	b := addr.Bytes()
+	k := make([]byte, 8+len(b))
+	binary.BigEndian.PutUint64(k, math.MaxUint64-coins)
+	copy(k[8:], b)
+	return k
+}
+
+// addrBalanceIndex maintains the address balance and richlist buckets,
+// and the count of addresses with unspent outputs
+type addrBalanceIndex struct{}
+
+func (ab addrBalanceIndex) get(tx *dbutil.Tx, addr cipher.Address) (uint64, error) {
+	v, err := dbutil.GetBucketValueNoCopy(tx, UnspentAddrBalanceBkt, addr.Bytes())
+	if err != nil {
+		return 0, err
+	} else if v == nil {
+		return 0, nil
+	}
+
+	return dbutil.Btoi(v), nil
+}
+
+// count returns the number of addresses with unspent outputs, false if it was never set
+func (ab addrBalanceIndex) count(tx *dbutil.Tx) (uint64, bool, error) {
+	v, err := dbutil.GetBucketValue(tx, UnspentMetaBkt, addrBalanceCountKey)
+	if err != nil {
+		return 0, false, err
+	} else if v == nil {
+		return 0, false, nil
+	}
+
+	return dbutil.Btoi(v), true, nil
+}
+
+func (ab addrBalanceIndex) setCount(tx *dbutil.Tx, n uint64) error {
+	return dbutil.PutBucketValue(tx, UnspentMetaBkt, addrBalanceCountKey, dbutil.Itob(n))
+}
+
+// adjust adds and subtracts coins from the balance of an address.
+// Addresses without coins are removed, so that the count only includes addresses with unspent outputs.
+func (ab addrBalanceIndex) adjust(tx *dbutil.Tx, addr cipher.Address, add, sub uint64) error {
+	if add == sub {
+		return nil
+	}
+
+	coins, err := ab.get(tx, addr)
+	if err != nil {
+		return err
+	}
+
+	newCoins, err := mathutil.AddUint64(coins, add)
+	if err != nil {
+		return err
+	}
+
+	if sub > newCoins {
+		return fmt.Errorf("addrBalanceIndex.adjust: address %s balance is less than the coins subtracted", addr.String())
+	}
+	newCoins -= sub
+
+	count, _, err := ab.count(tx)
+	if err != nil {
+		return err
+	}
+
+	if coins != 0 {
+		if err := dbutil.Delete(tx, UnspentRichlistBkt, richlistKey(addr, coins)); err != nil {
+			return err
+		}
+	}
+
+	if newCoins == 0 {
+		if err := dbutil.Delete(tx, UnspentAddrBalanceBkt, addr.Bytes()); err != nil {
+			return err
+		}
+
+		return ab.setCount(tx, count-1)
+	}
+
+	if err := dbutil.PutBucketValue(tx, UnspentRichlistBkt, richlistKey(addr, newCoins), nil); err != nil {
+		return err
+	}
+
+	if err := dbutil.PutBucketValue(tx, UnspentAddrBalanceBkt, addr.Bytes(), dbutil.Itob(newCoins)); err != nil {
+		return err
+	}
+
+	if coins == 0 {
+		return ab.setCount(tx, count+1)
+	}
+
+	return nil
+}
+
+// adjustUxOuts adds the coins of the added outputs to the balances of their addresses,
+// and subtracts the coins of the removed outputs
+func (ab addrBalanceIndex) adjustUxOuts(tx *dbutil.Tx, added, removed coin.UxArray) error {
+	var addrs []cipher.Address
+	changes := make(map[cipher.Address]*[2]uint64)
+	change := func(addr cipher.Address) *[2]uint64 {
+		c, ok := changes[addr]
+		if !ok {
+			c = &[2]uint64{}
+			changes[addr] = c
+			addrs = append(addrs, addr)
+		}
+		return c
+	}
+
+	for _, ux := range added {
+		c := change(ux.Body.Address)
+		coins, err := mathutil.AddUint64(c[0], ux.Body.Coins)
+		if err != nil {
+			return err
+		}
+		c[0] = coins
+	}
+
+	for _, ux := range removed {
+		c := change(ux.Body.Address)
+		coins, err := mathutil.AddUint64(c[1], ux.Body.Coins)
+		if err != nil {
+			return err
+		}
+		c[1] = coins
+	}
+
+	for _, addr := range addrs {
+		c := changes[addr]
+		if err := ab.adjust(tx, addr, c[0], c[1]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// build rebuilds the index from the unspent pool
+func (ab addrBalanceIndex) build(tx *dbutil.Tx) error {
+	// The buckets don't exist in databases created before the index
+	if err := dbutil.CreateBuckets(tx, [][]byte{UnspentAddrBalanceBkt, UnspentRichlistBkt}); err != nil {
+		return err
+	}
+
+	if err := dbutil.Reset(tx, UnspentAddrBalanceBkt); err != nil {
+		return err
+	}
+
+	if err := dbutil.Reset(tx, UnspentRichlistBkt); err != nil {
+		return err
+	}
+
+	balances, err := unspentPoolBalances(tx)
+	if err != nil {
+		return err
+	}
+
+	for addr, coins := range balances {
+		if err := dbutil.PutBucketValue(tx, UnspentRichlistBkt, richlistKey(addr, coins), nil); err != nil {
+			return err
+		}
+
+		if err := dbutil.PutBucketValue(tx, UnspentAddrBalanceBkt, addr.Bytes(), dbutil.Itob(coins)); err != nil {
+			return err
+		}
+	}
+
+	logger.Infof("Indexed balances of %d addresses", len(balances))
+
+	return ab.setCount(tx, uint64(len(balances)))
+}
+
+// walk calls f with the balances in decreasing order of coins, until f returns false
+func (ab addrBalanceIndex) walk(tx *dbutil.Tx, f func(AddressBalance) (bool, error)) error {
+	bkt := tx.Bucket(UnspentRichlistBkt)
+	if bkt == nil {
+		return dbutil.NewErrBucketNotExist(UnspentRichlistBkt)
+	}
+
+	cur := bkt.Cursor()
+	for k, _ := cur.First(); k != nil; k, _ = cur.Next() {
+		if len(k) <= 8 {
+			return errors.New("invalid richlist key")
+		}
+
+		addr, err := cipher.AddressFromBytes(k[8:])
+		if err != nil {
+			return err
+		}
+
+		more, err := f(AddressBalance{
+			Address: addr,
+			Coins:   math.MaxUint64 - binary.BigEndian.Uint64(k),
+		})
+		if err != nil {
+			return err
+		} else if !more {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// verify checks that the index matches the unspent pool.
+// An index that was never built is not verified, it is built by MaybeBuildIndexes.
+func (ab addrBalanceIndex) verify(tx *dbutil.Tx) error {
+	count, ok, err := ab.count(tx)
+	if err != nil {
+		return err
+	} else if !ok {
+		return nil
+	}
+
+	balances, err := unspentPoolBalances(tx)
+	if err != nil {
+		return err
+	}
+
+	if count != uint64(len(balances)) {
+		return NewErrUnspentBalanceIndexCorrupted(fmt.Errorf("address count is %d, the unspent pool has %d addresses", count, len(balances)))
+	}
+
+	var n int
+	if err := dbutil.ForEach(tx, UnspentAddrBalanceBkt, func(k, v []byte) error {
+		n++
+
+		addr, err := cipher.AddressFromBytes(k)
+		if err != nil {
+			return err
+		}
+
+		if coins, ok := balances[addr]; !ok || coins != dbutil.Btoi(v) {
+			return NewErrUnspentBalanceIndexCorrupted(fmt.Errorf("balance of address %s doesn't match the unspent pool", addr.String()))
+		}
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	if n != len(balances) {
+		return NewErrUnspentBalanceIndexCorrupted(fmt.Errorf("%d address balances are indexed, the unspent pool has %d addresses", n, len(balances)))
+	}
+
+	n = 0
+	if err := ab.walk(tx, func(b AddressBalance) (bool, error) {
+		n++
+
+		if coins, ok := balances[b.Address]; !ok || coins != b.Coins {
+			return false, NewErrUnspentBalanceIndexCorrupted(fmt.Errorf("richlist balance of address %s doesn't match the unspent pool", b.Address.String()))
+		}
+
+		return true, nil
+	}); err != nil {
+		return err
+	}
+
+	if n != len(balances) {
+		return NewErrUnspentBalanceIndexCorrupted(fmt.Errorf("%d richlist balances are indexed, the unspent pool has %d addresses", n, len(balances)))
+	}
+
+	return nil
+}
+
+// unspentPoolBalances sums the coins of the unspent outputs in the pool by address
+func unspentPoolBalances(tx *dbutil.Tx) (map[cipher.Address]uint64, error) {
+	balances := make(map[cipher.Address]uint64)
+	if err := dbutil.ForEach(tx, UnspentPoolBkt, func(k, v []byte) error {
+		var ux coin.UxOut
+		if err := decodeUxOutExact(v, &ux); err != nil {
+			return err
+		}
+
+		h := ux.Hash()
+		if !bytes.Equal(k, h[:]) {
+			return errors.New("Unspent pool uxout.Hash() does not match its key")
+		}
+
+		coins, err := mathutil.AddUint64(balances[ux.Body.Address], ux.Body.Coins)
+		if err != nil {
+			return err
+		}
+		balances[ux.Body.Address] = coins
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return balances, nil
+}
@@ -0,0 +1,207 @@
+package blockdb
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestUnspentAddressBalances(t *testing.T) {
+	db, closedb := prepareDB(t)
+	defer closedb()
+
+	up := NewUnspentPool()
+
+	addrA := testutil.MakeAddress()
+	addrB := testutil.MakeAddress()
+	addrC := testutil.MakeAddress()
+	addrD := testutil.MakeAddress()
+
+	makeAddrUxOut := func(addr cipher.Address, coins uint64) coin.UxOut {
+		ux := makeUxOut(t)
+		ux.Body.Address = addr
+		ux.Body.Coins = coins
+		return ux
+	}
+
+	uxs := coin.UxArray{
+		makeAddrUxOut(addrA, 3e6),
+		makeAddrUxOut(addrA, 1e6),
+		makeAddrUxOut(addrB, 2e6),
+		makeAddrUxOut(addrC, 2e6),
+	}
+
+	for _, ux := range uxs {
+		err := addUxOut(db, up, ux)
+		require.NoError(t, err)
+	}
+
+	// addrA spends one of its outputs and addrB spends all of its coins
+	txn := coin.Transaction{}
+	for _, in := range uxs[1:3] {
+		err := txn.PushInput(in.Hash())
+		require.NoError(t, err)
+	}
+
+	err := txn.PushOutput(addrD, 2e6, 10)
+	require.NoError(t, err)
+	err = txn.PushOutput(addrA, 1e6, 10)
+	require.NoError(t, err)
+
+	// sortedBalances sorts balances with equal coins by address bytes
+	sortedBalances := func(balances ...AddressBalance) []AddressBalance {
+		sort.SliceStable(balances, func(i, j int) bool {
+			if balances[i].Coins == balances[j].Coins {
+				return bytes.Compare(balances[i].Address.Bytes(), balances[j].Address.Bytes()) < 0
+			}
+			return balances[i].Coins > balances[j].Coins
+		})
+		return balances
+	}
+
+	checkBalances := func(expected []AddressBalance) {
+		err := db.View("", func(tx *dbutil.Tx) error {
+			var balances []AddressBalance
+			err := up.WalkAddressBalances(tx, func(b AddressBalance) (bool, error) {
+				balances = append(balances, b)
+				return true, nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, expected, balances)
+
+			n, err := up.AddressCount(tx)
+			require.NoError(t, err)
+			require.Equal(t, uint64(len(expected)), n)
+
+			return up.VerifyAddressBalances(tx)
+		})
+		require.NoError(t, err)
+	}
+
+	before := sortedBalances(
+		AddressBalance{Address: addrA, Coins: 4e6},
+		AddressBalance{Address: addrB, Coins: 2e6},
+		AddressBalance{Address: addrC, Coins: 2e6},
+	)
+	checkBalances(before)
+
+	var sb *coin.SignedBlock
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		uxHash, err := up.GetUxHash(tx)
+		require.NoError(t, err)
+
+		block, err := coin.NewBlock(coin.Block{}, uint64(time.Now().Unix()), uxHash, coin.Transactions{txn}, feeCalc)
+		require.NoError(t, err)
+		sb = &coin.SignedBlock{
+			Block: *block,
+		}
+
+		return up.ProcessBlock(tx, sb)
+	})
+	require.NoError(t, err)
+
+	checkBalances(sortedBalances(
+		AddressBalance{Address: addrA, Coins: 4e6},
+		AddressBalance{Address: addrC, Coins: 2e6},
+		AddressBalance{Address: addrD, Coins: 2e6},
+	))
+
+	// The walk stops when f returns false
+	err = db.View("", func(tx *dbutil.Tx) error {
+		var balances []AddressBalance
+		err := up.WalkAddressBalances(tx, func(b AddressBalance) (bool, error) {
+			balances = append(balances, b)
+			return false, nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, []AddressBalance{{Address: addrA, Coins: 4e6}}, balances)
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return up.RollbackBlock(tx, sb, uxs[1:3])
+	})
+	require.NoError(t, err)
+
+	checkBalances(before)
+
+	// A corrupted index is detected and rebuilt
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return up.addrBalances.adjust(tx, addrC, 1, 0)
+	})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		return up.VerifyAddressBalances(tx)
+	})
+	require.Error(t, err)
+	require.IsType(t, ErrUnspentBalanceIndexCorrupted{}, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return up.RebuildAddressBalances(tx)
+	})
+	require.NoError(t, err)
+
+	checkBalances(before)
+}
+
+func TestUnspentMaybeBuildIndexesAddressBalances(t *testing.T) {
+	// Test with a database created before the address balance index
+	db, shutdown := setupNoUnspentAddrIndexDB(t)
+	defer shutdown()
+
+	u := NewUnspentPool()
+
+	// An index that was never built is not verified
+	err := db.View("", func(tx *dbutil.Tx) error {
+		return u.VerifyAddressBalances(tx)
+	})
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(UnspentPoolAddrIndexBkt); err != nil {
+			return err
+		}
+
+		return u.MaybeBuildIndexes(tx, 180)
+	})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		if err := u.VerifyAddressBalances(tx); err != nil {
+			return err
+		}
+
+		n, err := u.AddressCount(tx)
+		require.NoError(t, err)
+
+		length, err := dbutil.Len(tx, UnspentPoolAddrIndexBkt)
+		require.NoError(t, err)
+		require.Equal(t, length, n)
+
+		var prev *AddressBalance
+		var walked uint64
+		err = u.WalkAddressBalances(tx, func(b AddressBalance) (bool, error) {
+			walked++
+			if prev != nil {
+				require.True(t, prev.Coins >= b.Coins)
+			}
+			prev = &b
+			return true, nil
+		})
+		require.NoError(t, err)
+		require.Equal(t, n, walked)
+
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -68,7 +68,11 @@ func addUxOut(db *dbutil.DB, up *Unspents, ux coin.UxOut) error {
 			return err
 		}
 
-		return up.poolAddrIndex.adjust(tx, ux.Body.Address, []cipher.SHA256{ux.Hash()}, nil)
+		if err := up.poolAddrIndex.adjust(tx, ux.Body.Address, []cipher.SHA256{ux.Hash()}, nil); err != nil {
+			return err
+		}
+
+		return up.addrBalances.adjust(tx, ux.Body.Address, ux.Body.Coins, 0)
 	})
 }
 
@@ -635,6 +639,134 @@ func TestUnspentProcessBlock(t *testing.T) {
 	}
 }
 
+func TestUnspentRollbackBlock(t *testing.T) {
+	var uxs coin.UxArray
+	for i := 0; i < 5; i++ {
+		ux := makeUxOut(t)
+		uxs = append(uxs, ux)
+	}
+
+	db, closedb := prepareDB(t)
+	defer closedb()
+
+	up := NewUnspentPool()
+
+	for _, ux := range uxs {
+		err := addUxOut(db, up, ux)
+		require.NoError(t, err)
+	}
+
+	// spend two outputs, one of them back to the spending address
+	txn := coin.Transaction{}
+	for _, in := range uxs[:2] {
+		err := txn.PushInput(in.Hash())
+		require.NoError(t, err)
+	}
+
+	err := txn.PushOutput(testutil.MakeAddress(), 1e6, 10)
+	require.NoError(t, err)
+	err = txn.PushOutput(uxs[0].Body.Address, 1e6, 10)
+	require.NoError(t, err)
+
+	type poolState struct {
+		uxHash    cipher.SHA256
+		unspents  coin.UxArray
+		addrIndex map[cipher.Address][]cipher.SHA256
+	}
+
+	getPoolState := func(tx *dbutil.Tx) poolState {
+		uxHash, err := up.GetUxHash(tx)
+		require.NoError(t, err)
+
+		unspents, err := up.GetAll(tx)
+		require.NoError(t, err)
+		sort.Slice(unspents, func(i, j int) bool {
+			a := unspents[i].Hash()
+			b := unspents[j].Hash()
+			return bytes.Compare(a[:], b[:]) < 0
+		})
+
+		addrIndex := make(map[cipher.Address][]cipher.SHA256)
+		err = dbutil.ForEach(tx, UnspentPoolAddrIndexBkt, func(k, v []byte) error {
+			addr, err := cipher.AddressFromBytes(k)
+			require.NoError(t, err)
+
+			hashes, err := up.poolAddrIndex.get(tx, addr)
+			require.NoError(t, err)
+			sort.Slice(hashes, func(i, j int) bool {
+				return bytes.Compare(hashes[i][:], hashes[j][:]) < 0
+			})
+
+			addrIndex[addr] = hashes
+			return nil
+		})
+		require.NoError(t, err)
+
+		return poolState{
+			uxHash:    uxHash,
+			unspents:  unspents,
+			addrIndex: addrIndex,
+		}
+	}
+
+	var before poolState
+	var sb *coin.SignedBlock
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		// rolling back before the block was processed fails
+		uxHash, err := up.GetUxHash(tx)
+		require.NoError(t, err)
+
+		block, err := coin.NewBlock(coin.Block{}, uint64(time.Now().Unix()), uxHash, coin.Transactions{txn}, feeCalc)
+		require.NoError(t, err)
+		sb = &coin.SignedBlock{
+			Block: *block,
+		}
+
+		err = up.RollbackBlock(tx, sb, uxs[:2])
+		require.EqualError(t, err, "unspent pool rolling back blocks out of order")
+
+		before = getPoolState(tx)
+
+		return up.ProcessBlock(tx, sb)
+	})
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		// the spent outputs must be provided
+		err := up.RollbackBlock(tx, sb, uxs[:1])
+		require.EqualError(t, err, "block spends 2 outputs but 1 spent outputs were provided")
+
+		err = up.RollbackBlock(tx, sb, uxs[1:3])
+		require.EqualError(t, err, fmt.Sprintf("spent output %s of the block was not provided", uxs[0].Hash().Hex()))
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return up.RollbackBlock(tx, sb, uxs[:2])
+	})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		require.Equal(t, before, getPoolState(tx))
+
+		addrIndexHeight, ok, err := up.meta.getAddrIndexHeight(tx)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, uint64(0), addrIndexHeight)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	// the block can be processed again
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return up.ProcessBlock(tx, sb)
+	})
+	require.NoError(t, err)
+}
+
 func TestUnspentPoolAddrIndex(t *testing.T) {
 	addrs := make([]cipher.Address, 10)
 	for i := range addrs {
@@ -152,5 +152,69 @@ func VerifyDBSkyencoderSafe(tx *dbutil.Tx, quit <-chan struct{}) error {
 		return err
 	}
 
-	return nil
+	if err := verifyKeyCheckpointsSkyencoderSafe(tx, quit); err != nil {
+		return err
+	}
+
+	return verifyBlockCertificatesSkyencoderSafe(tx, quit)
+}
+
+func verifyKeyCheckpointsSkyencoderSafe(tx *dbutil.Tx, quit <-chan struct{}) error {
+	if !dbutil.Exists(tx, KeyCheckpointsBkt) {
+		return nil
+	}
+
+	return dbutil.ForEach(tx, KeyCheckpointsBkt, func(_, v []byte) error {
+		select {
+		case <-quit:
+			return ErrVerifyStopped
+		default:
+		}
+
+		var b1 KeyCheckpoint
+		if err := decodeKeyCheckpointExact(v, &b1); err != nil {
+			return err
+		}
+
+		var b2 KeyCheckpoint
+		if err := encoder.DeserializeRawExact(v, &b2); err != nil {
+			return err
+		}
+
+		if b1 != b2 {
+			return errors.New("KeyCheckpointsBkt key checkpoint mismatch")
+		}
+
+		return nil
+	})
+}
+
+func verifyBlockCertificatesSkyencoderSafe(tx *dbutil.Tx, quit <-chan struct{}) error {
+	if !dbutil.Exists(tx, BlockCertificatesBkt) {
+		return nil
+	}
+
+	return dbutil.ForEach(tx, BlockCertificatesBkt, func(_, v []byte) error {
+		select {
+		case <-quit:
+			return ErrVerifyStopped
+		default:
+		}
+
+		var b1 BlockCertificate
+		if err := decodeBlockCertificateExact(v, &b1); err != nil {
+			return err
+		}
+
+		var b2 BlockCertificate
+		if err := encoder.DeserializeRawExact(v, &b2); err != nil {
+			return err
+		}
+
+		if !reflect.DeepEqual(b1, b2) {
+			return errors.New("BlockCertificatesBkt block certificate mismatch")
+		}
+
+		return nil
+	})
 }
@@ -8,23 +8,45 @@ import (
 	"github.com/skycoin/skycoin/src/params"
 )
 
+// DefaultMaxReorgDepth is the default maximum number of main chain blocks rolled back by a reorg
+const DefaultMaxReorgDepth = 100
+
 // Config configuration parameters for the Visor
 type Config struct {
 	// Is this a block publishing node
 	IsBlockPublisher bool
 
-	// Public key of the blockchain
+	// Public key of the blockchain. Key checkpoints stored in the db hand the block signing authority
+	// to other pubkeys, without changing this pubkey
 	BlockchainPubkey cipher.PubKey
 
-	// Secret key of the blockchain (required if block publisher)
+	// Secret key of the blockchain (required if block publisher).
+	// It may belong to the pubkey of a key checkpoint instead of BlockchainPubkey
 	BlockchainSeckey cipher.SecKey
 
+	// Public keys of the block publishers that produce blocks together.
+	// Blocks signed by them are accepted besides blocks signed by BlockchainPubkey,
+	// once PublisherQuorum of them signed the block.
+	// A block publisher's BlockchainSeckey may belong to one of them.
+	PublisherPubkeys []cipher.PubKey
+	// Number of PublisherPubkeys that must sign a block. 0 means a majority
+	PublisherQuorum int
+
 	// Transaction verification parameters used for unconfirmed transactions
 	UnconfirmedVerifyTxn params.VerifyTxn
 	// Transaction verification parameters used when creating a block
 	CreateBlockVerifyTxn params.VerifyTxn
 	// Maximum size of a block, in bytes for creating blocks
 	MaxBlockTransactionsSize uint32
+	// Maximum number of unconfirmed transactions. 0 means no limit.
+	MaxUnconfirmedCount uint64
+	// Maximum total size of unconfirmed transactions, in bytes. 0 means no limit.
+	MaxUnconfirmedBytes uint64
+	// Replace unconfirmed transactions by transactions that spend the same inputs and burn more coin hours.
+	// Any unconfirmed transaction can be replaced, transactions do not signal whether they may be replaced.
+	EnableReplaceByFee bool
+	// Maximum number of main chain blocks rolled back to reorganize the chain to a side branch. 0 means no limit.
+	MaxReorgDepth uint64
 
 	// Coin distribution parameters (necessary for txn verification)
 	Distribution params.Distribution
@@ -44,6 +66,10 @@ type Config struct {
 	GenesisCoinVolume uint64
 	// enable arbitrating mode
 	Arbitrating bool
+
+	// Number of unused addresses kept after the last used address of each chain of the loaded bip44 wallets
+	// by the background address discovery. 0 disables the background address discovery
+	WalletGapLimit uint64
 }
 
 // NewConfig creates Config
@@ -57,11 +83,14 @@ func NewConfig() Config {
 		UnconfirmedVerifyTxn:     params.UserVerifyTxn,
 		CreateBlockVerifyTxn:     params.UserVerifyTxn,
 		MaxBlockTransactionsSize: params.UserVerifyTxn.MaxTransactionSize,
+		MaxReorgDepth:            DefaultMaxReorgDepth,
 
 		GenesisAddress:    cipher.Address{},
 		GenesisSignature:  cipher.Sig{},
 		GenesisTimestamp:  0,
 		GenesisCoinVolume: 0, //100e12, 100e6 * 10e6
+
+		WalletGapLimit: DefaultGapLimit,
 	}
 
 	return c
@@ -70,11 +99,15 @@ func NewConfig() Config {
 // Verify verifies the configuration
 func (c Config) Verify() error {
 	if c.IsBlockPublisher {
-		if c.BlockchainPubkey != cipher.MustPubKeyFromSecKey(c.BlockchainSeckey) {
-			return errors.New("Cannot run as block publisher: invalid seckey for pubkey")
+		if _, err := cipher.PubKeyFromSecKey(c.BlockchainSeckey); err != nil {
+			return errors.New("Cannot run as block publisher: invalid seckey")
 		}
 	}
 
+	if c.PublisherQuorum < 0 || c.PublisherQuorum > len(c.PublisherPubkeys) {
+		return errors.New("PublisherQuorum must be between 0 and the number of PublisherPubkeys")
+	}
+
 	if err := c.UnconfirmedVerifyTxn.Validate(); err != nil {
 		return err
 	}
@@ -111,6 +144,10 @@ func (c Config) Verify() error {
 		return errors.New("MaxBlockTransactionsSize must be >= CreateBlockVerifyTxn.MaxTransactionSize")
 	}
 
+	if c.MaxUnconfirmedBytes != 0 && c.MaxUnconfirmedBytes < uint64(c.UnconfirmedVerifyTxn.MaxTransactionSize) {
+		return errors.New("MaxUnconfirmedBytes must be >= UnconfirmedVerifyTxn.MaxTransactionSize")
+	}
+
 	if err := c.Distribution.Validate(); err != nil {
 		return err
 	}
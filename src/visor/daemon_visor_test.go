@@ -28,7 +28,7 @@ import (
 func setupSimpleVisor(t *testing.T, db *dbutil.DB, bc *Blockchain) *Visor {
 	cfg := NewConfig()
 
-	pool, err := NewUnconfirmedTransactionPool(db)
+	pool, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{})
 	require.NoError(t, err)
 
 	return &Visor{
@@ -14,13 +14,13 @@ import (
 
 	"github.com/boltdb/bolt"
 
+	"github.com/ness-network/ness/src/visor/blockdb"
+	"github.com/ness-network/ness/src/visor/historydb"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/cipher/encoder"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/util/elapse"
-	"github.com/skycoin/skycoin/src/visor/blockdb"
 	"github.com/skycoin/skycoin/src/visor/dbutil"
-	"github.com/skycoin/skycoin/src/visor/historydb"
 )
 
 var (
@@ -34,8 +34,9 @@ type ErrCorruptDB struct {
 	error
 }
 
-// CheckDatabase checks the database for corruption, rebuild history if corrupted
-func CheckDatabase(db *dbutil.DB, pubkey cipher.PubKey, quit chan struct{}) error {
+// CheckDatabase checks the database for corruption, rebuild history if corrupted.
+// Blocks must be signed by pubkey, or by publisherQuorum of the publisherPubkeys (0 means a majority).
+func CheckDatabase(db *dbutil.DB, pubkey cipher.PubKey, publisherPubkeys []cipher.PubKey, publisherQuorum int, quit chan struct{}) error {
 	elapser := elapse.NewElapser(time.Second*30, logger)
 	elapser.Register("CheckDatabase")
 	defer elapser.CheckForDone()
@@ -53,7 +54,11 @@ func CheckDatabase(db *dbutil.DB, pubkey cipher.PubKey, quit chan struct{}) erro
 		return nil
 	}
 
-	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
+	bc, err := NewBlockchain(db, BlockchainConfig{
+		Pubkey:           pubkey,
+		PublisherPubkeys: publisherPubkeys,
+		PublisherQuorum:  publisherQuorum,
+	})
 	if err != nil {
 		return err
 	}
@@ -65,7 +70,7 @@ func CheckDatabase(db *dbutil.DB, pubkey cipher.PubKey, quit chan struct{}) erro
 	var lock sync.Mutex
 	verifyFunc := func(tx *dbutil.Tx, b *coin.SignedBlock) error {
 		// Verify signature
-		if err := bc.VerifySignature(b); err != nil {
+		if err := bc.VerifySignature(tx, b); err != nil {
 			return err
 		}
 
@@ -86,10 +91,17 @@ func CheckDatabase(db *dbutil.DB, pubkey cipher.PubKey, quit chan struct{}) erro
 		lock.Lock()
 		err = historyVerifyErr
 		lock.Unlock()
-		return err
+		if err != nil {
+			return err
+		}
 	default:
 		return err
 	}
+
+	// Verify the address balance index against the unspent pool
+	return db.View("CheckDatabase", func(tx *dbutil.Tx) error {
+		return bc.Unspent().VerifyAddressBalances(tx)
+	})
 }
 
 // backup the corrypted db first, then rebuild the history DB.
@@ -169,8 +181,10 @@ func backupDB(db *dbutil.DB) (*dbutil.DB, error) { //nolint:unused,megacheck
 // - encoder.ErrMaxLenExceeded
 // If the database is deemed to be corrupted then it is erased and the db starts over.
 // A copy of the corrupted database is saved.
-func ResetCorruptDB(db *dbutil.DB, pubkey cipher.PubKey, quit chan struct{}) (*dbutil.DB, error) {
-	err := CheckDatabase(db, pubkey, quit)
+// If only the address balance index is corrupted (blockdb.ErrUnspentBalanceIndexCorrupted),
+// the index is rebuilt from the unspent pool instead.
+func ResetCorruptDB(db *dbutil.DB, pubkey cipher.PubKey, publisherPubkeys []cipher.PubKey, publisherQuorum int, quit chan struct{}) (*dbutil.DB, error) {
+	err := CheckDatabase(db, pubkey, publisherPubkeys, publisherQuorum, quit)
 
 	// Check if an encoder error has been reported.
 	// These are not types like the errors below so cannot be included in the
@@ -187,11 +201,34 @@ func ResetCorruptDB(db *dbutil.DB, pubkey cipher.PubKey, quit chan struct{}) (*d
 		historydb.ErrHistoryDBCorrupted:
 		logger.Critical().Errorf("Database is corrupted, recreating db: %v", err)
 		return resetCorruptDB(db)
+	case blockdb.ErrUnspentBalanceIndexCorrupted:
+		logger.Critical().Errorf("Address balance index is corrupted, rebuilding index: %v", err)
+		return rebuildAddressBalances(db, pubkey, publisherPubkeys, publisherQuorum)
 	default:
 		return nil, err
 	}
 }
 
+// rebuildAddressBalances rebuilds the address balance index of the unspent pool
+func rebuildAddressBalances(db *dbutil.DB, pubkey cipher.PubKey, publisherPubkeys []cipher.PubKey, publisherQuorum int) (*dbutil.DB, error) {
+	bc, err := NewBlockchain(db, BlockchainConfig{
+		Pubkey:           pubkey,
+		PublisherPubkeys: publisherPubkeys,
+		PublisherQuorum:  publisherQuorum,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := db.Update("rebuildAddressBalances", func(tx *dbutil.Tx) error {
+		return bc.Unspent().RebuildAddressBalances(tx)
+	}); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
 func rebuildCorruptDB(db *dbutil.DB, pubkey cipher.PubKey, quit chan struct{}) (*dbutil.DB, error) { //nolint:deadcode,unused,megacheck
 	history := historydb.New()
 	bc, err := NewBlockchain(db, BlockchainConfig{Pubkey: pubkey})
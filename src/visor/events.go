@@ -0,0 +1,313 @@
+package visor
+
+import (
+	"sync"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// EventType is the type of an Event
+type EventType string
+
+const (
+	// EventBlock is published when a block is added to the blockchain
+	EventBlock EventType = "block"
+	// EventUnconfirmedTxnAdded is published when a transaction enters the unconfirmed pool
+	EventUnconfirmedTxnAdded EventType = "unconfirmed_txn_added"
+	// EventUnconfirmedTxnRemoved is published when a transaction leaves the unconfirmed pool
+	EventUnconfirmedTxnRemoved EventType = "unconfirmed_txn_removed"
+	// EventBlockDisconnected is published when a block is rolled back from the main chain by a reorg
+	EventBlockDisconnected EventType = "block_disconnected"
+)
+
+const (
+	// RemovedTxnConfirmed is the Reason of an EventUnconfirmedTxnRemoved for a transaction executed in a block
+	RemovedTxnConfirmed = "confirmed"
+	// RemovedTxnInvalid is the Reason of an EventUnconfirmedTxnRemoved for a transaction that became permanently invalid
+	RemovedTxnInvalid = "invalid"
+	// RemovedTxnEvicted is the Reason of an EventUnconfirmedTxnRemoved for a transaction evicted from the full pool
+	// by a transaction that pays a higher fee per byte
+	RemovedTxnEvicted = "evicted"
+	// RemovedTxnReplaced is the Reason of an EventUnconfirmedTxnRemoved for a transaction replaced by a transaction
+	// that spends the same inputs and burns more coin hours
+	RemovedTxnReplaced = "replaced"
+
+	// eventSubscriptionSize is the number of events buffered for a subscriber
+	eventSubscriptionSize = 1024
+)
+
+// Event is a change of the blockchain or the unconfirmed pool, published to the Visor event subscribers
+type Event struct {
+	Type EventType
+	// Block is the executed block, for EventBlock, or the rolled back block, for EventBlockDisconnected
+	Block *coin.SignedBlock
+	// BlockInputs are the inputs of the block transactions, for EventBlock and EventBlockDisconnected
+	BlockInputs [][]TransactionInput
+	// Transaction is the unconfirmed transaction, for EventUnconfirmedTxnAdded and EventUnconfirmedTxnRemoved
+	Transaction *UnconfirmedTransaction
+	// TransactionInputs are the inputs of Transaction
+	TransactionInputs []TransactionInput
+	// Reason is why Transaction left the unconfirmed pool, for EventUnconfirmedTxnRemoved
+	Reason string
+}
+
+// TransactionAddresses returns the addresses of the inputs and outputs of a transaction
+func TransactionAddresses(txn coin.Transaction, inputs []TransactionInput) []cipher.Address {
+	seen := make(map[cipher.Address]struct{}, len(inputs)+len(txn.Out))
+	addrs := make([]cipher.Address, 0, len(inputs)+len(txn.Out))
+
+	add := func(addr cipher.Address) {
+		if _, ok := seen[addr]; !ok {
+			seen[addr] = struct{}{}
+			addrs = append(addrs, addr)
+		}
+	}
+
+	for _, in := range inputs {
+		add(in.UxOut.Body.Address)
+	}
+	for _, o := range txn.Out {
+		add(o.Address)
+	}
+
+	return addrs
+}
+
+// EventSubscription receives the events published by the Visor
+type EventSubscription struct {
+	// Events receives the published events. It is closed by Unsubscribe, or when the subscriber
+	// does not keep up with the events and they are dropped
+	Events <-chan Event
+
+	events chan Event
+	broker *eventBroker
+}
+
+// Unsubscribe stops the subscription and closes Events
+func (s *EventSubscription) Unsubscribe() {
+	if s.broker != nil {
+		s.broker.unsubscribe(s)
+	}
+}
+
+// eventBroker fans the published events out to the subscribers.
+// A subscriber whose buffer is full is dropped, so that a slow subscriber does not block the Visor.
+type eventBroker struct {
+	sync.Mutex
+	subs map[*EventSubscription]struct{}
+}
+
+func newEventBroker() *eventBroker {
+	return &eventBroker{
+		subs: make(map[*EventSubscription]struct{}),
+	}
+}
+
+func (b *eventBroker) subscribe(size int) *EventSubscription {
+	b.Lock()
+	defer b.Unlock()
+
+	events := make(chan Event, size)
+	s := &EventSubscription{
+		Events: events,
+		events: events,
+		broker: b,
+	}
+	b.subs[s] = struct{}{}
+
+	return s
+}
+
+func (b *eventBroker) unsubscribe(s *EventSubscription) {
+	b.Lock()
+	defer b.Unlock()
+
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.events)
+	}
+}
+
+func (b *eventBroker) hasSubscribers() bool {
+	b.Lock()
+	defer b.Unlock()
+
+	return len(b.subs) != 0
+}
+
+func (b *eventBroker) publish(events ...Event) {
+	b.Lock()
+	defer b.Unlock()
+
+	for s := range b.subs {
+		if !s.send(events) {
+			logger.Warning("Event subscriber is too slow, dropping it")
+			delete(b.subs, s)
+			close(s.events)
+		}
+	}
+}
+
+// send sends events without blocking. Returns false if the buffer is full
+func (s *EventSubscription) send(events []Event) bool {
+	for _, e := range events {
+		select {
+		case s.events <- e:
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// SubscribeEvents subscribes to the blocks added to or rolled back from the blockchain and the transactions
+// entering or leaving the unconfirmed pool. Events are published once the database changes are committed.
+// The caller must call Unsubscribe when done.
+func (vs *Visor) SubscribeEvents() *EventSubscription {
+	return vs.events.subscribe(eventSubscriptionSize)
+}
+
+// hasSubscribers returns true if anyone subscribed to the events, so that the events must be created.
+// A Visor that is not created by New has no event broker, so it has no subscribers and publishes nothing
+func (vs *Visor) hasSubscribers() bool {
+	return vs.events != nil && vs.hasSubscribers()
+}
+
+// publish publishes events once tx is committed, if anyone subscribed to them
+func (vs *Visor) publish(tx *dbutil.Tx, events ...Event) {
+	if len(events) == 0 || !vs.hasSubscribers() {
+		return
+	}
+
+	tx.OnCommit(func() {
+		vs.events.publish(events...)
+	})
+}
+
+// blockEvents creates the events of an executed block.
+// confirmed are the transactions of the block that were removed from the unconfirmed pool.
+func (vs *Visor) blockEvents(tx *dbutil.Tx, b coin.SignedBlock, confirmed []UnconfirmedTransaction) ([]Event, error) {
+	inputs, err := vs.getBlockInputs(tx, &b)
+	if err != nil {
+		return nil, err
+	}
+
+	events := []Event{
+		{
+			Type:        EventBlock,
+			Block:       &b,
+			BlockInputs: inputs,
+		},
+	}
+
+	txnInputs := make(map[cipher.SHA256][]TransactionInput, len(b.Body.Transactions))
+	for i, txn := range b.Body.Transactions {
+		txnInputs[txn.Hash()] = inputs[i]
+	}
+
+	for i := range confirmed {
+		events = append(events, Event{
+			Type:              EventUnconfirmedTxnRemoved,
+			Transaction:       &confirmed[i],
+			TransactionInputs: txnInputs[confirmed[i].Transaction.Hash()],
+			Reason:            RemovedTxnConfirmed,
+		})
+	}
+
+	return events, nil
+}
+
+// blockDisconnectedEvent creates the event of a block rolled back by a reorg.
+// It must be created before the block is rolled back from the HistoryDB, which holds the inputs of the block.
+func (vs *Visor) blockDisconnectedEvent(tx *dbutil.Tx, b coin.SignedBlock) (Event, error) {
+	inputs, err := vs.getBlockInputs(tx, &b)
+	if err != nil {
+		return Event{}, err
+	}
+
+	return Event{
+		Type:        EventBlockDisconnected,
+		Block:       &b,
+		BlockInputs: inputs,
+	}, nil
+}
+
+// unconfirmedTxnEvents creates events of type typ for unconfirmed transactions
+func (vs *Visor) unconfirmedTxnEvents(tx *dbutil.Tx, typ EventType, txns []UnconfirmedTransaction, reason string) ([]Event, error) {
+	inputs, err := vs.getTransactionInputsForUnconfirmedTxns(tx, txns)
+	if err != nil {
+		return nil, err
+	}
+
+	events := make([]Event, len(txns))
+	for i := range txns {
+		events[i] = Event{
+			Type:              typ,
+			Transaction:       &txns[i],
+			TransactionInputs: inputs[i],
+			Reason:            reason,
+		}
+	}
+
+	return events, nil
+}
+
+// getUnconfirmedTxns returns the transactions of hashes that are in the unconfirmed pool
+func (vs *Visor) getUnconfirmedTxns(tx *dbutil.Tx, hashes []cipher.SHA256) ([]UnconfirmedTransaction, error) {
+	var txns []UnconfirmedTransaction
+	for _, h := range hashes {
+		ut, err := vs.unconfirmed.Get(tx, h)
+		if err != nil {
+			return nil, err
+		}
+
+		if ut != nil {
+			txns = append(txns, *ut)
+		}
+	}
+
+	return txns, nil
+}
+
+// publishUnconfirmedTxnAdded publishes the addition of txn to the unconfirmed pool
+func (vs *Visor) publishUnconfirmedTxnAdded(tx *dbutil.Tx, txn coin.Transaction) error {
+	if !vs.hasSubscribers() {
+		return nil
+	}
+
+	txns, err := vs.getUnconfirmedTxns(tx, []cipher.SHA256{txn.Hash()})
+	if err != nil {
+		return err
+	}
+
+	events, err := vs.unconfirmedTxnEvents(tx, EventUnconfirmedTxnAdded, txns, "")
+	if err != nil {
+		return err
+	}
+
+	vs.publish(tx, events...)
+	return nil
+}
+
+// publishUnconfirmedTxnsRemoved publishes the removal of txns from the unconfirmed pool for the given reason.
+// It must be called before the txns are removed.
+func (vs *Visor) publishUnconfirmedTxnsRemoved(tx *dbutil.Tx, hashes []cipher.SHA256, reason string) error {
+	if !vs.hasSubscribers() {
+		return nil
+	}
+
+	txns, err := vs.getUnconfirmedTxns(tx, hashes)
+	if err != nil {
+		return err
+	}
+
+	events, err := vs.unconfirmedTxnEvents(tx, EventUnconfirmedTxnRemoved, txns, reason)
+	if err != nil {
+		return err
+	}
+
+	vs.publish(tx, events...)
+	return nil
+}
@@ -0,0 +1,245 @@
+package visor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ness-network/ness/src/visor/historydb"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestEventBroker(t *testing.T) {
+	b := newEventBroker()
+	require.False(t, b.hasSubscribers())
+
+	s1 := b.subscribe(4)
+	s2 := b.subscribe(1)
+	require.True(t, b.hasSubscribers())
+
+	e1 := Event{Type: EventBlock}
+	e2 := Event{Type: EventUnconfirmedTxnAdded}
+
+	b.publish(e1)
+	require.Equal(t, e1, <-s1.Events)
+	require.Equal(t, e1, <-s2.Events)
+
+	// A subscriber that can't take all the events is dropped
+	b.publish(e1, e2)
+	require.Equal(t, e1, <-s1.Events)
+	require.Equal(t, e2, <-s1.Events)
+	require.Equal(t, e1, <-s2.Events)
+	_, ok := <-s2.Events
+	require.False(t, ok)
+
+	// Unsubscribe closes the channel and can be called again
+	s2.Unsubscribe()
+	s1.Unsubscribe()
+	s1.Unsubscribe()
+	_, ok = <-s1.Events
+	require.False(t, ok)
+	require.False(t, b.hasSubscribers())
+
+	// Publishing without subscribers does nothing
+	b.publish(e1)
+
+	// A Visor that is not created by New has no subscribers and publishes nothing
+	v := &Visor{}
+	require.False(t, v.hasSubscribers())
+	v.publish(nil, e1)
+}
+
+func TestTransactionAddresses(t *testing.T) {
+	addr1 := testutil.MakeAddress()
+	addr2 := testutil.MakeAddress()
+	addr3 := testutil.MakeAddress()
+
+	txn := coin.Transaction{
+		Out: []coin.TransactionOutput{
+			{Address: addr2},
+			{Address: addr3},
+			{Address: addr1},
+		},
+	}
+
+	inputs := []TransactionInput{
+		{UxOut: coin.UxOut{Body: coin.UxBody{Address: addr1}}},
+		{UxOut: coin.UxOut{Body: coin.UxBody{Address: addr1}}},
+	}
+
+	require.Equal(t, []cipher.Address{addr1, addr2, addr3}, TransactionAddresses(txn, inputs))
+	require.Equal(t, []cipher.Address{addr2, addr3, addr1}, TransactionAddresses(txn, nil))
+}
+
+func TestVisorEvents(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, BlockchainConfig{
+		Pubkey: genPublic,
+	})
+	require.NoError(t, err)
+
+	unconfirmed, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{})
+	require.NoError(t, err)
+
+	cfg := NewConfig()
+	cfg.IsBlockPublisher = true
+	cfg.BlockchainPubkey = genPublic
+	cfg.BlockchainSeckey = genSecret
+	cfg.GenesisAddress = genAddress
+
+	v := &Visor{
+		Config:      cfg,
+		unconfirmed: unconfirmed,
+		blockchain:  bc,
+		db:          db,
+		history:     historydb.New(),
+		events:      newEventBroker(),
+	}
+
+	gb := addGenesisBlockToVisor(t, v)
+	uxs := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])
+
+	s := v.SubscribeEvents()
+	defer s.Unsubscribe()
+
+	// A transaction entering the unconfirmed pool
+	toAddr := testutil.MakeAddress()
+	txn := makeSpendTxn(t, uxs, []cipher.SecKey{genSecret}, toAddr, 1e6)
+	known, softErr, err := v.InjectForeignTransaction(txn)
+	require.NoError(t, err)
+	require.Nil(t, softErr)
+	require.False(t, known)
+
+	e := <-s.Events
+	require.Equal(t, EventUnconfirmedTxnAdded, e.Type)
+	require.Equal(t, txn, e.Transaction.Transaction)
+	require.Len(t, e.TransactionInputs, 1)
+	require.Equal(t, uxs[0], e.TransactionInputs[0].UxOut)
+	require.Equal(t, []cipher.Address{genAddress, toAddr}, TransactionAddresses(e.Transaction.Transaction, e.TransactionInputs))
+
+	// A known transaction is not published again
+	known, _, err = v.InjectForeignTransaction(txn)
+	require.NoError(t, err)
+	require.True(t, known)
+	require.Len(t, s.Events, 0)
+
+	// Nothing is published when the database transaction fails
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, v.publishUnconfirmedTxnAdded(tx, txn))
+		return ErrKeyCheckpointSeq
+	})
+	require.Equal(t, ErrKeyCheckpointSeq, err)
+	require.Len(t, s.Events, 0)
+
+	// Executing a block publishes the block and the confirmation of its unconfirmed transactions
+	b, err := v.CreateAndExecuteBlock()
+	require.NoError(t, err)
+
+	e = <-s.Events
+	require.Equal(t, EventBlock, e.Type)
+	require.Equal(t, b, *e.Block)
+	require.Len(t, e.BlockInputs, 1)
+	require.Equal(t, uxs[0], e.BlockInputs[0][0].UxOut)
+
+	e = <-s.Events
+	require.Equal(t, EventUnconfirmedTxnRemoved, e.Type)
+	require.Equal(t, RemovedTxnConfirmed, e.Reason)
+	require.Equal(t, txn, e.Transaction.Transaction)
+	require.Equal(t, uxs[0], e.TransactionInputs[0].UxOut)
+
+	require.Len(t, s.Events, 0)
+}
+
+func TestVisorEventsUnconfirmedTxnRemoved(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, BlockchainConfig{
+		Pubkey: genPublic,
+	})
+	require.NoError(t, err)
+
+	unconfirmed, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{
+		MaxCount:     1,
+		ReplaceByFee: true,
+	})
+	require.NoError(t, err)
+
+	cfg := NewConfig()
+	cfg.IsBlockPublisher = true
+	cfg.BlockchainPubkey = genPublic
+	cfg.BlockchainSeckey = genSecret
+	cfg.GenesisAddress = genAddress
+
+	v := &Visor{
+		Config:      cfg,
+		unconfirmed: unconfirmed,
+		blockchain:  bc,
+		db:          db,
+		history:     historydb.New(),
+		events:      newEventBroker(),
+	}
+	unconfirmed.onRemove = v.publishUnconfirmedTxnsRemoved
+
+	gb := addGenesisBlockToVisor(t, v)
+	uxs := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])
+
+	// Split the genesis output in two outputs of genAddress
+	txn := makeSpendTxn(t, uxs, []cipher.SecKey{genSecret}, genAddress, 1e6)
+	_, _, err = v.InjectForeignTransaction(txn)
+	require.NoError(t, err)
+	b, err := v.CreateAndExecuteBlock()
+	require.NoError(t, err)
+	uxs = coin.CreateUnspents(b.Head, txn)
+
+	s := v.SubscribeEvents()
+	defer s.Unsubscribe()
+
+	toAddr := testutil.MakeAddress()
+	txn1 := makeSpendTxWithFee(t, uxs[:1], []cipher.SecKey{genSecret}, toAddr, 1e5, 0)
+	_, softErr, err := v.InjectForeignTransaction(txn1)
+	require.NoError(t, err)
+	require.Nil(t, softErr)
+
+	e := <-s.Events
+	require.Equal(t, EventUnconfirmedTxnAdded, e.Type)
+	require.Equal(t, txn1, e.Transaction.Transaction)
+
+	// A txn that spends the same input and burns more coin hours replaces txn1
+	txn2 := makeSpendTxWithFee(t, uxs[:1], []cipher.SecKey{genSecret}, toAddr, 1e5, 1)
+	_, softErr, err = v.InjectForeignTransaction(txn2)
+	require.NoError(t, err)
+	require.Nil(t, softErr)
+
+	e = <-s.Events
+	require.Equal(t, EventUnconfirmedTxnRemoved, e.Type)
+	require.Equal(t, RemovedTxnReplaced, e.Reason)
+	require.Equal(t, txn1, e.Transaction.Transaction)
+	require.Equal(t, uxs[0], e.TransactionInputs[0].UxOut)
+
+	e = <-s.Events
+	require.Equal(t, EventUnconfirmedTxnAdded, e.Type)
+	require.Equal(t, txn2, e.Transaction.Transaction)
+
+	// A txn that pays a higher fee per byte evicts txn2 from the full pool
+	txn3 := makeSpendTxWithFee(t, uxs[1:], []cipher.SecKey{genSecret}, toAddr, 1e5, 2)
+	_, softErr, err = v.InjectForeignTransaction(txn3)
+	require.NoError(t, err)
+	require.Nil(t, softErr)
+
+	e = <-s.Events
+	require.Equal(t, EventUnconfirmedTxnRemoved, e.Type)
+	require.Equal(t, RemovedTxnEvicted, e.Reason)
+	require.Equal(t, txn2, e.Transaction.Transaction)
+
+	e = <-s.Events
+	require.Equal(t, EventUnconfirmedTxnAdded, e.Type)
+	require.Equal(t, txn3, e.Transaction.Transaction)
+
+	require.Len(t, s.Events, 0)
+}
@@ -0,0 +1,157 @@
+package historydb
+
+// address_balance.go indexes the confirmed coin balance changes of addresses by block.
+// The address_balance_deltas bucket maps an address followed by a block seq to the coins
+// the address received and sent in the block, and the time of the block.
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/util/mathutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// AddressBalanceDeltasBkt maps addresses followed by block seqs to balance deltas
+var AddressBalanceDeltasBkt = []byte("address_balance_deltas")
+
+// addressBalanceDeltaLen is the length of an encoded AddressBalanceDelta value
+const addressBalanceDeltaLen = 8 * 3
+
+// AddressBalanceDelta is the change of the confirmed coin balance of an address in a block
+type AddressBalanceDelta struct {
+	BlockSeq uint64
+	Time     uint64
+	// Received is the sum of the coins of the block's outputs to the address
+	Received uint64
+	// Sent is the sum of the coins of the address's outputs spent in the block
+	Sent uint64
+}
+
+func addressBalanceDeltaKey(addr cipher.Address, seq uint64) []byte {
+	prefix := addr.Bytes()
+	k := make([]byte, len(prefix)+8)
+	copy(k, prefix)
+	binary.BigEndian.PutUint64(k[len(prefix):], seq)
+	return k
+}
+
+// addressBalanceDeltas bucket for storing the balance deltas of addresses by block seq
+type addressBalanceDeltas struct{}
+
+// put sets the balance delta of an address in a block
+func (abd *addressBalanceDeltas) put(tx *dbutil.Tx, addr cipher.Address, d AddressBalanceDelta) error {
+	v := make([]byte, addressBalanceDeltaLen)
+	binary.BigEndian.PutUint64(v, d.Time)
+	binary.BigEndian.PutUint64(v[8:], d.Received)
+	binary.BigEndian.PutUint64(v[16:], d.Sent)
+	return dbutil.PutBucketValue(tx, AddressBalanceDeltasBkt, addressBalanceDeltaKey(addr, d.BlockSeq), v)
+}
+
+// delete removes the balance delta of an address in a block
+func (abd *addressBalanceDeltas) delete(tx *dbutil.Tx, addr cipher.Address, seq uint64) error {
+	return dbutil.Delete(tx, AddressBalanceDeltasBkt, addressBalanceDeltaKey(addr, seq))
+}
+
+// getRange returns the balance deltas of an address in the inclusive range of block seqs, in block seq order
+func (abd *addressBalanceDeltas) getRange(tx *dbutil.Tx, addr cipher.Address, fromSeq, toSeq uint64) ([]AddressBalanceDelta, error) {
+	bkt := tx.Bucket(AddressBalanceDeltasBkt)
+	if bkt == nil {
+		return nil, dbutil.NewErrBucketNotExist(AddressBalanceDeltasBkt)
+	}
+
+	prefix := addr.Bytes()
+
+	var deltas []AddressBalanceDelta
+	cur := bkt.Cursor()
+	for k, v := cur.Seek(addressBalanceDeltaKey(addr, fromSeq)); k != nil && bytes.HasPrefix(k, prefix); k, v = cur.Next() {
+		if len(k) != len(prefix)+8 || len(v) != addressBalanceDeltaLen {
+			return nil, errors.New("invalid address balance delta")
+		}
+
+		seq := binary.BigEndian.Uint64(k[len(prefix):])
+		if seq > toSeq {
+			break
+		}
+
+		deltas = append(deltas, AddressBalanceDelta{
+			BlockSeq: seq,
+			Time:     binary.BigEndian.Uint64(v),
+			Received: binary.BigEndian.Uint64(v[8:]),
+			Sent:     binary.BigEndian.Uint64(v[16:]),
+		})
+	}
+
+	return deltas, nil
+}
+
+// isEmpty checks if the address balance deltas bucket is empty
+func (abd *addressBalanceDeltas) isEmpty(tx *dbutil.Tx) (bool, error) {
+	return dbutil.IsEmpty(tx, AddressBalanceDeltasBkt)
+}
+
+// reset resets the bucket
+func (abd *addressBalanceDeltas) reset(tx *dbutil.Tx) error {
+	return dbutil.Reset(tx, AddressBalanceDeltasBkt)
+}
+
+// blockBalanceDeltas accumulates the balance deltas of the addresses touched by a block
+type blockBalanceDeltas struct {
+	seq    uint64
+	time   uint64
+	addrs  []cipher.Address
+	deltas map[cipher.Address]*AddressBalanceDelta
+}
+
+func newBlockBalanceDeltas(seq, time uint64) *blockBalanceDeltas {
+	return &blockBalanceDeltas{
+		seq:    seq,
+		time:   time,
+		deltas: make(map[cipher.Address]*AddressBalanceDelta),
+	}
+}
+
+func (bd *blockBalanceDeltas) get(addr cipher.Address) *AddressBalanceDelta {
+	d, ok := bd.deltas[addr]
+	if !ok {
+		d = &AddressBalanceDelta{
+			BlockSeq: bd.seq,
+			Time:     bd.time,
+		}
+		bd.deltas[addr] = d
+		bd.addrs = append(bd.addrs, addr)
+	}
+	return d
+}
+
+func (bd *blockBalanceDeltas) receive(addr cipher.Address, coins uint64) error {
+	d := bd.get(addr)
+	received, err := mathutil.AddUint64(d.Received, coins)
+	if err != nil {
+		return err
+	}
+	d.Received = received
+	return nil
+}
+
+func (bd *blockBalanceDeltas) send(addr cipher.Address, coins uint64) error {
+	d := bd.get(addr)
+	sent, err := mathutil.AddUint64(d.Sent, coins)
+	if err != nil {
+		return err
+	}
+	d.Sent = sent
+	return nil
+}
+
+// GetAddressBalanceDeltas returns the balance deltas of an address in the inclusive range of block seqs,
+// in block seq order. Blocks that don't touch the address have no delta.
+func (hd HistoryDB) GetAddressBalanceDeltas(tx *dbutil.Tx, addr cipher.Address, fromSeq, toSeq uint64) ([]AddressBalanceDelta, error) {
+	if fromSeq > toSeq {
+		return nil, nil
+	}
+
+	return hd.addrBalanceDeltas.getRange(tx, addr, fromSeq, toSeq)
+}
@@ -0,0 +1,176 @@
+package historydb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestGetAddressBalanceDeltas(t *testing.T) {
+	db, teardown := prepareDB(t)
+	defer teardown()
+	bc := newBlockchain()
+	gb := bc.CreateGenesisBlock(genAddress, genCoins, genTime)
+
+	hisDB := New()
+
+	addrA := cipher.MustDecodeBase58Address("2RxP5N26GhDqHrP6SK45ZzEMSmSpeUeWxsS")
+	addrB := cipher.MustDecodeBase58Address("222uMeCeL1PbkJGZJDgAz5sib2uisv9hYUm")
+
+	b1, txn1, err := addBlock(bc, testData{
+		PreBlockHash: gb.HashHeader(),
+		Vin: txIn{
+			SigKey:   genSecret.Hex(),
+			Addr:     genAddress.String(),
+			TxID:     gb.Body.Transactions[0].Hash(),
+			BlockSeq: 0,
+		},
+		Vouts: []txOut{
+			{
+				ToAddr: addrA.String(),
+				Coins:  10e6,
+				Hours:  100,
+			},
+			{
+				ToAddr: addrB.String(),
+				Coins:  genCoins - 10e6,
+				Hours:  400,
+			},
+		},
+	}, incTime)
+	require.NoError(t, err)
+
+	// addrB sends coins to addrA and to itself
+	b2, _, err := addBlock(bc, testData{
+		PreBlockHash: b1.HashHeader(),
+		Vin: txIn{
+			SigKey:   "62f4d675d991c41a2819d908a4fcf4ba44ff0c31564039e80508c9d68197f90c",
+			Addr:     addrB.String(),
+			TxID:     txn1.Hash(),
+			BlockSeq: 1,
+		},
+		Vouts: []txOut{
+			{
+				ToAddr: addrA.String(),
+				Coins:  10e6,
+				Hours:  100,
+			},
+			{
+				ToAddr: addrB.String(),
+				Coins:  genCoins - 20e6,
+				Hours:  100,
+			},
+		},
+	}, incTime*2)
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		if err := hisDB.ParseBlock(tx, gb); err != nil {
+			return err
+		}
+		if err := hisDB.ParseBlock(tx, *b1); err != nil {
+			return err
+		}
+		return hisDB.ParseBlock(tx, *b2)
+	})
+	require.NoError(t, err)
+
+	cases := []struct {
+		name    string
+		addr    cipher.Address
+		fromSeq uint64
+		toSeq   uint64
+		expect  []AddressBalanceDelta
+	}{
+		{
+			name:  "genesis address",
+			addr:  genAddress,
+			toSeq: 10,
+			expect: []AddressBalanceDelta{
+				{
+					BlockSeq: 0,
+					Time:     gb.Time(),
+					Received: genCoins,
+				},
+				{
+					BlockSeq: 1,
+					Time:     b1.Time(),
+					Sent:     genCoins,
+				},
+			},
+		},
+		{
+			name:  "received and sent in the same block",
+			addr:  addrB,
+			toSeq: 10,
+			expect: []AddressBalanceDelta{
+				{
+					BlockSeq: 1,
+					Time:     b1.Time(),
+					Received: genCoins - 10e6,
+				},
+				{
+					BlockSeq: 2,
+					Time:     b2.Time(),
+					Received: genCoins - 20e6,
+					Sent:     genCoins - 10e6,
+				},
+			},
+		},
+		{
+			name:    "seq range",
+			addr:    addrA,
+			fromSeq: 2,
+			toSeq:   2,
+			expect: []AddressBalanceDelta{
+				{
+					BlockSeq: 2,
+					Time:     b2.Time(),
+					Received: 10e6,
+				},
+			},
+		},
+		{
+			name:    "empty seq range",
+			addr:    addrA,
+			fromSeq: 2,
+			toSeq:   1,
+		},
+		{
+			name:  "unknown address",
+			addr:  testutil.MakeAddress(),
+			toSeq: 10,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := db.View("", func(tx *dbutil.Tx) error {
+				deltas, err := hisDB.GetAddressBalanceDeltas(tx, tc.addr, tc.fromSeq, tc.toSeq)
+				require.NoError(t, err)
+				require.Equal(t, tc.expect, deltas)
+				return nil
+			})
+			require.NoError(t, err)
+		})
+	}
+
+	// Rolling back a block removes its deltas
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		return hisDB.RollbackBlock(tx, *b2)
+	})
+	require.NoError(t, err)
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		deltas, err := hisDB.GetAddressBalanceDeltas(tx, addrB, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, deltas, 1)
+		require.Equal(t, uint64(1), deltas[0].BlockSeq)
+		return nil
+	})
+	require.NoError(t, err)
+}
@@ -63,6 +63,28 @@ func (atx *addressTxns) add(tx *dbutil.Tx, addr cipher.Address, hash cipher.SHA2
 	return dbutil.PutBucketValue(tx, AddressTxnsBkt, addr.Bytes(), buf)
 }
 
+// remove removes a hash from an address's hash list, the address is deleted if it has no hashes left
+func (atx *addressTxns) remove(tx *dbutil.Tx, addr cipher.Address, hash cipher.SHA256) error {
+	hashes, err := atx.get(tx, addr)
+	if err != nil {
+		return err
+	}
+
+	hashes = removeHash(hashes, hash)
+	if len(hashes) == 0 {
+		return dbutil.Delete(tx, AddressTxnsBkt, addr.Bytes())
+	}
+
+	buf, err := encodeHashesWrapper(&hashesWrapper{
+		Hashes: hashes,
+	})
+	if err != nil {
+		return err
+	}
+
+	return dbutil.PutBucketValue(tx, AddressTxnsBkt, addr.Bytes(), buf)
+}
+
 // contains returns true if an address has transactions
 func (atx *addressTxns) contains(tx *dbutil.Tx, addr cipher.Address) (bool, error) {
 	return dbutil.BucketHasKey(tx, AddressTxnsBkt, addr.Bytes())
@@ -77,3 +99,13 @@ func (atx *addressTxns) isEmpty(tx *dbutil.Tx) (bool, error) {
 func (atx *addressTxns) reset(tx *dbutil.Tx) error {
 	return dbutil.Reset(tx, AddressTxnsBkt)
 }
+
+func removeHash(hashes []cipher.SHA256, hash cipher.SHA256) []cipher.SHA256 {
+	var hs []cipher.SHA256
+	for _, h := range hashes {
+		if h != hash {
+			hs = append(hs, h)
+		}
+	}
+	return hs
+}
@@ -55,6 +55,28 @@ func (au *addressUx) add(tx *dbutil.Tx, address cipher.Address, uxHash cipher.SH
 	return dbutil.PutBucketValue(tx, AddressUxBkt, address.Bytes(), buf)
 }
 
+// remove removes a hash from an address's hash list, the address is deleted if it has no hashes left
+func (au *addressUx) remove(tx *dbutil.Tx, address cipher.Address, uxHash cipher.SHA256) error {
+	hashes, err := au.get(tx, address)
+	if err != nil {
+		return err
+	}
+
+	hashes = removeHash(hashes, uxHash)
+	if len(hashes) == 0 {
+		return dbutil.Delete(tx, AddressUxBkt, address.Bytes())
+	}
+
+	buf, err := encodeHashesWrapper(&hashesWrapper{
+		Hashes: hashes,
+	})
+	if err != nil {
+		return err
+	}
+
+	return dbutil.PutBucketValue(tx, AddressUxBkt, address.Bytes(), buf)
+}
+
 // isEmpty checks if the addressUx bucket is empty
 func (au *addressUx) isEmpty(tx *dbutil.Tx) (bool, error) {
 	return dbutil.IsEmpty(tx, AddressUxBkt)
@@ -24,26 +24,35 @@ func CreateBuckets(tx *dbutil.Tx) error {
 		HistoryMetaBkt,
 		UxOutsBkt,
 		TransactionsBkt,
+		TxnCursorsBkt,
+		AddressTxnCursorsBkt,
+		AddressBalanceDeltasBkt,
 	})
 }
 
 // HistoryDB provides APIs for blockchain explorer
 type HistoryDB struct {
-	outputs  *uxOuts       // outputs bucket
-	txns     *transactions // transactions bucket
-	addrUx   *addressUx    // bucket which stores all UxOuts that address received
-	addrTxns *addressTxns  // address related transaction bucket
-	meta     *historyMeta  // stores history meta info
+	outputs           *uxOuts               // outputs bucket
+	txns              *transactions         // transactions bucket
+	addrUx            *addressUx            // bucket which stores all UxOuts that address received
+	addrTxns          *addressTxns          // address related transaction bucket
+	meta              *historyMeta          // stores history meta info
+	txnCursors        *txnCursors           // transactions in blockchain order
+	addrTxnCursors    *addressTxnCursors    // address related transactions in blockchain order
+	addrBalanceDeltas *addressBalanceDeltas // address balance changes by block
 }
 
 // New create HistoryDB instance
 func New() *HistoryDB {
 	return &HistoryDB{
-		outputs:  &uxOuts{},
-		txns:     &transactions{},
-		addrUx:   &addressUx{},
-		addrTxns: &addressTxns{},
-		meta:     &historyMeta{},
+		outputs:           &uxOuts{},
+		txns:              &transactions{},
+		addrUx:            &addressUx{},
+		addrTxns:          &addressTxns{},
+		meta:              &historyMeta{},
+		txnCursors:        &txnCursors{},
+		addrTxnCursors:    &addressTxnCursors{},
+		addrBalanceDeltas: &addressBalanceDeltas{},
 	}
 }
 
@@ -79,7 +88,22 @@ func (hd *HistoryDB) NeedsReset(tx *dbutil.Tx) (bool, error) {
 		return false, err
 	}
 
-	if addrTxnsEmpty || addrUxEmpty || txnsEmpty || outputsEmpty {
+	txnCursorsEmpty, err := hd.txnCursors.isEmpty(tx)
+	if err != nil {
+		return false, err
+	}
+
+	addrTxnCursorsEmpty, err := hd.addrTxnCursors.isEmpty(tx)
+	if err != nil {
+		return false, err
+	}
+
+	addrBalanceDeltasEmpty, err := hd.addrBalanceDeltas.isEmpty(tx)
+	if err != nil {
+		return false, err
+	}
+
+	if addrTxnsEmpty || addrUxEmpty || txnsEmpty || outputsEmpty || txnCursorsEmpty || addrTxnCursorsEmpty || addrBalanceDeltasEmpty {
 		return true, nil
 	}
 
@@ -105,6 +129,18 @@ func (hd *HistoryDB) Erase(tx *dbutil.Tx) error {
 		return err
 	}
 
+	if err := hd.txnCursors.reset(tx); err != nil {
+		return err
+	}
+
+	if err := hd.addrTxnCursors.reset(tx); err != nil {
+		return err
+	}
+
+	if err := hd.addrBalanceDeltas.reset(tx); err != nil {
+		return err
+	}
+
 	return hd.txns.reset(tx)
 }
 
@@ -125,7 +161,9 @@ func (hd *HistoryDB) GetUxOuts(tx *dbutil.Tx, uxIDs []cipher.SHA256) ([]UxOut, e
 
 // ParseBlock builds indexes out of the block data
 func (hd *HistoryDB) ParseBlock(tx *dbutil.Tx, b coin.Block) error {
-	for _, t := range b.Body.Transactions {
+	balanceDeltas := newBlockBalanceDeltas(b.Seq(), b.Time())
+
+	for i, t := range b.Body.Transactions {
 		txn := Transaction{
 			Txn:      t,
 			BlockSeq: b.Seq(),
@@ -137,6 +175,15 @@ func (hd *HistoryDB) ParseBlock(tx *dbutil.Tx, b coin.Block) error {
 			return err
 		}
 
+		cursor := TxnCursor{
+			BlockSeq: b.Seq(),
+			TxnIndex: uint32(i),
+		}
+
+		if err := hd.txnCursors.put(tx, cursor, spentTxnID); err != nil {
+			return err
+		}
+
 		for _, in := range t.In {
 			o, err := hd.outputs.get(tx, in)
 			if err != nil {
@@ -158,6 +205,14 @@ func (hd *HistoryDB) ParseBlock(tx *dbutil.Tx, b coin.Block) error {
 			if err := hd.addrTxns.add(tx, o.Out.Body.Address, spentTxnID); err != nil {
 				return err
 			}
+
+			if err := hd.addrTxnCursors.put(tx, o.Out.Body.Address, cursor, spentTxnID); err != nil {
+				return err
+			}
+
+			if err := balanceDeltas.send(o.Out.Body.Address, o.Out.Body.Coins); err != nil {
+				return err
+			}
 		}
 
 		// handle the tx out
@@ -176,12 +231,115 @@ func (hd *HistoryDB) ParseBlock(tx *dbutil.Tx, b coin.Block) error {
 			if err := hd.addrTxns.add(tx, ux.Body.Address, spentTxnID); err != nil {
 				return err
 			}
+
+			if err := hd.addrTxnCursors.put(tx, ux.Body.Address, cursor, spentTxnID); err != nil {
+				return err
+			}
+
+			if err := balanceDeltas.receive(ux.Body.Address, ux.Body.Coins); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, addr := range balanceDeltas.addrs {
+		if err := hd.addrBalanceDeltas.put(tx, addr, *balanceDeltas.deltas[addr]); err != nil {
+			return err
 		}
 	}
 
 	return hd.SetParsedBlockSeq(tx, b.Seq())
 }
 
+// RollbackBlock removes the indexes built out of the block data by ParseBlock.
+// The block must be the last parsed block.
+func (hd *HistoryDB) RollbackBlock(tx *dbutil.Tx, b coin.Block) error {
+	if b.Seq() == 0 {
+		return errors.New("HistoryDB.RollbackBlock: cannot roll back the genesis block")
+	}
+
+	parsedSeq, ok, err := hd.meta.parsedBlockSeq(tx)
+	if err != nil {
+		return err
+	} else if !ok || parsedSeq != b.Seq() {
+		return errors.New("HistoryDB.RollbackBlock: block is not the last parsed block")
+	}
+
+	txns := b.Body.Transactions
+	for i := len(txns) - 1; i >= 0; i-- {
+		t := txns[i]
+		txnID := t.Hash()
+		cursor := TxnCursor{
+			BlockSeq: b.Seq(),
+			TxnIndex: uint32(i),
+		}
+
+		// remove the tx out
+		uxArray := coin.CreateUnspents(b.Head, t)
+		for _, ux := range uxArray {
+			if err := hd.outputs.delete(tx, ux.Hash()); err != nil {
+				return err
+			}
+
+			if err := hd.addrUx.remove(tx, ux.Body.Address, ux.Hash()); err != nil {
+				return err
+			}
+
+			if err := hd.addrTxns.remove(tx, ux.Body.Address, txnID); err != nil {
+				return err
+			}
+
+			if err := hd.addrTxnCursors.delete(tx, ux.Body.Address, cursor); err != nil {
+				return err
+			}
+
+			if err := hd.addrBalanceDeltas.delete(tx, ux.Body.Address, b.Seq()); err != nil {
+				return err
+			}
+		}
+
+		for _, in := range t.In {
+			o, err := hd.outputs.get(tx, in)
+			if err != nil {
+				return err
+			}
+
+			if o == nil {
+				return errors.New("HistoryDB.RollbackBlock: transaction input not found in outputs bucket")
+			}
+
+			// the output is unspent again
+			o.SpentBlockSeq = 0
+			o.SpentTxnID = cipher.SHA256{}
+			if err := hd.outputs.put(tx, *o); err != nil {
+				return err
+			}
+
+			if err := hd.addrTxns.remove(tx, o.Out.Body.Address, txnID); err != nil {
+				return err
+			}
+
+			if err := hd.addrTxnCursors.delete(tx, o.Out.Body.Address, cursor); err != nil {
+				return err
+			}
+
+			if err := hd.addrBalanceDeltas.delete(tx, o.Out.Body.Address, b.Seq()); err != nil {
+				return err
+			}
+		}
+
+		if err := hd.txns.delete(tx, txnID); err != nil {
+			return err
+		}
+
+		if err := hd.txnCursors.delete(tx, cursor); err != nil {
+			return err
+		}
+	}
+
+	return hd.SetParsedBlockSeq(tx, b.Seq()-1)
+}
+
 // GetTransaction get transaction by hash.
 func (hd HistoryDB) GetTransaction(tx *dbutil.Tx, hash cipher.SHA256) (*Transaction, error) {
 	return hd.txns.get(tx, hash)
@@ -353,6 +353,133 @@ func TestProcessBlock(t *testing.T) {
 	testEngine(t, testData, bc, hisDB, db)
 }
 
+func TestRollbackBlock(t *testing.T) {
+	db, teardown := prepareDB(t)
+	defer teardown()
+	bc := newBlockchain()
+	gb := bc.CreateGenesisBlock(genAddress, genCoins, genTime)
+
+	hisDB := New()
+
+	// dumpBuckets returns the contents of the historydb buckets
+	dumpBuckets := func() map[string]map[string]string {
+		dump := make(map[string]map[string]string)
+		err := db.View("", func(tx *dbutil.Tx) error {
+			for _, bkt := range [][]byte{AddressTxnsBkt, AddressUxBkt, HistoryMetaBkt, UxOutsBkt, TransactionsBkt, TxnCursorsBkt, AddressTxnCursorsBkt, AddressBalanceDeltasBkt} {
+				kvs := make(map[string]string)
+				if err := dbutil.ForEach(tx, bkt, func(k, v []byte) error {
+					kvs[string(k)] = string(v)
+					return nil
+				}); err != nil {
+					return err
+				}
+				dump[string(bkt)] = kvs
+			}
+			return nil
+		})
+		require.NoError(t, err)
+		return dump
+	}
+
+	parseBlock := func(b coin.Block) {
+		err := db.Update("", func(tx *dbutil.Tx) error {
+			return hisDB.ParseBlock(tx, b)
+		})
+		require.NoError(t, err)
+	}
+
+	rollbackBlock := func(b coin.Block) error {
+		return db.Update("", func(tx *dbutil.Tx) error {
+			return hisDB.RollbackBlock(tx, b)
+		})
+	}
+
+	parseBlock(gb)
+	genesisDump := dumpBuckets()
+
+	err := rollbackBlock(gb)
+	require.EqualError(t, err, "HistoryDB.RollbackBlock: cannot roll back the genesis block")
+
+	b1, txn1, err := addBlock(bc, testData{
+		PreBlockHash: gb.HashHeader(),
+		Vin: txIn{
+			SigKey:   genSecret.Hex(),
+			Addr:     genAddress.String(),
+			TxID:     gb.Body.Transactions[0].Hash(),
+			BlockSeq: 0,
+		},
+		Vouts: []txOut{
+			{
+				ToAddr: "2RxP5N26GhDqHrP6SK45ZzEMSmSpeUeWxsS",
+				Coins:  10e6,
+				Hours:  100,
+			},
+			{
+				ToAddr: "222uMeCeL1PbkJGZJDgAz5sib2uisv9hYUm",
+				Coins:  genCoins - 10e6,
+				Hours:  400,
+			},
+		},
+	}, incTime)
+	require.NoError(t, err)
+	parseBlock(*b1)
+	b1Dump := dumpBuckets()
+
+	b2, _, err := addBlock(bc, testData{
+		PreBlockHash: b1.HashHeader(),
+		Vin: txIn{
+			SigKey:   "62f4d675d991c41a2819d908a4fcf4ba44ff0c31564039e80508c9d68197f90c",
+			Addr:     "222uMeCeL1PbkJGZJDgAz5sib2uisv9hYUm",
+			TxID:     txn1.Hash(),
+			BlockSeq: 1,
+		},
+		Vouts: []txOut{
+			{
+				ToAddr: "2RxP5N26GhDqHrP6SK45ZzEMSmSpeUeWxsS",
+				Coins:  10e6,
+				Hours:  100,
+			},
+			{
+				ToAddr: "222uMeCeL1PbkJGZJDgAz5sib2uisv9hYUm",
+				Coins:  genCoins - 20e6,
+				Hours:  100,
+			},
+		},
+	}, incTime*2)
+	require.NoError(t, err)
+	parseBlock(*b2)
+
+	// only the last parsed block can be rolled back
+	err = rollbackBlock(*b1)
+	require.EqualError(t, err, "HistoryDB.RollbackBlock: block is not the last parsed block")
+
+	err = rollbackBlock(*b2)
+	require.NoError(t, err)
+	require.Equal(t, b1Dump, dumpBuckets())
+
+	err = rollbackBlock(*b1)
+	require.NoError(t, err)
+	require.Equal(t, genesisDump, dumpBuckets())
+
+	err = db.View("", func(tx *dbutil.Tx) error {
+		seq, ok, err := hisDB.ParsedBlockSeq(tx)
+		require.NoError(t, err)
+		require.True(t, ok)
+		require.Equal(t, uint64(0), seq)
+
+		seen, err := hisDB.AddressSeen(tx, cipher.MustDecodeBase58Address("2RxP5N26GhDqHrP6SK45ZzEMSmSpeUeWxsS"))
+		require.NoError(t, err)
+		require.False(t, seen)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	// the rolled back block can be parsed again
+	parseBlock(*b1)
+	require.Equal(t, b1Dump, dumpBuckets())
+}
+
 func testEngine(t *testing.T, tds []testData, bc *fakeBlockchain, hdb *HistoryDB, db *dbutil.DB) {
 	for i, td := range tds {
 		b, txn, err := addBlock(bc, td, incTime*(uint64(i)+1))
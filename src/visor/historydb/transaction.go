@@ -43,6 +43,11 @@ func (txs *transactions) put(tx *dbutil.Tx, txn *Transaction) error {
 	return dbutil.PutBucketValue(tx, TransactionsBkt, hash[:], buf)
 }
 
+// delete removes transaction by transaction hash
+func (txs *transactions) delete(tx *dbutil.Tx, hash cipher.SHA256) error {
+	return dbutil.Delete(tx, TransactionsBkt, hash[:])
+}
+
 // get gets transaction by transaction hash, return nil on not found
 func (txs *transactions) get(tx *dbutil.Tx, hash cipher.SHA256) (*Transaction, error) {
 	var txn Transaction
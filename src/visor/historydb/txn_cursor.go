@@ -0,0 +1,290 @@
+package historydb
+
+// txn_cursor.go indexes the confirmed transactions by their position in the blockchain, so that
+// they can be paged through in blockchain order without collecting all of their hashes.
+// The txn_cursors bucket maps cursors to transaction hashes, and the address_txn_cursors bucket maps
+// an address followed by a cursor to the hash of a transaction that touches the address.
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+var (
+	// TxnCursorsBkt maps transaction cursors to transaction hashes
+	TxnCursorsBkt = []byte("txn_cursors")
+	// AddressTxnCursorsBkt maps addresses followed by transaction cursors to transaction hashes
+	AddressTxnCursorsBkt = []byte("address_txn_cursors")
+
+	// ErrInvalidTxnCursor is returned when a transaction cursor can't be parsed
+	ErrInvalidTxnCursor = errors.New("invalid transaction cursor")
+)
+
+// txnCursorLen is the length of an encoded TxnCursor
+const txnCursorLen = 8 + 4
+
+// TxnCursor is the position of a confirmed transaction in the blockchain,
+// the seq of its block and its index in the block
+type TxnCursor struct {
+	BlockSeq uint64
+	TxnIndex uint32
+}
+
+// Less returns true if c is before d in the blockchain
+func (c TxnCursor) Less(d TxnCursor) bool {
+	if c.BlockSeq != d.BlockSeq {
+		return c.BlockSeq < d.BlockSeq
+	}
+	return c.TxnIndex < d.TxnIndex
+}
+
+// String returns the cursor as an opaque string, which can be parsed with ParseTxnCursor
+func (c TxnCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString(c.key(nil))
+}
+
+// key appends the big endian encoding of the cursor, which sorts in blockchain order, to prefix
+func (c TxnCursor) key(prefix []byte) []byte {
+	k := make([]byte, len(prefix)+txnCursorLen)
+	copy(k, prefix)
+	binary.BigEndian.PutUint64(k[len(prefix):], c.BlockSeq)
+	binary.BigEndian.PutUint32(k[len(prefix)+8:], c.TxnIndex)
+	return k
+}
+
+func txnCursorFromKey(k []byte) (TxnCursor, error) {
+	if len(k) != txnCursorLen {
+		return TxnCursor{}, ErrInvalidTxnCursor
+	}
+
+	return TxnCursor{
+		BlockSeq: binary.BigEndian.Uint64(k),
+		TxnIndex: binary.BigEndian.Uint32(k[8:]),
+	}, nil
+}
+
+// ParseTxnCursor parses a cursor returned by TxnCursor.String
+func ParseTxnCursor(s string) (TxnCursor, error) {
+	k, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return TxnCursor{}, ErrInvalidTxnCursor
+	}
+
+	return txnCursorFromKey(k)
+}
+
+// CursorTxn is the hash of a confirmed transaction and its cursor
+type CursorTxn struct {
+	Cursor TxnCursor
+	Hash   cipher.SHA256
+}
+
+// TxnCursorQuery selects the transactions returned by GetTransactionCursors
+type TxnCursorQuery struct {
+	// After excludes the transactions up to and including it in the walk order, if not nil
+	After *TxnCursor
+	// FromSeq and ToSeq are the inclusive range of block seqs of the transactions
+	FromSeq uint64
+	ToSeq   uint64
+	// Desc walks the transactions from the last to the first
+	Desc bool
+	// Limit is the maximum number of transactions returned
+	Limit int
+}
+
+// txnCursors bucket for storing transaction hashes by cursor
+type txnCursors struct{}
+
+// put adds a transaction hash at cursor c
+func (tc *txnCursors) put(tx *dbutil.Tx, c TxnCursor, hash cipher.SHA256) error {
+	return dbutil.PutBucketValue(tx, TxnCursorsBkt, c.key(nil), hash[:])
+}
+
+// delete removes the transaction hash at cursor c
+func (tc *txnCursors) delete(tx *dbutil.Tx, c TxnCursor) error {
+	return dbutil.Delete(tx, TxnCursorsBkt, c.key(nil))
+}
+
+// isEmpty checks if the transaction cursors bucket is empty
+func (tc *txnCursors) isEmpty(tx *dbutil.Tx) (bool, error) {
+	return dbutil.IsEmpty(tx, TxnCursorsBkt)
+}
+
+// reset resets the bucket
+func (tc *txnCursors) reset(tx *dbutil.Tx) error {
+	return dbutil.Reset(tx, TxnCursorsBkt)
+}
+
+// addressTxnCursors bucket for storing the transaction hashes of addresses by cursor
+type addressTxnCursors struct{}
+
+// put adds a transaction hash at cursor c of an address
+func (atc *addressTxnCursors) put(tx *dbutil.Tx, addr cipher.Address, c TxnCursor, hash cipher.SHA256) error {
+	return dbutil.PutBucketValue(tx, AddressTxnCursorsBkt, c.key(addr.Bytes()), hash[:])
+}
+
+// delete removes the transaction hash at cursor c of an address
+func (atc *addressTxnCursors) delete(tx *dbutil.Tx, addr cipher.Address, c TxnCursor) error {
+	return dbutil.Delete(tx, AddressTxnCursorsBkt, c.key(addr.Bytes()))
+}
+
+// isEmpty checks if the address transaction cursors bucket is empty
+func (atc *addressTxnCursors) isEmpty(tx *dbutil.Tx) (bool, error) {
+	return dbutil.IsEmpty(tx, AddressTxnCursorsBkt)
+}
+
+// reset resets the bucket
+func (atc *addressTxnCursors) reset(tx *dbutil.Tx) error {
+	return dbutil.Reset(tx, AddressTxnCursorsBkt)
+}
+
+// walkTxnCursors returns the transactions of the keys of a bucket that start with prefix,
+// followed by a cursor, in the order and range of q
+func walkTxnCursors(tx *dbutil.Tx, bktName, prefix []byte, q TxnCursorQuery) ([]CursorTxn, error) {
+	bkt := tx.Bucket(bktName)
+	if bkt == nil {
+		return nil, dbutil.NewErrBucketNotExist(bktName)
+	}
+
+	var txns []CursorTxn
+	add := func(c TxnCursor, v []byte) (bool, error) {
+		hash, err := cipher.SHA256FromBytes(v)
+		if err != nil {
+			return false, err
+		}
+
+		txns = append(txns, CursorTxn{
+			Cursor: c,
+			Hash:   hash,
+		})
+		return len(txns) < q.Limit, nil
+	}
+
+	cur := bkt.Cursor()
+
+	if !q.Desc {
+		start := TxnCursor{BlockSeq: q.FromSeq}
+		if q.After != nil && start.Less(*q.After) {
+			start = *q.After
+		}
+
+		for k, v := cur.Seek(start.key(prefix)); k != nil && bytes.HasPrefix(k, prefix); k, v = cur.Next() {
+			c, err := txnCursorFromKey(k[len(prefix):])
+			if err != nil {
+				return nil, err
+			}
+
+			if c.BlockSeq > q.ToSeq {
+				break
+			}
+
+			if q.After != nil && !q.After.Less(c) {
+				continue
+			}
+
+			if more, err := add(c, v); err != nil {
+				return nil, err
+			} else if !more {
+				break
+			}
+		}
+
+		return txns, nil
+	}
+
+	end := TxnCursor{
+		BlockSeq: q.ToSeq,
+		TxnIndex: math.MaxUint32,
+	}
+	if q.After != nil && q.After.Less(end) {
+		end = *q.After
+	}
+
+	k, v := cur.Seek(end.key(prefix))
+	if k == nil {
+		k, v = cur.Last()
+	}
+
+	for ; k != nil; k, v = cur.Prev() {
+		if !bytes.HasPrefix(k, prefix) {
+			// Seek can land on the keys following the prefix
+			if bytes.Compare(k, prefix) > 0 {
+				continue
+			}
+			break
+		}
+
+		c, err := txnCursorFromKey(k[len(prefix):])
+		if err != nil {
+			return nil, err
+		}
+
+		if end.Less(c) || (q.After != nil && !c.Less(*q.After)) {
+			continue
+		}
+
+		if c.BlockSeq < q.FromSeq {
+			break
+		}
+
+		if more, err := add(c, v); err != nil {
+			return nil, err
+		} else if !more {
+			break
+		}
+	}
+
+	return txns, nil
+}
+
+// GetTransactionCursors returns the confirmed transactions selected by q, in the order of q.
+// If addrs is not empty, only the transactions that touch any of the addresses are returned.
+func (hd HistoryDB) GetTransactionCursors(tx *dbutil.Tx, addrs []cipher.Address, q TxnCursorQuery) ([]CursorTxn, error) {
+	if q.Limit <= 0 || q.FromSeq > q.ToSeq {
+		return nil, nil
+	}
+
+	if len(addrs) == 0 {
+		return walkTxnCursors(tx, TxnCursorsBkt, nil, q)
+	}
+
+	// Each address contributes at most q.Limit transactions to the merged page
+	var txns []CursorTxn
+	for _, addr := range addrs {
+		addrTxns, err := walkTxnCursors(tx, AddressTxnCursorsBkt, addr.Bytes(), q)
+		if err != nil {
+			return nil, err
+		}
+
+		txns = append(txns, addrTxns...)
+	}
+
+	sort.Slice(txns, func(i, j int) bool {
+		if q.Desc {
+			return txns[j].Cursor.Less(txns[i].Cursor)
+		}
+		return txns[i].Cursor.Less(txns[j].Cursor)
+	})
+
+	// A transaction that touches several of the addresses is found once for each of them
+	merged := txns[:0]
+	for _, t := range txns {
+		if len(merged) != 0 && t.Cursor == merged[len(merged)-1].Cursor {
+			continue
+		}
+		merged = append(merged, t)
+	}
+
+	if len(merged) > q.Limit {
+		merged = merged[:q.Limit]
+	}
+
+	return merged, nil
+}
@@ -0,0 +1,277 @@
+package historydb
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestParseTxnCursor(t *testing.T) {
+	c := TxnCursor{
+		BlockSeq: 123456,
+		TxnIndex: 7,
+	}
+
+	parsed, err := ParseTxnCursor(c.String())
+	require.NoError(t, err)
+	require.Equal(t, c, parsed)
+
+	_, err = ParseTxnCursor("")
+	require.Equal(t, ErrInvalidTxnCursor, err)
+
+	_, err = ParseTxnCursor("!!")
+	require.Equal(t, ErrInvalidTxnCursor, err)
+
+	_, err = ParseTxnCursor(c.String() + "AA")
+	require.Equal(t, ErrInvalidTxnCursor, err)
+
+	// Cursors sort in blockchain order
+	require.True(t, TxnCursor{BlockSeq: 1, TxnIndex: 5}.Less(TxnCursor{BlockSeq: 2}))
+	require.True(t, TxnCursor{BlockSeq: 2}.Less(TxnCursor{BlockSeq: 2, TxnIndex: 1}))
+	require.False(t, c.Less(c))
+}
+
+func TestGetTransactionCursors(t *testing.T) {
+	db, teardown := prepareDB(t)
+	defer teardown()
+
+	hisDB := New()
+
+	addr1 := testutil.MakeAddress()
+	addr2 := testutil.MakeAddress()
+	addr3 := testutil.MakeAddress()
+
+	// Transactions of blocks 1 to 5, the first ones of blocks 2 and 4 touch both addresses
+	var all []CursorTxn
+	addrTxns := map[cipher.Address][]CursorTxn{}
+	for seq := uint64(1); seq <= 5; seq++ {
+		for i := uint32(0); i < 2; i++ {
+			ct := CursorTxn{
+				Cursor: TxnCursor{
+					BlockSeq: seq,
+					TxnIndex: i,
+				},
+				Hash: testutil.RandSHA256(t),
+			}
+			all = append(all, ct)
+
+			addrs := []cipher.Address{addr1}
+			if i == 1 {
+				addrs = []cipher.Address{addr2}
+			}
+			if i == 0 && seq%2 == 0 {
+				addrs = append(addrs, addr2)
+			}
+
+			for _, a := range addrs {
+				addrTxns[a] = append(addrTxns[a], ct)
+			}
+		}
+	}
+
+	err := db.Update("", func(tx *dbutil.Tx) error {
+		for _, ct := range all {
+			if err := hisDB.txnCursors.put(tx, ct.Cursor, ct.Hash); err != nil {
+				return err
+			}
+		}
+
+		for a, txns := range addrTxns {
+			for _, ct := range txns {
+				if err := hisDB.addrTxnCursors.put(tx, a, ct.Cursor, ct.Hash); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	reverse := func(txns []CursorTxn) []CursorTxn {
+		r := make([]CursorTxn, len(txns))
+		for i, t := range txns {
+			r[len(txns)-1-i] = t
+		}
+		return r
+	}
+
+	cursor := func(seq uint64, i uint32) *TxnCursor {
+		return &TxnCursor{
+			BlockSeq: seq,
+			TxnIndex: i,
+		}
+	}
+
+	cases := []struct {
+		name   string
+		addrs  []cipher.Address
+		q      TxnCursorQuery
+		expect []CursorTxn
+	}{
+		{
+			name: "all",
+			q: TxnCursorQuery{
+				ToSeq: 10,
+				Limit: 100,
+			},
+			expect: all,
+		},
+		{
+			name: "all desc",
+			q: TxnCursorQuery{
+				ToSeq: 10,
+				Desc:  true,
+				Limit: 100,
+			},
+			expect: reverse(all),
+		},
+		{
+			name: "limit",
+			q: TxnCursorQuery{
+				ToSeq: 10,
+				Limit: 3,
+			},
+			expect: all[:3],
+		},
+		{
+			name: "after",
+			q: TxnCursorQuery{
+				After: cursor(2, 0),
+				ToSeq: 10,
+				Limit: 3,
+			},
+			expect: all[3:6],
+		},
+		{
+			name: "after desc",
+			q: TxnCursorQuery{
+				After: cursor(2, 0),
+				ToSeq: 10,
+				Desc:  true,
+				Limit: 100,
+			},
+			expect: reverse(all[:2]),
+		},
+		{
+			name: "seq range",
+			q: TxnCursorQuery{
+				FromSeq: 2,
+				ToSeq:   3,
+				Limit:   100,
+			},
+			expect: all[2:6],
+		},
+		{
+			name: "seq range desc",
+			q: TxnCursorQuery{
+				FromSeq: 2,
+				ToSeq:   3,
+				Desc:    true,
+				Limit:   100,
+			},
+			expect: reverse(all[2:6]),
+		},
+		{
+			name: "after before the seq range",
+			q: TxnCursorQuery{
+				After:   cursor(1, 1),
+				FromSeq: 3,
+				ToSeq:   10,
+				Limit:   2,
+			},
+			expect: all[4:6],
+		},
+		{
+			name: "after past the seq range",
+			q: TxnCursorQuery{
+				After:   cursor(4, 0),
+				FromSeq: 1,
+				ToSeq:   3,
+				Limit:   100,
+			},
+		},
+		{
+			name: "empty seq range",
+			q: TxnCursorQuery{
+				FromSeq: 3,
+				ToSeq:   2,
+				Limit:   100,
+			},
+		},
+		{
+			name:  "address",
+			addrs: []cipher.Address{addr1},
+			q: TxnCursorQuery{
+				ToSeq: 10,
+				Limit: 100,
+			},
+			expect: addrTxns[addr1],
+		},
+		{
+			name:  "address desc after",
+			addrs: []cipher.Address{addr1},
+			q: TxnCursorQuery{
+				After: cursor(3, 0),
+				ToSeq: 10,
+				Desc:  true,
+				Limit: 100,
+			},
+			expect: reverse(addrTxns[addr1][:2]),
+		},
+		{
+			name:  "last address desc",
+			addrs: []cipher.Address{addr2},
+			q: TxnCursorQuery{
+				ToSeq: 10,
+				Desc:  true,
+				Limit: 2,
+			},
+			expect: reverse(addrTxns[addr2])[:2],
+		},
+		{
+			name:  "addresses are merged",
+			addrs: []cipher.Address{addr2, addr1},
+			q: TxnCursorQuery{
+				ToSeq: 10,
+				Limit: 100,
+			},
+			expect: all,
+		},
+		{
+			name:  "addresses are merged desc with limit",
+			addrs: []cipher.Address{addr1, addr2},
+			q: TxnCursorQuery{
+				FromSeq: 2,
+				ToSeq:   4,
+				Desc:    true,
+				Limit:   4,
+			},
+			expect: reverse(all[4:8]),
+		},
+		{
+			name:  "unknown address",
+			addrs: []cipher.Address{addr3},
+			q: TxnCursorQuery{
+				ToSeq: 10,
+				Limit: 100,
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := db.View("", func(tx *dbutil.Tx) error {
+				txns, err := hisDB.GetTransactionCursors(tx, tc.addrs, tc.q)
+				require.NoError(t, err)
+				require.Equal(t, tc.expect, txns)
+				return nil
+			})
+			require.NoError(t, err)
+		})
+	}
+}
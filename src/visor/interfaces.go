@@ -1,13 +1,13 @@
 package visor
 
 import (
+	"github.com/ness-network/ness/src/visor/blockdb"
+	"github.com/ness-network/ness/src/visor/historydb"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/transaction"
-	"github.com/skycoin/skycoin/src/visor/blockdb"
 	"github.com/skycoin/skycoin/src/visor/dbutil"
-	"github.com/skycoin/skycoin/src/visor/historydb"
 )
 
 //go:generate mockery -name Historyer -case underscore -inpkg -testonly
@@ -18,10 +18,13 @@ import (
 type Historyer interface {
 	GetUxOuts(tx *dbutil.Tx, uxids []cipher.SHA256) ([]historydb.UxOut, error)
 	ParseBlock(tx *dbutil.Tx, b coin.Block) error
+	RollbackBlock(tx *dbutil.Tx, b coin.Block) error
 	GetTransaction(tx *dbutil.Tx, hash cipher.SHA256) (*historydb.Transaction, error)
 	GetTransactionsNum(tx *dbutil.Tx) (uint64, error)
 	GetOutputsForAddress(tx *dbutil.Tx, address cipher.Address) ([]historydb.UxOut, error)
 	GetTransactionHashesForAddresses(tx *dbutil.Tx, addresses []cipher.Address) ([]cipher.SHA256, error)
+	GetTransactionCursors(tx *dbutil.Tx, addresses []cipher.Address, q historydb.TxnCursorQuery) ([]historydb.CursorTxn, error)
+	GetAddressBalanceDeltas(tx *dbutil.Tx, address cipher.Address, fromSeq, toSeq uint64) ([]historydb.AddressBalanceDelta, error)
 	AddressSeen(tx *dbutil.Tx, address cipher.Address) (bool, error)
 	NeedsReset(tx *dbutil.Tx) (bool, error)
 	Erase(tx *dbutil.Tx) error
@@ -44,7 +47,16 @@ type Blockchainer interface {
 	Time(tx *dbutil.Tx) (uint64, error)
 	NewBlock(tx *dbutil.Tx, txns coin.Transactions, currentTime uint64) (*coin.Block, error)
 	ExecuteBlock(tx *dbutil.Tx, sb *coin.SignedBlock) error
+	AddSideBlock(tx *dbutil.Tx, sb *coin.SignedBlock) error
+	ApplySideBlock(tx *dbutil.Tx, sb *coin.SignedBlock) error
+	RollbackHead(tx *dbutil.Tx, spent coin.UxArray) error
 	VerifyBlock(tx *dbutil.Tx, sb *coin.SignedBlock) error
+	VerifySignature(tx *dbutil.Tx, block *coin.SignedBlock) error
+	AddKeyCheckpoint(tx *dbutil.Tx, kc blockdb.KeyCheckpoint) (bool, error)
+	GetKeyCheckpoints(tx *dbutil.Tx) ([]blockdb.KeyCheckpoint, error)
+	AddBlockCertificate(tx *dbutil.Tx, hash cipher.SHA256, sigs []cipher.Sig) error
+	GetBlockCertificate(tx *dbutil.Tx, hash cipher.SHA256) (*blockdb.BlockCertificate, error)
+	BlockchainPubkeys(tx *dbutil.Tx) ([]cipher.PubKey, error)
 	VerifyBlockTxnConstraints(tx *dbutil.Tx, txn coin.Transaction) error
 	VerifySingleTxnHardConstraints(tx *dbutil.Tx, txn coin.Transaction, signed transaction.TxnSignedFlag) error
 	VerifySingleTxnSoftHardConstraints(tx *dbutil.Tx, txn coin.Transaction, distParams params.Distribution, verifyParams params.VerifyTxn, signed transaction.TxnSignedFlag) (*coin.SignedBlock, coin.UxArray, error)
@@ -70,4 +82,5 @@ type UnconfirmedTransactionPooler interface {
 	ForEach(tx *dbutil.Tx, f func(cipher.SHA256, UnconfirmedTransaction) error) error
 	GetUnspentsOfAddr(tx *dbutil.Tx, addr cipher.Address) (coin.UxArray, error)
 	Len(tx *dbutil.Tx) (uint64, error)
+	MinFee(tx *dbutil.Tx, size uint32) (UnconfirmedTxnFee, error)
 }
@@ -3,8 +3,8 @@
 package visor
 
 import (
+	blockdb "github.com/ness-network/ness/src/visor/blockdb"
 	cipher "github.com/skycoin/skycoin/src/cipher"
-	blockdb "github.com/skycoin/skycoin/src/visor/blockdb"
 
 	coin "github.com/skycoin/skycoin/src/coin"
 
@@ -22,6 +22,92 @@ type MockBlockchainer struct {
 	mock.Mock
 }
 
+// AddBlockCertificate provides a mock function with given fields: tx, hash, sigs
+func (_m *MockBlockchainer) AddBlockCertificate(tx *dbutil.Tx, hash cipher.SHA256, sigs []cipher.Sig) error {
+	ret := _m.Called(tx, hash, sigs)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, cipher.SHA256, []cipher.Sig) error); ok {
+		r0 = rf(tx, hash, sigs)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// AddKeyCheckpoint provides a mock function with given fields: tx, kc
+func (_m *MockBlockchainer) AddKeyCheckpoint(tx *dbutil.Tx, kc blockdb.KeyCheckpoint) (bool, error) {
+	ret := _m.Called(tx, kc)
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, blockdb.KeyCheckpoint) bool); ok {
+		r0 = rf(tx, kc)
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, blockdb.KeyCheckpoint) error); ok {
+		r1 = rf(tx, kc)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AddSideBlock provides a mock function with given fields: tx, sb
+func (_m *MockBlockchainer) AddSideBlock(tx *dbutil.Tx, sb *coin.SignedBlock) error {
+	ret := _m.Called(tx, sb)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, *coin.SignedBlock) error); ok {
+		r0 = rf(tx, sb)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// ApplySideBlock provides a mock function with given fields: tx, sb
+func (_m *MockBlockchainer) ApplySideBlock(tx *dbutil.Tx, sb *coin.SignedBlock) error {
+	ret := _m.Called(tx, sb)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, *coin.SignedBlock) error); ok {
+		r0 = rf(tx, sb)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// BlockchainPubkeys provides a mock function with given fields: tx
+func (_m *MockBlockchainer) BlockchainPubkeys(tx *dbutil.Tx) ([]cipher.PubKey, error) {
+	ret := _m.Called(tx)
+
+	var r0 []cipher.PubKey
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx) []cipher.PubKey); ok {
+		r0 = rf(tx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]cipher.PubKey)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx) error); ok {
+		r1 = rf(tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ExecuteBlock provides a mock function with given fields: tx, sb
 func (_m *MockBlockchainer) ExecuteBlock(tx *dbutil.Tx, sb *coin.SignedBlock) error {
 	ret := _m.Called(tx, sb)
@@ -36,6 +122,29 @@ func (_m *MockBlockchainer) ExecuteBlock(tx *dbutil.Tx, sb *coin.SignedBlock) er
 	return r0
 }
 
+// GetBlockCertificate provides a mock function with given fields: tx, hash
+func (_m *MockBlockchainer) GetBlockCertificate(tx *dbutil.Tx, hash cipher.SHA256) (*blockdb.BlockCertificate, error) {
+	ret := _m.Called(tx, hash)
+
+	var r0 *blockdb.BlockCertificate
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, cipher.SHA256) *blockdb.BlockCertificate); ok {
+		r0 = rf(tx, hash)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*blockdb.BlockCertificate)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, cipher.SHA256) error); ok {
+		r1 = rf(tx, hash)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetBlocks provides a mock function with given fields: tx, seqs
 func (_m *MockBlockchainer) GetBlocks(tx *dbutil.Tx, seqs []uint64) ([]coin.SignedBlock, error) {
 	ret := _m.Called(tx, seqs)
@@ -105,6 +214,29 @@ func (_m *MockBlockchainer) GetGenesisBlock(tx *dbutil.Tx) (*coin.SignedBlock, e
 	return r0, r1
 }
 
+// GetKeyCheckpoints provides a mock function with given fields: tx
+func (_m *MockBlockchainer) GetKeyCheckpoints(tx *dbutil.Tx) ([]blockdb.KeyCheckpoint, error) {
+	ret := _m.Called(tx)
+
+	var r0 []blockdb.KeyCheckpoint
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx) []blockdb.KeyCheckpoint); ok {
+		r0 = rf(tx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]blockdb.KeyCheckpoint)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx) error); ok {
+		r1 = rf(tx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetLastBlocks provides a mock function with given fields: tx, n
 func (_m *MockBlockchainer) GetLastBlocks(tx *dbutil.Tx, n uint64) ([]coin.SignedBlock, error) {
 	ret := _m.Called(tx, n)
@@ -269,6 +401,20 @@ func (_m *MockBlockchainer) NewBlock(tx *dbutil.Tx, txns coin.Transactions, curr
 	return r0, r1
 }
 
+// RollbackHead provides a mock function with given fields: tx, spent
+func (_m *MockBlockchainer) RollbackHead(tx *dbutil.Tx, spent coin.UxArray) error {
+	ret := _m.Called(tx, spent)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, coin.UxArray) error); ok {
+		r0 = rf(tx, spent)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Time provides a mock function with given fields: tx
 func (_m *MockBlockchainer) Time(tx *dbutil.Tx) (uint64, error) {
 	ret := _m.Called(tx)
@@ -350,6 +496,20 @@ func (_m *MockBlockchainer) VerifyBlockTxnConstraints(tx *dbutil.Tx, txn coin.Tr
 	return r0
 }
 
+// VerifySignature provides a mock function with given fields: tx, block
+func (_m *MockBlockchainer) VerifySignature(tx *dbutil.Tx, block *coin.SignedBlock) error {
+	ret := _m.Called(tx, block)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, *coin.SignedBlock) error); ok {
+		r0 = rf(tx, block)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // VerifySingleTxnHardConstraints provides a mock function with given fields: tx, txn, signed
 func (_m *MockBlockchainer) VerifySingleTxnHardConstraints(tx *dbutil.Tx, txn coin.Transaction, signed transaction.TxnSignedFlag) error {
 	ret := _m.Called(tx, txn, signed)
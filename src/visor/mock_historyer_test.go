@@ -8,7 +8,7 @@ import (
 
 	dbutil "github.com/skycoin/skycoin/src/visor/dbutil"
 
-	historydb "github.com/skycoin/skycoin/src/visor/historydb"
+	historydb "github.com/ness-network/ness/src/visor/historydb"
 
 	mock "github.com/stretchr/testify/mock"
 )
@@ -67,6 +67,29 @@ func (_m *MockHistoryer) ForEachTxn(tx *dbutil.Tx, f func(cipher.SHA256, *histor
 	return r0
 }
 
+// GetAddressBalanceDeltas provides a mock function with given fields: tx, address, fromSeq, toSeq
+func (_m *MockHistoryer) GetAddressBalanceDeltas(tx *dbutil.Tx, address cipher.Address, fromSeq uint64, toSeq uint64) ([]historydb.AddressBalanceDelta, error) {
+	ret := _m.Called(tx, address, fromSeq, toSeq)
+
+	var r0 []historydb.AddressBalanceDelta
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, cipher.Address, uint64, uint64) []historydb.AddressBalanceDelta); ok {
+		r0 = rf(tx, address, fromSeq, toSeq)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]historydb.AddressBalanceDelta)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, cipher.Address, uint64, uint64) error); ok {
+		r1 = rf(tx, address, fromSeq, toSeq)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetOutputsForAddress provides a mock function with given fields: tx, address
 func (_m *MockHistoryer) GetOutputsForAddress(tx *dbutil.Tx, address cipher.Address) ([]historydb.UxOut, error) {
 	ret := _m.Called(tx, address)
@@ -113,6 +136,29 @@ func (_m *MockHistoryer) GetTransaction(tx *dbutil.Tx, hash cipher.SHA256) (*his
 	return r0, r1
 }
 
+// GetTransactionCursors provides a mock function with given fields: tx, addresses, q
+func (_m *MockHistoryer) GetTransactionCursors(tx *dbutil.Tx, addresses []cipher.Address, q historydb.TxnCursorQuery) ([]historydb.CursorTxn, error) {
+	ret := _m.Called(tx, addresses, q)
+
+	var r0 []historydb.CursorTxn
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, []cipher.Address, historydb.TxnCursorQuery) []historydb.CursorTxn); ok {
+		r0 = rf(tx, addresses, q)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]historydb.CursorTxn)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, []cipher.Address, historydb.TxnCursorQuery) error); ok {
+		r1 = rf(tx, addresses, q)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetTransactionHashesForAddresses provides a mock function with given fields: tx, addresses
 func (_m *MockHistoryer) GetTransactionHashesForAddresses(tx *dbutil.Tx, addresses []cipher.Address) ([]cipher.SHA256, error) {
 	ret := _m.Called(tx, addresses)
@@ -242,3 +288,17 @@ func (_m *MockHistoryer) ParsedBlockSeq(tx *dbutil.Tx) (uint64, bool, error) {
 
 	return r0, r1, r2
 }
+
+// RollbackBlock provides a mock function with given fields: tx, b
+func (_m *MockHistoryer) RollbackBlock(tx *dbutil.Tx, b coin.Block) error {
+	ret := _m.Called(tx, b)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, coin.Block) error); ok {
+		r0 = rf(tx, b)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -269,6 +269,27 @@ func (_m *MockUnconfirmedTransactionPooler) Len(tx *dbutil.Tx) (uint64, error) {
 	return r0, r1
 }
 
+// MinFee provides a mock function with given fields: tx, size
+func (_m *MockUnconfirmedTransactionPooler) MinFee(tx *dbutil.Tx, size uint32) (UnconfirmedTxnFee, error) {
+	ret := _m.Called(tx, size)
+
+	var r0 UnconfirmedTxnFee
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, uint32) UnconfirmedTxnFee); ok {
+		r0 = rf(tx, size)
+	} else {
+		r0 = ret.Get(0).(UnconfirmedTxnFee)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*dbutil.Tx, uint32) error); ok {
+		r1 = rf(tx, size)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RecvOfAddresses provides a mock function with given fields: tx, bh, addrs
 func (_m *MockUnconfirmedTransactionPooler) RecvOfAddresses(tx *dbutil.Tx, bh coin.BlockHeader, addrs []cipher.Address) (coin.AddressUxOuts, error) {
 	ret := _m.Called(tx, bh, addrs)
@@ -8,7 +8,7 @@ import (
 
 	mock "github.com/stretchr/testify/mock"
 
-	"github.com/skycoin/skycoin/src/visor/blockdb"
+	"github.com/ness-network/ness/src/visor/blockdb"
 	dbutil "github.com/skycoin/skycoin/src/visor/dbutil"
 )
 
@@ -245,3 +245,59 @@ func (_m *MockUnspentPooler) ProcessBlock(_a0 *dbutil.Tx, _a1 *coin.SignedBlock)
 
 	return r0
 }
+
+// RebuildAddressBalances provides a mock function with given fields: _a0
+func (_m *MockUnspentPooler) RebuildAddressBalances(_a0 *dbutil.Tx) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// RollbackBlock provides a mock function with given fields: _a0, _a1, _a2
+func (_m *MockUnspentPooler) RollbackBlock(_a0 *dbutil.Tx, _a1 *coin.SignedBlock, _a2 coin.UxArray) error {
+	ret := _m.Called(_a0, _a1, _a2)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, *coin.SignedBlock, coin.UxArray) error); ok {
+		r0 = rf(_a0, _a1, _a2)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// VerifyAddressBalances provides a mock function with given fields: _a0
+func (_m *MockUnspentPooler) VerifyAddressBalances(_a0 *dbutil.Tx) error {
+	ret := _m.Called(_a0)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx) error); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// WalkAddressBalances provides a mock function with given fields: _a0, _a1
+func (_m *MockUnspentPooler) WalkAddressBalances(_a0 *dbutil.Tx, _a1 func(blockdb.AddressBalance) (bool, error)) error {
+	ret := _m.Called(_a0, _a1)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(*dbutil.Tx, func(blockdb.AddressBalance) (bool, error)) error); ok {
+		r0 = rf(_a0, _a1)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -3,6 +3,7 @@ package visor
 import (
 	"time"
 
+	"github.com/ness-network/ness/src/visor/blockdb"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/transaction"
 )
@@ -128,14 +129,17 @@ type BlockchainMetadata struct {
 	Unspents uint64
 	// Number of known unconfirmed txns
 	Unconfirmed uint64
+	// Block publisher key checkpoints, ordered by seq
+	KeyCheckpoints []blockdb.KeyCheckpoint
 }
 
 // NewBlockchainMetadata creates blockchain meta data
-func NewBlockchainMetadata(head coin.SignedBlock, unconfirmedLen, unspentsLen uint64) (*BlockchainMetadata, error) {
+func NewBlockchainMetadata(head coin.SignedBlock, unconfirmedLen, unspentsLen uint64, keyCheckpoints []blockdb.KeyCheckpoint) (*BlockchainMetadata, error) {
 	return &BlockchainMetadata{
-		HeadBlock:   head,
-		Unspents:    unspentsLen,
-		Unconfirmed: unconfirmedLen,
+		HeadBlock:      head,
+		Unspents:       unspentsLen,
+		Unconfirmed:    unconfirmedLen,
+		KeyCheckpoints: keyCheckpoints,
 	}, nil
 }
 
@@ -34,22 +34,26 @@ func NewRichlist(allAccounts map[cipher.Address]uint64, lockedAddrs map[cipher.A
 		})
 	}
 
-	// Sort order:
-	// Higher coins
-	// Locked > unlocked
-	// Address bytes
-	sort.Slice(richlist, func(i, j int) bool {
-		if richlist[i].Coins == richlist[j].Coins {
-			if richlist[i].Locked == richlist[j].Locked {
-				return bytes.Compare(richlist[i].Address.Bytes(), richlist[j].Address.Bytes()) < 0
+	richlist.sortBalances()
+
+	return richlist, nil
+}
+
+// sortBalances sorts the richlist by:
+// Higher coins
+// Locked > unlocked
+// Address bytes
+func (r Richlist) sortBalances() {
+	sort.Slice(r, func(i, j int) bool {
+		if r[i].Coins == r[j].Coins {
+			if r[i].Locked == r[j].Locked {
+				return bytes.Compare(r[i].Address.Bytes(), r[j].Address.Bytes()) < 0
 			}
-			return richlist[i].Locked
+			return r[i].Locked
 		}
 
-		return richlist[i].Coins > richlist[j].Coins
+		return r[i].Coins > r[j].Coins
 	})
-
-	return richlist, nil
 }
 
 // FilterAddresses returns the richlist without addresses from the map
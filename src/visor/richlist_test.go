@@ -1,11 +1,19 @@
 package visor
 
 import (
+	"bytes"
+	"errors"
+	"sort"
 	"testing"
 
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ness-network/ness/src/visor/blockdb"
 	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
 )
 
 func getLockedMap(distributionAddresses [4]cipher.Address) map[cipher.Address]struct{} {
@@ -122,3 +130,139 @@ func TestRichlist(t *testing.T) {
 		})
 	}
 }
+
+func TestGetRichlist(t *testing.T) {
+	var otherAddresses, distributionAddresses [4]cipher.Address
+	for i := range otherAddresses {
+		otherAddresses[i] = testutil.MakeAddress()
+		distributionAddresses[i] = testutil.MakeAddress()
+	}
+
+	distribution := params.Distribution{
+		MaxCoinSupply:        4e6,
+		InitialUnlockedCount: 1,
+	}
+	for _, a := range distributionAddresses {
+		distribution.Addresses = append(distribution.Addresses, a.String())
+	}
+
+	// The balances in the order of the address balance index
+	accMap := getAllAccounts(distributionAddresses, otherAddresses)
+	var balances []blockdb.AddressBalance
+	for addr, coins := range accMap {
+		balances = append(balances, blockdb.AddressBalance{
+			Address: addr,
+			Coins:   coins,
+		})
+	}
+	sort.Slice(balances, func(i, j int) bool {
+		if balances[i].Coins == balances[j].Coins {
+			return bytes.Compare(balances[i].Address.Bytes(), balances[j].Address.Bytes()) < 0
+		}
+		return balances[i].Coins > balances[j].Coins
+	})
+
+	// distributionAddresses[0] is unlocked
+	lockedMap := getLockedMap(distributionAddresses)
+	delete(lockedMap, distributionAddresses[0])
+
+	fullRichlist, err := NewRichlist(accMap, lockedMap)
+	require.NoError(t, err)
+
+	cases := []struct {
+		name                string
+		includeDistribution bool
+		n                   int
+		walkErr             error
+		walked              int
+		result              Richlist
+		err                 error
+	}{
+		{
+			name:                "all",
+			includeDistribution: true,
+			walked:              8,
+			result:              fullRichlist,
+		},
+		{
+			name:                "top n with ties walks through the balances equal to the nth balance",
+			includeDistribution: true,
+			n:                   5,
+			walked:              8,
+			result:              fullRichlist[:5],
+		},
+		{
+			name:   "without distribution addresses",
+			walked: 8,
+			result: Richlist{
+				RichlistBalance{Address: otherAddresses[2], Coins: 4010000},
+				RichlistBalance{Address: otherAddresses[0], Coins: 3010000},
+				RichlistBalance{Address: otherAddresses[1], Coins: 2010000},
+				RichlistBalance{Address: otherAddresses[3], Coins: 1000000},
+			},
+		},
+		{
+			name:   "top n without distribution addresses stops the walk",
+			n:      2,
+			walked: 3,
+			result: Richlist{
+				RichlistBalance{Address: otherAddresses[2], Coins: 4010000},
+				RichlistBalance{Address: otherAddresses[0], Coins: 3010000},
+			},
+		},
+		{
+			name:    "walk error",
+			walkErr: errors.New("walk failed"),
+			err:     errors.New("walk failed"),
+		},
+	}
+
+	matchDBTx := mock.MatchedBy(func(tx *dbutil.Tx) bool {
+		return true
+	})
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, shutdown := testutil.PrepareDB(t)
+			defer shutdown()
+
+			bc := &MockBlockchainer{}
+			unspent := &MockUnspentPooler{}
+			bc.On("Unspent").Return(unspent)
+
+			var walked int
+			unspent.On("WalkAddressBalances", matchDBTx, mock.Anything).Return(func(tx *dbutil.Tx, f func(blockdb.AddressBalance) (bool, error)) error {
+				if tc.walkErr != nil {
+					return tc.walkErr
+				}
+
+				for _, b := range balances {
+					walked++
+					if more, err := f(b); err != nil {
+						return err
+					} else if !more {
+						return nil
+					}
+				}
+				return nil
+			})
+
+			v := &Visor{
+				blockchain: bc,
+				db:         db,
+				Config: Config{
+					Distribution: distribution,
+				},
+			}
+
+			richlist, err := v.GetRichlist(tc.includeDistribution, tc.n)
+			require.Equal(t, tc.err, err)
+			if err != nil {
+				return
+			}
+
+			require.Equal(t, tc.result, richlist)
+			require.Equal(t, tc.walked, walked)
+		})
+	}
+}
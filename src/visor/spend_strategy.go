@@ -0,0 +1,124 @@
+package visor
+
+// This file contains transaction creation with a spend strategy
+
+import (
+	"errors"
+	"fmt"
+
+	nesstransaction "github.com/ness-network/ness/src/transaction"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/transaction"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// ErrInvalidSpendStrategy the spend strategy is not one of the supported spend strategies
+var ErrInvalidSpendStrategy = NewUserError(errors.New("Invalid spend strategy"))
+
+// validateSpendStrategy checks that spendStrategy is empty or a supported spend strategy
+func validateSpendStrategy(spendStrategy string) error {
+	switch spendStrategy {
+	case "", nesstransaction.SpendStrategyMinimizeUxOuts, nesstransaction.SpendStrategyPrivacy:
+		return nil
+	default:
+		return ErrInvalidSpendStrategy
+	}
+}
+
+// nessTransactionErrors maps the errors of the ness transaction package to the equivalent errors
+// of the skycoin transaction package, which callers compare errors against
+var nessTransactionErrors = map[error]error{
+	nesstransaction.ErrInsufficientBalance: transaction.ErrInsufficientBalance,
+	nesstransaction.ErrInsufficientHours:   transaction.ErrInsufficientHours,
+	nesstransaction.ErrZeroSpend:           transaction.ErrZeroSpend,
+	nesstransaction.ErrNoUnspents:          transaction.ErrNoUnspents,
+}
+
+// createTransaction creates an unsigned transaction, choosing the outputs to spend with spendStrategy.
+// The skycoin transaction package only has the minimize uxouts strategy, so the transaction is created
+// by the ness transaction package.
+func createTransaction(p transaction.Params, spendStrategy string, auxs coin.AddressUxOuts, headTime uint64) (*coin.Transaction, []transaction.UxBalance, error) {
+	np := nesstransaction.Params{
+		HoursSelection: nesstransaction.HoursSelection{
+			Type:        p.HoursSelection.Type,
+			Mode:        p.HoursSelection.Mode,
+			ShareFactor: p.HoursSelection.ShareFactor,
+		},
+		To:            p.To,
+		ChangeAddress: p.ChangeAddress,
+		SpendStrategy: spendStrategy,
+	}
+
+	txn, nuxb, err := nesstransaction.Create(np, auxs, headTime)
+	if err != nil {
+		if e, ok := nessTransactionErrors[err]; ok {
+			return nil, nil, e
+		}
+		if _, ok := err.(nesstransaction.Error); ok {
+			return nil, nil, transaction.NewError(err)
+		}
+		return nil, nil, err
+	}
+
+	uxb := make([]transaction.UxBalance, len(nuxb))
+	for i, ux := range nuxb {
+		uxb[i] = transaction.UxBalance(ux)
+	}
+
+	return txn, uxb, nil
+}
+
+// createWalletTransaction creates a transaction spending outputs of the wallet entries selected by options,
+// choosing the outputs to spend with spendStrategy, and signs it with wallet.SignTransaction if signed is
+// transaction.TxnSigned. wallet.CreateTransaction and wallet.CreateTransactionSigned have no spend strategy,
+// and look up the entries of account 0 only for bip44 wallets.
+func createWalletTransaction(w wallet.Wallet, options []wallet.Option, p transaction.Params, spendStrategy string,
+	auxs coin.AddressUxOuts, headTime uint64, signed transaction.TxnSignedFlag) (*coin.Transaction, []transaction.UxBalance, error) {
+	if p.ChangeAddress == nil && w.Type() == wallet.WalletTypeBip44 {
+		return nil, nil, errors.New("change address must not be nil")
+	}
+
+	// Check that auxs does not contain addresses that are not known to the selected entries
+	for a := range auxs {
+		has, err := w.HasEntry(a, options...)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !has {
+			return nil, nil, fmt.Errorf("Address %s from auxs not found in wallet", a)
+		}
+	}
+
+	txn, uxb, err := createTransaction(p, spendStrategy, auxs, headTime)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if signed != transaction.TxnSigned {
+		return txn, uxb, nil
+	}
+
+	uxOuts := make(map[cipher.SHA256]coin.UxOut)
+	for _, uxs := range auxs {
+		for _, ux := range uxs {
+			uxOuts[ux.Hash()] = ux
+		}
+	}
+
+	inputs := make([]coin.UxOut, len(txn.In))
+	for i, h := range txn.In {
+		inputs[i] = uxOuts[h]
+	}
+
+	signedTxn, err := wallet.SignTransaction(signingWallet(w), txn, nil, inputs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := transaction.VerifyCreatedInvariants(p, signedTxn, uxb); err != nil {
+		return nil, nil, err
+	}
+
+	return signedTxn, uxb, nil
+}
@@ -0,0 +1,173 @@
+package visor
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ness-network/ness/src/visor/historydb"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/crypto"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/transaction"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func TestWalletCreateTransactionSpendStrategy(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, BlockchainConfig{
+		Pubkey: genPublic,
+	})
+	require.NoError(t, err)
+
+	unconfirmed, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{})
+	require.NoError(t, err)
+
+	ws, err := wallet.NewService(wallet.Config{
+		EnableWalletAPI: true,
+		CryptoType:      crypto.CryptoTypeScryptChacha20poly1305Insecure,
+		WalletDir:       prepareWltDir(),
+	})
+	require.NoError(t, err)
+
+	cfg := NewConfig()
+	cfg.IsBlockPublisher = true
+	cfg.BlockchainPubkey = genPublic
+	cfg.BlockchainSeckey = genSecret
+	cfg.GenesisAddress = genAddress
+	cfg.Distribution = params.MainNetDistribution
+
+	v := &Visor{
+		Config:      cfg,
+		unconfirmed: unconfirmed,
+		blockchain:  bc,
+		db:          db,
+		history:     historydb.New(),
+		wallets:     ws,
+		tf:          mockTxnsFinder{},
+	}
+
+	gb := addGenesisBlockToVisor(t, v)
+
+	w, err := ws.CreateWallet("t.wlt", wallet.Options{
+		Label:     "test",
+		Coin:      wallet.CoinTypeSkycoin,
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "voyage say extend find sheriff surge priority merit ignore maple cash argue",
+		GenerateN: 2,
+	})
+	require.NoError(t, err)
+
+	addrs, err := w.GetAddresses()
+	require.NoError(t, err)
+	require.Len(t, addrs, 2)
+	addrA := addrs[0].(cipher.Address)
+	addrB := addrs[1].(cipher.Address)
+
+	// Address A has one output of 5 coins, address B has outputs of 4 and 2 coins
+	uxs := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])
+	var fundTxn coin.Transaction
+	require.NoError(t, fundTxn.PushInput(uxs[0].Hash()))
+	hours := uxs[0].Body.Hours / 8
+	require.NoError(t, fundTxn.PushOutput(addrA, 5e6, hours))
+	require.NoError(t, fundTxn.PushOutput(addrB, 4e6, hours))
+	require.NoError(t, fundTxn.PushOutput(addrB, 2e6, hours))
+	require.NoError(t, fundTxn.PushOutput(genAddress, uxs[0].Body.Coins-11e6, hours))
+	fundTxn.SignInputs([]cipher.SecKey{genSecret})
+	require.NoError(t, fundTxn.UpdateHeader())
+
+	b, err := v.CreateBlockFromTxns(coin.Transactions{fundTxn}, genTime+100)
+	require.NoError(t, err)
+	require.NoError(t, v.ExecuteSignedBlock(coin.SignedBlock{
+		Block: b,
+		Sig:   cipher.MustSignHash(b.HashHeader(), genSecret),
+	}))
+
+	shareFactor := decimal.New(5, -1)
+	p := transaction.Params{
+		HoursSelection: transaction.HoursSelection{
+			Type:        transaction.HoursSelectionTypeAuto,
+			Mode:        transaction.HoursSelectionModeShare,
+			ShareFactor: &shareFactor,
+		},
+		To: []coin.TransactionOutput{
+			{
+				Address: testutil.MakeAddress(),
+				Coins:   55e5,
+			},
+		},
+	}
+
+	inputAddresses := func(inputs []TransactionInput) []cipher.Address {
+		var addrs []cipher.Address
+		for _, in := range inputs {
+			addrs = append(addrs, in.UxOut.Body.Address)
+		}
+		return addrs
+	}
+
+	_, _, err = v.WalletCreateTransaction("t.wlt", p, CreateTransactionParams{
+		SpendStrategy: "foo",
+	})
+	require.Equal(t, ErrInvalidSpendStrategy, err)
+
+	// Spending 5.5 coins with the fewest outputs links address A and address B
+	for _, spendStrategy := range []string{"", "minimize_uxouts"} {
+		txn, inputs, err := v.WalletCreateTransactionSigned("t.wlt", nil, p, CreateTransactionParams{
+			SpendStrategy: spendStrategy,
+		})
+		require.NoError(t, err)
+		require.True(t, txn.IsFullySigned())
+		require.Equal(t, []cipher.Address{addrA, addrB}, inputAddresses(inputs))
+	}
+
+	// The privacy strategy spends all outputs of address B only
+	for _, signed := range []transaction.TxnSignedFlag{transaction.TxnUnsigned, transaction.TxnSigned} {
+		var txn *coin.Transaction
+		var inputs []TransactionInput
+		if signed == transaction.TxnSigned {
+			txn, inputs, err = v.WalletCreateTransactionSigned("t.wlt", nil, p, CreateTransactionParams{
+				SpendStrategy: "privacy",
+			})
+			require.NoError(t, err)
+			require.True(t, txn.IsFullySigned())
+		} else {
+			txn, inputs, err = v.WalletCreateTransaction("t.wlt", p, CreateTransactionParams{
+				SpendStrategy: "privacy",
+			})
+			require.NoError(t, err)
+			require.False(t, txn.IsFullySigned())
+		}
+
+		require.Equal(t, []cipher.Address{addrB, addrB}, inputAddresses(inputs))
+		require.Len(t, txn.Out, 2)
+		require.Equal(t, uint64(5e5), txn.Out[1].Coins)
+		require.Equal(t, addrB, txn.Out[1].Address)
+	}
+
+	// Not enough coins in the wallet
+	pTooMuch := p
+	pTooMuch.To = []coin.TransactionOutput{
+		{
+			Address: testutil.MakeAddress(),
+			Coins:   12e6,
+		},
+	}
+	_, _, err = v.WalletCreateTransaction("t.wlt", pTooMuch, CreateTransactionParams{
+		SpendStrategy: "privacy",
+	})
+	require.Equal(t, transaction.ErrInsufficientBalance, err)
+
+	// The strategy is also used for transactions not created by a wallet
+	_, inputs, err := v.CreateTransaction(p, CreateTransactionParams{
+		Addresses:     []cipher.Address{addrA, addrB},
+		SpendStrategy: "privacy",
+	})
+	require.NoError(t, err)
+	require.Equal(t, []cipher.Address{addrB, addrB}, inputAddresses(inputs))
+}
@@ -0,0 +1,231 @@
+package visor
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/ness-network/ness/src/visor/historydb"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+// TransactionsCursorQuery selects a page of confirmed transactions, walked in blockchain order
+type TransactionsCursorQuery struct {
+	// Addrs restricts the transactions to the ones that touch any of the addresses, if not empty
+	Addrs []cipher.Address
+	// After is the cursor returned with the previous page, nil for the first page
+	After *historydb.TxnCursor
+	// Order is the order of the walk, AscOrder if unknown
+	Order SortOrder
+	// FromSeq and ToSeq restrict the transactions to an inclusive range of block seqs, if not nil
+	FromSeq *uint64
+	ToSeq   *uint64
+	// FromTime and ToTime restrict the transactions to an inclusive range of block times, if not nil
+	FromTime *uint64
+	ToTime   *uint64
+	// Limit is the page size
+	Limit uint64
+}
+
+// GetTransactionsByCursor returns a page of confirmed transactions, and the cursor of the next page.
+// The cursor is nil if there are no more transactions.
+// Unlike GetTransactions, the page is read from the transaction cursors index without collecting
+// all the matching transactions, and pages do not shift when new blocks are executed.
+func (vs *Visor) GetTransactionsByCursor(q TransactionsCursorQuery) ([]Transaction, *historydb.TxnCursor, error) {
+	var txns []Transaction
+	var next *historydb.TxnCursor
+	if err := vs.db.View("GetTransactionsByCursor", func(tx *dbutil.Tx) error {
+		var err error
+		txns, next, err = vs.getTransactionsByCursor(tx, q)
+		return err
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	return txns, next, nil
+}
+
+// GetTransactionsByCursorWithInputs is the same as GetTransactionsByCursor but also returns verbose transaction input data
+func (vs *Visor) GetTransactionsByCursorWithInputs(q TransactionsCursorQuery) ([]Transaction, [][]TransactionInput, *historydb.TxnCursor, error) {
+	var txns []Transaction
+	var inputs [][]TransactionInput
+	var next *historydb.TxnCursor
+	if err := vs.db.View("GetTransactionsByCursorWithInputs", func(tx *dbutil.Tx) error {
+		var err error
+		txns, next, err = vs.getTransactionsByCursor(tx, q)
+		if err != nil {
+			return err
+		}
+
+		inputs = make([][]TransactionInput, len(txns))
+		for i, txn := range txns {
+			feeCalcTime, err := vs.getFeeCalcTimeForTransaction(tx, txn)
+			if err != nil {
+				return err
+			}
+			if feeCalcTime == nil {
+				continue
+			}
+
+			txnInputs, err := vs.getTransactionInputs(tx, *feeCalcTime, txn.Transaction.In)
+			if err != nil {
+				return err
+			}
+
+			inputs[i] = txnInputs
+		}
+
+		return nil
+	}); err != nil {
+		return nil, nil, nil, err
+	}
+
+	return txns, inputs, next, nil
+}
+
+func (vs *Visor) getTransactionsByCursor(tx *dbutil.Tx, q TransactionsCursorQuery) ([]Transaction, *historydb.TxnCursor, error) {
+	if q.Limit == 0 {
+		return nil, nil, ErrZeroPageSize
+	}
+
+	if q.Limit > MaxTxnPageSize {
+		return nil, nil, ErrMaxTxnPageSize
+	}
+
+	headSeq, ok, err := vs.blockchain.HeadSeq(tx)
+	if err != nil {
+		return nil, nil, err
+	} else if !ok {
+		return nil, nil, nil
+	}
+
+	fromSeq, toSeq, ok, err := vs.blockSeqRange(tx, headSeq, q.FromSeq, q.ToSeq, q.FromTime, q.ToTime)
+	if err != nil {
+		return nil, nil, err
+	} else if !ok {
+		return nil, nil, nil
+	}
+
+	hq := historydb.TxnCursorQuery{
+		After:   q.After,
+		FromSeq: fromSeq,
+		ToSeq:   toSeq,
+		Desc:    q.Order == DescOrder,
+		// One more transaction tells whether there is a next page
+		Limit: int(q.Limit) + 1,
+	}
+
+	cursorTxns, err := vs.history.GetTransactionCursors(tx, q.Addrs, hq)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var next *historydb.TxnCursor
+	if uint64(len(cursorTxns)) > q.Limit {
+		cursorTxns = cursorTxns[:q.Limit]
+		next = &cursorTxns[len(cursorTxns)-1].Cursor
+	}
+
+	ct := confirmedTxnsGetter{
+		transactionModel{
+			history:     vs.history,
+			unconfirmed: vs.unconfirmed,
+			blockchain:  vs.blockchain,
+		},
+	}
+
+	txns := make([]Transaction, len(cursorTxns))
+	for i, c := range cursorTxns {
+		txn, err := ct.getTransaction(tx, c.Hash)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		txns[i] = *txn
+	}
+
+	return txns, next, nil
+}
+
+// blockSeqRange returns the inclusive range of block seqs selected by the optional seq and time bounds,
+// up to headSeq. It returns false if no block is in the range.
+func (vs *Visor) blockSeqRange(tx *dbutil.Tx, headSeq uint64, fromSeq, toSeq, fromTime, toTime *uint64) (uint64, uint64, bool, error) {
+	from := uint64(0)
+	to := headSeq
+
+	if fromSeq != nil && *fromSeq > from {
+		from = *fromSeq
+	}
+
+	if toSeq != nil && *toSeq < to {
+		to = *toSeq
+	}
+
+	if fromTime != nil {
+		seq, err := vs.firstBlockSeqAtOrAfter(tx, headSeq, *fromTime)
+		if err != nil {
+			return 0, 0, false, err
+		}
+
+		// No block is that recent
+		if seq > headSeq {
+			return 0, 0, false, nil
+		}
+
+		if seq > from {
+			from = seq
+		}
+	}
+
+	if toTime != nil {
+		seq, err := vs.firstBlockSeqAtOrAfter(tx, headSeq, *toTime+1)
+		if err != nil {
+			return 0, 0, false, err
+		}
+
+		// No block is that old
+		if seq == 0 {
+			return 0, 0, false, nil
+		}
+
+		if seq-1 < to {
+			to = seq - 1
+		}
+	}
+
+	if from > to {
+		return 0, 0, false, nil
+	}
+
+	return from, to, true, nil
+}
+
+// firstBlockSeqAtOrAfter returns the seq of the first block whose time is not before t,
+// or headSeq+1 if there is none. Block times increase with the block seq.
+func (vs *Visor) firstBlockSeqAtOrAfter(tx *dbutil.Tx, headSeq uint64, t uint64) (uint64, error) {
+	var searchErr error
+	n := sort.Search(int(headSeq+1), func(i int) bool {
+		if searchErr != nil {
+			return true
+		}
+
+		b, err := vs.blockchain.GetSignedBlockBySeq(tx, uint64(i))
+		if err != nil {
+			searchErr = err
+			return true
+		}
+
+		if b == nil {
+			searchErr = fmt.Errorf("block seq=%d doesn't exist", i)
+			return true
+		}
+
+		return b.Time() >= t
+	})
+
+	if searchErr != nil {
+		return 0, searchErr
+	}
+
+	return uint64(n), nil
+}
@@ -0,0 +1,218 @@
+package visor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ness-network/ness/src/visor/historydb"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/testutil"
+)
+
+func TestGetTransactionsByCursor(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, BlockchainConfig{
+		Pubkey: genPublic,
+	})
+	require.NoError(t, err)
+
+	unconfirmed, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{})
+	require.NoError(t, err)
+
+	cfg := NewConfig()
+	cfg.IsBlockPublisher = true
+	cfg.BlockchainPubkey = genPublic
+	cfg.BlockchainSeckey = genSecret
+	cfg.GenesisAddress = genAddress
+
+	v := &Visor{
+		Config:      cfg,
+		unconfirmed: unconfirmed,
+		blockchain:  bc,
+		db:          db,
+		history:     historydb.New(),
+	}
+
+	gb := addGenesisBlockToVisor(t, v)
+	genTxn := gb.Body.Transactions[0]
+
+	executeBlock := func(txn coin.Transaction, when uint64) coin.SignedBlock {
+		b, err := v.CreateBlockFromTxns(coin.Transactions{txn}, when)
+		require.NoError(t, err)
+		sb := coin.SignedBlock{
+			Block: b,
+			Sig:   cipher.MustSignHash(b.HashHeader(), genSecret),
+		}
+		require.NoError(t, v.ExecuteSignedBlock(sb))
+		return sb
+	}
+
+	pubA, secA := cipher.GenerateKeyPair()
+	addrA := cipher.AddressFromPubKey(pubA)
+	addrB := testutil.MakeAddress()
+
+	// Block 1 sends coins to addrA, blocks 2 and 3 send coins from genAddress and addrA to addrB
+	txn1 := makeSpendTxn(t, coin.CreateUnspents(gb.Head, genTxn), []cipher.SecKey{genSecret}, addrA, 10e6)
+	b1 := executeBlock(txn1, genTime+100)
+	b1Uxs := coin.CreateUnspents(b1.Head, txn1)
+
+	txn2 := makeSpendTxn(t, b1Uxs[1:], []cipher.SecKey{genSecret}, addrB, 10e6)
+	executeBlock(txn2, genTime+200)
+
+	txn3 := makeSpendTxn(t, b1Uxs[:1], []cipher.SecKey{secA}, addrB, 10e6)
+	executeBlock(txn3, genTime+300)
+
+	seq := func(n uint64) *uint64 {
+		return &n
+	}
+
+	cursor := func(seq uint64) *historydb.TxnCursor {
+		return &historydb.TxnCursor{
+			BlockSeq: seq,
+		}
+	}
+
+	cases := []struct {
+		name   string
+		q      TransactionsCursorQuery
+		txns   []coin.Transaction
+		next   *historydb.TxnCursor
+		err    error
+		verify func(t *testing.T, txns []Transaction)
+	}{
+		{
+			name: "first page",
+			q: TransactionsCursorQuery{
+				Limit: 2,
+			},
+			txns: []coin.Transaction{genTxn, txn1},
+			next: cursor(1),
+			verify: func(t *testing.T, txns []Transaction) {
+				require.Equal(t, NewConfirmedTransactionStatus(3, 1), txns[1].Status)
+				require.Equal(t, b1.Time(), txns[1].Time)
+			},
+		},
+		{
+			name: "last page",
+			q: TransactionsCursorQuery{
+				After: cursor(1),
+				Limit: 2,
+			},
+			txns: []coin.Transaction{txn2, txn3},
+		},
+		{
+			name: "desc",
+			q: TransactionsCursorQuery{
+				Order: DescOrder,
+				Limit: 3,
+			},
+			txns: []coin.Transaction{txn3, txn2, txn1},
+			next: cursor(1),
+		},
+		{
+			name: "desc last page",
+			q: TransactionsCursorQuery{
+				After: cursor(1),
+				Order: DescOrder,
+				Limit: 3,
+			},
+			txns: []coin.Transaction{genTxn},
+		},
+		{
+			name: "addresses",
+			q: TransactionsCursorQuery{
+				Addrs: []cipher.Address{addrB},
+				Limit: 10,
+			},
+			txns: []coin.Transaction{txn2, txn3},
+		},
+		{
+			name: "seq range",
+			q: TransactionsCursorQuery{
+				FromSeq: seq(1),
+				ToSeq:   seq(2),
+				Limit:   10,
+			},
+			txns: []coin.Transaction{txn1, txn2},
+		},
+		{
+			name: "time range",
+			q: TransactionsCursorQuery{
+				FromTime: seq(genTime + 150),
+				ToTime:   seq(genTime + 300),
+				Limit:    10,
+			},
+			txns: []coin.Transaction{txn2, txn3},
+		},
+		{
+			name: "time range and addresses",
+			q: TransactionsCursorQuery{
+				Addrs:  []cipher.Address{addrA},
+				ToTime: seq(genTime + 299),
+				Limit:  10,
+			},
+			txns: []coin.Transaction{txn1},
+		},
+		{
+			name: "time after the head block",
+			q: TransactionsCursorQuery{
+				FromTime: seq(genTime + 301),
+				Limit:    10,
+			},
+		},
+		{
+			name: "time before the genesis block",
+			q: TransactionsCursorQuery{
+				ToTime: seq(genTime - 1),
+				Limit:  10,
+			},
+		},
+		{
+			name: "zero limit",
+			q:    TransactionsCursorQuery{},
+			err:  ErrZeroPageSize,
+		},
+		{
+			name: "limit too large",
+			q: TransactionsCursorQuery{
+				Limit: MaxTxnPageSize + 1,
+			},
+			err: ErrMaxTxnPageSize,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			txns, next, err := v.GetTransactionsByCursor(tc.q)
+			require.Equal(t, tc.err, err)
+			if err != nil {
+				return
+			}
+
+			require.Equal(t, tc.next, next)
+			require.Len(t, txns, len(tc.txns))
+			for i, txn := range txns {
+				require.Equal(t, tc.txns[i], txn.Transaction)
+				require.True(t, txn.Status.Confirmed)
+			}
+
+			if tc.verify != nil {
+				tc.verify(t, txns)
+			}
+
+			txns, inputs, next, err := v.GetTransactionsByCursorWithInputs(tc.q)
+			require.NoError(t, err)
+			require.Equal(t, tc.next, next)
+			require.Len(t, txns, len(tc.txns))
+			require.Len(t, inputs, len(tc.txns))
+			for i, txn := range txns {
+				require.Equal(t, tc.txns[i], txn.Transaction)
+				require.Len(t, inputs[i], len(txn.Transaction.In))
+			}
+		})
+	}
+}
@@ -1,14 +1,23 @@
 package visor
 
 import (
+	"bytes"
+	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
+	"math/bits"
 	"time"
 
+	"github.com/sirupsen/logrus"
+
+	"github.com/ness-network/ness/src/visor/blockdb"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/transaction"
+	"github.com/skycoin/skycoin/src/util/fee"
+	"github.com/skycoin/skycoin/src/util/mathutil"
 	"github.com/skycoin/skycoin/src/visor/dbutil"
 )
 
@@ -17,12 +26,32 @@ var (
 	UnconfirmedTxnsBkt = []byte("unconfirmed_txns")
 	// UnconfirmedUnspentsBkt holds unconfirmed unspent outputs
 	UnconfirmedUnspentsBkt = []byte("unconfirmed_unspents")
+	// UnconfirmedTxnFeesBkt holds the fee and size of unconfirmed transactions
+	UnconfirmedTxnFeesBkt = []byte("unconfirmed_txn_fees")
+	// UnconfirmedTxnFeeRatesBkt indexes unconfirmed transactions by fee per byte, from the lowest
+	UnconfirmedTxnFeeRatesBkt = []byte("unconfirmed_txn_fee_rates")
+	// UnconfirmedTxnInputsBkt indexes unconfirmed transactions by the hashes of the outputs they spend
+	UnconfirmedTxnInputsBkt = []byte("unconfirmed_txn_inputs")
+	// UnconfirmedPoolSizeBkt holds the number of unconfirmed transactions with a recorded fee and their total size
+	UnconfirmedPoolSizeBkt = []byte("unconfirmed_pool_size")
+
+	// ErrTxnFeeTooLow is returned if the unconfirmed pool is full and a transaction does not pay
+	// a higher fee per byte than the transactions that would be evicted to make room for it
+	ErrTxnFeeTooLow = errors.New("Transaction fee is too low to enter the full unconfirmed transaction pool")
+	// ErrTxnReplacementFeeTooLow is returned if a transaction spends the inputs of unconfirmed transactions
+	// but does not burn more coin hours than all of them together
+	ErrTxnReplacementFeeTooLow = errors.New("Transaction fee is too low to replace the unconfirmed transactions that spend the same inputs")
 
 	errUpdateObjectDoesNotExist = errors.New("object does not exist in bucket")
+	errPoolSizeUnderflow        = errors.New("unconfirmed pool size is lower than the transactions it holds")
 )
 
+// poolSizeKey is the key of the pool size in UnconfirmedPoolSizeBkt
+var poolSizeKey = []byte("size")
+
 //go:generate skyencoder -unexported -struct UnconfirmedTransaction
 //go:generate skyencoder -unexported -struct UxArray
+//go:generate skyencoder -unexported -struct UnconfirmedTxnFee
 
 // UxArray wraps coin.UxArray
 type UxArray struct {
@@ -167,18 +196,299 @@ func (txus *txnUnspents) getByAddr(tx *dbutil.Tx, a cipher.Address) (coin.UxArra
 	return uxo, nil
 }
 
+// unconfirmed transaction inputs bucket. Keys are the hash of a spent output followed by the hash of the txn,
+// so that the txns spending an output are found without reading the whole pool.
+type unconfirmedTxnInputs struct{}
+
+func txnInputKey(in, hash cipher.SHA256) []byte {
+	k := make([]byte, len(in)+len(hash))
+	copy(k, in[:])
+	copy(k[len(in):], hash[:])
+	return k
+}
+
+func (uti *unconfirmedTxnInputs) put(tx *dbutil.Tx, hash cipher.SHA256, inputs []cipher.SHA256) error {
+	for _, in := range inputs {
+		if err := dbutil.PutBucketValue(tx, UnconfirmedTxnInputsBkt, txnInputKey(in, hash), []byte{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (uti *unconfirmedTxnInputs) delete(tx *dbutil.Tx, hash cipher.SHA256, inputs []cipher.SHA256) error {
+	for _, in := range inputs {
+		if err := dbutil.Delete(tx, UnconfirmedTxnInputsBkt, txnInputKey(in, hash)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// spenders returns the hashes of the txns that spend any of the inputs, each once
+func (uti *unconfirmedTxnInputs) spenders(tx *dbutil.Tx, inputs []cipher.SHA256) ([]cipher.SHA256, error) {
+	bkt := tx.Bucket(UnconfirmedTxnInputsBkt)
+	if bkt == nil {
+		return nil, dbutil.NewErrBucketNotExist(UnconfirmedTxnInputsBkt)
+	}
+
+	seen := make(map[cipher.SHA256]struct{})
+	var hashes []cipher.SHA256
+	cur := bkt.Cursor()
+	for _, in := range inputs {
+		for k, _ := cur.Seek(in[:]); k != nil && bytes.HasPrefix(k, in[:]); k, _ = cur.Next() {
+			if len(k) != 2*len(cipher.SHA256{}) {
+				return nil, errors.New("invalid unconfirmed txn input key")
+			}
+
+			var hash cipher.SHA256
+			copy(hash[:], k[len(in):])
+			if _, ok := seen[hash]; ok {
+				continue
+			}
+
+			seen[hash] = struct{}{}
+			hashes = append(hashes, hash)
+		}
+	}
+
+	return hashes, nil
+}
+
+// UnconfirmedTxnFee is the fee and size of an unconfirmed transaction, used to order
+// the transactions of a full pool by fee rate
+type UnconfirmedTxnFee struct {
+	// Coin hour fee
+	Fee uint64
+	// Size of the transaction, in bytes
+	Size uint32
+}
+
+// Less returns true if f pays a lower fee per byte than g
+func (f UnconfirmedTxnFee) Less(g UnconfirmedTxnFee) bool {
+	// Compare f.Fee/f.Size < g.Fee/g.Size without division or overflow
+	hi1, lo1 := bits.Mul64(f.Fee, uint64(g.Size))
+	hi2, lo2 := bits.Mul64(g.Fee, uint64(f.Size))
+	return hi1 < hi2 || (hi1 == hi2 && lo1 < lo2)
+}
+
+// PerKB returns the fee per 1000 bytes, rounded up
+func (f UnconfirmedTxnFee) PerKB() uint64 {
+	if f.Size == 0 {
+		return 0
+	}
+
+	hi, lo := bits.Mul64(f.Fee, 1000)
+	lo, carry := bits.Add64(lo, uint64(f.Size)-1, 0)
+	hi += carry
+	if hi >= uint64(f.Size) {
+		// Overflows uint64
+		return ^uint64(0)
+	}
+
+	q, _ := bits.Div64(hi, lo, uint64(f.Size))
+	return q
+}
+
+// feeRateKey returns the key of a txn in the fee rates index. Keys sort by fee per byte, then by hash.
+// The fee per byte is encoded as a 128 bit fixed point number, Fee * 2^64 / Size rounded down, which
+// orders fees like UnconfirmedTxnFee.Less because sizes are 32 bit.
+func feeRateKey(f UnconfirmedTxnFee, hash cipher.SHA256) []byte {
+	k := make([]byte, 16+len(hash))
+	if f.Size == 0 {
+		for i := 0; i < 16; i++ {
+			k[i] = 0xff
+		}
+	} else {
+		hi, r := bits.Div64(0, f.Fee, uint64(f.Size))
+		lo, _ := bits.Div64(r, 0, uint64(f.Size))
+		binary.BigEndian.PutUint64(k, hi)
+		binary.BigEndian.PutUint64(k[8:], lo)
+	}
+	copy(k[16:], hash[:])
+	return k
+}
+
+// unconfirmed transaction fees buckets. The fees are indexed by fee per byte, and the number and total size
+// of the txns with a fee are kept up to date, so that the txns to evict from a full pool are found without
+// reading the whole pool.
+type unconfirmedTxnFees struct{}
+
+func (utf *unconfirmedTxnFees) get(tx *dbutil.Tx, hash cipher.SHA256) (*UnconfirmedTxnFee, error) {
+	v, err := dbutil.GetBucketValueNoCopy(tx, UnconfirmedTxnFeesBkt, []byte(hash.Hex()))
+	if err != nil {
+		return nil, err
+	} else if v == nil {
+		return nil, nil
+	}
+
+	var f UnconfirmedTxnFee
+	if err := decodeUnconfirmedTxnFeeExact(v, &f); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+func (utf *unconfirmedTxnFees) put(tx *dbutil.Tx, hash cipher.SHA256, f UnconfirmedTxnFee) error {
+	if err := utf.delete(tx, hash); err != nil {
+		return err
+	}
+
+	buf, err := encodeUnconfirmedTxnFee(&f)
+	if err != nil {
+		return err
+	}
+
+	if err := dbutil.PutBucketValue(tx, UnconfirmedTxnFeesBkt, []byte(hash.Hex()), buf); err != nil {
+		return err
+	}
+
+	if err := dbutil.PutBucketValue(tx, UnconfirmedTxnFeeRatesBkt, feeRateKey(f, hash), buf); err != nil {
+		return err
+	}
+
+	count, total, err := utf.size(tx)
+	if err != nil {
+		return err
+	}
+
+	return utf.setSize(tx, count+1, total+uint64(f.Size))
+}
+
+func (utf *unconfirmedTxnFees) delete(tx *dbutil.Tx, hash cipher.SHA256) error {
+	f, err := utf.get(tx, hash)
+	if err != nil {
+		return err
+	} else if f == nil {
+		return nil
+	}
+
+	if err := dbutil.Delete(tx, UnconfirmedTxnFeesBkt, []byte(hash.Hex())); err != nil {
+		return err
+	}
+
+	if err := dbutil.Delete(tx, UnconfirmedTxnFeeRatesBkt, feeRateKey(*f, hash)); err != nil {
+		return err
+	}
+
+	count, total, err := utf.size(tx)
+	if err != nil {
+		return err
+	}
+
+	if count == 0 || total < uint64(f.Size) {
+		return errPoolSizeUnderflow
+	}
+
+	return utf.setSize(tx, count-1, total-uint64(f.Size))
+}
+
+// size returns the number of txns with a fee and their total size
+func (utf *unconfirmedTxnFees) size(tx *dbutil.Tx) (uint64, uint64, error) {
+	v, err := dbutil.GetBucketValueNoCopy(tx, UnconfirmedPoolSizeBkt, poolSizeKey)
+	if err != nil {
+		return 0, 0, err
+	} else if v == nil {
+		return 0, 0, nil
+	}
+
+	if len(v) != 16 {
+		return 0, 0, errors.New("invalid unconfirmed pool size")
+	}
+
+	return binary.BigEndian.Uint64(v), binary.BigEndian.Uint64(v[8:]), nil
+}
+
+func (utf *unconfirmedTxnFees) setSize(tx *dbutil.Tx, count, total uint64) error {
+	v := make([]byte, 16)
+	binary.BigEndian.PutUint64(v, count)
+	binary.BigEndian.PutUint64(v[8:], total)
+	return dbutil.PutBucketValue(tx, UnconfirmedPoolSizeBkt, poolSizeKey, v)
+}
+
+// hasSize returns true if the pool size is recorded
+func (utf *unconfirmedTxnFees) hasSize(tx *dbutil.Tx) (bool, error) {
+	return dbutil.BucketHasKey(tx, UnconfirmedPoolSizeBkt, poolSizeKey)
+}
+
+// forEachByRate calls f with the txns in increasing order of fee per byte, until f returns false
+func (utf *unconfirmedTxnFees) forEachByRate(tx *dbutil.Tx, f func(hash cipher.SHA256, fee UnconfirmedTxnFee) (bool, error)) error {
+	bkt := tx.Bucket(UnconfirmedTxnFeeRatesBkt)
+	if bkt == nil {
+		return dbutil.NewErrBucketNotExist(UnconfirmedTxnFeeRatesBkt)
+	}
+
+	cur := bkt.Cursor()
+	for k, v := cur.First(); k != nil; k, v = cur.Next() {
+		if len(k) != 16+len(cipher.SHA256{}) {
+			return errors.New("invalid unconfirmed txn fee rate key")
+		}
+
+		var hash cipher.SHA256
+		copy(hash[:], k[16:])
+
+		var fee UnconfirmedTxnFee
+		if err := decodeUnconfirmedTxnFeeExact(v, &fee); err != nil {
+			return err
+		}
+
+		next, err := f(hash, fee)
+		if err != nil {
+			return err
+		}
+		if !next {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// reset removes all the fees
+func (utf *unconfirmedTxnFees) reset(tx *dbutil.Tx) error {
+	for _, bkt := range [][]byte{UnconfirmedTxnFeesBkt, UnconfirmedTxnFeeRatesBkt, UnconfirmedPoolSizeBkt} {
+		if err := dbutil.Reset(tx, bkt); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UnconfirmedTransactionPoolConfig configures the limits of the UnconfirmedTransactionPool
+type UnconfirmedTransactionPoolConfig struct {
+	// Maximum number of transactions in the pool. 0 means no limit.
+	MaxCount uint64
+	// Maximum total size of the transactions in the pool, in bytes. 0 means no limit.
+	MaxBytes uint64
+	// ReplaceByFee enables the replacement of txns by a txn that spends the same inputs
+	// and burns more coin hours. It applies to every txn in the pool, txns do not signal
+	// whether they may be replaced.
+	ReplaceByFee bool
+}
+
 // UnconfirmedTransactionPool manages unconfirmed transactions
 type UnconfirmedTransactionPool struct {
 	db   *dbutil.DB
+	cfg  UnconfirmedTransactionPoolConfig
 	txns *unconfirmedTxns
 	// Predicted unspents, assuming txns are valid.  Needed to predict
 	// our future balance and avoid double spending our own coins
 	// Maps from Transaction.Hash() to UxArray.
 	unspent *txnUnspents
+	// Fee and size of the txns, used to evict txns when the pool is full
+	fees *unconfirmedTxnFees
+	// Txns by the outputs they spend, used to find the txns replaced by a new txn
+	inputs *unconfirmedTxnInputs
+	// onRemove is called with the txns that are replaced or evicted by a new txn, before they are removed
+	onRemove func(tx *dbutil.Tx, hashes []cipher.SHA256, reason string) error
 }
 
 // NewUnconfirmedTransactionPool creates an UnconfirmedTransactionPool instance
-func NewUnconfirmedTransactionPool(db *dbutil.DB) (*UnconfirmedTransactionPool, error) {
+func NewUnconfirmedTransactionPool(db *dbutil.DB, cfg UnconfirmedTransactionPoolConfig) (*UnconfirmedTransactionPool, error) {
 	if err := db.View("Check unconfirmed txn pool size", func(tx *dbutil.Tx) error {
 		n, err := dbutil.Len(tx, UnconfirmedTxnsBkt)
 		if err != nil {
@@ -193,8 +503,11 @@ func NewUnconfirmedTransactionPool(db *dbutil.DB) (*UnconfirmedTransactionPool,
 
 	return &UnconfirmedTransactionPool{
 		db:      db,
+		cfg:     cfg,
 		txns:    &unconfirmedTxns{},
 		unspent: &txnUnspents{},
+		fees:    &unconfirmedTxnFees{},
+		inputs:  &unconfirmedTxnInputs{},
 	}, nil
 }
 
@@ -271,6 +584,30 @@ func (utp *UnconfirmedTransactionPool) InjectTransaction(tx *dbutil.Tx, bc Block
 		return true, softErr, nil
 	}
 
+	head, err := bc.Head(tx)
+	if err != nil {
+		logger.Errorf("InjectTransaction bc.Head() failed: %v", err)
+		return false, nil, err
+	}
+
+	txnFee, err := utp.txnFee(tx, bc, head, txn)
+	if err != nil {
+		logger.Errorf("InjectTransaction txnFee failed: %v", err)
+		return false, nil, err
+	}
+
+	// Replace txns that spend the same inputs and burn fewer coin hours
+	if utp.cfg.ReplaceByFee && isValid == 1 {
+		if err := utp.replace(tx, bc, head, txn, *txnFee); err != nil {
+			return false, nil, err
+		}
+	}
+
+	// Evict txns that pay a lower fee per byte if the pool is full
+	if err := utp.makeRoom(tx, *txnFee); err != nil {
+		return false, nil, err
+	}
+
 	utx := NewUnconfirmedTransaction(txn)
 	utx.IsValid = isValid
 
@@ -280,9 +617,13 @@ func (utp *UnconfirmedTransactionPool) InjectTransaction(tx *dbutil.Tx, bc Block
 		return false, nil, err
 	}
 
-	head, err := bc.Head(tx)
-	if err != nil {
-		logger.Errorf("InjectTransaction bc.Head() failed: %v", err)
+	if err := utp.fees.put(tx, hash, *txnFee); err != nil {
+		logger.Errorf("InjectTransaction put new unconfirmed txn fee failed: %v", err)
+		return false, nil, err
+	}
+
+	if err := utp.inputs.put(tx, hash, txn.In); err != nil {
+		logger.Errorf("InjectTransaction put new unconfirmed txn inputs failed: %v", err)
 		return false, nil, err
 	}
 
@@ -296,6 +637,276 @@ func (utp *UnconfirmedTransactionPool) InjectTransaction(tx *dbutil.Tx, bc Block
 	return false, softErr, nil
 }
 
+// txnFee computes the fee and size of a transaction.
+// The fee of a transaction whose inputs are not in the unspent pool is 0.
+func (utp *UnconfirmedTransactionPool) txnFee(tx *dbutil.Tx, bc Blockchainer, head *coin.SignedBlock, txn coin.Transaction) (*UnconfirmedTxnFee, error) {
+	size, err := txn.Size()
+	if err != nil {
+		return nil, err
+	}
+
+	inputs, err := bc.Unspent().GetArray(tx, txn.In)
+	if err != nil {
+		switch err.(type) {
+		case blockdb.ErrUnspentNotExist:
+			return &UnconfirmedTxnFee{
+				Size: size,
+			}, nil
+		default:
+			return nil, err
+		}
+	}
+
+	f, err := fee.TransactionFee(&txn, head.Time(), inputs)
+	if err != nil {
+		// The txn spends more coin hours than its inputs have, it is invalid and pays no fee
+		f = 0
+	}
+
+	return &UnconfirmedTxnFee{
+		Fee:  f,
+		Size: size,
+	}, nil
+}
+
+// MaybeBuildFeeIndex records the fees of the txns in the pool, with their fee per byte index and the pool size,
+// if the pool size is not recorded. Pools created before the fee index have no pool size.
+func (utp *UnconfirmedTransactionPool) MaybeBuildFeeIndex(tx *dbutil.Tx, bc Blockchainer) error {
+	built, err := utp.fees.hasSize(tx)
+	if err != nil || built {
+		return err
+	}
+
+	logger.Info("Building the fee index of the unconfirmed pool")
+
+	var pooled []pooledTxnFee
+	n, err := utp.txns.len(tx)
+	if err != nil {
+		return err
+	}
+
+	if n != 0 {
+		head, err := bc.Head(tx)
+		if err != nil {
+			return err
+		}
+
+		if err := utp.txns.forEach(tx, func(hash cipher.SHA256, utxn UnconfirmedTransaction) error {
+			f, err := utp.pooledFee(tx, bc, head, hash, utxn.Transaction)
+			if err != nil {
+				return err
+			}
+
+			pooled = append(pooled, pooledTxnFee{
+				hash:              hash,
+				UnconfirmedTxnFee: *f,
+			})
+			return nil
+		}); err != nil {
+			return err
+		}
+	}
+
+	if err := utp.fees.reset(tx); err != nil {
+		return err
+	}
+
+	if err := utp.fees.setSize(tx, 0, 0); err != nil {
+		return err
+	}
+
+	for _, p := range pooled {
+		if err := utp.fees.put(tx, p.hash, p.UnconfirmedTxnFee); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MaybeBuildInputIndex indexes the txns in the pool by the outputs they spend, if the index is empty.
+// Pools created before the input index have no index. Every txn in the pool spends an output,
+// so the index of a pool with txns is never empty.
+func (utp *UnconfirmedTransactionPool) MaybeBuildInputIndex(tx *dbutil.Tx) error {
+	empty, err := dbutil.IsEmpty(tx, UnconfirmedTxnInputsBkt)
+	if err != nil || !empty {
+		return err
+	}
+
+	n, err := utp.txns.len(tx)
+	if err != nil || n == 0 {
+		return err
+	}
+
+	logger.Info("Building the input index of the unconfirmed pool")
+
+	return utp.txns.forEach(tx, func(hash cipher.SHA256, utxn UnconfirmedTransaction) error {
+		return utp.inputs.put(tx, hash, utxn.Transaction.In)
+	})
+}
+
+// pooledFee returns the fee and size of a txn in the pool
+func (utp *UnconfirmedTransactionPool) pooledFee(tx *dbutil.Tx, bc Blockchainer, head *coin.SignedBlock, hash cipher.SHA256, txn coin.Transaction) (*UnconfirmedTxnFee, error) {
+	f, err := utp.fees.get(tx, hash)
+	if err != nil {
+		return nil, err
+	}
+
+	// Txns added before fees were recorded
+	if f == nil {
+		return utp.txnFee(tx, bc, head, txn)
+	}
+
+	return f, nil
+}
+
+// replace removes the txns that spend any input of txn from the pool.
+// If txn does not burn more coin hours than the removed txns together, nothing is removed
+// and ErrTxnReplacementFeeTooLow is returned as a soft constraint violation.
+func (utp *UnconfirmedTransactionPool) replace(tx *dbutil.Tx, bc Blockchainer, head *coin.SignedBlock, txn coin.Transaction, f UnconfirmedTxnFee) error {
+	replaced, err := utp.inputs.spenders(tx, txn.In)
+	if err != nil {
+		return err
+	}
+
+	var replacedFee uint64
+	for _, hash := range replaced {
+		utxn, err := utp.txns.get(tx, hash)
+		if err != nil {
+			return err
+		} else if utxn == nil {
+			return fmt.Errorf("unconfirmed txn %s of the input index is not in the pool", hash.Hex())
+		}
+
+		pf, err := utp.pooledFee(tx, bc, head, hash, utxn.Transaction)
+		if err != nil {
+			return err
+		}
+
+		replacedFee, err = mathutil.AddUint64(replacedFee, pf.Fee)
+		if err != nil {
+			replacedFee = math.MaxUint64
+		}
+	}
+
+	if len(replaced) == 0 {
+		return nil
+	}
+
+	if f.Fee <= replacedFee {
+		return transaction.NewErrTxnViolatesSoftConstraint(ErrTxnReplacementFeeTooLow)
+	}
+
+	for _, hash := range replaced {
+		logger.WithFields(logrus.Fields{
+			"txid":       hash.Hex(),
+			"replacedBy": txn.Hash().Hex(),
+		}).Info("Replacing unconfirmed txn by a txn that burns more coin hours")
+	}
+
+	return utp.removeTransactions(tx, replaced, RemovedTxnReplaced)
+}
+
+// pooledTxnFee is the fee of a transaction in the pool
+type pooledTxnFee struct {
+	hash cipher.SHA256
+	UnconfirmedTxnFee
+}
+
+// evictions returns the txns with the lowest fee per byte that must be removed from the pool
+// to make room for a txn of the given size, ordered from the lowest to the highest fee per byte.
+// Returns nil if the txn fits in the pool.
+func (utp *UnconfirmedTransactionPool) evictions(tx *dbutil.Tx, size uint32) ([]pooledTxnFee, error) {
+	if utp.cfg.MaxCount == 0 && utp.cfg.MaxBytes == 0 {
+		return nil, nil
+	}
+
+	count, total, err := utp.fees.size(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	full := func() bool {
+		return (utp.cfg.MaxCount != 0 && count+1 > utp.cfg.MaxCount) ||
+			(utp.cfg.MaxBytes != 0 && total+uint64(size) > utp.cfg.MaxBytes)
+	}
+
+	if !full() {
+		return nil, nil
+	}
+
+	var evict []pooledTxnFee
+	if err := utp.fees.forEachByRate(tx, func(hash cipher.SHA256, f UnconfirmedTxnFee) (bool, error) {
+		evict = append(evict, pooledTxnFee{
+			hash:              hash,
+			UnconfirmedTxnFee: f,
+		})
+		count--
+		total -= uint64(f.Size)
+		return full(), nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return evict, nil
+}
+
+// makeRoom removes the txns with the lowest fee per byte from a full pool, to make room for a new txn.
+// If the new txn does not pay a higher fee per byte than each of the txns that would be removed,
+// nothing is removed and ErrTxnFeeTooLow is returned as a soft constraint violation.
+func (utp *UnconfirmedTransactionPool) makeRoom(tx *dbutil.Tx, f UnconfirmedTxnFee) error {
+	evict, err := utp.evictions(tx, f.Size)
+	if err != nil {
+		return err
+	}
+
+	if len(evict) == 0 {
+		return nil
+	}
+
+	// The highest fee per byte to be evicted must be lower than the new txn's
+	if !evict[len(evict)-1].Less(f) {
+		return transaction.NewErrTxnViolatesSoftConstraint(ErrTxnFeeTooLow)
+	}
+
+	hashes := make([]cipher.SHA256, len(evict))
+	for i, p := range evict {
+		hashes[i] = p.hash
+		logger.WithField("txid", p.hash.Hex()).Info("Evicting txn with a low fee from the full unconfirmed pool")
+	}
+
+	return utp.removeTransactions(tx, hashes, RemovedTxnEvicted)
+}
+
+// removeTransactions removes the txns that are replaced or evicted by a new txn, for the given reason
+func (utp *UnconfirmedTransactionPool) removeTransactions(tx *dbutil.Tx, hashes []cipher.SHA256, reason string) error {
+	if utp.onRemove != nil {
+		if err := utp.onRemove(tx, hashes, reason); err != nil {
+			return err
+		}
+	}
+
+	return utp.RemoveTransactions(tx, hashes)
+}
+
+// MinFee returns the fee of the txn with the highest fee per byte that would be evicted to make room
+// for a txn of the given size. A txn of that size must pay a higher fee per byte to enter the pool.
+// Returns a zero fee if the txn fits in the pool.
+func (utp *UnconfirmedTransactionPool) MinFee(tx *dbutil.Tx, size uint32) (UnconfirmedTxnFee, error) {
+	evict, err := utp.evictions(tx, size)
+	if err != nil {
+		return UnconfirmedTxnFee{}, err
+	}
+
+	if len(evict) == 0 {
+		return UnconfirmedTxnFee{
+			Size: size,
+		}, nil
+	}
+
+	return evict[len(evict)-1].UnconfirmedTxnFee, nil
+}
+
 // AllRawTransactions returns underlying coin.Transactions
 func (utp *UnconfirmedTransactionPool) AllRawTransactions(tx *dbutil.Tx) (coin.Transactions, error) {
 	utxns, err := utp.txns.getAll(tx)
@@ -312,10 +923,25 @@ func (utp *UnconfirmedTransactionPool) AllRawTransactions(tx *dbutil.Tx) (coin.T
 
 // Remove a single txn by hash
 func (utp *UnconfirmedTransactionPool) removeTransaction(tx *dbutil.Tx, txHash cipher.SHA256) error {
+	utxn, err := utp.txns.get(tx, txHash)
+	if err != nil {
+		return err
+	}
+
+	if utxn != nil {
+		if err := utp.inputs.delete(tx, txHash, utxn.Transaction.In); err != nil {
+			return err
+		}
+	}
+
 	if err := utp.txns.delete(tx, txHash); err != nil {
 		return err
 	}
 
+	if err := utp.fees.delete(tx, txHash); err != nil {
+		return err
+	}
+
 	return utp.unspent.delete(tx, txHash)
 }
 
@@ -0,0 +1,515 @@
+package visor
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/transaction"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+)
+
+func TestUnconfirmedTxnFee(t *testing.T) {
+	a := UnconfirmedTxnFee{Fee: 10, Size: 100}
+	b := UnconfirmedTxnFee{Fee: 21, Size: 200}
+	c := UnconfirmedTxnFee{Fee: 20, Size: 200}
+
+	require.True(t, a.Less(b))
+	require.False(t, b.Less(a))
+	require.False(t, a.Less(c))
+	require.False(t, c.Less(a))
+
+	// No overflow with large fees
+	d := UnconfirmedTxnFee{Fee: math.MaxUint64, Size: 1000}
+	e := UnconfirmedTxnFee{Fee: math.MaxUint64 - 1, Size: 1000}
+	require.True(t, e.Less(d))
+	require.False(t, d.Less(e))
+
+	require.Equal(t, uint64(100), a.PerKB())
+	require.Equal(t, uint64(105), b.PerKB())
+	require.Equal(t, uint64(4), UnconfirmedTxnFee{Fee: 1, Size: 300}.PerKB())
+	require.Equal(t, uint64(0), UnconfirmedTxnFee{}.PerKB())
+	require.Equal(t, uint64(math.MaxUint64), d.PerKB())
+}
+
+func TestFeeRateKey(t *testing.T) {
+	fees := []UnconfirmedTxnFee{
+		{Fee: 0, Size: 1},
+		{Fee: 10, Size: 100},
+		{Fee: 20, Size: 200},
+		{Fee: 21, Size: 200},
+		{Fee: 1, Size: math.MaxUint32},
+		{Fee: 2, Size: math.MaxUint32},
+		{Fee: 1, Size: math.MaxUint32 - 1},
+		{Fee: math.MaxUint64 - 1, Size: 1000},
+		{Fee: math.MaxUint64, Size: 1000},
+		{Fee: math.MaxUint64, Size: 1},
+		{Fee: 1, Size: 0},
+	}
+
+	for i := 0; i < 100; i++ {
+		fees = append(fees, UnconfirmedTxnFee{
+			Fee:  uint64(i*i) % 97,
+			Size: uint32(i%13) + 1,
+		})
+	}
+
+	// Keys of the same txn order like the fees
+	hash := testutil.RandSHA256(t)
+	for _, f := range fees {
+		for _, g := range fees {
+			cmp := bytes.Compare(feeRateKey(f, hash), feeRateKey(g, hash))
+			switch {
+			case f.Less(g):
+				require.Equal(t, -1, cmp, "%v %v", f, g)
+			case g.Less(f):
+				require.Equal(t, 1, cmp, "%v %v", f, g)
+			default:
+				require.Equal(t, 0, cmp, "%v %v", f, g)
+			}
+		}
+	}
+
+	// Txns with the same fee per byte are ordered by hash
+	h1 := cipher.SHA256{1}
+	h2 := cipher.SHA256{2}
+	require.Equal(t, -1, bytes.Compare(feeRateKey(fees[1], h1), feeRateKey(fees[2], h2)))
+	require.Equal(t, 1, bytes.Compare(feeRateKey(fees[1], h2), feeRateKey(fees[2], h1)))
+}
+
+// makeUnconfirmedPoolTxns creates n independent transactions that spend outputs of the blockchain.
+// The fee of the i-th transaction is fees[i].
+func makeUnconfirmedPoolTxns(t *testing.T, db *dbutil.DB, bc *Blockchain, fees []uint64) coin.Transactions {
+	n := len(fees)
+
+	// Split the genesis output so that each transaction can spend its own output
+	var split coin.Transaction
+	err := db.View("", func(tx *dbutil.Tx) error {
+		uxOuts, err := bc.Unspent().GetAll(tx)
+		require.NoError(t, err)
+		require.Len(t, uxOuts, 1)
+
+		hours, err := uxOuts[0].CoinHours(GenesisTime)
+		require.NoError(t, err)
+
+		err = split.PushInput(uxOuts[0].Hash())
+		require.NoError(t, err)
+		for i := 0; i < n; i++ {
+			// Outputs must differ, otherwise they would be duplicates
+			err = split.PushOutput(GenesisAddress, GenesisCoins/uint64(n), hours/uint64(2*n)-uint64(i))
+			require.NoError(t, err)
+		}
+
+		split.SignInputs([]cipher.SecKey{GenesisSecret})
+		return split.UpdateHeader()
+	})
+	require.NoError(t, err)
+
+	ExecuteGenesisSpendTransaction(t, db, bc, split)
+
+	var head *coin.SignedBlock
+	err = db.View("", func(tx *dbutil.Tx) error {
+		var err error
+		head, err = bc.Head(tx)
+		return err
+	})
+	require.NoError(t, err)
+
+	uxs := coin.CreateUnspents(head.Head, split)
+	txns := make(coin.Transactions, n)
+	for i := range txns {
+		txns[i] = makeSpendTxWithFee(t, uxs[i:i+1], []cipher.SecKey{GenesisSecret}, testutil.MakeAddress(), uxs[i].Body.Coins, fees[i])
+	}
+
+	return txns
+}
+
+func TestUnconfirmedTransactionPoolLimits(t *testing.T) {
+	cases := []struct {
+		name string
+		cfg  func(size uint32) UnconfirmedTransactionPoolConfig
+	}{
+		{
+			name: "max count",
+			cfg: func(size uint32) UnconfirmedTransactionPoolConfig {
+				return UnconfirmedTransactionPoolConfig{
+					MaxCount: 2,
+				}
+			},
+		},
+		{
+			name: "max bytes",
+			cfg: func(size uint32) UnconfirmedTransactionPoolConfig {
+				return UnconfirmedTransactionPoolConfig{
+					MaxBytes: uint64(size)*3 - 1,
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, close := prepareDB(t)
+			defer close()
+
+			bc := MakeBlockchain(t, db, GenesisSecret)
+			txns := makeUnconfirmedPoolTxns(t, db, bc, []uint64{10, 20, 5, 30, 20})
+
+			size, err := txns[0].Size()
+			require.NoError(t, err)
+			for _, txn := range txns {
+				s, err := txn.Size()
+				require.NoError(t, err)
+				require.Equal(t, size, s)
+			}
+
+			pool, err := NewUnconfirmedTransactionPool(db, tc.cfg(size))
+			require.NoError(t, err)
+
+			inject := func(txn coin.Transaction) error {
+				return db.Update("", func(tx *dbutil.Tx) error {
+					_, softErr, err := pool.InjectTransaction(tx, bc, txn, params.MainNetDistribution, params.UserVerifyTxn)
+					require.Nil(t, softErr)
+					return err
+				})
+			}
+
+			requirePool := func(expected ...coin.Transaction) {
+				err := db.View("", func(tx *dbutil.Tx) error {
+					txns, err := pool.AllRawTransactions(tx)
+					require.NoError(t, err)
+					require.ElementsMatch(t, expected, txns)
+					return nil
+				})
+				require.NoError(t, err)
+			}
+
+			minFee := func() UnconfirmedTxnFee {
+				var f UnconfirmedTxnFee
+				err := db.View("", func(tx *dbutil.Tx) error {
+					var err error
+					f, err = pool.MinFee(tx, size)
+					return err
+				})
+				require.NoError(t, err)
+				return f
+			}
+
+			// The pool is not full
+			require.NoError(t, inject(txns[0]))
+			require.Equal(t, UnconfirmedTxnFee{Size: size}, minFee())
+			require.NoError(t, inject(txns[1]))
+			requirePool(txns[0], txns[1])
+
+			require.Equal(t, UnconfirmedTxnFee{Fee: txnFeeOf(t, db, bc, txns[0]), Size: size}, minFee())
+
+			// A txn with a lower fee is rejected
+			err = inject(txns[2])
+			require.Equal(t, transaction.NewErrTxnViolatesSoftConstraint(ErrTxnFeeTooLow), err)
+			requirePool(txns[0], txns[1])
+
+			// A txn with a higher fee evicts the txn with the lowest fee
+			require.NoError(t, inject(txns[3]))
+			requirePool(txns[1], txns[3])
+
+			// The pool minimum rises as txns with low fees are evicted
+			err = inject(txns[4])
+			require.Equal(t, transaction.NewErrTxnViolatesSoftConstraint(ErrTxnFeeTooLow), err)
+			requirePool(txns[1], txns[3])
+
+			// Known txns are updated when the pool is full
+			require.NoError(t, inject(txns[1]))
+			requirePool(txns[1], txns[3])
+
+			// Removed txns make room
+			err = db.Update("", func(tx *dbutil.Tx) error {
+				return pool.RemoveTransactions(tx, []cipher.SHA256{txns[3].Hash()})
+			})
+			require.NoError(t, err)
+			require.NoError(t, inject(txns[2]))
+			requirePool(txns[1], txns[2])
+		})
+	}
+}
+
+func TestUnconfirmedTransactionPoolMaybeBuildFeeIndex(t *testing.T) {
+	db, close := prepareDB(t)
+	defer close()
+
+	bc := MakeBlockchain(t, db, GenesisSecret)
+	txns := makeUnconfirmedPoolTxns(t, db, bc, []uint64{10, 20, 5, 1})[:3]
+
+	size, err := txns[0].Size()
+	require.NoError(t, err)
+
+	pool, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{
+		MaxCount: 3,
+	})
+	require.NoError(t, err)
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		for _, txn := range txns {
+			_, softErr, err := pool.InjectTransaction(tx, bc, txn, params.MainNetDistribution, params.UserVerifyTxn)
+			require.NoError(t, err)
+			require.Nil(t, softErr)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+
+	requireIndex := func() {
+		err := db.View("", func(tx *dbutil.Tx) error {
+			count, total, err := pool.fees.size(tx)
+			require.NoError(t, err)
+			require.Equal(t, uint64(3), count)
+			require.Equal(t, 3*uint64(size), total)
+
+			var hashes []cipher.SHA256
+			err = pool.fees.forEachByRate(tx, func(hash cipher.SHA256, f UnconfirmedTxnFee) (bool, error) {
+				hashes = append(hashes, hash)
+				return true, nil
+			})
+			require.NoError(t, err)
+			require.Equal(t, []cipher.SHA256{txns[2].Hash(), txns[0].Hash(), txns[1].Hash()}, hashes)
+
+			f, err := pool.MinFee(tx, size)
+			require.NoError(t, err)
+			require.Equal(t, UnconfirmedTxnFee{Fee: txnFeeOf(t, db, bc, txns[2]), Size: size}, f)
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	requireIndex()
+
+	// The index is built for pools created before it
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, pool.fees.reset(tx))
+		require.NoError(t, pool.MaybeBuildFeeIndex(tx, bc))
+		return nil
+	})
+	require.NoError(t, err)
+	requireIndex()
+
+	// The index is only built once
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, dbutil.Reset(tx, UnconfirmedTxnFeeRatesBkt))
+		require.NoError(t, pool.MaybeBuildFeeIndex(tx, bc))
+
+		empty, err := dbutil.IsEmpty(tx, UnconfirmedTxnFeeRatesBkt)
+		require.NoError(t, err)
+		require.True(t, empty)
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Removed txns are removed from the index
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, pool.fees.reset(tx))
+		require.NoError(t, pool.MaybeBuildFeeIndex(tx, bc))
+		require.NoError(t, pool.RemoveTransactions(tx, []cipher.SHA256{txns[2].Hash()}))
+
+		count, total, err := pool.fees.size(tx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), count)
+		require.Equal(t, 2*uint64(size), total)
+
+		f, err := pool.MinFee(tx, size)
+		require.NoError(t, err)
+		require.Equal(t, UnconfirmedTxnFee{Size: size}, f)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestUnconfirmedTransactionPoolMaybeBuildInputIndex(t *testing.T) {
+	db, close := prepareDB(t)
+	defer close()
+
+	bc := MakeBlockchain(t, db, GenesisSecret)
+	txns := makeUnconfirmedPoolTxns(t, db, bc, []uint64{10, 20})
+
+	pool, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{})
+	require.NoError(t, err)
+
+	requireIndex := func() {
+		err := db.View("", func(tx *dbutil.Tx) error {
+			for _, txn := range txns {
+				spenders, err := pool.inputs.spenders(tx, txn.In)
+				require.NoError(t, err)
+				require.Equal(t, []cipher.SHA256{txn.Hash()}, spenders)
+			}
+
+			spenders, err := pool.inputs.spenders(tx, []cipher.SHA256{testutil.RandSHA256(t)})
+			require.NoError(t, err)
+			require.Empty(t, spenders)
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		// Nothing to index in an empty pool
+		require.NoError(t, pool.MaybeBuildInputIndex(tx))
+
+		for _, txn := range txns {
+			_, softErr, err := pool.InjectTransaction(tx, bc, txn, params.MainNetDistribution, params.UserVerifyTxn)
+			require.NoError(t, err)
+			require.Nil(t, softErr)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	requireIndex()
+
+	// The index is built for pools created before it
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, dbutil.Reset(tx, UnconfirmedTxnInputsBkt))
+		return pool.MaybeBuildInputIndex(tx)
+	})
+	require.NoError(t, err)
+	requireIndex()
+
+	// Removed txns are removed from the index
+	err = db.Update("", func(tx *dbutil.Tx) error {
+		require.NoError(t, pool.RemoveTransactions(tx, []cipher.SHA256{txns[0].Hash()}))
+
+		spenders, err := pool.inputs.spenders(tx, txns[0].In)
+		require.NoError(t, err)
+		require.Empty(t, spenders)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestUnconfirmedTransactionPoolReplaceByFee(t *testing.T) {
+	for _, replaceByFee := range []bool{true, false} {
+		t.Run(fmt.Sprintf("replace by fee %v", replaceByFee), func(t *testing.T) {
+			db, close := prepareDB(t)
+			defer close()
+
+			bc := MakeBlockchain(t, db, GenesisSecret)
+			txns := makeUnconfirmedPoolTxns(t, db, bc, []uint64{10, 0})
+
+			var head *coin.SignedBlock
+			err := db.View("", func(tx *dbutil.Tx) error {
+				var err error
+				head, err = bc.Head(tx)
+				return err
+			})
+			require.NoError(t, err)
+
+			// The outputs spent by txns
+			uxs := coin.CreateUnspents(head.Head, head.Body.Transactions[0])
+			require.Len(t, uxs, 2)
+
+			spend := func(uxs coin.UxArray, fee uint64) coin.Transaction {
+				return makeSpendTxWithFee(t, uxs, []cipher.SecKey{GenesisSecret}, testutil.MakeAddress(), uxs[0].Body.Coins, fee)
+			}
+
+			lower := spend(uxs[:1], 5)
+			same := spend(uxs[:1], 10)
+			higher := spend(uxs[:1], 20)
+			// Spends the outputs of both higher and txns[1]
+			keys := []cipher.SecKey{GenesisSecret, GenesisSecret}
+			bothLower := makeSpendTxWithFee(t, uxs, keys, testutil.MakeAddress(), uxs[0].Body.Coins+uxs[1].Body.Coins, 10)
+			both := makeSpendTxWithFee(t, uxs, keys, testutil.MakeAddress(), uxs[0].Body.Coins+uxs[1].Body.Coins, 30)
+
+			pool, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{
+				ReplaceByFee: replaceByFee,
+			})
+			require.NoError(t, err)
+
+			inject := func(txn coin.Transaction) error {
+				return db.Update("", func(tx *dbutil.Tx) error {
+					_, softErr, err := pool.InjectTransaction(tx, bc, txn, params.MainNetDistribution, params.UserVerifyTxn)
+					require.Nil(t, softErr)
+					return err
+				})
+			}
+
+			requirePool := func(expected ...coin.Transaction) {
+				err := db.View("", func(tx *dbutil.Tx) error {
+					txns, err := pool.AllRawTransactions(tx)
+					require.NoError(t, err)
+					require.ElementsMatch(t, expected, txns)
+					return nil
+				})
+				require.NoError(t, err)
+			}
+
+			require.NoError(t, inject(txns[0]))
+			require.NoError(t, inject(txns[1]))
+
+			if !replaceByFee {
+				// Conflicting txns are kept alongside each other
+				require.NoError(t, inject(lower))
+				require.NoError(t, inject(higher))
+				requirePool(txns[0], txns[1], lower, higher)
+				return
+			}
+
+			// A txn that burns fewer coin hours is rejected
+			err = inject(lower)
+			require.Equal(t, transaction.NewErrTxnViolatesSoftConstraint(ErrTxnReplacementFeeTooLow), err)
+			requirePool(txns[0], txns[1])
+
+			// The replacement must burn strictly more coin hours
+			err = inject(same)
+			require.Equal(t, transaction.NewErrTxnViolatesSoftConstraint(ErrTxnReplacementFeeTooLow), err)
+			requirePool(txns[0], txns[1])
+
+			// A txn that burns more coin hours replaces the txn
+			require.NoError(t, inject(higher))
+			requirePool(higher, txns[1])
+
+			// A txn must burn more coin hours than all the txns it replaces together
+			require.True(t, txnFeeOf(t, db, bc, bothLower) > txnFeeOf(t, db, bc, higher))
+			err = inject(bothLower)
+			require.Equal(t, transaction.NewErrTxnViolatesSoftConstraint(ErrTxnReplacementFeeTooLow), err)
+			requirePool(higher, txns[1])
+
+			require.NoError(t, inject(both))
+			requirePool(both)
+
+			// The unspent outputs and the inputs of the replaced txns were removed
+			err = db.View("", func(tx *dbutil.Tx) error {
+				uxs, err := pool.GetUnspentsOfAddr(tx, higher.Out[0].Address)
+				require.NoError(t, err)
+				require.Empty(t, uxs)
+
+				n, err := dbutil.Len(tx, UnconfirmedTxnInputsBkt)
+				require.NoError(t, err)
+				require.Equal(t, uint64(len(both.In)), n)
+
+				spenders, err := pool.inputs.spenders(tx, both.In)
+				require.NoError(t, err)
+				require.Equal(t, []cipher.SHA256{both.Hash()}, spenders)
+				return nil
+			})
+			require.NoError(t, err)
+		})
+	}
+}
+
+// txnFeeOf returns the coin hour fee of a txn
+func txnFeeOf(t *testing.T, db *dbutil.DB, bc *Blockchain, txn coin.Transaction) uint64 {
+	var f *UnconfirmedTxnFee
+	err := db.View("", func(tx *dbutil.Tx) error {
+		head, err := bc.Head(tx)
+		require.NoError(t, err)
+
+		pool := &UnconfirmedTransactionPool{}
+		f, err = pool.txnFee(tx, bc, head, txn)
+		return err
+	})
+	require.NoError(t, err)
+	return f.Fee
+}
@@ -16,6 +16,8 @@ import (
 
 	"time"
 
+	"github.com/ness-network/ness/src/visor/blockdb"
+	"github.com/ness-network/ness/src/visor/historydb"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/params"
@@ -23,9 +25,7 @@ import (
 	"github.com/skycoin/skycoin/src/util/logging"
 	"github.com/skycoin/skycoin/src/util/mathutil"
 	"github.com/skycoin/skycoin/src/util/timeutil"
-	"github.com/skycoin/skycoin/src/visor/blockdb"
 	"github.com/skycoin/skycoin/src/visor/dbutil"
-	"github.com/skycoin/skycoin/src/visor/historydb"
 	"github.com/skycoin/skycoin/src/wallet"
 )
 
@@ -43,6 +43,7 @@ type Visor struct {
 	wallets     *wallet.Service
 	txns        transactionsGetter
 	tf          wallet.TransactionsFinder
+	events      *eventBroker
 }
 
 // New creates a Visor for managing the blockchain database
@@ -63,6 +64,13 @@ func New(c Config, db *dbutil.DB, wltServ *wallet.Service) (*Visor, error) {
 	logger.Infof("Max transaction size for transactions when creating blocks is %d", c.CreateBlockVerifyTxn.MaxTransactionSize)
 	logger.Infof("Max decimals for transactions when creating blocks is %d", c.CreateBlockVerifyTxn.MaxDropletPrecision)
 	logger.Infof("Max block size is %d", c.MaxBlockTransactionsSize)
+	logger.Infof("Max unconfirmed pool transactions is %d", c.MaxUnconfirmedCount)
+	logger.Infof("Max unconfirmed pool size is %d", c.MaxUnconfirmedBytes)
+	logger.Infof("Unconfirmed replace-by-fee enabled: %v", c.EnableReplaceByFee)
+	logger.Infof("Max reorg depth is %d", c.MaxReorgDepth)
+	for _, pk := range c.PublisherPubkeys {
+		logger.Infof("Block publisher pubkey %s", pk.Hex())
+	}
 
 	if !db.IsReadOnly() {
 		if err := CreateBuckets(db); err != nil {
@@ -72,8 +80,10 @@ func New(c Config, db *dbutil.DB, wltServ *wallet.Service) (*Visor, error) {
 	}
 
 	bc, err := NewBlockchain(db, BlockchainConfig{
-		Pubkey:      c.BlockchainPubkey,
-		Arbitrating: c.Arbitrating,
+		Pubkey:           c.BlockchainPubkey,
+		PublisherPubkeys: c.PublisherPubkeys,
+		PublisherQuorum:  c.PublisherQuorum,
+		Arbitrating:      c.Arbitrating,
 	})
 	if err != nil {
 		return nil, err
@@ -98,11 +108,26 @@ func New(c Config, db *dbutil.DB, wltServ *wallet.Service) (*Visor, error) {
 		}
 	}
 
-	utp, err := NewUnconfirmedTransactionPool(db)
+	utp, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{
+		MaxCount:     c.MaxUnconfirmedCount,
+		MaxBytes:     c.MaxUnconfirmedBytes,
+		ReplaceByFee: c.EnableReplaceByFee,
+	})
 	if err != nil {
 		return nil, err
 	}
 
+	if !db.IsReadOnly() {
+		if err := db.Update("build unconfirmed pool indexes", func(tx *dbutil.Tx) error {
+			if err := utp.MaybeBuildFeeIndex(tx, bc); err != nil {
+				return err
+			}
+			return utp.MaybeBuildInputIndex(tx)
+		}); err != nil {
+			return nil, err
+		}
+	}
+
 	txns := transactionModel{
 		history:     history,
 		unconfirmed: utp,
@@ -118,9 +143,11 @@ func New(c Config, db *dbutil.DB, wltServ *wallet.Service) (*Visor, error) {
 		history:     history,
 		wallets:     wltServ,
 		txns:        &txns,
+		events:      newEventBroker(),
 	}
 
 	v.tf = newTransactionsFinder(v)
+	utp.onRemove = v.publishUnconfirmedTxnsRemoved
 
 	return v, nil
 }
@@ -143,6 +170,12 @@ func (vs *Visor) Init() error {
 			return err
 		}
 
+		if vs.Config.IsBlockPublisher {
+			if err := vs.verifyPublisherSeckey(tx); err != nil {
+				return err
+			}
+		}
+
 		removed, err := vs.unconfirmed.RemoveInvalid(tx, vs.blockchain)
 		if err != nil {
 			return err
@@ -153,6 +186,22 @@ func (vs *Visor) Init() error {
 	})
 }
 
+// verifyPublisherSeckey checks that the seckey of the block publisher belongs to the genesis blockchain pubkey,
+// a key checkpoint pubkey or one of the block publishers
+func (vs *Visor) verifyPublisherSeckey(tx *dbutil.Tx) error {
+	pubkeys, err := vs.blockchain.BlockchainPubkeys(tx)
+	if err != nil {
+		return err
+	}
+
+	pubkey := cipher.MustPubKeyFromSecKey(vs.Config.BlockchainSeckey)
+	if !containsPubkey(pubkeys, pubkey) && !containsPubkey(vs.Config.PublisherPubkeys, pubkey) {
+		return errors.New("Cannot run as block publisher: invalid seckey for pubkey")
+	}
+
+	return nil
+}
+
 func initHistory(tx *dbutil.Tx, bc *Blockchain, history *historydb.HistoryDB) error {
 	logger.Info("Visor initHistory")
 
@@ -280,7 +329,7 @@ func (vs *Visor) RemoveInvalidUnconfirmed() ([]cipher.SHA256, error) {
 	var hashes []cipher.SHA256
 	if err := vs.db.Update("RemoveInvalidUnconfirmed", func(tx *dbutil.Tx) error {
 		var err error
-		hashes, err = vs.unconfirmed.RemoveInvalid(tx, vs.blockchain)
+		hashes, err = vs.removeInvalidUnconfirmed(tx, nil)
 		return err
 	}); err != nil {
 		return nil, err
@@ -289,6 +338,84 @@ func (vs *Visor) RemoveInvalidUnconfirmed() ([]cipher.SHA256, error) {
 	return hashes, nil
 }
 
+// removeInvalidUnconfirmed removes the transactions that violate hard constraints from the pool
+// and publishes their removal. poolInputs are the inputs of the pooled transactions read before a reorg,
+// since the inputs created by rolled back blocks can't be read from the HistoryDB afterwards.
+func (vs *Visor) removeInvalidUnconfirmed(tx *dbutil.Tx, poolInputs map[cipher.SHA256][]TransactionInput) ([]cipher.SHA256, error) {
+	publish := vs.hasSubscribers()
+
+	// The removed transactions can't be read from the pool afterwards
+	var pool map[cipher.SHA256]UnconfirmedTransaction
+	if publish {
+		pool = make(map[cipher.SHA256]UnconfirmedTransaction)
+		if err := vs.unconfirmed.ForEach(tx, func(h cipher.SHA256, ut UnconfirmedTransaction) error {
+			pool[h] = ut
+			return nil
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	hashes, err := vs.unconfirmed.RemoveInvalid(tx, vs.blockchain)
+	if err != nil {
+		return nil, err
+	}
+
+	if publish && len(hashes) != 0 {
+		var txns []UnconfirmedTransaction
+		var events []Event
+		for _, h := range hashes {
+			ut, ok := pool[h]
+			if !ok {
+				continue
+			}
+
+			if inputs, ok := poolInputs[h]; ok {
+				events = append(events, Event{
+					Type:              EventUnconfirmedTxnRemoved,
+					Transaction:       &ut,
+					TransactionInputs: inputs,
+					Reason:            RemovedTxnInvalid,
+				})
+				continue
+			}
+
+			txns = append(txns, ut)
+		}
+
+		txnEvents, err := vs.unconfirmedTxnEvents(tx, EventUnconfirmedTxnRemoved, txns, RemovedTxnInvalid)
+		if err != nil {
+			return nil, err
+		}
+		vs.publish(tx, append(events, txnEvents...)...)
+	}
+
+	return hashes, nil
+}
+
+// getUnconfirmedInputs returns the inputs of all the transactions in the unconfirmed pool
+func (vs *Visor) getUnconfirmedInputs(tx *dbutil.Tx) (map[cipher.SHA256][]TransactionInput, error) {
+	var txns []UnconfirmedTransaction
+	if err := vs.unconfirmed.ForEach(tx, func(_ cipher.SHA256, ut UnconfirmedTransaction) error {
+		txns = append(txns, ut)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	inputs, err := vs.getTransactionInputsForUnconfirmedTxns(tx, txns)
+	if err != nil {
+		return nil, err
+	}
+
+	poolInputs := make(map[cipher.SHA256][]TransactionInput, len(txns))
+	for i, ut := range txns {
+		poolInputs[ut.Transaction.Hash()] = inputs[i]
+	}
+
+	return poolInputs, nil
+}
+
 // createBlock creates a SignedBlock from pending transactions
 func (vs *Visor) createBlock(tx *dbutil.Tx, when uint64) (coin.SignedBlock, error) {
 	if !vs.Config.IsBlockPublisher {
@@ -317,61 +444,20 @@ func (vs *Visor) createBlockFromTxns(tx *dbutil.Tx, txns coin.Transactions, when
 
 	logger.Infof("unconfirmed pool has %d transactions pending", len(txns))
 
-	// Filter transactions that violate all constraints
-	var filteredTxns coin.Transactions
-	for _, txn := range txns {
-		if _, _, err := vs.blockchain.VerifySingleTxnSoftHardConstraints(tx, txn, vs.Config.Distribution, vs.Config.CreateBlockVerifyTxn, transaction.TxnSigned); err != nil {
-			switch err.(type) {
-			case transaction.ErrTxnViolatesHardConstraint, transaction.ErrTxnViolatesSoftConstraint:
-				logger.Warningf("Transaction %s violates constraints: %v", txn.Hash().Hex(), err)
-			default:
-				return coin.Block{}, err
-			}
-		} else {
-			filteredTxns = append(filteredTxns, txn)
-		}
-	}
-
-	nRemoved := len(txns) - len(filteredTxns)
-	if nRemoved > 0 {
-		logger.Infof("CreateBlock ignored %d transactions violating constraints", nRemoved)
-	}
-
-	txns = filteredTxns
-
-	if len(txns) == 0 {
-		logger.Info("No transactions after filtering for constraint violations")
-		return coin.Block{}, errors.New("No transactions after filtering for constraint violations")
-	}
-
-	head, err := vs.blockchain.Head(tx)
-	if err != nil {
-		return coin.Block{}, err
-	}
-
-	// Sort them by highest fee per kilobyte
-	txns, err = coin.SortTransactions(txns, vs.blockchain.TransactionFee(tx, head.Time()))
+	// Select the transactions that burn the highest fee per byte
+	bt, err := vs.newBlockTemplate(tx, txns)
 	if err != nil {
-		logger.Critical().WithError(err).Error("SortTransactions failed, no block can be made until the offending transaction is removed")
 		return coin.Block{}, err
 	}
 
-	// Apply block size transaction limit
-	txns, err = txns.TruncateBytesTo(vs.Config.MaxBlockTransactionsSize)
-	if err != nil {
-		logger.Critical().WithError(err).Error("TruncateBytesTo failed, no block can be made until the offending transaction is removed")
-		return coin.Block{}, err
-	}
-
-	if len(txns) > coin.MaxBlockTransactions {
-		txns = txns[:coin.MaxBlockTransactions]
-	}
+	txns = bt.Txns()
 
 	if len(txns) == 0 {
-		logger.Panic("TruncateBytesTo removed all transactions")
+		logger.Info("No transactions in the block template")
+		return coin.Block{}, errors.New("No transactions in the block template")
 	}
 
-	logger.Infof("Creating new block with %d transactions, head time %d", len(txns), when)
+	logger.Infof("Creating new block with %d transactions, fee %d, head time %d", len(txns), bt.Fee, when)
 
 	b, err := vs.blockchain.NewBlock(tx, txns, when)
 	if err != nil {
@@ -382,6 +468,26 @@ func (vs *Visor) createBlockFromTxns(tx *dbutil.Tx, txns coin.Transactions, when
 	return *b, nil
 }
 
+// GetBlockTemplate returns the transactions of the unconfirmed pool that the block publisher
+// would put into the next block
+func (vs *Visor) GetBlockTemplate() (*BlockTemplate, error) {
+	var bt *BlockTemplate
+
+	if err := vs.db.View("GetBlockTemplate", func(tx *dbutil.Tx) error {
+		txns, err := vs.unconfirmed.AllRawTransactions(tx)
+		if err != nil {
+			return err
+		}
+
+		bt, err = vs.newBlockTemplate(tx, txns)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return bt, nil
+}
+
 // CreateAndExecuteBlock creates a SignedBlock from pending transactions and executes it
 func (vs *Visor) CreateAndExecuteBlock() (coin.SignedBlock, error) {
 	var sb coin.SignedBlock
@@ -399,6 +505,28 @@ func (vs *Visor) CreateAndExecuteBlock() (coin.SignedBlock, error) {
 	return sb, err
 }
 
+// CreateBlock creates an unsigned Block from pending transactions without executing it.
+// The block is a candidate for the block publishers to sign.
+func (vs *Visor) CreateBlock() (coin.Block, error) {
+	if !vs.Config.IsBlockPublisher {
+		logger.Panic("Only a block publisher node can create blocks")
+	}
+
+	var b coin.Block
+
+	err := vs.db.View("CreateBlock", func(tx *dbutil.Tx) error {
+		txns, err := vs.unconfirmed.AllRawTransactions(tx)
+		if err != nil {
+			return err
+		}
+
+		b, err = vs.createBlockFromTxns(tx, txns, uint64(time.Now().UTC().Unix()))
+		return err
+	})
+
+	return b, err
+}
+
 // CreateBlockFromTxns creates a Block from specified set of transactions according to set of determinstic rules.
 func (vs *Visor) CreateBlockFromTxns(txns coin.Transactions, when uint64) (coin.Block, error) {
 	var sb coin.Block
@@ -431,6 +559,53 @@ func (vs *Visor) ExecuteSignedBlock(b coin.SignedBlock) error {
 	})
 }
 
+// ExecuteCertifiedBlock adds a block to the blockchain, or returns error.
+// sigs are the signatures of the block publishers that settled the block, which are stored as the certificate
+// of the block. A block signed by a block publisher must be signed by a quorum of block publishers.
+// A block that does not extend the head block is stored as a side block, see AddSideBlock.
+// Nothing is stored if the block is invalid.
+func (vs *Visor) ExecuteCertifiedBlock(b coin.SignedBlock, sigs []cipher.Sig) error {
+	return vs.db.Update("ExecuteCertifiedBlock", func(tx *dbutil.Tx) error {
+		if err := vs.blockchain.AddBlockCertificate(tx, b.HashHeader(), sigs); err != nil {
+			return err
+		}
+
+		head, err := vs.blockchain.Head(tx)
+		if err != nil && err != blockdb.ErrNoHeadBlock {
+			return err
+		}
+
+		if head != nil && b.Head.PrevHash != head.HashHeader() {
+			return vs.addSideBlock(tx, b)
+		}
+
+		return vs.executeSignedBlock(tx, b)
+	})
+}
+
+// GetBlockCertificates returns the block publisher signatures of the blocks with hashes.
+// Blocks without a certificate, e.g. blocks signed by the blockchain pubkey, are skipped.
+func (vs *Visor) GetBlockCertificates(hashes []cipher.SHA256) ([]blockdb.BlockCertificate, error) {
+	var certs []blockdb.BlockCertificate
+	if err := vs.db.View("GetBlockCertificates", func(tx *dbutil.Tx) error {
+		for _, hash := range hashes {
+			cert, err := vs.blockchain.GetBlockCertificate(tx, hash)
+			if err != nil {
+				return err
+			}
+
+			if cert != nil {
+				certs = append(certs, *cert)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return certs, nil
+}
+
 // ExecuteSignedBlockUnsafe adds block to the blockchain, or returns error.
 // Blocks must be executed in sequence. Block signature is not verified.
 func (vs *Visor) ExecuteSignedBlockUnsafe(b coin.SignedBlock) error {
@@ -442,7 +617,7 @@ func (vs *Visor) ExecuteSignedBlockUnsafe(b coin.SignedBlock) error {
 // executeSignedBlock adds a block to the blockchain, or returns error.
 // Blocks must be executed in sequence, and be signed by a block publisher node.
 func (vs *Visor) executeSignedBlock(tx *dbutil.Tx, b coin.SignedBlock) error {
-	if err := b.VerifySignature(vs.Config.BlockchainPubkey); err != nil {
+	if err := vs.blockchain.VerifySignature(tx, &b); err != nil {
 		return err
 	}
 
@@ -462,12 +637,269 @@ func (vs *Visor) executeSignedBlockUnsafe(tx *dbutil.Tx, b coin.SignedBlock) err
 		txnHashes = append(txnHashes, txn.Hash())
 	}
 
+	publish := vs.hasSubscribers()
+
+	var confirmed []UnconfirmedTransaction
+	if publish {
+		var err error
+		confirmed, err = vs.getUnconfirmedTxns(tx, txnHashes)
+		if err != nil {
+			return err
+		}
+	}
+
 	if err := vs.unconfirmed.RemoveTransactions(tx, txnHashes); err != nil {
 		return err
 	}
 
 	// Update the HistoryDB
-	return vs.history.ParseBlock(tx, b.Block)
+	if err := vs.history.ParseBlock(tx, b.Block); err != nil {
+		return err
+	}
+
+	if publish {
+		events, err := vs.blockEvents(tx, b, confirmed)
+		if err != nil {
+			return err
+		}
+		vs.publish(tx, events...)
+	}
+
+	return nil
+}
+
+// AddSideBlock stores a block signed by a block publisher that does not extend the head block, e.g. a block of
+// a competing branch after a block publisher key rotation or a double signing incident.
+// sigs are the block publisher signatures received for the block, which are merged into its certificate.
+// The parent of the block must be stored already, blocks that are stored already are ignored.
+// If the branch that ends with the block is longer than the main chain, the chain is reorganized to the branch
+// in the same database transaction.
+func (vs *Visor) AddSideBlock(b coin.SignedBlock, sigs []cipher.Sig) error {
+	hash := b.HashHeader()
+
+	var known bool
+	if err := vs.db.View("AddSideBlock", func(tx *dbutil.Tx) error {
+		sb, err := vs.blockchain.GetSignedBlockByHash(tx, hash)
+		known = sb != nil
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if known {
+		return nil
+	}
+
+	return vs.db.Update("AddSideBlock", func(tx *dbutil.Tx) error {
+		if err := vs.blockchain.AddBlockCertificate(tx, hash, sigs); err != nil {
+			return err
+		}
+
+		return vs.addSideBlock(tx, b)
+	})
+}
+
+// addSideBlock stores a side block and reorganizes the chain to its branch if the branch is longer than the main chain
+func (vs *Visor) addSideBlock(tx *dbutil.Tx, b coin.SignedBlock) error {
+	if err := vs.blockchain.VerifySignature(tx, &b); err != nil {
+		return err
+	}
+
+	if err := vs.blockchain.AddSideBlock(tx, &b); err != nil {
+		return err
+	}
+
+	headSeq, _, err := vs.blockchain.HeadSeq(tx)
+	if err != nil {
+		return err
+	}
+
+	if b.Seq() <= headSeq {
+		return nil
+	}
+
+	return vs.reorg(tx, b.HashHeader())
+}
+
+// reorg makes the branch that ends with the block of given hash the main chain.
+// The unspent pool, the HistoryDB and the unconfirmed pool are rolled back to the common ancestor of the branch
+// and the main chain, then the blocks of the branch are applied. The transactions of the rolled back blocks
+// that are not in the branch are returned to the unconfirmed pool, if they are still valid.
+// Nothing is changed if any block of the branch is invalid, since the database transaction is rolled back.
+// A branch that would roll back more than Config.MaxReorgDepth blocks of the main chain is rejected.
+func (vs *Visor) reorg(tx *dbutil.Tx, hash cipher.SHA256) error {
+	headSeq, _, err := vs.blockchain.HeadSeq(tx)
+	if err != nil {
+		return err
+	}
+
+	// Collect the blocks of the branch, from the tip down to the common ancestor
+	var branch []coin.SignedBlock
+	for {
+		b, err := vs.blockchain.GetSignedBlockByHash(tx, hash)
+		if err != nil {
+			return err
+		} else if b == nil {
+			return fmt.Errorf("Block %s of the branch is not stored", hash.Hex())
+		}
+
+		mb, err := vs.blockchain.GetSignedBlockBySeq(tx, b.Seq())
+		if err != nil {
+			return err
+		}
+
+		if mb != nil && mb.HashHeader() == b.HashHeader() {
+			break
+		}
+
+		// Rolling back to the parent of b would roll back more than MaxReorgDepth blocks
+		if vs.Config.MaxReorgDepth != 0 && b.Seq()+vs.Config.MaxReorgDepth <= headSeq {
+			return ErrReorgTooDeep
+		}
+
+		branch = append(branch, *b)
+		hash = b.Head.PrevHash
+	}
+
+	if len(branch) == 0 {
+		return ErrReorgMainChain
+	}
+
+	ancestorSeq := branch[len(branch)-1].Seq() - 1
+
+	publish := vs.hasSubscribers()
+
+	var poolInputs map[cipher.SHA256][]TransactionInput
+	if publish {
+		poolInputs, err = vs.getUnconfirmedInputs(tx)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Roll back the main chain to the common ancestor
+	var rolledBack coin.Transactions
+	for seq := headSeq; seq > ancestorSeq; seq-- {
+		head, err := vs.blockchain.Head(tx)
+		if err != nil {
+			return err
+		}
+
+		if publish {
+			e, err := vs.blockDisconnectedEvent(tx, *head)
+			if err != nil {
+				return err
+			}
+			vs.publish(tx, e)
+		}
+
+		var inputs []cipher.SHA256
+		for _, txn := range head.Body.Transactions {
+			inputs = append(inputs, txn.In...)
+		}
+
+		uxOuts, err := vs.history.GetUxOuts(tx, inputs)
+		if err != nil {
+			return err
+		}
+
+		spent := make(coin.UxArray, len(uxOuts))
+		for i, ux := range uxOuts {
+			spent[i] = ux.Out
+		}
+
+		if err := vs.history.RollbackBlock(tx, head.Block); err != nil {
+			return err
+		}
+
+		if err := vs.blockchain.RollbackHead(tx, spent); err != nil {
+			return err
+		}
+
+		// Copy the transactions of the block, appending to them could write into the backing array of the block
+		txns := make(coin.Transactions, len(head.Body.Transactions), len(head.Body.Transactions)+len(rolledBack))
+		copy(txns, head.Body.Transactions)
+		rolledBack = append(txns, rolledBack...)
+	}
+
+	// Apply the blocks of the branch
+	branchTxns := make(map[cipher.SHA256]struct{})
+	for i := len(branch) - 1; i >= 0; i-- {
+		b := branch[i]
+
+		if err := vs.blockchain.VerifySignature(tx, &b); err != nil {
+			return err
+		}
+
+		if err := vs.blockchain.ApplySideBlock(tx, &b); err != nil {
+			return err
+		}
+
+		txnHashes := make([]cipher.SHA256, 0, len(b.Body.Transactions))
+		for _, txn := range b.Body.Transactions {
+			h := txn.Hash()
+			txnHashes = append(txnHashes, h)
+			branchTxns[h] = struct{}{}
+		}
+
+		var confirmed []UnconfirmedTransaction
+		if publish {
+			var err error
+			confirmed, err = vs.getUnconfirmedTxns(tx, txnHashes)
+			if err != nil {
+				return err
+			}
+		}
+
+		if err := vs.unconfirmed.RemoveTransactions(tx, txnHashes); err != nil {
+			return err
+		}
+
+		if err := vs.history.ParseBlock(tx, b.Block); err != nil {
+			return err
+		}
+
+		if publish {
+			events, err := vs.blockEvents(tx, b, confirmed)
+			if err != nil {
+				return err
+			}
+			vs.publish(tx, events...)
+		}
+	}
+
+	// Remove the unconfirmed transactions that spend outputs of the rolled back blocks
+	if _, err := vs.removeInvalidUnconfirmed(tx, poolInputs); err != nil {
+		return err
+	}
+
+	// Return the transactions of the rolled back blocks to the unconfirmed pool
+	for _, txn := range rolledBack {
+		if _, ok := branchTxns[txn.Hash()]; ok {
+			continue
+		}
+
+		known, _, err := vs.unconfirmed.InjectTransaction(tx, vs.blockchain, txn, vs.Config.Distribution, vs.Config.UnconfirmedVerifyTxn)
+		if err != nil {
+			switch err.(type) {
+			case transaction.ErrTxnViolatesHardConstraint, transaction.ErrTxnViolatesSoftConstraint:
+				logger.WithError(err).WithField("txid", txn.Hash().Hex()).Info("Dropped transaction of a rolled back block")
+				continue
+			default:
+				return err
+			}
+		}
+
+		if !known {
+			if err := vs.publishUnconfirmedTxnAdded(tx, txn); err != nil {
+				return err
+			}
+		}
+	}
+
+	logger.Infof("Reorganized the blockchain from head %d to head %d, common ancestor %d", headSeq, branch[0].Seq(), ancestorSeq)
+
+	return nil
 }
 
 // signBlock signs a block for a block publisher node. Will panic if anything is invalid
@@ -632,6 +1064,7 @@ func (vs *Visor) HeadBkSeq() (uint64, bool, error) {
 func (vs *Visor) GetBlockchainMetadata() (*BlockchainMetadata, error) {
 	var head *coin.SignedBlock
 	var unconfirmedLen, unspentsLen uint64
+	var keyCheckpoints []blockdb.KeyCheckpoint
 
 	if err := vs.db.View("GetBlockchainMetadata", func(tx *dbutil.Tx) error {
 		var err error
@@ -646,12 +1079,76 @@ func (vs *Visor) GetBlockchainMetadata() (*BlockchainMetadata, error) {
 		}
 
 		unspentsLen, err = vs.blockchain.Unspent().Len(tx)
+		if err != nil {
+			return err
+		}
+
+		keyCheckpoints, err = vs.blockchain.GetKeyCheckpoints(tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return NewBlockchainMetadata(*head, unconfirmedLen, unspentsLen, keyCheckpoints)
+}
+
+// GetKeyCheckpoints returns the block publisher key checkpoints, ordered by seq
+func (vs *Visor) GetKeyCheckpoints() ([]blockdb.KeyCheckpoint, error) {
+	var checkpoints []blockdb.KeyCheckpoint
+	if err := vs.db.View("GetKeyCheckpoints", func(tx *dbutil.Tx) error {
+		var err error
+		checkpoints, err = vs.blockchain.GetKeyCheckpoints(tx)
 		return err
 	}); err != nil {
 		return nil, err
 	}
 
-	return NewBlockchainMetadata(*head, unconfirmedLen, unspentsLen)
+	return checkpoints, nil
+}
+
+// BlockchainPubkeys returns the genesis blockchain pubkey followed by the pubkeys of the key checkpoints
+func (vs *Visor) BlockchainPubkeys() ([]cipher.PubKey, error) {
+	var pubkeys []cipher.PubKey
+	if err := vs.db.View("BlockchainPubkeys", func(tx *dbutil.Tx) error {
+		var err error
+		pubkeys, err = vs.blockchain.BlockchainPubkeys(tx)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return pubkeys, nil
+}
+
+// AddKeyCheckpoint adds a key checkpoint that hands the block signing authority to a new pubkey.
+// Returns false if the checkpoint is known already.
+func (vs *Visor) AddKeyCheckpoint(kc blockdb.KeyCheckpoint) (bool, error) {
+	var added bool
+	if err := vs.db.Update("AddKeyCheckpoint", func(tx *dbutil.Tx) error {
+		var err error
+		added, err = vs.blockchain.AddKeyCheckpoint(tx, kc)
+		return err
+	}); err != nil {
+		return false, err
+	}
+
+	return added, nil
+}
+
+// CreateKeyCheckpoint creates a key checkpoint that hands the block signing authority to pubkey from
+// the block with seq onward, signs it with the block publisher seckey and adds it.
+// The block publisher must then be restarted with the seckey of pubkey before the block with seq is created.
+func (vs *Visor) CreateKeyCheckpoint(seq uint64, pubkey cipher.PubKey) (blockdb.KeyCheckpoint, error) {
+	if !vs.Config.IsBlockPublisher {
+		return blockdb.KeyCheckpoint{}, ErrKeyCheckpointNotPublisher
+	}
+
+	kc := blockdb.NewKeyCheckpoint(seq, pubkey, vs.Config.BlockchainSeckey)
+	if _, err := vs.AddKeyCheckpoint(kc); err != nil {
+		return blockdb.KeyCheckpoint{}, err
+	}
+
+	return kc, nil
 }
 
 // GetBlock returns a copy of the block at seq. Returns error if seq out of range
@@ -814,7 +1311,11 @@ func (vs *Visor) InjectForeignTransaction(txn coin.Transaction) (bool, *transact
 	if err := vs.db.Update("InjectForeignTransaction", func(tx *dbutil.Tx) error {
 		var err error
 		known, softErr, err = vs.unconfirmed.InjectTransaction(tx, vs.blockchain, txn, vs.Config.Distribution, vs.Config.UnconfirmedVerifyTxn)
-		return err
+		if err != nil || known {
+			return err
+		}
+
+		return vs.publishUnconfirmedTxnAdded(tx, txn)
 	}); err != nil {
 		return false, nil, err
 	}
@@ -862,6 +1363,12 @@ func (vs *Visor) InjectUserTransactionTx(tx *dbutil.Tx, txn coin.Transaction) (b
 		logger.WithError(softErr).Warning("InjectUserTransaction vs.unconfirmed.InjectTransaction returned a softErr unexpectedly")
 	}
 
+	if err == nil && !known {
+		if err := vs.publishUnconfirmedTxnAdded(tx, txn); err != nil {
+			return false, nil, nil, err
+		}
+	}
+
 	return known, head, inputs, err
 }
 
@@ -1160,6 +1667,22 @@ func (vs *Visor) GetAllUnconfirmedTransactions() ([]UnconfirmedTransaction, erro
 	return txns, nil
 }
 
+// GetUnconfirmedMinFeePerKB returns the coin hour fee per 1000 bytes that a transaction must exceed
+// to enter the unconfirmed pool. Returns 0 if the pool is not full.
+func (vs *Visor) GetUnconfirmedMinFeePerKB() (uint64, error) {
+	var minFee UnconfirmedTxnFee
+
+	if err := vs.db.View("GetUnconfirmedMinFeePerKB", func(tx *dbutil.Tx) error {
+		var err error
+		minFee, err = vs.unconfirmed.MinFee(tx, vs.Config.UnconfirmedVerifyTxn.MaxTransactionSize)
+		return err
+	}); err != nil {
+		return 0, err
+	}
+
+	return minFee.PerKB(), nil
+}
+
 // GetAllUnconfirmedTransactionsVerbose returns all unconfirmed transactions with verbose transaction input data
 func (vs *Visor) GetAllUnconfirmedTransactionsVerbose() ([]UnconfirmedTransaction, [][]TransactionInput, error) {
 	var txns []UnconfirmedTransaction
@@ -1710,124 +2233,14 @@ func (vs Visor) GetBalanceOfAddresses(addrs []cipher.Address) ([]wallet.BalanceP
 		return nil, nil
 	}
 
-	auxs := make(coin.AddressUxOuts, len(addrs))
-	recvUxs := make(coin.AddressUxOuts, len(addrs))
-	var uxa coin.UxArray
-	var head *coin.SignedBlock
-
-	if err := vs.db.View("GetBalanceOfAddresses", func(tx *dbutil.Tx) error {
-		var err error
-		head, err = vs.blockchain.Head(tx)
-		if err != nil {
-			return err
-		}
-
-		// Get all transactions from the unconfirmed pool
-		txns, err := vs.unconfirmed.AllRawTransactions(tx)
-		if err != nil {
-			return err
-		}
-
-		// Create predicted unspent outputs from the unconfirmed transactions
-		recvUxs, err = txnOutputsForAddrs(head.Head, addrs, txns)
-		if err != nil {
-			return err
-		}
-
-		var inputs []cipher.SHA256
-		for _, txn := range txns {
-			inputs = append(inputs, txn.In...)
-		}
-
-		// Get unspents for the inputs being spent
-		uxa, err = vs.blockchain.Unspent().GetArray(tx, inputs)
-		if err != nil {
-			return fmt.Errorf("GetArray failed when checking addresses balance: %v", err)
-		}
-
-		// Get unspents owned by the addresses
-		auxs, err = vs.blockchain.Unspent().GetUnspentsOfAddrs(tx, addrs)
-		if err != nil {
-			return fmt.Errorf("GetUnspentsOfAddrs failed when checking addresses balance: %v", err)
-		}
-
+	bps := make([]wallet.BalancePair, 0, len(addrs))
+	if err := vs.WalkBalancesOfAddresses(addrs, func(_ uint64, b AddressBalancePair) error {
+		bps = append(bps, b.BalancePair)
 		return nil
 	}); err != nil {
 		return nil, err
 	}
 
-	// Build all unconfirmed transaction inputs that are associated with the addresses
-	spendUxs := make(coin.AddressUxOuts, len(addrs))
-
-	addrm := make(map[cipher.Address]struct{}, len(addrs))
-	for _, addr := range addrs {
-		addrm[addr] = struct{}{}
-	}
-
-	for _, ux := range uxa {
-		if _, ok := addrm[ux.Body.Address]; ok {
-			spendUxs[ux.Body.Address] = append(spendUxs[ux.Body.Address], ux)
-		}
-	}
-
-	var bps []wallet.BalancePair
-
-	headTime := head.Time()
-	for _, addr := range addrs {
-		uxs, ok := auxs[addr]
-		if !ok {
-			bps = append(bps, wallet.BalancePair{})
-			continue
-		}
-
-		outUxs := spendUxs[addr]
-		inUxs := recvUxs[addr]
-		predictedUxs := uxs.Sub(outUxs).Add(inUxs)
-
-		coins, err := uxs.Coins()
-		if err != nil {
-			return nil, fmt.Errorf("uxs.Coins failed: %v", err)
-		}
-
-		coinHours, err := uxs.CoinHours(headTime)
-		if err != nil {
-			switch err {
-			case coin.ErrAddEarnedCoinHoursAdditionOverflow:
-				coinHours = 0
-			default:
-				return nil, fmt.Errorf("uxs.CoinHours failed: %v", err)
-			}
-		}
-
-		pcoins, err := predictedUxs.Coins()
-		if err != nil {
-			return nil, fmt.Errorf("predictedUxs.Coins failed: %v", err)
-		}
-
-		pcoinHours, err := predictedUxs.CoinHours(headTime)
-		if err != nil {
-			switch err {
-			case coin.ErrAddEarnedCoinHoursAdditionOverflow:
-				coinHours = 0
-			default:
-				return nil, fmt.Errorf("predictedUxs.CoinHours failed: %v", err)
-			}
-		}
-
-		bp := wallet.BalancePair{
-			Confirmed: wallet.Balance{
-				Coins: coins,
-				Hours: coinHours,
-			},
-			Predicted: wallet.Balance{
-				Coins: pcoins,
-				Hours: pcoinHours,
-			},
-		}
-
-		bps = append(bps, bp)
-	}
-
 	return bps, nil
 }
 
@@ -2160,44 +2573,56 @@ func (vs *Visor) GetUnspentOutputsSummary(filters []OutputsFilter) (*UnspentOutp
 	}, nil
 }
 
-// GetRichlist returns a Richlist
-func (vs *Visor) GetRichlist(includeDistribution bool) (Richlist, error) {
-	rbOuts, err := vs.GetUnspentOutputsSummary(nil)
-	if err != nil {
-		return nil, err
+// GetRichlist returns the top n of the Richlist, or the whole Richlist if n <= 0.
+// Balances are read from the address balance index of the unspent pool, so only
+// the top of the index is walked.
+func (vs *Visor) GetRichlist(includeDistribution bool, n int) (Richlist, error) {
+	lockedAddrs := vs.Config.Distribution.LockedAddressesDecoded()
+	lockedMap := make(map[cipher.Address]struct{}, len(lockedAddrs))
+	for _, a := range lockedAddrs {
+		lockedMap[a] = struct{}{}
 	}
 
-	// Build a map from addresses to total coins held
-	allAccounts := map[cipher.Address]uint64{}
-	for _, out := range rbOuts.Confirmed {
-		if _, ok := allAccounts[out.Body.Address]; ok {
-			var err error
-			allAccounts[out.Body.Address], err = mathutil.AddUint64(allAccounts[out.Body.Address], out.Body.Coins)
-			if err != nil {
-				return nil, err
-			}
-		} else {
-			allAccounts[out.Body.Address] = out.Body.Coins
+	excludedMap := make(map[cipher.Address]struct{})
+	if !includeDistribution {
+		for _, a := range lockedAddrs {
+			excludedMap[a] = struct{}{}
+		}
+		for _, a := range vs.Config.Distribution.UnlockedAddressesDecoded() {
+			excludedMap[a] = struct{}{}
 		}
 	}
 
-	lockedAddrs := vs.Config.Distribution.LockedAddressesDecoded()
-	addrsMap := make(map[cipher.Address]struct{}, len(lockedAddrs))
-	for _, a := range lockedAddrs {
-		addrsMap[a] = struct{}{}
-	}
+	var richlist Richlist
+	if err := vs.db.View("GetRichlist", func(tx *dbutil.Tx) error {
+		return vs.blockchain.Unspent().WalkAddressBalances(tx, func(b blockdb.AddressBalance) (bool, error) {
+			// Keep walking through the balances equal to the nth balance,
+			// since locked addresses are sorted before unlocked addresses with the same coins
+			if n > 0 && len(richlist) >= n && b.Coins < richlist[n-1].Coins {
+				return false, nil
+			}
 
-	richlist, err := NewRichlist(allAccounts, addrsMap)
-	if err != nil {
+			if _, ok := excludedMap[b.Address]; ok {
+				return true, nil
+			}
+
+			_, locked := lockedMap[b.Address]
+			richlist = append(richlist, RichlistBalance{
+				Address: b.Address,
+				Coins:   b.Coins,
+				Locked:  locked,
+			})
+
+			return true, nil
+		})
+	}); err != nil {
 		return nil, err
 	}
 
-	if !includeDistribution {
-		unlockedAddrs := vs.Config.Distribution.UnlockedAddressesDecoded()
-		for _, a := range unlockedAddrs {
-			addrsMap[a] = struct{}{}
-		}
-		richlist = richlist.FilterAddresses(addrsMap)
+	richlist.sortBalances()
+
+	if n > 0 && len(richlist) > n {
+		richlist = richlist[:n]
 	}
 
 	return richlist, nil
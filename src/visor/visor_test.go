@@ -19,6 +19,8 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ness-network/ness/src/visor/blockdb"
+	"github.com/ness-network/ness/src/visor/historydb"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/params"
@@ -26,9 +28,7 @@ import (
 	_require "github.com/skycoin/skycoin/src/testutil/require"
 	"github.com/skycoin/skycoin/src/util/fee"
 	"github.com/skycoin/skycoin/src/util/timeutil"
-	"github.com/skycoin/skycoin/src/visor/blockdb"
 	"github.com/skycoin/skycoin/src/visor/dbutil"
-	"github.com/skycoin/skycoin/src/visor/historydb"
 )
 
 const (
@@ -148,7 +148,7 @@ func TestErrMissingSignatureRecreateDB(t *testing.T) {
 
 		// err = db.View("", func(tx *dbutil.Tx) error {
 		f := func(tx *dbutil.Tx, b *coin.SignedBlock) error {
-			return bc.VerifySignature(b)
+			return bc.VerifySignature(tx, b)
 		}
 
 		err = bc.WalkChain(BlockchainVerifyTheadNum, f, nil)
@@ -165,7 +165,7 @@ func TestErrMissingSignatureRecreateDB(t *testing.T) {
 	require.NotEmpty(t, badDB.Path())
 	t.Logf("badDB.Path() == %s", badDB.Path())
 
-	db, err := ResetCorruptDB(badDB, pubkey, nil)
+	db, err := ResetCorruptDB(badDB, pubkey, nil, 0, nil)
 	require.NoError(t, err)
 
 	err = db.Close()
@@ -272,7 +272,7 @@ func TestVisorCreateBlock(t *testing.T) {
 		Pubkey: genPublic,
 	})
 
-	unconfirmed, err := NewUnconfirmedTransactionPool(db)
+	unconfirmed, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{})
 	require.NoError(t, err)
 
 	his := historydb.New()
@@ -497,7 +497,7 @@ func TestVisorInjectTransaction(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	unconfirmed, err := NewUnconfirmedTransactionPool(db)
+	unconfirmed, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{})
 	require.NoError(t, err)
 
 	his := historydb.New()
@@ -1980,7 +1980,7 @@ func TestRefreshUnconfirmed(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	unconfirmed, err := NewUnconfirmedTransactionPool(db)
+	unconfirmed, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{})
 	require.NoError(t, err)
 
 	his := historydb.New()
@@ -2111,7 +2111,7 @@ func TestRemoveInvalidUnconfirmedDoubleSpendArbitrating(t *testing.T) {
 	})
 	require.NoError(t, err)
 
-	unconfirmed, err := NewUnconfirmedTransactionPool(db)
+	unconfirmed, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{})
 	require.NoError(t, err)
 
 	his := historydb.New()
@@ -2211,6 +2211,633 @@ func TestRemoveInvalidUnconfirmedDoubleSpendArbitrating(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestVisorReorg(t *testing.T) {
+	publisherPubkey, publisherSeckey := cipher.GenerateKeyPair()
+
+	newVisor := func() (*Visor, func()) {
+		db, shutdown := prepareDB(t)
+
+		bc, err := NewBlockchain(db, BlockchainConfig{
+			Pubkey:           genPublic,
+			PublisherPubkeys: []cipher.PubKey{publisherPubkey},
+		})
+		require.NoError(t, err)
+
+		unconfirmed, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{})
+		require.NoError(t, err)
+
+		cfg := NewConfig()
+		cfg.IsBlockPublisher = true
+		cfg.BlockchainPubkey = genPublic
+		cfg.PublisherPubkeys = []cipher.PubKey{publisherPubkey}
+		cfg.GenesisAddress = genAddress
+		cfg.BlockchainSeckey = genSecret
+
+		v := &Visor{
+			Config:      cfg,
+			unconfirmed: unconfirmed,
+			blockchain:  bc,
+			db:          db,
+			history:     historydb.New(),
+			events:      newEventBroker(),
+		}
+
+		addGenesisBlockToVisor(t, v)
+
+		return v, shutdown
+	}
+
+	// makeBlock creates a block on top of the head block of v, signed by seckey
+	makeBlock := func(v *Visor, txns coin.Transactions, when uint64, seckey cipher.SecKey) coin.SignedBlock {
+		b, err := v.CreateBlockFromTxns(txns, when)
+		require.NoError(t, err)
+		require.Equal(t, len(txns), len(b.Body.Transactions))
+
+		return coin.SignedBlock{
+			Block: b,
+			Sig:   cipher.MustSignHash(b.HashHeader(), seckey),
+		}
+	}
+
+	// main is reorganized to the branch executed by fork
+	main, shutdownMain := newVisor()
+	defer shutdownMain()
+	fork, shutdownFork := newVisor()
+	defer shutdownFork()
+
+	gb, err := main.GetSignedBlockBySeq(0)
+	require.NoError(t, err)
+	genUxs := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])
+
+	pubA, secA := cipher.GenerateKeyPair()
+	addrA := cipher.AddressFromPubKey(pubA)
+	pubD, secD := cipher.GenerateKeyPair()
+	addrD := cipher.AddressFromPubKey(pubD)
+	pubE, secE := cipher.GenerateKeyPair()
+	addrE := cipher.AddressFromPubKey(pubE)
+	addrB := testutil.MakeAddress()
+	addrC := testutil.MakeAddress()
+	addrF := testutil.MakeAddress()
+
+	// Common block: split the genesis output between addrA and genAddress
+	txn1 := makeSpendTxn(t, genUxs, []cipher.SecKey{genSecret}, addrA, 10e6)
+	b1 := makeBlock(main, coin.Transactions{txn1}, genTime+100, genSecret)
+	require.NoError(t, main.ExecuteSignedBlock(b1))
+	require.NoError(t, fork.ExecuteSignedBlock(b1))
+	b1Uxs := coin.CreateUnspents(b1.Head, txn1)
+
+	// Main chain: spend the output of addrA to addrB, then the output of genAddress to addrC
+	txn2 := makeSpendTxn(t, b1Uxs[:1], []cipher.SecKey{secA}, addrB, 10e6)
+	b2 := makeBlock(main, coin.Transactions{txn2}, genTime+200, genSecret)
+	require.NoError(t, main.ExecuteSignedBlock(b2))
+
+	txn3 := makeSpendTxn(t, b1Uxs[1:], []cipher.SecKey{genSecret}, addrC, 10e6)
+	b3 := makeBlock(main, coin.Transactions{txn3}, genTime+300, genSecret)
+	require.NoError(t, main.ExecuteSignedBlock(b3))
+
+	// Side branch, signed by the block publisher: double spend the output of addrA to addrD, then spend it to addrE
+	txn2b := makeSpendTxn(t, b1Uxs[:1], []cipher.SecKey{secA}, addrD, 10e6)
+	b2b := makeBlock(fork, coin.Transactions{txn2b}, genTime+250, publisherSeckey)
+	require.NoError(t, fork.ExecuteSignedBlock(b2b))
+
+	txn4 := makeSpendTxn(t, coin.CreateUnspents(b2b.Head, txn2b), []cipher.SecKey{secD}, addrE, 10e6)
+	b3b := makeBlock(fork, coin.Transactions{txn4}, genTime+350, publisherSeckey)
+	require.NoError(t, fork.ExecuteSignedBlock(b3b))
+
+	txn7 := makeSpendTxn(t, coin.CreateUnspents(b3b.Head, txn4), []cipher.SecKey{secE}, addrF, 10e6)
+	b4b := makeBlock(fork, coin.Transactions{txn7}, genTime+450, publisherSeckey)
+	require.NoError(t, fork.ExecuteSignedBlock(b4b))
+
+	// An invalid block on top of the common block, spending the output of addrA twice
+	b2c, err := coin.NewBlock(b1.Block, genTime+260, b2b.Head.UxHash, coin.Transactions{txn2b, txn2}, feeCalc)
+	require.NoError(t, err)
+	sb2c := coin.SignedBlock{
+		Block: *b2c,
+		Sig:   cipher.MustSignHash(b2c.HashHeader(), publisherSeckey),
+	}
+
+	// Side blocks must be signed by a block publisher and have a known parent
+	_, badSeckey := cipher.GenerateKeyPair()
+	err = main.AddSideBlock(coin.SignedBlock{
+		Block: b2b.Block,
+		Sig:   cipher.MustSignHash(b2b.HashHeader(), badSeckey),
+	}, nil)
+	require.Equal(t, ErrBlockSignerNotPublisher, err)
+
+	err = main.AddSideBlock(b3b, nil)
+	require.Equal(t, ErrSideBlockNoParent, err)
+
+	require.NoError(t, main.AddSideBlock(b2b, nil))
+	require.NoError(t, main.AddSideBlock(b3b, nil))
+	require.NoError(t, main.AddSideBlock(sb2c, nil))
+
+	// Blocks that are stored already are ignored
+	require.NoError(t, main.AddSideBlock(b2b, nil))
+	require.NoError(t, main.AddSideBlock(b2, nil))
+
+	// Adding side blocks that are not above the head block does not change the main chain
+	head, _, err := main.HeadBkSeq()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), head)
+
+	reorg := func(hash cipher.SHA256) error {
+		return main.db.Update("", func(tx *dbutil.Tx) error {
+			return main.reorg(tx, hash)
+		})
+	}
+
+	// Reorganizing to a block of the main chain fails
+	err = reorg(b2.HashHeader())
+	require.Equal(t, ErrReorgMainChain, err)
+
+	// Put a txn spending the change output of txn3 in the unconfirmed pool, it is invalid after the reorg
+	txn5 := makeSpendTxn(t, coin.CreateUnspents(b3.Head, txn3)[1:], []cipher.SecKey{genSecret}, addrB, 10e6)
+	_, softErr, err := main.InjectForeignTransaction(txn5)
+	require.NoError(t, err)
+	require.Nil(t, softErr)
+
+	type chainState struct {
+		headHash    cipher.SHA256
+		uxHash      cipher.SHA256
+		unspents    coin.UxArray
+		unconfirmed []cipher.SHA256
+	}
+
+	getChainState := func(v *Visor) chainState {
+		var state chainState
+		err := v.db.View("", func(tx *dbutil.Tx) error {
+			head, err := v.blockchain.Head(tx)
+			require.NoError(t, err)
+			state.headHash = head.HashHeader()
+
+			state.uxHash, err = v.blockchain.Unspent().GetUxHash(tx)
+			require.NoError(t, err)
+
+			state.unspents, err = v.blockchain.Unspent().GetAll(tx)
+			require.NoError(t, err)
+			sort.Slice(state.unspents, func(i, j int) bool {
+				a := state.unspents[i].Hash()
+				b := state.unspents[j].Hash()
+				return bytes.Compare(a[:], b[:]) < 0
+			})
+
+			state.unconfirmed, err = v.unconfirmed.GetHashes(tx, All)
+			require.NoError(t, err)
+
+			return nil
+		})
+		require.NoError(t, err)
+		return state
+	}
+
+	// Reorganizing to a branch that rolls back more than MaxReorgDepth blocks fails and changes nothing
+	before := getChainState(main)
+	main.Config.MaxReorgDepth = 1
+	err = reorg(b3b.HashHeader())
+	require.Equal(t, ErrReorgTooDeep, err)
+	require.Equal(t, before, getChainState(main))
+	main.Config.MaxReorgDepth = DefaultMaxReorgDepth
+
+	// Reorganizing to an invalid branch fails and changes nothing
+	err = reorg(sb2c.HashHeader())
+	require.EqualError(t, err, "Cannot spend output twice in the same block")
+	require.Equal(t, before, getChainState(main))
+
+	sub := main.SubscribeEvents()
+	defer sub.Unsubscribe()
+
+	// A block that does not extend the head block is stored as a side block, and the chain is reorganized
+	// to the branch of the block publisher once it is longer than the main chain
+	err = main.ExecuteCertifiedBlock(b4b, nil)
+	require.NoError(t, err)
+
+	// The rolled back blocks are published from the head block down, followed by the blocks of the branch
+	// and the changes of the unconfirmed pool
+	for _, expect := range []struct {
+		typ   EventType
+		block *coin.SignedBlock
+		txn   coin.Transaction
+	}{
+		{typ: EventBlockDisconnected, block: &b3},
+		{typ: EventBlockDisconnected, block: &b2},
+		{typ: EventBlock, block: &b2b},
+		{typ: EventBlock, block: &b3b},
+		{typ: EventBlock, block: &b4b},
+		{typ: EventUnconfirmedTxnRemoved, txn: txn5},
+		{typ: EventUnconfirmedTxnAdded, txn: txn3},
+	} {
+		e := <-sub.Events
+		require.Equal(t, expect.typ, e.Type)
+		if expect.block != nil {
+			require.Equal(t, *expect.block, *e.Block)
+			require.Len(t, e.BlockInputs, len(e.Block.Body.Transactions))
+		} else {
+			require.Equal(t, expect.txn, e.Transaction.Transaction)
+		}
+	}
+	require.Len(t, sub.Events, 0)
+
+	after := getChainState(main)
+	expected := getChainState(fork)
+	require.Equal(t, b4b.HashHeader(), after.headHash)
+	require.Equal(t, expected.uxHash, after.uxHash)
+	require.Equal(t, expected.unspents, after.unspents)
+
+	// txn3 is returned to the unconfirmed pool, txn2 is a double spend of the branch and txn5 spends
+	// an output that no longer exists
+	require.Equal(t, []cipher.SHA256{txn3.Hash()}, after.unconfirmed)
+
+	for i, b := range []coin.SignedBlock{*gb, b1, b2b, b3b, b4b} {
+		sb, err := main.GetSignedBlockBySeq(uint64(i))
+		require.NoError(t, err)
+		require.Equal(t, b, *sb)
+	}
+
+	// The HistoryDB follows the branch
+	err = main.db.View("", func(tx *dbutil.Tx) error {
+		for _, txn := range []coin.Transaction{txn2, txn3} {
+			htxn, err := main.history.GetTransaction(tx, txn.Hash())
+			require.NoError(t, err)
+			require.Nil(t, htxn)
+		}
+
+		for i, txn := range []coin.Transaction{txn2b, txn4, txn7} {
+			htxn, err := main.history.GetTransaction(tx, txn.Hash())
+			require.NoError(t, err)
+			require.NotNil(t, htxn)
+			require.Equal(t, uint64(i+2), htxn.BlockSeq)
+		}
+
+		for _, addr := range []cipher.Address{addrB, addrC} {
+			seen, err := main.history.AddressSeen(tx, addr)
+			require.NoError(t, err)
+			require.False(t, seen)
+		}
+
+		outs, err := main.history.GetUxOuts(tx, []cipher.SHA256{b1Uxs[0].Hash(), b1Uxs[1].Hash()})
+		require.NoError(t, err)
+		require.Equal(t, txn2b.Hash(), outs[0].SpentTxnID)
+		require.Equal(t, uint64(2), outs[0].SpentBlockSeq)
+		require.Equal(t, cipher.SHA256{}, outs[1].SpentTxnID)
+		require.Equal(t, uint64(0), outs[1].SpentBlockSeq)
+
+		parsedSeq, _, err := main.history.ParsedBlockSeq(tx)
+		require.NoError(t, err)
+		require.Equal(t, uint64(4), parsedSeq)
+
+		return nil
+	})
+	require.NoError(t, err)
+
+	// Reorganize back to the original main chain, which was kept as a side branch
+	err = reorg(b3.HashHeader())
+	require.NoError(t, err)
+
+	after = getChainState(main)
+	require.Equal(t, b3.HashHeader(), after.headHash)
+	require.Empty(t, after.unconfirmed)
+
+	// The branch continues after the reorg
+	txn6 := makeSpendTxn(t, coin.CreateUnspents(b3.Head, txn3)[1:], []cipher.SecKey{genSecret}, addrE, 10e6)
+	b4 := makeBlock(main, coin.Transactions{txn6}, genTime+400, genSecret)
+	require.NoError(t, main.ExecuteSignedBlock(b4))
+}
+
+func TestVisorReorgFullUnconfirmedPool(t *testing.T) {
+	publisherPubkey, publisherSeckey := cipher.GenerateKeyPair()
+
+	newVisor := func(maxCount uint64) (*Visor, func()) {
+		db, shutdown := prepareDB(t)
+
+		bc, err := NewBlockchain(db, BlockchainConfig{
+			Pubkey:           genPublic,
+			PublisherPubkeys: []cipher.PubKey{publisherPubkey},
+		})
+		require.NoError(t, err)
+
+		unconfirmed, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{
+			MaxCount: maxCount,
+		})
+		require.NoError(t, err)
+
+		cfg := NewConfig()
+		cfg.IsBlockPublisher = true
+		cfg.BlockchainPubkey = genPublic
+		cfg.PublisherPubkeys = []cipher.PubKey{publisherPubkey}
+		cfg.GenesisAddress = genAddress
+		cfg.BlockchainSeckey = genSecret
+
+		v := &Visor{
+			Config:      cfg,
+			unconfirmed: unconfirmed,
+			blockchain:  bc,
+			db:          db,
+			history:     historydb.New(),
+		}
+
+		addGenesisBlockToVisor(t, v)
+
+		return v, shutdown
+	}
+
+	makeBlock := func(v *Visor, txns coin.Transactions, when uint64, seckey cipher.SecKey) coin.SignedBlock {
+		b, err := v.CreateBlockFromTxns(txns, when)
+		require.NoError(t, err)
+		require.Equal(t, len(txns), len(b.Body.Transactions))
+
+		return coin.SignedBlock{
+			Block: b,
+			Sig:   cipher.MustSignHash(b.HashHeader(), seckey),
+		}
+	}
+
+	// The unconfirmed pool of main holds a single txn
+	main, shutdownMain := newVisor(1)
+	defer shutdownMain()
+	fork, shutdownFork := newVisor(0)
+	defer shutdownFork()
+
+	gb, err := main.GetSignedBlockBySeq(0)
+	require.NoError(t, err)
+	genUxs := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])
+
+	pubA, secA := cipher.GenerateKeyPair()
+	addrA := cipher.AddressFromPubKey(pubA)
+	pubD, secD := cipher.GenerateKeyPair()
+	addrD := cipher.AddressFromPubKey(pubD)
+	pubE, secE := cipher.GenerateKeyPair()
+	addrE := cipher.AddressFromPubKey(pubE)
+
+	// Common blocks: create outputs of addrA and addrD
+	txn1 := makeSpendTxn(t, genUxs, []cipher.SecKey{genSecret}, addrA, 10e6)
+	b1 := makeBlock(main, coin.Transactions{txn1}, genTime+100, genSecret)
+	require.NoError(t, main.ExecuteSignedBlock(b1))
+	require.NoError(t, fork.ExecuteSignedBlock(b1))
+	b1Uxs := coin.CreateUnspents(b1.Head, txn1)
+
+	txn2 := makeSpendTxn(t, b1Uxs[1:], []cipher.SecKey{genSecret}, addrD, 10e6)
+	b2 := makeBlock(main, coin.Transactions{txn2}, genTime+200, genSecret)
+	require.NoError(t, main.ExecuteSignedBlock(b2))
+	require.NoError(t, fork.ExecuteSignedBlock(b2))
+	b2Uxs := coin.CreateUnspents(b2.Head, txn2)
+
+	// Main chain: two txns that remain valid on the branch
+	txn3 := makeSpendTxn(t, b1Uxs[:1], []cipher.SecKey{secA}, testutil.MakeAddress(), 10e6)
+	txn4 := makeSpendTxn(t, b2Uxs[1:], []cipher.SecKey{genSecret}, testutil.MakeAddress(), 10e6)
+	b3 := makeBlock(main, coin.Transactions{txn3, txn4}, genTime+300, genSecret)
+	require.NoError(t, main.ExecuteSignedBlock(b3))
+
+	// Side branch, signed by the block publisher
+	txn5 := makeSpendTxn(t, b2Uxs[:1], []cipher.SecKey{secD}, addrE, 10e6)
+	b3b := makeBlock(fork, coin.Transactions{txn5}, genTime+350, publisherSeckey)
+	require.NoError(t, fork.ExecuteSignedBlock(b3b))
+
+	txn6 := makeSpendTxn(t, coin.CreateUnspents(b3b.Head, txn5), []cipher.SecKey{secE}, testutil.MakeAddress(), 10e6)
+	b4b := makeBlock(fork, coin.Transactions{txn6}, genTime+450, publisherSeckey)
+	require.NoError(t, fork.ExecuteSignedBlock(b4b))
+
+	require.NoError(t, main.ExecuteCertifiedBlock(b3b, nil))
+	require.NoError(t, main.ExecuteCertifiedBlock(b4b, nil))
+
+	head, err := main.GetSignedBlockBySeq(4)
+	require.NoError(t, err)
+	require.Equal(t, b4b, *head)
+
+	// Only one of the rolled back txns fits in the unconfirmed pool, the other one is dropped
+	err = main.db.View("", func(tx *dbutil.Tx) error {
+		hashes, err := main.unconfirmed.GetHashes(tx, All)
+		require.NoError(t, err)
+		require.Len(t, hashes, 1)
+		require.Contains(t, []cipher.SHA256{txn3.Hash(), txn4.Hash()}, hashes[0])
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestVisorKeyCheckpoints(t *testing.T) {
+	newVisor := func(seckey cipher.SecKey) (*Visor, func()) {
+		db, shutdown := prepareDB(t)
+
+		bc, err := NewBlockchain(db, BlockchainConfig{
+			Pubkey: genPublic,
+		})
+		require.NoError(t, err)
+
+		unconfirmed, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{})
+		require.NoError(t, err)
+
+		cfg := NewConfig()
+		cfg.IsBlockPublisher = true
+		cfg.BlockchainPubkey = genPublic
+		cfg.GenesisAddress = genAddress
+		cfg.BlockchainSeckey = seckey
+
+		v := &Visor{
+			Config:      cfg,
+			unconfirmed: unconfirmed,
+			blockchain:  bc,
+			db:          db,
+			history:     historydb.New(),
+		}
+
+		addGenesisBlockToVisor(t, v)
+
+		return v, shutdown
+	}
+
+	makeBlock := func(v *Visor, txns coin.Transactions, when uint64, seckey cipher.SecKey) coin.SignedBlock {
+		b, err := v.CreateBlockFromTxns(txns, when)
+		require.NoError(t, err)
+
+		return coin.SignedBlock{
+			Block: b,
+			Sig:   cipher.MustSignHash(b.HashHeader(), seckey),
+		}
+	}
+
+	publisher, shutdownPublisher := newVisor(genSecret)
+	defer shutdownPublisher()
+	follower, shutdownFollower := newVisor(genSecret)
+	defer shutdownFollower()
+	follower.Config.IsBlockPublisher = false
+
+	gb, err := publisher.GetSignedBlockBySeq(0)
+	require.NoError(t, err)
+	uxs := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])
+
+	spend := func(when uint64, seckey cipher.SecKey) coin.SignedBlock {
+		txn := makeSpendTxn(t, uxs, []cipher.SecKey{genSecret}, testutil.MakeAddress(), 1e6)
+		b := makeBlock(publisher, coin.Transactions{txn}, when, seckey)
+		uxs = coin.CreateUnspents(b.Head, txn)[1:]
+		return b
+	}
+
+	b1 := spend(genTime+100, genSecret)
+	require.NoError(t, publisher.ExecuteSignedBlock(b1))
+	require.NoError(t, follower.ExecuteSignedBlock(b1))
+
+	newPubkey, newSeckey := cipher.GenerateKeyPair()
+
+	// Only a block publisher creates key checkpoints
+	_, err = follower.CreateKeyCheckpoint(3, newPubkey)
+	require.Error(t, err)
+
+	// The checkpoint must be above the head block
+	_, err = publisher.CreateKeyCheckpoint(1, newPubkey)
+	require.Equal(t, ErrKeyCheckpointSeq, err)
+
+	kc, err := publisher.CreateKeyCheckpoint(3, newPubkey)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), kc.Seq)
+	require.Equal(t, newPubkey, kc.Pubkey)
+
+	// The checkpoint must be signed by the blockchain pubkey in effect
+	_, err = follower.AddKeyCheckpoint(blockdb.NewKeyCheckpoint(3, newPubkey, newSeckey))
+	require.Equal(t, ErrKeyCheckpointSigner, err)
+
+	added, err := follower.AddKeyCheckpoint(kc)
+	require.NoError(t, err)
+	require.True(t, added)
+
+	added, err = follower.AddKeyCheckpoint(kc)
+	require.NoError(t, err)
+	require.False(t, added)
+
+	// A later checkpoint must be signed by the pubkey of the previous checkpoint
+	otherPubkey, _ := cipher.GenerateKeyPair()
+	_, err = follower.AddKeyCheckpoint(blockdb.NewKeyCheckpoint(5, otherPubkey, genSecret))
+	require.Equal(t, ErrKeyCheckpointSigner, err)
+
+	_, err = follower.AddKeyCheckpoint(blockdb.NewKeyCheckpoint(3, otherPubkey, genSecret))
+	require.Equal(t, ErrKeyCheckpointSeq, err)
+
+	pubkeys, err := follower.BlockchainPubkeys()
+	require.NoError(t, err)
+	require.Equal(t, []cipher.PubKey{genPublic, newPubkey}, pubkeys)
+
+	// Blocks below the checkpoint are signed by the genesis pubkey
+	_, err = follower.AddKeyCheckpoint(blockdb.NewKeyCheckpoint(2, otherPubkey, newSeckey))
+	require.Equal(t, ErrKeyCheckpointSeq, err)
+
+	b2 := spend(genTime+200, genSecret)
+	require.NoError(t, publisher.ExecuteSignedBlock(b2))
+	require.NoError(t, follower.ExecuteSignedBlock(b2))
+
+	// Blocks from the checkpoint onward are signed by the new pubkey
+	b3 := spend(genTime+300, genSecret)
+	require.Error(t, follower.ExecuteSignedBlock(b3))
+
+	b3.Sig = cipher.MustSignHash(b3.HashHeader(), newSeckey)
+	require.NoError(t, publisher.ExecuteSignedBlock(b3))
+	require.NoError(t, follower.ExecuteSignedBlock(b3))
+
+	// The checkpoints are part of the blockchain metadata
+	metadata, err := follower.GetBlockchainMetadata()
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), metadata.HeadBlock.Seq())
+	require.Equal(t, []blockdb.KeyCheckpoint{kc}, metadata.KeyCheckpoints)
+
+	checkpoints, err := publisher.GetKeyCheckpoints()
+	require.NoError(t, err)
+	require.Equal(t, []blockdb.KeyCheckpoint{kc}, checkpoints)
+
+	// The block publisher may run with the seckey of the new pubkey
+	publisher.Config.BlockchainSeckey = newSeckey
+	require.NoError(t, publisher.Init())
+
+	_, otherSeckey := cipher.GenerateKeyPair()
+	publisher.Config.BlockchainSeckey = otherSeckey
+	require.Error(t, publisher.Init())
+}
+
+func TestVisorPublisherQuorum(t *testing.T) {
+	pubkey1, seckey1 := cipher.GenerateKeyPair()
+	pubkey2, seckey2 := cipher.GenerateKeyPair()
+	pubkey3, _ := cipher.GenerateKeyPair()
+	_, otherSeckey := cipher.GenerateKeyPair()
+	publishers := []cipher.PubKey{pubkey1, pubkey2, pubkey3}
+
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, BlockchainConfig{
+		Pubkey:           genPublic,
+		PublisherPubkeys: publishers,
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, bc.PublisherQuorum())
+
+	unconfirmed, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{})
+	require.NoError(t, err)
+
+	cfg := NewConfig()
+	cfg.IsBlockPublisher = true
+	cfg.BlockchainPubkey = genPublic
+	cfg.PublisherPubkeys = publishers
+	cfg.GenesisAddress = genAddress
+	cfg.BlockchainSeckey = genSecret
+
+	v := &Visor{
+		Config:      cfg,
+		unconfirmed: unconfirmed,
+		blockchain:  bc,
+		db:          db,
+		history:     historydb.New(),
+	}
+
+	gb := addGenesisBlockToVisor(t, v)
+	genUxs := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])
+
+	txn1 := makeSpendTxn(t, genUxs, []cipher.SecKey{genSecret}, testutil.MakeAddress(), 10e6)
+	b, err := v.CreateBlockFromTxns(coin.Transactions{txn1}, genTime+100)
+	require.NoError(t, err)
+	hash := b.HashHeader()
+	sb := coin.SignedBlock{
+		Block: b,
+		Sig:   cipher.MustSignHash(hash, seckey1),
+	}
+
+	// A single block publisher is not a quorum, and signatures by other keys don't count
+	require.Equal(t, ErrBlockPublisherQuorum, v.ExecuteSignedBlock(sb))
+	require.Equal(t, ErrBlockPublisherQuorum, v.ExecuteCertifiedBlock(sb, []cipher.Sig{
+		cipher.MustSignHash(hash, seckey1),
+		cipher.MustSignHash(hash, otherSeckey),
+	}))
+
+	// The certificate of the rejected block is not stored
+	certs, err := v.GetBlockCertificates([]cipher.SHA256{hash})
+	require.NoError(t, err)
+	require.Empty(t, certs)
+
+	sig2 := cipher.MustSignHash(hash, seckey2)
+	require.NoError(t, v.ExecuteCertifiedBlock(sb, []cipher.Sig{
+		cipher.MustSignHash(hash, otherSeckey),
+		sig2,
+	}))
+
+	certs, err = v.GetBlockCertificates([]cipher.SHA256{gb.HashHeader(), hash})
+	require.NoError(t, err)
+	require.Equal(t, []blockdb.BlockCertificate{
+		{
+			Hash: hash,
+			Sigs: []cipher.Sig{sig2},
+		},
+	}, certs)
+
+	// The stored certificate is verified with the database
+	require.NoError(t, CheckDatabase(db, genPublic, publishers, 0, nil))
+	require.Equal(t, ErrBlockPublisherQuorum, CheckDatabase(db, genPublic, publishers, 3, nil))
+
+	// Blocks signed by the blockchain pubkey don't need a certificate
+	txn2 := makeSpendTxn(t, coin.CreateUnspents(b.Head, txn1)[1:], []cipher.SecKey{genSecret}, testutil.MakeAddress(), 10e6)
+	b2, err := v.CreateBlockFromTxns(coin.Transactions{txn2}, genTime+200)
+	require.NoError(t, err)
+	require.NoError(t, v.ExecuteSignedBlock(coin.SignedBlock{
+		Block: b2,
+		Sig:   cipher.MustSignHash(b2.HashHeader(), genSecret),
+	}))
+}
+
 func makeTxn(t *testing.T, headTime uint64, in, out []coin.UxOut, keys []cipher.SecKey) (coin.Transaction, []TransactionInput) {
 	inputs := make([]cipher.SHA256, len(in))
 	for i, input := range in {
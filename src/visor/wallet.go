@@ -5,10 +5,13 @@ package visor
 import (
 	"errors"
 
+	"github.com/ness-network/ness/src/psbt"
+	"github.com/ness-network/ness/src/wallet/watch"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/transaction"
+	"github.com/skycoin/skycoin/src/util/fee"
 	"github.com/skycoin/skycoin/src/util/mathutil"
 	"github.com/skycoin/skycoin/src/visor/dbutil"
 	"github.com/skycoin/skycoin/src/wallet"
@@ -47,6 +50,16 @@ var (
 	ErrUxOutsOrAddressesRequired = NewUserError(errors.New("UxOuts or Addresses must not be empty"))
 	// ErrNoSpendableOutputs after filtering unconfirmed spend outputs, there are no remaining outputs available for transaction creation
 	ErrNoSpendableOutputs = NewUserError(errors.New("All selected outputs are unavailable for spending"))
+	// ErrTxnNotUnconfirmed the transaction to replace is not in the unconfirmed pool
+	ErrTxnNotUnconfirmed = NewUserError(errors.New("Transaction is not in the unconfirmed pool"))
+	// ErrTxnInputsNotInWallet the transaction to replace spends outputs that do not belong to the wallet
+	ErrTxnInputsNotInWallet = NewUserError(errors.New("Transaction spends outputs that do not belong to the wallet"))
+	// ErrBumpFeeTooLow the new fee is not higher than the fee of the transaction to replace
+	ErrBumpFeeTooLow = NewUserError(errors.New("Fee must be higher than the fee of the transaction"))
+	// ErrBumpFeeInsufficientHours the outputs to the wallet do not have enough coin hours to pay the new fee
+	ErrBumpFeeInsufficientHours = NewUserError(errors.New("Not enough coin hours in the outputs to the wallet to pay the fee"))
+	// ErrPacketInputsNotInWallet none of the unsigned inputs of a partially signed transaction belong to the wallet
+	ErrPacketInputsNotInWallet = NewUserError(errors.New("No unsigned input of the packet belongs to the wallet"))
 )
 
 // GetWalletBalance returns balance pairs of specific wallet
@@ -150,8 +163,18 @@ func (vs *Visor) GetWalletUnconfirmedTransactionsVerbose(wltID string) ([]Unconf
 	return txns, inputs, nil
 }
 
+// walletCanSign returns wallet.ErrWalletCantSign if the wallet type has no secret keys
+func walletCanSign(w wallet.Wallet) error {
+	switch w.Type() {
+	case wallet.WalletTypeXPub, watch.WalletType:
+		return wallet.ErrWalletCantSign
+	}
+	return nil
+}
+
 // WalletSignTransaction signs a transaction. Specific inputs may be signed by specifying signIndexes.
 // If signIndexes is empty, all inputs will be signed. The transaction must be fully valid and spendable.
+// Inputs that spend outputs of any account of a bip44 wallet are signed.
 func (vs *Visor) WalletSignTransaction(wltID string, password []byte, txn *coin.Transaction, signIndexes []int) (*coin.Transaction, []TransactionInput, error) {
 	var inputs []TransactionInput
 	var signedTxn *coin.Transaction
@@ -161,6 +184,10 @@ func (vs *Visor) WalletSignTransaction(wltID string, password []byte, txn *coin.
 	}
 
 	if err := vs.wallets.ViewSecrets(wltID, password, func(w wallet.Wallet) error {
+		if err := walletCanSign(w); err != nil {
+			return err
+		}
+
 		return vs.db.View("WalletSignTransaction", func(tx *dbutil.Tx) error {
 			// Verify the transaction before signing
 			if err := transaction.VerifySingleTxnUserConstraints(*txn); err != nil {
@@ -186,7 +213,7 @@ func (vs *Visor) WalletSignTransaction(wltID string, password []byte, txn *coin.
 				uxOuts[i] = in.UxOut
 			}
 
-			signedTxn, err = wallet.SignTransaction(w, txn, signIndexes, uxOuts)
+			signedTxn, err = wallet.SignTransaction(signingWallet(w), txn, signIndexes, uxOuts)
 			if err != nil {
 				logger.WithError(err).Error("wallet.SignTransaction failed")
 				return err
@@ -218,6 +245,184 @@ func (vs *Visor) WalletSignTransaction(wltID string, password []byte, txn *coin.
 	return signedTxn, inputs, nil
 }
 
+// WalletSignPacket signs the unsigned inputs of a partially signed transaction that spend outputs of the wallet.
+// The packet holds the outputs spent by the transaction, so the blockchain is not used and the packet can be
+// signed by a node that is offline. The inputs signed are given the wallet filename as wallet hint, if they have none.
+// Inputs that spend outputs of any account of a bip44 wallet are signed.
+// The packet is not modified, a signed copy is returned.
+func (vs *Visor) WalletSignPacket(wltID string, password []byte, p *psbt.Packet) (*psbt.Packet, error) {
+	if p.IsFullySigned() {
+		return nil, ErrTransactionAlreadySigned
+	}
+
+	signedPkt := p.Clone()
+
+	if err := vs.wallets.ViewSecrets(wltID, password, func(w wallet.Wallet) error {
+		if err := walletCanSign(w); err != nil {
+			return err
+		}
+
+		sw := signingWallet(w)
+		keys := make(map[cipher.Address]cipher.SecKey)
+		for _, in := range signedPkt.Inputs {
+			if in.IsSigned() {
+				continue
+			}
+
+			addr := in.UxOut.Body.Address
+			if _, ok := keys[addr]; ok {
+				continue
+			}
+
+			e, err := sw.GetEntry(addr)
+			switch err {
+			case nil:
+				keys[addr] = e.Secret
+			case wallet.ErrEntryNotFound:
+			default:
+				return err
+			}
+		}
+
+		signed, err := signedPkt.SignWithKeys(keys)
+		if err != nil {
+			logger.WithError(err).Error("psbt.Packet.SignWithKeys failed")
+			return err
+		}
+
+		if len(signed) == 0 {
+			return ErrPacketInputsNotInWallet
+		}
+
+		for _, i := range signed {
+			if signedPkt.Inputs[i].WalletHint == "" {
+				signedPkt.Inputs[i].WalletHint = w.Filename()
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return signedPkt, nil
+}
+
+// WalletBumpFee creates a signed transaction that replaces the unconfirmed transaction txid and burns
+// newFee coin hours. The transaction must spend outputs of the wallet. The extra coin hours are taken from
+// the outputs to addresses of the wallet, starting with the last output.
+// The transaction is not injected, and only replaces txid if the node enables replace-by-fee.
+func (vs *Visor) WalletBumpFee(wltID string, password []byte, txid cipher.SHA256, newFee uint64) (*coin.Transaction, []TransactionInput, error) {
+	var inputs []TransactionInput
+	var signedTxn *coin.Transaction
+
+	if err := vs.wallets.ViewSecrets(wltID, password, func(w wallet.Wallet) error {
+		if err := walletCanSign(w); err != nil {
+			return err
+		}
+
+		w = signingWallet(w)
+
+		return vs.db.View("WalletBumpFee", func(tx *dbutil.Tx) error {
+			utxn, err := vs.unconfirmed.Get(tx, txid)
+			if err != nil {
+				return err
+			}
+			if utxn == nil {
+				return ErrTxnNotUnconfirmed
+			}
+
+			addrs, err := w.GetAddresses()
+			if err != nil {
+				return err
+			}
+			wltAddrs := make(map[cipher.Address]struct{}, len(addrs))
+			for _, a := range wallet.SkycoinAddresses(addrs) {
+				wltAddrs[a] = struct{}{}
+			}
+
+			headTime, err := vs.blockchain.Time(tx)
+			if err != nil {
+				logger.WithError(err).Error("blockchain.Time failed")
+				return err
+			}
+
+			// The replacement spends the same outputs, which must still be unspent
+			uxOuts, err := vs.blockchain.Unspent().GetArray(tx, utxn.Transaction.In)
+			if err != nil {
+				return err
+			}
+
+			inputs = make([]TransactionInput, len(uxOuts))
+			for i, ux := range uxOuts {
+				if _, ok := wltAddrs[ux.Body.Address]; !ok {
+					return ErrTxnInputsNotInWallet
+				}
+
+				inputs[i], err = NewTransactionInput(ux, headTime)
+				if err != nil {
+					return err
+				}
+			}
+
+			txn := utxn.Transaction
+			oldFee, err := fee.TransactionFee(&txn, headTime, uxOuts)
+			if err != nil {
+				return err
+			}
+
+			if newFee <= oldFee {
+				return ErrBumpFeeTooLow
+			}
+
+			// Take the extra coin hours from the outputs to the wallet, starting with the last output
+			txn.Out = append([]coin.TransactionOutput{}, txn.Out...)
+			extra := newFee - oldFee
+			for i := len(txn.Out) - 1; i >= 0 && extra > 0; i-- {
+				if _, ok := wltAddrs[txn.Out[i].Address]; !ok {
+					continue
+				}
+
+				hours := txn.Out[i].Hours
+				if hours > extra {
+					hours = extra
+				}
+				txn.Out[i].Hours -= hours
+				extra -= hours
+			}
+
+			if extra > 0 {
+				return ErrBumpFeeInsufficientHours
+			}
+
+			txn.Sigs = make([]cipher.Sig, len(txn.In))
+			if err := txn.UpdateHeader(); err != nil {
+				return err
+			}
+
+			if err := transaction.VerifySingleTxnUserConstraints(txn); err != nil {
+				return err
+			}
+
+			signedTxn, err = wallet.SignTransaction(w, &txn, nil, uxOuts)
+			if err != nil {
+				logger.WithError(err).Error("wallet.SignTransaction failed")
+				return err
+			}
+
+			if _, _, err := vs.blockchain.VerifySingleTxnSoftHardConstraints(tx, *signedTxn, vs.Config.Distribution, params.UserVerifyTxn, transaction.TxnSigned); err != nil {
+				return err
+			}
+
+			return nil
+		})
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	return signedTxn, inputs, nil
+}
+
 // CreateTransactionParams parameters for transaction creation
 type CreateTransactionParams struct {
 	UxOuts    []cipher.SHA256
@@ -225,6 +430,12 @@ type CreateTransactionParams struct {
 	// IgnoreUnconfirmed if true, outputs matching Addresses or UxOuts spent by
 	// an unconfirmed transactions will be ignored, otherwise an error will be returned
 	IgnoreUnconfirmed bool
+	// Account is the bip44 account to spend from. If set, the change is always sent
+	// to the change chain of the account. Only valid for bip44 wallets.
+	Account *uint32
+	// SpendStrategy is the strategy that chooses the outputs to spend, "minimize_uxouts" or "privacy".
+	// Defaults to "minimize_uxouts" if empty.
+	SpendStrategy string
 }
 
 // Validate validates params
@@ -256,7 +467,7 @@ func (p CreateTransactionParams) Validate() error {
 		uxOuts[o] = struct{}{}
 	}
 
-	return nil
+	return validateSpendStrategy(p.SpendStrategy)
 }
 
 // WalletCreateTransactionSigned creates a signed transaction based upon the parameters in CreateTransactionParams
@@ -276,6 +487,16 @@ func (vs *Visor) WalletCreateTransactionSigned(wltID string, password []byte, p
 		return nil, nil, err
 	}
 
+	if err := walletCanSign(w); err != nil {
+		return nil, nil, err
+	}
+
+	if wp.Account != nil {
+		if err := checkWalletAccount(w, *wp.Account); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	if p.ChangeAddress == nil && w.Type() == wallet.WalletTypeBip44 {
 		// TODO: Maybe add the `PeekChangeAddress` to wallet.Wallet interface, and
 		// only bip44 wallet will implement it, all others do nothing. In this way
@@ -283,13 +504,12 @@ func (vs *Visor) WalletCreateTransactionSigned(wltID string, password []byte, p
 		//
 		// For bip44 wallet, peek a change address if p.ChangeAddress is nill
 		if err := vs.wallets.Update(wltID, func(w wallet.Wallet) error {
-			addr, err := w.(*bip44wallet.Wallet).PeekChangeAddress(vs.tf)
+			addr, err := vs.peekChangeAddress(w.(*bip44wallet.Wallet), wp.Account)
 			if err != nil {
 				logger.Critical().WithError(err).Error("PeekChangeAddress failed")
 				return err
 			}
-			skyAddr := addr.(cipher.Address)
-			p.ChangeAddress = &skyAddr
+			p.ChangeAddress = &addr
 			return nil
 		}); err != nil {
 			return nil, nil, err
@@ -322,19 +542,24 @@ func (vs *Visor) WalletCreateTransaction(wltID string, p transaction.Params, wp
 	var inputs []TransactionInput
 
 	if err := vs.wallets.Update(wltID, func(w wallet.Wallet) error {
+		if wp.Account != nil {
+			if err := checkWalletAccount(w, *wp.Account); err != nil {
+				return err
+			}
+		}
+
 		if p.ChangeAddress == nil && w.Type() == wallet.WalletTypeBip44 {
 			// TODO: Maybe add the `PeekChangeAddress` to wallet.Wallet interface, and
 			// only bip44 wallet will implement it, all others do nothing. In this way
 			// we don't have to explicitly check the wallet type here.
 			//
 			// For bip44 wallet, peek a change address if p.ChangeAddress is nill
-			addr, err := w.(*bip44wallet.Wallet).PeekChangeAddress(vs.tf)
+			addr, err := vs.peekChangeAddress(w.(*bip44wallet.Wallet), wp.Account)
 			if err != nil {
 				logger.Critical().WithError(err).Error("PeekChangeAddress failed")
 				return err
 			}
-			skyAddr := addr.(cipher.Address)
-			p.ChangeAddress = &skyAddr
+			p.ChangeAddress = &addr
 		}
 
 		var err error
@@ -355,9 +580,21 @@ func (vs *Visor) walletCreateTransaction(methodName string, w wallet.Wallet, p t
 		return nil, nil, err
 	}
 
-	// Get all addresses from the wallet for checking params against
+	if wp.Account != nil {
+		if err := checkWalletAccount(w, *wp.Account); err != nil {
+			return nil, nil, err
+		}
+
+		if p.ChangeAddress != nil {
+			if err := checkAccountChangeAddress(w, *wp.Account, *p.ChangeAddress); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	// Get all addresses from the wallet, or from the account spent from, for checking params against
 	walletAddresses, err := func() ([]cipher.Address, error) {
-		addrs, err := w.GetAddresses()
+		addrs, err := w.GetAddresses(wp.walletOptions()...)
 		if err != nil {
 			return nil, err
 		}
@@ -412,9 +649,21 @@ func (vs *Visor) walletCreateTransactionTx(tx *dbutil.Tx, methodName string,
 		return nil, nil, err
 	}
 
+	frozen, err := walletFrozenOutputs(w)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Get mapping of addresses to uxOuts based upon CreateTransactionParams
 	var auxs coin.AddressUxOuts
 	if len(wp.UxOuts) != 0 {
+		// Frozen outputs can not be spent, even if they are chosen explicitly
+		for _, h := range wp.UxOuts {
+			if _, ok := frozen[h]; ok {
+				return nil, nil, ErrWalletOutputFrozen
+			}
+		}
+
 		var err error
 		auxs, err = vs.getCreateTransactionAuxsUxOut(tx, wp.UxOuts, wp.IgnoreUnconfirmed)
 		if err != nil {
@@ -433,16 +682,23 @@ func (vs *Visor) walletCreateTransactionTx(tx *dbutil.Tx, methodName string,
 		if err != nil {
 			return nil, nil, err
 		}
+
+		auxs = filterFrozenUxOuts(auxs, frozen)
+		if len(auxs) == 0 {
+			return nil, nil, transaction.ErrNoUnspents
+		}
 	}
 
 	// Create and sign transaction
 	var txn *coin.Transaction
 	var uxb []transaction.UxBalance
 
-	switch signed {
-	case transaction.TxnSigned:
+	switch {
+	case wp.Account != nil || wp.SpendStrategy != "":
+		txn, uxb, err = createWalletTransaction(w, wp.walletOptions(), p, wp.SpendStrategy, auxs, head.Time(), signed)
+	case signed == transaction.TxnSigned:
 		txn, uxb, err = wallet.CreateTransactionSigned(w, p, auxs, head.Time())
-	case transaction.TxnUnsigned:
+	case signed == transaction.TxnUnsigned:
 		txn, uxb, err = wallet.CreateTransaction(w, p, auxs, head.Time())
 	default:
 		logger.Panic("Invalid TxnSignedFlag")
@@ -517,7 +773,7 @@ func (vs *Visor) createTransactionTx(tx *dbutil.Tx, p transaction.Params, wp Cre
 		return nil, nil, err
 	}
 
-	txn, uxb, err := transaction.Create(p, auxs, head.Time())
+	txn, uxb, err := createTransaction(p, wp.SpendStrategy, auxs, head.Time())
 	if err != nil {
 		return nil, nil, err
 	}
@@ -0,0 +1,269 @@
+package visor
+
+// This file contains Visor methods for the accounts of bip44 wallets
+
+import (
+	"errors"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/util/mathutil"
+	"github.com/skycoin/skycoin/src/wallet"
+	"github.com/skycoin/skycoin/src/wallet/bip44wallet"
+)
+
+var (
+	// ErrWalletNotBip44 accounts are only defined for bip44 wallets
+	ErrWalletNotBip44 = NewUserError(errors.New("Accounts are only supported by bip44 wallets"))
+	// ErrUnknownAccount the bip44 account does not exist in the wallet
+	ErrUnknownAccount = NewUserError(errors.New("Wallet account does not exist"))
+	// ErrChangeAddressNotInAccount the change address is not on the change chain of the account being spent from
+	ErrChangeAddressNotInAccount = NewUserError(errors.New("Change address is not on the change chain of the account"))
+)
+
+// WalletAccount is an account of a bip44 wallet with its addresses and balance
+type WalletAccount struct {
+	wallet.Bip44Account
+	ExternalAddresses []cipher.Address
+	ChangeAddresses   []cipher.Address
+	Balance           wallet.BalancePair
+}
+
+// GetWalletAccounts returns the accounts of a bip44 wallet with the addresses and the balance of each account
+func (vs *Visor) GetWalletAccounts(wltID string) ([]WalletAccount, error) {
+	var accounts []WalletAccount
+	if err := vs.wallets.View(wltID, func(w wallet.Wallet) error {
+		if w.Type() != wallet.WalletTypeBip44 {
+			return ErrWalletNotBip44
+		}
+
+		for _, a := range w.Accounts() {
+			external, err := w.GetAddresses(wallet.OptionAccount(a.Index), wallet.OptionExternal())
+			if err != nil {
+				return err
+			}
+
+			change, err := w.GetAddresses(wallet.OptionAccount(a.Index), wallet.OptionChange())
+			if err != nil {
+				return err
+			}
+
+			accounts = append(accounts, WalletAccount{
+				Bip44Account:      a,
+				ExternalAddresses: wallet.SkycoinAddresses(external),
+				ChangeAddresses:   wallet.SkycoinAddresses(change),
+			})
+		}
+
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	// Query the balances of all accounts at once
+	var addrs []cipher.Address
+	for _, a := range accounts {
+		addrs = append(addrs, a.ExternalAddresses...)
+		addrs = append(addrs, a.ChangeAddresses...)
+	}
+
+	balances, err := vs.GetBalanceOfAddresses(addrs)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range accounts {
+		n := len(accounts[i].ExternalAddresses) + len(accounts[i].ChangeAddresses)
+		for _, b := range balances[:n] {
+			accounts[i].Balance, err = addBalancePair(accounts[i].Balance, b)
+			if err != nil {
+				return nil, err
+			}
+		}
+		balances = balances[n:]
+	}
+
+	return accounts, nil
+}
+
+// NewWalletAccount creates an account in a bip44 wallet and returns its index.
+// The seed is needed to derive the account key, so the password must be provided if the wallet is encrypted.
+func (vs *Visor) NewWalletAccount(wltID string, password []byte, name string) (uint32, error) {
+	var index uint32
+	if err := vs.wallets.UpdateSecrets(wltID, password, func(w wallet.Wallet) error {
+		bw, ok := w.(*bip44wallet.Wallet)
+		if !ok {
+			return ErrWalletNotBip44
+		}
+
+		var err error
+		index, err = bw.NewAccount(name)
+		return err
+	}); err != nil {
+		return 0, err
+	}
+
+	return index, nil
+}
+
+func addBalancePair(a, b wallet.BalancePair) (wallet.BalancePair, error) {
+	var err error
+	a.Confirmed.Coins, err = mathutil.AddUint64(a.Confirmed.Coins, b.Confirmed.Coins)
+	if err != nil {
+		return a, err
+	}
+	a.Confirmed.Hours, err = mathutil.AddUint64(a.Confirmed.Hours, b.Confirmed.Hours)
+	if err != nil {
+		return a, err
+	}
+	a.Predicted.Coins, err = mathutil.AddUint64(a.Predicted.Coins, b.Predicted.Coins)
+	if err != nil {
+		return a, err
+	}
+	a.Predicted.Hours, err = mathutil.AddUint64(a.Predicted.Hours, b.Predicted.Hours)
+	if err != nil {
+		return a, err
+	}
+	return a, nil
+}
+
+// checkWalletAccount checks that the wallet is a bip44 wallet with the account
+func checkWalletAccount(w wallet.Wallet, account uint32) error {
+	if w.Type() != wallet.WalletTypeBip44 {
+		return ErrWalletNotBip44
+	}
+
+	for _, a := range w.Accounts() {
+		if a.Index == account {
+			return nil
+		}
+	}
+
+	return ErrUnknownAccount
+}
+
+// walletOptions returns the wallet options selecting the entries the transaction spends from
+func (p CreateTransactionParams) walletOptions() []wallet.Option {
+	if p.Account == nil {
+		return nil
+	}
+	return []wallet.Option{wallet.OptionAccount(*p.Account)}
+}
+
+// peekChangeAddress returns the change address of a bip44 wallet transaction.
+// Without an account it is bip44wallet.Wallet.PeekChangeAddress, which only uses account 0.
+// With an account, it is the last address of the account's change chain if that address has
+// no transactions, otherwise a new address of the account's change chain.
+func (vs *Visor) peekChangeAddress(w *bip44wallet.Wallet, account *uint32) (cipher.Address, error) {
+	if account == nil {
+		addr, err := w.PeekChangeAddress(vs.tf)
+		if err != nil {
+			return cipher.Address{}, err
+		}
+		return addr.(cipher.Address), nil
+	}
+
+	opts := []wallet.Option{wallet.OptionAccount(*account), wallet.OptionChange()}
+	addrs, err := w.GetAddresses(opts...)
+	if err != nil {
+		return cipher.Address{}, err
+	}
+
+	if len(addrs) != 0 {
+		last := addrs[len(addrs)-1]
+		active, err := vs.tf.AddressesActivity([]cipher.Addresser{last})
+		if err != nil {
+			return cipher.Address{}, err
+		}
+
+		if !active[0] {
+			return last.(cipher.Address), nil
+		}
+	}
+
+	addrs, err = w.GenerateAddresses(append(opts, wallet.OptionGenerateN(1))...)
+	if err != nil {
+		return cipher.Address{}, err
+	}
+
+	return addrs[0].(cipher.Address), nil
+}
+
+// checkAccountChangeAddress checks that the change address is on the change chain of the account
+func checkAccountChangeAddress(w wallet.Wallet, account uint32, addr cipher.Address) error {
+	change, err := w.GetAddresses(wallet.OptionAccount(account), wallet.OptionChange())
+	if err != nil {
+		return err
+	}
+
+	for _, a := range change {
+		if a == addr {
+			return nil
+		}
+	}
+
+	return ErrChangeAddressNotInAccount
+}
+
+// allAccountsWallet is a wallet whose entries are the entries of all its bip44 accounts.
+// Without options, bip44 wallets only look up the entries of account 0, so an allAccountsWallet is used
+// to find and sign the inputs that spend outputs of any account.
+type allAccountsWallet struct {
+	wallet.Wallet
+}
+
+// signingWallet returns the wallet used to find and sign the inputs of a transaction
+func signingWallet(w wallet.Wallet) wallet.Wallet {
+	if w.Type() != wallet.WalletTypeBip44 {
+		return w
+	}
+	return allAccountsWallet{w}
+}
+
+// GetEntries returns the entries of all accounts if no options are specified
+func (w allAccountsWallet) GetEntries(options ...wallet.Option) (wallet.Entries, error) {
+	if len(options) != 0 {
+		return w.Wallet.GetEntries(options...)
+	}
+
+	var entries wallet.Entries
+	for _, a := range w.Accounts() {
+		es, err := w.Wallet.GetEntries(wallet.OptionAccount(a.Index))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, es...)
+	}
+
+	return entries, nil
+}
+
+// GetAddresses returns the addresses of all accounts if no options are specified
+func (w allAccountsWallet) GetAddresses(options ...wallet.Option) ([]cipher.Addresser, error) {
+	entries, err := w.GetEntries(options...)
+	if err != nil {
+		return nil, err
+	}
+
+	addrs := make([]cipher.Addresser, len(entries))
+	for i, e := range entries {
+		addrs[i] = e.Address
+	}
+
+	return addrs, nil
+}
+
+// GetEntry searches the entry of addr in all accounts if no options are specified
+func (w allAccountsWallet) GetEntry(addr cipher.Addresser, options ...wallet.Option) (wallet.Entry, error) {
+	if len(options) != 0 {
+		return w.Wallet.GetEntry(addr, options...)
+	}
+
+	for _, a := range w.Accounts() {
+		e, err := w.Wallet.GetEntry(addr, wallet.OptionAccount(a.Index))
+		if err != wallet.ErrEntryNotFound {
+			return e, err
+		}
+	}
+
+	return wallet.Entry{}, wallet.ErrEntryNotFound
+}
@@ -0,0 +1,198 @@
+package visor
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ness-network/ness/src/psbt"
+	"github.com/ness-network/ness/src/visor/historydb"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/crypto"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/transaction"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func TestWalletAccounts(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, BlockchainConfig{
+		Pubkey: genPublic,
+	})
+	require.NoError(t, err)
+
+	unconfirmed, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{})
+	require.NoError(t, err)
+
+	ws, err := wallet.NewService(wallet.Config{
+		EnableWalletAPI: true,
+		CryptoType:      crypto.CryptoTypeScryptChacha20poly1305Insecure,
+		WalletDir:       prepareWltDir(),
+	})
+	require.NoError(t, err)
+
+	cfg := NewConfig()
+	cfg.IsBlockPublisher = true
+	cfg.BlockchainPubkey = genPublic
+	cfg.BlockchainSeckey = genSecret
+	cfg.GenesisAddress = genAddress
+	cfg.Distribution = params.MainNetDistribution
+
+	v := &Visor{
+		Config:      cfg,
+		unconfirmed: unconfirmed,
+		blockchain:  bc,
+		db:          db,
+		history:     historydb.New(),
+		wallets:     ws,
+		tf:          mockTxnsFinder{},
+	}
+
+	gb := addGenesisBlockToVisor(t, v)
+
+	_, err = ws.CreateWallet("bip44.wlt", wallet.Options{
+		Label:     "test",
+		Coin:      wallet.CoinTypeSkycoin,
+		Type:      wallet.WalletTypeBip44,
+		Seed:      "voyage say extend find sheriff surge priority merit ignore maple cash argue",
+		GenerateN: 1,
+	})
+	require.NoError(t, err)
+
+	_, err = ws.CreateWallet("foo.wlt", wallet.Options{
+		Label: "test",
+		Coin:  wallet.CoinTypeSkycoin,
+		Type:  wallet.WalletTypeCollection,
+	})
+	require.NoError(t, err)
+
+	// Accounts are only supported by bip44 wallets
+	_, err = v.NewWalletAccount("foo.wlt", nil, "savings")
+	require.Equal(t, ErrWalletNotBip44, err)
+	_, err = v.GetWalletAccounts("foo.wlt")
+	require.Equal(t, ErrWalletNotBip44, err)
+
+	index, err := v.NewWalletAccount("bip44.wlt", nil, "savings")
+	require.NoError(t, err)
+	require.Equal(t, uint32(1), index)
+
+	account := uint32(1)
+	addrs, err := ws.NewAddresses("bip44.wlt", nil, wallet.OptionAccount(account), wallet.OptionGenerateN(1))
+	require.NoError(t, err)
+	require.Len(t, addrs, 1)
+	savingsAddr := addrs[0]
+
+	// Send coins to the external chain of the savings account
+	txn := makeSpendTxn(t, coin.CreateUnspents(gb.Head, gb.Body.Transactions[0]), []cipher.SecKey{genSecret}, savingsAddr, 10e6)
+	b, err := v.CreateBlockFromTxns(coin.Transactions{txn}, genTime+100)
+	require.NoError(t, err)
+	require.NoError(t, v.ExecuteSignedBlock(coin.SignedBlock{
+		Block: b,
+		Sig:   cipher.MustSignHash(b.HashHeader(), genSecret),
+	}))
+
+	accounts, err := v.GetWalletAccounts("bip44.wlt")
+	require.NoError(t, err)
+	require.Len(t, accounts, 2)
+	require.Equal(t, uint32(0), accounts[0].Index)
+	require.Len(t, accounts[0].ExternalAddresses, 1)
+	require.Equal(t, wallet.BalancePair{}, accounts[0].Balance)
+	require.Equal(t, "savings", accounts[1].Name)
+	require.Equal(t, []cipher.Address{savingsAddr}, accounts[1].ExternalAddresses)
+	require.Empty(t, accounts[1].ChangeAddresses)
+	require.Equal(t, uint64(10e6), accounts[1].Balance.Confirmed.Coins)
+	require.Equal(t, uint64(10e6), accounts[1].Balance.Predicted.Coins)
+
+	shareFactor := decimal.New(5, -1)
+	p := transaction.Params{
+		HoursSelection: transaction.HoursSelection{
+			Type:        transaction.HoursSelectionTypeAuto,
+			Mode:        transaction.HoursSelectionModeShare,
+			ShareFactor: &shareFactor,
+		},
+		To: []coin.TransactionOutput{
+			{
+				Address: testutil.MakeAddress(),
+				Coins:   1e6,
+			},
+		},
+	}
+
+	// Account 0 has no coins
+	_, _, err = v.WalletCreateTransactionSigned("bip44.wlt", nil, p, CreateTransactionParams{})
+	require.Equal(t, transaction.ErrNoUnspents, err)
+
+	unknown := uint32(5)
+	_, _, err = v.WalletCreateTransactionSigned("bip44.wlt", nil, p, CreateTransactionParams{
+		Account: &unknown,
+	})
+	require.Equal(t, ErrUnknownAccount, err)
+
+	// A change address outside of the account's change chain is rejected
+	badChange := accounts[0].ExternalAddresses[0]
+	pBadChange := p
+	pBadChange.ChangeAddress = &badChange
+	_, _, err = v.WalletCreateTransactionSigned("bip44.wlt", nil, pBadChange, CreateTransactionParams{
+		Account: &account,
+	})
+	require.Equal(t, ErrChangeAddressNotInAccount, err)
+
+	for _, signed := range []transaction.TxnSignedFlag{transaction.TxnUnsigned, transaction.TxnSigned} {
+		var txn *coin.Transaction
+		var inputs []TransactionInput
+		if signed == transaction.TxnSigned {
+			txn, inputs, err = v.WalletCreateTransactionSigned("bip44.wlt", nil, p, CreateTransactionParams{
+				Account: &account,
+			})
+			require.NoError(t, err)
+			require.True(t, txn.IsFullySigned())
+		} else {
+			txn, inputs, err = v.WalletCreateTransaction("bip44.wlt", p, CreateTransactionParams{
+				Account: &account,
+			})
+			require.NoError(t, err)
+			require.False(t, txn.IsFullySigned())
+		}
+
+		require.Len(t, inputs, 1)
+		require.Equal(t, savingsAddr, inputs[0].UxOut.Body.Address)
+
+		// The change goes to the change chain of the savings account
+		require.Len(t, txn.Out, 2)
+		accounts, err := v.GetWalletAccounts("bip44.wlt")
+		require.NoError(t, err)
+		require.Equal(t, []cipher.Address{txn.Out[1].Address}, accounts[1].ChangeAddresses)
+		require.Equal(t, uint64(9e6), txn.Out[1].Coins)
+	}
+
+	// The inputs of accounts other than account 0 are signed
+	unsignedTxn, inputs, err := v.WalletCreateTransaction("bip44.wlt", p, CreateTransactionParams{
+		Account: &account,
+	})
+	require.NoError(t, err)
+
+	signedTxn, _, err := v.WalletSignTransaction("bip44.wlt", nil, unsignedTxn, nil)
+	require.NoError(t, err)
+	require.True(t, signedTxn.IsFullySigned())
+
+	uxOuts := make([]coin.UxOut, len(inputs))
+	for i, in := range inputs {
+		uxOuts[i] = in.UxOut
+	}
+
+	pkt, err := psbt.New(*unsignedTxn, uxOuts)
+	require.NoError(t, err)
+	signedPkt, err := v.WalletSignPacket("bip44.wlt", nil, pkt)
+	require.NoError(t, err)
+	require.True(t, signedPkt.IsFullySigned())
+	require.Equal(t, "bip44.wlt", signedPkt.Inputs[0].WalletHint)
+
+	pktTxn, err := signedPkt.Finalize()
+	require.NoError(t, err)
+	require.NoError(t, pktTxn.VerifyInputSignatures(uxOuts))
+}
@@ -0,0 +1,273 @@
+package visor
+
+// This file contains the gap limit address discovery of bip44 wallets
+
+import (
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/wallet"
+	"github.com/skycoin/skycoin/src/wallet/bip44wallet"
+)
+
+// DefaultGapLimit is the number of consecutive unused addresses kept after the last used address
+// of each chain of a bip44 wallet account
+const DefaultGapLimit = 20
+
+// DiscoverWalletAddresses generates the addresses of a bip44 wallet until each chain of each account
+// ends with gapLimit consecutive addresses without transactions. gapLimit 0 means DefaultGapLimit.
+// If the seed is available, i.e. the wallet is not encrypted or the password is provided,
+// the accounts following the last account are discovered too, stopping at the first account without transactions.
+// Returns the generated addresses.
+func (vs *Visor) DiscoverWalletAddresses(wltID string, password []byte, gapLimit uint64) ([]cipher.Address, error) {
+	if gapLimit == 0 {
+		gapLimit = DefaultGapLimit
+	}
+
+	var addrs []cipher.Addresser
+	f := func(discoverAccounts bool) func(wallet.Wallet) error {
+		return func(w wallet.Wallet) error {
+			bw, ok := w.(*bip44wallet.Wallet)
+			if !ok {
+				return ErrWalletNotBip44
+			}
+
+			var err error
+			addrs, err = vs.discoverWalletAddresses(bw, gapLimit, discoverAccounts)
+			return err
+		}
+	}
+
+	if len(password) != 0 {
+		if err := vs.wallets.UpdateSecrets(wltID, password, f(true)); err != nil {
+			return nil, err
+		}
+	} else {
+		if err := vs.wallets.Update(wltID, func(w wallet.Wallet) error {
+			return f(!w.IsEncrypted())(w)
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	return wallet.SkycoinAddresses(addrs), nil
+}
+
+// discoverWalletAddresses generates the addresses of the existing accounts of w, then discovers
+// the following accounts if discoverAccounts is true. The wallet must be unlocked to discover accounts.
+func (vs *Visor) discoverWalletAddresses(w *bip44wallet.Wallet, gapLimit uint64, discoverAccounts bool) ([]cipher.Addresser, error) {
+	var addrs []cipher.Addresser
+	var lastActive bool
+	for _, a := range w.Accounts() {
+		accountAddrs, active, err := vs.discoverAccountAddresses(w, a.Index, gapLimit)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, accountAddrs...)
+		lastActive = active
+	}
+
+	// The next account is only kept if it has transactions, so discover it on a copy of the wallet
+	for discoverAccounts && lastActive {
+		w2 := w.Clone().(*bip44wallet.Wallet)
+		index, err := w2.NewAccount("")
+		if err != nil {
+			return nil, err
+		}
+
+		accountAddrs, active, err := vs.discoverAccountAddresses(w2, index, gapLimit)
+		if err != nil {
+			return nil, err
+		}
+
+		if active {
+			logger.Infof("Discovered account %d of bip44 wallet %s", index, w.Filename())
+			w.CopyFromRef(w2)
+			addrs = append(addrs, accountAddrs...)
+		}
+		lastActive = active
+	}
+
+	return addrs, nil
+}
+
+// discoverAccountAddresses generates the addresses of both chains of an account.
+// Returns the generated addresses and whether any address of the account has transactions.
+func (vs *Visor) discoverAccountAddresses(w *bip44wallet.Wallet, account uint32, gapLimit uint64) ([]cipher.Addresser, bool, error) {
+	var addrs []cipher.Addresser
+	var active bool
+	for _, chain := range []wallet.Option{wallet.OptionExternal(), wallet.OptionChange()} {
+		chainAddrs, chainActive, err := vs.discoverChainAddresses(w, []wallet.Option{wallet.OptionAccount(account), chain}, gapLimit)
+		if err != nil {
+			return nil, false, err
+		}
+		addrs = append(addrs, chainAddrs...)
+		active = active || chainActive
+	}
+
+	return addrs, active, nil
+}
+
+// discoverChainAddresses generates the addresses of the account chain selected by opts until it ends
+// with gapLimit consecutive addresses without transactions.
+// Returns the generated addresses and whether any address of the chain has transactions.
+func (vs *Visor) discoverChainAddresses(w *bip44wallet.Wallet, opts []wallet.Option, gapLimit uint64) ([]cipher.Addresser, bool, error) {
+	addrs, err := w.GetAddresses(opts...)
+	if err != nil {
+		return nil, false, err
+	}
+
+	// used is the number of addresses up to the last address with transactions
+	var used uint64
+	checkActivity := func(addrs []cipher.Addresser, offset uint64) error {
+		active, err := vs.tf.AddressesActivity(addrs)
+		if err != nil {
+			return err
+		}
+
+		for i := len(active) - 1; i >= 0; i-- {
+			if active[i] {
+				used = offset + uint64(i) + 1
+				break
+			}
+		}
+		return nil
+	}
+
+	if err := checkActivity(addrs, 0); err != nil {
+		return nil, false, err
+	}
+
+	var generated []cipher.Addresser
+	for n := uint64(len(addrs)); n < used+gapLimit; n = uint64(len(addrs)) {
+		newAddrs, err := w.GenerateAddresses(append(opts, wallet.OptionGenerateN(used+gapLimit-n))...)
+		if err != nil {
+			return nil, false, err
+		}
+
+		if err := checkActivity(newAddrs, n); err != nil {
+			return nil, false, err
+		}
+
+		addrs = append(addrs, newAddrs...)
+		generated = append(generated, newAddrs...)
+	}
+
+	return generated, used != 0, nil
+}
+
+// RunWalletAddressDiscovery keeps Config.WalletGapLimit unused addresses after the last used address of each chain
+// of the loaded bip44 wallets, discovering their addresses again when a block or an unconfirmed transaction
+// involves one of their addresses. It returns when quit is closed.
+func (vs *Visor) RunWalletAddressDiscovery(quit <-chan struct{}) {
+	if vs.Config.WalletGapLimit == 0 || vs.events == nil {
+		return
+	}
+
+	sub := vs.SubscribeEvents()
+	defer func() {
+		sub.Unsubscribe()
+	}()
+
+	// The wallets may have been used by another node since they were last loaded
+	vs.discoverLoadedWalletAddresses(nil)
+
+	for {
+		select {
+		case <-quit:
+			return
+		case e, ok := <-sub.Events:
+			if !ok {
+				// The subscription fell behind and was dropped, the missed events may involve any wallet
+				logger.Warning("Wallet address discovery fell behind, checking all wallets")
+				sub = vs.SubscribeEvents()
+				vs.discoverLoadedWalletAddresses(nil)
+				continue
+			}
+
+			if addrs := eventAddresses(e); len(addrs) != 0 {
+				vs.discoverLoadedWalletAddresses(addrs)
+			}
+		}
+	}
+}
+
+// discoverLoadedWalletAddresses discovers the addresses of the loaded bip44 wallets that own any of addrs,
+// or of all of them if addrs is nil
+func (vs *Visor) discoverLoadedWalletAddresses(addrs []cipher.Address) {
+	wlts, err := vs.wallets.GetWallets()
+	if err != nil {
+		if err != wallet.ErrWalletAPIDisabled {
+			logger.WithError(err).Error("discoverLoadedWalletAddresses: GetWallets failed")
+		}
+		return
+	}
+
+	addrsMap := make(map[cipher.Address]struct{}, len(addrs))
+	for _, a := range addrs {
+		addrsMap[a] = struct{}{}
+	}
+
+	for wltID, w := range wlts {
+		if w.Type() != wallet.WalletTypeBip44 {
+			continue
+		}
+
+		if addrs != nil {
+			owns, err := walletOwnsAddress(w, addrsMap)
+			if err != nil {
+				logger.WithError(err).WithField("wallet", wltID).Error("discoverLoadedWalletAddresses: walletOwnsAddress failed")
+				continue
+			}
+			if !owns {
+				continue
+			}
+		}
+
+		generated, err := vs.DiscoverWalletAddresses(wltID, nil, vs.Config.WalletGapLimit)
+		if err != nil {
+			logger.WithError(err).WithField("wallet", wltID).Error("discoverLoadedWalletAddresses: DiscoverWalletAddresses failed")
+			continue
+		}
+
+		if len(generated) != 0 {
+			logger.WithField("wallet", wltID).Infof("Generated %d addresses by address discovery", len(generated))
+		}
+	}
+}
+
+// walletOwnsAddress returns true if any address of the accounts of a bip44 wallet is in addrs
+func walletOwnsAddress(w wallet.Wallet, addrs map[cipher.Address]struct{}) (bool, error) {
+	for _, a := range w.Accounts() {
+		accountAddrs, err := w.GetAddresses(wallet.OptionAccount(a.Index))
+		if err != nil {
+			return false, err
+		}
+
+		for _, addr := range wallet.SkycoinAddresses(accountAddrs) {
+			if _, ok := addrs[addr]; ok {
+				return true, nil
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// eventAddresses returns the addresses of the transactions of a block or of an added unconfirmed transaction
+func eventAddresses(e Event) []cipher.Address {
+	switch e.Type {
+	case EventBlock:
+		var addrs []cipher.Address
+		for i, txn := range e.Block.Body.Transactions {
+			var inputs []TransactionInput
+			if i < len(e.BlockInputs) {
+				inputs = e.BlockInputs[i]
+			}
+			addrs = append(addrs, TransactionAddresses(txn, inputs)...)
+		}
+		return addrs
+	case EventUnconfirmedTxnAdded:
+		return TransactionAddresses(e.Transaction.Transaction, e.TransactionInputs)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,185 @@
+package visor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/crypto"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/wallet"
+	"github.com/skycoin/skycoin/src/wallet/bip44wallet"
+)
+
+func TestDiscoverWalletAddresses(t *testing.T) {
+	seed := "voyage say extend find sheriff surge priority merit ignore maple cash argue"
+
+	// Derive the addresses of the seed to mark some of them as used
+	ref, err := wallet.NewWallet("ref.wlt", "ref", seed, wallet.Options{
+		Coin:      wallet.CoinTypeSkycoin,
+		Type:      wallet.WalletTypeBip44,
+		GenerateN: 1,
+	})
+	require.NoError(t, err)
+	refWallet := ref.(*bip44wallet.Wallet)
+	for i := 0; i < 2; i++ {
+		_, err := refWallet.NewAccount("")
+		require.NoError(t, err)
+	}
+
+	addrsOf := func(account uint32, chain wallet.Option, n uint64) []cipher.Address {
+		l, err := ref.EntriesLen(wallet.OptionAccount(account), chain)
+		require.NoError(t, err)
+		if uint64(l) < n {
+			_, err := ref.GenerateAddresses(wallet.OptionAccount(account), chain, wallet.OptionGenerateN(n-uint64(l)))
+			require.NoError(t, err)
+		}
+		addrs, err := ref.GetAddresses(wallet.OptionAccount(account), chain)
+		require.NoError(t, err)
+		return wallet.SkycoinAddresses(addrs)[:n]
+	}
+
+	external0 := addrsOf(0, wallet.OptionExternal(), 60)
+	change0 := addrsOf(0, wallet.OptionChange(), 60)
+	external1 := addrsOf(1, wallet.OptionExternal(), 60)
+	change1 := addrsOf(1, wallet.OptionChange(), 60)
+
+	tf := mockTxnsFinder{
+		// The address 24 is within the gap of the address 15
+		external0[15]: true,
+		external0[24]: true,
+		// The address 50 is beyond the gap of the address 24
+		external0[50]: true,
+		change0[2]:    true,
+		external1[0]:  true,
+	}
+
+	newVisor := func(t *testing.T) (*Visor, *wallet.Service) {
+		ws, err := wallet.NewService(wallet.Config{
+			EnableWalletAPI: true,
+			CryptoType:      crypto.CryptoTypeScryptChacha20poly1305Insecure,
+			WalletDir:       prepareWltDir(),
+		})
+		require.NoError(t, err)
+
+		return &Visor{
+			Config:  NewConfig(),
+			wallets: ws,
+			tf:      tf,
+		}, ws
+	}
+
+	requireAccounts := func(t *testing.T, ws *wallet.Service, wltID string, lens [][2]int) {
+		w, err := ws.GetWallet(wltID)
+		require.NoError(t, err)
+		require.Len(t, w.Accounts(), len(lens))
+		for i, l := range lens {
+			n, err := w.EntriesLen(wallet.OptionAccount(uint32(i)), wallet.OptionExternal())
+			require.NoError(t, err)
+			require.Equal(t, l[0], n, "account %d external", i)
+			n, err = w.EntriesLen(wallet.OptionAccount(uint32(i)), wallet.OptionChange())
+			require.NoError(t, err)
+			require.Equal(t, l[1], n, "account %d change", i)
+		}
+	}
+
+	t.Run("not bip44", func(t *testing.T) {
+		v, ws := newVisor(t)
+		_, err := ws.CreateWallet("foo.wlt", wallet.Options{
+			Label: "foo",
+			Coin:  wallet.CoinTypeSkycoin,
+			Type:  wallet.WalletTypeCollection,
+		})
+		require.NoError(t, err)
+
+		_, err = v.DiscoverWalletAddresses("foo.wlt", nil, 0)
+		require.Equal(t, ErrWalletNotBip44, err)
+	})
+
+	t.Run("unencrypted", func(t *testing.T) {
+		v, ws := newVisor(t)
+		_, err := ws.CreateWallet("bip44.wlt", wallet.Options{
+			Label:     "bip44",
+			Coin:      wallet.CoinTypeSkycoin,
+			Type:      wallet.WalletTypeBip44,
+			Seed:      seed,
+			GenerateN: 1,
+		})
+		require.NoError(t, err)
+
+		addrs, err := v.DiscoverWalletAddresses("bip44.wlt", nil, 0)
+		require.NoError(t, err)
+
+		// Account 1 is discovered, account 2 has no transactions
+		requireAccounts(t, ws, "bip44.wlt", [][2]int{{45, 23}, {21, 20}})
+		require.Len(t, addrs, 44+22+21+20)
+		require.Equal(t, external0[1:45], addrs[:44])
+		require.Equal(t, change0[1:23], addrs[44:66])
+		require.Equal(t, external1[:21], addrs[66:87])
+		require.Equal(t, change1[:20], addrs[87:])
+
+		// The window is complete
+		addrs, err = v.DiscoverWalletAddresses("bip44.wlt", nil, 0)
+		require.NoError(t, err)
+		require.Empty(t, addrs)
+
+		// A larger gap limit finds the address 50
+		addrs, err = v.DiscoverWalletAddresses("bip44.wlt", nil, 30)
+		require.NoError(t, err)
+		require.NotEmpty(t, addrs)
+		requireAccounts(t, ws, "bip44.wlt", [][2]int{{81, 33}, {31, 30}})
+	})
+
+	t.Run("encrypted", func(t *testing.T) {
+		v, ws := newVisor(t)
+		_, err := ws.CreateWallet("bip44.wlt", wallet.Options{
+			Label:      "bip44",
+			Coin:       wallet.CoinTypeSkycoin,
+			Type:       wallet.WalletTypeBip44,
+			Seed:       seed,
+			GenerateN:  1,
+			Encrypt:    true,
+			Password:   []byte("pwd"),
+			CryptoType: crypto.CryptoTypeScryptChacha20poly1305Insecure,
+		})
+		require.NoError(t, err)
+
+		// Without the password the accounts can not be discovered
+		addrs, err := v.DiscoverWalletAddresses("bip44.wlt", nil, 0)
+		require.NoError(t, err)
+		require.Len(t, addrs, 44+22)
+		requireAccounts(t, ws, "bip44.wlt", [][2]int{{45, 23}})
+
+		_, err = v.DiscoverWalletAddresses("bip44.wlt", []byte("wrong"), 0)
+		require.Equal(t, wallet.ErrInvalidPassword, err)
+
+		addrs, err = v.DiscoverWalletAddresses("bip44.wlt", []byte("pwd"), 0)
+		require.NoError(t, err)
+		require.Len(t, addrs, 41)
+		requireAccounts(t, ws, "bip44.wlt", [][2]int{{45, 23}, {21, 20}})
+
+		w, err := ws.GetWallet("bip44.wlt")
+		require.NoError(t, err)
+		require.True(t, w.IsEncrypted())
+	})
+
+	t.Run("loaded wallets", func(t *testing.T) {
+		v, ws := newVisor(t)
+		_, err := ws.CreateWallet("bip44.wlt", wallet.Options{
+			Label:     "bip44",
+			Coin:      wallet.CoinTypeSkycoin,
+			Type:      wallet.WalletTypeBip44,
+			Seed:      seed,
+			GenerateN: 1,
+		})
+		require.NoError(t, err)
+
+		// Addresses that are not in the wallet do not start the discovery
+		v.discoverLoadedWalletAddresses([]cipher.Address{testutil.MakeAddress(), external0[15]})
+		requireAccounts(t, ws, "bip44.wlt", [][2]int{{1, 1}})
+
+		v.discoverLoadedWalletAddresses([]cipher.Address{external0[0]})
+		requireAccounts(t, ws, "bip44.wlt", [][2]int{{45, 23}, {21, 20}})
+	})
+}
@@ -0,0 +1,298 @@
+package visor
+
+// This file contains the coin control of wallets: labels and freeze flags of the wallet outputs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ness-network/ness/src/wallet/watch"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/visor/dbutil"
+	"github.com/skycoin/skycoin/src/wallet"
+	"github.com/skycoin/skycoin/src/wallet/bip44wallet"
+	"github.com/skycoin/skycoin/src/wallet/collection"
+	"github.com/skycoin/skycoin/src/wallet/deterministic"
+	"github.com/skycoin/skycoin/src/wallet/xpubwallet"
+)
+
+// metaOutputs is the wallet meta field with the labels and freeze flags of the wallet outputs, as JSON
+const metaOutputs = "outputs"
+
+var (
+	// ErrWalletOutputNotFound the output is not an unspent output of the wallet
+	ErrWalletOutputNotFound = NewUserError(errors.New("Output is not an unspent output of the wallet"))
+	// ErrWalletOutputFrozen the output is frozen and can not be spent
+	ErrWalletOutputFrozen = NewUserError(errors.New("Output is frozen, unfreeze it to spend it"))
+)
+
+// WalletOutputMeta is the coin control data of a wallet output
+type WalletOutputMeta struct {
+	Label  string `json:"label,omitempty"`
+	Frozen bool   `json:"frozen,omitempty"`
+}
+
+// WalletOutput is an unspent output of a wallet with its label and freeze flag
+type WalletOutput struct {
+	UnspentOutput
+	WalletOutputMeta
+}
+
+// walletMeta returns the meta data of a wallet, which is shared with the wallet
+func walletMeta(w wallet.Wallet) (wallet.Meta, error) {
+	switch w := w.(type) {
+	case *bip44wallet.Wallet:
+		return w.Meta, nil
+	case *deterministic.Wallet:
+		return w.Meta, nil
+	case *collection.Wallet:
+		return w.Meta, nil
+	case *xpubwallet.Wallet:
+		return w.Meta, nil
+	case *watch.Wallet:
+		return w.Meta, nil
+	default:
+		return nil, fmt.Errorf("wallet type %q has no meta data", w.Type())
+	}
+}
+
+// walletOutputsMeta returns the coin control data of the wallet outputs, by output hash
+func walletOutputsMeta(w wallet.Wallet) (map[cipher.SHA256]WalletOutputMeta, error) {
+	m, err := walletMeta(w)
+	if err != nil {
+		return nil, err
+	}
+
+	var hexOutputs map[string]WalletOutputMeta
+	if v := m.Find(metaOutputs); v != "" {
+		if err := json.Unmarshal([]byte(v), &hexOutputs); err != nil {
+			return nil, fmt.Errorf("invalid wallet outputs meta data: %v", err)
+		}
+	}
+
+	outputs := make(map[cipher.SHA256]WalletOutputMeta, len(hexOutputs))
+	for k, o := range hexOutputs {
+		h, err := cipher.SHA256FromHex(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid wallet outputs meta data: %v", err)
+		}
+		outputs[h] = o
+	}
+
+	return outputs, nil
+}
+
+// setWalletOutputsMeta sets the coin control data of the wallet outputs
+func setWalletOutputsMeta(w wallet.Wallet, outputs map[cipher.SHA256]WalletOutputMeta) error {
+	m, err := walletMeta(w)
+	if err != nil {
+		return err
+	}
+
+	if len(outputs) == 0 {
+		delete(m, metaOutputs)
+		return nil
+	}
+
+	hexOutputs := make(map[string]WalletOutputMeta, len(outputs))
+	for h, o := range outputs {
+		hexOutputs[h.Hex()] = o
+	}
+
+	b, err := json.Marshal(hexOutputs)
+	if err != nil {
+		return err
+	}
+
+	m[metaOutputs] = string(b)
+	return nil
+}
+
+// walletFrozenOutputs returns the hashes of the frozen outputs of a wallet
+func walletFrozenOutputs(w wallet.Wallet) (map[cipher.SHA256]struct{}, error) {
+	outputs, err := walletOutputsMeta(w)
+	if err != nil {
+		return nil, err
+	}
+
+	frozen := make(map[cipher.SHA256]struct{})
+	for h, o := range outputs {
+		if o.Frozen {
+			frozen[h] = struct{}{}
+		}
+	}
+
+	return frozen, nil
+}
+
+// walletAllAddresses returns the addresses of all accounts and chains of a wallet
+func walletAllAddresses(w wallet.Wallet) ([]cipher.Address, error) {
+	if w.Type() != wallet.WalletTypeBip44 {
+		addrs, err := w.GetAddresses()
+		if err != nil {
+			return nil, err
+		}
+		return wallet.SkycoinAddresses(addrs), nil
+	}
+
+	var addrs []cipher.Address
+	for _, a := range w.Accounts() {
+		accountAddrs, err := w.GetAddresses(wallet.OptionAccount(a.Index))
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, wallet.SkycoinAddresses(accountAddrs)...)
+	}
+
+	return addrs, nil
+}
+
+// walletOutputs returns the confirmed unspent outputs of a wallet with their coin control data
+func (vs *Visor) walletOutputs(w wallet.Wallet) ([]WalletOutput, error) {
+	addrs, err := walletAllAddresses(w)
+	if err != nil {
+		return nil, err
+	}
+
+	meta, err := walletOutputsMeta(w)
+	if err != nil {
+		return nil, err
+	}
+
+	var uxOuts coin.UxArray
+	var head *coin.SignedBlock
+	if err := vs.db.View("walletOutputs", func(tx *dbutil.Tx) error {
+		var err error
+		head, err = vs.blockchain.Head(tx)
+		if err != nil {
+			return err
+		}
+
+		addrHashes, err := vs.blockchain.Unspent().GetUnspentHashesOfAddrs(tx, addrs)
+		if err != nil {
+			return err
+		}
+
+		uxOuts, err = vs.blockchain.Unspent().GetArray(tx, addrHashes.Flatten())
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	outputs := make([]WalletOutput, len(uxOuts))
+	for i, ux := range uxOuts {
+		uo, err := NewUnspentOutput(ux, head.Time())
+		if err != nil {
+			return nil, err
+		}
+
+		outputs[i] = WalletOutput{
+			UnspentOutput:    uo,
+			WalletOutputMeta: meta[ux.Hash()],
+		}
+	}
+
+	return outputs, nil
+}
+
+// GetWalletOutputs returns the confirmed unspent outputs of a wallet with their labels and freeze flags
+func (vs *Visor) GetWalletOutputs(wltID string) ([]WalletOutput, error) {
+	var outputs []WalletOutput
+	if err := vs.wallets.View(wltID, func(w wallet.Wallet) error {
+		var err error
+		outputs, err = vs.walletOutputs(w)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+
+	return outputs, nil
+}
+
+// FreezeWalletOutputs freezes or unfreezes unspent outputs of a wallet. A frozen output is not spent
+// by the transactions created by the wallet, unless it is unfrozen.
+// Returns the updated outputs.
+func (vs *Visor) FreezeWalletOutputs(wltID string, hashes []cipher.SHA256, frozen bool) ([]WalletOutput, error) {
+	return vs.updateWalletOutputs(wltID, hashes, func(m *WalletOutputMeta) {
+		m.Frozen = frozen
+	})
+}
+
+// SetWalletOutputLabel sets the label of an unspent output of a wallet. An empty label removes it.
+// Returns the updated output.
+func (vs *Visor) SetWalletOutputLabel(wltID string, hash cipher.SHA256, label string) (*WalletOutput, error) {
+	outputs, err := vs.updateWalletOutputs(wltID, []cipher.SHA256{hash}, func(m *WalletOutputMeta) {
+		m.Label = label
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &outputs[0], nil
+}
+
+// updateWalletOutputs applies f to the coin control data of unspent outputs of a wallet and saves it.
+// The data of the outputs that were spent is removed.
+func (vs *Visor) updateWalletOutputs(wltID string, hashes []cipher.SHA256, f func(*WalletOutputMeta)) ([]WalletOutput, error) {
+	var updated []WalletOutput
+	if err := vs.wallets.Update(wltID, func(w wallet.Wallet) error {
+		outputs, err := vs.walletOutputs(w)
+		if err != nil {
+			return err
+		}
+
+		index := make(map[cipher.SHA256]int, len(outputs))
+		for i, o := range outputs {
+			index[o.Hash()] = i
+		}
+
+		updated = make([]WalletOutput, 0, len(hashes))
+		for _, h := range hashes {
+			i, ok := index[h]
+			if !ok {
+				return ErrWalletOutputNotFound
+			}
+
+			f(&outputs[i].WalletOutputMeta)
+			updated = append(updated, outputs[i])
+		}
+
+		meta := make(map[cipher.SHA256]WalletOutputMeta)
+		for _, o := range outputs {
+			if o.WalletOutputMeta != (WalletOutputMeta{}) {
+				meta[o.Hash()] = o.WalletOutputMeta
+			}
+		}
+
+		return setWalletOutputsMeta(w, meta)
+	}); err != nil {
+		return nil, err
+	}
+
+	return updated, nil
+}
+
+// filterFrozenUxOuts removes the frozen outputs from auxs
+func filterFrozenUxOuts(auxs coin.AddressUxOuts, frozen map[cipher.SHA256]struct{}) coin.AddressUxOuts {
+	if len(frozen) == 0 {
+		return auxs
+	}
+
+	filtered := make(coin.AddressUxOuts, len(auxs))
+	for addr, uxOuts := range auxs {
+		var spendable coin.UxArray
+		for _, ux := range uxOuts {
+			if _, ok := frozen[ux.Hash()]; !ok {
+				spendable = append(spendable, ux)
+			}
+		}
+
+		if len(spendable) != 0 {
+			filtered[addr] = spendable
+		}
+	}
+
+	return filtered
+}
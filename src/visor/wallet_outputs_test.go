@@ -0,0 +1,189 @@
+package visor
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ness-network/ness/src/visor/historydb"
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/cipher/crypto"
+	"github.com/skycoin/skycoin/src/coin"
+	"github.com/skycoin/skycoin/src/params"
+	"github.com/skycoin/skycoin/src/testutil"
+	"github.com/skycoin/skycoin/src/transaction"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+func TestWalletOutputs(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	bc, err := NewBlockchain(db, BlockchainConfig{
+		Pubkey: genPublic,
+	})
+	require.NoError(t, err)
+
+	unconfirmed, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{})
+	require.NoError(t, err)
+
+	walletDir := prepareWltDir()
+	ws, err := wallet.NewService(wallet.Config{
+		EnableWalletAPI: true,
+		CryptoType:      crypto.CryptoTypeScryptChacha20poly1305Insecure,
+		WalletDir:       walletDir,
+	})
+	require.NoError(t, err)
+
+	cfg := NewConfig()
+	cfg.IsBlockPublisher = true
+	cfg.BlockchainPubkey = genPublic
+	cfg.BlockchainSeckey = genSecret
+	cfg.GenesisAddress = genAddress
+	cfg.Distribution = params.MainNetDistribution
+
+	v := &Visor{
+		Config:      cfg,
+		unconfirmed: unconfirmed,
+		blockchain:  bc,
+		db:          db,
+		history:     historydb.New(),
+		wallets:     ws,
+		tf:          mockTxnsFinder{},
+	}
+
+	gb := addGenesisBlockToVisor(t, v)
+
+	w, err := ws.CreateWallet("foo.wlt", wallet.Options{
+		Label:     "foo",
+		Coin:      wallet.CoinTypeSkycoin,
+		Type:      wallet.WalletTypeDeterministic,
+		Seed:      "fooseed",
+		GenerateN: 2,
+	})
+	require.NoError(t, err)
+	addrs, err := w.GetAddresses()
+	require.NoError(t, err)
+
+	// Send an output to each address of the wallet
+	uxs := coin.CreateUnspents(gb.Head, gb.Body.Transactions[0])
+	for i, a := range addrs {
+		txn := makeSpendTxn(t, uxs, []cipher.SecKey{genSecret}, a.(cipher.Address), 10e6)
+		b, err := v.CreateBlockFromTxns(coin.Transactions{txn}, genTime+uint64(i+1)*100)
+		require.NoError(t, err)
+		sb := coin.SignedBlock{
+			Block: b,
+			Sig:   cipher.MustSignHash(b.HashHeader(), genSecret),
+		}
+		require.NoError(t, v.ExecuteSignedBlock(sb))
+
+		// The change output of the genesis address
+		uxs = coin.CreateUnspents(sb.Head, txn)[1:]
+	}
+
+	outputs, err := v.GetWalletOutputs("foo.wlt")
+	require.NoError(t, err)
+	require.Len(t, outputs, 2)
+	for _, o := range outputs {
+		require.Equal(t, WalletOutputMeta{}, o.WalletOutputMeta)
+	}
+
+	frozenOutput := outputs[0]
+	spendableOutput := outputs[1]
+
+	_, err = v.FreezeWalletOutputs("foo.wlt", []cipher.SHA256{testutil.RandSHA256(t)}, true)
+	require.Equal(t, ErrWalletOutputNotFound, err)
+
+	updated, err := v.FreezeWalletOutputs("foo.wlt", []cipher.SHA256{frozenOutput.Hash()}, true)
+	require.NoError(t, err)
+	require.Len(t, updated, 1)
+	require.True(t, updated[0].Frozen)
+
+	labeled, err := v.SetWalletOutputLabel("foo.wlt", frozenOutput.Hash(), "cold storage")
+	require.NoError(t, err)
+	require.Equal(t, WalletOutputMeta{
+		Label:  "cold storage",
+		Frozen: true,
+	}, labeled.WalletOutputMeta)
+
+	// The labels and freeze flags are saved with the wallet
+	ws2, err := wallet.NewService(wallet.Config{
+		EnableWalletAPI: true,
+		CryptoType:      crypto.CryptoTypeScryptChacha20poly1305Insecure,
+		WalletDir:       walletDir,
+	})
+	require.NoError(t, err)
+	v.wallets = ws2
+
+	outputs, err = v.GetWalletOutputs("foo.wlt")
+	require.NoError(t, err)
+	for _, o := range outputs {
+		if o.Hash() == frozenOutput.Hash() {
+			require.Equal(t, labeled.WalletOutputMeta, o.WalletOutputMeta)
+		} else {
+			require.Equal(t, WalletOutputMeta{}, o.WalletOutputMeta)
+		}
+	}
+
+	shareFactor := decimal.New(5, -1)
+	p := transaction.Params{
+		HoursSelection: transaction.HoursSelection{
+			Type:        transaction.HoursSelectionTypeAuto,
+			Mode:        transaction.HoursSelectionModeShare,
+			ShareFactor: &shareFactor,
+		},
+		To: []coin.TransactionOutput{
+			{
+				Address: testutil.MakeAddress(),
+				Coins:   1e6,
+			},
+		},
+	}
+
+	// The frozen output is not spent
+	_, inputs, err := v.WalletCreateTransaction("foo.wlt", p, CreateTransactionParams{})
+	require.NoError(t, err)
+	require.Len(t, inputs, 1)
+	require.Equal(t, spendableOutput.Hash(), inputs[0].UxOut.Hash())
+
+	// The frozen output can not be chosen explicitly
+	_, _, err = v.WalletCreateTransaction("foo.wlt", p, CreateTransactionParams{
+		UxOuts: []cipher.SHA256{frozenOutput.Hash()},
+	})
+	require.Equal(t, ErrWalletOutputFrozen, err)
+
+	// The frozen coins are not available
+	pAll := p
+	pAll.To = []coin.TransactionOutput{
+		{
+			Address: testutil.MakeAddress(),
+			Coins:   15e6,
+		},
+	}
+	_, _, err = v.WalletCreateTransaction("foo.wlt", pAll, CreateTransactionParams{})
+	require.Equal(t, transaction.ErrInsufficientBalance, err)
+
+	// Freezing all the outputs leaves nothing to spend
+	_, err = v.FreezeWalletOutputs("foo.wlt", []cipher.SHA256{spendableOutput.Hash()}, true)
+	require.NoError(t, err)
+	_, _, err = v.WalletCreateTransaction("foo.wlt", p, CreateTransactionParams{})
+	require.Equal(t, transaction.ErrNoUnspents, err)
+
+	// Unfrozen outputs are spent again
+	_, err = v.FreezeWalletOutputs("foo.wlt", []cipher.SHA256{frozenOutput.Hash(), spendableOutput.Hash()}, false)
+	require.NoError(t, err)
+	_, inputs, err = v.WalletCreateTransaction("foo.wlt", pAll, CreateTransactionParams{})
+	require.NoError(t, err)
+	require.Len(t, inputs, 2)
+
+	outputs, err = v.GetWalletOutputs("foo.wlt")
+	require.NoError(t, err)
+	for _, o := range outputs {
+		if o.Hash() == frozenOutput.Hash() {
+			require.Equal(t, WalletOutputMeta{Label: "cold storage"}, o.WalletOutputMeta)
+		} else {
+			require.Equal(t, WalletOutputMeta{}, o.WalletOutputMeta)
+		}
+	}
+}
@@ -13,13 +13,15 @@ import (
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
+	"github.com/ness-network/ness/src/psbt"
+	"github.com/ness-network/ness/src/visor/blockdb"
+	"github.com/ness-network/ness/src/wallet/watch"
 	"github.com/skycoin/skycoin/src/cipher"
 	"github.com/skycoin/skycoin/src/cipher/crypto"
 	"github.com/skycoin/skycoin/src/coin"
 	"github.com/skycoin/skycoin/src/params"
 	"github.com/skycoin/skycoin/src/testutil"
 	"github.com/skycoin/skycoin/src/transaction"
-	"github.com/skycoin/skycoin/src/visor/blockdb"
 	"github.com/skycoin/skycoin/src/visor/dbutil"
 	"github.com/skycoin/skycoin/src/wallet"
 	"github.com/skycoin/skycoin/src/wallet/collection"
@@ -1652,3 +1654,296 @@ func (mb mockTxnsFinder) AddressesActivity(addrs []cipher.Addresser) ([]bool, er
 	}
 	return active, nil
 }
+
+func TestWalletBumpFee(t *testing.T) {
+	db, shutdown := prepareDB(t)
+	defer shutdown()
+
+	bc := MakeBlockchain(t, db, GenesisSecret)
+	makeUnconfirmedPoolTxns(t, db, bc, []uint64{0})
+
+	var head *coin.SignedBlock
+	err := db.View("", func(tx *dbutil.Tx) error {
+		var err error
+		head, err = bc.Head(tx)
+		return err
+	})
+	require.NoError(t, err)
+
+	// The output of GenesisAddress created by makeUnconfirmedPoolTxns
+	ux := coin.CreateUnspents(head.Head, head.Body.Transactions[0])[0]
+
+	// Spend ux to another address with a change output, burning half of the coin hours
+	toAddr := testutil.MakeAddress()
+	var stuck coin.Transaction
+	err = stuck.PushInput(ux.Hash())
+	require.NoError(t, err)
+	err = stuck.PushOutput(toAddr, ux.Body.Coins/2, ux.Body.Hours/4)
+	require.NoError(t, err)
+	err = stuck.PushOutput(GenesisAddress, ux.Body.Coins/2, ux.Body.Hours/4)
+	require.NoError(t, err)
+	stuck.SignInputs([]cipher.SecKey{GenesisSecret})
+	err = stuck.UpdateHeader()
+	require.NoError(t, err)
+
+	stuckFee := txnFeeOf(t, db, bc, stuck)
+
+	pool, err := NewUnconfirmedTransactionPool(db, UnconfirmedTransactionPoolConfig{
+		ReplaceByFee: true,
+	})
+	require.NoError(t, err)
+
+	inject := func(txn coin.Transaction) {
+		err := db.Update("", func(tx *dbutil.Tx) error {
+			_, softErr, err := pool.InjectTransaction(tx, bc, txn, params.MainNetDistribution, params.UserVerifyTxn)
+			require.Nil(t, softErr)
+			return err
+		})
+		require.NoError(t, err)
+	}
+	inject(stuck)
+
+	ws, err := wallet.NewService(wallet.Config{
+		EnableWalletAPI: true,
+		CryptoType:      crypto.CryptoTypeScryptChacha20poly1305Insecure,
+		WalletDir:       prepareWltDir(),
+	})
+	require.NoError(t, err)
+
+	createWallet := func(wltID string, entries ...wallet.Entry) {
+		_, err := ws.CreateWallet(wltID, wallet.Options{
+			Label: "test",
+			Coin:  wallet.CoinTypeSkycoin,
+			Type:  wallet.WalletTypeCollection,
+		})
+		require.NoError(t, err)
+
+		err = ws.UpdateSecrets(wltID, nil, func(w wallet.Wallet) error {
+			for _, e := range entries {
+				require.NoError(t, w.(*collection.Wallet).AddEntry(e))
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}
+
+	genesisEntry := wallet.Entry{
+		Address: GenesisAddress,
+		Public:  cipher.MustPubKeyFromSecKey(GenesisSecret),
+		Secret:  GenesisSecret,
+	}
+	otherEntries, _ := makeEntries(1)
+	createWallet("genesis.wlt", genesisEntry)
+	createWallet("other.wlt", otherEntries...)
+
+	_, err = ws.CreateWallet("watch.wlt", wallet.Options{
+		Label: "test",
+		Seed:  GenesisAddress.String(),
+		Type:  watch.WalletType,
+	})
+	require.NoError(t, err)
+
+	v := &Visor{
+		db:          db,
+		blockchain:  bc,
+		unconfirmed: pool,
+		wallets:     ws,
+		Config: Config{
+			Distribution: params.MainNetDistribution,
+		},
+	}
+
+	cases := []struct {
+		name   string
+		wltID  string
+		txid   cipher.SHA256
+		newFee uint64
+		err    error
+	}{
+		{
+			name:   "unknown txn",
+			wltID:  "genesis.wlt",
+			txid:   testutil.RandSHA256(t),
+			newFee: stuckFee + 1,
+			err:    ErrTxnNotUnconfirmed,
+		},
+		{
+			name:   "inputs not in the wallet",
+			wltID:  "other.wlt",
+			txid:   stuck.Hash(),
+			newFee: stuckFee + 1,
+			err:    ErrTxnInputsNotInWallet,
+		},
+		{
+			name:   "fee not higher",
+			wltID:  "genesis.wlt",
+			txid:   stuck.Hash(),
+			newFee: stuckFee,
+			err:    ErrBumpFeeTooLow,
+		},
+		{
+			name:   "change output has too few hours",
+			wltID:  "genesis.wlt",
+			txid:   stuck.Hash(),
+			newFee: stuckFee + stuck.Out[1].Hours + 1,
+			err:    ErrBumpFeeInsufficientHours,
+		},
+		{
+			name:   "watch wallet",
+			wltID:  "watch.wlt",
+			txid:   stuck.Hash(),
+			newFee: stuckFee + 1,
+			err:    wallet.ErrWalletCantSign,
+		},
+		{
+			name:   "wallet not found",
+			wltID:  "missing.wlt",
+			txid:   stuck.Hash(),
+			newFee: stuckFee + 1,
+			err:    wallet.ErrWalletNotExist,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, _, err := v.WalletBumpFee(tc.wltID, nil, tc.txid, tc.newFee)
+			require.Equal(t, tc.err, err)
+		})
+	}
+
+	// The extra coin hours are taken from the change output
+	txn, inputs, err := v.WalletBumpFee("genesis.wlt", nil, stuck.Hash(), stuckFee+100)
+	require.NoError(t, err)
+	require.True(t, txn.IsFullySigned())
+	require.Equal(t, stuck.In, txn.In)
+	require.Equal(t, stuck.Out[0], txn.Out[0])
+	require.Equal(t, stuck.Out[1].Hours-100, txn.Out[1].Hours)
+	require.Equal(t, stuckFee+100, txnFeeOf(t, db, bc, *txn))
+	require.Len(t, inputs, 1)
+	require.Equal(t, ux, inputs[0].UxOut)
+
+	// The new txn replaces the stuck txn
+	inject(*txn)
+	err = db.View("", func(tx *dbutil.Tx) error {
+		txns, err := pool.AllRawTransactions(tx)
+		require.NoError(t, err)
+		require.Equal(t, coin.Transactions{*txn}, txns)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
+func TestWalletSignPacket(t *testing.T) {
+	ws, err := wallet.NewService(wallet.Config{
+		EnableWalletAPI: true,
+		CryptoType:      crypto.CryptoTypeScryptChacha20poly1305Insecure,
+		WalletDir:       prepareWltDir(),
+	})
+	require.NoError(t, err)
+
+	entries, addrs := makeEntries(3)
+	createWallet := func(wltID string, entries ...wallet.Entry) {
+		_, err := ws.CreateWallet(wltID, wallet.Options{
+			Label: "test",
+			Coin:  wallet.CoinTypeSkycoin,
+			Type:  wallet.WalletTypeCollection,
+		})
+		require.NoError(t, err)
+
+		err = ws.UpdateSecrets(wltID, nil, func(w wallet.Wallet) error {
+			for _, e := range entries {
+				require.NoError(t, w.(*collection.Wallet).AddEntry(e))
+			}
+			return nil
+		})
+		require.NoError(t, err)
+	}
+	createWallet("a.wlt", entries[0])
+	createWallet("b.wlt", entries[1:]...)
+	createWallet("other.wlt")
+
+	_, err = ws.CreateWallet("watch.wlt", wallet.Options{
+		Label: "test",
+		Seed:  addrs[0].String(),
+		Type:  watch.WalletType,
+	})
+	require.NoError(t, err)
+
+	// The packet spends one output of each address; the blockchain is not needed to sign it
+	var txn coin.Transaction
+	var uxOuts []coin.UxOut
+	for _, a := range addrs {
+		ux := coin.UxOut{
+			Body: coin.UxBody{
+				SrcTransaction: testutil.RandSHA256(t),
+				Address:        a,
+				Coins:          1e6,
+				Hours:          100,
+			},
+		}
+		require.NoError(t, txn.PushInput(ux.Hash()))
+		uxOuts = append(uxOuts, ux)
+	}
+	require.NoError(t, txn.PushOutput(testutil.MakeAddress(), 3e6, 100))
+	require.NoError(t, txn.UpdateHeader())
+
+	pkt, err := psbt.New(txn, uxOuts)
+	require.NoError(t, err)
+
+	v := &Visor{
+		wallets: ws,
+	}
+
+	cases := []struct {
+		name  string
+		wltID string
+		err   error
+	}{
+		{
+			name:  "watch wallet",
+			wltID: "watch.wlt",
+			err:   wallet.ErrWalletCantSign,
+		},
+		{
+			name:  "inputs not in the wallet",
+			wltID: "other.wlt",
+			err:   ErrPacketInputsNotInWallet,
+		},
+		{
+			name:  "wallet not found",
+			wltID: "missing.wlt",
+			err:   wallet.ErrWalletNotExist,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := v.WalletSignPacket(tc.wltID, nil, pkt)
+			require.Equal(t, tc.err, err)
+		})
+	}
+
+	pktA, err := v.WalletSignPacket("a.wlt", nil, pkt)
+	require.NoError(t, err)
+	require.True(t, pktA.Inputs[0].IsSigned())
+	require.Equal(t, "a.wlt", pktA.Inputs[0].WalletHint)
+	require.False(t, pktA.Inputs[1].IsSigned())
+	require.False(t, pktA.Inputs[2].IsSigned())
+	require.False(t, pkt.Inputs[0].IsSigned())
+
+	// Inputs signed already are skipped
+	_, err = v.WalletSignPacket("a.wlt", nil, pktA)
+	require.Equal(t, ErrPacketInputsNotInWallet, err)
+
+	pktB, err := v.WalletSignPacket("b.wlt", nil, pktA)
+	require.NoError(t, err)
+	require.True(t, pktB.IsFullySigned())
+	require.Equal(t, "b.wlt", pktB.Inputs[2].WalletHint)
+
+	_, err = v.WalletSignPacket("b.wlt", nil, pktB)
+	require.Equal(t, ErrTransactionAlreadySigned, err)
+
+	signedTxn, err := pktB.Finalize()
+	require.NoError(t, err)
+	require.NoError(t, signedTxn.VerifyInputSignatures(uxOuts))
+}
@@ -3,7 +3,7 @@ Package wallet implements wallets and the wallet database service
 
 Values of the Wallet interface can be created by calling function NewWallet,
 or by loading from `[]byte` that containing wallet data of type such as
-"deterministic", "collection", "bip44" or "xpubwallet". Loading any particular
+"deterministic", "collection", "bip44", "xpubwallet" or "watch". Loading any particular
 type of wallet requires the prior registration of a loader. Registration is typically
 automatic as a side effect of initializing that wallet's package so that, to load a
 "deterministic" wallet, it suffices to have
@@ -0,0 +1,97 @@
+package watch
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// JSONDecoder implements the the WalletDecoder interface,
+// which provides methods for encoding and decoding a watch wallet in JSON format.
+type JSONDecoder struct{}
+
+// Encode encodes the watch wallet to []byte, and error if any
+func (d JSONDecoder) Encode(w wallet.Wallet) ([]byte, error) {
+	return json.MarshalIndent(newReadableWallet(w.(*Wallet)), "", "    ")
+}
+
+// Decode decodes the watch wallet from byte slice
+func (d JSONDecoder) Decode(b []byte) (wallet.Wallet, error) {
+	rw := readableWallet{}
+	if err := json.Unmarshal(b, &rw); err != nil {
+		return nil, err
+	}
+
+	return rw.toWallet()
+}
+
+type readableWallet struct {
+	wallet.Meta `json:"meta"`
+	Entries     readableWatchEntries `json:"entries"`
+}
+
+func (w readableWallet) toWallet() (*Wallet, error) {
+	ct, err := wallet.ResolveCoinType(string(w.Coin()))
+	if err != nil {
+		return nil, err
+	}
+
+	meta := w.Meta.Clone()
+	meta.SetCoin(ct)
+
+	if err := validateMeta(meta); err != nil {
+		return nil, fmt.Errorf("invalid wallet %q: %v", meta.Filename(), err)
+	}
+
+	entries, err := w.Entries.toWatchEntries(wallet.ResolveAddressDecoder(ct))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Wallet{
+		Meta:    meta,
+		entries: entries,
+		decoder: &JSONDecoder{},
+	}, nil
+}
+
+func newReadableWallet(w *Wallet) *readableWallet {
+	return &readableWallet{
+		Meta:    w.Meta.Clone(),
+		Entries: newReadableEntries(w.entries),
+	}
+}
+
+type readableWatchEntries []readableWatchEntry
+
+func (es readableWatchEntries) toWatchEntries(ad wallet.AddressDecoder) (wallet.Entries, error) {
+	entries := make(wallet.Entries, len(es))
+	for i, e := range es {
+		addr, err := ad.DecodeBase58Address(e.Address)
+		if err != nil {
+			return nil, err
+		}
+
+		entries[i] = wallet.Entry{
+			Address: addr,
+		}
+	}
+
+	return entries, nil
+}
+
+func newReadableEntries(entries wallet.Entries) readableWatchEntries {
+	res := make(readableWatchEntries, len(entries))
+	for i, e := range entries {
+		res[i] = readableWatchEntry{
+			Address: e.Address.String(),
+		}
+	}
+
+	return res
+}
+
+type readableWatchEntry struct {
+	Address string `json:"address"`
+}
@@ -0,0 +1,332 @@
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/wallet"
+)
+
+// WalletType represents the watch-only wallet type
+const WalletType = "watch"
+
+var defaultWalletDecoder = &JSONDecoder{}
+
+var (
+	// ErrMissingAddresses is returned when creating a watch wallet without addresses
+	ErrMissingAddresses = wallet.NewError(errors.New("watch wallet requires at least one address"))
+	// ErrDuplicateAddress is returned when an address is added to a watch wallet twice
+	ErrDuplicateAddress = wallet.NewError(errors.New("watch wallet already contains this address"))
+)
+
+func init() {
+	if err := wallet.RegisterCreator(WalletType, &Creator{}); err != nil {
+		panic(err)
+	}
+
+	if err := wallet.RegisterLoader(WalletType, &Loader{}); err != nil {
+		panic(err)
+	}
+}
+
+// Wallet holds a list of addresses without any keys.
+// Watch wallets show the balance and history of the addresses and can create unsigned
+// transactions, but can't sign them.
+// This wallet does not support address scanning, generation or encryption.
+// This wallet does not use seeds.
+type Wallet struct {
+	wallet.Meta
+	entries wallet.Entries
+	decoder wallet.Decoder
+}
+
+// NewWallet creates a watch wallet for the addresses addrs
+func NewWallet(filename, label string, addrs []cipher.Addresser, options ...wallet.Option) (*Wallet, error) {
+	if label == "" {
+		return nil, wallet.ErrMissingLabel
+	}
+
+	if len(addrs) == 0 {
+		return nil, ErrMissingAddresses
+	}
+
+	wlt := &Wallet{
+		Meta: wallet.Meta{
+			wallet.MetaFilename:  filename,
+			wallet.MetaLabel:     label,
+			wallet.MetaType:      WalletType,
+			wallet.MetaVersion:   wallet.Version,
+			wallet.MetaCoin:      string(wallet.CoinTypeSkycoin),
+			wallet.MetaTimestamp: strconv.FormatInt(time.Now().Unix(), 10),
+		},
+		entries: wallet.Entries{},
+		decoder: defaultWalletDecoder,
+	}
+
+	advOpts := &wallet.AdvancedOptions{}
+	for _, opt := range options {
+		opt(wlt)
+		opt(advOpts)
+	}
+
+	if err := validateMeta(wlt.Meta); err != nil {
+		return nil, err
+	}
+
+	if advOpts.Encrypt {
+		return nil, wallet.NewError(errors.New("watch wallet does not support encryption"))
+	}
+
+	if advOpts.GenerateN != 0 || advOpts.ScanN != 0 {
+		return nil, wallet.NewError(fmt.Errorf("wallet scanning is not defined for %q wallet", WalletType))
+	}
+
+	if err := wlt.AddAddresses(addrs); err != nil {
+		return nil, err
+	}
+
+	return wlt, nil
+}
+
+// ParseAddresses parses a list of addresses of coinType joined with commas
+func ParseAddresses(coinType wallet.CoinType, s string) ([]cipher.Addresser, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	d := wallet.ResolveAddressDecoder(coinType)
+	var addrs []cipher.Addresser
+	for _, as := range strings.Split(s, ",") {
+		as = strings.TrimSpace(as)
+		if as == "" {
+			continue
+		}
+
+		a, err := d.DecodeBase58Address(as)
+		if err != nil {
+			return nil, wallet.NewError(fmt.Errorf("invalid address %q: %v", as, err))
+		}
+
+		addrs = append(addrs, a)
+	}
+
+	return addrs, nil
+}
+
+// SetDecoder sets the wallet decoder
+func (w *Wallet) SetDecoder(d wallet.Decoder) {
+	w.decoder = d
+}
+
+func validateMeta(m wallet.Meta) error {
+	if m[wallet.MetaType] != WalletType {
+		return wallet.ErrInvalidWalletType
+	}
+
+	if m[wallet.MetaSeed] != "" {
+		return wallet.NewError(fmt.Errorf("seed should not be provided for %q wallets", WalletType))
+	}
+
+	return wallet.ValidateMeta(m)
+}
+
+// Serialize encodes the watch wallet to []byte
+func (w Wallet) Serialize() ([]byte, error) {
+	if w.decoder == nil {
+		w.decoder = defaultWalletDecoder
+	}
+
+	return w.decoder.Encode(&w)
+}
+
+// Deserialize decodes the []byte to a watch wallet
+func (w *Wallet) Deserialize(b []byte) error {
+	if w.decoder == nil {
+		w.decoder = defaultWalletDecoder
+	}
+
+	toW, err := w.decoder.Decode(b)
+	if err != nil {
+		return err
+	}
+
+	toW2 := toW.(*Wallet)
+	toW2.decoder = w.decoder
+	*w = *toW2
+	return nil
+}
+
+// IsEncrypted returns whether the wallet is encrypted
+func (w Wallet) IsEncrypted() bool {
+	return w.Meta.IsEncrypted()
+}
+
+// Lock will do nothing to the watch wallet
+func (w Wallet) Lock(_ []byte) error {
+	return wallet.NewError(errors.New("watch wallet does not support encryption"))
+}
+
+// Unlock will do nothing to the watch wallet
+func (w *Wallet) Unlock(_ []byte) (wallet.Wallet, error) {
+	return nil, wallet.NewError(errors.New("watch wallet does not support encryption"))
+}
+
+// Fingerprint returns an empty string; fingerprints are only defined for
+// wallets with a seed or xpub key
+func (w *Wallet) Fingerprint() string {
+	return ""
+}
+
+// Clone returns a copy of the wallet
+func (w Wallet) Clone() wallet.Wallet {
+	return &Wallet{
+		Meta:    w.Meta.Clone(),
+		entries: w.entries.Clone(),
+		decoder: w.decoder,
+	}
+}
+
+// CopyFromRef copies the src wallet with a pointer dereference
+func (w *Wallet) CopyFromRef(src wallet.Wallet) {
+	*w = *(src.(*Wallet))
+}
+
+// Accounts is not defined for watch wallet
+func (w *Wallet) Accounts() []wallet.Bip44Account {
+	return nil
+}
+
+// Erase does nothing, the watch wallet has no sensitive data
+func (w *Wallet) Erase() {
+}
+
+// ScanAddresses is not defined for watch wallet
+func (w *Wallet) ScanAddresses(_ uint64, _ wallet.TransactionsFinder) ([]cipher.Addresser, error) {
+	return nil, wallet.NewError(errors.New("A watch wallet does not implement ScanAddresses"))
+}
+
+// GenerateAddresses is not defined for watch wallet, addresses are added with AddAddresses
+func (w *Wallet) GenerateAddresses(_ ...wallet.Option) ([]cipher.Addresser, error) {
+	return nil, wallet.NewError(errors.New("A watch wallet does not implement GenerateAddresses"))
+}
+
+// AddAddresses appends addrs to the wallet's entries.
+// No address is added if any of addrs is already in the wallet.
+func (w *Wallet) AddAddresses(addrs []cipher.Addresser) error {
+	seen := make(map[cipher.Addresser]struct{}, len(addrs))
+	for _, a := range addrs {
+		if a.Null() {
+			return wallet.NewError(errors.New("watch wallet address must not be the null address"))
+		}
+
+		if _, ok := seen[a]; ok || w.entries.Has(a) {
+			return ErrDuplicateAddress
+		}
+		seen[a] = struct{}{}
+	}
+
+	for _, a := range addrs {
+		w.entries = append(w.entries, wallet.Entry{
+			Address: a,
+		})
+	}
+
+	return nil
+}
+
+// GetAddresses returns all addresses of the wallet
+func (w *Wallet) GetAddresses(_ ...wallet.Option) ([]cipher.Addresser, error) {
+	return w.entries.GetAddresses(), nil
+}
+
+// GetEntries returns a copy of all entries held by the wallet
+func (w *Wallet) GetEntries(_ ...wallet.Option) (wallet.Entries, error) {
+	return w.entries.Clone(), nil
+}
+
+// GetEntryAt returns the entry at a given index in the entries array
+func (w *Wallet) GetEntryAt(i int, _ ...wallet.Option) (wallet.Entry, error) {
+	if i < 0 || i >= len(w.entries) {
+		return wallet.Entry{}, fmt.Errorf("entry index %d is out of range", i)
+	}
+	return w.entries[i], nil
+}
+
+// GetEntry returns the entry of given address
+func (w *Wallet) GetEntry(addr cipher.Addresser, _ ...wallet.Option) (wallet.Entry, error) {
+	e, ok := w.entries.Get(addr)
+	if !ok {
+		return wallet.Entry{}, wallet.ErrEntryNotFound
+	}
+	return e, nil
+}
+
+// HasEntry returns true if the wallet has an Entry with a given address
+func (w *Wallet) HasEntry(addr cipher.Addresser, _ ...wallet.Option) (bool, error) {
+	return w.entries.Has(addr), nil
+}
+
+// EntriesLen returns the number of entries in the wallet
+func (w *Wallet) EntriesLen(_ ...wallet.Option) (int, error) {
+	return len(w.entries), nil
+}
+
+// Loader implements the wallet.Loader interface
+type Loader struct{}
+
+// Load loads the watch wallet from byte slice
+func (l Loader) Load(data []byte) (wallet.Wallet, error) {
+	w := &Wallet{}
+	if err := w.Deserialize(data); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// Creator implements the wallet.Creator interface
+type Creator struct{}
+
+// Create creates a watch wallet.
+// wallet.Options has no field for an address list, so the addresses are passed
+// in place of the seed, joined with commas.
+func (c Creator) Create(filename, label, addrs string, options wallet.Options) (wallet.Wallet, error) {
+	coinType := options.Coin
+	if coinType == "" {
+		coinType = wallet.CoinTypeSkycoin
+	}
+
+	as, err := ParseAddresses(coinType, addrs)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWallet(filename, label, as, convertOptions(options)...)
+}
+
+func convertOptions(options wallet.Options) []wallet.Option {
+	var opts []wallet.Option
+
+	if options.Coin != "" {
+		opts = append(opts, wallet.OptionCoinType(options.Coin))
+	}
+
+	if options.Decoder != nil {
+		opts = append(opts, wallet.OptionDecoder(options.Decoder))
+	}
+
+	if options.Encrypt {
+		opts = append(opts, wallet.OptionEncrypt(true))
+	}
+
+	if options.Temp {
+		opts = append(opts, wallet.OptionTemp(true))
+	}
+
+	return opts
+}
@@ -0,0 +1,260 @@
+package watch
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/skycoin/skycoin/src/cipher"
+	"github.com/skycoin/skycoin/src/wallet"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	testSkycoinAddresses = stringsToAddresses([]string{
+		"2JBfeo6y6FQn2rCiuhdQ8F1E6bj6rpnHo5U",
+		"28Wn9scn3wb5nkScHiTHgNmLjSUS3F2SqAj",
+		"qHVbkuuzzxGE6p6CnLY1JxY9ifK1RxjoNS",
+	})
+)
+
+func stringsToAddresses(addrsStr []string) []cipher.Addresser {
+	var addrs []cipher.Addresser
+	for _, addr := range addrsStr {
+		a := cipher.MustDecodeBase58Address(addr)
+		addrs = append(addrs, a)
+	}
+
+	return addrs
+}
+
+func TestNewWallet(t *testing.T) {
+	tt := []struct {
+		name    string
+		wltName string
+		label   string
+		addrs   []cipher.Addresser
+		opts    []wallet.Option
+		meta    map[string]string
+		err     error
+	}{
+		{
+			name:    "ok all defaults",
+			wltName: "test.wlt",
+			label:   "test",
+			addrs:   testSkycoinAddresses,
+			meta: map[string]string{
+				"label":    "test",
+				"filename": "test.wlt",
+				"coin":     string(wallet.CoinTypeSkycoin),
+				"type":     WalletType,
+				"version":  wallet.Version,
+			},
+		},
+		{
+			name:    "temp wallet",
+			wltName: "test.wlt",
+			label:   "test",
+			addrs:   testSkycoinAddresses,
+			opts: []wallet.Option{
+				wallet.OptionTemp(true),
+			},
+			meta: map[string]string{
+				"type": WalletType,
+				"temp": "true",
+			},
+		},
+		{
+			name:  "missing filename",
+			label: "test",
+			addrs: testSkycoinAddresses,
+			err:   fmt.Errorf("filename not set"),
+		},
+		{
+			name:    "missing label",
+			wltName: "test.wlt",
+			addrs:   testSkycoinAddresses,
+			err:     wallet.ErrMissingLabel,
+		},
+		{
+			name:    "missing addresses",
+			wltName: "test.wlt",
+			label:   "test",
+			err:     ErrMissingAddresses,
+		},
+		{
+			name:    "duplicate addresses",
+			wltName: "test.wlt",
+			label:   "test",
+			addrs:   append(testSkycoinAddresses, testSkycoinAddresses[0]),
+			err:     ErrDuplicateAddress,
+		},
+		{
+			name:    "null address",
+			wltName: "test.wlt",
+			label:   "test",
+			addrs:   []cipher.Addresser{cipher.Address{}},
+			err:     wallet.NewError(errors.New("watch wallet address must not be the null address")),
+		},
+		{
+			name:    "encrypt",
+			wltName: "test.wlt",
+			label:   "test",
+			addrs:   testSkycoinAddresses,
+			opts: []wallet.Option{
+				wallet.OptionEncrypt(true),
+			},
+			err: wallet.NewError(errors.New("watch wallet does not support encryption")),
+		},
+		{
+			name:    "generate addresses",
+			wltName: "test.wlt",
+			label:   "test",
+			addrs:   testSkycoinAddresses,
+			opts: []wallet.Option{
+				wallet.OptionGenerateN(1),
+			},
+			err: wallet.NewError(fmt.Errorf("wallet scanning is not defined for %q wallet", WalletType)),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			w, err := NewWallet(tc.wltName, tc.label, tc.addrs, tc.opts...)
+			require.Equal(t, tc.err, err)
+			if err != nil {
+				return
+			}
+
+			require.NotEmpty(t, w.Timestamp())
+			require.NotNil(t, w.decoder)
+			require.Empty(t, w.Fingerprint())
+
+			for k, v := range tc.meta {
+				require.Equal(t, v, w.Meta[k])
+			}
+
+			addrs, err := w.GetAddresses()
+			require.NoError(t, err)
+			require.Equal(t, tc.addrs, addrs)
+
+			entries, err := w.GetEntries()
+			require.NoError(t, err)
+			for _, e := range entries {
+				require.True(t, e.Public.Null())
+				require.True(t, e.Secret.Null())
+			}
+		})
+	}
+}
+
+func TestCreator(t *testing.T) {
+	tt := []struct {
+		name  string
+		addrs string
+		opts  wallet.Options
+		err   error
+	}{
+		{
+			name:  "ok",
+			addrs: "2JBfeo6y6FQn2rCiuhdQ8F1E6bj6rpnHo5U, 28Wn9scn3wb5nkScHiTHgNmLjSUS3F2SqAj,qHVbkuuzzxGE6p6CnLY1JxY9ifK1RxjoNS",
+		},
+		{
+			name:  "ignores generate and scan options",
+			addrs: "2JBfeo6y6FQn2rCiuhdQ8F1E6bj6rpnHo5U,28Wn9scn3wb5nkScHiTHgNmLjSUS3F2SqAj,qHVbkuuzzxGE6p6CnLY1JxY9ifK1RxjoNS",
+			opts: wallet.Options{
+				GenerateN: 1,
+				ScanN:     5,
+			},
+		},
+		{
+			name:  "invalid address",
+			addrs: "2JBfeo6y6FQn2rCiuhdQ8F1E6bj6rpnHo5U,badaddr",
+			err:   wallet.NewError(errors.New(`invalid address "badaddr": Invalid address length`)),
+		},
+		{
+			name: "no addresses",
+			err:  ErrMissingAddresses,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.opts.Type = WalletType
+			w, err := wallet.NewWallet("test.wlt", "test", tc.addrs, tc.opts)
+			require.Equal(t, tc.err, err)
+			if err != nil {
+				return
+			}
+
+			require.Equal(t, WalletType, w.Type())
+
+			addrs, err := w.GetAddresses()
+			require.NoError(t, err)
+			require.Equal(t, testSkycoinAddresses, addrs)
+		})
+	}
+}
+
+func TestWalletAddAddresses(t *testing.T) {
+	w, err := NewWallet("test.wlt", "test", testSkycoinAddresses[:1])
+	require.NoError(t, err)
+
+	// No address is added if one of them is already in the wallet
+	err = w.AddAddresses(testSkycoinAddresses)
+	require.Equal(t, ErrDuplicateAddress, err)
+
+	n, err := w.EntriesLen()
+	require.NoError(t, err)
+	require.Equal(t, 1, n)
+
+	err = w.AddAddresses(testSkycoinAddresses[1:])
+	require.NoError(t, err)
+
+	addrs, err := w.GetAddresses()
+	require.NoError(t, err)
+	require.Equal(t, testSkycoinAddresses, addrs)
+
+	_, err = w.GenerateAddresses(wallet.OptionGenerateN(1))
+	require.Error(t, err)
+}
+
+func TestWalletSerialize(t *testing.T) {
+	w, err := NewWallet("test.wlt", "test", testSkycoinAddresses)
+	require.NoError(t, err)
+
+	w.SetTimestamp(0)
+	b, err := w.Serialize()
+	require.NoError(t, err)
+
+	// load wallet file and compare
+	fb, err := ioutil.ReadFile("./testdata/wallet_serialize.wlt")
+	require.NoError(t, err)
+	require.Equal(t, bytes.TrimRight(fb, "\n"), b)
+
+	wlt := Wallet{}
+	err = wlt.Deserialize(b)
+	require.NoError(t, err)
+}
+
+func TestWalletDeserialize(t *testing.T) {
+	b, err := ioutil.ReadFile("./testdata/wallet_serialize.wlt")
+	require.NoError(t, err)
+
+	w := Wallet{}
+	err = w.Deserialize(b)
+	require.NoError(t, err)
+
+	require.Equal(t, w.Filename(), "test.wlt")
+	require.Equal(t, w.Label(), "test")
+	require.Equal(t, WalletType, w.Type())
+	entries, err := w.GetEntries()
+	require.NoError(t, err)
+	require.Equal(t, 3, len(entries))
+	for i, e := range entries {
+		require.Equal(t, testSkycoinAddresses[i], e.Address)
+		require.True(t, e.Public.Null())
+	}
+}